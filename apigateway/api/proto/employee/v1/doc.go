@@ -0,0 +1,5 @@
+// Package employeev1 holds the gRPC API of the employee service, generated
+// from employee.proto with protoc-gen-go and protoc-gen-go-grpc.
+package employeev1
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative ../../employee/v1/employee.proto
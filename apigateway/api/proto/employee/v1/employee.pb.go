@@ -0,0 +1,706 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.7
+// 	protoc        (unknown)
+// source: employee/v1/employee.proto
+
+package employeev1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Employee mirrors the employee table. Dates are YYYY-MM-DD.
+type Employee struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Id        int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	FirstName string                 `protobuf:"bytes,2,opt,name=first_name,json=firstName,proto3" json:"first_name,omitempty"`
+	LastName  string                 `protobuf:"bytes,3,opt,name=last_name,json=lastName,proto3" json:"last_name,omitempty"`
+	// M or F
+	Gender        string `protobuf:"bytes,4,opt,name=gender,proto3" json:"gender,omitempty"`
+	BirthDate     string `protobuf:"bytes,5,opt,name=birth_date,json=birthDate,proto3" json:"birth_date,omitempty"`
+	HireDate      string `protobuf:"bytes,6,opt,name=hire_date,json=hireDate,proto3" json:"hire_date,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Employee) Reset() {
+	*x = Employee{}
+	mi := &file_employee_v1_employee_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Employee) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Employee) ProtoMessage() {}
+
+func (x *Employee) ProtoReflect() protoreflect.Message {
+	mi := &file_employee_v1_employee_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Employee.ProtoReflect.Descriptor instead.
+func (*Employee) Descriptor() ([]byte, []int) {
+	return file_employee_v1_employee_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Employee) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Employee) GetFirstName() string {
+	if x != nil {
+		return x.FirstName
+	}
+	return ""
+}
+
+func (x *Employee) GetLastName() string {
+	if x != nil {
+		return x.LastName
+	}
+	return ""
+}
+
+func (x *Employee) GetGender() string {
+	if x != nil {
+		return x.Gender
+	}
+	return ""
+}
+
+func (x *Employee) GetBirthDate() string {
+	if x != nil {
+		return x.BirthDate
+	}
+	return ""
+}
+
+func (x *Employee) GetHireDate() string {
+	if x != nil {
+		return x.HireDate
+	}
+	return ""
+}
+
+type GetEmployeeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetEmployeeRequest) Reset() {
+	*x = GetEmployeeRequest{}
+	mi := &file_employee_v1_employee_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetEmployeeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetEmployeeRequest) ProtoMessage() {}
+
+func (x *GetEmployeeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_employee_v1_employee_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetEmployeeRequest.ProtoReflect.Descriptor instead.
+func (*GetEmployeeRequest) Descriptor() ([]byte, []int) {
+	return file_employee_v1_employee_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *GetEmployeeRequest) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type ListEmployeesRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// page_size caps the page, 0 lists every employee
+	PageSize int32 `protobuf:"varint,1,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// page_token is the next_page_token of the previous page
+	PageToken string `protobuf:"bytes,2,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	// name_prefix matches the start of the first or last name, ignoring case
+	NamePrefix string `protobuf:"bytes,3,opt,name=name_prefix,json=namePrefix,proto3" json:"name_prefix,omitempty"`
+	Gender     string `protobuf:"bytes,4,opt,name=gender,proto3" json:"gender,omitempty"`
+	// dept_no keeps the current employees of a department
+	DeptNo        string `protobuf:"bytes,5,opt,name=dept_no,json=deptNo,proto3" json:"dept_no,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListEmployeesRequest) Reset() {
+	*x = ListEmployeesRequest{}
+	mi := &file_employee_v1_employee_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListEmployeesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListEmployeesRequest) ProtoMessage() {}
+
+func (x *ListEmployeesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_employee_v1_employee_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListEmployeesRequest.ProtoReflect.Descriptor instead.
+func (*ListEmployeesRequest) Descriptor() ([]byte, []int) {
+	return file_employee_v1_employee_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListEmployeesRequest) GetPageSize() int32 {
+	if x != nil {
+		return x.PageSize
+	}
+	return 0
+}
+
+func (x *ListEmployeesRequest) GetPageToken() string {
+	if x != nil {
+		return x.PageToken
+	}
+	return ""
+}
+
+func (x *ListEmployeesRequest) GetNamePrefix() string {
+	if x != nil {
+		return x.NamePrefix
+	}
+	return ""
+}
+
+func (x *ListEmployeesRequest) GetGender() string {
+	if x != nil {
+		return x.Gender
+	}
+	return ""
+}
+
+func (x *ListEmployeesRequest) GetDeptNo() string {
+	if x != nil {
+		return x.DeptNo
+	}
+	return ""
+}
+
+type ListEmployeesResponse struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Employees []*Employee            `protobuf:"bytes,1,rep,name=employees,proto3" json:"employees,omitempty"`
+	// next_page_token is empty on the last page
+	NextPageToken string `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListEmployeesResponse) Reset() {
+	*x = ListEmployeesResponse{}
+	mi := &file_employee_v1_employee_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListEmployeesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListEmployeesResponse) ProtoMessage() {}
+
+func (x *ListEmployeesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_employee_v1_employee_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListEmployeesResponse.ProtoReflect.Descriptor instead.
+func (*ListEmployeesResponse) Descriptor() ([]byte, []int) {
+	return file_employee_v1_employee_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ListEmployeesResponse) GetEmployees() []*Employee {
+	if x != nil {
+		return x.Employees
+	}
+	return nil
+}
+
+func (x *ListEmployeesResponse) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+type CreateEmployeeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Employee      *Employee              `protobuf:"bytes,1,opt,name=employee,proto3" json:"employee,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateEmployeeRequest) Reset() {
+	*x = CreateEmployeeRequest{}
+	mi := &file_employee_v1_employee_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateEmployeeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateEmployeeRequest) ProtoMessage() {}
+
+func (x *CreateEmployeeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_employee_v1_employee_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateEmployeeRequest.ProtoReflect.Descriptor instead.
+func (*CreateEmployeeRequest) Descriptor() ([]byte, []int) {
+	return file_employee_v1_employee_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *CreateEmployeeRequest) GetEmployee() *Employee {
+	if x != nil {
+		return x.Employee
+	}
+	return nil
+}
+
+type UpdateEmployeeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Employee      *Employee              `protobuf:"bytes,1,opt,name=employee,proto3" json:"employee,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UpdateEmployeeRequest) Reset() {
+	*x = UpdateEmployeeRequest{}
+	mi := &file_employee_v1_employee_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateEmployeeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateEmployeeRequest) ProtoMessage() {}
+
+func (x *UpdateEmployeeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_employee_v1_employee_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateEmployeeRequest.ProtoReflect.Descriptor instead.
+func (*UpdateEmployeeRequest) Descriptor() ([]byte, []int) {
+	return file_employee_v1_employee_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *UpdateEmployeeRequest) GetEmployee() *Employee {
+	if x != nil {
+		return x.Employee
+	}
+	return nil
+}
+
+type DeleteEmployeeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteEmployeeRequest) Reset() {
+	*x = DeleteEmployeeRequest{}
+	mi := &file_employee_v1_employee_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteEmployeeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteEmployeeRequest) ProtoMessage() {}
+
+func (x *DeleteEmployeeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_employee_v1_employee_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteEmployeeRequest.ProtoReflect.Descriptor instead.
+func (*DeleteEmployeeRequest) Descriptor() ([]byte, []int) {
+	return file_employee_v1_employee_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DeleteEmployeeRequest) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type DeleteEmployeeResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteEmployeeResponse) Reset() {
+	*x = DeleteEmployeeResponse{}
+	mi := &file_employee_v1_employee_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteEmployeeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteEmployeeResponse) ProtoMessage() {}
+
+func (x *DeleteEmployeeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_employee_v1_employee_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteEmployeeResponse.ProtoReflect.Descriptor instead.
+func (*DeleteEmployeeResponse) Descriptor() ([]byte, []int) {
+	return file_employee_v1_employee_proto_rawDescGZIP(), []int{7}
+}
+
+type GenerateReportRequest struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	TemplateId string                 `protobuf:"bytes,1,opt,name=template_id,json=templateId,proto3" json:"template_id,omitempty"`
+	// variables set the template variables, parsed as in query strings
+	Variables map[string]string `protobuf:"bytes,2,rep,name=variables,proto3" json:"variables,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// format is xlsx (the default), csv or html
+	Format        string `protobuf:"bytes,3,opt,name=format,proto3" json:"format,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GenerateReportRequest) Reset() {
+	*x = GenerateReportRequest{}
+	mi := &file_employee_v1_employee_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GenerateReportRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GenerateReportRequest) ProtoMessage() {}
+
+func (x *GenerateReportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_employee_v1_employee_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GenerateReportRequest.ProtoReflect.Descriptor instead.
+func (*GenerateReportRequest) Descriptor() ([]byte, []int) {
+	return file_employee_v1_employee_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *GenerateReportRequest) GetTemplateId() string {
+	if x != nil {
+		return x.TemplateId
+	}
+	return ""
+}
+
+func (x *GenerateReportRequest) GetVariables() map[string]string {
+	if x != nil {
+		return x.Variables
+	}
+	return nil
+}
+
+func (x *GenerateReportRequest) GetFormat() string {
+	if x != nil {
+		return x.Format
+	}
+	return ""
+}
+
+// ReportChunk is a piece of the generated file. The first chunk also
+// carries the file metadata.
+type ReportChunk struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ContentType   string                 `protobuf:"bytes,1,opt,name=content_type,json=contentType,proto3" json:"content_type,omitempty"`
+	FileName      string                 `protobuf:"bytes,2,opt,name=file_name,json=fileName,proto3" json:"file_name,omitempty"`
+	Data          []byte                 `protobuf:"bytes,3,opt,name=data,proto3" json:"data,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReportChunk) Reset() {
+	*x = ReportChunk{}
+	mi := &file_employee_v1_employee_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReportChunk) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportChunk) ProtoMessage() {}
+
+func (x *ReportChunk) ProtoReflect() protoreflect.Message {
+	mi := &file_employee_v1_employee_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportChunk.ProtoReflect.Descriptor instead.
+func (*ReportChunk) Descriptor() ([]byte, []int) {
+	return file_employee_v1_employee_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ReportChunk) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *ReportChunk) GetFileName() string {
+	if x != nil {
+		return x.FileName
+	}
+	return ""
+}
+
+func (x *ReportChunk) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+var File_employee_v1_employee_proto protoreflect.FileDescriptor
+
+const file_employee_v1_employee_proto_rawDesc = "" +
+	"\n" +
+	"\x1aemployee/v1/employee.proto\x12\x0femployeemgmt.v1\"\xaa\x01\n" +
+	"\bEmployee\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x1d\n" +
+	"\n" +
+	"first_name\x18\x02 \x01(\tR\tfirstName\x12\x1b\n" +
+	"\tlast_name\x18\x03 \x01(\tR\blastName\x12\x16\n" +
+	"\x06gender\x18\x04 \x01(\tR\x06gender\x12\x1d\n" +
+	"\n" +
+	"birth_date\x18\x05 \x01(\tR\tbirthDate\x12\x1b\n" +
+	"\thire_date\x18\x06 \x01(\tR\bhireDate\"$\n" +
+	"\x12GetEmployeeRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\"\xa4\x01\n" +
+	"\x14ListEmployeesRequest\x12\x1b\n" +
+	"\tpage_size\x18\x01 \x01(\x05R\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x02 \x01(\tR\tpageToken\x12\x1f\n" +
+	"\vname_prefix\x18\x03 \x01(\tR\n" +
+	"namePrefix\x12\x16\n" +
+	"\x06gender\x18\x04 \x01(\tR\x06gender\x12\x17\n" +
+	"\adept_no\x18\x05 \x01(\tR\x06deptNo\"x\n" +
+	"\x15ListEmployeesResponse\x127\n" +
+	"\temployees\x18\x01 \x03(\v2\x19.employeemgmt.v1.EmployeeR\temployees\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\"N\n" +
+	"\x15CreateEmployeeRequest\x125\n" +
+	"\bemployee\x18\x01 \x01(\v2\x19.employeemgmt.v1.EmployeeR\bemployee\"N\n" +
+	"\x15UpdateEmployeeRequest\x125\n" +
+	"\bemployee\x18\x01 \x01(\v2\x19.employeemgmt.v1.EmployeeR\bemployee\"'\n" +
+	"\x15DeleteEmployeeRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\"\x18\n" +
+	"\x16DeleteEmployeeResponse\"\xe3\x01\n" +
+	"\x15GenerateReportRequest\x12\x1f\n" +
+	"\vtemplate_id\x18\x01 \x01(\tR\n" +
+	"templateId\x12S\n" +
+	"\tvariables\x18\x02 \x03(\v25.employeemgmt.v1.GenerateReportRequest.VariablesEntryR\tvariables\x12\x16\n" +
+	"\x06format\x18\x03 \x01(\tR\x06format\x1a<\n" +
+	"\x0eVariablesEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"a\n" +
+	"\vReportChunk\x12!\n" +
+	"\fcontent_type\x18\x01 \x01(\tR\vcontentType\x12\x1b\n" +
+	"\tfile_name\x18\x02 \x01(\tR\bfileName\x12\x12\n" +
+	"\x04data\x18\x03 \x01(\fR\x04data2\xcd\x03\n" +
+	"\x0fEmployeeService\x12M\n" +
+	"\vGetEmployee\x12#.employeemgmt.v1.GetEmployeeRequest\x1a\x19.employeemgmt.v1.Employee\x12^\n" +
+	"\rListEmployees\x12%.employeemgmt.v1.ListEmployeesRequest\x1a&.employeemgmt.v1.ListEmployeesResponse\x12S\n" +
+	"\x0eCreateEmployee\x12&.employeemgmt.v1.CreateEmployeeRequest\x1a\x19.employeemgmt.v1.Employee\x12S\n" +
+	"\x0eUpdateEmployee\x12&.employeemgmt.v1.UpdateEmployeeRequest\x1a\x19.employeemgmt.v1.Employee\x12a\n" +
+	"\x0eDeleteEmployee\x12&.employeemgmt.v1.DeleteEmployeeRequest\x1a'.employeemgmt.v1.DeleteEmployeeResponse2i\n" +
+	"\rReportService\x12X\n" +
+	"\x0eGenerateReport\x12&.employeemgmt.v1.GenerateReportRequest\x1a\x1c.employeemgmt.v1.ReportChunk0\x01B]Z[github.com/locvowork/employee_management_sample/apigateway/api/proto/employee/v1;employeev1b\x06proto3"
+
+var (
+	file_employee_v1_employee_proto_rawDescOnce sync.Once
+	file_employee_v1_employee_proto_rawDescData []byte
+)
+
+func file_employee_v1_employee_proto_rawDescGZIP() []byte {
+	file_employee_v1_employee_proto_rawDescOnce.Do(func() {
+		file_employee_v1_employee_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_employee_v1_employee_proto_rawDesc), len(file_employee_v1_employee_proto_rawDesc)))
+	})
+	return file_employee_v1_employee_proto_rawDescData
+}
+
+var file_employee_v1_employee_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_employee_v1_employee_proto_goTypes = []any{
+	(*Employee)(nil),               // 0: employeemgmt.v1.Employee
+	(*GetEmployeeRequest)(nil),     // 1: employeemgmt.v1.GetEmployeeRequest
+	(*ListEmployeesRequest)(nil),   // 2: employeemgmt.v1.ListEmployeesRequest
+	(*ListEmployeesResponse)(nil),  // 3: employeemgmt.v1.ListEmployeesResponse
+	(*CreateEmployeeRequest)(nil),  // 4: employeemgmt.v1.CreateEmployeeRequest
+	(*UpdateEmployeeRequest)(nil),  // 5: employeemgmt.v1.UpdateEmployeeRequest
+	(*DeleteEmployeeRequest)(nil),  // 6: employeemgmt.v1.DeleteEmployeeRequest
+	(*DeleteEmployeeResponse)(nil), // 7: employeemgmt.v1.DeleteEmployeeResponse
+	(*GenerateReportRequest)(nil),  // 8: employeemgmt.v1.GenerateReportRequest
+	(*ReportChunk)(nil),            // 9: employeemgmt.v1.ReportChunk
+	nil,                            // 10: employeemgmt.v1.GenerateReportRequest.VariablesEntry
+}
+var file_employee_v1_employee_proto_depIdxs = []int32{
+	0,  // 0: employeemgmt.v1.ListEmployeesResponse.employees:type_name -> employeemgmt.v1.Employee
+	0,  // 1: employeemgmt.v1.CreateEmployeeRequest.employee:type_name -> employeemgmt.v1.Employee
+	0,  // 2: employeemgmt.v1.UpdateEmployeeRequest.employee:type_name -> employeemgmt.v1.Employee
+	10, // 3: employeemgmt.v1.GenerateReportRequest.variables:type_name -> employeemgmt.v1.GenerateReportRequest.VariablesEntry
+	1,  // 4: employeemgmt.v1.EmployeeService.GetEmployee:input_type -> employeemgmt.v1.GetEmployeeRequest
+	2,  // 5: employeemgmt.v1.EmployeeService.ListEmployees:input_type -> employeemgmt.v1.ListEmployeesRequest
+	4,  // 6: employeemgmt.v1.EmployeeService.CreateEmployee:input_type -> employeemgmt.v1.CreateEmployeeRequest
+	5,  // 7: employeemgmt.v1.EmployeeService.UpdateEmployee:input_type -> employeemgmt.v1.UpdateEmployeeRequest
+	6,  // 8: employeemgmt.v1.EmployeeService.DeleteEmployee:input_type -> employeemgmt.v1.DeleteEmployeeRequest
+	8,  // 9: employeemgmt.v1.ReportService.GenerateReport:input_type -> employeemgmt.v1.GenerateReportRequest
+	0,  // 10: employeemgmt.v1.EmployeeService.GetEmployee:output_type -> employeemgmt.v1.Employee
+	3,  // 11: employeemgmt.v1.EmployeeService.ListEmployees:output_type -> employeemgmt.v1.ListEmployeesResponse
+	0,  // 12: employeemgmt.v1.EmployeeService.CreateEmployee:output_type -> employeemgmt.v1.Employee
+	0,  // 13: employeemgmt.v1.EmployeeService.UpdateEmployee:output_type -> employeemgmt.v1.Employee
+	7,  // 14: employeemgmt.v1.EmployeeService.DeleteEmployee:output_type -> employeemgmt.v1.DeleteEmployeeResponse
+	9,  // 15: employeemgmt.v1.ReportService.GenerateReport:output_type -> employeemgmt.v1.ReportChunk
+	10, // [10:16] is the sub-list for method output_type
+	4,  // [4:10] is the sub-list for method input_type
+	4,  // [4:4] is the sub-list for extension type_name
+	4,  // [4:4] is the sub-list for extension extendee
+	0,  // [0:4] is the sub-list for field type_name
+}
+
+func init() { file_employee_v1_employee_proto_init() }
+func file_employee_v1_employee_proto_init() {
+	if File_employee_v1_employee_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_employee_v1_employee_proto_rawDesc), len(file_employee_v1_employee_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   2,
+		},
+		GoTypes:           file_employee_v1_employee_proto_goTypes,
+		DependencyIndexes: file_employee_v1_employee_proto_depIdxs,
+		MessageInfos:      file_employee_v1_employee_proto_msgTypes,
+	}.Build()
+	File_employee_v1_employee_proto = out.File
+	file_employee_v1_employee_proto_goTypes = nil
+	file_employee_v1_employee_proto_depIdxs = nil
+}
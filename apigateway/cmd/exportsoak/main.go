@@ -0,0 +1,233 @@
+// Command exportsoak runs large streaming employee exports in a loop against a
+// seeded database and samples memory, goroutine, file descriptor and DB pool
+// counts over time. Run it before a release to catch leaks in the streamer,
+// the dataflow stages and the connection pool:
+//
+//	go run ./cmd/exportsoak -duration 30m -interval 30s
+//
+// It exits non-zero when the counts grew beyond the allowed limits between
+// the first and the last sample.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/config"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/database"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/repository"
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/dataflow"
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/simpleexcelv2"
+)
+
+// soakSection is the streamed section of every export.
+const soakSection = "employees"
+
+type employeeRow struct {
+	ID        int
+	FirstName string
+	LastName  string
+	Gender    string
+	BirthDate string
+	HireDate  string
+}
+
+func main() {
+	duration := flag.Duration("duration", 10*time.Minute, "How long to keep exporting")
+	interval := flag.Duration("interval", 30*time.Second, "Time between samples")
+	rows := flag.Int("rows", 0, "Rows per export, 0 exports every employee")
+	chunk := flag.Int("chunk", 1000, "Employees fetched per chunk query")
+	workers := flag.Int("workers", 4, "Workers of the row conversion stage")
+	maxHeap := flag.Int("max-heap-growth", 64, "Allowed heap growth in MiB")
+	maxGoroutines := flag.Int("max-goroutine-growth", 5, "Allowed goroutine growth")
+	maxFDs := flag.Int("max-fd-growth", 5, "Allowed open file descriptor growth")
+
+	flag.Parse()
+
+	if err := config.LoadEnvConfig(); err != nil {
+		log.Fatalf("❌ Failed to load config: %v", err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// Only the database is needed, so the app bootstrap is skipped
+	cfg := config.DefaultEnvConfig
+	db, err := database.NewPostgresDB(ctx, database.Config{
+		Driver:          cfg.DB_DRIVER,
+		Host:            cfg.DB_HOST,
+		Port:            cfg.DB_PORT,
+		User:            cfg.DB_USER,
+		Password:        cfg.DB_PASSWORD,
+		DBName:          cfg.DB_NAME,
+		SSLMode:         cfg.DB_SSL_MODE,
+		MaxOpenConns:    cfg.DB_MAX_OPEN_CONNS,
+		MaxIdleConns:    cfg.DB_MAX_IDLE_CONNS,
+		ConnMaxLifetime: cfg.DB_CONN_MAX_LIFETIME,
+	})
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+	repo := repository.NewEmployeeRepository(db)
+
+	fmt.Println("🔥 Export Soak Test")
+	fmt.Println(strings.Repeat("=", 50))
+	fmt.Printf("📊 %s of exports, sampling every %s\n", *duration, *interval)
+
+	ctx, cancel := context.WithTimeout(ctx, *duration)
+	defer cancel()
+
+	// A warm-up export fills the pool and the caches before the baseline
+	filter := domain.EmployeeFilter{Limit: *rows}
+	if _, err := runExport(ctx, repo, filter, *chunk, *workers); err != nil {
+		log.Fatalf("❌ Warm-up export failed: %v", err)
+	}
+
+	var exports, failures, written int64
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for ctx.Err() == nil {
+			n, err := runExport(ctx, repo, filter, *chunk, *workers)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				atomic.AddInt64(&failures, 1)
+				fmt.Printf("⚠️  Export failed: %v\n", err)
+				continue
+			}
+			atomic.AddInt64(&exports, 1)
+			atomic.AddInt64(&written, n)
+		}
+	}()
+
+	fmt.Println(sampleHeader)
+	first := takeSample(db)
+	fmt.Println(first.row(0, 0, 0))
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+loop:
+	for {
+		select {
+		case <-done:
+			break loop
+		case <-ticker.C:
+			s := takeSample(db)
+			fmt.Println(s.row(atomic.LoadInt64(&exports), atomic.LoadInt64(&failures), atomic.LoadInt64(&written)))
+		}
+	}
+
+	// Give the stages of the last export time to wind down before the
+	// final sample
+	time.Sleep(time.Second)
+	last := takeSample(db)
+	fmt.Println(last.row(exports, failures, written))
+
+	leaks := last.growth(first, uint64(*maxHeap)<<20, *maxGoroutines, *maxFDs)
+	for _, leak := range leaks {
+		fmt.Printf("❌ %s\n", leak)
+	}
+	if exports == 0 {
+		leaks = append(leaks, "no export finished")
+		fmt.Println("❌ No export finished")
+	}
+	if len(leaks) > 0 {
+		os.Exit(1)
+	}
+	fmt.Printf("\n✅ %d export(s), %d failure(s), no leaks detected\n", exports, failures)
+}
+
+// runExport streams one export of the employees matching filter to a
+// discarding writer and returns the bytes written. Chunks are converted to
+// rows by a dataflow stage, as the report exports do.
+func runExport(ctx context.Context, repo domain.EmployeeRepository, filter domain.EmployeeFilter, chunkSize, workers int) (int64, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	exporter := simpleexcelv2.NewExcelDataExporter()
+	exporter.AddSheet("Employees").AddSection(&simpleexcelv2.SectionConfig{
+		ID:         soakSection,
+		Title:      "Employees",
+		ShowHeader: true,
+		Columns: []simpleexcelv2.ColumnConfig{
+			{FieldName: "ID", Header: "ID", Width: 10},
+			{FieldName: "FirstName", Header: "First Name", Width: 20},
+			{FieldName: "LastName", Header: "Last Name", Width: 20},
+			{FieldName: "Gender", Header: "Gender", Width: 8},
+			{FieldName: "BirthDate", Header: "Birth Date", Width: 12},
+			{FieldName: "HireDate", Header: "Hire Date", Width: 12},
+		},
+	})
+	var out countWriter
+	streamer, err := exporter.StartStream(&out)
+	if err != nil {
+		return 0, err
+	}
+
+	chunks := make(chan interface{})
+	var queryErr error
+	go func() {
+		defer close(chunks)
+		queryErr = repo.ListChunks(ctx, filter, chunkSize, func(employees []domain.Employee) error {
+			select {
+			case chunks <- employees:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	converted := dataflow.Map(ctx, dataflow.New(chunks), func(msg interface{}) (interface{}, error) {
+		employees := msg.([]domain.Employee)
+		rows := make([]employeeRow, len(employees))
+		for i, e := range employees {
+			rows[i] = employeeRow{
+				ID:        e.ID,
+				FirstName: e.FirstName,
+				LastName:  e.LastName,
+				Gender:    e.Gender,
+				BirthDate: e.BirthDate.Format("2006-01-02"),
+				HireDate:  e.HireDate.Format("2006-01-02"),
+			}
+		}
+		return rows, nil
+	}, dataflow.WithWorkers(workers))
+
+	err = dataflow.ForEach(ctx, converted, func(msg interface{}) error {
+		return streamer.Write(soakSection, msg)
+	})
+	if err != nil {
+		cancel()
+		// Drain so the query goroutine does not outlive the export
+		for range chunks {
+		}
+		streamer.Close()
+		return 0, err
+	}
+	if queryErr != nil {
+		streamer.Close()
+		return 0, queryErr
+	}
+	if err := streamer.Close(); err != nil {
+		return 0, err
+	}
+	return out.n, nil
+}
+
+// countWriter discards the export and counts its bytes.
+type countWriter struct{ n int64 }
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
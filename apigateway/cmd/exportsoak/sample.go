@@ -0,0 +1,71 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+)
+
+const sampleHeader = "time      exports  failed  written_mb  heap_mb  goroutines  fds  db_open  db_in_use  db_wait"
+
+// sample is a snapshot of the resources a leak would grow.
+type sample struct {
+	at         time.Time
+	heap       uint64
+	goroutines int
+	fds        int
+	db         sql.DBStats
+}
+
+// takeSample collects garbage first, so heap growth reflects live memory.
+func takeSample(db *sql.DB) sample {
+	runtime.GC()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return sample{
+		at:         time.Now(),
+		heap:       m.HeapAlloc,
+		goroutines: runtime.NumGoroutine(),
+		fds:        openFDs(),
+		db:         db.Stats(),
+	}
+}
+
+// openFDs counts the open file descriptors of the process, -1 where /proc is
+// not available.
+func openFDs() int {
+	entries, err := os.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+	return len(entries)
+}
+
+func (s sample) row(exports, failures, written int64) string {
+	return fmt.Sprintf("%-8s  %7d  %6d  %10.1f  %7.1f  %10d  %3d  %7d  %9d  %7d",
+		s.at.Format("15:04:05"), exports, failures, float64(written)/(1<<20),
+		float64(s.heap)/(1<<20), s.goroutines, s.fds, s.db.OpenConnections, s.db.InUse, s.db.WaitCount)
+}
+
+// growth reports the resources of s that grew beyond the limits since first.
+// Connections still in use once the exports stopped are always a leak.
+func (s sample) growth(first sample, maxHeap uint64, maxGoroutines, maxFDs int) []string {
+	var leaks []string
+	if s.heap > first.heap+maxHeap {
+		leaks = append(leaks, fmt.Sprintf("heap grew from %.1f MiB to %.1f MiB",
+			float64(first.heap)/(1<<20), float64(s.heap)/(1<<20)))
+	}
+	if s.goroutines > first.goroutines+maxGoroutines {
+		leaks = append(leaks, fmt.Sprintf("goroutines grew from %d to %d", first.goroutines, s.goroutines))
+	}
+	// Pooled connections hold a descriptor each and come and go
+	if first.fds >= 0 && s.fds-s.db.OpenConnections > first.fds-first.db.OpenConnections+maxFDs {
+		leaks = append(leaks, fmt.Sprintf("open file descriptors grew from %d to %d", first.fds, s.fds))
+	}
+	if s.db.InUse > first.db.InUse {
+		leaks = append(leaks, fmt.Sprintf("%d database connection(s) still in use", s.db.InUse-first.db.InUse))
+	}
+	return leaks
+}
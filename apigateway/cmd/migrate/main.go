@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/config"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/database"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/database/migrations"
+)
+
+func main() {
+	action := flag.String("action", "status", "Action to perform: up, down, status")
+	steps := flag.Int("steps", 1, "Number of migrations to roll back with -action down")
+
+	flag.Parse()
+
+	if err := config.LoadEnvConfig(); err != nil {
+		log.Fatalf("❌ Failed to load config: %v", err)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	// Only the database is needed, so the app bootstrap is skipped
+	cfg := config.DefaultEnvConfig
+	db, err := database.NewPostgresDB(ctx, database.Config{
+		Driver:          cfg.DB_DRIVER,
+		Host:            cfg.DB_HOST,
+		Port:            cfg.DB_PORT,
+		User:            cfg.DB_USER,
+		Password:        cfg.DB_PASSWORD,
+		DBName:          cfg.DB_NAME,
+		SSLMode:         cfg.DB_SSL_MODE,
+		MaxOpenConns:    cfg.DB_MAX_OPEN_CONNS,
+		MaxIdleConns:    cfg.DB_MAX_IDLE_CONNS,
+		ConnMaxLifetime: cfg.DB_CONN_MAX_LIFETIME,
+	})
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	migrator, err := migrations.New(db)
+	if err != nil {
+		log.Fatalf("❌ Failed to load migrations: %v", err)
+	}
+
+	switch *action {
+	case "up":
+		done, err := migrator.Up(ctx)
+		printMigrations("⬆️ ", done)
+		if err != nil {
+			log.Fatalf("❌ Migration failed: %v", err)
+		}
+		fmt.Printf("✅ Applied %d migration(s)\n", len(done))
+
+	case "down":
+		if *steps <= 0 {
+			log.Fatalf("❌ -steps must be positive, got %d", *steps)
+		}
+		done, err := migrator.Down(ctx, *steps)
+		printMigrations("⬇️ ", done)
+		if err != nil {
+			log.Fatalf("❌ Rollback failed: %v", err)
+		}
+		fmt.Printf("✅ Rolled back %d migration(s)\n", len(done))
+
+	case "status":
+		status, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatalf("❌ Status failed: %v", err)
+		}
+		for _, s := range status {
+			applied := "pending"
+			if !s.AppliedAt.IsZero() {
+				applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%03d_%-40s %s\n", s.Version, s.Name, applied)
+		}
+
+	default:
+		fmt.Printf("❌ Unknown action: %s\n", *action)
+		flag.PrintDefaults()
+	}
+}
+
+func printMigrations(prefix string, done []migrations.Migration) {
+	for _, m := range done {
+		fmt.Printf("%s %03d_%s\n", prefix, m.Version, m.Name)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"time"
 
 	"github.com/locvowork/employee_management_sample/apigateway/internal/bootstrap"
 	"github.com/locvowork/employee_management_sample/apigateway/internal/database"
@@ -14,17 +15,26 @@ import (
 
 func main() {
 	// Define flags
-	action := flag.String("action", "seed", "Action to perform: seed, clear")
+	action := flag.String("action", "seed", "Action to perform: seed, plan (print what seed would create), clear")
+	data := flag.String("data", "products", "Data to seed or clear: products, employees, all")
 	preset := flag.String("preset", "large", "Data preset: small, medium, large, xlarge")
 	brands := flag.Int("brands", 0, "Number of brands (overrides preset)")
 	products := flag.Int("products", 0, "Number of products per brand (overrides preset)")
 	features := flag.Int("features", 0, "Number of features per product (overrides preset)")
+	employees := flag.Int("employees", 0, "Number of employees (overrides preset)")
+	departments := flag.Int("departments", 0, "Number of departments, up to 9 (overrides preset)")
+	workers := flag.Int("workers", 4, "Number of concurrent insert batches")
+	seed := flag.Int64("seed", 0, "Seed of the data generators, 0 picks one; the same seed generates the same data")
+	asOf := flag.String("as-of", "", "Date the employee histories run to, YYYY-MM-DD (defaults to today)")
+	manifest := flag.String("manifest", "seed_manifest.json", "File recording the seed and counts of the seeded data, empty to skip it")
+	only := flag.String("only", "", "Data to clear instead of -data, comma separated: brands, products, features, employees")
+	force := flag.Bool("force", false, "Clear without asking for confirmation")
 
 	flag.Parse()
 
 	ctx := context.Background()
 
-	fmt.Println("🚀 Product Data Seeder")
+	fmt.Println("🚀 Data Seeder")
 	fmt.Println(strings.Repeat("=", 50))
 
 	// Initialize app
@@ -42,25 +52,66 @@ func main() {
 		log.Fatal("Database connection is nil")
 	}
 
-	// Get Datastore client and wrap it
-	dsRawClient := app.DataStoreClient
-	if dsRawClient == nil {
-		logger.ErrorLog(ctx, "Raw datastore client is nil")
-		log.Fatal("Datastore client is nil")
+	seedProducts := *data == "products" || *data == "all"
+	seedEmployees := *data == "employees" || *data == "all"
+	if !seedProducts && !seedEmployees {
+		fmt.Printf("❌ Unknown data: %s\n", *data)
+		flag.PrintDefaults()
+		return
 	}
 
-	dsClient := database.WrapDatastoreClient(dsRawClient)
+	// Product infos go to Datastore, employees only need the database
+	var dsClient *database.DatastoreClient
+	if seedProducts && *action == "seed" {
+		dsRawClient := app.DataStoreClient
+		if dsRawClient == nil {
+			logger.ErrorLog(ctx, "Raw datastore client is nil")
+			log.Fatal("Datastore client is nil")
+		}
+		dsClient = database.WrapDatastoreClient(dsRawClient)
+	}
 
-	// Create seeder
-	seeder := database.NewDataSeeder(db, dsClient)
+	// Create seeder, reporting the seed so the data can be generated again
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+	asOfDate := time.Now()
+	if *asOf != "" {
+		var err error
+		if asOfDate, err = time.Parse("2006-01-02", *asOf); err != nil {
+			log.Fatalf("❌ Invalid -as-of %q, expected YYYY-MM-DD", *asOf)
+		}
+	}
+	fmt.Printf("🎲 Seed: %d\n", *seed)
+	seeder := database.NewDataSeeder(db, dsClient, *seed, *workers)
 
 	// Execute action
 	switch *action {
 	case "seed":
-		performSeed(ctx, seeder, preset, brands, products, features)
+		if seedProducts {
+			performSeed(ctx, seeder, preset, brands, products, features)
+		}
+		if seedEmployees {
+			performEmployeeSeed(ctx, seeder, preset, employees, departments, asOfDate)
+		}
+		if *manifest != "" {
+			if err := seeder.WriteManifest(*manifest); err != nil {
+				log.Fatalf("❌ %v", err)
+			}
+			fmt.Printf("📝 Wrote %s\n", *manifest)
+		}
+
+	case "plan":
+		if seedProducts {
+			performPlan(seeder, preset, brands, products, features)
+		}
+		if seedEmployees {
+			performEmployeePlan(ctx, seeder, preset, employees, departments, asOfDate)
+		}
 
 	case "clear":
-		performClear(ctx, seeder)
+		targets := clearTargets(*only, seedProducts, seedEmployees)
+		performClear(ctx, seeder, targets, *force)
 
 	default:
 		fmt.Printf("❌ Unknown action: %s\n", *action)
@@ -71,7 +122,17 @@ func main() {
 }
 
 func performSeed(ctx context.Context, seeder *database.DataSeeder, preset *string, brands, products, features *int) {
-	var numBrands, numProducts, numFeatures int
+	numBrands, numProducts, numFeatures := productConfig(preset, brands, products, features)
+
+	// Perform seeding
+	if err := seeder.SeedData(ctx, numBrands, numProducts, numFeatures); err != nil {
+		log.Fatalf("❌ Seeding failed: %v", err)
+	}
+}
+
+// productConfig returns the product sizes of the flags, from the preset
+// unless all three are given.
+func productConfig(preset *string, brands, products, features *int) (numBrands, numProducts, numFeatures int) {
 
 	// Determine configuration
 	if *brands > 0 && *products > 0 && *features > 0 {
@@ -87,25 +148,102 @@ func performSeed(ctx context.Context, seeder *database.DataSeeder, preset *strin
 		numBrands, numProducts, numFeatures = database.GetPresetConfig(presetConfig)
 		fmt.Printf("📊 Using preset: %s\n", *preset)
 	}
+	return numBrands, numProducts, numFeatures
+}
 
-	// Perform seeding
-	if err := seeder.SeedData(ctx, numBrands, numProducts, numFeatures); err != nil {
-		log.Fatalf("❌ Seeding failed: %v", err)
+func performEmployeeSeed(ctx context.Context, seeder *database.DataSeeder, preset *string, employees, departments *int, asOf time.Time) {
+	numEmployees, numDepartments := employeeConfig(preset, employees, departments)
+
+	if err := seeder.SeedEmployeeData(ctx, numEmployees, numDepartments, asOf); err != nil {
+		log.Fatalf("❌ Seeding employees failed: %v", err)
+	}
+}
+
+// employeeConfig returns the employee sizes of the flags, from the preset
+// unless given.
+func employeeConfig(preset *string, employees, departments *int) (numEmployees, numDepartments int) {
+	numEmployees, numDepartments = database.GetEmployeePresetConfig(database.SeedPreset(*preset))
+	if *employees > 0 {
+		numEmployees = *employees
+	}
+	if *departments > 0 {
+		numDepartments = *departments
+	}
+	fmt.Printf("📊 Using %d employees over %d departments\n", numEmployees, numDepartments)
+	return numEmployees, numDepartments
+}
+
+// performPlan prints the products seed would create, inserting nothing.
+func performPlan(seeder *database.DataSeeder, preset *string, brands, products, features *int) {
+	numBrands, numProducts, numFeatures := productConfig(preset, brands, products, features)
+	data := seeder.PlanData(numBrands, numProducts, numFeatures)
+
+	fmt.Println("📋 Would create:")
+	fmt.Printf("   %d products\n", len(data.Products))
+	fmt.Printf("   %d features\n", len(data.Features))
+	fmt.Printf("   %d product infos in Datastore\n", len(data.ProductInfos))
+}
+
+// performEmployeePlan prints the employees seed would create, inserting
+// nothing.
+func performEmployeePlan(ctx context.Context, seeder *database.DataSeeder, preset *string, employees, departments *int, asOf time.Time) {
+	numEmployees, numDepartments := employeeConfig(preset, employees, departments)
+	data, err := seeder.PlanEmployeeData(ctx, numEmployees, numDepartments, asOf)
+	if err != nil {
+		log.Fatalf("❌ Planning employees failed: %v", err)
 	}
+
+	fmt.Println("📋 Would create:")
+	if len(data.Employees) > 0 {
+		fmt.Printf("   %d employees, ids %d to %d\n", len(data.Employees), data.Employees[0].ID, data.Employees[len(data.Employees)-1].ID)
+	}
+	fmt.Printf("   %d departments (existing ones are kept)\n", len(data.Departments))
+	fmt.Printf("   %d department assignments\n", len(data.DeptEmps))
+	fmt.Printf("   %d managers\n", len(data.DeptManagers))
+	fmt.Printf("   %d salaries\n", len(data.Salaries))
 }
 
-func performClear(ctx context.Context, seeder *database.DataSeeder) {
-	fmt.Println("⚠️  This will delete all seeded data!")
-	fmt.Print("Continue? (yes/no): ")
+// clearTargets returns the data to clear: the comma separated list of only,
+// or the products and employees of -data.
+func clearTargets(only string, products, employees bool) []string {
+	var targets []string
+	if only != "" {
+		for _, target := range strings.Split(only, ",") {
+			target = strings.TrimSpace(target)
+			switch target {
+			case database.ClearBrands, database.ClearProducts, database.ClearFeatures, database.ClearEmployees:
+				targets = append(targets, target)
+			default:
+				log.Fatalf("❌ Unknown -only %q, expected brands, products, features or employees", target)
+			}
+		}
+		return targets
+	}
+	if products {
+		targets = append(targets, database.ClearProducts)
+	}
+	if employees {
+		targets = append(targets, database.ClearEmployees)
+	}
+	return targets
+}
 
-	var response string
-	fmt.Scanln(&response)
+func performClear(ctx context.Context, seeder *database.DataSeeder, targets []string, force bool) {
+	if !force {
+		fmt.Printf("⚠️  This will delete the seeded %s!\n", strings.Join(targets, ", "))
+		fmt.Print("Continue? (yes/no): ")
 
-	if response == "yes" {
-		if err := seeder.ClearData(ctx); err != nil {
+		var response string
+		fmt.Scanln(&response)
+		if response != "yes" {
+			fmt.Println("Cancelled.")
+			return
+		}
+	}
+
+	for _, target := range targets {
+		if err := seeder.ClearOnly(ctx, target); err != nil {
 			log.Fatalf("❌ Clear failed: %v", err)
 		}
-	} else {
-		fmt.Println("Cancelled.")
 	}
 }
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/config"
+	"github.com/locvowork/employee_management_sample/apigateway/templates"
+)
+
+func main() {
+	action := flag.String("action", "list", "Action to perform: list, copy")
+	dir := flag.String("dir", "", "Directory to copy the templates to (defaults to REPORT_TEMPLATE_DIR)")
+	overwrite := flag.Bool("overwrite", false, "Overwrite templates that already exist in the directory")
+
+	flag.Parse()
+
+	switch *action {
+	case "list":
+		names, err := templates.List()
+		if err != nil {
+			log.Fatalf("❌ List failed: %v", err)
+		}
+		for _, name := range names {
+			fmt.Println(name)
+		}
+
+	case "copy":
+		if *dir == "" {
+			if err := config.LoadEnvConfig(); err != nil {
+				log.Fatalf("❌ Failed to load config: %v", err)
+			}
+			*dir = config.DefaultEnvConfig.REPORT_TEMPLATE_DIR
+		}
+		written, err := templates.CopyTo(*dir, *overwrite)
+		if err != nil {
+			log.Fatalf("❌ Copy failed: %v", err)
+		}
+		for _, name := range written {
+			fmt.Printf("📄 %s\n", name)
+		}
+		fmt.Printf("✅ Copied %d template(s) to %s\n", len(written), *dir)
+
+	default:
+		fmt.Printf("❌ Unknown action: %s\n", *action)
+		flag.PrintDefaults()
+	}
+}
@@ -0,0 +1,128 @@
+// Package apperror holds the typed errors of the services. Each has a Kind,
+// which the HTTP and gRPC layers map to their status codes, and a stable
+// Code clients can branch on.
+//
+//	var ErrLeaveNotPending = apperror.Conflict("leave_not_pending", "leave request is not pending")
+//
+//	return fmt.Errorf("review leave %d: %w", id, ErrLeaveNotPending)
+package apperror
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// Kind classifies errors by what the caller can do about them.
+type Kind int
+
+const (
+	// KindInternal errors are failures of the service, the default
+	KindInternal Kind = iota
+	// KindNotFound errors name something that does not exist
+	KindNotFound
+	// KindConflict errors clash with the current state, e.g. a taken key
+	KindConflict
+	// KindValidation errors reject the input of the caller
+	KindValidation
+)
+
+// Codes of the errors without a code of their own.
+const (
+	CodeInternal   = "internal"
+	CodeNotFound   = "not_found"
+	CodeConflict   = "conflict"
+	CodeValidation = "validation_failed"
+)
+
+func (k Kind) String() string {
+	switch k {
+	case KindNotFound:
+		return CodeNotFound
+	case KindConflict:
+		return CodeConflict
+	case KindValidation:
+		return CodeValidation
+	}
+	return CodeInternal
+}
+
+// Error is an error of a Kind. Errors created by the constructors match
+// each other with errors.Is when their kinds and codes do, so wrapping a
+// sentinel error with a cause keeps it recognizable.
+type Error struct {
+	Kind Kind
+	// Code identifies the error, e.g. "leave_not_pending"
+	Code    string
+	Message string
+	// Err is the cause, nil for sentinel errors
+	Err error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Kind == e.Kind && t.Code == e.Code
+}
+
+// NotFound returns an error of KindNotFound.
+func NotFound(code, message string) *Error {
+	return &Error{Kind: KindNotFound, Code: code, Message: message}
+}
+
+// Conflict returns an error of KindConflict.
+func Conflict(code, message string) *Error {
+	return &Error{Kind: KindConflict, Code: code, Message: message}
+}
+
+// Validation returns an error of KindValidation, for input rejected as a
+// whole. Field errors are reported with a domain.ValidationError.
+func Validation(code, message string) *Error {
+	return &Error{Kind: KindValidation, Code: code, Message: message}
+}
+
+// Internal returns an error of KindInternal.
+func Internal(code, message string) *Error {
+	return &Error{Kind: KindInternal, Code: code, Message: message}
+}
+
+// Kinded is implemented by the errors of other packages that have a Kind,
+// such as domain.ValidationError.
+type Kinded interface {
+	error
+	ErrorKind() Kind
+}
+
+// KindOf returns the Kind of the first typed error in the chain of err.
+// sql.ErrNoRows is KindNotFound, untyped errors are KindInternal.
+func KindOf(err error) Kind {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Kind
+	}
+	var k Kinded
+	if errors.As(err, &k) {
+		return k.ErrorKind()
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return KindNotFound
+	}
+	return KindInternal
+}
+
+// CodeOf returns the Code of the first Error in the chain of err, the name
+// of its Kind otherwise.
+func CodeOf(err error) string {
+	var e *Error
+	if errors.As(err, &e) && e.Code != "" {
+		return e.Code
+	}
+	return KindOf(err).String()
+}
@@ -0,0 +1,41 @@
+package apperror_test
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/apperror"
+	"github.com/stretchr/testify/assert"
+)
+
+var errTaken = apperror.Conflict("name_taken", "name is taken")
+
+func TestKindOfAndCodeOf(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantKind apperror.Kind
+		wantCode string
+	}{
+		{"wrapped typed error", fmt.Errorf("create: %w", errTaken), apperror.KindConflict, "name_taken"},
+		{"no rows", fmt.Errorf("get: %w", sql.ErrNoRows), apperror.KindNotFound, apperror.CodeNotFound},
+		{"untyped", errors.New("boom"), apperror.KindInternal, apperror.CodeInternal},
+		{"typed without code", &apperror.Error{Kind: apperror.KindValidation}, apperror.KindValidation, apperror.CodeValidation},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantKind, apperror.KindOf(tt.err))
+			assert.Equal(t, tt.wantCode, apperror.CodeOf(tt.err))
+		})
+	}
+}
+
+func TestError_IsMatchesKindAndCode(t *testing.T) {
+	withCause := &apperror.Error{Kind: apperror.KindConflict, Code: "name_taken", Message: "name is taken", Err: errors.New("unique violation")}
+
+	assert.ErrorIs(t, fmt.Errorf("create: %w", withCause), errTaken)
+	assert.NotErrorIs(t, withCause, apperror.Conflict("other", "name is taken"))
+	assert.Equal(t, "name is taken: unique violation", withCause.Error())
+}
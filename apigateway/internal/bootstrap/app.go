@@ -3,7 +3,10 @@ package bootstrap
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"time"
@@ -13,11 +16,21 @@ import (
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/locvowork/employee_management_sample/apigateway/internal/config"
 	"github.com/locvowork/employee_management_sample/apigateway/internal/database"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/database/dbtx"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/database/migrations"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/grpcserver"
 	"github.com/locvowork/employee_management_sample/apigateway/internal/handler"
 	"github.com/locvowork/employee_management_sample/apigateway/internal/logger"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/metrics"
 	"github.com/locvowork/employee_management_sample/apigateway/internal/repository"
 	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/dataflow"
 	"github.com/locvowork/employee_management_sample/apigateway/pkg/googlecloud"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
 )
 
 type App struct {
@@ -25,6 +38,18 @@ type App struct {
 	DB              *sql.DB
 	GCP             *googlecloud.Client
 	DataStoreClient *datastore.Client
+	// ReplicaDB serves the reads of DB that tolerate lag, nil without
+	// DB_REPLICA_HOST, see dbtx.Read
+	ReplicaDB *sql.DB
+	// GRPC serves internal callers when GRPC_PORT is set
+	GRPC *grpc.Server
+	// Metrics and the registry served on /metrics, nil unless METRICS_ENABLED
+	Metrics         *metrics.Metrics
+	MetricsRegistry *prometheus.Registry
+	// reloader reloads the templates and runtime settings, see Run
+	reloader *reloader
+	// replica routes the reads to ReplicaDB, monitored in Run
+	replica *dbtx.Replica
 	// `type envConfig struct` -> unexported.
 	// I should probably export it if I want to put it in the struct, or just use `interface{}` or ignore it in the struct.
 	// For now, I'll skip storing config in App struct if not strictly needed, or just use the global.
@@ -67,7 +92,7 @@ func (a *App) Initialize(ctx context.Context) error {
 
 	if keyPath != "" {
 		os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", keyPath)
-		logger.InfoLog(ctx, fmt.Sprintf("Found GCP credentials at: %s", keyPath))
+		logger.InfoLog(ctx, "Found GCP credentials at: %s", keyPath)
 	} else {
 		logger.InfoLog(ctx, "GCP credentials file not found, will skip GCP features")
 	}
@@ -84,11 +109,35 @@ func (a *App) Initialize(ctx context.Context) error {
 	os.Unsetenv("FIRESTORE_EMULATOR_HOST")
 
 	// Initialize logging
-	logger.InitLogging(config.DefaultEnvConfig.LOG_FILE_PATH)
+	if err := logger.InitLogging(logger.Config{
+		FilePath: config.DefaultEnvConfig.LOG_FILE_PATH,
+		Rotation: logger.Rotation{
+			MaxSize:    int64(config.DefaultEnvConfig.LOG_MAX_SIZE_MB) << 20,
+			Interval:   config.DefaultEnvConfig.LOG_ROTATE_INTERVAL,
+			MaxAge:     config.DefaultEnvConfig.LOG_MAX_AGE,
+			MaxBackups: config.DefaultEnvConfig.LOG_MAX_BACKUPS,
+			Compress:   config.DefaultEnvConfig.LOG_COMPRESS,
+		},
+		Level:  config.DefaultEnvConfig.LOG_LEVEL,
+		Format: config.DefaultEnvConfig.LOG_FORMAT,
+	}); err != nil {
+		return fmt.Errorf("failed to initialize logging: %w", err)
+	}
 	logger.InfoLog(ctx, "Environment variables loaded successfully")
 
+	if config.DefaultEnvConfig.METRICS_ENABLED {
+		reg := prometheus.NewRegistry()
+		reg.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+		m, err := metrics.New(reg)
+		if err != nil {
+			return fmt.Errorf("failed to initialize metrics: %w", err)
+		}
+		a.Metrics, a.MetricsRegistry = m, reg
+	}
+
 	// Initialize database connection
 	dbConfig := database.Config{
+		Driver:          config.DefaultEnvConfig.DB_DRIVER,
 		Host:            config.DefaultEnvConfig.DB_HOST,
 		Port:            config.DefaultEnvConfig.DB_PORT,
 		User:            config.DefaultEnvConfig.DB_USER,
@@ -98,6 +147,13 @@ func (a *App) Initialize(ctx context.Context) error {
 		MaxOpenConns:    config.DefaultEnvConfig.DB_MAX_OPEN_CONNS,
 		MaxIdleConns:    config.DefaultEnvConfig.DB_MAX_IDLE_CONNS,
 		ConnMaxLifetime: config.DefaultEnvConfig.DB_CONN_MAX_LIFETIME,
+		// Slow statements are logged with their arguments redacted
+		SlowQueryThreshold: config.DefaultEnvConfig.DB_SLOW_QUERY_THRESHOLD,
+	}
+	if a.Metrics != nil {
+		dbConfig.Observer = func(ctx context.Context, q database.Query) {
+			a.Metrics.ObserveQuery(q.Operation, q.Duration, q.Err)
+		}
 	}
 
 	db, err := database.NewPostgresDB(ctx, dbConfig)
@@ -105,25 +161,65 @@ func (a *App) Initialize(ctx context.Context) error {
 		return fmt.Errorf("failed to initialize database: %w", err)
 	}
 	a.DB = db
+	if config.DefaultEnvConfig.DB_MIGRATE_ON_START {
+		if err := migrate(ctx, db); err != nil {
+			return err
+		}
+	}
+	// Transient failures of idempotent reads are retried, see dbtx.Read
+	dbtx.SetRetryPolicy(db, dbtx.RetryPolicy{
+		Attempts: config.DefaultEnvConfig.DB_READ_RETRY_ATTEMPTS,
+		Backoff:  config.DefaultEnvConfig.DB_READ_RETRY_BACKOFF,
+	})
+	if a.MetricsRegistry != nil {
+		// Open, idle and in-use connections and the waits for them
+		a.MetricsRegistry.MustRegister(collectors.NewDBStatsCollector(db, config.DefaultEnvConfig.DB_NAME))
+	}
+	if host := config.DefaultEnvConfig.DB_REPLICA_HOST; host != "" {
+		replicaConfig := dbConfig
+		replicaConfig.Host = host
+		if port := config.DefaultEnvConfig.DB_REPLICA_PORT; port > 0 {
+			replicaConfig.Port = port
+		}
+		replicaDB, err := database.NewPostgresDB(ctx, replicaConfig)
+		if err != nil {
+			// Reads go to the primary, as they do when the replica goes down later
+			logger.WarnLog(ctx, "failed to connect to the read replica: %v (reads will use the primary)", err)
+		} else {
+			a.ReplicaDB = replicaDB
+			a.replica = dbtx.SetReplica(db, replicaDB)
+			if a.MetricsRegistry != nil {
+				a.MetricsRegistry.MustRegister(collectors.NewDBStatsCollector(replicaDB, config.DefaultEnvConfig.DB_NAME+"_replica"))
+			}
+		}
+	}
 
 	dsClient, err := datastore.NewClient(ctx, config.DefaultEnvConfig.GCP_PROJECT_ID)
 	if err != nil {
-		logger.WarnLog(ctx, fmt.Sprintf("failed to initialize datastore client: %v (dump will be skipped)", err))
+		logger.WarnLog(ctx, "failed to initialize datastore client: %v (dump will be skipped)", err)
 		// Don't fail - just skip dump if datastore is unavailable
 	} else {
 		a.DataStoreClient = dsClient
 	}
 
 	// Initialize dependencies
+	txm := dbtx.NewManager(db)
 	empRepo := repository.NewEmployeeRepository(db)
-	empSvc := service.NewEmployeeService(empRepo)
-	empHandler := handler.NewEmployeeHandler(empSvc)
-	compHandler := handler.NewComparisonHandler()
+	empSvc := service.NewEmployeeService(empRepo, txm)
+	attRepo := repository.NewAttendanceRepository(db)
+	attSvc := service.NewAttendanceService(attRepo, empRepo, txm)
+	paySvc := service.NewPayrollService(empRepo, attRepo, service.DefaultRates)
+	annSvc := service.NewAnnotationService(repository.NewAnnotationRepository(db), txm)
+	var stageObserver dataflow.Observer
+	if a.Metrics != nil {
+		stageObserver = a.Metrics.Dataflow
+	}
+	compHandler := handler.NewComparisonHandler(stageObserver)
 
 	// Initialize GCP Datastore Client
 	gcpClient, err := googlecloud.NewClient(ctx, config.DefaultEnvConfig.GCP_PROJECT_ID)
 	if err != nil {
-		logger.ErrorLog(ctx, fmt.Sprintf("failed to initialize GCP client: %v", err))
+		logger.ErrorLog(ctx, "failed to initialize GCP client: %v", err)
 		// We might not want to fail the whole app if GCP is optional, but for now let's be strict if configured.
 	}
 	a.GCP = gcpClient
@@ -137,12 +233,74 @@ func (a *App) Initialize(ctx context.Context) error {
 	// batchSize: 50 (smaller for better concurrency), numWorkers: 10 (more workers)
 	productMerger := service.NewProductMerger(productRepo, featureRepo, datastoreClient, 50, 10)
 	productMergeHandler := handler.NewProductMergeHandler(productMerger)
+	productGraphQLHandler := handler.NewProductGraphQLHandler(productMerger)
+
+	// Initialize report generation
+	reportOpts := []service.ReportServiceOption{service.WithExportScratch(config.DefaultEnvConfig.REPORT_SCRATCH_DIR,
+		int64(config.DefaultEnvConfig.REPORT_SCRATCH_QUOTA))}
+	if a.Metrics != nil {
+		reportOpts = append(reportOpts, service.WithExportObserver(a.Metrics.ObserveExport))
+	}
+	reportSvc := service.NewReportService(nil, reportOpts...)
+	reportSvc.Register(service.NewEmployeeListReport(empSvc, filepath.Join(config.DefaultEnvConfig.REPORT_TEMPLATE_DIR, "employee_list.yaml")))
+	reportSvc.Register(service.NewDeptManagerTimelineReport(empSvc, filepath.Join(config.DefaultEnvConfig.REPORT_TEMPLATE_DIR, "dept_manager_timeline.yaml")))
+	reportSvc.Register(service.NewDeptOrgChartReport(empSvc, filepath.Join(config.DefaultEnvConfig.REPORT_TEMPLATE_DIR, "dept_orgchart.yaml")))
+	reportSvc.Register(service.NewEmployeeEditReport(empSvc, filepath.Join(config.DefaultEnvConfig.REPORT_TEMPLATE_DIR, "employee_edit.yaml"),
+		[]byte(config.DefaultEnvConfig.REPORT_CHECKSUM_KEY)))
+	reportSvc.Register(service.NewEmployeeReport(empSvc, annSvc, filepath.Join(config.DefaultEnvConfig.REPORT_TEMPLATE_DIR, "employee_report.yaml"),
+		[]byte(config.DefaultEnvConfig.REPORT_CHECKSUM_KEY)))
+	reportSvc.Register(service.NewAttendanceTimesheetReport(attSvc, filepath.Join(config.DefaultEnvConfig.REPORT_TEMPLATE_DIR, "attendance_timesheet.yaml"),
+		[]byte(config.DefaultEnvConfig.REPORT_CHECKSUM_KEY)))
+	reportSvc.Register(service.NewPayrollSummaryReport(paySvc, filepath.Join(config.DefaultEnvConfig.REPORT_TEMPLATE_DIR, "payroll_summary.yaml")))
+	reportSvc.Register(service.NewHeadcountTrendReport(empSvc, filepath.Join(config.DefaultEnvConfig.REPORT_TEMPLATE_DIR, "headcount_trend.yaml")))
+	reportSvc.Register(service.NewUpcomingCelebrationsReport(empSvc, filepath.Join(config.DefaultEnvConfig.REPORT_TEMPLATE_DIR, "upcoming_celebrations.yaml")))
+	if err := reportSvc.WarmUp(ctx); err != nil {
+		// Broken templates only affect their own reports, keep serving the rest
+		logger.ErrorLog(ctx, "report warm-up failed: %v", err)
+	}
+	reportHandler := handler.NewReportHandler(reportSvc)
+	planHandler := handler.NewExportPlanHandler(service.NewExportPlanService(reportSvc, repository.NewReportDeliveryRepository(db)))
+	empHandler := handler.NewEmployeeHandler(empSvc, reportSvc)
+	attHandler := handler.NewAttendanceHandler(attSvc, reportSvc)
+	annHandler := handler.NewAnnotationHandler(annSvc, reportSvc)
+	deptHandler := handler.NewDepartmentHandler(service.NewDepartmentService(repository.NewDepartmentRepository(db), empRepo))
+	salaryHandler := handler.NewSalaryHandler(service.NewSalaryService(repository.NewSalaryRepository(db), empRepo, txm))
+	adhocHandler := handler.NewAdhocQueryHandler(service.NewAdhocQueryService(repository.NewAdhocQueryRepository(db, config.DefaultEnvConfig.ADHOC_QUERY_ROLE), service.AdhocQueryLimits{
+		MaxRows: config.DefaultEnvConfig.ADHOC_QUERY_MAX_ROWS,
+		MaxCost: float64(config.DefaultEnvConfig.ADHOC_QUERY_MAX_COST),
+		Timeout: config.DefaultEnvConfig.ADHOC_QUERY_TIMEOUT,
+	}))
+	apiKeySvc := service.NewAPIKeyService(repository.NewAPIKeyRepository(db))
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeySvc)
+	auth := handler.NewAuthenticator(apiKeySvc, config.DefaultEnvConfig.AUTH_JWT_SECRET, config.DefaultEnvConfig.AUTH_REQUIRED)
+	// Report exports are expensive, each client gets its own quota
+	settings := runtimeSettings{
+		LogLevel: config.DefaultEnvConfig.LOG_LEVEL,
+		RateLimit: handler.RateLimit{
+			PerMinute: config.DefaultEnvConfig.RATE_LIMIT_REPORTS_PER_MINUTE,
+			Burst:     config.DefaultEnvConfig.RATE_LIMIT_REPORTS_BURST,
+		},
+	}
+	reportLimiter := handler.NewRateLimiter(settings.RateLimit)
+	a.reloader = &reloader{reports: reportSvc, limiter: reportLimiter, settings: settings}
+	reloadHandler := handler.NewReloadHandler(a.reloader.Reload)
+
+	if config.DefaultEnvConfig.GRPC_PORT != "" {
+		a.GRPC = grpcserver.New(empSvc, reportSvc)
+	}
+
+	// Clients must not pick their address, and so their rate limit, with headers
+	ipExtractor, err := handler.ClientIPExtractor(config.DefaultEnvConfig.TRUSTED_PROXIES)
+	if err != nil {
+		return fmt.Errorf("invalid TRUSTED_PROXIES: %w", err)
+	}
+	a.Echo.IPExtractor = ipExtractor
 
 	// Register Middlewares
 	a.RegisterMiddlewares()
 
 	// Register Routes
-	a.RegisterRoutes(empHandler, compHandler, gcpHandler, productMergeHandler)
+	a.RegisterRoutes(empHandler, attHandler, annHandler, deptHandler, salaryHandler, compHandler, gcpHandler, productMergeHandler, productGraphQLHandler, reportHandler, planHandler, adhocHandler, apiKeyHandler, reloadHandler, auth, reportLimiter)
 
 	// Dump test data to GCP Datastore (async, non-blocking, optional)
 	// if a.DataStoreClient != nil {
@@ -153,7 +311,7 @@ func (a *App) Initialize(ctx context.Context) error {
 	// 		logger.InfoLog(dumpCtx, "Dumping test data to GCP Datastore...")
 	// 		err := DumpUserToGCP(dumpCtx, a.DataStoreClient)
 	// 		if err != nil {
-	// 			logger.WarnLog(dumpCtx, fmt.Sprintf("Dump to GCP Datastore failed (non-critical): %v", err))
+	// 			logger.WarnLog(dumpCtx, "Dump to GCP Datastore failed (non-critical): %v", err)
 	// 		} else {
 	// 			logger.InfoLog(dumpCtx, "Dump to GCP Datastore completed successfully")
 	// 		}
@@ -202,32 +360,106 @@ func queryUser(ctx context.Context, dsClient *datastore.Client) error {
 }
 
 func (a *App) RegisterMiddlewares() {
+	a.Echo.HTTPErrorHandler = handler.ErrorHandler
+	a.Echo.Use(handler.RequestIDMiddleware)
 	a.Echo.Use(middleware.Logger())
-	a.Echo.Use(middleware.Recover())
-	a.Echo.Use(middleware.CORS())
+	if a.Metrics != nil {
+		a.Echo.Use(a.Metrics.Middleware)
+	}
+	a.Echo.Use(handler.RecoverMiddleware)
+	a.Echo.Use(handler.ActorMiddleware)
+	// Browsers only let clients read the export warning and quota headers when exposed
+	a.Echo.Use(middleware.CORSWithConfig(middleware.CORSConfig{
+		ExposeHeaders: []string{handler.ExportWarningCountHeader, handler.ExportWarningHeader,
+			handler.RateLimitLimitHeader, handler.RateLimitRemainingHeader, handler.RetryAfterHeader, echo.HeaderXRequestID},
+	}))
 }
 
-func (a *App) RegisterRoutes(empHandler *handler.EmployeeHandler, compHandler *handler.ComparisonHandler, gcpHandler *handler.GCPDemoHandler, productMergeHandler *handler.ProductMergeHandler) {
+func (a *App) RegisterRoutes(empHandler *handler.EmployeeHandler, attHandler *handler.AttendanceHandler, annHandler *handler.AnnotationHandler, deptHandler *handler.DepartmentHandler, salaryHandler *handler.SalaryHandler, compHandler *handler.ComparisonHandler, gcpHandler *handler.GCPDemoHandler, productMergeHandler *handler.ProductMergeHandler, productGraphQLHandler *handler.ProductGraphQLHandler, reportHandler *handler.ReportHandler, planHandler *handler.ExportPlanHandler, adhocHandler *handler.AdhocQueryHandler, apiKeyHandler *handler.APIKeyHandler, reloadHandler *handler.ReloadHandler, auth *handler.Authenticator, reportLimiter *handler.RateLimiter) {
+	// Uploaded workbooks are parsed in memory, reject oversized ones up front
+	importLimit := middleware.BodyLimit(config.DefaultEnvConfig.IMPORT_MAX_UPLOAD_SIZE)
+	// Report downloads accept a user JWT or an API key, see AUTH_REQUIRED
+	reportLimit := reportLimiter.Middleware
+	reportsRead := auth.RequireScope(domain.ScopeReportsRead)
+	reportsRun := auth.RequireScope(domain.ScopeReportsRun)
+
 	a.Echo.POST("/employees", empHandler.CreateHandler)
+	a.Echo.POST("/employees/import", empHandler.ImportHandler, importLimit)
+	a.Echo.POST("/employees/batch", empHandler.BatchUpsertHandler)
+	a.Echo.GET("/employees/export", reportHandler.EmployeeExportHandler, reportsRead, reportLimit)
+	a.Echo.GET("/employees/export-stats", empHandler.ExportStatsHandler)
 	a.Echo.GET("/employees/:id", empHandler.GetHandler)
 	a.Echo.PUT("/employees/:id", empHandler.UpdateHandler)
 	a.Echo.DELETE("/employees/:id", empHandler.DeleteHandler)
+	a.Echo.POST("/employees/:id/restore", empHandler.RestoreHandler)
 	a.Echo.GET("/employees", empHandler.ListHandler)
 	a.Echo.GET("/employees/:id/report", empHandler.ReportHandler)
+	a.Echo.GET("/employees/:id/report.xlsx", reportHandler.EmployeeReportHandler, reportsRead, reportLimit)
+	a.Echo.POST("/employees/:id/report/import", annHandler.ImportHandler, importLimit)
+	a.Echo.GET("/employees/:id/annotations", annHandler.ListHandler)
+	a.Echo.GET("/employees/:id/salaries", salaryHandler.HistoryHandler)
+	a.Echo.GET("/employees/:id/salaries/current", salaryHandler.CurrentHandler)
+	a.Echo.POST("/employees/:id/salaries", salaryHandler.AddHandler)
+	a.Echo.PUT("/employees/:id/salaries/:from_date", salaryHandler.UpdateHandler)
+	a.Echo.DELETE("/employees/:id/salaries/:from_date", salaryHandler.DeleteHandler)
+	a.Echo.PUT("/annotations/:id/status", annHandler.ReviewHandler)
+
+	attendanceGroup := a.Echo.Group("/attendance")
+	attendanceGroup.POST("/check-in", attHandler.CheckInHandler)
+	attendanceGroup.POST("/check-out", attHandler.CheckOutHandler)
+	attendanceGroup.GET("", attHandler.ListAttendanceHandler)
+	attendanceGroup.POST("/timesheet/import", attHandler.ImportTimesheetHandler, importLimit)
+
+	a.Echo.POST("/leaves", attHandler.CreateLeaveHandler)
+	a.Echo.GET("/leaves", attHandler.ListLeavesHandler)
+	a.Echo.PUT("/leaves/:id/status", attHandler.ReviewLeaveHandler)
 
 	// Product merge routes (sequential + concurrent)
 	a.Echo.GET("/products/details-merged", productMergeHandler.GetAllProductsWithDetailsMerged)
 	a.Echo.GET("/products/details-concurrent", productMergeHandler.GetAllProductsWithDetailsConcurrent)
-
-	exportGroup := a.Echo.Group("/export")
-	exportGroup.GET("/fluent", empHandler.ExportFluentConfigHandler)
-	exportGroup.GET("/yaml", empHandler.ExportFromYAMLHandler)
-
-	exportGroupV2 := a.Echo.Group("/export/v2")
-	exportGroupV2.GET("/fluent", empHandler.ExportFluentConfigHandler)
-	exportGroupV2.GET("/yaml", empHandler.ExportV2FromYAMLHandler)
-	exportGroupV2.GET("/largedata", empHandler.ExportLargeDataHandler)
-	exportGroupV2.GET("/perf", empHandler.ExportLargeColumnHandler)
+	a.Echo.GET("/graphql", productGraphQLHandler.QueryHandler)
+	a.Echo.POST("/graphql", productGraphQLHandler.QueryHandler)
+	a.Echo.GET("/graphql/schema", productGraphQLHandler.SchemaHandler)
+
+	reportGroup := a.Echo.Group("/reports")
+	reportGroup.POST("/generate", reportHandler.GenerateHandler, reportsRead, reportLimit)
+	reportGroup.GET("/:id/variables", reportHandler.VariablesHandler, reportsRead)
+	reportGroup.POST("/templates/validate", reportHandler.ValidateTemplateHandler, reportsRead)
+	reportGroup.POST("/plans/run", planHandler.RunHandler, reportsRun, reportLimit)
+	reportGroup.GET("/plans/runs/:id/deliveries", planHandler.DeliveriesHandler, reportsRead)
+
+	deptGroup := a.Echo.Group("/departments")
+	deptGroup.GET("", deptHandler.ListHandler)
+	deptGroup.POST("", deptHandler.CreateHandler)
+	deptGroup.GET("/:id", deptHandler.GetHandler)
+	deptGroup.PUT("/:id", deptHandler.UpdateHandler)
+	deptGroup.DELETE("/:id", deptHandler.DeleteHandler)
+	deptGroup.POST("/:id/restore", deptHandler.RestoreHandler)
+	deptGroup.GET("/:id/managers", deptHandler.ListManagersHandler)
+	deptGroup.POST("/:id/managers", deptHandler.AssignManagerHandler)
+	deptGroup.PUT("/:id/managers/:emp_no/terminate", deptHandler.TerminateManagerHandler)
+	deptGroup.DELETE("/:id/managers/:emp_no", deptHandler.DeleteManagerHandler)
+	deptGroup.GET("/:id/orgchart.xlsx", reportHandler.DeptOrgChartHandler, reportsRead, reportLimit)
+
+	// Admin only, disabled without ADHOC_QUERY_ADMIN_TOKEN
+	adminGroup := a.Echo.Group("/admin", handler.RequireAdminToken(config.DefaultEnvConfig.ADHOC_QUERY_ADMIN_TOKEN))
+	adminGroup.POST("/query/export", adhocHandler.ExportHandler)
+	adminGroup.POST("/api-keys", apiKeyHandler.CreateHandler)
+	adminGroup.GET("/api-keys", apiKeyHandler.ListHandler)
+	adminGroup.DELETE("/api-keys/:id", apiKeyHandler.RevokeHandler)
+	adminGroup.POST("/reload", reloadHandler.ReloadHandler)
+
+	// The configuration, secrets redacted, for admins
+	configHandler := handler.NewConfigHandler(config.DefaultEnvConfig.Settings())
+	a.Echo.GET("/debug/config", configHandler.DumpHandler, handler.RequireAdminToken(config.DefaultEnvConfig.ADHOC_QUERY_ADMIN_TOKEN))
+
+	docsHandler := handler.NewOpenAPIHandler(handler.APIDocs())
+	a.Echo.GET("/openapi.json", docsHandler.SpecHandler)
+	a.Echo.GET("/docs", docsHandler.SwaggerUIHandler)
+
+	if a.MetricsRegistry != nil {
+		a.Echo.GET("/metrics", echo.WrapHandler(promhttp.HandlerFor(a.MetricsRegistry, promhttp.HandlerOpts{})))
+	}
 
 	compGroup := a.Echo.Group("/comparison")
 	compGroup.GET("/wiki/tpl", compHandler.ExportWikiTPL)
@@ -245,15 +477,131 @@ func (a *App) RegisterRoutes(empHandler *handler.EmployeeHandler, compHandler *h
 	}
 }
 
-func (a *App) Run() error {
-	defer a.DB.Close()
-	if a.GCP != nil {
-		defer a.GCP.Close()
+// Run serves HTTP, and gRPC when GRPC_PORT is set, until ctx is done or a
+// server fails, then shuts the app down, see Shutdown.
+func (a *App) Run(ctx context.Context) error {
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	defer stopWatch()
+	if interval := config.DefaultEnvConfig.RELOAD_WATCH_INTERVAL; interval > 0 {
+		paths := []string{config.DefaultEnvConfig.REPORT_TEMPLATE_DIR}
+		if config.DefaultEnvConfig.CONFIG_FILE != "" {
+			paths = append(paths, config.DefaultEnvConfig.CONFIG_FILE)
+		}
+		go a.reloader.watch(watchCtx, interval, paths)
+	}
+	if a.replica != nil {
+		go a.replica.Monitor(watchCtx, config.DefaultEnvConfig.DB_REPLICA_CHECK_INTERVAL)
 	}
+
+	errc := make(chan error, 2)
+	if a.GRPC != nil {
+		lis, err := net.Listen("tcp", ":"+config.DefaultEnvConfig.GRPC_PORT)
+		if err != nil {
+			return errors.Join(fmt.Errorf("failed to listen for gRPC: %w", err), a.Shutdown(0))
+		}
+		go func() {
+			if err := a.GRPC.Serve(lis); err != nil {
+				errc <- fmt.Errorf("gRPC server stopped: %w", err)
+			}
+		}()
+	}
+	go func() {
+		if err := a.Echo.Start(":" + config.DefaultEnvConfig.APP_PORT); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errc <- err
+		}
+	}()
+
+	var err error
+	select {
+	case <-ctx.Done():
+		logger.InfoLog(context.Background(), "Shutting down, waiting up to %s for running requests", config.DefaultEnvConfig.SHUTDOWN_TIMEOUT)
+	case err = <-errc:
+	}
+	stopWatch()
+	return errors.Join(err, a.Shutdown(config.DefaultEnvConfig.SHUTDOWN_TIMEOUT))
+}
+
+// Shutdown stops accepting requests and waits up to timeout for the running
+// ones, report exports and their pipeline stages included, before
+// cancelling them. It then closes the cloud clients and the database, and
+// flushes the logs.
+func (a *App) Shutdown(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var errs []error
+	grpcDone := make(chan struct{})
+	go func() {
+		defer close(grpcDone)
+		if a.GRPC != nil {
+			stopGRPC(ctx, a.GRPC)
+		}
+	}()
+	if err := a.Echo.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("requests still running after %s: %w", timeout, err))
+		// Closing the connections cancels the contexts of the requests left
+		a.Echo.Close()
+	}
+	<-grpcDone
+
 	if a.DataStoreClient != nil {
-		defer a.DataStoreClient.Close()
+		if err := a.DataStoreClient.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close datastore client: %w", err))
+		}
+	}
+	if a.GCP != nil {
+		if err := a.GCP.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close GCP client: %w", err))
+		}
+	}
+	if a.ReplicaDB != nil {
+		if err := a.ReplicaDB.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close read replica: %w", err))
+		}
+	}
+	if a.DB != nil {
+		if err := a.DB.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close database: %w", err))
+		}
+	}
+	logger.InfoLog(context.Background(), "Shut down")
+	if err := logger.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to close log file: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// migrate applies the pending schema migrations of db. Instances starting
+// together wait for each other, see migrations.Migrator.
+func migrate(ctx context.Context, db *sql.DB) error {
+	migrator, err := migrations.New(db)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+	done, err := migrator.Up(ctx)
+	for _, m := range done {
+		logger.InfoLog(ctx, "Applied migration %03d_%s", m.Version, m.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to migrate database: %w", err)
+	}
+	return nil
+}
+
+// stopGRPC lets the running calls finish until ctx is done, then cancels
+// them.
+func stopGRPC(ctx context.Context, s *grpc.Server) {
+	stopped := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		s.Stop()
+		<-stopped
 	}
-	return a.Echo.Start(":" + config.DefaultEnvConfig.APP_PORT)
 }
 
 func DumpUserToGCP(ctx context.Context, dsClient *datastore.Client) error {
@@ -265,14 +613,14 @@ func DumpUserToGCP(ctx context.Context, dsClient *datastore.Client) error {
 		{ID: 3, Name: "Charlie", Email: "charlie@example.com"},
 	}
 
-	logger.InfoLog(ctx, fmt.Sprintf("[Dump] Creating %d users", len(users)))
+	logger.InfoLog(ctx, "[Dump] Creating %d users", len(users))
 	var keys []*datastore.Key
 	for range users {
 		key := datastore.IncompleteKey("User", nil)
 		keys = append(keys, key)
 	}
 
-	logger.InfoLog(ctx, fmt.Sprintf("[Dump] Calling PutMulti with %d keys", len(keys)))
+	logger.InfoLog(ctx, "[Dump] Calling PutMulti with %d keys", len(keys))
 
 	// Use a shorter timeout for the actual put operation
 	putCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
@@ -280,7 +628,7 @@ func DumpUserToGCP(ctx context.Context, dsClient *datastore.Client) error {
 
 	_, err := dsClient.PutMulti(putCtx, keys, users)
 	if err != nil {
-		logger.ErrorLog(ctx, fmt.Sprintf("[Dump] PutMulti failed: %v", err))
+		logger.ErrorLog(ctx, "[Dump] PutMulti failed: %v", err)
 		return fmt.Errorf("failed to dump users to GCP datastore: %w", err)
 	}
 
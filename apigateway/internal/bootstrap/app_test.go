@@ -0,0 +1,82 @@
+package bootstrap
+
+import (
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// serve starts a.Echo on a free port and returns its address.
+func serve(t *testing.T, a *App) string {
+	t.Helper()
+	a.Echo.HideBanner, a.Echo.HidePort = true, true
+	go a.Echo.Start("127.0.0.1:0")
+	require.Eventually(t, func() bool { return a.Echo.ListenerAddr() != nil }, time.Second, time.Millisecond)
+	return a.Echo.ListenerAddr().String()
+}
+
+func TestApp_ShutdownWaitsForRunningRequests(t *testing.T) {
+	a := NewApp()
+	started, release := make(chan struct{}), make(chan struct{})
+	a.Echo.GET("/export", func(c echo.Context) error {
+		close(started)
+		<-release
+		return c.String(http.StatusOK, "done")
+	})
+	addr := serve(t, a)
+
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/export")
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		b, _ := io.ReadAll(resp.Body)
+		body <- string(b)
+	}()
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() { shutdown <- a.Shutdown(5 * time.Second) }()
+	select {
+	case <-shutdown:
+		t.Fatal("Shutdown returned while a request was running")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	require.NoError(t, <-shutdown)
+	assert.Equal(t, "done", <-body)
+	_, err := http.Get("http://" + addr + "/export")
+	assert.Error(t, err, "no new requests are accepted")
+}
+
+func TestApp_ShutdownCancelsRequestsPastTimeout(t *testing.T) {
+	a := NewApp()
+	started, cancelled := make(chan struct{}), make(chan struct{})
+	a.Echo.GET("/export", func(c echo.Context) error {
+		close(started)
+		<-c.Request().Context().Done()
+		close(cancelled)
+		return c.Request().Context().Err()
+	})
+	addr := serve(t, a)
+	go http.Get("http://" + addr + "/export")
+	<-started
+
+	err := a.Shutdown(50 * time.Millisecond)
+
+	assert.ErrorContains(t, err, "requests still running after 50ms")
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("the running request was not cancelled")
+	}
+}
@@ -0,0 +1,144 @@
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/config"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/handler"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/logger"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+)
+
+// runtimeSettings are the settings applied without a restart.
+type runtimeSettings struct {
+	LogLevel  string
+	RateLimit handler.RateLimit
+}
+
+// loadRuntimeSettings reads the settings from the environment and the
+// config file again.
+func loadRuntimeSettings() (runtimeSettings, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return runtimeSettings{}, err
+	}
+	return runtimeSettings{
+		LogLevel: cfg.LOG_LEVEL,
+		RateLimit: handler.RateLimit{
+			PerMinute: cfg.RATE_LIMIT_REPORTS_PER_MINUTE,
+			Burst:     cfg.RATE_LIMIT_REPORTS_BURST,
+		},
+	}, nil
+}
+
+// reloader reloads the report templates and the runtime settings, on
+// POST /admin/reload or when their files change. Reloads run one at a time.
+type reloader struct {
+	reports service.ReportService
+	limiter *handler.RateLimiter
+
+	mu       sync.Mutex
+	settings runtimeSettings
+}
+
+// Reload is the handler.Reloader of the app. The templates are reloaded
+// even when the settings fail to load, their errors are in the result.
+func (r *reloader) Reload(ctx context.Context) (*domain.ReloadResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := &domain.ReloadResult{Templates: r.reports.ReloadTemplates(ctx), Settings: []string{}}
+	for _, t := range result.Templates {
+		switch {
+		case t.Error != "":
+			logger.ErrorLog(ctx, "Failed to reload the template of report %s: %s", t.ReportID, t.Error)
+		case t.Changed:
+			logger.InfoLog(ctx, "Reloaded the template of report %s, revision %s", t.ReportID, t.Revision)
+		}
+	}
+
+	settings, err := loadRuntimeSettings()
+	if err != nil {
+		return result, fmt.Errorf("failed to reload settings: %w", err)
+	}
+	if settings.LogLevel != r.settings.LogLevel {
+		if err := logger.SetLevel(settings.LogLevel); err != nil {
+			return result, err
+		}
+		result.Settings = append(result.Settings, "LOG_LEVEL")
+	}
+	if settings.RateLimit.PerMinute != r.settings.RateLimit.PerMinute {
+		result.Settings = append(result.Settings, "RATE_LIMIT_REPORTS_PER_MINUTE")
+	}
+	if settings.RateLimit.Burst != r.settings.RateLimit.Burst {
+		result.Settings = append(result.Settings, "RATE_LIMIT_REPORTS_BURST")
+	}
+	if settings.RateLimit != r.settings.RateLimit {
+		r.limiter.SetLimit(settings.RateLimit)
+	}
+	r.settings = settings
+	if len(result.Settings) > 0 {
+		logger.InfoLog(ctx, "Reloaded settings %s", strings.Join(result.Settings, ", "))
+	}
+	return result, nil
+}
+
+// watch reloads whenever the files under paths change, checking every
+// interval until ctx is done.
+func (r *reloader) watch(ctx context.Context, interval time.Duration, paths []string) {
+	last := fingerprint(paths)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		current := fingerprint(paths)
+		if current == last {
+			continue
+		}
+		last = current
+		if _, err := r.Reload(ctx); err != nil {
+			logger.ErrorLog(ctx, "Failed to reload: %v", err)
+		}
+	}
+}
+
+// fingerprint identifies the state of the files at paths, and of the files
+// in them for directories, by their sizes and modification times.
+func fingerprint(paths []string) string {
+	var b strings.Builder
+	stat := func(path string, info os.FileInfo) {
+		fmt.Fprintf(&b, "%s %d %d\n", path, info.Size(), info.ModTime().UnixNano())
+	}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			fmt.Fprintf(&b, "%s missing\n", path)
+			continue
+		}
+		stat(path, info)
+		if !info.IsDir() {
+			continue
+		}
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if info, err := e.Info(); err == nil {
+				stat(filepath.Join(path, e.Name()), info)
+			}
+		}
+	}
+	return b.String()
+}
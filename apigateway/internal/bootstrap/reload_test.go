@@ -0,0 +1,92 @@
+package bootstrap
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/handler"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const reloadTemplate = `
+sheets:
+  - name: "Report"
+    sections:
+      - id: "rows"
+        columns:
+          - field_name: "Name"
+            header: "%s"
+`
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	// Edits within the same modification time are reloaded too
+	now := time.Now()
+	require.NoError(t, os.Chtimes(path, now, now))
+}
+
+func TestReloader_Reload(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Cleanup(func() { zerolog.SetGlobalLevel(zerolog.TraceLevel) })
+	tmplPath := filepath.Join(dir, "report.yaml")
+	writeFile(t, tmplPath, "name: v1"+reloadTemplate)
+	writeFile(t, "config.yaml", "LOG_LEVEL: info\nRATE_LIMIT_REPORTS_PER_MINUTE: 30\n")
+
+	reports := service.NewReportService(nil)
+	reports.Register(service.ReportDefinition{ID: "report", TemplatePath: tmplPath})
+	settings := runtimeSettings{LogLevel: "info", RateLimit: handler.RateLimit{PerMinute: 30, Burst: 10}}
+	r := &reloader{reports: reports, limiter: handler.NewRateLimiter(settings.RateLimit), settings: settings}
+
+	first, err := r.Reload(context.Background())
+	require.NoError(t, err)
+	require.Len(t, first.Templates, 1)
+	assert.True(t, first.Templates[0].Changed)
+	assert.Empty(t, first.Settings)
+
+	writeFile(t, tmplPath, "name: v2"+reloadTemplate)
+	writeFile(t, "config.yaml", "LOG_LEVEL: debug\nRATE_LIMIT_REPORTS_PER_MINUTE: 30\n")
+	second, err := r.Reload(context.Background())
+	require.NoError(t, err)
+	assert.True(t, second.Templates[0].Changed)
+	assert.NotEqual(t, first.Templates[0].Revision, second.Templates[0].Revision)
+	assert.Equal(t, []string{"LOG_LEVEL"}, second.Settings)
+	assert.Equal(t, zerolog.DebugLevel, zerolog.GlobalLevel())
+
+	third, err := r.Reload(context.Background())
+	require.NoError(t, err)
+	assert.False(t, third.Templates[0].Changed)
+	assert.Empty(t, third.Settings)
+}
+
+func TestReloader_ReportsBrokenTemplates(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	tmplPath := filepath.Join(dir, "report.yaml")
+	writeFile(t, tmplPath, "sheets: [")
+
+	reports := service.NewReportService(nil)
+	reports.Register(service.ReportDefinition{ID: "report", TemplatePath: tmplPath})
+	r := &reloader{reports: reports, limiter: handler.NewRateLimiter(handler.RateLimit{}), settings: runtimeSettings{LogLevel: "info"}}
+
+	result, err := r.Reload(context.Background())
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.Templates[0].Error)
+	assert.Empty(t, result.Templates[0].Revision)
+}
+
+func TestFingerprint(t *testing.T) {
+	dir := t.TempDir()
+	before := fingerprint([]string{dir, filepath.Join(dir, "missing.yaml")})
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "report.yaml"), []byte("sheets: []"), 0644))
+
+	assert.NotEqual(t, before, fingerprint([]string{dir, filepath.Join(dir, "missing.yaml")}))
+}
@@ -1,7 +1,9 @@
 package config
 
 import (
-	"os"
+	"errors"
+	"fmt"
+	"sort"
 	"strconv"
 	"time"
 
@@ -10,70 +12,240 @@ import (
 
 var DefaultEnvConfig *envConfig
 
+// Profiles of APP_PROFILE, each selects its section of the config file.
+const (
+	ProfileDev   = "dev"
+	ProfileStage = "stage"
+	ProfileProd  = "prod"
+)
+
 type envConfig struct {
+	// APP_PROFILE is dev, stage or prod; CONFIG_FILE is the YAML file read
+	// under the environment, see LoadEnvConfig
+	APP_PROFILE string
+	CONFIG_FILE string
 	// database config
+	DB_DRIVER            string // "postgres" (lib/pq) or "pgx"
 	DB_HOST              string
 	DB_PORT              int
 	DB_USER              string
-	DB_PASSWORD          string
+	DB_PASSWORD          string `config:"secret"`
 	DB_NAME              string
 	DB_SSL_MODE          string
 	DB_CONN_MAX_LIFETIME time.Duration
 	DB_MAX_IDLE_CONNS    int
 	DB_MAX_OPEN_CONNS    int
+	// DB_SLOW_QUERY_THRESHOLD logs the statements taking longer, 0 disables it
+	DB_SLOW_QUERY_THRESHOLD time.Duration
+	// DB_REPLICA_HOST is a read replica for lists and reports, empty disables
+	// it; DB_REPLICA_PORT 0 means DB_PORT. The replica is pinged every
+	// DB_REPLICA_CHECK_INTERVAL while reads fall back to the primary
+	DB_REPLICA_HOST           string
+	DB_REPLICA_PORT           int
+	DB_REPLICA_CHECK_INTERVAL time.Duration
+	// Reads failing with transient errors are tried DB_READ_RETRY_ATTEMPTS
+	// times in all, waiting DB_READ_RETRY_BACKOFF then twice as long each time
+	DB_READ_RETRY_ATTEMPTS int
+	DB_READ_RETRY_BACKOFF  time.Duration
+	// DB_MIGRATE_ON_START applies the pending schema migrations at startup,
+	// see cmd/migrate
+	DB_MIGRATE_ON_START bool
 	// logger config
 	LOG_FILE_PATH string
+	// LOG_LEVEL is the minimum level logged, LOG_FORMAT "json" or "console"
+	LOG_LEVEL  string
+	LOG_FORMAT string
+	// LOG_FILE_PATH rotates past LOG_MAX_SIZE_MB or every LOG_ROTATE_INTERVAL,
+	// rotated files are gzipped with LOG_COMPRESS and removed past
+	// LOG_MAX_AGE or LOG_MAX_BACKUPS, 0 disables each
+	LOG_MAX_SIZE_MB     int
+	LOG_ROTATE_INTERVAL time.Duration
+	LOG_MAX_AGE         time.Duration
+	LOG_MAX_BACKUPS     int
+	LOG_COMPRESS        bool
 	// app config
 	APP_PORT string
+	// GRPC_PORT serves the employee and report services over gRPC, empty disables it
+	GRPC_PORT string
+	// METRICS_ENABLED serves Prometheus metrics on /metrics
+	METRICS_ENABLED bool
 	// gcp config
 	GCP_PROJECT_ID string
+	// report config
+	REPORT_TEMPLATE_DIR string
+	// REPORT_CHECKSUM_KEY signs locked cells of editable exports, empty disables it
+	REPORT_CHECKSUM_KEY string `config:"secret"`
+	// REPORT_SCRATCH_DIR spools xlsx exports to a directory per export,
+	// empty writes them directly; REPORT_SCRATCH_QUOTA caps each in bytes
+	REPORT_SCRATCH_DIR   string
+	REPORT_SCRATCH_QUOTA int
+	// IMPORT_MAX_UPLOAD_SIZE caps the request body of workbook imports,
+	// e.g. "10M" or "512K"
+	IMPORT_MAX_UPLOAD_SIZE string
+	// ad-hoc query config, an empty ADHOC_QUERY_ADMIN_TOKEN disables the endpoint
+	ADHOC_QUERY_ADMIN_TOKEN string `config:"secret"`
+	ADHOC_QUERY_MAX_ROWS    int
+	ADHOC_QUERY_MAX_COST    int
+	ADHOC_QUERY_TIMEOUT     time.Duration
+	// ADHOC_QUERY_ROLE is the read-only database role queries run as
+	ADHOC_QUERY_ROLE string
+	// AUTH_JWT_SECRET verifies HS256 bearer tokens, empty accepts API keys only;
+	// AUTH_REQUIRED rejects report requests without credentials
+	AUTH_JWT_SECRET string `config:"secret"`
+	AUTH_REQUIRED   bool
+	// RATE_LIMIT_REPORTS_PER_MINUTE is the quota of report requests of each
+	// client, API key, token subject or IP, 0 disables it; RATE_LIMIT_REPORTS_BURST
+	// is how many of them can be made at once
+	RATE_LIMIT_REPORTS_PER_MINUTE int
+	RATE_LIMIT_REPORTS_BURST      int
+	// TRUSTED_PROXIES lists the addresses or CIDR ranges of the proxies in
+	// front of the server, comma separated. X-Forwarded-For is only read on
+	// their requests; empty takes the client IP from the connection.
+	TRUSTED_PROXIES string
+	// RELOAD_WATCH_INTERVAL polls the report templates and the config file
+	// for changes and reloads them, 0 disables it; admins can also reload
+	// on POST /admin/reload
+	RELOAD_WATCH_INTERVAL time.Duration
+	// SHUTDOWN_TIMEOUT is how long running requests get to finish once the
+	// gateway is asked to stop
+	SHUTDOWN_TIMEOUT time.Duration
+
+	// sources records where each setting came from, see Settings
+	sources map[string]string
 }
 
+// LoadEnvConfig loads DefaultEnvConfig. Each setting is read from the
+// environment, .env included, then from the APP_PROFILE section of the
+// config file, then from the rest of the file, falling back to its default.
+// CONFIG_FILE names the file, config.yaml is read when it exists. Malformed
+// values, unknown settings and values out of range fail the load.
 func LoadEnvConfig() error {
+	cfg, err := Load()
+	if err != nil {
+		return err
+	}
+	DefaultEnvConfig = cfg
+	return nil
+}
+
+// Load reads the configuration like LoadEnvConfig without installing it,
+// e.g. to reload the settings that can change at runtime. Variables already
+// in the environment are kept, so reloads pick up edits of the config file.
+func Load() (*envConfig, error) {
 	// Load .env file if it exists, but don't fail if it doesn't
 	_ = godotenv.Load()
 
-	DefaultEnvConfig = &envConfig{
-		DB_HOST:              getEnvString("DB_HOST", "localhost"),
-		DB_PORT:              getEnvInt("DB_PORT", 5432),
-		DB_USER:              getEnvString("DB_USER", "postgres"),
-		DB_PASSWORD:          getEnvString("DB_PASSWORD", "postgres"),
-		DB_NAME:              getEnvString("DB_NAME", "postgres"),
-		DB_SSL_MODE:          getEnvString("DB_SSL_MODE", "disable"),
-		DB_CONN_MAX_LIFETIME: getEnvDuration("DB_CONN_MAX_LIFETIME", 20*time.Minute),
-		DB_MAX_IDLE_CONNS:    getEnvInt("DB_MAX_IDLE_CONNS", 10),
-		DB_MAX_OPEN_CONNS:    getEnvInt("DB_MAX_OPEN_CONNS", 100),
-		LOG_FILE_PATH:        getEnvString("LOG_FILE_PATH", ""),
-		APP_PORT:             getEnvString("APP_PORT", "8080"),
-		GCP_PROJECT_ID:       getEnvString("GCP_PROJECT_ID", "demo-project"),
+	l := &loader{sources: make(map[string]string)}
+	profile := l.string("APP_PROFILE", ProfileDev)
+	path := l.string("CONFIG_FILE", "")
+	if err := l.readFile(path, profile); err != nil {
+		return nil, err
 	}
-	return nil
-}
 
-func getEnvString(key, fallback string) string {
-	if val := os.Getenv(key); val != "" {
-		return val
+	cfg := &envConfig{
+		APP_PROFILE:                   profile,
+		CONFIG_FILE:                   l.path,
+		DB_DRIVER:                     l.string("DB_DRIVER", "postgres"),
+		DB_HOST:                       l.string("DB_HOST", "localhost"),
+		DB_PORT:                       l.int("DB_PORT", 5432),
+		DB_USER:                       l.string("DB_USER", "postgres"),
+		DB_PASSWORD:                   l.string("DB_PASSWORD", "postgres"),
+		DB_NAME:                       l.string("DB_NAME", "postgres"),
+		DB_SSL_MODE:                   l.string("DB_SSL_MODE", "disable"),
+		DB_CONN_MAX_LIFETIME:          l.duration("DB_CONN_MAX_LIFETIME", 20*time.Minute),
+		DB_MAX_IDLE_CONNS:             l.int("DB_MAX_IDLE_CONNS", 10),
+		DB_MAX_OPEN_CONNS:             l.int("DB_MAX_OPEN_CONNS", 100),
+		DB_SLOW_QUERY_THRESHOLD:       l.duration("DB_SLOW_QUERY_THRESHOLD", 500*time.Millisecond),
+		DB_REPLICA_HOST:               l.string("DB_REPLICA_HOST", ""),
+		DB_REPLICA_PORT:               l.int("DB_REPLICA_PORT", 0),
+		DB_REPLICA_CHECK_INTERVAL:     l.duration("DB_REPLICA_CHECK_INTERVAL", 10*time.Second),
+		DB_READ_RETRY_ATTEMPTS:        l.int("DB_READ_RETRY_ATTEMPTS", 3),
+		DB_READ_RETRY_BACKOFF:         l.duration("DB_READ_RETRY_BACKOFF", 100*time.Millisecond),
+		DB_MIGRATE_ON_START:           l.bool("DB_MIGRATE_ON_START", false),
+		LOG_FILE_PATH:                 l.string("LOG_FILE_PATH", ""),
+		LOG_LEVEL:                     l.string("LOG_LEVEL", "info"),
+		LOG_FORMAT:                    l.string("LOG_FORMAT", "json"),
+		LOG_MAX_SIZE_MB:               l.int("LOG_MAX_SIZE_MB", 100),
+		LOG_ROTATE_INTERVAL:           l.duration("LOG_ROTATE_INTERVAL", 24*time.Hour),
+		LOG_MAX_AGE:                   l.duration("LOG_MAX_AGE", 30*24*time.Hour),
+		LOG_MAX_BACKUPS:               l.int("LOG_MAX_BACKUPS", 0),
+		LOG_COMPRESS:                  l.bool("LOG_COMPRESS", true),
+		APP_PORT:                      l.string("APP_PORT", "8080"),
+		GRPC_PORT:                     l.string("GRPC_PORT", ""),
+		METRICS_ENABLED:               l.bool("METRICS_ENABLED", true),
+		GCP_PROJECT_ID:                l.string("GCP_PROJECT_ID", "demo-project"),
+		REPORT_TEMPLATE_DIR:           l.string("REPORT_TEMPLATE_DIR", "templates"),
+		REPORT_CHECKSUM_KEY:           l.string("REPORT_CHECKSUM_KEY", ""),
+		REPORT_SCRATCH_DIR:            l.string("REPORT_SCRATCH_DIR", ""),
+		REPORT_SCRATCH_QUOTA:          l.int("REPORT_SCRATCH_QUOTA", 0),
+		IMPORT_MAX_UPLOAD_SIZE:        l.string("IMPORT_MAX_UPLOAD_SIZE", "10M"),
+		ADHOC_QUERY_ADMIN_TOKEN:       l.string("ADHOC_QUERY_ADMIN_TOKEN", ""),
+		ADHOC_QUERY_MAX_ROWS:          l.int("ADHOC_QUERY_MAX_ROWS", 100000),
+		ADHOC_QUERY_MAX_COST:          l.int("ADHOC_QUERY_MAX_COST", 10000000),
+		ADHOC_QUERY_TIMEOUT:           l.duration("ADHOC_QUERY_TIMEOUT", 30*time.Second),
+		ADHOC_QUERY_ROLE:              l.string("ADHOC_QUERY_ROLE", "adhoc_reader"),
+		AUTH_JWT_SECRET:               l.string("AUTH_JWT_SECRET", ""),
+		AUTH_REQUIRED:                 l.bool("AUTH_REQUIRED", false),
+		RATE_LIMIT_REPORTS_PER_MINUTE: l.int("RATE_LIMIT_REPORTS_PER_MINUTE", 30),
+		RATE_LIMIT_REPORTS_BURST:      l.int("RATE_LIMIT_REPORTS_BURST", 10),
+		TRUSTED_PROXIES:               l.string("TRUSTED_PROXIES", ""),
+		RELOAD_WATCH_INTERVAL:         l.duration("RELOAD_WATCH_INTERVAL", 30*time.Second),
+		SHUTDOWN_TIMEOUT:              l.duration("SHUTDOWN_TIMEOUT", 30*time.Second),
+	}
+	l.checkUnread()
+	cfg.sources = l.sources
+	if err := errors.Join(append(l.errs, cfg.validate()...)...); err != nil {
+		return nil, err
 	}
-	return fallback
+	return cfg, nil
 }
 
-func getEnvInt(key string, fallback int) int {
-	if val := os.Getenv(key); val != "" {
-		if i, err := strconv.Atoi(val); err == nil {
-			return i
+// validate checks the ranges of the settings the services don't check
+// themselves.
+func (c *envConfig) validate() []error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
 		}
 	}
-	return fallback
+	check(c.DB_DRIVER == "postgres" || c.DB_DRIVER == "pgx", "invalid DB_DRIVER %q, expected postgres or pgx", c.DB_DRIVER)
+	check(c.DB_PORT > 0 && c.DB_PORT < 1<<16, "invalid DB_PORT %d, expected a port", c.DB_PORT)
+	check(c.DB_REPLICA_PORT >= 0 && c.DB_REPLICA_PORT < 1<<16, "invalid DB_REPLICA_PORT %d, expected a port or 0", c.DB_REPLICA_PORT)
+	check(c.DB_REPLICA_HOST == "" || c.DB_REPLICA_CHECK_INTERVAL > 0, "invalid DB_REPLICA_CHECK_INTERVAL %s, expected more than 0 with a replica", c.DB_REPLICA_CHECK_INTERVAL)
+	check(validPort(c.APP_PORT), "invalid APP_PORT %q, expected a port", c.APP_PORT)
+	check(c.GRPC_PORT == "" || validPort(c.GRPC_PORT), "invalid GRPC_PORT %q, expected a port or empty", c.GRPC_PORT)
+	for name, n := range map[string]int{
+		"DB_MAX_IDLE_CONNS":             c.DB_MAX_IDLE_CONNS,
+		"DB_MAX_OPEN_CONNS":             c.DB_MAX_OPEN_CONNS,
+		"DB_READ_RETRY_ATTEMPTS":        c.DB_READ_RETRY_ATTEMPTS,
+		"LOG_MAX_SIZE_MB":               c.LOG_MAX_SIZE_MB,
+		"LOG_MAX_BACKUPS":               c.LOG_MAX_BACKUPS,
+		"REPORT_SCRATCH_QUOTA":          c.REPORT_SCRATCH_QUOTA,
+		"ADHOC_QUERY_MAX_ROWS":          c.ADHOC_QUERY_MAX_ROWS,
+		"ADHOC_QUERY_MAX_COST":          c.ADHOC_QUERY_MAX_COST,
+		"RATE_LIMIT_REPORTS_PER_MINUTE": c.RATE_LIMIT_REPORTS_PER_MINUTE,
+		"RATE_LIMIT_REPORTS_BURST":      c.RATE_LIMIT_REPORTS_BURST,
+	} {
+		check(n >= 0, "invalid %s %d, expected 0 or more", name, n)
+	}
+	for name, d := range map[string]time.Duration{
+		"DB_CONN_MAX_LIFETIME":  c.DB_CONN_MAX_LIFETIME,
+		"DB_READ_RETRY_BACKOFF": c.DB_READ_RETRY_BACKOFF,
+		"LOG_ROTATE_INTERVAL":   c.LOG_ROTATE_INTERVAL,
+		"LOG_MAX_AGE":           c.LOG_MAX_AGE,
+		"ADHOC_QUERY_TIMEOUT":   c.ADHOC_QUERY_TIMEOUT,
+		"RELOAD_WATCH_INTERVAL": c.RELOAD_WATCH_INTERVAL,
+		"SHUTDOWN_TIMEOUT":      c.SHUTDOWN_TIMEOUT,
+	} {
+		check(d >= 0, "invalid %s %s, expected 0 or more", name, d)
+	}
+	// The maps are unordered, keep the errors stable
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errs
 }
 
-func getEnvDuration(key string, fallback time.Duration) time.Duration {
-	if val := os.Getenv(key); val != "" {
-		if d, err := time.ParseDuration(val); err == nil {
-			return d
-		}
-		if i, err := strconv.Atoi(val); err == nil {
-			return time.Duration(i) * time.Second
-		}
-	}
-	return fallback
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n < 1<<16
 }
@@ -0,0 +1,98 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeConfig writes config.yaml to a new working directory.
+func writeConfig(t *testing.T, content string) {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.yaml"), []byte(content), 0644))
+	t.Chdir(dir)
+}
+
+func settingOf(t *testing.T, name string) config.Setting {
+	t.Helper()
+	for _, s := range config.DefaultEnvConfig.Settings() {
+		if s.Name == name {
+			return s
+		}
+	}
+	t.Fatalf("no setting %s", name)
+	return config.Setting{}
+}
+
+func TestLoadEnvConfig_LayersFileProfileAndEnv(t *testing.T) {
+	writeConfig(t, `
+DB_HOST: file-host
+DB_PORT: 6543
+DB_NAME: file-db
+LOG_ROTATE_INTERVAL: 3600
+profiles:
+  prod:
+    DB_HOST: prod-host
+    AUTH_REQUIRED: true
+  stage:
+    DB_HOST: stage-host
+`)
+	t.Setenv("APP_PROFILE", config.ProfileProd)
+	t.Setenv("DB_NAME", "env-db")
+
+	require.NoError(t, config.LoadEnvConfig())
+
+	cfg := config.DefaultEnvConfig
+	assert.Equal(t, "prod-host", cfg.DB_HOST)
+	assert.Equal(t, 6543, cfg.DB_PORT)
+	assert.Equal(t, "env-db", cfg.DB_NAME)
+	assert.True(t, cfg.AUTH_REQUIRED)
+	assert.Equal(t, time.Hour, cfg.LOG_ROTATE_INTERVAL)
+	assert.Equal(t, "config.yaml", cfg.CONFIG_FILE)
+
+	assert.Equal(t, config.SourceProfile, settingOf(t, "DB_HOST").Source)
+	assert.Equal(t, config.SourceFile, settingOf(t, "DB_PORT").Source)
+	assert.Equal(t, config.SourceEnv, settingOf(t, "DB_NAME").Source)
+	assert.Equal(t, config.SourceDefault, settingOf(t, "DB_USER").Source)
+	assert.Equal(t, "1h0m0s", settingOf(t, "LOG_ROTATE_INTERVAL").Value)
+}
+
+func TestLoadEnvConfig_RejectsInvalidSettings(t *testing.T) {
+	writeConfig(t, `
+DB_PORT: not-a-port
+DB_HOTS: typo
+RATE_LIMIT_REPORTS_BURST: -1
+`)
+	t.Setenv("LOG_MAX_AGE", "forever")
+
+	err := config.LoadEnvConfig()
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `invalid DB_PORT "not-a-port" from file, expected an integer`)
+	assert.Contains(t, err.Error(), `invalid LOG_MAX_AGE "forever" from env`)
+	assert.Contains(t, err.Error(), "unknown setting DB_HOTS in config.yaml")
+	assert.Contains(t, err.Error(), "invalid RATE_LIMIT_REPORTS_BURST -1")
+}
+
+func TestLoadEnvConfig_RejectsUnknownProfile(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("APP_PROFILE", "production")
+
+	assert.ErrorContains(t, config.LoadEnvConfig(), `invalid APP_PROFILE "production"`)
+}
+
+func TestSettings_RedactsSecrets(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("DB_PASSWORD", "hunter2")
+	require.NoError(t, config.LoadEnvConfig())
+
+	assert.Equal(t, "[REDACTED]", settingOf(t, "DB_PASSWORD").Value)
+	assert.Equal(t, "", settingOf(t, "AUTH_JWT_SECRET").Value, "unset secrets show as empty")
+	assert.Equal(t, "localhost", settingOf(t, "DB_HOST").Value)
+}
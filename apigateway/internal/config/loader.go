@@ -0,0 +1,211 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Sources of the settings, see Settings.
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceProfile = "profile"
+	SourceEnv     = "env"
+)
+
+// defaultConfigFile is read when CONFIG_FILE is unset and it exists.
+const defaultConfigFile = "config.yaml"
+
+// profilesKey holds the sections of the profiles in the config file.
+const profilesKey = "profiles"
+
+// loader reads the settings from their layers, collecting the malformed
+// values instead of failing at the first one.
+type loader struct {
+	// file holds the settings of the config file with the section of the
+	// profile applied, fileSources where each came from
+	file        map[string]string
+	fileSources map[string]string
+	path        string
+
+	sources map[string]string
+	errs    []error
+}
+
+// readFile reads the config file and applies the section of profile. An
+// empty path reads defaultConfigFile if it exists.
+func (l *loader) readFile(path, profile string) error {
+	switch profile {
+	case ProfileDev, ProfileStage, ProfileProd:
+	default:
+		return fmt.Errorf("invalid APP_PROFILE %q, expected %q, %q or %q", profile, ProfileDev, ProfileStage, ProfileProd)
+	}
+	if path == "" {
+		if _, err := os.Stat(defaultConfigFile); err != nil {
+			return nil
+		}
+		path = defaultConfigFile
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	l.path = path
+	l.file = make(map[string]string)
+	l.fileSources = make(map[string]string)
+	var profiles map[string]interface{}
+	for key, value := range doc {
+		if key == profilesKey {
+			if profiles, err = section(value); err != nil {
+				return fmt.Errorf("%s in %s: %w", profilesKey, path, err)
+			}
+			continue
+		}
+		if err := l.set(key, value, SourceFile); err != nil {
+			return err
+		}
+	}
+	for name, value := range profiles {
+		switch name {
+		case ProfileDev, ProfileStage, ProfileProd:
+		default:
+			return fmt.Errorf("unknown profile %q in %s", name, path)
+		}
+		if name != profile {
+			continue
+		}
+		settings, err := section(value)
+		if err != nil {
+			return fmt.Errorf("profile %s in %s: %w", name, path, err)
+		}
+		for key, v := range settings {
+			if err := l.set(key, v, SourceProfile); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func section(value interface{}) (map[string]interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	m, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("expected a mapping")
+	}
+	return m, nil
+}
+
+// set stores a setting of the file, its value a scalar.
+func (l *loader) set(key string, value interface{}, source string) error {
+	if key == "APP_PROFILE" || key == "CONFIG_FILE" {
+		return fmt.Errorf("setting %s in %s: it selects the file, set it in the environment", key, l.path)
+	}
+	switch v := value.(type) {
+	case nil:
+		l.file[key] = ""
+	case string:
+		l.file[key] = v
+	case int, float64, bool:
+		l.file[key] = fmt.Sprint(v)
+	default:
+		return fmt.Errorf("setting %s in %s: expected a scalar, got %T", key, l.path, value)
+	}
+	l.fileSources[key] = source
+	return nil
+}
+
+// lookup returns the value of key and records its source. Empty
+// environment variables are unset, empty file settings are not.
+func (l *loader) lookup(key string) (string, bool) {
+	if v := os.Getenv(key); v != "" {
+		l.sources[key] = SourceEnv
+		return v, true
+	}
+	if v, ok := l.file[key]; ok {
+		l.sources[key] = l.fileSources[key]
+		return v, true
+	}
+	l.sources[key] = SourceDefault
+	return "", false
+}
+
+func (l *loader) invalid(key, value, expected string) {
+	l.errs = append(l.errs, fmt.Errorf("invalid %s %q from %s, expected %s", key, value, l.sources[key], expected))
+}
+
+func (l *loader) string(key, fallback string) string {
+	if val, ok := l.lookup(key); ok {
+		return val
+	}
+	return fallback
+}
+
+func (l *loader) int(key string, fallback int) int {
+	val, ok := l.lookup(key)
+	if !ok {
+		return fallback
+	}
+	i, err := strconv.Atoi(val)
+	if err != nil {
+		l.invalid(key, val, "an integer")
+		return fallback
+	}
+	return i
+}
+
+func (l *loader) bool(key string, fallback bool) bool {
+	val, ok := l.lookup(key)
+	if !ok {
+		return fallback
+	}
+	b, err := strconv.ParseBool(val)
+	if err != nil {
+		l.invalid(key, val, "true or false")
+		return fallback
+	}
+	return b
+}
+
+// duration reads a duration such as "5m", a bare integer counts seconds.
+func (l *loader) duration(key string, fallback time.Duration) time.Duration {
+	val, ok := l.lookup(key)
+	if !ok {
+		return fallback
+	}
+	if d, err := time.ParseDuration(val); err == nil {
+		return d
+	}
+	if i, err := strconv.Atoi(val); err == nil {
+		return time.Duration(i) * time.Second
+	}
+	l.invalid(key, val, `a duration such as "30s" or "5m"`)
+	return fallback
+}
+
+// checkUnread fails the settings of the file no one read, likely typos.
+func (l *loader) checkUnread() {
+	var unknown []string
+	for key := range l.file {
+		if _, ok := l.sources[key]; !ok {
+			unknown = append(unknown, key)
+		}
+	}
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		l.errs = append(l.errs, fmt.Errorf("unknown setting %s in %s", key, l.path))
+	}
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"reflect"
+	"time"
+)
+
+// redacted replaces the values of secret settings in Settings.
+const redacted = "[REDACTED]"
+
+// Setting is a loaded setting, for the /debug/config dump.
+type Setting struct {
+	Name string `json:"name"`
+	// Value is the value of the setting, redacted for secrets unless empty
+	Value interface{} `json:"value"`
+	// Source is where the value came from: default, file, profile or env
+	Source string `json:"source"`
+}
+
+// Settings lists the settings in declaration order. Secrets, tagged
+// `config:"secret"`, are redacted and durations written as strings.
+func (c *envConfig) Settings() []Setting {
+	v := reflect.ValueOf(c).Elem()
+	t := v.Type()
+	var settings []Setting
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		var value interface{} = v.Field(i).Interface()
+		switch {
+		case field.Tag.Get("config") == "secret" && !v.Field(i).IsZero():
+			value = redacted
+		case field.Type == reflect.TypeOf(time.Duration(0)):
+			value = value.(time.Duration).String()
+		}
+		settings = append(settings, Setting{Name: field.Name, Value: value, Source: c.sources[field.Name]})
+	}
+	return settings
+}
@@ -8,16 +8,32 @@ import (
 	"time"
 
 	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
-	"github.com/locvowork/employee_management_sample/apigateway/internal/repository"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/repository/builder"
 )
 
 type DataSeeder struct {
 	db              *sql.DB
 	datastoreClient *DatastoreClient
+	// seed seeds the generators, the same seed generates the same data
+	seed int64
+	// workers run the inserts concurrently
+	workers  int
+	manifest SeedManifest
 }
 
-func NewDataSeeder(db *sql.DB, dc *DatastoreClient) *DataSeeder {
-	return &DataSeeder{db: db, datastoreClient: dc}
+// NewDataSeeder returns a seeder generating its data from seed, see
+// Manifest, and inserting it with workers concurrent batches.
+func NewDataSeeder(db *sql.DB, dc *DatastoreClient, seed int64, workers int) *DataSeeder {
+	if workers <= 0 {
+		workers = 1
+	}
+	return &DataSeeder{
+		db:              db,
+		datastoreClient: dc,
+		seed:            seed,
+		workers:         workers,
+		manifest:        SeedManifest{Seed: seed, Params: map[string]int{}, Counts: map[string]int{}},
+	}
 }
 
 var (
@@ -27,51 +43,54 @@ var (
 	featureNames = []string{"High Performance", "Energy Efficient", "Noise Reduction", "Smart Control", "Eco Friendly", "AI Powered", "Cloud Connected", "IoT Enabled", "Wireless", "USB-C"}
 )
 
-// SeedData đơn giản
-func (ds *DataSeeder) SeedData(ctx context.Context, numBrands, numProductsPerBrand, numFeaturesPerCountry int) error {
-	start := time.Now()
-	fmt.Println("🚀 Seeding data...")
+// ProductData is the products SeedData inserts, their features and the
+// product infos saved to Datastore.
+type ProductData struct {
+	Products     []domain.Product
+	Features     []domain.Feature
+	ProductInfos []domain.ProductInfo
+}
 
-	rand.Seed(time.Now().UnixNano())
+// generateProductData generates the products of the seed, recording the
+// sizes and counts in the manifest.
+func (ds *DataSeeder) generateProductData(numBrands, numProductsPerBrand, numFeaturesPerCountry int) *ProductData {
+	rng := rand.New(rand.NewSource(ds.seed))
 
 	if numBrands > len(brands) {
 		numBrands = len(brands)
 	}
+	ds.manifest.Params["brands"] = numBrands
+	ds.manifest.Params["products_per_brand"] = numProductsPerBrand
+	ds.manifest.Params["features_per_country"] = numFeaturesPerCountry
 
-	// 1. Tạo Products + Features (SQL)
-	fmt.Println("📦 Creating products and features...")
-	repo := repository.NewProductRepository(ds.db)
-
-	var products []domain.Product
-	var features []domain.Feature
-
+	data := &ProductData{}
 	for b := 0; b < numBrands; b++ {
 		brand := brands[b]
 
 		for p := 1; p <= numProductsPerBrand; p++ {
-			products = append(products, domain.Product{
+			data.Products = append(data.Products, domain.Product{
 				ID:       int64(p),
 				Brand:    brand,
 				Revision: 0,
 			})
 
 			// Mỗi product có features ở 2-5 countries
-			numCountriesForProduct := rand.Intn(4) + 2
-			selectedCountries := randomSelect(countries, numCountriesForProduct)
+			numCountriesForProduct := rng.Intn(4) + 2
+			selectedCountries := randomSelect(rng, countries, numCountriesForProduct)
 
 			for _, country := range selectedCountries {
 				// Mỗi product-country có N features
-				numFeatures := rand.Intn(numFeaturesPerCountry) + 1
+				numFeatures := rng.Intn(numFeaturesPerCountry) + 1
 				if numFeatures > 10 {
 					numFeatures = 10
 				}
 
 				for i := 1; i <= numFeatures; i++ {
-					features = append(features, domain.Feature{
+					data.Features = append(data.Features, domain.Feature{
 						ID:        int64(p),
 						Brand:     brand,
 						Country:   country,
-						Content:   featureNames[rand.Intn(len(featureNames))] + fmt.Sprintf(" v%d", i),
+						Content:   featureNames[rng.Intn(len(featureNames))] + fmt.Sprintf(" v%d", i),
 						SubNumber: i,
 					})
 				}
@@ -79,81 +98,82 @@ func (ds *DataSeeder) SeedData(ctx context.Context, numBrands, numProductsPerBra
 		}
 	}
 
-	// Batch insert products
-	if err := repo.BatchCreate(ctx, products); err != nil {
-		return fmt.Errorf("failed to insert products: %w", err)
-	}
-	fmt.Printf("✅ Created %d products\n", len(products))
-
-	// Batch insert features
-	if err := ds.batchInsertFeatures(ctx, features); err != nil {
-		return fmt.Errorf("failed to insert features: %w", err)
-	}
-	fmt.Printf("✅ Created %d features\n", len(features))
-
-	// 2. Tạo ProductInfo (Datastore)
-	fmt.Println("📋 Creating product infos in Datastore...")
-	var productInfos []domain.ProductInfo
-
-	for _, product := range products {
-		numCountriesForProduct := rand.Intn(4) + 2
-		selectedCountries := randomSelect(countries, numCountriesForProduct)
+	for _, product := range data.Products {
+		numCountriesForProduct := rng.Intn(4) + 2
+		selectedCountries := randomSelect(rng, countries, numCountriesForProduct)
 
 		for _, country := range selectedCountries {
-			numProducts := rand.Intn(3) + 1
+			numProducts := rng.Intn(3) + 1
 			for i := 1; i <= numProducts; i++ {
-				productInfos = append(productInfos, domain.ProductInfo{
+				data.ProductInfos = append(data.ProductInfos, domain.ProductInfo{
 					ID:        product.ID,
 					Brand:     product.Brand,
 					Country:   country,
-					Place:     places[rand.Intn(len(places))],
-					Year:      2020 + rand.Intn(5),
+					Place:     places[rng.Intn(len(places))],
+					Year:      2020 + rng.Intn(5),
 					SubNumber: i,
 				})
 			}
 		}
 	}
 
-	if err := ds.datastoreClient.BatchSaveProductInfos(ctx, productInfos); err != nil {
-		return fmt.Errorf("failed to insert product infos: %w", err)
-	}
-	fmt.Printf("✅ Created %d product infos\n", len(productInfos))
-
-	elapsed := time.Since(start)
-	fmt.Printf("🎉 Done in %v\n", elapsed)
-	fmt.Printf("📊 Stats: %d products, %d features, %d product infos\n", len(products), len(features), len(productInfos))
+	ds.manifest.Counts["products"] = len(data.Products)
+	ds.manifest.Counts["features"] = len(data.Features)
+	ds.manifest.Counts["product_infos"] = len(data.ProductInfos)
+	return data
+}
 
-	return nil
+// PlanData generates the products SeedData would create without inserting
+// them, recording their counts in the manifest.
+func (ds *DataSeeder) PlanData(numBrands, numProductsPerBrand, numFeaturesPerCountry int) *ProductData {
+	return ds.generateProductData(numBrands, numProductsPerBrand, numFeaturesPerCountry)
 }
 
-func (ds *DataSeeder) batchInsertFeatures(ctx context.Context, feats []domain.Feature) error {
-	if len(feats) == 0 {
-		return nil
-	}
+// SeedData đơn giản
+func (ds *DataSeeder) SeedData(ctx context.Context, numBrands, numProductsPerBrand, numFeaturesPerCountry int) error {
+	start := time.Now()
+	fmt.Println("🚀 Seeding data...")
 
-	tx, err := ds.db.BeginTx(ctx, nil)
-	if err != nil {
+	data := ds.generateProductData(numBrands, numProductsPerBrand, numFeaturesPerCountry)
+	products, features, productInfos := data.Products, data.Features, data.ProductInfos
+
+	// 1. Tạo Products + Features (SQL)
+	fmt.Println("📦 Creating products and features...")
+
+	// Batch insert products, then their features
+	if err := ds.insertBatches(ctx, "products", insertTable{
+		name: "product", columns: []string{"id", "brand", "revision"},
+		conflictTarget: "(brand, id)", onConflict: "DO UPDATE SET revision = EXCLUDED.revision", rows: len(products),
+		row: func(i int) []interface{} {
+			p := products[i]
+			return []interface{}{p.ID, p.Brand, p.Revision}
+		},
+	}); err != nil {
 		return err
 	}
-	defer tx.Rollback()
-
-	stmt, err := tx.PrepareContext(ctx, `
-		INSERT INTO feature (id, brand, country, content, sub_number) 
-		VALUES ($1, $2, $3, $4, $5)
-		ON CONFLICT DO NOTHING
-	`)
-	if err != nil {
+	if err := ds.insertBatches(ctx, "features", insertTable{
+		name: "feature", columns: []string{"id", "brand", "country", "content", "sub_number"},
+		onConflict: "DO NOTHING", rows: len(features),
+		row: func(i int) []interface{} {
+			f := features[i]
+			return []interface{}{f.ID, f.Brand, f.Country, f.Content, f.SubNumber}
+		},
+	}); err != nil {
 		return err
 	}
-	defer stmt.Close()
 
-	for _, f := range feats {
-		if _, err := stmt.ExecContext(ctx, f.ID, f.Brand, f.Country, f.Content, f.SubNumber); err != nil {
-			return err
-		}
+	// 2. Tạo ProductInfo (Datastore)
+	fmt.Println("📋 Creating product infos in Datastore...")
+	if err := ds.datastoreClient.BatchSaveProductInfos(ctx, productInfos); err != nil {
+		return fmt.Errorf("failed to insert product infos: %w", err)
 	}
+	fmt.Printf("✅ Created %d product infos\n", len(productInfos))
 
-	return tx.Commit()
+	elapsed := time.Since(start)
+	fmt.Printf("🎉 Done in %v\n", elapsed)
+	fmt.Printf("📊 Stats: %d products, %d features, %d product infos\n", len(products), len(features), len(productInfos))
+
+	return nil
 }
 
 func (ds *DataSeeder) ClearData(ctx context.Context) error {
@@ -178,6 +198,65 @@ func (ds *DataSeeder) ClearData(ctx context.Context) error {
 	return nil
 }
 
+// Targets of ClearOnly.
+const (
+	ClearBrands    = "brands"
+	ClearProducts  = "products"
+	ClearFeatures  = "features"
+	ClearEmployees = "employees"
+)
+
+// ClearOnly deletes one kind of seeded data:
+//   - brands: the products of the seeded brands with their features, the
+//     products of other brands are kept
+//   - products: every product with its features
+//   - features: every feature, the products are kept
+//   - employees: the seeded employees, see ClearEmployeeData
+func (ds *DataSeeder) ClearOnly(ctx context.Context, target string) error {
+	switch target {
+	case ClearBrands:
+		return ds.clearBrands(ctx)
+	case ClearProducts:
+		return ds.ClearData(ctx)
+	case ClearFeatures:
+		fmt.Println("🗑️  Clearing features...")
+		if _, err := ds.db.ExecContext(ctx, "DELETE FROM feature"); err != nil {
+			return fmt.Errorf("failed to delete features: %w", err)
+		}
+		fmt.Println("✅ Cleared features")
+		return nil
+	case ClearEmployees:
+		return ds.ClearEmployeeData(ctx)
+	default:
+		return fmt.Errorf("unknown data to clear %q, expected %s, %s, %s or %s", target, ClearBrands, ClearProducts, ClearFeatures, ClearEmployees)
+	}
+}
+
+// clearBrands deletes the products of the seeded brands and their features.
+func (ds *DataSeeder) clearBrands(ctx context.Context) error {
+	fmt.Println("🗑️  Clearing seeded brands...")
+
+	tx, err := ds.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Features first, they reference their products
+	for _, table := range []string{"feature", "product"} {
+		query, args := builder.NewSQLBuilder().Delete(table).WhereIn("brand", brands).Build()
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("failed to delete the %ss of the seeded brands: %w", table, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	fmt.Println("✅ Cleared seeded brands")
+	return nil
+}
+
 // Presets
 type SeedPreset string
 
@@ -189,12 +268,12 @@ const (
 )
 
 // randomSelect randomly selects N items from a list
-func randomSelect(items []string, count int) []string {
+func randomSelect(rng *rand.Rand, items []string, count int) []string {
 	if count > len(items) {
 		count = len(items)
 	}
 	result := make([]string, count)
-	perm := rand.Perm(len(items))
+	perm := rng.Perm(len(items))
 	for i := 0; i < count; i++ {
 		result[i] = items[perm[i]]
 	}
@@ -6,11 +6,20 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgxpool"
+	_ "github.com/jackc/pgx/v5/stdlib"
 	_ "github.com/lib/pq"
 )
 
+// Drivers NewPostgresDB can connect with.
+const (
+	DriverPQ  = "postgres" // lib/pq, the default
+	DriverPgx = "pgx"      // pgx through its database/sql driver
+)
+
 // Config holds database configuration parameters
 type Config struct {
+	Driver          string // DriverPQ or DriverPgx, empty means DriverPQ
 	Host            string
 	Port            int
 	User            string
@@ -20,27 +29,38 @@ type Config struct {
 	MaxOpenConns    int
 	MaxIdleConns    int
 	ConnMaxLifetime time.Duration
+	// Observer, when set, times every call made through the connections
+	Observer QueryObserver
+	// SlowQueryThreshold logs the statements taking this long or longer,
+	// see SlowQueryLogger; 0 disables it
+	SlowQueryThreshold time.Duration
 }
 
 // NewPostgresDB initializes and returns a new PostgreSQL database connection
 // It configures the connection pool and verifies connectivity with a ping test
 func NewPostgresDB(ctx context.Context, cfg Config) (*sql.DB, error) {
-	// Build PostgreSQL connection string (DSN format)
-	dsn := fmt.Sprintf(
-		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		cfg.Host,
-		cfg.Port,
-		cfg.User,
-		cfg.Password,
-		cfg.DBName,
-		cfg.SSLMode,
-	)
+	driver := cfg.Driver
+	if driver == "" {
+		driver = DriverPQ
+	}
+	if driver != DriverPQ && driver != DriverPgx {
+		return nil, fmt.Errorf("unknown database driver %q", cfg.Driver)
+	}
 
 	// Open database connection
-	db, err := sql.Open("postgres", dsn)
+	db, err := sql.Open(driver, cfg.dsn())
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database connection: %w", err)
 	}
+	observe := cfg.Observer
+	if cfg.SlowQueryThreshold > 0 {
+		observe = observeAll(observe, SlowQueryLogger(cfg.SlowQueryThreshold))
+	}
+	if observe != nil {
+		if db, err = instrument(db, cfg.dsn(), observe); err != nil {
+			return nil, fmt.Errorf("failed to open database connection: %w", err)
+		}
+	}
 
 	// Configure connection pool
 	db.SetMaxOpenConns(cfg.MaxOpenConns)
@@ -55,3 +75,44 @@ func NewPostgresDB(ctx context.Context, cfg Config) (*sql.DB, error) {
 
 	return db, nil
 }
+
+// NewPgxPool initializes a native pgx connection pool, for callers that scan
+// large result sets without the database/sql overhead. The pool is sized
+// like the database/sql pool of NewPostgresDB and verified with a ping test.
+func NewPgxPool(ctx context.Context, cfg Config) (*pgxpool.Pool, error) {
+	poolConfig, err := pgxpool.ParseConfig(cfg.dsn())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse pgx pool config: %w", err)
+	}
+	if cfg.MaxOpenConns > 0 {
+		poolConfig.MaxConns = int32(cfg.MaxOpenConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		poolConfig.MaxConnLifetime = cfg.ConnMaxLifetime
+	}
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pgx pool: %w", err)
+	}
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	return pool, nil
+}
+
+// dsn builds the PostgreSQL connection string (keyword/value format), which
+// both drivers accept.
+func (cfg Config) dsn() string {
+	return fmt.Sprintf(
+		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		cfg.Host,
+		cfg.Port,
+		cfg.User,
+		cfg.Password,
+		cfg.DBName,
+		cfg.SSLMode,
+	)
+}
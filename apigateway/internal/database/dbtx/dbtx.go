@@ -0,0 +1,85 @@
+// Package dbtx shares a database transaction through the request context, so
+// a service can run several repository calls atomically. Repositories run
+// their queries on Conn, which is the transaction of the context when there
+// is one and the pool otherwise, and the reads tolerating replication lag on
+// Read, which prefers the read replica of the pool, see SetReplica.
+package dbtx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Querier is the part of *sql.DB and *sql.Tx the repositories use.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// txKey keys the transaction by its pool, so repositories of another
+// database are not handed it.
+type txKey struct{ db *sql.DB }
+
+// Manager runs functions in a transaction of db, see WithinTx.
+type Manager struct {
+	db *sql.DB
+}
+
+func NewManager(db *sql.DB) *Manager {
+	return &Manager{db: db}
+}
+
+// WithinTx runs fn in a transaction of the manager's pool.
+func (m *Manager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return WithinTx(ctx, m.db, fn)
+}
+
+// WithinTx runs fn with a context carrying a transaction of db, committing
+// it when fn returns nil and rolling it back when fn fails or panics. Calls
+// within fn join the same transaction, only the outermost one commits.
+func WithinTx(ctx context.Context, db *sql.DB, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{db}).(*sql.Tx); ok {
+		return fn(ctx)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{db}, tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Conn returns the transaction of db carried by ctx, or db itself outside
+// of WithinTx.
+func Conn(ctx context.Context, db *sql.DB) Querier {
+	if tx, ok := ctx.Value(txKey{db}).(*sql.Tx); ok {
+		return tx
+	}
+	return db
+}
+
+// Stmt returns stmt, prepared on db, bound to the transaction carried by ctx
+// if there is one.
+func Stmt(ctx context.Context, db *sql.DB, stmt *sql.Stmt) *sql.Stmt {
+	if tx, ok := ctx.Value(txKey{db}).(*sql.Tx); ok {
+		return tx.StmtContext(ctx, stmt)
+	}
+	return stmt
+}
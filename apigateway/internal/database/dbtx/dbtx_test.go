@@ -0,0 +1,140 @@
+package dbtx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// logDriver records the statements and transaction calls it receives.
+// Connecting fails while refuse is set, see setRefuse, and the next queries
+// with the errors of failures.
+type logDriver struct {
+	log      []string
+	failures []error
+
+	mu     sync.Mutex
+	refuse error
+}
+
+func (d *logDriver) Connect(context.Context) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.refuse != nil {
+		return nil, d.refuse
+	}
+	return &logConn{d: d}, nil
+}
+
+func (d *logDriver) setRefuse(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.refuse = err
+}
+
+func (d *logDriver) Driver() driver.Driver { return nil }
+
+type logConn struct{ d *logDriver }
+
+func (c *logConn) Prepare(query string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *logConn) Close() error                              { return nil }
+func (c *logConn) Begin() (driver.Tx, error) {
+	c.d.log = append(c.d.log, "begin")
+	return &logTx{d: c.d}, nil
+}
+func (c *logConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.d.log = append(c.d.log, query)
+	return driver.RowsAffected(1), nil
+}
+func (c *logConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.d.log = append(c.d.log, query)
+	if len(c.d.failures) > 0 {
+		err := c.d.failures[0]
+		c.d.failures = c.d.failures[1:]
+		return nil, err
+	}
+	return noRows{}, nil
+}
+
+type noRows struct{}
+
+func (noRows) Columns() []string              { return nil }
+func (noRows) Close() error                   { return nil }
+func (noRows) Next(dest []driver.Value) error { return io.EOF }
+
+type logTx struct{ d *logDriver }
+
+func (t *logTx) Commit() error   { t.d.log = append(t.d.log, "commit"); return nil }
+func (t *logTx) Rollback() error { t.d.log = append(t.d.log, "rollback"); return nil }
+
+func newLogDB(t *testing.T) (*sql.DB, *logDriver) {
+	d := &logDriver{}
+	db := sql.OpenDB(d)
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db, d
+}
+
+func TestWithinTx_NestedCallsShareTheTransaction(t *testing.T) {
+	db, d := newLogDB(t)
+	m := NewManager(db)
+
+	err := m.WithinTx(context.Background(), func(ctx context.Context) error {
+		if _, err := Conn(ctx, db).ExecContext(ctx, "first"); err != nil {
+			return err
+		}
+		return WithinTx(ctx, db, func(ctx context.Context) error {
+			_, err := Conn(ctx, db).ExecContext(ctx, "second")
+			return err
+		})
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"begin", "first", "second", "commit"}, d.log)
+}
+
+func TestWithinTx_RollsBackOnError(t *testing.T) {
+	db, d := newLogDB(t)
+	failure := errors.New("boom")
+
+	err := WithinTx(context.Background(), db, func(ctx context.Context) error {
+		if _, err := Conn(ctx, db).ExecContext(ctx, "first"); err != nil {
+			return err
+		}
+		return failure
+	})
+
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, []string{"begin", "first", "rollback"}, d.log)
+}
+
+func TestWithinTx_RollsBackOnPanic(t *testing.T) {
+	db, d := newLogDB(t)
+
+	assert.Panics(t, func() {
+		_ = WithinTx(context.Background(), db, func(ctx context.Context) error {
+			panic("boom")
+		})
+	})
+	assert.Equal(t, []string{"begin", "rollback"}, d.log)
+}
+
+func TestConn_IgnoresTransactionsOfOtherPools(t *testing.T) {
+	db, _ := newLogDB(t)
+	other, _ := newLogDB(t)
+
+	err := WithinTx(context.Background(), db, func(ctx context.Context) error {
+		assert.IsType(t, &sql.Tx{}, Conn(ctx, db))
+		assert.Same(t, other, Conn(ctx, other))
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Same(t, db, Conn(context.Background(), db))
+}
@@ -0,0 +1,174 @@
+package dbtx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/logger"
+)
+
+// replicas holds the read replica of each pool, see SetReplica.
+var replicas sync.Map // *sql.DB -> *Replica
+
+// Replica is a read-only copy of a pool's database. The queries repositories
+// run on Read go to it while it is up, and to the pool otherwise.
+type Replica struct {
+	db   *sql.DB
+	down atomic.Bool
+}
+
+// SetReplica routes the reads of primary to replica, see Read. A nil replica
+// sends them back to primary.
+func SetReplica(primary, replica *sql.DB) *Replica {
+	if replica == nil {
+		replicas.Delete(primary)
+		return nil
+	}
+	r := &Replica{db: replica}
+	replicas.Store(primary, r)
+	return r
+}
+
+// Available reports whether reads go to the replica.
+func (r *Replica) Available() bool {
+	return !r.down.Load()
+}
+
+// Monitor pings the replica every interval until ctx is done, taking it out
+// of rotation while the pings fail and back in once one succeeds.
+func (r *Replica) Monitor(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		pingCtx, cancel := context.WithTimeout(ctx, interval)
+		err := r.db.PingContext(pingCtx)
+		cancel()
+		switch {
+		case err != nil && ctx.Err() == nil:
+			r.markDown(ctx, err)
+		case err == nil && r.down.CompareAndSwap(true, false):
+			logger.InfoLog(ctx, "Read replica is back, routing reads to it")
+		}
+	}
+}
+
+// markDown takes the replica out of rotation until Monitor sees it back.
+func (r *Replica) markDown(ctx context.Context, err error) {
+	if r.down.CompareAndSwap(false, true) {
+		logger.WarnLog(ctx, "Read replica unavailable, routing reads to the primary: %v", err)
+	}
+}
+
+// replicaOf returns the replica reads of db in ctx go to, nil inside a
+// transaction of db, which must see its own writes, or when the replica is
+// down or there is none.
+func replicaOf(ctx context.Context, db *sql.DB) *Replica {
+	if _, ok := ctx.Value(txKey{db}).(*sql.Tx); ok {
+		return nil
+	}
+	v, ok := replicas.Load(db)
+	if !ok {
+		return nil
+	}
+	r := v.(*Replica)
+	if !r.Available() {
+		return nil
+	}
+	return r
+}
+
+// Read is Conn for the queries that tolerate replication lag, such as lists
+// and reports: outside of a transaction they run on the replica of db, and
+// on db when the replica cannot be reached, and are retried following the
+// retry policy of db, see SetRetryPolicy. Writes through it always go to db,
+// once.
+func Read(ctx context.Context, db *sql.DB) Querier {
+	q := Conn(ctx, db)
+	if r := replicaOf(ctx, db); r != nil {
+		q = &replicaQuerier{replica: r, primary: db}
+	}
+	if p, ok := retryPolicyOf(ctx, db); ok {
+		q = &retryQuerier{Querier: q, policy: p}
+	}
+	return q
+}
+
+// OnReplica reports whether Read(ctx, db) runs its queries on a replica.
+func OnReplica(ctx context.Context, db *sql.DB) bool {
+	return replicaOf(ctx, db) != nil
+}
+
+// BeginRead begins a transaction for reads on the replica of db, falling
+// back to db as Read does.
+func BeginRead(ctx context.Context, db *sql.DB, opts *sql.TxOptions) (*sql.Tx, error) {
+	r := replicaOf(ctx, db)
+	if r == nil {
+		return db.BeginTx(ctx, opts)
+	}
+	tx, err := r.db.BeginTx(ctx, opts)
+	if err != nil && unreachable(ctx, err) {
+		r.markDown(ctx, err)
+		return db.BeginTx(ctx, opts)
+	}
+	return tx, err
+}
+
+// replicaQuerier runs reads on the replica and retries those failing to
+// reach it on the primary.
+type replicaQuerier struct {
+	replica *Replica
+	primary *sql.DB
+}
+
+func (q *replicaQuerier) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return q.primary.ExecContext(ctx, query, args...)
+}
+
+func (q *replicaQuerier) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := q.replica.db.QueryContext(ctx, query, args...)
+	if err != nil && unreachable(ctx, err) {
+		q.replica.markDown(ctx, err)
+		return q.primary.QueryContext(ctx, query, args...)
+	}
+	return rows, err
+}
+
+func (q *replicaQuerier) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	row := q.replica.db.QueryRowContext(ctx, query, args...)
+	// Err holds the error of the query, not sql.ErrNoRows
+	if err := row.Err(); err != nil && unreachable(ctx, err) {
+		q.replica.markDown(ctx, err)
+		return q.primary.QueryRowContext(ctx, query, args...)
+	}
+	return row
+}
+
+func (q *replicaQuerier) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	stmt, err := q.replica.db.PrepareContext(ctx, query)
+	if err != nil && unreachable(ctx, err) {
+		q.replica.markDown(ctx, err)
+		return q.primary.PrepareContext(ctx, query)
+	}
+	return stmt, err
+}
+
+// unreachable reports whether err is a failure to reach the database rather
+// than an error of the query or the end of ctx.
+func unreachable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) || errors.As(err, &netErr)
+}
@@ -0,0 +1,97 @@
+package dbtx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// refused is the error of a replica that cannot be reached.
+var refused = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+
+func newReplica(t *testing.T) (primary *logDriver, replica *logDriver, db *sql.DB, r *Replica) {
+	t.Helper()
+	db, primary = newLogDB(t)
+	replicaDB, replica := newLogDB(t)
+	r = SetReplica(db, replicaDB)
+	t.Cleanup(func() { SetReplica(db, nil) })
+	return primary, replica, db, r
+}
+
+func TestRead_RoutesReadsToTheReplica(t *testing.T) {
+	primary, replica, db, _ := newReplica(t)
+	ctx := context.Background()
+
+	rows, err := Read(ctx, db).QueryContext(ctx, "list")
+	require.NoError(t, err)
+	rows.Close()
+	require.NoError(t, Read(ctx, db).QueryRowContext(ctx, "report").Err())
+	_, err = Read(ctx, db).ExecContext(ctx, "write")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"list", "report"}, replica.log)
+	assert.Equal(t, []string{"write"}, primary.log, "writes go to the primary")
+	assert.True(t, OnReplica(ctx, db))
+}
+
+func TestRead_TransactionsReadTheirWrites(t *testing.T) {
+	primary, replica, db, _ := newReplica(t)
+
+	err := WithinTx(context.Background(), db, func(ctx context.Context) error {
+		assert.False(t, OnReplica(ctx, db))
+		rows, err := Read(ctx, db).QueryContext(ctx, "list")
+		if err != nil {
+			return err
+		}
+		return rows.Close()
+	})
+
+	require.NoError(t, err)
+	assert.Empty(t, replica.log)
+	assert.Equal(t, []string{"begin", "list", "commit"}, primary.log)
+}
+
+func TestRead_FallsBackToThePrimary(t *testing.T) {
+	primary, replica, db, r := newReplica(t)
+	replica.setRefuse(refused)
+	ctx := context.Background()
+
+	rows, err := Read(ctx, db).QueryContext(ctx, "list")
+	require.NoError(t, err)
+	rows.Close()
+	assert.False(t, r.Available(), "the replica is out of rotation")
+	assert.False(t, OnReplica(ctx, db))
+
+	require.NoError(t, Read(ctx, db).QueryRowContext(ctx, "report").Err())
+	assert.Equal(t, []string{"list", "report"}, primary.log)
+}
+
+func TestRead_KeepsQueryErrors(t *testing.T) {
+	_, replica, db, r := newReplica(t)
+	failure := errors.New("syntax error")
+	replica.setRefuse(failure)
+
+	_, err := Read(context.Background(), db).QueryContext(context.Background(), "list")
+
+	assert.ErrorIs(t, err, failure)
+	assert.True(t, r.Available(), "only unreachable replicas are taken out")
+}
+
+func TestReplica_MonitorBringsTheReplicaBack(t *testing.T) {
+	_, replica, db, r := newReplica(t)
+	replica.setRefuse(refused)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.Monitor(ctx, time.Millisecond)
+
+	require.Eventually(t, func() bool { return !r.Available() }, time.Second, time.Millisecond)
+	replica.setRefuse(nil)
+	require.Eventually(t, r.Available, time.Second, time.Millisecond)
+	assert.True(t, OnReplica(ctx, db))
+}
@@ -0,0 +1,145 @@
+package dbtx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/logger"
+)
+
+// retryPolicies holds the retry policy of the reads of each pool, see
+// SetRetryPolicy.
+var retryPolicies sync.Map // *sql.DB -> RetryPolicy
+
+// RetryPolicy retries the reads of a pool failing with transient errors:
+// serialization failures, deadlocks and lost connections.
+type RetryPolicy struct {
+	// Attempts is the number of tries in all, 1 or less disables retries
+	Attempts int
+	// Backoff is the wait before the first retry, doubled before each next
+	// one and jittered by up to half
+	Backoff time.Duration
+}
+
+// SetRetryPolicy retries the reads of db on Read and Retry with p.
+func SetRetryPolicy(db *sql.DB, p RetryPolicy) {
+	if p.Attempts <= 1 {
+		retryPolicies.Delete(db)
+		return
+	}
+	retryPolicies.Store(db, p)
+}
+
+// retryPolicyOf returns the retry policy of the reads of db in ctx, none
+// inside a transaction of db: a failed statement aborts it, only the whole
+// transaction could be retried.
+func retryPolicyOf(ctx context.Context, db *sql.DB) (RetryPolicy, bool) {
+	if _, ok := ctx.Value(txKey{db}).(*sql.Tx); ok {
+		return RetryPolicy{}, false
+	}
+	v, ok := retryPolicies.Load(db)
+	if !ok {
+		return RetryPolicy{}, false
+	}
+	return v.(RetryPolicy), true
+}
+
+// Retry runs fn, a read of db, again while it fails with a transient error,
+// following the retry policy of db. fn must be idempotent.
+func Retry(ctx context.Context, db *sql.DB, fn func() error) error {
+	p, ok := retryPolicyOf(ctx, db)
+	if !ok {
+		return fn()
+	}
+	return p.retry(ctx, fn)
+}
+
+func (p RetryPolicy) retry(ctx context.Context, fn func() error) error {
+	wait := p.Backoff
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.Attempts || !transient(ctx, err) {
+			return err
+		}
+		logger.WarnLog(ctx, "Retrying read after transient error (attempt %d of %d): %v", attempt+1, p.Attempts, err)
+		timer := time.NewTimer(jitter(wait))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		wait *= 2
+	}
+}
+
+// jitter returns d less up to half of it, so the clients failing together
+// do not retry together.
+func jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	return d - time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// SQLSTATEs of the errors a retry may clear.
+const (
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+)
+
+// transient reports whether a retry of the statement failing with err may
+// succeed. Both lib/pq and pgx errors have SQLState.
+func transient(ctx context.Context, err error) bool {
+	if unreachable(ctx, err) {
+		return true
+	}
+	if ctx.Err() != nil {
+		return false
+	}
+	var state interface{ SQLState() string }
+	if errors.As(err, &state) {
+		code := state.SQLState()
+		return code == sqlStateSerializationFailure || code == sqlStateDeadlockDetected
+	}
+	// The server closing the connection mid-reply
+	return errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// retryQuerier retries the reads of its Querier, writes are not.
+type retryQuerier struct {
+	Querier
+	policy RetryPolicy
+}
+
+func (q *retryQuerier) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := q.policy.retry(ctx, func() (err error) {
+		rows, err = q.Querier.QueryContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
+
+func (q *retryQuerier) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	var row *sql.Row
+	q.policy.retry(ctx, func() error {
+		row = q.Querier.QueryRowContext(ctx, query, args...)
+		return row.Err()
+	})
+	return row
+}
+
+func (q *retryQuerier) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	var stmt *sql.Stmt
+	err := q.policy.retry(ctx, func() (err error) {
+		stmt, err = q.Querier.PrepareContext(ctx, query)
+		return err
+	})
+	return stmt, err
+}
@@ -0,0 +1,92 @@
+package dbtx
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stateError is a server error with its SQLSTATE, as lib/pq and pgx report
+// them.
+type stateError string
+
+func (e stateError) Error() string    { return "SQLSTATE " + string(e) }
+func (e stateError) SQLState() string { return string(e) }
+
+func newRetryDB(t *testing.T, attempts int) (*sql.DB, *logDriver) {
+	t.Helper()
+	db, d := newLogDB(t)
+	SetRetryPolicy(db, RetryPolicy{Attempts: attempts, Backoff: time.Millisecond})
+	t.Cleanup(func() { SetRetryPolicy(db, RetryPolicy{}) })
+	return db, d
+}
+
+func TestRead_RetriesTransientErrors(t *testing.T) {
+	db, d := newRetryDB(t, 3)
+	d.failures = []error{stateError(sqlStateSerializationFailure), stateError(sqlStateDeadlockDetected)}
+	ctx := context.Background()
+
+	rows, err := Read(ctx, db).QueryContext(ctx, "list")
+
+	require.NoError(t, err)
+	rows.Close()
+	assert.Equal(t, []string{"list", "list", "list"}, d.log)
+}
+
+func TestRead_GivesUpAfterTheLastAttempt(t *testing.T) {
+	db, d := newRetryDB(t, 2)
+	d.failures = []error{stateError(sqlStateSerializationFailure), stateError(sqlStateSerializationFailure)}
+	ctx := context.Background()
+
+	err := Read(ctx, db).QueryRowContext(ctx, "report").Err()
+
+	assert.Equal(t, stateError(sqlStateSerializationFailure), err)
+	assert.Equal(t, []string{"report", "report"}, d.log)
+}
+
+func TestRead_DoesNotRetryQueryErrors(t *testing.T) {
+	db, d := newRetryDB(t, 3)
+	syntax := stateError("42601")
+	d.failures = []error{syntax}
+	ctx := context.Background()
+
+	_, err := Read(ctx, db).QueryContext(ctx, "list")
+
+	assert.Equal(t, syntax, err)
+	assert.Equal(t, []string{"list"}, d.log)
+}
+
+func TestRead_DoesNotRetryInTransactions(t *testing.T) {
+	db, d := newRetryDB(t, 3)
+	d.failures = []error{stateError(sqlStateSerializationFailure)}
+
+	err := WithinTx(context.Background(), db, func(ctx context.Context) error {
+		_, err := Read(ctx, db).QueryContext(ctx, "list")
+		return err
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, []string{"begin", "list", "rollback"}, d.log)
+}
+
+func TestRetry_StopsWhenTheContextIsDone(t *testing.T) {
+	db, _ := newLogDB(t)
+	SetRetryPolicy(db, RetryPolicy{Attempts: 5, Backoff: time.Hour})
+	t.Cleanup(func() { SetRetryPolicy(db, RetryPolicy{}) })
+	ctx, cancel := context.WithCancel(context.Background())
+	failure := stateError(sqlStateDeadlockDetected)
+
+	calls := 0
+	err := Retry(ctx, db, func() error {
+		calls++
+		time.AfterFunc(time.Millisecond, cancel)
+		return failure
+	})
+
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, 1, calls)
+}
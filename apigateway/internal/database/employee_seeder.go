@@ -0,0 +1,375 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+)
+
+// seedUser is the created_by of the seeded employees, ClearEmployeeData
+// deletes them by it.
+const seedUser = "seeder"
+
+// seedDepartment is a department the seeder can create, with its share of
+// the employees.
+type seedDepartment struct {
+	domain.Department
+	weight float64
+	// baseSalary is the median starting salary
+	baseSalary float64
+}
+
+// seedDepartments are the departments of the employees sample database,
+// largest first.
+var seedDepartments = []seedDepartment{
+	{domain.Department{DeptNo: "d005", DeptName: "Development"}, 0.26, 62000},
+	{domain.Department{DeptNo: "d004", DeptName: "Production"}, 0.22, 48000},
+	{domain.Department{DeptNo: "d007", DeptName: "Sales"}, 0.15, 70000},
+	{domain.Department{DeptNo: "d009", DeptName: "Customer Service"}, 0.08, 45000},
+	{domain.Department{DeptNo: "d006", DeptName: "Quality Management"}, 0.07, 52000},
+	{domain.Department{DeptNo: "d008", DeptName: "Research"}, 0.07, 66000},
+	{domain.Department{DeptNo: "d001", DeptName: "Marketing"}, 0.06, 60000},
+	{domain.Department{DeptNo: "d003", DeptName: "Human Resources"}, 0.05, 50000},
+	{domain.Department{DeptNo: "d002", DeptName: "Finance"}, 0.04, 64000},
+}
+
+var (
+	maleFirstNames   = []string{"Georgi", "Bezalel", "Parto", "Kyoichi", "Tzvetan", "Sumant", "Patricio", "Eberhardt", "Guoxiang", "Kazuhito", "Cristinel", "Ramzi", "Shahaf", "Bojan", "Prasadram", "Yongqiao", "Domenick", "Otmar", "Elvis", "Minh", "Khoa", "Tuan", "Hung", "Luis", "Marco"}
+	femaleFirstNames = []string{"Anneke", "Saniya", "Duangkaew", "Mary", "Lillian", "Mayuko", "Suzette", "Kendra", "Amabile", "Sailaja", "Hilari", "Jungsoon", "Yinghua", "Weiyi", "Magy", "Mona", "Lan", "Thu", "Huong", "Linh", "Sofia", "Elena", "Aiko", "Priya", "Clara"}
+	lastNames        = []string{"Facello", "Simmel", "Bamford", "Koblick", "Maliniak", "Preusig", "Zielinski", "Kalloufi", "Peac", "Piveteau", "Sluis", "Bridgland", "Terkki", "Genin", "Nooteboom", "Cappelletti", "Bouloucos", "Peha", "Haddadi", "Warwick", "Erde", "Famili", "Montemayor", "Pettey", "Heyers", "Berztiss", "Reistad", "Tempesti", "Herbst", "Demeyer", "Nguyen", "Tran", "Le", "Pham", "Hoang"}
+)
+
+// EmployeeData is the data the seeder generates for the employees schema.
+type EmployeeData struct {
+	Departments  []domain.Department
+	Employees    []domain.Employee
+	DeptEmps     []domain.DeptEmp
+	DeptManagers []domain.DeptManager
+	Salaries     []domain.Salary
+}
+
+// GenerateEmployeeData generates numEmployees employees, ids from firstID,
+// spread over the numDepartments largest departments as of now:
+//   - hires since 1990, more of them in recent years, aged 20 to 60 and
+//     mostly in their late twenties and thirties when hired
+//   - about one in ten moved to another department and one in eight left
+//   - salaries starting around the median of the department, log-normally
+//     spread, raised every year by 0 to 8%
+//   - one to three successive managers per department, the last current
+func GenerateEmployeeData(rng *rand.Rand, numEmployees, numDepartments, firstID int, now time.Time) *EmployeeData {
+	if numDepartments <= 0 || numDepartments > len(seedDepartments) {
+		numDepartments = len(seedDepartments)
+	}
+	depts := seedDepartments[:numDepartments]
+	now = date(now)
+	data := &EmployeeData{}
+	for _, d := range depts {
+		data.Departments = append(data.Departments, d.Department)
+	}
+
+	byDept := make(map[string][]domain.DeptEmp)
+	for i := 0; i < numEmployees; i++ {
+		hired := hireDate(rng, now)
+		e := domain.Employee{
+			ID:        firstID + i,
+			BirthDate: date(hired.AddDate(-ageAtHire(rng), 0, -rng.Intn(365))),
+			LastName:  lastNames[rng.Intn(len(lastNames))],
+			Gender:    "M",
+			HireDate:  hired,
+		}
+		if rng.Float64() < 0.4 {
+			e.Gender = "F"
+			e.FirstName = femaleFirstNames[rng.Intn(len(femaleFirstNames))]
+		} else {
+			e.FirstName = maleFirstNames[rng.Intn(len(maleFirstNames))]
+		}
+		data.Employees = append(data.Employees, e)
+
+		// The day after the last one, OpenEndDate while employed
+		end := domain.OpenEndDate
+		if left := hired.AddDate(0, 6, 0); left.Before(now) && rng.Float64() < 0.125 {
+			end = between(rng, left, now)
+		}
+
+		dept := pickDepartment(rng, depts)
+		from := hired
+		if len(depts) > 1 && rng.Float64() < 0.1 {
+			if moved := between(rng, hired.AddDate(1, 0, 0), minDate(end, now)); moved.After(hired) && moved.Before(end) && !moved.After(now) {
+				first := domain.DeptEmp{EmpNo: e.ID, DeptNo: dept.DeptNo, FromDate: hired, ToDate: moved}
+				data.DeptEmps = append(data.DeptEmps, first)
+				byDept[dept.DeptNo] = append(byDept[dept.DeptNo], first)
+				// dept_emp is keyed by employee and department, move elsewhere
+				next := pickDepartment(rng, depts)
+				for next.DeptNo == dept.DeptNo {
+					next = pickDepartment(rng, depts)
+				}
+				dept, from = next, moved
+			}
+		}
+		de := domain.DeptEmp{EmpNo: e.ID, DeptNo: dept.DeptNo, FromDate: from, ToDate: end}
+		data.DeptEmps = append(data.DeptEmps, de)
+		byDept[dept.DeptNo] = append(byDept[dept.DeptNo], de)
+
+		data.Salaries = append(data.Salaries, salaryHistory(rng, e.ID, dept.baseSalary, hired, end, now)...)
+	}
+
+	for _, d := range depts {
+		data.DeptManagers = append(data.DeptManagers, managerHistory(rng, d.DeptNo, byDept[d.DeptNo], now)...)
+	}
+	return data
+}
+
+// hireDate returns a hire date since 1990, skewed towards now as the company
+// grew.
+func hireDate(rng *rand.Rand, now time.Time) time.Time {
+	first := time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+	span := now.Sub(first)
+	return date(first.Add(time.Duration(math.Sqrt(rng.Float64()) * float64(span))))
+}
+
+// ageAtHire returns an age from 20 to 60, the lesser of two uniform draws,
+// so most hires are in their late twenties and thirties.
+func ageAtHire(rng *rand.Rand) int {
+	return 20 + min(rng.Intn(41), rng.Intn(41))
+}
+
+// pickDepartment picks a department by its weight.
+func pickDepartment(rng *rand.Rand, depts []seedDepartment) seedDepartment {
+	var total float64
+	for _, d := range depts {
+		total += d.weight
+	}
+	r := rng.Float64() * total
+	for _, d := range depts {
+		if r < d.weight {
+			return d
+		}
+		r -= d.weight
+	}
+	return depts[len(depts)-1]
+}
+
+// salaryHistory returns the yearly salaries of an employee hired on hired
+// until end, the day after they left or OpenEndDate.
+func salaryHistory(rng *rand.Rand, empNo int, base float64, hired, end, now time.Time) []domain.Salary {
+	amount := base * math.Exp(rng.NormFloat64()*0.2)
+	var salaries []domain.Salary
+	for from := hired; from.Before(end) && !from.After(now); {
+		to := from.AddDate(1, 0, 0)
+		if to.After(now) || !to.Before(end) {
+			to = end
+		}
+		salaries = append(salaries, domain.Salary{EmployeeID: empNo, Salary: int(math.Round(amount)), FromDate: from, ToDate: to})
+		amount *= 1 + rng.Float64()*0.08
+		from = to
+	}
+	return salaries
+}
+
+// managerHistory returns one to three successive managers of a department
+// picked among its current employees, the last one current. They take turns
+// from the day the last of them joined.
+func managerHistory(rng *rand.Rand, deptNo string, staff []domain.DeptEmp, now time.Time) []domain.DeptManager {
+	var current []domain.DeptEmp
+	for _, de := range staff {
+		if de.ToDate.After(now) {
+			current = append(current, de)
+		}
+	}
+	if len(current) == 0 {
+		return nil
+	}
+	// The longest serving employees make the managers
+	sort.Slice(current, func(i, j int) bool { return current[i].FromDate.Before(current[j].FromDate) })
+	n := min(1+rng.Intn(3), len(current))
+	picked := current[:n]
+
+	managers := make([]domain.DeptManager, n)
+	from := picked[n-1].FromDate
+	for i := range managers {
+		to := domain.OpenEndDate
+		if i < n-1 {
+			// Each turn takes up to its share of the time left
+			to = between(rng, from.AddDate(0, 0, 1), from.Add(now.Sub(from)/time.Duration(n-i)))
+		}
+		managers[i] = domain.DeptManager{DeptNo: deptNo, EmpNo: picked[n-1-i].EmpNo, FromDate: from, ToDate: to}
+		from = to
+	}
+	return managers
+}
+
+// between returns a day from from to to, from when to is not after it.
+func between(rng *rand.Rand, from, to time.Time) time.Time {
+	days := int(to.Sub(from).Hours() / 24)
+	if days <= 0 {
+		return from
+	}
+	return date(from.AddDate(0, 0, rng.Intn(days+1)))
+}
+
+func date(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func minDate(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// GetEmployeePresetConfig returns the number of employees and departments of
+// a preset.
+func GetEmployeePresetConfig(preset SeedPreset) (numEmployees, numDepartments int) {
+	switch preset {
+	case PresetSmall:
+		return 100, 4
+	case PresetMedium:
+		return 1000, 6
+	case PresetLarge:
+		return 10000, 9
+	case PresetXLarge:
+		return 100000, 9
+	default:
+		return 1000, 6
+	}
+}
+
+// generateEmployeeData generates the employees of the seed, numbered after
+// the last employee of the database, recording the sizes and counts in the
+// manifest.
+func (ds *DataSeeder) generateEmployeeData(ctx context.Context, numEmployees, numDepartments int, asOf time.Time) (*EmployeeData, error) {
+	var maxID int
+	if err := ds.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 10000) FROM employees.employee").Scan(&maxID); err != nil {
+		return nil, fmt.Errorf("failed to find the last employee id: %w", err)
+	}
+	// The products draw from their own generator, so seeding them first does
+	// not change the employees
+	rng := rand.New(rand.NewSource(ds.seed + 1))
+	data := GenerateEmployeeData(rng, numEmployees, numDepartments, maxID+1, asOf)
+
+	ds.manifest.AsOf = date(asOf).Format("2006-01-02")
+	ds.manifest.FirstEmployeeID = maxID + 1
+	ds.manifest.Params["employees"] = numEmployees
+	ds.manifest.Params["departments"] = len(data.Departments)
+	ds.manifest.Counts["departments"] = len(data.Departments)
+	ds.manifest.Counts["employees"] = len(data.Employees)
+	ds.manifest.Counts["dept_emps"] = len(data.DeptEmps)
+	ds.manifest.Counts["dept_managers"] = len(data.DeptManagers)
+	ds.manifest.Counts["salaries"] = len(data.Salaries)
+	return data, nil
+}
+
+// PlanEmployeeData generates the employees SeedEmployeeData would create
+// without inserting them, recording their counts in the manifest. It only
+// reads the database, for the first employee id.
+func (ds *DataSeeder) PlanEmployeeData(ctx context.Context, numEmployees, numDepartments int, asOf time.Time) (*EmployeeData, error) {
+	return ds.generateEmployeeData(ctx, numEmployees, numDepartments, asOf)
+}
+
+// SeedEmployeeData generates numEmployees employees over numDepartments
+// departments as of asOf, see GenerateEmployeeData, and inserts them after
+// the existing ones with concurrent batched inserts, see insertBatches.
+// Missing departments are created, existing ones kept. The same seed and
+// asOf generate the same employees.
+func (ds *DataSeeder) SeedEmployeeData(ctx context.Context, numEmployees, numDepartments int, asOf time.Time) error {
+	start := time.Now()
+	fmt.Println("👥 Seeding employees...")
+
+	data, err := ds.generateEmployeeData(ctx, numEmployees, numDepartments, asOf)
+	if err != nil {
+		return err
+	}
+
+	// Employees reference their departments, their histories the employees
+	tables := []struct {
+		label string
+		table insertTable
+	}{
+		{"departments", insertTable{
+			name: "employees.department", columns: []string{"dept_no", "dept_name", "created_by", "updated_by"},
+			onConflict: "DO NOTHING", rows: len(data.Departments),
+			row: func(i int) []interface{} {
+				d := data.Departments[i]
+				return []interface{}{d.DeptNo, d.DeptName, seedUser, seedUser}
+			},
+		}},
+		{"employees", insertTable{
+			name: "employees.employee", columns: []string{"id", "birth_date", "first_name", "last_name", "gender", "hire_date", "created_by", "updated_by"},
+			rows: len(data.Employees),
+			row: func(i int) []interface{} {
+				e := data.Employees[i]
+				return []interface{}{e.ID, e.BirthDate, e.FirstName, e.LastName, e.Gender, e.HireDate, seedUser, seedUser}
+			},
+		}},
+		{"department assignments", insertTable{
+			name: "employees.dept_emp", columns: []string{"emp_no", "dept_no", "from_date", "to_date"},
+			rows: len(data.DeptEmps),
+			row: func(i int) []interface{} {
+				de := data.DeptEmps[i]
+				return []interface{}{de.EmpNo, de.DeptNo, de.FromDate, de.ToDate}
+			},
+		}},
+		{"managers", insertTable{
+			name: "employees.dept_manager", columns: []string{"emp_no", "dept_no", "from_date", "to_date"},
+			rows: len(data.DeptManagers),
+			row: func(i int) []interface{} {
+				m := data.DeptManagers[i]
+				return []interface{}{m.EmpNo, m.DeptNo, m.FromDate, m.ToDate}
+			},
+		}},
+		{"salaries", insertTable{
+			name: "employees.salary", columns: []string{"employee_id", "salary", "from_date", "to_date"},
+			rows: len(data.Salaries),
+			row: func(i int) []interface{} {
+				s := data.Salaries[i]
+				return []interface{}{s.EmployeeID, s.Salary, s.FromDate, s.ToDate}
+			},
+		}},
+	}
+	for _, t := range tables {
+		if err := ds.insertBatches(ctx, t.label, t.table); err != nil {
+			return fmt.Errorf("%w (ClearEmployeeData removes the rows inserted)", err)
+		}
+	}
+
+	fmt.Printf("🎉 Done in %v\n", time.Since(start))
+	fmt.Printf("📊 Stats: %d departments, %d employees, %d department assignments, %d managers, %d salaries\n",
+		len(data.Departments), len(data.Employees), len(data.DeptEmps), len(data.DeptManagers), len(data.Salaries))
+	return nil
+}
+
+// ClearEmployeeData deletes the employees the seeder created with their
+// history. Departments are kept, other employees may belong to them.
+func (ds *DataSeeder) ClearEmployeeData(ctx context.Context) error {
+	fmt.Println("🗑️  Clearing seeded employees...")
+
+	tx, err := ds.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	seeded := "(SELECT id FROM employees.employee WHERE created_by = $1)"
+	for _, query := range []string{
+		"DELETE FROM employees.salary WHERE employee_id IN " + seeded,
+		"DELETE FROM employees.dept_manager WHERE emp_no IN " + seeded,
+		"DELETE FROM employees.dept_emp WHERE emp_no IN " + seeded,
+		"DELETE FROM employees.employee WHERE created_by = $1",
+	} {
+		if _, err := tx.ExecContext(ctx, query, seedUser); err != nil {
+			return fmt.Errorf("failed to clear seeded employees: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	fmt.Println("✅ Cleared seeded employees")
+	return nil
+}
@@ -0,0 +1,94 @@
+package database
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateEmployeeData_KeepsHistoriesConsistent(t *testing.T) {
+	now := time.Date(2026, 6, 15, 0, 0, 0, 0, time.UTC)
+	data := GenerateEmployeeData(rand.New(rand.NewSource(1)), 2000, 4, 500001, now)
+
+	require.Len(t, data.Departments, 4)
+	require.Len(t, data.Employees, 2000)
+	depts := make(map[string]bool)
+	for _, d := range data.Departments {
+		depts[d.DeptNo] = true
+	}
+
+	employees := make(map[int]domain.Employee)
+	women := 0
+	for i, e := range data.Employees {
+		assert.Equal(t, 500001+i, e.ID)
+		require.NoError(t, e.Validate().ErrOrNil(), "employee %d", e.ID)
+		age := e.HireDate.Year() - e.BirthDate.Year()
+		assert.True(t, age >= 20 && age <= 61, "employee %d hired at %d", e.ID, age)
+		assert.False(t, e.HireDate.After(now))
+		if e.Gender == "F" {
+			women++
+		}
+		employees[e.ID] = e
+	}
+	assert.InDelta(t, 800, women, 100)
+
+	// Each employee has contiguous department assignments from their hire
+	// date, the last one open unless they left
+	lastDept := make(map[int]domain.DeptEmp)
+	for _, de := range data.DeptEmps {
+		assert.True(t, depts[de.DeptNo], de.DeptNo)
+		assert.True(t, de.ToDate.After(de.FromDate), "emp %d", de.EmpNo)
+		if prev, ok := lastDept[de.EmpNo]; ok {
+			assert.Equal(t, prev.ToDate, de.FromDate, "emp %d", de.EmpNo)
+			assert.NotEqual(t, prev.DeptNo, de.DeptNo, "emp %d", de.EmpNo)
+		} else {
+			assert.Equal(t, employees[de.EmpNo].HireDate, de.FromDate, "emp %d", de.EmpNo)
+		}
+		lastDept[de.EmpNo] = de
+	}
+	assert.Len(t, lastDept, len(employees))
+	assert.Greater(t, len(data.DeptEmps), len(employees), "some employees moved")
+
+	// Salaries run from the hire date to the end of the last assignment
+	lastSalary := make(map[int]domain.Salary)
+	for _, s := range data.Salaries {
+		assert.True(t, s.ToDate.After(s.FromDate), "emp %d", s.EmployeeID)
+		assert.Positive(t, s.Salary)
+		if prev, ok := lastSalary[s.EmployeeID]; ok {
+			assert.Equal(t, prev.ToDate, s.FromDate, "emp %d", s.EmployeeID)
+			assert.GreaterOrEqual(t, s.Salary, prev.Salary, "emp %d", s.EmployeeID)
+		} else {
+			assert.Equal(t, employees[s.EmployeeID].HireDate, s.FromDate, "emp %d", s.EmployeeID)
+		}
+		lastSalary[s.EmployeeID] = s
+	}
+	for id, s := range lastSalary {
+		assert.Equal(t, lastDept[id].ToDate, s.ToDate, "emp %d", id)
+	}
+
+	// Every department has exactly one current manager, employed there
+	current := make(map[string]int)
+	for _, m := range data.DeptManagers {
+		require.NoError(t, m.Validate().ErrOrNil(), "manager %d of %s", m.EmpNo, m.DeptNo)
+		de := lastDept[m.EmpNo]
+		assert.Equal(t, m.DeptNo, de.DeptNo)
+		assert.False(t, m.FromDate.Before(de.FromDate), "manager %d of %s", m.EmpNo, m.DeptNo)
+		if m.IsCurrent() {
+			current[m.DeptNo]++
+		}
+	}
+	assert.Equal(t, map[string]int{"d005": 1, "d004": 1, "d007": 1, "d009": 1}, current)
+}
+
+func TestGenerateEmployeeData_IsReproducible(t *testing.T) {
+	now := time.Date(2026, 6, 15, 0, 0, 0, 0, time.UTC)
+	a := GenerateEmployeeData(rand.New(rand.NewSource(7)), 50, 0, 1, now)
+	b := GenerateEmployeeData(rand.New(rand.NewSource(7)), 50, 0, 1, now)
+
+	assert.Equal(t, a, b)
+	assert.Len(t, a.Departments, len(seedDepartments), "0 departments means all of them")
+}
@@ -0,0 +1,240 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"time"
+)
+
+// Query is a database call made through a connection of NewPostgresDB.
+type Query struct {
+	// Operation is one of "exec", "query", "prepare", "begin", "commit" or
+	// "rollback"
+	Operation string
+	// Statement and Args are empty for begin, commit and rollback
+	Statement string
+	Args      []driver.NamedValue
+	// Duration of the call, queries are timed until their first rows are
+	// ready, not until they are read
+	Duration time.Duration
+	Err      error
+}
+
+// QueryObserver receives every database call made through a connection of
+// NewPostgresDB, e.g. to export its duration as metrics. ctx is the context
+// of the call, of BeginTx for commits and rollbacks.
+type QueryObserver func(ctx context.Context, q Query)
+
+// instrument reopens db, opened with dsn, with connections reporting their
+// calls to observe. db is closed.
+func instrument(db *sql.DB, dsn string, observe QueryObserver) (*sql.DB, error) {
+	defer db.Close()
+	var connector driver.Connector = dsnConnector{dsn: dsn, drv: db.Driver()}
+	if dc, ok := db.Driver().(driver.DriverContext); ok {
+		var err error
+		if connector, err = dc.OpenConnector(dsn); err != nil {
+			return nil, err
+		}
+	}
+	return sql.OpenDB(&instrumentedConnector{Connector: connector, observe: observe}), nil
+}
+
+// dsnConnector is the connector of drivers without driver.DriverContext.
+type dsnConnector struct {
+	dsn string
+	drv driver.Driver
+}
+
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) { return c.drv.Open(c.dsn) }
+func (c dsnConnector) Driver() driver.Driver                        { return c.drv }
+
+type instrumentedConnector struct {
+	driver.Connector
+	observe QueryObserver
+}
+
+func (c *instrumentedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedConn{Conn: conn, observe: c.observe}, nil
+}
+
+// instrumentedConn times the calls of a connection. The optional driver
+// interfaces are forwarded, returning driver.ErrSkip where the driver lacks
+// them so database/sql falls back like it would without the wrapper.
+type instrumentedConn struct {
+	driver.Conn
+	observe QueryObserver
+}
+
+// timed reports q, started at start, to the observer unless the driver
+// skipped it.
+func timed(ctx context.Context, observe QueryObserver, q Query, start time.Time) {
+	if !errors.Is(q.Err, driver.ErrSkip) {
+		q.Duration = time.Since(start)
+		observe(ctx, q)
+	}
+}
+
+func (c *instrumentedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	start := time.Now()
+	var stmt driver.Stmt
+	var err error
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, query)
+	} else if err = ctx.Err(); err == nil {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	timed(ctx, c.observe, Query{Operation: "prepare", Statement: query, Err: err}, start)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedStmt{Stmt: stmt, query: query, conn: c.Conn, observe: c.observe}, nil
+}
+
+func (c *instrumentedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	start := time.Now()
+	var tx driver.Tx
+	var err error
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		tx, err = b.BeginTx(ctx, opts)
+	} else if opts.Isolation != driver.IsolationLevel(0) || opts.ReadOnly {
+		err = errors.New("database: driver does not support transaction options")
+	} else if err = ctx.Err(); err == nil {
+		tx, err = c.Conn.Begin()
+	}
+	timed(ctx, c.observe, Query{Operation: "begin", Err: err}, start)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedTx{Tx: tx, ctx: ctx, observe: c.observe}, nil
+}
+
+func (c *instrumentedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	res, err := e.ExecContext(ctx, query, args)
+	timed(ctx, c.observe, Query{Operation: "exec", Statement: query, Args: args, Err: err}, start)
+	return res, err
+}
+
+func (c *instrumentedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	start := time.Now()
+	rows, err := q.QueryContext(ctx, query, args)
+	timed(ctx, c.observe, Query{Operation: "query", Statement: query, Args: args, Err: err}, start)
+	return rows, err
+}
+
+func (c *instrumentedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *instrumentedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type instrumentedStmt struct {
+	driver.Stmt
+	query   string
+	conn    driver.Conn
+	observe QueryObserver
+}
+
+func (s *instrumentedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+	var res driver.Result
+	var err error
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		res, err = e.ExecContext(ctx, args)
+	} else if err = ctx.Err(); err == nil {
+		res, err = s.Stmt.Exec(values(args))
+	}
+	timed(ctx, s.observe, Query{Operation: "exec", Statement: s.query, Args: args, Err: err}, start)
+	return res, err
+}
+
+func (s *instrumentedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+	var rows driver.Rows
+	var err error
+	if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = q.QueryContext(ctx, args)
+	} else if err = ctx.Err(); err == nil {
+		rows, err = s.Stmt.Query(values(args))
+	}
+	timed(ctx, s.observe, Query{Operation: "query", Statement: s.query, Args: args, Err: err}, start)
+	return rows, err
+}
+
+// CheckNamedValue checks with the statement, or the connection like
+// database/sql does when the statement has no checker.
+func (s *instrumentedStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	if n, ok := s.conn.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// values drops the names of args, for statements taking positional values.
+func values(args []driver.NamedValue) []driver.Value {
+	vals := make([]driver.Value, len(args))
+	for i, a := range args {
+		vals[i] = a.Value
+	}
+	return vals
+}
+
+type instrumentedTx struct {
+	driver.Tx
+	// ctx is the context of BeginTx, commits and rollbacks have none
+	ctx     context.Context
+	observe QueryObserver
+}
+
+func (t *instrumentedTx) Commit() error {
+	start := time.Now()
+	err := t.Tx.Commit()
+	timed(t.ctx, t.observe, Query{Operation: "commit", Err: err}, start)
+	return err
+}
+
+func (t *instrumentedTx) Rollback() error {
+	start := time.Now()
+	err := t.Tx.Rollback()
+	timed(t.ctx, t.observe, Query{Operation: "rollback", Err: err}, start)
+	return err
+}
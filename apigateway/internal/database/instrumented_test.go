@@ -0,0 +1,102 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConnector hands out connections answering every query with one row,
+// failing the statements named "fail".
+type fakeConnector struct{}
+
+func (fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn{}, nil }
+func (fakeConnector) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt{query: query}, nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return fakeTx{}, nil }
+func (fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if query == "fail" {
+		return nil, errors.New("boom")
+	}
+	return driver.RowsAffected(1), nil
+}
+
+// fakeStmt only has the methods without context, like old drivers.
+type fakeStmt struct{ query string }
+
+func (s fakeStmt) Close() error  { return nil }
+func (s fakeStmt) NumInput() int { return -1 }
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(1), nil
+}
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &fakeRows{}, nil
+}
+
+type fakeRows struct{ done bool }
+
+func (r *fakeRows) Columns() []string { return []string{"n"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(1)
+	return nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+func TestInstrumentedConn_ObservesCalls(t *testing.T) {
+	var ops []string
+	var failed []string
+	var statements []string
+	db := sql.OpenDB(&instrumentedConnector{Connector: fakeConnector{}, observe: func(ctx context.Context, q Query) {
+		ops = append(ops, q.Operation)
+		statements = append(statements, q.Statement)
+		if q.Err != nil {
+			failed = append(failed, q.Operation)
+		}
+	}})
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	ctx := context.Background()
+
+	_, err := db.ExecContext(ctx, "update")
+	require.NoError(t, err)
+	_, err = db.ExecContext(ctx, "fail")
+	require.Error(t, err)
+
+	// the connection has no QueryerContext, database/sql prepares the query
+	var n int
+	require.NoError(t, db.QueryRowContext(ctx, "select $1", 1).Scan(&n))
+	assert.Equal(t, 1, n)
+
+	tx, err := db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	_, err = tx.ExecContext(ctx, "update")
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+
+	tx, err = db.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	require.NoError(t, tx.Rollback())
+
+	assert.Equal(t, []string{"exec", "exec", "prepare", "query", "begin", "exec", "commit", "begin", "rollback"}, ops)
+	assert.Equal(t, []string{"exec"}, failed)
+	assert.Equal(t, []string{"update", "fail", "select $1", "select $1", "", "update", "", "", ""}, statements)
+}
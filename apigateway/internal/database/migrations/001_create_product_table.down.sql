@@ -0,0 +1,4 @@
+-- Drops the product and feature tables, their rows included
+
+DROP TABLE IF EXISTS feature;
+DROP TABLE IF EXISTS product;
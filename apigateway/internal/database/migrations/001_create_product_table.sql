@@ -8,8 +8,8 @@ CREATE TABLE IF NOT EXISTS product (
     PRIMARY KEY (brand, id)
 );
 
-CREATE INDEX idx_product_id ON product(id);
-CREATE INDEX idx_product_brand ON product(brand);
+CREATE INDEX IF NOT EXISTS idx_product_id ON product(id);
+CREATE INDEX IF NOT EXISTS idx_product_brand ON product(brand);
 
 -- Feature table (stores features for each product-country combination)
 CREATE TABLE IF NOT EXISTS feature (
@@ -22,6 +22,6 @@ CREATE TABLE IF NOT EXISTS feature (
     FOREIGN KEY (brand, id) REFERENCES product(brand, id) ON DELETE CASCADE
 );
 
-CREATE INDEX idx_feature_product ON feature(brand, id);
-CREATE INDEX idx_feature_country ON feature(country);
-CREATE INDEX idx_feature_brand ON feature(brand);
+CREATE INDEX IF NOT EXISTS idx_feature_product ON feature(brand, id);
+CREATE INDEX IF NOT EXISTS idx_feature_country ON feature(country);
+CREATE INDEX IF NOT EXISTS idx_feature_brand ON feature(brand);
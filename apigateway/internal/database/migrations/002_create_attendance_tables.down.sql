@@ -0,0 +1,4 @@
+-- Drops the attendance and leave tables, their rows included
+
+DROP TABLE IF EXISTS employees.leave_request;
+DROP TABLE IF EXISTS employees.attendance;
@@ -0,0 +1,28 @@
+-- Attendance and leave tables for the employees schema
+
+-- Attendance table (one row per employee and day)
+CREATE TABLE IF NOT EXISTS employees.attendance (
+    emp_no INTEGER NOT NULL REFERENCES employees.employee(id) ON DELETE CASCADE,
+    work_date DATE NOT NULL,
+    check_in TIMESTAMP,
+    check_out TIMESTAMP,
+    leave_type VARCHAR(16),
+    PRIMARY KEY (emp_no, work_date),
+    CHECK (check_out IS NULL OR check_in IS NULL OR check_out >= check_in)
+);
+
+CREATE INDEX IF NOT EXISTS idx_attendance_work_date ON employees.attendance(work_date);
+
+-- Leave request table (approving a request sets leave_type on its attendance days)
+CREATE TABLE IF NOT EXISTS employees.leave_request (
+    id SERIAL PRIMARY KEY,
+    emp_no INTEGER NOT NULL REFERENCES employees.employee(id) ON DELETE CASCADE,
+    from_date DATE NOT NULL,
+    to_date DATE NOT NULL,
+    leave_type VARCHAR(16) NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    reason TEXT NOT NULL DEFAULT '',
+    CHECK (to_date >= from_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_leave_request_emp ON employees.leave_request(emp_no, from_date);
@@ -0,0 +1,3 @@
+-- Drops the delivery history, export plans then deliver every step again
+
+DROP TABLE IF EXISTS employees.report_delivery;
@@ -0,0 +1,18 @@
+-- Delivery history of export plan steps delivered to buckets
+
+CREATE TABLE IF NOT EXISTS employees.report_delivery (
+    id SERIAL PRIMARY KEY,
+    run_id VARCHAR(36) NOT NULL,
+    step_id VARCHAR(255) NOT NULL,
+    idempotency_key VARCHAR(64) NOT NULL,
+    target TEXT NOT NULL,
+    attempt INTEGER NOT NULL,
+    status VARCHAR(16) NOT NULL,
+    error TEXT NOT NULL DEFAULT '',
+    data_as_of TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_report_delivery_run ON employees.report_delivery(run_id, id);
+CREATE INDEX IF NOT EXISTS idx_report_delivery_key ON employees.report_delivery(idempotency_key) WHERE status = 'delivered';
+CREATE INDEX IF NOT EXISTS idx_report_delivery_target ON employees.report_delivery(target, data_as_of DESC) WHERE status = 'delivered';
@@ -0,0 +1,3 @@
+-- Drops the annotations of employee reports
+
+DROP TABLE IF EXISTS employees.employee_annotation;
@@ -0,0 +1,12 @@
+-- Notes and goals managers add to employee reports, reviewed before they count
+
+CREATE TABLE IF NOT EXISTS employees.employee_annotation (
+    id SERIAL PRIMARY KEY,
+    emp_no INTEGER NOT NULL REFERENCES employees.employee(id) ON DELETE CASCADE,
+    kind VARCHAR(16) NOT NULL,
+    text TEXT NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_employee_annotation_emp ON employees.employee_annotation(emp_no, id);
@@ -0,0 +1,14 @@
+-- Drops the audit columns. Soft deleted rows become visible again, delete
+-- them first to keep them gone.
+
+DROP INDEX IF EXISTS employees.idx_employee_live;
+
+ALTER TABLE employees.department
+    DROP COLUMN IF EXISTS created_by,
+    DROP COLUMN IF EXISTS updated_by,
+    DROP COLUMN IF EXISTS deleted_at;
+
+ALTER TABLE employees.employee
+    DROP COLUMN IF EXISTS created_by,
+    DROP COLUMN IF EXISTS updated_by,
+    DROP COLUMN IF EXISTS deleted_at;
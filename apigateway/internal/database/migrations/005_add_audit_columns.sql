@@ -0,0 +1,14 @@
+-- Audit columns and soft delete for employees and departments. Deleted rows
+-- keep deleted_at set and are hidden by the repositories until restored.
+
+ALTER TABLE employees.employee
+    ADD COLUMN IF NOT EXISTS created_by VARCHAR(64) NOT NULL DEFAULT 'system',
+    ADD COLUMN IF NOT EXISTS updated_by VARCHAR(64) NOT NULL DEFAULT 'system',
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+ALTER TABLE employees.department
+    ADD COLUMN IF NOT EXISTS created_by VARCHAR(64) NOT NULL DEFAULT 'system',
+    ADD COLUMN IF NOT EXISTS updated_by VARCHAR(64) NOT NULL DEFAULT 'system',
+    ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_employee_live ON employees.employee(id) WHERE deleted_at IS NULL;
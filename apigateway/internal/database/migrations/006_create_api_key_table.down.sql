@@ -0,0 +1,3 @@
+-- Drops the API keys, the jobs using them need new ones
+
+DROP TABLE IF EXISTS employees.api_key;
@@ -0,0 +1,15 @@
+-- API keys of scheduled jobs and external systems. Only the SHA-256 of the
+-- key is stored; scopes are space separated, e.g. 'reports:read reports:run'
+
+CREATE TABLE IF NOT EXISTS employees.api_key (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(16) NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    scopes TEXT NOT NULL,
+    created_by VARCHAR(64) NOT NULL DEFAULT 'system',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
@@ -0,0 +1,245 @@
+// Package migrations embeds the schema migrations and applies them. A
+// migration is NNN_name.sql, run to upgrade the schema, with an optional
+// NNN_name.down.sql to roll it back; NNN is its version. The applied
+// versions are recorded in the schema_migrations table.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// Table records the applied migrations.
+const Table = "schema_migrations"
+
+// lockKey is the advisory lock held while migrating, so instances starting
+// together do not apply the same migration twice.
+const lockKey = 7_264_121_001
+
+// downSuffix ends the file names of the down migrations.
+const downSuffix = ".down.sql"
+
+// Migration is a versioned schema change.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	// Down is empty for migrations that cannot be rolled back
+	Down string
+}
+
+// Status is a migration and when it was applied, zero if it was not.
+type Status struct {
+	Migration
+	AppliedAt time.Time
+}
+
+// Load reads the migrations of fsys, sorted by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	names, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int]*Migration)
+	for _, name := range names {
+		base := strings.TrimSuffix(name, downSuffix)
+		down := base != name
+		if !down {
+			base = strings.TrimSuffix(name, ".sql")
+		}
+		prefix, label, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration file name %s, expected NNN_name.sql", name)
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: label}
+			byVersion[version] = m
+		}
+		if m.Name != label {
+			return nil, fmt.Errorf("migration version %d is used by %s and %s", version, m.Name, label)
+		}
+		if down {
+			m.Down = string(data)
+		} else {
+			m.Up = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %03d_%s has a down migration but no %03d_%s.sql", m.Version, m.Name, m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator applies migrations to a database.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// New returns a Migrator of the embedded migrations.
+func New(db *sql.DB) (*Migrator, error) {
+	migrations, err := Load(files)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Status returns every migration with when it was applied.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var status []Status
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedAt(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			status = append(status, Status{Migration: mig, AppliedAt: applied[mig.Version]})
+		}
+		return nil
+	})
+	return status, err
+}
+
+// Up applies the migrations not applied yet, in order, each in its own
+// transaction, and returns them. It stops at the first failing one.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedAt(ctx, conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok {
+				continue
+			}
+			err := apply(ctx, conn, mig.Up, "INSERT INTO "+Table+" (version, name) VALUES ($1, $2)", mig.Version, mig.Name)
+			if err != nil {
+				return fmt.Errorf("failed to apply migration %03d_%s: %w", mig.Version, mig.Name, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Down rolls back the last steps applied migrations, latest first, and
+// returns them. Nothing is rolled back when one of them has no down
+// migration.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var done []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		applied, err := appliedAt(ctx, conn)
+		if err != nil {
+			return err
+		}
+		var todo []Migration
+		for i := len(m.migrations) - 1; i >= 0 && len(todo) < steps; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; !ok {
+				continue
+			}
+			if mig.Down == "" {
+				return fmt.Errorf("migration %03d_%s cannot be rolled back, it has no down migration", mig.Version, mig.Name)
+			}
+			todo = append(todo, mig)
+		}
+		for _, mig := range todo {
+			if err := apply(ctx, conn, mig.Down, "DELETE FROM "+Table+" WHERE version = $1", mig.Version); err != nil {
+				return fmt.Errorf("failed to roll back migration %03d_%s: %w", mig.Version, mig.Name, err)
+			}
+			done = append(done, mig)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// locked runs fn on a connection holding the migration lock, with the
+// migrations table created.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		return fmt.Errorf("failed to take the migration lock: %w", err)
+	}
+	// The lock is the session's, release it before the connection returns to the pool
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey)
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS `+Table+` (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", Table, err)
+	}
+	return fn(conn)
+}
+
+// appliedAt returns when each applied migration was applied, by version.
+func appliedAt(ctx context.Context, conn *sql.Conn) (map[int]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM "+Table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
+
+// apply runs script and the statement recording it in one transaction.
+func apply(ctx context.Context, conn *sql.Conn, script, record string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	// Without arguments both drivers run the script as one simple query,
+	// its statements included
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
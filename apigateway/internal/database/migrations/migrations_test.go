@@ -0,0 +1,223 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// schemaDriver keeps the versions of the migrations table in memory and
+// records the scripts it runs. Scripts in fail fail.
+type schemaDriver struct {
+	mu      sync.Mutex
+	applied map[int]bool
+	scripts []string
+	fail    map[string]bool
+}
+
+func (d *schemaDriver) Connect(context.Context) (driver.Conn, error) { return &schemaConn{d: d}, nil }
+func (d *schemaDriver) Driver() driver.Driver                        { return nil }
+
+type schemaConn struct {
+	d *schemaDriver
+	// pending are the changes of the running transaction to the versions
+	pending map[int]bool
+}
+
+func (c *schemaConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *schemaConn) Close() error                        { return nil }
+func (c *schemaConn) Begin() (driver.Tx, error) {
+	c.pending = make(map[int]bool)
+	return c, nil
+}
+
+func (c *schemaConn) Commit() error {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	for version, applied := range c.pending {
+		if applied {
+			c.d.applied[version] = true
+		} else {
+			delete(c.d.applied, version)
+		}
+	}
+	c.pending = nil
+	return nil
+}
+
+func (c *schemaConn) Rollback() error {
+	c.pending = nil
+	return nil
+}
+
+func (c *schemaConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	switch {
+	case strings.HasPrefix(query, "INSERT INTO "+Table):
+		c.pending[int(args[0].Value.(int64))] = true
+	case strings.HasPrefix(query, "DELETE FROM "+Table):
+		c.pending[int(args[0].Value.(int64))] = false
+	case strings.HasPrefix(query, "SELECT pg_advisory"), strings.HasPrefix(query, "CREATE TABLE IF NOT EXISTS "+Table):
+	default:
+		c.d.scripts = append(c.d.scripts, query)
+		if c.d.fail[query] {
+			return nil, errors.New("syntax error")
+		}
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *schemaConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	var versions []int
+	for version := range c.d.applied {
+		versions = append(versions, version)
+	}
+	sort.Ints(versions)
+	return &versionRows{versions: versions}, nil
+}
+
+type versionRows struct{ versions []int }
+
+func (r *versionRows) Columns() []string { return []string{"version", "applied_at"} }
+func (r *versionRows) Close() error      { return nil }
+func (r *versionRows) Next(dest []driver.Value) error {
+	if len(r.versions) == 0 {
+		return io.EOF
+	}
+	dest[0], dest[1] = int64(r.versions[0]), time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.versions = r.versions[1:]
+	return nil
+}
+
+func newMigrator(t *testing.T, migrations []Migration, applied ...int) (*Migrator, *schemaDriver) {
+	t.Helper()
+	d := &schemaDriver{applied: make(map[int]bool), fail: make(map[string]bool)}
+	for _, version := range applied {
+		d.applied[version] = true
+	}
+	db := sql.OpenDB(d)
+	t.Cleanup(func() { db.Close() })
+	return &Migrator{db: db, migrations: migrations}, d
+}
+
+var testMigrations = []Migration{
+	{Version: 1, Name: "create_a", Up: "create a", Down: "drop a"},
+	{Version: 2, Name: "create_b", Up: "create b", Down: "drop b"},
+	{Version: 3, Name: "alter_b", Up: "alter b"},
+}
+
+func versions(migrations []Migration) []int {
+	var vs []int
+	for _, m := range migrations {
+		vs = append(vs, m.Version)
+	}
+	return vs
+}
+
+func TestLoad_PairsUpAndDownFilesByVersion(t *testing.T) {
+	migrations, err := Load(fstest.MapFS{
+		"002_create_b.sql":      {Data: []byte("create b")},
+		"001_create_a.sql":      {Data: []byte("create a")},
+		"001_create_a.down.sql": {Data: []byte("drop a")},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []Migration{
+		{Version: 1, Name: "create_a", Up: "create a", Down: "drop a"},
+		{Version: 2, Name: "create_b", Up: "create b"},
+	}, migrations)
+}
+
+func TestLoad_RejectsMalformedSets(t *testing.T) {
+	for name, fsys := range map[string]fstest.MapFS{
+		"no version":      {"create_a.sql": {}},
+		"shared version":  {"001_create_a.sql": {}, "001_create_b.sql": {}},
+		"down without up": {"001_create_a.down.sql": {Data: []byte("drop a")}},
+	} {
+		_, err := Load(fsys)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestLoad_EmbeddedMigrationsCanBeRolledBack(t *testing.T) {
+	migrations, err := Load(files)
+
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	for i, m := range migrations {
+		assert.Equal(t, i+1, m.Version, "versions follow each other")
+		assert.NotEmpty(t, m.Down, "%03d_%s", m.Version, m.Name)
+	}
+}
+
+func TestMigrator_UpAppliesPendingMigrationsInOrder(t *testing.T) {
+	m, d := newMigrator(t, testMigrations, 1)
+	ctx := context.Background()
+
+	done, err := m.Up(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 3}, versions(done))
+	assert.Equal(t, []string{"create b", "alter b"}, d.scripts)
+
+	done, err = m.Up(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, done, "applied migrations are not run again")
+}
+
+func TestMigrator_UpStopsAtTheFailingMigration(t *testing.T) {
+	m, d := newMigrator(t, testMigrations)
+	d.fail["create b"] = true
+
+	done, err := m.Up(context.Background())
+
+	assert.ErrorContains(t, err, "002_create_b")
+	assert.Equal(t, []int{1}, versions(done))
+	assert.Equal(t, map[int]bool{1: true}, d.applied, "the failed migration is not recorded")
+}
+
+func TestMigrator_DownRollsBackTheLatestMigrations(t *testing.T) {
+	m, d := newMigrator(t, testMigrations[:2], 1, 2)
+
+	done, err := m.Down(context.Background(), 1)
+
+	require.NoError(t, err)
+	assert.Equal(t, []int{2}, versions(done))
+	assert.Equal(t, []string{"drop b"}, d.scripts)
+	assert.Equal(t, map[int]bool{1: true}, d.applied)
+}
+
+func TestMigrator_DownRefusesMigrationsWithoutDown(t *testing.T) {
+	m, d := newMigrator(t, testMigrations, 1, 2, 3)
+
+	_, err := m.Down(context.Background(), 2)
+
+	assert.ErrorContains(t, err, "003_alter_b")
+	assert.Empty(t, d.scripts, "nothing is rolled back")
+}
+
+func TestMigrator_Status(t *testing.T) {
+	m, _ := newMigrator(t, testMigrations, 1)
+
+	status, err := m.Status(context.Background())
+
+	require.NoError(t, err)
+	require.Len(t, status, 3)
+	assert.False(t, status[0].AppliedAt.IsZero())
+	assert.True(t, status[1].AppliedAt.IsZero())
+	assert.True(t, status[2].AppliedAt.IsZero())
+}
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/repository/builder"
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/dataflow"
+)
+
+// maxInsertParams is the number of parameters Postgres allows in a statement.
+const maxInsertParams = 65535
+
+// seedBatchRows caps the rows of one seeding INSERT.
+const seedBatchRows = 1000
+
+// progressInterval is how often the progress of an insert is printed.
+const progressInterval = 200 * time.Millisecond
+
+// insertTable describes the rows of a table to insert, see insertBatches.
+type insertTable struct {
+	name    string
+	columns []string
+	// conflictTarget and onConflict make up the ON CONFLICT clause, if any
+	conflictTarget, onConflict string
+	rows                       int
+	row                        func(i int) []interface{}
+}
+
+// insertBatches inserts the rows of t with multi-row INSERTs, run by the
+// workers of the seeder concurrently, printing the progress. Each batch
+// commits on its own: a failure leaves the batches done before it.
+func (ds *DataSeeder) insertBatches(ctx context.Context, label string, t insertTable) error {
+	batch := min(seedBatchRows, maxInsertParams/len(t.columns))
+	var starts []interface{}
+	for i := 0; i < t.rows; i += batch {
+		starts = append(starts, i)
+	}
+
+	progress := newSeedProgress(os.Stdout, label, t.rows)
+	defer progress.finish()
+
+	// The first failure stops the other workers
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var errOnce sync.Once
+	var firstErr error
+	err := dataflow.ForEach(ctx, dataflow.From(ctx, starts...), func(msg interface{}) error {
+		start := msg.(int)
+		end := min(start+batch, t.rows)
+		b := builder.NewSQLBuilder().Insert(t.name, t.columns...)
+		for i := start; i < end; i++ {
+			b.Values(t.row(i)...)
+		}
+		if t.onConflict != "" {
+			b.OnConflict(t.conflictTarget, t.onConflict)
+		}
+		query, args := b.Build()
+		if _, err := ds.db.ExecContext(ctx, query, args...); err != nil {
+			errOnce.Do(func() {
+				firstErr = fmt.Errorf("failed to insert %s: %w", label, err)
+				cancel()
+			})
+			return err
+		}
+		progress.add(end - start)
+		return nil
+	}, dataflow.WithWorkers(ds.workers))
+	if firstErr != nil {
+		return firstErr
+	}
+	return err
+}
+
+// seedProgress prints how many rows of an insert are done, on one line
+// rewritten until finish prints the rate.
+type seedProgress struct {
+	w     io.Writer
+	label string
+	total int
+	start time.Time
+	done  atomic.Int64
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+func newSeedProgress(w io.Writer, label string, total int) *seedProgress {
+	p := &seedProgress{w: w, label: label, total: total, start: time.Now(), stop: make(chan struct{}), stopped: make(chan struct{})}
+	go p.run()
+	return p
+}
+
+func (p *seedProgress) add(n int) {
+	p.done.Add(int64(n))
+}
+
+func (p *seedProgress) run() {
+	defer close(p.stopped)
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			done := p.done.Load()
+			percent := 100
+			if p.total > 0 {
+				percent = int(done * 100 / int64(p.total))
+			}
+			fmt.Fprintf(p.w, "\r⏳ %s: %d/%d (%d%%)", p.label, done, p.total, percent)
+		}
+	}
+}
+
+// finish stops the progress line and prints the rows inserted and their
+// rate.
+func (p *seedProgress) finish() {
+	close(p.stop)
+	<-p.stopped
+	elapsed := time.Since(p.start)
+	done := p.done.Load()
+	fmt.Fprintf(p.w, "\r✅ Inserted %d %s in %v (%.0f rows/s)\n", done, p.label, elapsed.Round(time.Millisecond), float64(done)/elapsed.Seconds())
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// batchDriver records the rows of the INSERTs it runs and how many ran at
+// once. The INSERTs whose first argument is fail fail.
+type batchDriver struct {
+	mu      sync.Mutex
+	rows    int
+	batches int
+	fail    interface{}
+
+	running, peak atomic.Int32
+}
+
+func (d *batchDriver) Connect(context.Context) (driver.Conn, error) { return &batchConn{d: d}, nil }
+func (d *batchDriver) Driver() driver.Driver                        { return nil }
+
+type batchConn struct{ d *batchDriver }
+
+func (c *batchConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *batchConn) Close() error                        { return nil }
+func (c *batchConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+func (c *batchConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	n := c.d.running.Add(1)
+	defer c.d.running.Add(-1)
+	for {
+		peak := c.d.peak.Load()
+		if n <= peak || c.d.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	time.Sleep(time.Millisecond)
+
+	if c.d.fail != nil && args[0].Value == c.d.fail {
+		return nil, errors.New("duplicate key")
+	}
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	c.d.batches++
+	c.d.rows += strings.Count(query, "), (") + 1
+	return driver.RowsAffected(1), nil
+}
+
+func newBatchSeeder(t *testing.T, workers int) (*DataSeeder, *batchDriver) {
+	t.Helper()
+	d := &batchDriver{}
+	db := sql.OpenDB(d)
+	t.Cleanup(func() { db.Close() })
+	return NewDataSeeder(db, nil, 1, workers), d
+}
+
+func numbers(n int) insertTable {
+	return insertTable{
+		name: "numbers", columns: []string{"n", "square"}, rows: n,
+		row: func(i int) []interface{} { return []interface{}{int64(i), int64(i * i)} },
+	}
+}
+
+func TestInsertBatches_InsertsEveryRowInConcurrentBatches(t *testing.T) {
+	ds, d := newBatchSeeder(t, 3)
+
+	require.NoError(t, ds.insertBatches(context.Background(), "numbers", numbers(2*seedBatchRows+1)))
+
+	assert.Equal(t, 2*seedBatchRows+1, d.rows)
+	assert.Equal(t, 3, d.batches)
+	assert.LessOrEqual(t, d.peak.Load(), int32(3))
+}
+
+func TestInsertBatches_ReturnsTheFirstFailure(t *testing.T) {
+	ds, d := newBatchSeeder(t, 2)
+	d.fail = int64(seedBatchRows)
+
+	err := ds.insertBatches(context.Background(), "numbers", numbers(5*seedBatchRows))
+
+	assert.ErrorContains(t, err, "failed to insert numbers: duplicate key")
+	assert.Less(t, d.rows, 5*seedBatchRows)
+}
+
+func TestSeedProgress_PrintsTheRate(t *testing.T) {
+	var buf bytes.Buffer
+	p := newSeedProgress(&buf, "employees", 10)
+	p.add(10)
+	p.finish()
+
+	assert.Contains(t, buf.String(), "✅ Inserted 10 employees in ")
+	assert.Contains(t, buf.String(), " rows/s)\n")
+}
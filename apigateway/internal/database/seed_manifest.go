@@ -0,0 +1,42 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// SeedManifest records how a dataset was seeded: the seed, the sizes asked
+// for and the rows generated. Seeding an empty database again with the same
+// seed, sizes and AsOf generates the same rows, so exporter benchmarks run on
+// different environments compare like with like.
+type SeedManifest struct {
+	Seed int64 `json:"seed"`
+	// AsOf is the date the employee histories run to, empty without employees
+	AsOf            string         `json:"as_of,omitempty"`
+	FirstEmployeeID int            `json:"first_employee_id,omitempty"`
+	Params          map[string]int `json:"params"`
+	Counts          map[string]int `json:"counts"`
+	GeneratedAt     time.Time      `json:"generated_at"`
+}
+
+// Manifest returns the manifest of the data seeded so far.
+func (ds *DataSeeder) Manifest() SeedManifest {
+	m := ds.manifest
+	m.GeneratedAt = time.Now().UTC()
+	return m
+}
+
+// WriteManifest writes the manifest of the data seeded so far to path as
+// JSON.
+func (ds *DataSeeder) WriteManifest(path string) error {
+	data, err := json.MarshalIndent(ds.Manifest(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write seed manifest: %w", err)
+	}
+	return nil
+}
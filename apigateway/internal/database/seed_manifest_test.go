@@ -0,0 +1,47 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataSeeder_WriteManifest(t *testing.T) {
+	ds := NewDataSeeder(nil, nil, 42, 1)
+	ds.manifest.Params["employees"] = 100
+	ds.manifest.Counts["employees"] = 100
+	path := filepath.Join(t.TempDir(), "seed_manifest.json")
+
+	require.NoError(t, ds.WriteManifest(path))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var m SeedManifest
+	require.NoError(t, json.Unmarshal(data, &m))
+	assert.Equal(t, int64(42), m.Seed)
+	assert.Equal(t, map[string]int{"employees": 100}, m.Counts)
+	assert.False(t, m.GeneratedAt.IsZero())
+}
+
+func TestDataSeeder_PlanDataRecordsWhatSeedDataCreates(t *testing.T) {
+	ds := NewDataSeeder(nil, nil, 42, 1)
+
+	data := ds.PlanData(2, 5, 3)
+
+	assert.Len(t, data.Products, 10)
+	assert.Equal(t, data, NewDataSeeder(nil, nil, 42, 1).PlanData(2, 5, 3), "the same seed plans the same data")
+	m := ds.Manifest()
+	assert.Equal(t, map[string]int{"brands": 2, "products_per_brand": 5, "features_per_country": 3}, m.Params)
+	assert.Equal(t, map[string]int{"products": 10, "features": len(data.Features), "product_infos": len(data.ProductInfos)}, m.Counts)
+}
+
+func TestDataSeeder_ClearOnlyRejectsUnknownData(t *testing.T) {
+	ds := NewDataSeeder(nil, nil, 42, 1)
+
+	assert.ErrorContains(t, ds.ClearOnly(context.Background(), "orders"), `unknown data to clear "orders"`)
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/logger"
+)
+
+// maxLoggedStatement caps the statements of slow query logs, batch inserts
+// can be huge.
+const maxLoggedStatement = 1000
+
+// SlowQueryLogger returns a QueryObserver logging the statements taking
+// threshold or longer as warnings. The values of their arguments are
+// redacted, only their types and sizes are logged.
+func SlowQueryLogger(threshold time.Duration) QueryObserver {
+	return func(ctx context.Context, q Query) {
+		if q.Statement == "" || q.Duration < threshold {
+			return
+		}
+		ctx = logger.WithLogger(ctx, map[string]interface{}{
+			"statement":   compactStatement(q.Statement),
+			"args":        redactArgs(q.Args),
+			"duration_ms": q.Duration.Milliseconds(),
+		})
+		if q.Err != nil {
+			logger.WarnLog(ctx, "Slow database %s failed after %s: %v", q.Operation, q.Duration.Round(time.Millisecond), q.Err)
+			return
+		}
+		logger.WarnLog(ctx, "Slow database %s took %s", q.Operation, q.Duration.Round(time.Millisecond))
+	}
+}
+
+// observeAll returns an observer calling each of observers, nil ones
+// skipped, nil if all are.
+func observeAll(observers ...QueryObserver) QueryObserver {
+	var set []QueryObserver
+	for _, o := range observers {
+		if o != nil {
+			set = append(set, o)
+		}
+	}
+	if len(set) == 0 {
+		return nil
+	}
+	return func(ctx context.Context, q Query) {
+		for _, o := range set {
+			o(ctx, q)
+		}
+	}
+}
+
+// compactStatement collapses the whitespace of statement and truncates it.
+func compactStatement(statement string) string {
+	s := strings.Join(strings.Fields(statement), " ")
+	if len(s) > maxLoggedStatement {
+		s = s[:maxLoggedStatement] + "..."
+	}
+	return s
+}
+
+// redactArgs describes args without their values, e.g. "$1=<string, 12 bytes>".
+func redactArgs(args []driver.NamedValue) []string {
+	redacted := make([]string, len(args))
+	for i, a := range args {
+		name := "$" + strconv.Itoa(a.Ordinal)
+		if a.Name != "" {
+			name = "@" + a.Name
+		}
+		redacted[i] = name + "=" + redactValue(a.Value)
+	}
+	return redacted
+}
+
+func redactValue(v driver.Value) string {
+	switch v := v.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return fmt.Sprintf("<string, %d bytes>", len(v))
+	case []byte:
+		return fmt.Sprintf("<bytes, %d bytes>", len(v))
+	}
+	return fmt.Sprintf("<%T>", v)
+}
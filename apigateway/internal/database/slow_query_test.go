@@ -0,0 +1,51 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSlowQueryLogger(t *testing.T) {
+	var buf bytes.Buffer
+	l := zerolog.New(&buf)
+	ctx := l.WithContext(context.Background())
+	observe := SlowQueryLogger(100 * time.Millisecond)
+
+	observe(ctx, Query{Operation: "query", Statement: "select 1", Duration: 99 * time.Millisecond})
+	observe(ctx, Query{Operation: "commit", Duration: time.Second})
+	assert.Empty(t, buf.String(), "fast statements and transaction calls are not logged")
+
+	observe(ctx, Query{
+		Operation: "query",
+		Statement: "SELECT *\n\t  FROM employees\n WHERE last_name = $1 AND emp_no > $2 AND photo = @photo",
+		Args: []driver.NamedValue{
+			{Ordinal: 1, Value: "Facello"},
+			{Ordinal: 2, Value: int64(10001)},
+			{Ordinal: 3, Name: "photo", Value: nil},
+		},
+		Duration: 1500 * time.Millisecond,
+	})
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "warn", entry["level"])
+	assert.Equal(t, "Slow database query took 1.5s", entry["message"])
+	assert.Equal(t, "SELECT * FROM employees WHERE last_name = $1 AND emp_no > $2 AND photo = @photo", entry["statement"])
+	assert.Equal(t, []interface{}{"$1=<string, 7 bytes>", "$2=<int64>", "@photo=NULL"}, entry["args"])
+	assert.NotContains(t, buf.String(), "Facello")
+	assert.NotContains(t, buf.String(), "10001")
+}
+
+func TestCompactStatement_Truncates(t *testing.T) {
+	s := compactStatement("INSERT INTO t VALUES " + strings.Repeat("(?), ", 1000))
+	assert.Len(t, s, maxLoggedStatement+len("..."))
+}
@@ -0,0 +1,25 @@
+package domain
+
+import "context"
+
+// SystemActor is recorded in the audit columns of changes made without a
+// known caller, e.g. by the seeder or scheduled plans.
+const SystemActor = "system"
+
+// MaxActorLength matches the created_by and updated_by columns.
+const MaxActorLength = 64
+
+type actorKey struct{}
+
+// WithActor returns a context recording actor as the caller changing data.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom returns the caller recorded by WithActor, SystemActor if none.
+func ActorFrom(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	return SystemActor
+}
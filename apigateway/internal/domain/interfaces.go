@@ -1,11 +1,73 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // EmployeeFilter defines criteria for listing employees
 type EmployeeFilter struct {
 	Limit  int
 	Offset int
+	// AfterID keeps employees with a greater id, the keyset position of a
+	// pagination cursor; it replaces Offset
+	AfterID int
+	// NamePrefix matches the start of the first or last name, ignoring case
+	NamePrefix string
+	// Gender is "M" or "F"
+	Gender string
+	// HiredFrom and HiredTo bound the hire date, both inclusive; zero values
+	// leave that side open
+	HiredFrom time.Time
+	HiredTo   time.Time
+	// DeptNo keeps the current employees of a department
+	DeptNo string
+}
+
+// MaxEmployeeNamePrefix bounds the length of EmployeeFilter.NamePrefix.
+const MaxEmployeeNamePrefix = 50
+
+// Validate checks the filter criteria
+func (f EmployeeFilter) Validate() *ValidationError {
+	verr := &ValidationError{}
+	if f.Limit < 0 {
+		verr.Add("limit", "must not be negative, got %d", f.Limit)
+	}
+	if f.Offset < 0 {
+		verr.Add("offset", "must not be negative, got %d", f.Offset)
+	}
+	if f.AfterID > 0 && f.Offset > 0 {
+		verr.Add("cursor", "cannot be combined with offset")
+	}
+	if len(f.NamePrefix) > MaxEmployeeNamePrefix {
+		verr.Add("name", "must be at most %d characters", MaxEmployeeNamePrefix)
+	}
+	if f.Gender != "" && f.Gender != "M" && f.Gender != "F" {
+		verr.Add("gender", "must be M or F, got %q", f.Gender)
+	}
+	if !f.HiredFrom.IsZero() && !f.HiredTo.IsZero() && f.HiredTo.Before(f.HiredFrom) {
+		verr.Add("hired_to", "must not be before hired_from")
+	}
+	return verr
+}
+
+// HasCriteria reports whether the filter narrows the employees beyond paging
+func (f EmployeeFilter) HasCriteria() bool {
+	return f.NamePrefix != "" || f.Gender != "" || !f.HiredFrom.IsZero() || !f.HiredTo.IsZero() || f.DeptNo != ""
+}
+
+// StatementStats counts prepared statement activity, to confirm chunked
+// exports prepare their query once instead of once per chunk.
+type StatementStats struct {
+	Prepares int64 `json:"prepares"`
+	Execs    int64 `json:"execs"`
+}
+
+// TxManager runs service operations spanning several repository calls
+// atomically. Repositories called with the context passed to fn share its
+// transaction; fn returning an error rolls everything back.
+type TxManager interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
 // EmployeeRepository defines the interface for employee data access
@@ -14,12 +76,160 @@ type EmployeeRepository interface {
 	Upsert(ctx context.Context, e *Employee) error
 	GetByID(ctx context.Context, id int) (*Employee, error)
 	Update(ctx context.Context, e *Employee) error
+	// Delete marks the employee as deleted, hiding it from every query until
+	// it is restored.
 	Delete(ctx context.Context, id int) error
+	// Restore brings back a deleted employee, sql.ErrNoRows if there is none.
+	Restore(ctx context.Context, id int) error
 	List(ctx context.Context, filter EmployeeFilter) ([]Employee, error)
+	// BatchUpdate updates all employees in a single transaction, rolling
+	// back if any of them fails or does not exist. The failing employee is
+	// reported as a *BatchItemError.
+	BatchUpdate(ctx context.Context, employees []Employee) error
+	// BatchUpsert inserts or updates the employees with a single statement
+	// and reports, by id, whether each one was inserted. The ids must be
+	// unique.
+	BatchUpsert(ctx context.Context, employees []Employee) (map[int]bool, error)
+	// ListChunks passes employees ordered by id to fn in pages of chunkSize.
+	// filter.Limit caps the total (0 means all), filter.Offset skips rows and
+	// filter.AfterID starts after an id. The page query is prepared once and
+	// reused for every chunk.
+	ListChunks(ctx context.Context, filter EmployeeFilter, chunkSize int, fn func([]Employee) error) error
+	// PrepareStatements prepares the ListChunks query ahead of the first export.
+	PrepareStatements(ctx context.Context) error
+	StatementStats() StatementStats
 
 	// Advanced Queries
 	GetCurrentSalary(ctx context.Context, empID int) (*Salary, error)
 	GetDepartmentHistory(ctx context.Context, empID int) ([]DeptEmp, error)
 	GetManagers(ctx context.Context, deptNo string) ([]DeptManager, error)
+	// ListDepartmentEmployees returns the current employees of a department, ordered by id.
+	ListDepartmentEmployees(ctx context.Context, deptNo string) ([]Employee, error)
+	// ListDepartmentSalaries returns the current salaries of the current
+	// employees of a department.
+	ListDepartmentSalaries(ctx context.Context, deptNo string) ([]Salary, error)
+	ListDepartments(ctx context.Context) ([]Department, error)
+	// CountHeadcountByMonth returns the headcount of every department at the
+	// end of each month from from to to, ordered by month and department.
+	// Deleted employees are not counted.
+	CountHeadcountByMonth(ctx context.Context, from, to time.Time) ([]HeadcountPoint, error)
 	GetTitle(ctx context.Context, empID int) (*Title, error)
 }
+
+// DepartmentRepository defines the interface for department and department
+// manager data access. Missing rows are reported as sql.ErrNoRows. Deleted
+// departments are hidden until restored.
+type DepartmentRepository interface {
+	ListDepartments(ctx context.Context) ([]Department, error)
+	GetDepartment(ctx context.Context, deptNo string) (*Department, error)
+	// CreateDepartment stores d, replacing a deleted department with the
+	// same number. A live one is reported as sql.ErrNoRows.
+	CreateDepartment(ctx context.Context, d *Department) error
+	UpdateDepartment(ctx context.Context, d *Department) error
+	// DeleteDepartment marks the department as deleted.
+	DeleteDepartment(ctx context.Context, deptNo string) error
+	RestoreDepartment(ctx context.Context, deptNo string) error
+	// ListManagers returns the manager assignments of a department, most
+	// recent first.
+	ListManagers(ctx context.Context, deptNo string) ([]DeptManager, error)
+	// AssignManager ends the current manager assignment of m.DeptNo, if any,
+	// on m.FromDate and stores m, in a single transaction.
+	AssignManager(ctx context.Context, m *DeptManager) error
+	// TerminateManager sets the to_date of the current assignment of empNo
+	// as manager of deptNo.
+	TerminateManager(ctx context.Context, deptNo string, empNo int, toDate time.Time) error
+	// DeleteManager removes the assignment of empNo to deptNo starting on fromDate.
+	DeleteManager(ctx context.Context, deptNo string, empNo int, fromDate time.Time) error
+}
+
+// SalaryRepository defines the interface for salary history data access.
+// Missing rows are reported as sql.ErrNoRows.
+type SalaryRepository interface {
+	// ListSalaries returns the salary history of an employee, most recent first.
+	ListSalaries(ctx context.Context, empNo int) ([]Salary, error)
+	// GetCurrentSalary returns the salary with an open to_date.
+	GetCurrentSalary(ctx context.Context, empNo int) (*Salary, error)
+	// LockSalaries locks the salary history of an employee until the end of
+	// the transaction of ctx, so concurrent changes to it wait their turn.
+	LockSalaries(ctx context.Context, empNo int) error
+	// AddSalary stores s. An open-ended s first ends the current salary of
+	// the employee on s.FromDate, in the same transaction.
+	AddSalary(ctx context.Context, s *Salary) error
+	// UpdateSalaryAmount changes the amount of the salary starting on fromDate.
+	UpdateSalaryAmount(ctx context.Context, empNo int, fromDate time.Time, amount int) error
+	// DeleteSalary removes the salary starting on fromDate.
+	DeleteSalary(ctx context.Context, empNo int, fromDate time.Time) error
+}
+
+// AttendanceRepository defines the interface for attendance and leave data access
+type AttendanceRepository interface {
+	// CheckIn records the first check-in of the day, later ones are ignored.
+	CheckIn(ctx context.Context, empNo int, at time.Time) error
+	// CheckOut records the check-out of the day, returning sql.ErrNoRows
+	// without a check-in.
+	CheckOut(ctx context.Context, empNo int, at time.Time) error
+	// ListAttendance returns the recorded days ordered by employee and date.
+	// filter.DeptNo limits it to the department's current employees.
+	ListAttendance(ctx context.Context, filter AttendanceFilter) ([]Attendance, error)
+	// SaveAttendance upserts the days in a single transaction.
+	SaveAttendance(ctx context.Context, days []Attendance) error
+
+	CreateLeave(ctx context.Context, l *LeaveRequest) error
+	GetLeave(ctx context.Context, id int) (*LeaveRequest, error)
+	// ListLeaves returns the requests overlapping the filter dates, newest first.
+	ListLeaves(ctx context.Context, filter AttendanceFilter) ([]LeaveRequest, error)
+	// SetLeaveStatus changes the status of a request. Approving marks the
+	// days it covers with its leave type in the same transaction.
+	SetLeaveStatus(ctx context.Context, id int, status string) error
+}
+
+// AnnotationRepository defines the interface for employee annotation data access
+type AnnotationRepository interface {
+	// CreateAnnotations stores the annotations in a single transaction,
+	// setting their ID and CreatedAt.
+	CreateAnnotations(ctx context.Context, annotations []Annotation) error
+	GetAnnotation(ctx context.Context, id int) (*Annotation, error)
+	// ListAnnotations returns the annotations of an employee, oldest first.
+	ListAnnotations(ctx context.Context, empNo int) ([]Annotation, error)
+	SetAnnotationStatus(ctx context.Context, id int, status string) error
+}
+
+// AdhocQueryRepository runs analyst queries in read-only transactions as a
+// low-privilege role
+type AdhocQueryRepository interface {
+	// EstimateCost returns the planner's total cost of query, planning it
+	// with a statement timeout.
+	EstimateCost(ctx context.Context, query string, timeout time.Duration) (float64, error)
+	// Query runs query with a statement timeout, passing the result column
+	// names and database type names (e.g. "INT4", "DATE") to onColumns and
+	// then at most limit rows to onRow.
+	Query(ctx context.Context, query string, limit int, timeout time.Duration, onColumns func(columns, types []string) error, onRow func(row []interface{}) error) error
+}
+
+// ReportDeliveryRepository keeps the delivery history of export plan steps
+type ReportDeliveryRepository interface {
+	// Record stores d, setting its ID and CreatedAt.
+	Record(ctx context.Context, d *ReportDelivery) error
+	// FindDelivered returns the successful delivery made under an
+	// idempotency key, or sql.ErrNoRows.
+	FindDelivered(ctx context.Context, idempotencyKey string) (*ReportDelivery, error)
+	// LatestDelivered returns the successful delivery to target with the
+	// newest data, or sql.ErrNoRows.
+	LatestDelivered(ctx context.Context, target string) (*ReportDelivery, error)
+	// ListByRun returns the deliveries of a run in the order they were made.
+	ListByRun(ctx context.Context, runID string) ([]ReportDelivery, error)
+}
+
+// APIKeyRepository stores API keys by the hash of the key. Missing keys are
+// reported as sql.ErrNoRows.
+type APIKeyRepository interface {
+	// CreateAPIKey stores k with the hash of its key, setting its ID and CreatedAt.
+	CreateAPIKey(ctx context.Context, k *APIKey, hash string) error
+	FindAPIKey(ctx context.Context, hash string) (*APIKey, error)
+	// ListAPIKeys returns every key, revoked ones included, newest first.
+	ListAPIKeys(ctx context.Context) ([]APIKey, error)
+	// RevokeAPIKey revokes a key that is not revoked yet.
+	RevokeAPIKey(ctx context.Context, id int, at time.Time) error
+	// TouchAPIKey records the last use of a key.
+	TouchAPIKey(ctx context.Context, id int, at time.Time) error
+}
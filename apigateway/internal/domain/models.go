@@ -1,6 +1,15 @@
 package domain
 
-import "time"
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/apperror"
+)
 
 // ==================== PRODUCT MANAGEMENT ====================
 
@@ -65,6 +74,32 @@ type Employee struct {
 	HireDate  time.Time `json:"hire_date" db:"hire_date"`
 }
 
+// Validate checks the fields stored when an employee is created
+func (e *Employee) Validate() *ValidationError {
+	verr := &ValidationError{}
+	if e.ID <= 0 {
+		verr.Add("id", "must be a positive integer, got %d", e.ID)
+	}
+	if strings.TrimSpace(e.FirstName) == "" {
+		verr.Add("first_name", "is required")
+	}
+	if strings.TrimSpace(e.LastName) == "" {
+		verr.Add("last_name", "is required")
+	}
+	if e.Gender != "M" && e.Gender != "F" {
+		verr.Add("gender", "must be M or F, got %q", e.Gender)
+	}
+	if e.BirthDate.IsZero() {
+		verr.Add("birth_date", "is required")
+	}
+	if e.HireDate.IsZero() {
+		verr.Add("hire_date", "is required")
+	} else if !e.BirthDate.IsZero() && !e.HireDate.After(e.BirthDate) {
+		verr.Add("hire_date", "must be after birth_date")
+	}
+	return verr
+}
+
 // Department represents the departments table
 type Department struct {
 	DeptNo   string `json:"dept_no" db:"dept_no"`
@@ -87,6 +122,52 @@ type DeptManager struct {
 	ToDate   time.Time `json:"to_date" db:"to_date"`
 }
 
+// OpenEndDate is the to_date of current dept_emp, dept_manager, salary and
+// title rows.
+var OpenEndDate = time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// IsCurrent reports whether the assignment has not ended.
+func (m DeptManager) IsCurrent() bool {
+	return m.ToDate.Year() >= OpenEndDate.Year()
+}
+
+// Validate checks a manager assignment
+func (m *DeptManager) Validate() *ValidationError {
+	verr := &ValidationError{}
+	if m.EmpNo <= 0 {
+		verr.Add("emp_no", "must be a positive integer")
+	}
+	if m.FromDate.IsZero() {
+		verr.Add("from_date", "is required")
+	}
+	if !m.ToDate.IsZero() && !m.ToDate.After(m.FromDate) {
+		verr.Add("to_date", "must be after from_date")
+	}
+	return verr
+}
+
+// Department name and number limits of the department table
+const (
+	MaxDeptNoLength   = 4
+	MaxDeptNameLength = 40
+)
+
+// Validate checks a department
+func (d *Department) Validate() *ValidationError {
+	verr := &ValidationError{}
+	if d.DeptNo == "" {
+		verr.Add("dept_no", "is required")
+	} else if len(d.DeptNo) > MaxDeptNoLength {
+		verr.Add("dept_no", "must be at most %d characters", MaxDeptNoLength)
+	}
+	if d.DeptName == "" {
+		verr.Add("dept_name", "is required")
+	} else if len(d.DeptName) > MaxDeptNameLength {
+		verr.Add("dept_name", "must be at most %d characters", MaxDeptNameLength)
+	}
+	return verr
+}
+
 // Salary represents the salaries table
 type Salary struct {
 	EmployeeID int       `json:"employee_id" db:"employee_id"`
@@ -95,6 +176,31 @@ type Salary struct {
 	ToDate     time.Time `json:"to_date" db:"to_date"`
 }
 
+// IsCurrent reports whether the salary has not ended.
+func (s Salary) IsCurrent() bool {
+	return s.ToDate.Year() >= OpenEndDate.Year()
+}
+
+// Overlaps reports whether the [FromDate, ToDate) ranges of s and o share a day.
+func (s Salary) Overlaps(o Salary) bool {
+	return s.FromDate.Before(o.ToDate) && o.FromDate.Before(s.ToDate)
+}
+
+// Validate checks a salary record
+func (s *Salary) Validate() *ValidationError {
+	verr := &ValidationError{}
+	if s.Salary <= 0 {
+		verr.Add("salary", "must be positive, got %d", s.Salary)
+	}
+	if s.FromDate.IsZero() {
+		verr.Add("from_date", "is required")
+	}
+	if !s.ToDate.IsZero() && !s.ToDate.After(s.FromDate) {
+		verr.Add("to_date", "must be after from_date")
+	}
+	return verr
+}
+
 // Title represents the titles table
 type Title struct {
 	EmpNo    int       `json:"emp_no" db:"emp_no"`
@@ -111,3 +217,691 @@ type EmployeeReport struct {
 	DepartmentHistory []DeptEmp     `json:"department_history"`
 	ManagementHistory []DeptManager `json:"management_history"`
 }
+
+// OrgChartEntry is an employee of a department and the manager they report to
+type OrgChartEntry struct {
+	EmpNo     int    `json:"emp_no"`
+	ManagerNo int    `json:"manager_no"` // 0 for the department manager
+	Name      string `json:"name"`
+}
+
+// ==================== ATTENDANCE ====================
+
+// Leave types, also stored on the attendance days a leave covers
+const (
+	LeaveAnnual = "annual"
+	LeaveSick   = "sick"
+	LeaveUnpaid = "unpaid"
+)
+
+// Leave request statuses
+const (
+	LeavePending  = "pending"
+	LeaveApproved = "approved"
+	LeaveRejected = "rejected"
+)
+
+// ValidLeaveType reports whether t is one of the leave types
+func ValidLeaveType(t string) bool {
+	return t == LeaveAnnual || t == LeaveSick || t == LeaveUnpaid
+}
+
+// Attendance represents the attendance table, one row per employee and day
+type Attendance struct {
+	EmpNo     int        `json:"emp_no" db:"emp_no"`
+	WorkDate  time.Time  `json:"work_date" db:"work_date"`
+	CheckIn   *time.Time `json:"check_in,omitempty" db:"check_in"`
+	CheckOut  *time.Time `json:"check_out,omitempty" db:"check_out"`
+	LeaveType string     `json:"leave_type,omitempty" db:"leave_type"` // set on days off
+}
+
+// AttendanceFilter selects attendance days or leave requests. Zero fields
+// are not filtered on.
+type AttendanceFilter struct {
+	EmpNo  int
+	DeptNo string
+	From   time.Time
+	To     time.Time // inclusive
+	Status string    // leave requests only
+}
+
+// LeaveRequest represents the leave_request table
+type LeaveRequest struct {
+	ID        int       `json:"id" db:"id"`
+	EmpNo     int       `json:"emp_no" db:"emp_no"`
+	FromDate  time.Time `json:"from_date" db:"from_date"`
+	ToDate    time.Time `json:"to_date" db:"to_date"` // inclusive
+	LeaveType string    `json:"leave_type" db:"leave_type"`
+	Status    string    `json:"status" db:"status"`
+	Reason    string    `json:"reason" db:"reason"`
+}
+
+// Validate checks a new leave request
+func (l *LeaveRequest) Validate() *ValidationError {
+	verr := &ValidationError{}
+	if l.EmpNo <= 0 {
+		verr.Add("emp_no", "must be a positive integer")
+	}
+	if l.FromDate.IsZero() {
+		verr.Add("from_date", "is required")
+	}
+	if l.ToDate.IsZero() {
+		verr.Add("to_date", "is required")
+	} else if l.ToDate.Before(l.FromDate) {
+		verr.Add("to_date", "must not be before from_date")
+	}
+	if !ValidLeaveType(l.LeaveType) {
+		verr.Add("leave_type", "must be one of %q, %q or %q, got %q", LeaveAnnual, LeaveSick, LeaveUnpaid, l.LeaveType)
+	}
+	return verr
+}
+
+// TimesheetRow is one employee day of the editable timesheet export
+type TimesheetRow struct {
+	EmpNo     int
+	Name      string
+	WorkDate  time.Time
+	CheckIn   *time.Time
+	CheckOut  *time.Time
+	LeaveType string
+}
+
+// ==================== EMPLOYEE ANNOTATIONS ====================
+
+// Annotation kinds
+const (
+	AnnotationNote = "note"
+	AnnotationGoal = "goal"
+)
+
+// Annotation statuses, uploaded annotations wait for review
+const (
+	AnnotationPending  = "pending"
+	AnnotationApproved = "approved"
+	AnnotationRejected = "rejected"
+)
+
+// ValidAnnotationKind reports whether k is one of the annotation kinds
+func ValidAnnotationKind(k string) bool {
+	return k == AnnotationNote || k == AnnotationGoal
+}
+
+// Annotation represents the employee_annotation table, a note or goal a
+// manager added to the report of an employee
+type Annotation struct {
+	ID        int       `json:"id" db:"id"`
+	EmpNo     int       `json:"emp_no" db:"emp_no"`
+	Kind      string    `json:"kind" db:"kind"`
+	Text      string    `json:"text" db:"text"`
+	Status    string    `json:"status" db:"status"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// ==================== PAYROLL ====================
+
+// PayrollBaseCurrency is the currency salaries are stored in
+const PayrollBaseCurrency = "USD"
+
+// PayrollLine is the pay of one employee for a month, amounts in Currency
+type PayrollLine struct {
+	EmpNo         int     `json:"emp_no"`
+	Name          string  `json:"name"`
+	DeptNo        string  `json:"dept_no"`
+	DeptName      string  `json:"dept_name"`
+	Currency      string  `json:"currency"`
+	AnnualSalary  float64 `json:"annual_salary"`
+	GrossPay      float64 `json:"gross_pay"` // a twelfth of the annual salary
+	WorkedDays    int     `json:"worked_days"`
+	PaidLeaveDays int     `json:"paid_leave_days"`
+	UnpaidDays    int     `json:"unpaid_days"`
+	Deduction     float64 `json:"deduction"` // unpaid leave, prorated over the working days
+	NetPay        float64 `json:"net_pay"`
+}
+
+// PayrollDepartment totals the payroll lines of a department
+type PayrollDepartment struct {
+	DeptNo     string  `json:"dept_no"`
+	DeptName   string  `json:"dept_name"`
+	Currency   string  `json:"currency"`
+	Headcount  int     `json:"headcount"`
+	GrossPay   float64 `json:"gross_pay"`
+	Deductions float64 `json:"deductions"`
+	NetPay     float64 `json:"net_pay"`
+}
+
+// PayrollException flags an employee whose pay needs a manual check
+type PayrollException struct {
+	EmpNo  int    `json:"emp_no"`
+	Name   string `json:"name"`
+	DeptNo string `json:"dept_no"`
+	Issue  string `json:"issue"`
+}
+
+// Payroll is the payroll of a month, by department and employee
+type Payroll struct {
+	Month       time.Time           `json:"month"` // first day of the month
+	Currency    string              `json:"currency"`
+	Departments []PayrollDepartment `json:"departments"`
+	Lines       []PayrollLine       `json:"lines"`
+	Exceptions  []PayrollException  `json:"exceptions"`
+}
+
+// ==================== HEADCOUNT ====================
+
+// HeadcountPoint is the headcount of a department at the end of a month
+type HeadcountPoint struct {
+	Month     string `json:"month"` // YYYY-MM
+	DeptNo    string `json:"dept_no"`
+	DeptName  string `json:"dept_name"`
+	Headcount int    `json:"headcount"`
+}
+
+// Celebration kinds
+const (
+	CelebrationBirthday    = "birthday"
+	CelebrationAnniversary = "anniversary"
+)
+
+// Celebration is an upcoming birthday or work anniversary of an employee
+type Celebration struct {
+	EmpNo int       `json:"emp_no"`
+	Name  string    `json:"name"`
+	Kind  string    `json:"kind"`
+	Date  time.Time `json:"date"`
+	Years int       `json:"years"` // age turned or years of service
+}
+
+// ==================== REPORTING ====================
+
+// Export formats supported by the report generator
+const (
+	ExportFormatXLSX = "xlsx"
+	ExportFormatCSV  = "csv"
+	ExportFormatHTML = "html" // preview only, always delivered as a download
+)
+
+// Delivery target types
+const (
+	DeliveryDownload = "download"
+	DeliveryS3       = "s3"
+	DeliveryGCS      = "gcs"
+)
+
+// Masking modes
+const (
+	MaskFull    = "full"    // replace the whole value
+	MaskPartial = "partial" // keep the last 4 characters
+)
+
+// ExportRequest is the body of the report generation endpoint
+type ExportRequest struct {
+	TemplateID string                 `json:"template_id"`
+	Variables  map[string]interface{} `json:"variables"`
+	Format     string                 `json:"format"`
+	Delivery   *DeliveryTarget        `json:"delivery,omitempty"`
+	Masking    *MaskingPolicy         `json:"masking,omitempty"`
+}
+
+// DeliveryTarget describes where the generated file goes
+type DeliveryTarget struct {
+	Type   string `json:"type" yaml:"type"`
+	Bucket string `json:"bucket,omitempty" yaml:"bucket"`
+	Key    string `json:"key,omitempty" yaml:"key"`
+}
+
+// MaskingPolicy lists the columns (by field name) whose values are masked
+type MaskingPolicy struct {
+	Mode    string   `json:"mode" yaml:"mode"`
+	Columns []string `json:"columns" yaml:"columns"`
+}
+
+// FieldError describes a single invalid field of a request
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError is returned when a request is rejected before any work is done
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fe.Field + ": " + fe.Message
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// ErrorKind makes validation errors apperror.KindValidation.
+func (e *ValidationError) ErrorKind() apperror.Kind { return apperror.KindValidation }
+
+// Add records a field error
+func (e *ValidationError) Add(field, format string, args ...interface{}) {
+	e.Errors = append(e.Errors, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// ErrOrNil returns e if any field error was recorded, nil otherwise
+func (e *ValidationError) ErrOrNil() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+// Normalize fills defaults for optional fields
+func (r *ExportRequest) Normalize() {
+	r.Format = strings.ToLower(strings.TrimSpace(r.Format))
+	if r.Format == "" {
+		r.Format = ExportFormatXLSX
+	}
+	if r.Delivery == nil {
+		r.Delivery = &DeliveryTarget{Type: DeliveryDownload}
+	}
+	if r.Delivery.Type == "" {
+		r.Delivery.Type = DeliveryDownload
+	}
+	if r.Masking != nil && r.Masking.Mode == "" {
+		r.Masking.Mode = MaskFull
+	}
+	if r.Variables == nil {
+		r.Variables = make(map[string]interface{})
+	}
+}
+
+// Validate checks the parts of the request that don't depend on the template
+func (r *ExportRequest) Validate() *ValidationError {
+	verr := &ValidationError{}
+	if strings.TrimSpace(r.TemplateID) == "" {
+		verr.Add("template_id", "is required")
+	}
+	switch r.Format {
+	case ExportFormatXLSX, ExportFormatCSV:
+	case ExportFormatHTML:
+		if r.Delivery != nil && r.Delivery.Type != DeliveryDownload {
+			verr.Add("delivery.type", "%s previews can only be downloaded", ExportFormatHTML)
+		}
+	default:
+		verr.Add("format", "must be one of %q, %q or %q, got %q", ExportFormatXLSX, ExportFormatCSV, ExportFormatHTML, r.Format)
+	}
+	if r.Delivery != nil {
+		switch r.Delivery.Type {
+		case DeliveryDownload:
+		case DeliveryS3, DeliveryGCS:
+			if r.Delivery.Bucket == "" {
+				verr.Add("delivery.bucket", "is required for %s delivery", r.Delivery.Type)
+			}
+			if r.Delivery.Key == "" {
+				verr.Add("delivery.key", "is required for %s delivery", r.Delivery.Type)
+			}
+		default:
+			verr.Add("delivery.type", "must be one of %q, %q or %q, got %q", DeliveryDownload, DeliveryS3, DeliveryGCS, r.Delivery.Type)
+		}
+	}
+	if r.Masking != nil {
+		if r.Masking.Mode != MaskFull && r.Masking.Mode != MaskPartial {
+			verr.Add("masking.mode", "must be %q or %q, got %q", MaskFull, MaskPartial, r.Masking.Mode)
+		}
+		if len(r.Masking.Columns) == 0 {
+			verr.Add("masking.columns", "must list at least one column")
+		}
+	}
+	return verr
+}
+
+// ==================== EXPORT PLAN ====================
+
+// DeliveryZip collects the file of an export plan step into the archive
+// returned for the plan
+const DeliveryZip = "zip"
+
+// Export plan step statuses
+const (
+	PlanStepSucceeded = "succeeded"
+	PlanStepFailed    = "failed"
+	PlanStepSkipped   = "skipped" // a step it runs after did not succeed
+)
+
+// ExportPlan is a YAML document running several reports in one go
+type ExportPlan struct {
+	Name string `json:"name" yaml:"name"`
+	// Concurrency is the number of steps run at once, 1 when unset
+	Concurrency int `json:"concurrency" yaml:"concurrency"`
+	// Retries is the number of times a failing step is retried
+	Retries int `json:"retries" yaml:"retries"`
+	// IdempotencyKey identifies the job across runs: bucket deliveries
+	// already made under the same key are not repeated when it is run again
+	IdempotencyKey string           `json:"idempotency_key,omitempty" yaml:"idempotency_key"`
+	Reports        []ExportPlanStep `json:"reports" yaml:"reports"`
+}
+
+// ExportPlanStep is one report of an export plan
+type ExportPlanStep struct {
+	ID         string                 `json:"id" yaml:"id"`
+	TemplateID string                 `json:"template_id" yaml:"template_id"`
+	Format     string                 `json:"format" yaml:"format"`
+	Variables  map[string]interface{} `json:"variables" yaml:"variables"`
+	// Delivery defaults to the plan archive (zip), whose entry is named
+	// after Key or the step ID
+	Delivery *DeliveryTarget `json:"delivery,omitempty" yaml:"delivery"`
+	Masking  *MaskingPolicy  `json:"masking,omitempty" yaml:"masking"`
+	// After lists the IDs of steps that must succeed before this one runs
+	After []string `json:"after,omitempty" yaml:"after"`
+}
+
+// Archived reports whether the file of the step goes to the plan archive
+func (s *ExportPlanStep) Archived() bool {
+	return s.Delivery == nil || s.Delivery.Type == "" || s.Delivery.Type == DeliveryZip
+}
+
+// ExportRequest returns the report request of the step, with archived files
+// turned into a download
+func (s *ExportPlanStep) ExportRequest() *ExportRequest {
+	req := &ExportRequest{TemplateID: s.TemplateID, Format: s.Format, Masking: s.Masking}
+	req.Variables = make(map[string]interface{}, len(s.Variables))
+	for k, v := range s.Variables {
+		req.Variables[k] = v
+	}
+	if !s.Archived() {
+		d := *s.Delivery
+		req.Delivery = &d
+	}
+	return req
+}
+
+// ExportPlanResult reports the outcome of every step of a plan, in plan order
+type ExportPlanResult struct {
+	// RunID identifies the run in the delivery history
+	RunID     string                 `json:"run_id"`
+	Name      string                 `json:"name"`
+	Succeeded bool                   `json:"succeeded"`
+	Steps     []ExportPlanStepResult `json:"steps"`
+}
+
+// ExportPlanStepResult is the outcome of one export plan step
+type ExportPlanStepResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// Target is where the file went: the archive entry or bucket/key
+	Target string `json:"target,omitempty"`
+	// Delivery is the status of the last bucket delivery, see ReportDelivery
+	Delivery   string    `json:"delivery,omitempty"`
+	Attempts   int       `json:"attempts,omitempty"`
+	StartedAt  time.Time `json:"started_at,omitempty"`
+	FinishedAt time.Time `json:"finished_at,omitempty"`
+}
+
+// Report delivery statuses
+const (
+	DeliveryDelivered    = "delivered"
+	DeliveryFailed       = "failed"
+	DeliveryDeduplicated = "deduplicated" // already delivered under the same idempotency key
+	DeliverySuperseded   = "superseded"   // the target holds a file generated from newer data
+)
+
+// ReportDelivery records one attempt to deliver an export plan step to a
+// bucket
+type ReportDelivery struct {
+	ID             int    `json:"id"`
+	RunID          string `json:"run_id"`
+	StepID         string `json:"step_id"`
+	IdempotencyKey string `json:"idempotency_key"`
+	Target         string `json:"target"`
+	Attempt        int    `json:"attempt"`
+	Status         string `json:"status"`
+	Error          string `json:"error,omitempty"`
+	// DataAsOf is when the run started reading data, newer files are never
+	// overwritten by older ones
+	DataAsOf  time.Time `json:"data_as_of"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ==================== AD-HOC QUERY ====================
+
+// AdhocQueryRequest is a read-only SELECT exported to a workbook
+type AdhocQueryRequest struct {
+	SQL     string        `json:"sql"`
+	Title   string        `json:"title,omitempty"`
+	Columns []AdhocColumn `json:"columns,omitempty"` // optional, by result column name
+}
+
+// ErrQueryRejected wraps database errors caused by an ad-hoc query itself,
+// e.g. a syntax error, a write or a statement timeout, as opposed to the
+// database being unavailable
+var ErrQueryRejected = errors.New("query rejected")
+
+// AdhocColumn overrides the export of a result column
+type AdhocColumn struct {
+	Field  string  `json:"field"`
+	Header string  `json:"header,omitempty"`
+	Width  float64 `json:"width,omitempty"`
+	Format string  `json:"format,omitempty"` // Excel number format code
+}
+
+// ==================== IMPORT ====================
+
+// ImportRowError describes a rejected cell or row of an uploaded workbook.
+// Row is the 1-based sheet row, 0 for problems with the workbook as a whole.
+type ImportRowError struct {
+	Sheet   string `json:"sheet,omitempty"`
+	Row     int    `json:"row,omitempty"`
+	Cell    string `json:"cell,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Rule    string `json:"rule,omitempty"` // failed template validation rule, if any
+	Message string `json:"message"`
+}
+
+// ImportError is returned when an import is rejected. Nothing is written
+// when any row fails, so the whole file can be fixed and uploaded again.
+type ImportError struct {
+	Errors []ImportRowError `json:"errors"`
+}
+
+func (e *ImportError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, re := range e.Errors {
+		loc := re.Sheet
+		if re.Cell != "" {
+			loc += "!" + re.Cell
+		} else if re.Row > 0 {
+			loc += fmt.Sprintf(" row %d", re.Row)
+		}
+		if loc != "" {
+			msgs[i] = loc + ": " + re.Message
+		} else {
+			msgs[i] = re.Message
+		}
+	}
+	return "import rejected: " + strings.Join(msgs, "; ")
+}
+
+// Add records a row error
+func (e *ImportError) Add(rowErr ImportRowError) {
+	e.Errors = append(e.Errors, rowErr)
+}
+
+// ErrOrNil returns e if any row error was recorded, nil otherwise
+func (e *ImportError) ErrOrNil() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+// BatchItemError wraps the failure of one item of a batch operation, so
+// callers can map it back to their input.
+type BatchItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchItemError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchItemError) Unwrap() error {
+	return e.Err
+}
+
+// MaxEmployeeBatchSize bounds the employees of a batch upsert.
+const MaxEmployeeBatchSize = 1000
+
+// Batch upsert row statuses
+const (
+	BatchStatusCreated = "created"
+	BatchStatusUpdated = "updated"
+	BatchStatusInvalid = "invalid"
+)
+
+// BatchRowResult is the outcome of one employee of a batch upsert, in the
+// order of the request. Invalid rows are skipped and list their field errors.
+type BatchRowResult struct {
+	Index  int          `json:"index"`
+	ID     int          `json:"id"`
+	Status string       `json:"status"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+// ImportResult summarizes an applied import
+type ImportResult struct {
+	Updated int `json:"updated"`
+}
+
+// ErrInvalidCursor is returned for pagination cursors that were not issued
+// by this API.
+var ErrInvalidCursor = apperror.Validation("invalid_cursor", "invalid cursor")
+
+// EncodeCursor returns the opaque pagination cursor of a keyset position,
+// the sort key values of the last row of a page.
+func EncodeCursor(key ...interface{}) string {
+	raw, _ := json.Marshal(key)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeCursor reads the sort key values of a cursor made by EncodeCursor
+// into the pointers of key.
+func DecodeCursor(cursor string, key ...interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return ErrInvalidCursor
+	}
+	var values []json.RawMessage
+	if err := json.Unmarshal(raw, &values); err != nil || len(values) != len(key) {
+		return ErrInvalidCursor
+	}
+	for i, v := range values {
+		if err := json.Unmarshal(v, key[i]); err != nil {
+			return ErrInvalidCursor
+		}
+	}
+	return nil
+}
+
+// EmployeePage is one page of an employee listing. NextCursor continues the
+// listing after the page, it is empty on the last page.
+type EmployeePage struct {
+	Employees  []Employee
+	NextCursor string
+}
+
+// ProductPage is one page of the product listing ordered by brand and id.
+type ProductPage struct {
+	Products   []Product
+	NextCursor string
+}
+
+// ==================== API KEYS ====================
+
+// API key scopes
+const (
+	// ScopeReportsRead downloads reports and reads their variables
+	ScopeReportsRead = "reports:read"
+	// ScopeReportsRun runs export plans, which deliver reports to buckets
+	ScopeReportsRun = "reports:run"
+)
+
+// KnownScopes are the scopes an API key can be granted.
+var KnownScopes = []string{ScopeReportsRead, ScopeReportsRun}
+
+// MaxAPIKeyNameLength matches the name column of the api_key table.
+const MaxAPIKeyNameLength = 100
+
+// APIKey lets scheduled jobs and external systems call the API without a
+// user token. Only a hash of the key is stored, the key itself is shown
+// once when it is issued.
+type APIKey struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	// Prefix is the start of the key, to tell keys apart in listings
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// HasScope reports whether the key was granted scope.
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Active reports whether the key is neither revoked nor expired at now.
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || now.Before(*k.ExpiresAt))
+}
+
+// Validate checks a key about to be issued at now
+func (k *APIKey) Validate(now time.Time) *ValidationError {
+	verr := &ValidationError{}
+	if strings.TrimSpace(k.Name) == "" {
+		verr.Add("name", "is required")
+	} else if len(k.Name) > MaxAPIKeyNameLength {
+		verr.Add("name", "must be at most %d characters", MaxAPIKeyNameLength)
+	}
+	if len(k.Scopes) == 0 {
+		verr.Add("scopes", "must grant at least one of %s", strings.Join(KnownScopes, ", "))
+	}
+	for i, s := range k.Scopes {
+		known := false
+		for _, ks := range KnownScopes {
+			known = known || s == ks
+		}
+		if !known {
+			verr.Add(fmt.Sprintf("scopes[%d]", i), "must be one of %s, got %q", strings.Join(KnownScopes, ", "), s)
+		}
+	}
+	if k.ExpiresAt != nil && !k.ExpiresAt.After(now) {
+		verr.Add("expires_at", "must be in the future")
+	}
+	return verr
+}
+
+// ==================== RELOAD ====================
+
+// TemplateRevision is the template of a report as loaded by a reload
+type TemplateRevision struct {
+	ReportID string `json:"report_id"`
+	Path     string `json:"path"`
+	// Revision identifies the content of the template, empty when it failed
+	// to load
+	Revision string `json:"revision,omitempty"`
+	// Changed reports whether the revision differs from the one loaded before
+	Changed bool   `json:"changed"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ReloadResult reports what a reload of the templates and settings loaded
+type ReloadResult struct {
+	Templates []TemplateRevision `json:"templates"`
+	// Settings are the reloadable settings whose value changed
+	Settings []string `json:"settings"`
+}
@@ -0,0 +1,131 @@
+package grpcserver
+
+import (
+	"context"
+	"time"
+
+	employeev1 "github.com/locvowork/employee_management_sample/apigateway/api/proto/employee/v1"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+)
+
+// dateLayout is the format of the dates of employee messages.
+const dateLayout = "2006-01-02"
+
+// EmployeeServer implements employeev1.EmployeeServiceServer.
+type EmployeeServer struct {
+	employeev1.UnimplementedEmployeeServiceServer
+	svc service.EmployeeService
+}
+
+func NewEmployeeServer(svc service.EmployeeService) *EmployeeServer {
+	return &EmployeeServer{svc: svc}
+}
+
+func (s *EmployeeServer) GetEmployee(ctx context.Context, req *employeev1.GetEmployeeRequest) (*employeev1.Employee, error) {
+	emp, err := s.svc.Get(ctx, int(req.GetId()))
+	if err != nil {
+		return nil, statusError(ctx, "failed to get employee", err)
+	}
+	return toEmployeeMessage(emp), nil
+}
+
+// ListEmployees pages by keyset like GET /employees, next_page_token being
+// the cursor of the next page.
+func (s *EmployeeServer) ListEmployees(ctx context.Context, req *employeev1.ListEmployeesRequest) (*employeev1.ListEmployeesResponse, error) {
+	filter := domain.EmployeeFilter{
+		Limit:      int(req.GetPageSize()),
+		NamePrefix: req.GetNamePrefix(),
+		Gender:     req.GetGender(),
+		DeptNo:     req.GetDeptNo(),
+	}
+	if req.GetPageToken() != "" {
+		afterID, err := service.ParseEmployeeCursor(req.GetPageToken())
+		if err != nil {
+			verr := &domain.ValidationError{}
+			verr.Add("page_token", "%v", err)
+			return nil, statusError(ctx, "invalid page token", verr)
+		}
+		filter.AfterID = afterID
+	}
+
+	page, err := s.svc.ListPage(ctx, filter)
+	if err != nil {
+		return nil, statusError(ctx, "failed to list employees", err)
+	}
+	resp := &employeev1.ListEmployeesResponse{NextPageToken: page.NextCursor}
+	for i := range page.Employees {
+		resp.Employees = append(resp.Employees, toEmployeeMessage(&page.Employees[i]))
+	}
+	return resp, nil
+}
+
+func (s *EmployeeServer) CreateEmployee(ctx context.Context, req *employeev1.CreateEmployeeRequest) (*employeev1.Employee, error) {
+	emp, err := fromEmployeeMessage(req.GetEmployee())
+	if err != nil {
+		return nil, statusError(ctx, "invalid employee", err)
+	}
+	if err := s.svc.Create(ctx, emp); err != nil {
+		return nil, statusError(ctx, "failed to create employee", err)
+	}
+	return toEmployeeMessage(emp), nil
+}
+
+func (s *EmployeeServer) UpdateEmployee(ctx context.Context, req *employeev1.UpdateEmployeeRequest) (*employeev1.Employee, error) {
+	emp, err := fromEmployeeMessage(req.GetEmployee())
+	if err != nil {
+		return nil, statusError(ctx, "invalid employee", err)
+	}
+	if err := s.svc.Update(ctx, emp); err != nil {
+		return nil, statusError(ctx, "failed to update employee", err)
+	}
+	return toEmployeeMessage(emp), nil
+}
+
+func (s *EmployeeServer) DeleteEmployee(ctx context.Context, req *employeev1.DeleteEmployeeRequest) (*employeev1.DeleteEmployeeResponse, error) {
+	if err := s.svc.Delete(ctx, int(req.GetId())); err != nil {
+		return nil, statusError(ctx, "failed to delete employee", err)
+	}
+	return &employeev1.DeleteEmployeeResponse{}, nil
+}
+
+func toEmployeeMessage(e *domain.Employee) *employeev1.Employee {
+	return &employeev1.Employee{
+		Id:        int32(e.ID),
+		FirstName: e.FirstName,
+		LastName:  e.LastName,
+		Gender:    e.Gender,
+		BirthDate: e.BirthDate.Format(dateLayout),
+		HireDate:  e.HireDate.Format(dateLayout),
+	}
+}
+
+// fromEmployeeMessage converts and validates an employee message, returning
+// a *domain.ValidationError naming the invalid fields.
+func fromEmployeeMessage(m *employeev1.Employee) (*domain.Employee, error) {
+	verr := &domain.ValidationError{}
+	if m == nil {
+		verr.Add("employee", "is required")
+		return nil, verr
+	}
+	e := &domain.Employee{
+		ID:        int(m.GetId()),
+		FirstName: m.GetFirstName(),
+		LastName:  m.GetLastName(),
+		Gender:    m.GetGender(),
+	}
+	var err error
+	if e.BirthDate, err = time.Parse(dateLayout, m.GetBirthDate()); err != nil {
+		verr.Add("birth_date", "must be a date formatted YYYY-MM-DD, got %q", m.GetBirthDate())
+	}
+	if e.HireDate, err = time.Parse(dateLayout, m.GetHireDate()); err != nil {
+		verr.Add("hire_date", "must be a date formatted YYYY-MM-DD, got %q", m.GetHireDate())
+	}
+	if err := verr.ErrOrNil(); err != nil {
+		return nil, err
+	}
+	if err := e.Validate().ErrOrNil(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
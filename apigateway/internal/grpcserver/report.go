@@ -0,0 +1,114 @@
+package grpcserver
+
+import (
+	"fmt"
+
+	"google.golang.org/grpc"
+
+	employeev1 "github.com/locvowork/employee_management_sample/apigateway/api/proto/employee/v1"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/logger"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+)
+
+// reportChunkSize is the most data sent in a single ReportChunk, well below
+// the default 4MB message limit of gRPC clients.
+const reportChunkSize = 64 << 10
+
+// ReportServer implements employeev1.ReportServiceServer.
+type ReportServer struct {
+	employeev1.UnimplementedReportServiceServer
+	svc service.ReportService
+}
+
+func NewReportServer(svc service.ReportService) *ReportServer {
+	return &ReportServer{svc: svc}
+}
+
+// GenerateReport renders the report as a download and streams it as it is
+// written. The first chunk carries the content type and file name.
+func (s *ReportServer) GenerateReport(req *employeev1.GenerateReportRequest, stream grpc.ServerStreamingServer[employeev1.ReportChunk]) error {
+	ctx := stream.Context()
+	exportReq := domain.ExportRequest{
+		TemplateID: req.GetTemplateId(),
+		Format:     req.GetFormat(),
+		Delivery:   &domain.DeliveryTarget{Type: domain.DeliveryDownload},
+	}
+	if len(req.GetVariables()) > 0 {
+		exportReq.Variables = make(map[string]interface{}, len(req.GetVariables()))
+		for name, value := range req.GetVariables() {
+			exportReq.Variables[name] = value
+		}
+	}
+	// Reject bad requests up front instead of failing mid-stream
+	if err := s.svc.Validate(ctx, &exportReq); err != nil {
+		return statusError(ctx, "invalid report request", err)
+	}
+
+	w := &chunkWriter{stream: stream, first: &employeev1.ReportChunk{
+		ContentType: reportContentType(exportReq.Format),
+		FileName:    fmt.Sprintf("%s.%s", exportReq.TemplateID, exportReq.Format),
+	}}
+	if err := s.svc.Generate(ctx, &exportReq, w); err != nil {
+		logger.ErrorLog(ctx, "Failed to generate report %s: %v", exportReq.TemplateID, err)
+		return statusError(ctx, "failed to generate report", err)
+	}
+	return w.Flush()
+}
+
+func reportContentType(format string) string {
+	switch format {
+	case domain.ExportFormatCSV:
+		return "text/csv"
+	case domain.ExportFormatHTML:
+		return "text/html; charset=UTF-8"
+	}
+	return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+}
+
+// chunkWriter buffers the report and sends it in chunks of reportChunkSize.
+type chunkWriter struct {
+	stream grpc.ServerStreamingServer[employeev1.ReportChunk]
+	// first holds the metadata of the first chunk, nil once sent
+	first *employeev1.ReportChunk
+	buf   []byte
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		take := reportChunkSize - len(w.buf)
+		if take > len(p) {
+			take = len(p)
+		}
+		w.buf = append(w.buf, p[:take]...)
+		p = p[take:]
+		if len(w.buf) == reportChunkSize {
+			if err := w.send(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Flush sends the buffered data, and the first chunk even when the report
+// is empty.
+func (w *chunkWriter) Flush() error {
+	if len(w.buf) == 0 && w.first == nil {
+		return nil
+	}
+	return w.send()
+}
+
+func (w *chunkWriter) send() error {
+	chunk := w.first
+	if chunk == nil {
+		chunk = &employeev1.ReportChunk{}
+	}
+	w.first = nil
+	chunk.Data = w.buf
+	// the stream may still hold the sent slice, start a new one
+	w.buf = make([]byte, 0, reportChunkSize)
+	return w.stream.Send(chunk)
+}
@@ -0,0 +1,135 @@
+// Package grpcserver serves the employee and report services over gRPC for
+// internal callers, next to the JSON API and backed by the same services.
+package grpcserver
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	employeev1 "github.com/locvowork/employee_management_sample/apigateway/api/proto/employee/v1"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/logger"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+)
+
+// ActorMetadata is the metadata key naming the caller recorded in the audit
+// columns, the gRPC counterpart of the X-Actor header.
+const ActorMetadata = "x-actor"
+
+// RequestIDMetadata is the metadata key of the request ID logged with the
+// lines of the call, the gRPC counterpart of the X-Request-Id header. Calls
+// without one get a new ID, returned in the response header.
+const RequestIDMetadata = "x-request-id"
+
+// maxRequestIDLength bounds the request IDs taken from callers.
+const maxRequestIDLength = 128
+
+// New creates a gRPC server serving the employee and report services.
+func New(employees service.EmployeeService, reports service.ReportService, opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(contextUnaryInterceptor),
+		grpc.ChainStreamInterceptor(contextStreamInterceptor),
+	)
+	s := grpc.NewServer(opts...)
+	employeev1.RegisterEmployeeServiceServer(s, NewEmployeeServer(employees))
+	employeev1.RegisterReportServiceServer(s, NewReportServer(reports))
+	return s
+}
+
+// withActor records the ActorMetadata of the call in ctx, see domain.ActorFrom.
+func withActor(ctx context.Context) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(ActorMetadata)
+	if len(values) == 0 {
+		return ctx
+	}
+	actor := strings.TrimSpace(values[0])
+	if actor == "" {
+		return ctx
+	}
+	if len(actor) > domain.MaxActorLength {
+		actor = actor[:domain.MaxActorLength]
+	}
+	return domain.WithActor(ctx, actor)
+}
+
+// withRequestID records the RequestIDMetadata of the call in ctx, see
+// logger.WithRequestID, and returns the ID.
+func withRequestID(ctx context.Context) (context.Context, string) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var id string
+	if values := md.Get(RequestIDMetadata); len(values) > 0 {
+		id = strings.TrimSpace(values[0])
+	}
+	if id == "" || len(id) > maxRequestIDLength {
+		id = uuid.NewString()
+	}
+	return logger.WithRequestID(ctx, id), id
+}
+
+func contextUnaryInterceptor(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, id := withRequestID(withActor(ctx))
+	if err := grpc.SetHeader(ctx, metadata.Pairs(RequestIDMetadata, id)); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func contextStreamInterceptor(srv interface{}, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, id := withRequestID(withActor(ss.Context()))
+	if err := ss.SetHeader(metadata.Pairs(RequestIDMetadata, id)); err != nil {
+		return err
+	}
+	return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+}
+
+// contextStream replaces the context of a stream.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// statusError converts a service error to a gRPC status: validation errors
+// become INVALID_ARGUMENT with a BadRequest detail listing the fields, missing
+// rows NOT_FOUND, and anything else INTERNAL with msg.
+func statusError(ctx context.Context, msg string, err error) error {
+	var verr *domain.ValidationError
+	switch {
+	case errors.As(err, &verr):
+		st := status.New(codes.InvalidArgument, verr.Error())
+		br := &errdetails.BadRequest{}
+		for _, fe := range verr.Errors {
+			br.FieldViolations = append(br.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       fe.Field,
+				Description: fe.Message,
+			})
+		}
+		if detailed, derr := st.WithDetails(br); derr == nil {
+			st = detailed
+		}
+		return st.Err()
+	case errors.Is(err, sql.ErrNoRows), errors.Is(err, service.ErrReportNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, domain.ErrInvalidCursor):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, context.Canceled):
+		return status.Error(codes.Canceled, err.Error())
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	logger.ErrorLog(ctx, "%s: %v", msg, err)
+	return status.Error(codes.Internal, msg)
+}
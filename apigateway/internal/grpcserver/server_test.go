@@ -0,0 +1,203 @@
+package grpcserver_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	employeev1 "github.com/locvowork/employee_management_sample/apigateway/api/proto/employee/v1"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/grpcserver"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+)
+
+// stubEmployeeService keeps employees in memory by id.
+type stubEmployeeService struct {
+	service.EmployeeService
+	employees map[int]domain.Employee
+	// actor is the caller of the last Create
+	actor string
+}
+
+func (s *stubEmployeeService) Get(ctx context.Context, id int) (*domain.Employee, error) {
+	e, ok := s.employees[id]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &e, nil
+}
+
+func (s *stubEmployeeService) Create(ctx context.Context, e *domain.Employee) error {
+	s.actor = domain.ActorFrom(ctx)
+	s.employees[e.ID] = *e
+	return nil
+}
+
+func (s *stubEmployeeService) ListPage(ctx context.Context, filter domain.EmployeeFilter) (*domain.EmployeePage, error) {
+	page := &domain.EmployeePage{}
+	for id := filter.AfterID + 1; len(page.Employees) < filter.Limit; id++ {
+		e, ok := s.employees[id]
+		if !ok {
+			return page, nil
+		}
+		page.Employees = append(page.Employees, e)
+	}
+	page.NextCursor = domain.EncodeCursor(page.Employees[len(page.Employees)-1].ID)
+	return page, nil
+}
+
+// stubReportService renders size bytes for the "big" template.
+type stubReportService struct {
+	service.ReportService
+	size int
+	vars map[string]interface{}
+}
+
+func (s *stubReportService) Validate(ctx context.Context, req *domain.ExportRequest) error {
+	req.Normalize()
+	if req.TemplateID != "big" {
+		verr := &domain.ValidationError{}
+		verr.Add("template_id", "unknown template %q", req.TemplateID)
+		return verr
+	}
+	return nil
+}
+
+func (s *stubReportService) Generate(ctx context.Context, req *domain.ExportRequest, w io.Writer) error {
+	s.vars = req.Variables
+	_, err := w.Write(bytes.Repeat([]byte("x"), s.size))
+	return err
+}
+
+func dial(t *testing.T, emps service.EmployeeService, reports service.ReportService) *grpc.ClientConn {
+	lis := bufconn.Listen(1 << 20)
+	srv := grpcserver.New(emps, reports)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func newEmployee(id int) domain.Employee {
+	return domain.Employee{
+		ID: id, FirstName: "Ada", LastName: "Lovelace", Gender: "F",
+		BirthDate: time.Date(1990, 12, 10, 0, 0, 0, 0, time.UTC),
+		HireDate:  time.Date(2015, 3, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestEmployeeService_GetAndList(t *testing.T) {
+	emps := &stubEmployeeService{employees: map[int]domain.Employee{1: newEmployee(1), 2: newEmployee(2), 3: newEmployee(3)}}
+	client := employeev1.NewEmployeeServiceClient(dial(t, emps, &stubReportService{}))
+	ctx := context.Background()
+
+	got, err := client.GetEmployee(ctx, &employeev1.GetEmployeeRequest{Id: 2})
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), got.GetId())
+	assert.Equal(t, "1990-12-10", got.GetBirthDate())
+	assert.Equal(t, "2015-03-01", got.GetHireDate())
+
+	_, err = client.GetEmployee(ctx, &employeev1.GetEmployeeRequest{Id: 9})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	first, err := client.ListEmployees(ctx, &employeev1.ListEmployeesRequest{PageSize: 2})
+	require.NoError(t, err)
+	require.Len(t, first.GetEmployees(), 2)
+	require.NotEmpty(t, first.GetNextPageToken())
+
+	second, err := client.ListEmployees(ctx, &employeev1.ListEmployeesRequest{PageSize: 2, PageToken: first.GetNextPageToken()})
+	require.NoError(t, err)
+	require.Len(t, second.GetEmployees(), 1)
+	assert.Equal(t, int32(3), second.GetEmployees()[0].GetId())
+
+	_, err = client.ListEmployees(ctx, &employeev1.ListEmployeesRequest{PageSize: 2, PageToken: "garbage"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestEmployeeService_CreateValidatesAndRecordsActor(t *testing.T) {
+	emps := &stubEmployeeService{employees: map[int]domain.Employee{}}
+	client := employeev1.NewEmployeeServiceClient(dial(t, emps, &stubReportService{}))
+
+	_, err := client.CreateEmployee(context.Background(), &employeev1.CreateEmployeeRequest{Employee: &employeev1.Employee{
+		Id: 5, FirstName: "Ada", Gender: "X", BirthDate: "1990-12-10", HireDate: "03/01/2015",
+	}})
+	st := status.Convert(err)
+	require.Equal(t, codes.InvalidArgument, st.Code())
+	require.Len(t, st.Details(), 1)
+	br, ok := st.Details()[0].(*errdetails.BadRequest)
+	require.True(t, ok)
+	var fields []string
+	for _, v := range br.GetFieldViolations() {
+		fields = append(fields, v.GetField())
+	}
+	assert.ElementsMatch(t, []string{"hire_date"}, fields)
+	assert.Empty(t, emps.employees)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), grpcserver.ActorMetadata, "payroll-sync", grpcserver.RequestIDMetadata, "req-7")
+	var header metadata.MD
+	created, err := client.CreateEmployee(ctx, &employeev1.CreateEmployeeRequest{Employee: &employeev1.Employee{
+		Id: 5, FirstName: "Ada", LastName: "Lovelace", Gender: "F", BirthDate: "1990-12-10", HireDate: "2015-03-01",
+	}}, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"req-7"}, header.Get(grpcserver.RequestIDMetadata))
+	assert.Equal(t, int32(5), created.GetId())
+	assert.Contains(t, emps.employees, 5)
+	assert.Equal(t, "payroll-sync", emps.actor)
+}
+
+func TestReportService_GenerateReportStreamsChunks(t *testing.T) {
+	reports := &stubReportService{size: 150 << 10}
+	client := employeev1.NewReportServiceClient(dial(t, &stubEmployeeService{}, reports))
+
+	stream, err := client.GenerateReport(context.Background(), &employeev1.GenerateReportRequest{
+		TemplateId: "big",
+		Variables:  map[string]string{"dept_no": "d005"},
+	})
+	require.NoError(t, err)
+
+	var chunks []*employeev1.ReportChunk
+	var data []byte
+	for {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		chunks = append(chunks, chunk)
+		data = append(data, chunk.GetData()...)
+	}
+	require.Len(t, chunks, 3)
+	assert.Equal(t, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", chunks[0].GetContentType())
+	assert.Equal(t, "big.xlsx", chunks[0].GetFileName())
+	assert.Empty(t, chunks[1].GetFileName())
+	assert.Len(t, data, 150<<10)
+	assert.Equal(t, map[string]interface{}{"dept_no": "d005"}, reports.vars)
+}
+
+func TestReportService_GenerateReportRejectsInvalidRequest(t *testing.T) {
+	client := employeev1.NewReportServiceClient(dial(t, &stubEmployeeService{}, &stubReportService{}))
+
+	stream, err := client.GenerateReport(context.Background(), &employeev1.GenerateReportRequest{TemplateId: "nope"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
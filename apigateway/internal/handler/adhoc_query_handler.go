@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/logger"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service/serviceutils"
+)
+
+// AdminTokenHeader carries the admin token of admin-only endpoints.
+const AdminTokenHeader = "X-Admin-Token"
+
+// RequireAdminToken rejects requests without the admin token. An empty token
+// rejects every request.
+func RequireAdminToken(token string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			got := c.Request().Header.Get(AdminTokenHeader)
+			if token == "" || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				return serviceutils.ResponseError(c, http.StatusForbidden, "Admin access required", fmt.Errorf("missing or invalid %s header", AdminTokenHeader))
+			}
+			return next(c)
+		}
+	}
+}
+
+type AdhocQueryHandler struct {
+	svc service.AdhocQueryService
+}
+
+func NewAdhocQueryHandler(svc service.AdhocQueryService) *AdhocQueryHandler {
+	return &AdhocQueryHandler{svc: svc}
+}
+
+// ExportHandler handles POST /admin/query/export
+func (h *AdhocQueryHandler) ExportHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req domain.AdhocQueryRequest
+	if err := serviceutils.Bind(c, &req); err != nil {
+		return respondValidationError(c, "Invalid request body", err)
+	}
+
+	// The workbook is only written once the query has run through, so
+	// rejected queries still get a JSON error
+	c.Response().Header().Set(echo.HeaderContentType, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="query_%s.xlsx"`, time.Now().Format("20060102_150405")))
+	if err := h.svc.Export(ctx, &req, c.Response()); err != nil {
+		logger.ErrorLog(ctx, "Ad-hoc query export failed: %v", err)
+		if c.Response().Committed {
+			return err
+		}
+		c.Response().Header().Del(echo.HeaderContentDisposition)
+		return respondExportError(c, err)
+	}
+	return nil
+}
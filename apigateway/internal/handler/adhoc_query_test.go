@@ -0,0 +1,159 @@
+package handler_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/handler"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// stubAdhocRepo returns fixed rows at a fixed cost and records the query.
+type stubAdhocRepo struct {
+	cost    float64
+	columns []string
+	types   []string
+	rows    [][]interface{}
+	err     error
+	query   string
+	limit   int
+	timeout time.Duration
+}
+
+func (r *stubAdhocRepo) EstimateCost(ctx context.Context, query string, timeout time.Duration) (float64, error) {
+	return r.cost, r.err
+}
+
+func (r *stubAdhocRepo) Query(ctx context.Context, query string, limit int, timeout time.Duration, onColumns func(columns, types []string) error, onRow func([]interface{}) error) error {
+	r.query, r.limit, r.timeout = query, limit, timeout
+	if err := onColumns(r.columns, r.types); err != nil {
+		return err
+	}
+	for i, row := range r.rows {
+		if i == limit {
+			break
+		}
+		if err := onRow(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newAdhocRepo() *stubAdhocRepo {
+	hired := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	return &stubAdhocRepo{
+		cost:    120,
+		columns: []string{"id", "first_name", "hire_date", "id"},
+		types:   []string{"INT4", "VARCHAR", "DATE", "INT4"},
+		rows: [][]interface{}{
+			{int64(10001), "Georgi", hired, int64(1)},
+			{int64(10002), "Bezalel", hired, int64(2)},
+		},
+	}
+}
+
+func postAdhocQuery(t *testing.T, repo *stubAdhocRepo, token, body string) *httptest.ResponseRecorder {
+	e := echo.New()
+	h := handler.NewAdhocQueryHandler(service.NewAdhocQueryService(repo, service.AdhocQueryLimits{MaxRows: 2, MaxCost: 1000, Timeout: 5 * time.Second}))
+	e.POST("/admin/query/export", h.ExportHandler, handler.RequireAdminToken("secret"))
+
+	req := httptest.NewRequest(http.MethodPost, "/admin/query/export", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if token != "" {
+		req.Header.Set(handler.AdminTokenHeader, token)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestAdhocQuery_Export(t *testing.T) {
+	repo := newAdhocRepo()
+
+	rec := postAdhocQuery(t, repo, "secret", `{
+		"sql": "SELECT e.id, e.first_name, e.hire_date, d.id FROM employees.employee e -- join\n JOIN x d ON true;",
+		"title": "Analyst pull",
+		"columns": [{"field": "first_name", "header": "First Name", "width": 30}]
+	}`)
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Header().Get(echo.HeaderContentDisposition), "attachment")
+	assert.Equal(t, "SELECT e.id, e.first_name, e.hire_date, d.id FROM employees.employee e -- join\n JOIN x d ON true", repo.query)
+	assert.Equal(t, 3, repo.limit, "one row over the limit to detect it")
+	assert.Equal(t, 5*time.Second, repo.timeout)
+
+	f, err := excelize.OpenReader(rec.Body)
+	require.NoError(t, err)
+	defer f.Close()
+	rows, err := f.GetRows("Query")
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+	assert.Equal(t, "Analyst pull", rows[0][0])
+	assert.Equal(t, []string{"id", "First Name", "hire_date", "id_2"}, rows[1])
+	assert.Equal(t, []string{"10001", "Georgi", "2020-01-02", "1"}, rows[2])
+}
+
+func TestAdhocQuery_RequiresAdminToken(t *testing.T) {
+	for _, token := range []string{"", "wrong"} {
+		rec := postAdhocQuery(t, newAdhocRepo(), token, `{"sql": "SELECT 1"}`)
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	}
+}
+
+func TestAdhocQuery_Rejects(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		repo func(*stubAdhocRepo)
+		msg  string
+	}{
+		{"empty", ``, nil, "sql: is required"},
+		{"write", `DELETE FROM employees.employee`, nil, "must be a SELECT statement"},
+		{"cte write", `WITH gone AS (DELETE FROM employees.employee RETURNING id) SELECT * FROM gone`, nil, "DELETE is not allowed"},
+		{"select into", `SELECT * INTO backup FROM employees.employee`, nil, "INTO is not allowed"},
+		{"two statements", `SELECT 1; DROP TABLE employees.employee`, nil, "must be a single statement"},
+		{"unterminated", `SELECT 'oops`, nil, "unterminated quote"},
+		{"too costly", `SELECT * FROM employees.employee`, func(r *stubAdhocRepo) { r.cost = 5000 }, "estimated cost 5000 exceeds the limit of 1000"},
+		{"too many rows", `SELECT * FROM employees.employee`, func(r *stubAdhocRepo) { r.rows = append(r.rows, r.rows[0]) }, "returns more than 2 rows"},
+		{"unknown column", `SELECT * FROM employees.employee`, nil, `columns[0].field: \"salary\" is not a result column`},
+		{"database", `SELECT nope FROM employees.employee`, func(r *stubAdhocRepo) {
+			r.err = fmt.Errorf("%w: column \"nope\" does not exist", domain.ErrQueryRejected)
+		}, `column \"nope\" does not exist`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newAdhocRepo()
+			if tt.repo != nil {
+				tt.repo(repo)
+			}
+			columns := ""
+			if tt.name == "unknown column" {
+				columns = `, "columns": [{"field": "salary"}]`
+			}
+			rec := postAdhocQuery(t, repo, "secret", fmt.Sprintf(`{"sql": %q%s}`, tt.sql, columns))
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Contains(t, rec.Body.String(), tt.msg)
+			assert.Empty(t, rec.Header().Get(echo.HeaderContentDisposition))
+		})
+	}
+}
+
+func TestAdhocQuery_IgnoresQuotedKeywords(t *testing.T) {
+	repo := newAdhocRepo()
+
+	rec := postAdhocQuery(t, repo, "secret", `{"sql": "SELECT 'delete; me' AS \"update\" /* DROP */ FROM employees.employee"}`)
+
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+}
@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/logger"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service/serviceutils"
+)
+
+type AnnotationHandler struct {
+	svc service.AnnotationService
+	// reports provides the employee_report layout and rules for imports
+	reports service.ReportService
+}
+
+func NewAnnotationHandler(svc service.AnnotationService, reports service.ReportService) *AnnotationHandler {
+	return &AnnotationHandler{svc: svc, reports: reports}
+}
+
+// ListHandler handles GET /employees/:id/annotations
+func (h *AnnotationHandler) ListHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return serviceutils.ResponseError(c, http.StatusBadRequest, "Invalid employee ID", err)
+	}
+
+	annotations, err := h.svc.List(c.Request().Context(), id)
+	if err != nil {
+		return serviceutils.ResponseError(c, http.StatusInternalServerError, "Failed to list annotations", err)
+	}
+	return serviceutils.ResponseSuccess(c, http.StatusOK, "Annotations listed successfully", annotations)
+}
+
+// ImportHandler handles POST /employees/:id/report/import. The report
+// workbook with the added notes and goals is sent as the "file" field of a
+// multipart form; they are stored pending review.
+func (h *AnnotationHandler) ImportHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return serviceutils.ResponseError(c, http.StatusBadRequest, "Invalid employee ID", err)
+	}
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return serviceutils.ResponseError(c, http.StatusBadRequest, "Missing workbook in form field \"file\"", err)
+	}
+	file, err := fh.Open()
+	if err != nil {
+		return serviceutils.ResponseError(c, http.StatusBadRequest, "Failed to read uploaded workbook", err)
+	}
+	defer file.Close()
+
+	ctx := c.Request().Context()
+	layout, err := h.reports.ImportLayout(ctx, service.EmployeeReportID)
+	if err != nil {
+		logger.ErrorLog(ctx, "Failed to load employee report import layout: %v", err)
+		return serviceutils.ResponseError(c, http.StatusInternalServerError, "Failed to load import layout", err)
+	}
+	result, err := h.svc.Import(ctx, id, file, layout)
+	var ierr *domain.ImportError
+	if errors.As(err, &ierr) {
+		return c.JSON(http.StatusUnprocessableEntity, serviceutils.GenericResponse{
+			Success: false,
+			Message: "Workbook rejected, no changes were applied",
+			Data:    ierr.Errors,
+			Error:   ierr.Error(),
+		})
+	}
+	if err != nil {
+		logger.ErrorLog(ctx, "Failed to import annotations: %v", err)
+		return serviceutils.ResponseError(c, http.StatusInternalServerError, "Failed to import annotations", err)
+	}
+
+	return serviceutils.ResponseSuccess(c, http.StatusOK, "Annotations submitted for review", result)
+}
+
+// ReviewHandler handles PUT /annotations/:id/status with {"status": "approved"|"rejected"}
+func (h *AnnotationHandler) ReviewHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return serviceutils.ResponseError(c, http.StatusBadRequest, "Invalid annotation ID", err)
+	}
+	var req statusRequest
+	if err := serviceutils.Bind(c, &req); err != nil {
+		return respondValidationError(c, "Invalid request body", err)
+	}
+
+	err = h.svc.Review(c.Request().Context(), id, req.Status)
+	switch {
+	case err == nil:
+		return serviceutils.ResponseSuccess(c, http.StatusOK, "Annotation "+req.Status, nil)
+	case errors.Is(err, sql.ErrNoRows):
+		return serviceutils.ResponseAppError(c, "Annotation not found", err)
+	case errors.Is(err, service.ErrAnnotationNotPending):
+		return serviceutils.ResponseAppError(c, "Annotation was already reviewed", err)
+	}
+	return serviceutils.ResponseAppError(c, "Failed to review annotation", err)
+}
@@ -0,0 +1,242 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/handler"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/exceltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// stubReportRepo adds the report queries to stubEmployeeRepo.
+type stubReportRepo struct {
+	*stubEmployeeRepo
+}
+
+func (r stubReportRepo) GetCurrentSalary(ctx context.Context, empID int) (*domain.Salary, error) {
+	return &domain.Salary{EmployeeID: empID, Salary: 60117}, nil
+}
+
+func (r stubReportRepo) GetTitle(ctx context.Context, empID int) (*domain.Title, error) {
+	return &domain.Title{EmpNo: empID, Title: "Senior Engineer"}, nil
+}
+
+func (r stubReportRepo) GetDepartmentHistory(ctx context.Context, empID int) ([]domain.DeptEmp, error) {
+	return []domain.DeptEmp{{EmpNo: empID, DeptNo: "d005",
+		FromDate: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), ToDate: time.Date(9999, 1, 1, 0, 0, 0, 0, time.UTC)}}, nil
+}
+
+// stubAnnotationRepo keeps annotations in memory.
+type stubAnnotationRepo struct {
+	annotations []domain.Annotation
+}
+
+func (r *stubAnnotationRepo) CreateAnnotations(ctx context.Context, annotations []domain.Annotation) error {
+	for i := range annotations {
+		annotations[i].ID = len(r.annotations) + 1
+		annotations[i].CreatedAt = time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+		r.annotations = append(r.annotations, annotations[i])
+	}
+	return nil
+}
+
+func (r *stubAnnotationRepo) GetAnnotation(ctx context.Context, id int) (*domain.Annotation, error) {
+	for i := range r.annotations {
+		if r.annotations[i].ID == id {
+			a := r.annotations[i]
+			return &a, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *stubAnnotationRepo) ListAnnotations(ctx context.Context, empNo int) ([]domain.Annotation, error) {
+	var annotations []domain.Annotation
+	for _, a := range r.annotations {
+		if a.EmpNo == empNo {
+			annotations = append(annotations, a)
+		}
+	}
+	return annotations, nil
+}
+
+func (r *stubAnnotationRepo) SetAnnotationStatus(ctx context.Context, id int, status string) error {
+	for i := range r.annotations {
+		if r.annotations[i].ID == id {
+			r.annotations[i].Status = status
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+type annotationFixture struct {
+	repo    *stubAnnotationRepo
+	reports *handler.ReportHandler
+	h       *handler.AnnotationHandler
+}
+
+func newAnnotationFixture() *annotationFixture {
+	repo := &stubAnnotationRepo{}
+	annSvc := service.NewAnnotationService(repo, stubTx{})
+	empSvc := service.NewEmployeeService(stubReportRepo{&stubEmployeeRepo{employees: testEmployees()}}, stubTx{})
+	reportSvc := service.NewReportService(nil)
+	reportSvc.Register(service.NewEmployeeReport(empSvc, annSvc, "../../templates/employee_report.yaml", editChecksumKey))
+	return &annotationFixture{
+		repo:    repo,
+		reports: handler.NewReportHandler(reportSvc),
+		h:       handler.NewAnnotationHandler(annSvc, reportSvc),
+	}
+}
+
+func (fx *annotationFixture) export(t *testing.T, empNo int) []byte {
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+	c.SetParamNames("id")
+	c.SetParamValues(strconv.Itoa(empNo))
+	require.NoError(t, fx.reports.EmployeeReportHandler(c))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	return rec.Body.Bytes()
+}
+
+func (fx *annotationFixture) upload(t *testing.T, empNo int, workbook []byte) (*httptest.ResponseRecorder, map[string]interface{}) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", "report.xlsx")
+	require.NoError(t, err)
+	_, err = fw.Write(workbook)
+	require.NoError(t, err)
+	require.NoError(t, mw.Close())
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/", &body)
+	req.Header.Set(echo.HeaderContentType, mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(strconv.Itoa(empNo))
+	require.NoError(t, fx.h.ImportHandler(c))
+
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return rec, resp
+}
+
+func (fx *annotationFixture) review(t *testing.T, id int, status string) *httptest.ResponseRecorder {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPut, "/", strings.NewReader(fmt.Sprintf(`{"status": %q}`, status)))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(strconv.Itoa(id))
+	require.NoError(t, fx.h.ReviewHandler(c))
+	return rec
+}
+
+// annotate fills the blank annotation rows of the Report sheet in order,
+// each given as {kind, text}.
+func annotate(t *testing.T, workbook []byte, rows ...[2]string) []byte {
+	f, err := excelize.OpenReader(bytes.NewReader(workbook))
+	require.NoError(t, err)
+	defer f.Close()
+	all, err := f.GetRows("Report")
+	require.NoError(t, err)
+	first := 0
+	for i, row := range all {
+		if len(row) == 3 && row[2] == "Employee No" {
+			first = i + 2 // 1-based row after the header
+		}
+	}
+	require.NotZero(t, first, "annotation header not found")
+	for i, r := range rows {
+		require.NoError(t, f.SetCellValue("Report", fmt.Sprintf("A%d", first+i), r[0]))
+		require.NoError(t, f.SetCellValue("Report", fmt.Sprintf("B%d", first+i), r[1]))
+	}
+	buf, err := f.WriteToBuffer()
+	require.NoError(t, err)
+	return buf.Bytes()
+}
+
+func TestEmployeeReport_Export(t *testing.T) {
+	fx := newAnnotationFixture()
+	fx.repo.annotations = []domain.Annotation{{ID: 1, EmpNo: 10001, Kind: domain.AnnotationGoal, Text: "Lead the migration",
+		Status: domain.AnnotationApproved, CreatedAt: time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)}}
+
+	workbook := fx.export(t, 10001)
+
+	exceltest.AssertSheetProtected(t, workbook, "Report")
+	f := exceltest.Open(t, workbook)
+	rows, err := f.GetRows("Report")
+	require.NoError(t, err)
+	text := fmt.Sprint(rows)
+	assert.Contains(t, text, "[Name Georgi Facello]")
+	assert.Contains(t, text, "[Hire Date 2020-01-02]")
+	assert.Contains(t, text, "[Title Senior Engineer]")
+	assert.Contains(t, text, "[d005 2020-01-02 9999-01-01]")
+	assert.Contains(t, text, "[goal Lead the migration approved 2024-02-01]")
+}
+
+func TestAnnotationImport_StoresPending(t *testing.T) {
+	fx := newAnnotationFixture()
+	workbook := annotate(t, fx.export(t, 10001), [2]string{"goal", "Present at the all-hands"}, [2]string{"note", "Mentors two juniors"})
+
+	rec, resp := fx.upload(t, 10001, workbook)
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, float64(2), resp["Data"].(map[string]interface{})["updated"])
+	require.Len(t, fx.repo.annotations, 2)
+	assert.Equal(t, domain.Annotation{ID: 1, EmpNo: 10001, Kind: domain.AnnotationGoal, Text: "Present at the all-hands",
+		Status: domain.AnnotationPending, CreatedAt: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)}, fx.repo.annotations[0])
+
+	// Reviewed annotations show up read-only in the next export
+	assert.Equal(t, http.StatusOK, fx.review(t, 1, domain.AnnotationApproved).Code)
+	assert.Equal(t, http.StatusConflict, fx.review(t, 1, domain.AnnotationRejected).Code)
+	assert.Equal(t, http.StatusNotFound, fx.review(t, 9, domain.AnnotationRejected).Code)
+	assert.Equal(t, http.StatusBadRequest, fx.review(t, 2, "maybe").Code)
+	workbook = fx.export(t, 10001)
+	exceltest.AssertCellValue(t, workbook, "Report", "C15", "approved")
+	exceltest.AssertCellValue(t, workbook, "Report", "C16", "pending")
+}
+
+func TestAnnotationImport_RejectsInvalidRows(t *testing.T) {
+	fx := newAnnotationFixture()
+	workbook := annotate(t, fx.export(t, 10001), [2]string{"idea", "Learn Go"}, [2]string{"note", ""})
+
+	rec, resp := fx.upload(t, 10001, workbook)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Empty(t, fx.repo.annotations)
+	errs := resp["Data"].([]interface{})
+	require.Len(t, errs, 2)
+	assert.Equal(t, "Kind must be note or goal", errs[0].(map[string]interface{})["message"])
+	assert.Equal(t, "is required", errs[1].(map[string]interface{})["message"])
+}
+
+func TestAnnotationImport_RejectsOtherEmployee(t *testing.T) {
+	fx := newAnnotationFixture()
+	workbook := annotate(t, fx.export(t, 10001), [2]string{"note", "Mentors two juniors"})
+
+	rec, resp := fx.upload(t, 10002, workbook)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Empty(t, fx.repo.annotations)
+	assert.Contains(t, resp["Error"], `workbook belongs to employee "10001"`)
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service/serviceutils"
+)
+
+type APIKeyHandler struct {
+	svc service.APIKeyService
+}
+
+func NewAPIKeyHandler(svc service.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{svc: svc}
+}
+
+type apiKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// issuedAPIKey is the response of CreateHandler, the only time the key is shown.
+type issuedAPIKey struct {
+	domain.APIKey
+	Key string `json:"key"`
+}
+
+// CreateHandler handles POST /admin/api-keys with {"name", "scopes", "expires_at"}
+func (h *APIKeyHandler) CreateHandler(c echo.Context) error {
+	var req apiKeyRequest
+	if err := serviceutils.Bind(c, &req); err != nil {
+		return respondValidationError(c, "Invalid request body", err)
+	}
+
+	k := domain.APIKey{Name: req.Name, Scopes: req.Scopes, ExpiresAt: req.ExpiresAt}
+	key, err := h.svc.Create(c.Request().Context(), &k)
+	if err != nil {
+		return respondValidationError(c, "Failed to create API key", err)
+	}
+	return serviceutils.ResponseSuccess(c, http.StatusCreated, "API key created, store it now as it is not shown again", issuedAPIKey{APIKey: k, Key: key})
+}
+
+// ListHandler handles GET /admin/api-keys
+func (h *APIKeyHandler) ListHandler(c echo.Context) error {
+	keys, err := h.svc.List(c.Request().Context())
+	if err != nil {
+		return serviceutils.ResponseError(c, http.StatusInternalServerError, "Failed to list API keys", err)
+	}
+	return serviceutils.ResponseSuccess(c, http.StatusOK, "API keys listed successfully", keys)
+}
+
+// RevokeHandler handles DELETE /admin/api-keys/:id
+func (h *APIKeyHandler) RevokeHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return serviceutils.ResponseError(c, http.StatusBadRequest, "Invalid API key ID", err)
+	}
+	if err := h.svc.Revoke(c.Request().Context(), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return serviceutils.ResponseError(c, http.StatusNotFound, "API key not found or already revoked", err)
+		}
+		return serviceutils.ResponseError(c, http.StatusInternalServerError, "Failed to revoke API key", err)
+	}
+	return serviceutils.ResponseSuccess(c, http.StatusOK, "API key revoked successfully", nil)
+}
@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/logger"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service/serviceutils"
+)
+
+type AttendanceHandler struct {
+	svc service.AttendanceService
+	// reports provides the attendance_timesheet layout and rules for imports
+	reports service.ReportService
+}
+
+func NewAttendanceHandler(svc service.AttendanceService, reports service.ReportService) *AttendanceHandler {
+	return &AttendanceHandler{svc: svc, reports: reports}
+}
+
+// checkRequest is the body of the check-in and check-out endpoints
+type checkRequest struct {
+	EmpNo int `json:"emp_no"`
+}
+
+func (r *checkRequest) Validate() *domain.ValidationError {
+	verr := &domain.ValidationError{}
+	if r.EmpNo <= 0 {
+		verr.Add("emp_no", "is required")
+	}
+	return verr
+}
+
+// leaveRequest is the body of POST /leaves, dates are YYYY-MM-DD
+type leaveRequest struct {
+	EmpNo     int    `json:"emp_no"`
+	FromDate  string `json:"from_date"`
+	ToDate    string `json:"to_date"`
+	LeaveType string `json:"leave_type"`
+	Reason    string `json:"reason"`
+}
+
+// statusRequest is the body of the review endpoints
+type statusRequest struct {
+	Status string `json:"status"`
+}
+
+// CheckInHandler handles POST /attendance/check-in
+func (h *AttendanceHandler) CheckInHandler(c echo.Context) error {
+	var req checkRequest
+	if err := serviceutils.BindAndValidate(c, &req); err != nil {
+		return respondValidationError(c, "Invalid request body", err)
+	}
+	if err := h.svc.CheckIn(c.Request().Context(), req.EmpNo); err != nil {
+		return serviceutils.ResponseError(c, http.StatusInternalServerError, "Failed to check in", err)
+	}
+	return serviceutils.ResponseSuccess(c, http.StatusOK, "Checked in successfully", nil)
+}
+
+// CheckOutHandler handles POST /attendance/check-out
+func (h *AttendanceHandler) CheckOutHandler(c echo.Context) error {
+	var req checkRequest
+	if err := serviceutils.BindAndValidate(c, &req); err != nil {
+		return respondValidationError(c, "Invalid request body", err)
+	}
+	if err := h.svc.CheckOut(c.Request().Context(), req.EmpNo); err != nil {
+		return serviceutils.ResponseAppError(c, "Failed to check out", err)
+	}
+	return serviceutils.ResponseSuccess(c, http.StatusOK, "Checked out successfully", nil)
+}
+
+// ListAttendanceHandler handles GET /attendance?emp_no=&dept_no=&from=&to=
+func (h *AttendanceHandler) ListAttendanceHandler(c echo.Context) error {
+	filter, err := attendanceFilter(c)
+	if err != nil {
+		return serviceutils.ResponseError(c, http.StatusBadRequest, "Invalid query parameters", err)
+	}
+	days, err := h.svc.ListAttendance(c.Request().Context(), filter)
+	if err != nil {
+		return serviceutils.ResponseError(c, http.StatusInternalServerError, "Failed to list attendance", err)
+	}
+	return serviceutils.ResponseSuccess(c, http.StatusOK, "Attendance retrieved successfully", days)
+}
+
+// CreateLeaveHandler handles POST /leaves
+func (h *AttendanceHandler) CreateLeaveHandler(c echo.Context) error {
+	var req leaveRequest
+	if err := serviceutils.Bind(c, &req); err != nil {
+		return respondValidationError(c, "Invalid request body", err)
+	}
+
+	l := domain.LeaveRequest{EmpNo: req.EmpNo, LeaveType: req.LeaveType, Reason: req.Reason}
+	verr := &domain.ValidationError{}
+	l.FromDate = parseLeaveDate(verr, "from_date", req.FromDate)
+	l.ToDate = parseLeaveDate(verr, "to_date", req.ToDate)
+	if err := verr.ErrOrNil(); err != nil {
+		return respondAttendanceError(c, "Failed to create leave request", err)
+	}
+
+	if err := h.svc.RequestLeave(c.Request().Context(), &l); err != nil {
+		return respondAttendanceError(c, "Failed to create leave request", err)
+	}
+	return serviceutils.ResponseSuccess(c, http.StatusCreated, "Leave request created successfully", l)
+}
+
+// ListLeavesHandler handles GET /leaves?emp_no=&dept_no=&status=&from=&to=
+func (h *AttendanceHandler) ListLeavesHandler(c echo.Context) error {
+	filter, err := attendanceFilter(c)
+	if err != nil {
+		return serviceutils.ResponseError(c, http.StatusBadRequest, "Invalid query parameters", err)
+	}
+	filter.Status = c.QueryParam("status")
+	leaves, err := h.svc.ListLeaves(c.Request().Context(), filter)
+	if err != nil {
+		return serviceutils.ResponseError(c, http.StatusInternalServerError, "Failed to list leave requests", err)
+	}
+	return serviceutils.ResponseSuccess(c, http.StatusOK, "Leave requests retrieved successfully", leaves)
+}
+
+// ReviewLeaveHandler handles PUT /leaves/:id/status with {"status": "approved"|"rejected"}
+func (h *AttendanceHandler) ReviewLeaveHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return serviceutils.ResponseError(c, http.StatusBadRequest, "Invalid leave request ID", err)
+	}
+	var req statusRequest
+	if err := serviceutils.Bind(c, &req); err != nil {
+		return respondValidationError(c, "Invalid request body", err)
+	}
+
+	if err := h.svc.ReviewLeave(c.Request().Context(), id, req.Status); err != nil {
+		return respondAttendanceError(c, "Failed to review leave request", err)
+	}
+	return serviceutils.ResponseSuccess(c, http.StatusOK, "Leave request "+req.Status, nil)
+}
+
+// ImportTimesheetHandler handles POST /attendance/timesheet/import. The
+// edited workbook is sent as the "file" field of a multipart form.
+func (h *AttendanceHandler) ImportTimesheetHandler(c echo.Context) error {
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return serviceutils.ResponseError(c, http.StatusBadRequest, "Missing workbook in form field \"file\"", err)
+	}
+	file, err := fh.Open()
+	if err != nil {
+		return serviceutils.ResponseError(c, http.StatusBadRequest, "Failed to read uploaded workbook", err)
+	}
+	defer file.Close()
+
+	ctx := c.Request().Context()
+	layout, err := h.reports.ImportLayout(ctx, service.AttendanceTimesheetReportID)
+	if err != nil {
+		logger.ErrorLog(ctx, "Failed to load timesheet import layout: %v", err)
+		return serviceutils.ResponseError(c, http.StatusInternalServerError, "Failed to load import layout", err)
+	}
+	result, err := h.svc.ImportTimesheet(ctx, file, layout)
+	var ierr *domain.ImportError
+	if errors.As(err, &ierr) {
+		return c.JSON(http.StatusUnprocessableEntity, serviceutils.GenericResponse{
+			Success: false,
+			Message: "Workbook rejected, no changes were applied",
+			Data:    ierr.Errors,
+			Error:   ierr.Error(),
+		})
+	}
+	if err != nil {
+		logger.ErrorLog(ctx, "Failed to import timesheet: %v", err)
+		return serviceutils.ResponseError(c, http.StatusInternalServerError, "Failed to import timesheet", err)
+	}
+
+	return serviceutils.ResponseSuccess(c, http.StatusOK, "Timesheet imported successfully", result)
+}
+
+// parseLeaveDate parses a YYYY-MM-DD field, empty values are left to
+// LeaveRequest.Validate.
+func parseLeaveDate(verr *domain.ValidationError, field, raw string) time.Time {
+	if raw == "" {
+		return time.Time{}
+	}
+	d, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		verr.Add(field, "must be a date (YYYY-MM-DD), got %q", raw)
+	}
+	return d
+}
+
+// attendanceFilter reads the emp_no, dept_no, from and to query parameters.
+func attendanceFilter(c echo.Context) (domain.AttendanceFilter, error) {
+	filter := domain.AttendanceFilter{DeptNo: c.QueryParam("dept_no")}
+	if raw := c.QueryParam("emp_no"); raw != "" {
+		empNo, err := strconv.Atoi(raw)
+		if err != nil {
+			return filter, err
+		}
+		filter.EmpNo = empNo
+	}
+	var err error
+	if raw := c.QueryParam("from"); raw != "" {
+		if filter.From, err = time.Parse("2006-01-02", raw); err != nil {
+			return filter, err
+		}
+	}
+	if raw := c.QueryParam("to"); raw != "" {
+		if filter.To, err = time.Parse("2006-01-02", raw); err != nil {
+			return filter, err
+		}
+	}
+	return filter, nil
+}
+
+// respondAttendanceError answers err with the status of its kind, naming
+// unknown and already reviewed leave requests.
+func respondAttendanceError(c echo.Context, message string, err error) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		message = "Leave request not found"
+	case errors.Is(err, service.ErrLeaveNotPending):
+		message = "Leave request was already reviewed"
+	}
+	return serviceutils.ResponseAppError(c, message, err)
+}
@@ -0,0 +1,285 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/handler"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// stubAttendanceRepo serves fixed attendance days and leave requests and
+// records what is saved.
+type stubAttendanceRepo struct {
+	domain.AttendanceRepository
+	days     []domain.Attendance
+	leaves   []domain.LeaveRequest
+	saved    []domain.Attendance
+	statuses map[int]string
+}
+
+func (r *stubAttendanceRepo) ListAttendance(ctx context.Context, filter domain.AttendanceFilter) ([]domain.Attendance, error) {
+	return r.days, nil
+}
+
+func (r *stubAttendanceRepo) SaveAttendance(ctx context.Context, days []domain.Attendance) error {
+	r.saved = days
+	return nil
+}
+
+func (r *stubAttendanceRepo) CreateLeave(ctx context.Context, l *domain.LeaveRequest) error {
+	l.ID = len(r.leaves) + 1
+	r.leaves = append(r.leaves, *l)
+	return nil
+}
+
+func (r *stubAttendanceRepo) GetLeave(ctx context.Context, id int) (*domain.LeaveRequest, error) {
+	for i := range r.leaves {
+		if r.leaves[i].ID == id {
+			return &r.leaves[i], nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *stubAttendanceRepo) SetLeaveStatus(ctx context.Context, id int, status string) error {
+	if r.statuses == nil {
+		r.statuses = make(map[int]string)
+	}
+	r.statuses[id] = status
+	return nil
+}
+
+// openPeriod is the first day of the current month, the first editable day.
+func openPeriod() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func newTimesheetReports(attSvc service.AttendanceService) service.ReportService {
+	reportSvc := service.NewReportService(nil)
+	reportSvc.Register(service.NewAttendanceTimesheetReport(attSvc, "../../templates/attendance_timesheet.yaml", editChecksumKey))
+	return reportSvc
+}
+
+// exportTimesheet exports the last two days of the previous month (closed,
+// rows 3-6) and the first two of the current one (open, rows 10-13) for
+// the two test employees.
+func exportTimesheet(t *testing.T, attSvc service.AttendanceService) []byte {
+	open := openPeriod()
+	var buf bytes.Buffer
+	require.NoError(t, newTimesheetReports(attSvc).Generate(context.Background(), &domain.ExportRequest{
+		TemplateID: service.AttendanceTimesheetReportID,
+		Variables: map[string]interface{}{
+			"dept_no": "d001",
+			"from":    open.AddDate(0, 0, -2).Format("2006-01-02"),
+			"to":      open.AddDate(0, 0, 1).Format("2006-01-02"),
+		},
+	}, &buf))
+	return buf.Bytes()
+}
+
+func editTimesheet(t *testing.T, workbook []byte, cells map[string]interface{}) []byte {
+	f, err := excelize.OpenReader(bytes.NewReader(workbook))
+	require.NoError(t, err)
+	defer f.Close()
+	for cell, val := range cells {
+		require.NoError(t, f.SetCellValue("Timesheet", cell, val))
+	}
+	buf, err := f.WriteToBuffer()
+	require.NoError(t, err)
+	return buf.Bytes()
+}
+
+func postTimesheet(t *testing.T, h *handler.AttendanceHandler, workbook []byte) (*httptest.ResponseRecorder, map[string]interface{}) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", "timesheet.xlsx")
+	require.NoError(t, err)
+	_, err = fw.Write(workbook)
+	require.NoError(t, err)
+	require.NoError(t, mw.Close())
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/attendance/timesheet/import", &body)
+	req.Header.Set(echo.HeaderContentType, mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	require.NoError(t, h.ImportTimesheetHandler(e.NewContext(req, rec)))
+
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return rec, resp
+}
+
+func newAttendanceFixture(days ...domain.Attendance) (*stubAttendanceRepo, service.AttendanceService, *handler.AttendanceHandler) {
+	repo := &stubAttendanceRepo{days: days}
+	attSvc := service.NewAttendanceService(repo, &stubEmployeeRepo{employees: testEmployees()}, stubTx{})
+	return repo, attSvc, handler.NewAttendanceHandler(attSvc, newTimesheetReports(attSvc))
+}
+
+func TestTimesheetExport_LocksClosedPeriods(t *testing.T) {
+	open := openPeriod()
+	checkIn := open.Add(9 * time.Hour)
+	_, attSvc, _ := newAttendanceFixture(domain.Attendance{EmpNo: 10001, WorkDate: open, CheckIn: &checkIn})
+
+	f, err := excelize.OpenReader(bytes.NewReader(exportTimesheet(t, attSvc)))
+	require.NoError(t, err)
+	defer f.Close()
+
+	rows, err := f.GetRows("Timesheet")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10001", "Georgi Facello", open.AddDate(0, 0, -2).Format("2006-01-02")}, rows[2])
+	assert.Equal(t, []string{"10001", "Georgi Facello", open.Format("2006-01-02"), "09:00"}, rows[9])
+
+	for cell, locked := range map[string]bool{"D3": true, "C10": true, "D10": false, "F13": false} {
+		styleID, err := f.GetCellStyle("Timesheet", cell)
+		require.NoError(t, err)
+		style, err := f.GetStyle(styleID)
+		require.NoError(t, err)
+		require.NotNil(t, style.Protection, cell)
+		assert.Equal(t, locked, style.Protection.Locked, cell)
+	}
+}
+
+func TestTimesheetImport_AppliesOpenPeriod(t *testing.T) {
+	open := openPeriod()
+	checkIn := open.Add(9 * time.Hour)
+	repo, attSvc, h := newAttendanceFixture(domain.Attendance{EmpNo: 10001, WorkDate: open, CheckIn: &checkIn})
+	workbook := editTimesheet(t, exportTimesheet(t, attSvc), map[string]interface{}{
+		"D10": "",
+		"D11": "08:30",
+		"E11": "5:15 pm",
+		"F12": "sick",
+		"D13": 0.375, // a time Excel recognized
+	})
+
+	rec, resp := postTimesheet(t, h, workbook)
+
+	require.Equal(t, http.StatusOK, rec.Code, resp)
+	require.Len(t, repo.saved, 4)
+	day2 := open.AddDate(0, 0, 1)
+	assert.Equal(t, domain.Attendance{EmpNo: 10001, WorkDate: open}, repo.saved[0], "a cleared check-in")
+	assert.Equal(t, day2.Add(8*time.Hour+30*time.Minute), *repo.saved[1].CheckIn)
+	assert.Equal(t, day2.Add(17*time.Hour+15*time.Minute), *repo.saved[1].CheckOut)
+	assert.Equal(t, domain.Attendance{EmpNo: 10002, WorkDate: open, LeaveType: domain.LeaveSick}, repo.saved[2])
+	assert.Equal(t, day2.Add(9*time.Hour), *repo.saved[3].CheckIn)
+}
+
+func TestTimesheetImport_RejectsClosedPeriodEdits(t *testing.T) {
+	repo, attSvc, h := newAttendanceFixture()
+	workbook := editTimesheet(t, exportTimesheet(t, attSvc), map[string]interface{}{"D3": "09:00"})
+
+	rec, resp := postTimesheet(t, h, workbook)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Contains(t, resp["Error"], `read-only cells of section "closed" were modified`)
+	assert.Nil(t, repo.saved)
+}
+
+func TestTimesheetImport_RejectsInvalidCells(t *testing.T) {
+	repo, attSvc, h := newAttendanceFixture()
+	workbook := editTimesheet(t, exportTimesheet(t, attSvc), map[string]interface{}{
+		"D10": "25:00",
+		"D11": "08:00",
+		"E11": "07:00",
+		"E12": "17:00",
+		"F13": "holiday",
+	})
+
+	rec, resp := postTimesheet(t, h, workbook)
+
+	require.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Nil(t, repo.saved)
+	var cells []string
+	for _, e := range resp["Data"].([]interface{}) {
+		cells = append(cells, e.(map[string]interface{})["cell"].(string))
+	}
+	assert.Equal(t, []string{"D10", "E11", "D12", "F13"}, cells)
+}
+
+func TestTimesheetExport_RejectsLongRanges(t *testing.T) {
+	_, attSvc, _ := newAttendanceFixture()
+	h := handler.NewReportHandler(newTimesheetReports(attSvc))
+
+	rec := postGenerate(t, h, `{
+		"template_id": "attendance_timesheet",
+		"variables": {"dept_no": "d001", "from": "2024-01-01", "to": "2024-12-31"}
+	}`)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "timesheets cover at most 93 days")
+}
+
+func TestLeaveHandlers(t *testing.T) {
+	repo, _, h := newAttendanceFixture()
+	e := echo.New()
+	call := func(method, path, body string, fn echo.HandlerFunc, params ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		if len(params) > 0 {
+			c.SetParamNames("id")
+			c.SetParamValues(params...)
+		}
+		require.NoError(t, fn(c))
+		return rec
+	}
+
+	rec := call(http.MethodPost, "/leaves", `{"emp_no": 10001, "from_date": "2024-03-05", "to_date": "2024-03-01", "leave_type": "holiday"}`, h.CreateLeaveHandler)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "to_date: must not be before from_date")
+	assert.Contains(t, rec.Body.String(), "leave_type: must be one of")
+
+	rec = call(http.MethodPost, "/leaves", `{"emp_no": 10001, "from_date": "2024-03-01", "to_date": "2024-03-05", "leave_type": "annual"}`, h.CreateLeaveHandler)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.Len(t, repo.leaves, 1)
+	assert.Equal(t, domain.LeavePending, repo.leaves[0].Status)
+
+	rec = call(http.MethodPut, "/leaves/1/status", `{"status": "maybe"}`, h.ReviewLeaveHandler, "1")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	rec = call(http.MethodPut, "/leaves/2/status", `{"status": "approved"}`, h.ReviewLeaveHandler, "2")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = call(http.MethodPut, "/leaves/1/status", `{"status": "approved"}`, h.ReviewLeaveHandler, "1")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, map[int]string{1: domain.LeaveApproved}, repo.statuses)
+
+	repo.leaves[0].Status = domain.LeaveApproved
+	rec = call(http.MethodPut, "/leaves/1/status", `{"status": "rejected"}`, h.ReviewLeaveHandler, "1")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+}
+
+func TestCheckIn_RejectsInvalidBody(t *testing.T) {
+	_, _, h := newAttendanceFixture()
+
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"missing emp_no", `{}`, []string{"emp_no"}},
+		{"mistyped emp_no", `{"emp_no":"10001"}`, []string{"emp_no"}},
+		{"malformed json", `{"emp_no":`, []string{"body"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, fields := callJSON(t, h.CheckInHandler, http.MethodPost, tt.body)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.Equal(t, tt.want, fields)
+		})
+	}
+}
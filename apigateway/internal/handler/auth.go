@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service/serviceutils"
+)
+
+// APIKeyHeader carries the API key of scheduled jobs and external systems.
+const APIKeyHeader = "X-API-Key"
+
+// authCallerKey is the echo context key of the authenticated caller.
+const authCallerKey = "auth.caller"
+
+// errNoCredentials is returned by authenticate for requests carrying neither
+// a bearer token nor an API key.
+var errNoCredentials = errors.New("missing bearer token or " + APIKeyHeader + " header")
+
+// principal is the authenticated caller of a request.
+type principal struct {
+	actor  string
+	scopes []string
+}
+
+func (p *principal) hasScope(scope string) bool {
+	for _, s := range p.scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator checks the credentials of report requests, either an HS256
+// JWT in the Authorization header or an API key in APIKeyHeader.
+type Authenticator struct {
+	keys      service.APIKeyService
+	jwtSecret []byte
+	required  bool
+}
+
+// NewAuthenticator creates an Authenticator. An empty jwtSecret rejects
+// bearer tokens. Unless required, requests without credentials are let
+// through; invalid credentials are always rejected.
+func NewAuthenticator(keys service.APIKeyService, jwtSecret string, required bool) *Authenticator {
+	return &Authenticator{keys: keys, jwtSecret: []byte(jwtSecret), required: required}
+}
+
+// RequireScope rejects requests whose credentials do not grant scope with
+// 403, and invalid credentials with 401. The authenticated caller replaces
+// the ActorHeader as the actor of the request.
+func (a *Authenticator) RequireScope(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			p, err := a.authenticate(c)
+			if errors.Is(err, errNoCredentials) && !a.required {
+				return next(c)
+			}
+			if err != nil {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, "Bearer")
+				return serviceutils.ResponseError(c, http.StatusUnauthorized, "Authentication required", err)
+			}
+			if !p.hasScope(scope) {
+				return serviceutils.ResponseError(c, http.StatusForbidden, "Insufficient scope", fmt.Errorf("credentials do not grant %s", scope))
+			}
+
+			actor := p.actor
+			if len(actor) > domain.MaxActorLength {
+				actor = actor[:domain.MaxActorLength]
+			}
+			c.Set(authCallerKey, p.actor)
+			c.SetRequest(c.Request().WithContext(domain.WithActor(c.Request().Context(), actor)))
+			return next(c)
+		}
+	}
+}
+
+func (a *Authenticator) authenticate(c echo.Context) (*principal, error) {
+	if auth := c.Request().Header.Get(echo.HeaderAuthorization); auth != "" {
+		token := strings.TrimSpace(strings.TrimPrefix(auth, "Bearer "))
+		if token == auth {
+			return nil, errors.New("authorization header must be a bearer token")
+		}
+		return a.parseJWT(token)
+	}
+	if key := c.Request().Header.Get(APIKeyHeader); key != "" {
+		k, err := a.keys.Authenticate(c.Request().Context(), key)
+		if err != nil {
+			return nil, err
+		}
+		return &principal{actor: "api-key:" + k.Name, scopes: k.Scopes}, nil
+	}
+	return nil, errNoCredentials
+}
+
+// parseJWT verifies an HS256 token carrying the caller in "sub" and space
+// separated scopes in "scope". "exp" and "nbf" are checked when present.
+func (a *Authenticator) parseJWT(raw string) (*principal, error) {
+	if len(a.jwtSecret) == 0 {
+		return nil, errors.New("bearer tokens are not accepted")
+	}
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(t *jwt.Token) (interface{}, error) {
+		if t.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
+		}
+		return a.jwtSecret, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid bearer token: %w", err)
+	}
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return nil, errors.New("invalid bearer token: missing sub claim")
+	}
+	scope, _ := claims["scope"].(string)
+	return &principal{actor: sub, scopes: strings.Fields(scope)}, nil
+}
@@ -0,0 +1,177 @@
+package handler_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/handler"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubAPIKeyRepo keeps API keys in memory by hash.
+type stubAPIKeyRepo struct {
+	keys   map[string]*domain.APIKey
+	nextID int
+}
+
+func (r *stubAPIKeyRepo) CreateAPIKey(ctx context.Context, k *domain.APIKey, hash string) error {
+	if r.keys == nil {
+		r.keys = make(map[string]*domain.APIKey)
+	}
+	r.nextID++
+	k.ID, k.CreatedAt = r.nextID, time.Now()
+	stored := *k
+	r.keys[hash] = &stored
+	return nil
+}
+
+func (r *stubAPIKeyRepo) FindAPIKey(ctx context.Context, hash string) (*domain.APIKey, error) {
+	k, ok := r.keys[hash]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	found := *k
+	return &found, nil
+}
+
+func (r *stubAPIKeyRepo) ListAPIKeys(ctx context.Context) ([]domain.APIKey, error) {
+	var keys []domain.APIKey
+	for _, k := range r.keys {
+		keys = append(keys, *k)
+	}
+	return keys, nil
+}
+
+func (r *stubAPIKeyRepo) RevokeAPIKey(ctx context.Context, id int, at time.Time) error {
+	for _, k := range r.keys {
+		if k.ID == id && k.RevokedAt == nil {
+			k.RevokedAt = &at
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (r *stubAPIKeyRepo) TouchAPIKey(ctx context.Context, id int, at time.Time) error {
+	for _, k := range r.keys {
+		if k.ID == id {
+			k.LastUsedAt = &at
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+const testJWTSecret = "test-secret"
+
+func signJWT(t *testing.T, secret string, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	require.NoError(t, err)
+	return token
+}
+
+// issueAPIKey creates a key through the admin endpoint and returns it.
+func issueAPIKey(t *testing.T, h *handler.APIKeyHandler, body string) string {
+	t.Helper()
+	rec, _ := callJSON(t, h.CreateHandler, http.MethodPost, body)
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+
+	var resp struct {
+		Data struct {
+			ID     int    `json:"id"`
+			Key    string `json:"key"`
+			Prefix string `json:"prefix"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.True(t, strings.HasPrefix(resp.Data.Key, resp.Data.Prefix))
+	return resp.Data.Key
+}
+
+func TestAuthenticator_RequireScope(t *testing.T) {
+	keySvc := service.NewAPIKeyService(&stubAPIKeyRepo{})
+	keyHandler := handler.NewAPIKeyHandler(keySvc)
+	readKey := issueAPIKey(t, keyHandler, `{"name":"nightly","scopes":["reports:read"]}`)
+	runKey := issueAPIKey(t, keyHandler, `{"name":"runner","scopes":["reports:run"]}`)
+	revokedKey := issueAPIKey(t, keyHandler, `{"name":"old","scopes":["reports:read"]}`)
+	rec, _ := callJSON(t, keyHandler.RevokeHandler, http.MethodDelete, "", "id", "3")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+
+	tests := []struct {
+		name      string
+		required  bool
+		headers   map[string]string
+		wantCode  int
+		wantActor string
+	}{
+		{"anonymous when optional", false, nil, http.StatusOK, domain.SystemActor},
+		{"anonymous when required", true, nil, http.StatusUnauthorized, ""},
+		{"api key", true, map[string]string{handler.APIKeyHeader: readKey}, http.StatusOK, "api-key:nightly"},
+		{"api key without scope", true, map[string]string{handler.APIKeyHeader: runKey}, http.StatusForbidden, ""},
+		{"revoked api key", false, map[string]string{handler.APIKeyHeader: revokedKey}, http.StatusUnauthorized, ""},
+		{"unknown api key", false, map[string]string{handler.APIKeyHeader: "emk_unknown"}, http.StatusUnauthorized, ""},
+		{"jwt", true, map[string]string{echo.HeaderAuthorization: "Bearer " + signJWT(t, testJWTSecret, jwt.MapClaims{
+			"sub": "alice", "scope": "reports:read reports:run", "exp": time.Now().Add(time.Hour).Unix(),
+		})}, http.StatusOK, "alice"},
+		{"jwt overrides actor header", true, map[string]string{
+			handler.ActorHeader:      "mallory",
+			echo.HeaderAuthorization: "Bearer " + signJWT(t, testJWTSecret, jwt.MapClaims{"sub": "alice", "scope": "reports:read"}),
+		}, http.StatusOK, "alice"},
+		{"jwt without scope", true, map[string]string{echo.HeaderAuthorization: "Bearer " + signJWT(t, testJWTSecret, jwt.MapClaims{
+			"sub": "alice", "scope": "reports:run",
+		})}, http.StatusForbidden, ""},
+		{"expired jwt", true, map[string]string{echo.HeaderAuthorization: "Bearer " + signJWT(t, testJWTSecret, jwt.MapClaims{
+			"sub": "alice", "scope": "reports:read", "exp": time.Now().Add(-time.Hour).Unix(),
+		})}, http.StatusUnauthorized, ""},
+		{"jwt with wrong secret", true, map[string]string{echo.HeaderAuthorization: "Bearer " + signJWT(t, "other", jwt.MapClaims{
+			"sub": "alice", "scope": "reports:read",
+		})}, http.StatusUnauthorized, ""},
+		{"jwt without sub", true, map[string]string{echo.HeaderAuthorization: "Bearer " + signJWT(t, testJWTSecret, jwt.MapClaims{
+			"scope": "reports:read",
+		})}, http.StatusUnauthorized, ""},
+		{"basic auth", false, map[string]string{echo.HeaderAuthorization: "Basic YWxpY2U6c2VjcmV0"}, http.StatusUnauthorized, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			var actor string
+			auth := handler.NewAuthenticator(keySvc, testJWTSecret, tt.required)
+			err := handler.ActorMiddleware(auth.RequireScope(domain.ScopeReportsRead)(func(c echo.Context) error {
+				actor = domain.ActorFrom(c.Request().Context())
+				return c.NoContent(http.StatusOK)
+			}))(c)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantCode, rec.Code, rec.Body.String())
+			assert.Equal(t, tt.wantActor, actor)
+		})
+	}
+}
+
+func TestAPIKeyHandler_CreateValidation(t *testing.T) {
+	h := handler.NewAPIKeyHandler(service.NewAPIKeyService(&stubAPIKeyRepo{}))
+
+	rec, fields := callJSON(t, h.CreateHandler, http.MethodPost, `{"name":" ","scopes":["reports:write"],"expires_at":"2000-01-01T00:00:00Z"}`)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.ElementsMatch(t, []string{"name", "scopes[0]", "expires_at"}, fields)
+}
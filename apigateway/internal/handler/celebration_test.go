@@ -0,0 +1,76 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/handler"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func newCelebrationReports() service.ReportService {
+	date := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	repo := &stubEmployeeRepo{employees: []domain.Employee{
+		{ID: 10001, FirstName: "Georgi", LastName: "Facello", BirthDate: date(1980, 1, 3), HireDate: date(2020, 12, 28)},
+		{ID: 10002, FirstName: "Bezalel", LastName: "Simmel", BirthDate: date(1992, 2, 29), HireDate: date(2023, 12, 22)},
+		{ID: 10003, FirstName: "Parto", LastName: "Bamford", BirthDate: date(1975, 6, 1), HireDate: date(2010, 6, 1)},
+	}}
+	reportSvc := service.NewReportService(nil)
+	reportSvc.Register(service.NewUpcomingCelebrationsReport(service.NewEmployeeService(repo, stubTx{}), "../../templates/upcoming_celebrations.yaml"))
+	return reportSvc
+}
+
+func TestUpcomingCelebrationsReport(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, newCelebrationReports().Generate(context.Background(), &domain.ExportRequest{
+		TemplateID: service.UpcomingCelebrationsReportID,
+		Variables:  map[string]interface{}{"from": "2023-12-20", "days": 20},
+	}, &buf))
+
+	f, err := excelize.OpenReader(&buf)
+	require.NoError(t, err)
+	defer f.Close()
+
+	// The window spans the new year; 10002 was hired this year, so there is no
+	// anniversary yet
+	rows, err := f.GetRows("Celebrations")
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"10001", "Georgi Facello", "anniversary", "3"},
+		{"10001", "Georgi Facello", "birthday", "44"},
+	}, [][]string{rows[2][1:], rows[3][1:]})
+	assert.Len(t, rows, 4)
+}
+
+func TestUpcomingCelebrationsReport_LeapDay(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, newCelebrationReports().Generate(context.Background(), &domain.ExportRequest{
+		TemplateID: service.UpcomingCelebrationsReportID,
+		Variables:  map[string]interface{}{"from": "2023-02-27", "days": 3},
+	}, &buf))
+
+	f, err := excelize.OpenReader(&buf)
+	require.NoError(t, err)
+	defer f.Close()
+
+	rows, err := f.GetRows("Celebrations")
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+	assert.Equal(t, []string{"2023-03-01", "10002", "Bezalel Simmel", "birthday", "31"}, rows[2])
+}
+
+func TestUpcomingCelebrationsReport_RejectsDays(t *testing.T) {
+	h := handler.NewReportHandler(newCelebrationReports())
+
+	rec := postGenerate(t, h, `{"template_id": "upcoming_celebrations", "variables": {"days": 400}}`)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "must be between 1 and 366, got 400")
+}
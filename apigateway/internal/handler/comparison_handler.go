@@ -1,9 +1,11 @@
 package handler
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"regexp"
 	"time"
 
@@ -19,10 +21,18 @@ type WikiPerson struct {
 	URL  string `json:"url" excel:"URL"`
 }
 
-type ComparisonHandler struct{}
+type ComparisonHandler struct {
+	// observer receives the measurements of the dataflow stages, nil for none
+	observer dataflow.Observer
+	// WikiBaseURL is where the wiki pages are fetched from, Wikipedia by
+	// default.
+	WikiBaseURL string
+}
 
-func NewComparisonHandler() *ComparisonHandler {
-	return &ComparisonHandler{}
+// NewComparisonHandler creates a new handler, reporting the stages of the
+// dataflow pipelines to observer when it is not nil.
+func NewComparisonHandler(observer dataflow.Observer) *ComparisonHandler {
+	return &ComparisonHandler{observer: observer, WikiBaseURL: "https://en.wikipedia.org"}
 }
 
 // Global regex to match potential names in Wikipedia list pages
@@ -31,19 +41,51 @@ var nameRegex = regexp.MustCompile(`<li><a href="(/wiki/[^"]+)" title="([^"]+)">
 
 // --- Helper Functions ---
 
-func fetchWikiPage(url string) (string, error) {
-	client := &http.Client{}
-	req, err := http.NewRequest("GET", url, nil)
+// wikiFetchOptions returns opts with the retries and limits of the stages
+// fetching wiki pages: at most 5 requests a second, 2 at a time per host.
+func (h *ComparisonHandler) wikiFetchOptions(opts ...dataflow.Option) []dataflow.Option {
+	return append(append([]dataflow.Option{
+		dataflow.WithRetry(3, dataflow.ExponentialBackoff(100*time.Millisecond)),
+		dataflow.WithRateLimit(5, time.Second),
+		dataflow.WithKeyConcurrency(wikiHost, 2),
+	}, h.observed("wiki_fetch")...), opts...)
+}
+
+// observed reports a stage to the observer under name, if there is one.
+func (h *ComparisonHandler) observed(name string) []dataflow.Option {
+	if h.observer == nil {
+		return nil
+	}
+	return []dataflow.Option{dataflow.WithObserver(name, h.observer)}
+}
+
+// wikiHost returns the host of a page URL.
+func wikiHost(msg interface{}) string {
+	u, err := url.Parse(msg.(string))
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+// wikiClient fetches the wiki pages, forwarding the request ID
+var wikiClient = &http.Client{Transport: RequestIDTransport{}}
+
+func fetchWikiPage(ctx context.Context, url string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return "", err
 	}
 	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; AntigravityScraper/1.0; +http://localhost:8082)")
 
-	resp, err := client.Do(req)
+	resp, err := wikiClient.Do(req)
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fetching %s: %s", url, resp.Status)
+	}
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return "", err
@@ -69,33 +111,35 @@ func parseWikiNames(body string) []WikiPerson {
 
 func (h *ComparisonHandler) ExportWikiTPL(c echo.Context) error {
 	wikiURLs := []string{
-		"https://en.wikipedia.org/wiki/List_of_computer_scientists",
-		"https://en.wikipedia.org/wiki/List_of_American_mathematicians",
-		"https://en.wikipedia.org/wiki/Timeline_of_ancient_Greek_mathematicians",
+		h.WikiBaseURL + "/wiki/List_of_computer_scientists",
+		h.WikiBaseURL + "/wiki/List_of_American_mathematicians",
+		h.WikiBaseURL + "/wiki/Timeline_of_ancient_Greek_mathematicians",
 	}
 	ctx := c.Request().Context()
 	logger.InfoLog(ctx, "Exporting wiki names (TPL Style)")
 	start := time.Now()
 	// 1. Create Blocks
-	buffer := pipeline.NewBufferBlock(pipeline.WithBufferSize(10))
+	// Every block stops when the request is cancelled
+	buffer := pipeline.NewBufferBlock(pipeline.WithBufferSize(10), pipeline.WithContext(ctx))
 
 	fetchingRetry := pipeline.NewTransformBlock(
 		func(input interface{}) (interface{}, error) {
 			url := input.(string)
 			logger.InfoLog(ctx, "Fetching URL: %s", url)
-			return fetchWikiPage(url)
+			return fetchWikiPage(ctx, url)
 		},
 		pipeline.WithRetryPolicy(pipeline.RetryPolicy{
 			MaxRetries: 3,
 			Backoff:    100 * time.Millisecond,
 		}),
+		pipeline.WithContext(ctx),
 	)
 
 	parser := pipeline.NewTransformBlock(func(input interface{}) (interface{}, error) {
 		body := input.(string)
 		logger.InfoLog(ctx, "Parsing body...")
 		return parseWikiNames(body), nil
-	})
+	}, pipeline.WithContext(ctx))
 
 	var allPeople []WikiPerson
 	collector := pipeline.NewActionBlock(func(input interface{}) error {
@@ -103,7 +147,7 @@ func (h *ComparisonHandler) ExportWikiTPL(c echo.Context) error {
 		logger.InfoLog(ctx, "Collecting people: %#v", people)
 		allPeople = append(allPeople, people...)
 		return nil
-	})
+	}, pipeline.WithContext(ctx))
 
 	// 2. Link
 	pipeline.LinkTo(buffer, fetchingRetry, nil)
@@ -114,7 +158,9 @@ func (h *ComparisonHandler) ExportWikiTPL(c echo.Context) error {
 	go func() {
 		for _, url := range wikiURLs {
 			logger.InfoLog(ctx, "Posting URL: %s", url)
-			buffer.Post(url)
+			if !buffer.Post(url) {
+				break
+			}
 		}
 		buffer.Complete()
 	}()
@@ -142,36 +188,47 @@ func (h *ComparisonHandler) ExportWikiIdiomatic(c echo.Context) error {
 	logger.InfoLog(ctx, "Exporting wiki names (Idiomatic Style)")
 	start := time.Now()
 	wikiURLs := []interface{}{
-		"https://en.wikipedia.org/wiki/List_of_computer_scientists",
-		"https://en.wikipedia.org/wiki/List_of_American_mathematicians",
-		"https://en.wikipedia.org/wiki/Timeline_of_ancient_Greek_mathematicians",
+		h.WikiBaseURL + "/wiki/List_of_computer_scientists",
+		h.WikiBaseURL + "/wiki/List_of_American_mathematicians",
+		h.WikiBaseURL + "/wiki/Timeline_of_ancient_Greek_mathematicians",
 	}
 
 	// 1. Source
 	src := dataflow.From(ctx, wikiURLs...)
 
-	// 2. Fetch (Parallel) with Retry
+	// 2. Fetch (Parallel) with Retry, pages still failing are logged and skipped
+	var failures dataflow.Failures
 	bodies := dataflow.Map(ctx, src, func(msg interface{}) (interface{}, error) {
-		return fetchWikiPage(msg.(string))
-	}, dataflow.WithWorkers(2), dataflow.WithRetry(3, dataflow.ExponentialBackoff(100*time.Millisecond)))
-
-	// 3. Parse
-	parsed := dataflow.Map(ctx, bodies, func(msg interface{}) (interface{}, error) {
+		return fetchWikiPage(ctx, msg.(string))
+	}, h.wikiFetchOptions(dataflow.WithWorkers(2), dataflow.WithFailures(&failures),
+		dataflow.WithErrorHandler(func(err error) bool {
+			logger.WarnLog(ctx, "Skipping wiki page: %v", err)
+			return true
+		}))...)
+
+	// 3. Parse, one WikiPerson per message
+	people := dataflow.FlatMap(ctx, bodies, func(msg interface{}) (interface{}, error) {
 		return parseWikiNames(msg.(string)), nil
-	})
+	}, h.observed("wiki_parse")...)
 
 	// 4. Collect
-	var allPeople []WikiPerson
-	err := dataflow.ForEach(ctx, parsed, func(msg interface{}) error {
-		people := msg.([]WikiPerson)
-		allPeople = append(allPeople, people...)
-		return nil
+	collected, err := dataflow.Reduce(ctx, people, []WikiPerson(nil), func(acc, msg interface{}) (interface{}, error) {
+		return append(acc.([]WikiPerson), msg.(WikiPerson)), nil
 	})
 
 	if err != nil {
 		logger.ErrorLog(ctx, "Idiomatic Pipeline failed: %v", err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 	}
+	allPeople := collected.([]WikiPerson)
+	if err := failures.Err(); err != nil {
+		if failures.Len() >= len(wikiURLs) {
+			// Nothing to export, don't answer with an empty workbook
+			logger.ErrorLog(ctx, "Idiomatic Pipeline fetched no pages: %v", err)
+			return c.JSON(http.StatusBadGateway, map[string]string{"error": err.Error()})
+		}
+		logger.WarnLog(ctx, "Idiomatic Pipeline skipped pages: %v", err)
+	}
 	logger.InfoLog(ctx, "Idiomatic Pipeline finished in %v, collected %d people", time.Since(start), len(allPeople))
 
 	return h.exportToExcel(c, allPeople, "wiki_names_idiomatic.xlsx")
@@ -182,9 +239,9 @@ func (h *ComparisonHandler) ExportWikiStreaming(c echo.Context) error {
 	logger.InfoLog(ctx, "Exporting wiki names (Streaming Style)")
 	start := time.Now()
 	wikiURLs := []interface{}{
-		"https://en.wikipedia.org/wiki/List_of_computer_scientists",
-		"https://en.wikipedia.org/wiki/List_of_American_mathematicians",
-		"https://en.wikipedia.org/wiki/Timeline_of_ancient_Greek_mathematicians",
+		h.WikiBaseURL + "/wiki/List_of_computer_scientists",
+		h.WikiBaseURL + "/wiki/List_of_American_mathematicians",
+		h.WikiBaseURL + "/wiki/Timeline_of_ancient_Greek_mathematicians",
 	}
 
 	// 1. Prepare Exporter
@@ -219,12 +276,12 @@ func (h *ComparisonHandler) ExportWikiStreaming(c echo.Context) error {
 	src := dataflow.From(ctx, wikiURLs...)
 
 	bodies := dataflow.Map(ctx, src, func(msg interface{}) (interface{}, error) {
-		return fetchWikiPage(msg.(string))
-	}, dataflow.WithWorkers(2), dataflow.WithRetry(3, dataflow.ExponentialBackoff(100*time.Millisecond)))
+		return fetchWikiPage(ctx, msg.(string))
+	}, h.wikiFetchOptions(dataflow.WithWorkers(2))...)
 
 	parsed := dataflow.Map(ctx, bodies, func(msg interface{}) (interface{}, error) {
 		return parseWikiNames(msg.(string)), nil
-	})
+	}, h.observed("wiki_parse")...)
 
 	// 3. ForEach + Write Batch
 	var count int
@@ -273,9 +330,9 @@ func (h *ComparisonHandler) ExportWikiStreamingV2(c echo.Context) error {
 	logger.InfoLog(ctx, "Exporting wiki names (Streaming V2 - simpleexcelv2 + dataflow)")
 	start := time.Now()
 	wikiURLs := []interface{}{
-		"https://en.wikipedia.org/wiki/List_of_computer_scientists",
-		"https://en.wikipedia.org/wiki/List_of_American_mathematicians",
-		"https://en.wikipedia.org/wiki/Timeline_of_ancient_Greek_mathematicians",
+		h.WikiBaseURL + "/wiki/List_of_computer_scientists",
+		h.WikiBaseURL + "/wiki/List_of_American_mathematicians",
+		h.WikiBaseURL + "/wiki/Timeline_of_ancient_Greek_mathematicians",
 	}
 
 	// 1. Prepare Exporter (V2)
@@ -309,12 +366,12 @@ func (h *ComparisonHandler) ExportWikiStreamingV2(c echo.Context) error {
 	src := dataflow.From(ctx, wikiURLs...)
 
 	bodies := dataflow.Map(ctx, src, func(msg interface{}) (interface{}, error) {
-		return fetchWikiPage(msg.(string))
-	}, dataflow.WithWorkers(2), dataflow.WithRetry(3, dataflow.ExponentialBackoff(100*time.Millisecond)))
+		return fetchWikiPage(ctx, msg.(string))
+	}, h.wikiFetchOptions(dataflow.WithWorkers(2))...)
 
 	parsed := dataflow.Map(ctx, bodies, func(msg interface{}) (interface{}, error) {
 		return parseWikiNames(msg.(string)), nil
-	})
+	}, h.observed("wiki_parse")...)
 
 	// 3. ForEach + Write Batch
 	var count int
@@ -412,12 +469,12 @@ sheets:
 	runPipeline := func(url, sectionID string) error {
 		src := dataflow.From(ctx, url)
 		bodies := dataflow.Map(ctx, src, func(msg interface{}) (interface{}, error) {
-			return fetchWikiPage(msg.(string))
-		}, dataflow.WithRetry(3, dataflow.ExponentialBackoff(100*time.Millisecond)))
+			return fetchWikiPage(ctx, msg.(string))
+		}, h.wikiFetchOptions()...)
 
 		parsed := dataflow.Map(ctx, bodies, func(msg interface{}) (interface{}, error) {
 			return parseWikiNames(msg.(string)), nil
-		})
+		}, h.observed("wiki_parse")...)
 
 		return dataflow.ForEach(ctx, parsed, func(msg interface{}) error {
 			people := msg.([]WikiPerson)
@@ -432,14 +489,14 @@ sheets:
 
 	// 5. Run Pipelines Sequentially
 	// Section 1: Golang
-	if err := runPipeline("https://en.wikipedia.org/wiki/Go_(programming_language)", "wiki-golang"); err != nil {
+	if err := runPipeline(h.WikiBaseURL+"/wiki/Go_(programming_language)", "wiki-golang"); err != nil {
 		logger.ErrorLog(ctx, "Pipeline Golang failed: %v", err)
 		// Continue to next section? Or abort?
 		// For stream, better to continue or abort. Let's abort only if critical.
 	}
 
 	// Section 2: Python
-	if err := runPipeline("https://en.wikipedia.org/wiki/Python_(programming_language)", "wiki-python"); err != nil {
+	if err := runPipeline(h.WikiBaseURL+"/wiki/Python_(programming_language)", "wiki-python"); err != nil {
 		logger.ErrorLog(ctx, "Pipeline Python failed: %v", err)
 	}
 
@@ -1,6 +1,7 @@
 package handler_test
 
 import (
+	"bytes"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -8,11 +9,57 @@ import (
 	"github.com/labstack/echo/v4"
 	"github.com/locvowork/employee_management_sample/apigateway/internal/handler"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
 )
 
+// newWikiServer serves a list page with one person per path of people, and
+// 500 for the paths in failing.
+func newWikiServer(t *testing.T, people map[string]string, failing ...string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, path := range failing {
+			if r.URL.Path == path {
+				http.Error(w, "unavailable", http.StatusInternalServerError)
+				return
+			}
+		}
+		name, ok := people[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`<ul><li><a href="/wiki/` + name + `" title="` + name + `">` + name + `</a></li></ul>`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+var wikiPeople = map[string]string{
+	"/wiki/List_of_computer_scientists":              "Ada_Lovelace",
+	"/wiki/List_of_American_mathematicians":          "Norbert_Wiener",
+	"/wiki/Timeline_of_ancient_Greek_mathematicians": "Euclid",
+}
+
+// exportedNames returns the names in the first column of the workbook.
+func exportedNames(t *testing.T, body []byte) []string {
+	f, err := excelize.OpenReader(bytes.NewReader(body))
+	require.NoError(t, err)
+	defer f.Close()
+	rows, err := f.GetRows(f.GetSheetList()[0])
+	require.NoError(t, err)
+	var names []string
+	for _, row := range rows {
+		if len(row) > 0 {
+			names = append(names, row[0])
+		}
+	}
+	return names
+}
+
 func TestComparisonEndpoints(t *testing.T) {
 	e := echo.New()
-	compHandler := handler.NewComparisonHandler()
+	compHandler := handler.NewComparisonHandler(nil)
+	compHandler.WikiBaseURL = newWikiServer(t, wikiPeople).URL
 
 	t.Run("Idiomatic Wiki Export", func(t *testing.T) {
 		req := httptest.NewRequest(http.MethodGet, "/comparison/wiki/idiomatic", nil)
@@ -23,6 +70,7 @@ func TestComparisonEndpoints(t *testing.T) {
 			assert.Equal(t, http.StatusOK, rec.Code)
 			assert.Equal(t, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", rec.Header().Get(echo.HeaderContentType))
 			assert.Contains(t, rec.Header().Get(echo.HeaderContentDisposition), "wiki_names_idiomatic.xlsx")
+			assert.Subset(t, exportedNames(t, rec.Body.Bytes()), []string{"Ada_Lovelace", "Norbert_Wiener", "Euclid"})
 		}
 	})
 
@@ -35,6 +83,39 @@ func TestComparisonEndpoints(t *testing.T) {
 			assert.Equal(t, http.StatusOK, rec.Code)
 			assert.Equal(t, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", rec.Header().Get(echo.HeaderContentType))
 			assert.Contains(t, rec.Header().Get(echo.HeaderContentDisposition), "wiki_names_tpl.xlsx")
+			assert.Subset(t, exportedNames(t, rec.Body.Bytes()), []string{"Ada_Lovelace", "Norbert_Wiener", "Euclid"})
 		}
 	})
 }
+
+func TestComparisonIdiomatic_SkipsFailingPages(t *testing.T) {
+	e := echo.New()
+	compHandler := handler.NewComparisonHandler(nil)
+	compHandler.WikiBaseURL = newWikiServer(t, wikiPeople, "/wiki/List_of_American_mathematicians").URL
+
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/comparison/wiki/idiomatic", nil), rec)
+
+	require.NoError(t, compHandler.ExportWikiIdiomatic(c))
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	names := exportedNames(t, rec.Body.Bytes())
+	assert.Subset(t, names, []string{"Ada_Lovelace", "Euclid"})
+	assert.NotContains(t, names, "Norbert_Wiener")
+}
+
+func TestComparisonIdiomatic_FailsWithoutAnyPage(t *testing.T) {
+	e := echo.New()
+	compHandler := handler.NewComparisonHandler(nil)
+	var failing []string
+	for path := range wikiPeople {
+		failing = append(failing, path)
+	}
+	compHandler.WikiBaseURL = newWikiServer(t, wikiPeople, failing...).URL
+
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/comparison/wiki/idiomatic", nil), rec)
+
+	require.NoError(t, compHandler.ExportWikiIdiomatic(c))
+	assert.Equal(t, http.StatusBadGateway, rec.Code)
+	assert.Contains(t, rec.Body.String(), "500 Internal Server Error")
+}
@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/config"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service/serviceutils"
+)
+
+// ConfigHandler dumps the loaded configuration for debugging.
+type ConfigHandler struct {
+	settings []config.Setting
+}
+
+// NewConfigHandler serves settings, see config.DefaultEnvConfig.Settings.
+func NewConfigHandler(settings []config.Setting) *ConfigHandler {
+	return &ConfigHandler{settings: settings}
+}
+
+// DumpHandler handles GET /debug/config, secrets redacted.
+func (h *ConfigHandler) DumpHandler(c echo.Context) error {
+	return serviceutils.ResponseSuccess(c, http.StatusOK, "Configuration", h.settings)
+}
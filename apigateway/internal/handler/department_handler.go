@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service/serviceutils"
+)
+
+type DepartmentHandler struct {
+	svc service.DepartmentService
+}
+
+func NewDepartmentHandler(svc service.DepartmentService) *DepartmentHandler {
+	return &DepartmentHandler{svc: svc}
+}
+
+type departmentRequest struct {
+	DeptNo   string `json:"dept_no"`
+	DeptName string `json:"dept_name"`
+}
+
+type managerRequest struct {
+	EmpNo    int    `json:"emp_no"`
+	FromDate string `json:"from_date"`
+	ToDate   string `json:"to_date"`
+}
+
+// ListHandler handles GET /departments
+func (h *DepartmentHandler) ListHandler(c echo.Context) error {
+	departments, err := h.svc.List(c.Request().Context())
+	if err != nil {
+		return serviceutils.ResponseError(c, http.StatusInternalServerError, "Failed to list departments", err)
+	}
+	return serviceutils.ResponseSuccess(c, http.StatusOK, "Departments listed successfully", departments)
+}
+
+// GetHandler handles GET /departments/:id
+func (h *DepartmentHandler) GetHandler(c echo.Context) error {
+	d, err := h.svc.Get(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return respondDepartmentError(c, "Failed to get department", err)
+	}
+	return serviceutils.ResponseSuccess(c, http.StatusOK, "Department retrieved successfully", d)
+}
+
+// CreateHandler handles POST /departments with {"dept_no", "dept_name"}
+func (h *DepartmentHandler) CreateHandler(c echo.Context) error {
+	var req departmentRequest
+	if err := serviceutils.Bind(c, &req); err != nil {
+		return respondValidationError(c, "Invalid request body", err)
+	}
+
+	d := domain.Department{DeptNo: req.DeptNo, DeptName: req.DeptName}
+	if err := h.svc.Create(c.Request().Context(), &d); err != nil {
+		return respondDepartmentError(c, "Failed to create department", err)
+	}
+	return serviceutils.ResponseSuccess(c, http.StatusCreated, "Department created successfully", d)
+}
+
+// UpdateHandler handles PUT /departments/:id with {"dept_name"}
+func (h *DepartmentHandler) UpdateHandler(c echo.Context) error {
+	var req departmentRequest
+	if err := serviceutils.Bind(c, &req); err != nil {
+		return respondValidationError(c, "Invalid request body", err)
+	}
+
+	d := domain.Department{DeptNo: c.Param("id"), DeptName: req.DeptName}
+	if err := h.svc.Update(c.Request().Context(), &d); err != nil {
+		return respondDepartmentError(c, "Failed to update department", err)
+	}
+	return serviceutils.ResponseSuccess(c, http.StatusOK, "Department updated successfully", d)
+}
+
+// DeleteHandler handles DELETE /departments/:id
+func (h *DepartmentHandler) DeleteHandler(c echo.Context) error {
+	if err := h.svc.Delete(c.Request().Context(), c.Param("id")); err != nil {
+		return respondDepartmentError(c, "Failed to delete department", err)
+	}
+	return serviceutils.ResponseSuccess(c, http.StatusOK, "Department deleted successfully", nil)
+}
+
+// RestoreHandler handles POST /departments/:id/restore
+func (h *DepartmentHandler) RestoreHandler(c echo.Context) error {
+	if err := h.svc.Restore(c.Request().Context(), c.Param("id")); err != nil {
+		return respondDepartmentError(c, "Failed to restore department", err)
+	}
+	return serviceutils.ResponseSuccess(c, http.StatusOK, "Department restored successfully", nil)
+}
+
+// ListManagersHandler handles GET /departments/:id/managers
+func (h *DepartmentHandler) ListManagersHandler(c echo.Context) error {
+	managers, err := h.svc.ListManagers(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return respondDepartmentError(c, "Failed to list managers", err)
+	}
+	return serviceutils.ResponseSuccess(c, http.StatusOK, "Managers listed successfully", managers)
+}
+
+// AssignManagerHandler handles POST /departments/:id/managers with
+// {"emp_no", "from_date", "to_date"}. The current manager's term ends on
+// from_date; to_date is optional and leaves the new term open.
+func (h *DepartmentHandler) AssignManagerHandler(c echo.Context) error {
+	var req managerRequest
+	if err := serviceutils.Bind(c, &req); err != nil {
+		return respondValidationError(c, "Invalid request body", err)
+	}
+
+	m := domain.DeptManager{DeptNo: c.Param("id"), EmpNo: req.EmpNo}
+	verr := &domain.ValidationError{}
+	m.FromDate = parseDateField(verr, "from_date", req.FromDate)
+	m.ToDate = parseDateField(verr, "to_date", req.ToDate)
+	if err := verr.ErrOrNil(); err != nil {
+		return respondDepartmentError(c, "Failed to assign manager", err)
+	}
+
+	if err := h.svc.AssignManager(c.Request().Context(), &m); err != nil {
+		return respondDepartmentError(c, "Failed to assign manager", err)
+	}
+	return serviceutils.ResponseSuccess(c, http.StatusCreated, "Manager assigned successfully", m)
+}
+
+// TerminateManagerHandler handles PUT /departments/:id/managers/:emp_no/terminate
+// with {"to_date"}, ending the current term of the manager.
+func (h *DepartmentHandler) TerminateManagerHandler(c echo.Context) error {
+	empNo, err := strconv.Atoi(c.Param("emp_no"))
+	if err != nil {
+		return serviceutils.ResponseError(c, http.StatusBadRequest, "Invalid employee number", err)
+	}
+	var req managerRequest
+	if err := serviceutils.Bind(c, &req); err != nil {
+		return respondValidationError(c, "Invalid request body", err)
+	}
+
+	verr := &domain.ValidationError{}
+	toDate := parseDateField(verr, "to_date", req.ToDate)
+	if err := verr.ErrOrNil(); err != nil {
+		return respondDepartmentError(c, "Failed to terminate manager", err)
+	}
+
+	if err := h.svc.TerminateManager(c.Request().Context(), c.Param("id"), empNo, toDate); err != nil {
+		return respondDepartmentError(c, "Failed to terminate manager", err)
+	}
+	return serviceutils.ResponseSuccess(c, http.StatusOK, "Manager terminated successfully", nil)
+}
+
+// DeleteManagerHandler handles DELETE /departments/:id/managers/:emp_no?from_date=
+func (h *DepartmentHandler) DeleteManagerHandler(c echo.Context) error {
+	empNo, err := strconv.Atoi(c.Param("emp_no"))
+	if err != nil {
+		return serviceutils.ResponseError(c, http.StatusBadRequest, "Invalid employee number", err)
+	}
+	verr := &domain.ValidationError{}
+	fromDate := parseDateField(verr, "from_date", c.QueryParam("from_date"))
+	if fromDate.IsZero() && verr.ErrOrNil() == nil {
+		verr.Add("from_date", "is required")
+	}
+	if err := verr.ErrOrNil(); err != nil {
+		return respondDepartmentError(c, "Failed to delete manager", err)
+	}
+
+	if err := h.svc.DeleteManager(c.Request().Context(), c.Param("id"), empNo, fromDate); err != nil {
+		return respondDepartmentError(c, "Failed to delete manager", err)
+	}
+	return serviceutils.ResponseSuccess(c, http.StatusOK, "Manager deleted successfully", nil)
+}
+
+// parseDateField parses an optional YYYY-MM-DD date, recording malformed
+// values in verr.
+func parseDateField(verr *domain.ValidationError, field, raw string) time.Time {
+	if raw == "" {
+		return time.Time{}
+	}
+	d, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		verr.Add(field, "must be a date (YYYY-MM-DD), got %q", raw)
+	}
+	return d
+}
+
+// respondDepartmentError answers err with the status of its kind, naming
+// missing departments and assignments and taken department numbers.
+func respondDepartmentError(c echo.Context, message string, err error) error {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		message = "Department or manager assignment not found"
+	case errors.Is(err, service.ErrDepartmentExists):
+		message = "Department already exists"
+	}
+	return serviceutils.ResponseAppError(c, message, err)
+}
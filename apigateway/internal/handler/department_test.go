@@ -0,0 +1,247 @@
+package handler_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/handler"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubDepartmentRepo keeps departments and manager assignments in memory.
+type stubDepartmentRepo struct {
+	departments []domain.Department
+	deleted     []domain.Department
+	managers    []domain.DeptManager
+}
+
+func (r *stubDepartmentRepo) ListDepartments(ctx context.Context) ([]domain.Department, error) {
+	return r.departments, nil
+}
+
+func (r *stubDepartmentRepo) GetDepartment(ctx context.Context, deptNo string) (*domain.Department, error) {
+	for _, d := range r.departments {
+		if d.DeptNo == deptNo {
+			return &d, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *stubDepartmentRepo) CreateDepartment(ctx context.Context, d *domain.Department) error {
+	r.departments = append(r.departments, *d)
+	return nil
+}
+
+func (r *stubDepartmentRepo) UpdateDepartment(ctx context.Context, d *domain.Department) error {
+	for i := range r.departments {
+		if r.departments[i].DeptNo == d.DeptNo {
+			r.departments[i] = *d
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (r *stubDepartmentRepo) DeleteDepartment(ctx context.Context, deptNo string) error {
+	for i := range r.departments {
+		if r.departments[i].DeptNo == deptNo {
+			r.deleted = append(r.deleted, r.departments[i])
+			r.departments = append(r.departments[:i], r.departments[i+1:]...)
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (r *stubDepartmentRepo) RestoreDepartment(ctx context.Context, deptNo string) error {
+	for i := range r.deleted {
+		if r.deleted[i].DeptNo == deptNo {
+			r.departments = append(r.departments, r.deleted[i])
+			r.deleted = append(r.deleted[:i], r.deleted[i+1:]...)
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (r *stubDepartmentRepo) ListManagers(ctx context.Context, deptNo string) ([]domain.DeptManager, error) {
+	var managers []domain.DeptManager
+	for i := len(r.managers) - 1; i >= 0; i-- {
+		if r.managers[i].DeptNo == deptNo {
+			managers = append(managers, r.managers[i])
+		}
+	}
+	return managers, nil
+}
+
+func (r *stubDepartmentRepo) AssignManager(ctx context.Context, m *domain.DeptManager) error {
+	for i := range r.managers {
+		if r.managers[i].DeptNo == m.DeptNo && r.managers[i].IsCurrent() {
+			r.managers[i].ToDate = m.FromDate
+		}
+	}
+	r.managers = append(r.managers, *m)
+	return nil
+}
+
+func (r *stubDepartmentRepo) TerminateManager(ctx context.Context, deptNo string, empNo int, toDate time.Time) error {
+	for i := range r.managers {
+		if m := &r.managers[i]; m.DeptNo == deptNo && m.EmpNo == empNo && m.IsCurrent() {
+			m.ToDate = toDate
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (r *stubDepartmentRepo) DeleteManager(ctx context.Context, deptNo string, empNo int, fromDate time.Time) error {
+	for i, m := range r.managers {
+		if m.DeptNo == deptNo && m.EmpNo == empNo && m.FromDate.Equal(fromDate) {
+			r.managers = append(r.managers[:i], r.managers[i+1:]...)
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func newDepartmentHandler() (*handler.DepartmentHandler, *stubDepartmentRepo) {
+	repo := &stubDepartmentRepo{
+		departments: []domain.Department{{DeptNo: "d005", DeptName: "Development"}},
+		managers: []domain.DeptManager{{DeptNo: "d005", EmpNo: 10001,
+			FromDate: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), ToDate: domain.OpenEndDate}},
+	}
+	empRepo := &stubEmployeeRepo{employees: testEmployees()}
+	return handler.NewDepartmentHandler(service.NewDepartmentService(repo, empRepo)), repo
+}
+
+func callJSON(t *testing.T, fn func(echo.Context) error, method, body string, params ...string) (*httptest.ResponseRecorder, []string) {
+	e := echo.New()
+	req := httptest.NewRequest(method, "/", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	var names, values []string
+	for i := 0; i+1 < len(params); i += 2 {
+		names, values = append(names, params[i]), append(values, params[i+1])
+	}
+	c.SetParamNames(names...)
+	c.SetParamValues(values...)
+	require.NoError(t, fn(c))
+
+	var resp struct {
+		Data json.RawMessage
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	var fieldErrs []struct {
+		Field string `json:"field"`
+	}
+	_ = json.Unmarshal(resp.Data, &fieldErrs)
+	var fields []string
+	for _, fe := range fieldErrs {
+		fields = append(fields, fe.Field)
+	}
+	return rec, fields
+}
+
+func TestDepartment_Create(t *testing.T) {
+	h, repo := newDepartmentHandler()
+
+	rec, _ := callJSON(t, h.CreateHandler, http.MethodPost, `{"dept_no": "d010", "dept_name": "Legal"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Contains(t, repo.departments, domain.Department{DeptNo: "d010", DeptName: "Legal"})
+
+	rec, _ = callJSON(t, h.CreateHandler, http.MethodPost, `{"dept_no": "d005", "dept_name": "Dev"}`)
+	assert.Equal(t, http.StatusConflict, rec.Code)
+
+	rec, fields := callJSON(t, h.CreateHandler, http.MethodPost, `{"dept_no": "d00010"}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.ElementsMatch(t, []string{"dept_no", "dept_name"}, fields)
+}
+
+func TestDepartment_UpdateMissing(t *testing.T) {
+	h, _ := newDepartmentHandler()
+
+	rec, _ := callJSON(t, h.UpdateHandler, http.MethodPut, `{"dept_name": "Nothing"}`, "id", "d999")
+
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestDepartment_DeleteAndRestore(t *testing.T) {
+	h, repo := newDepartmentHandler()
+
+	rec, _ := callJSON(t, h.DeleteHandler, http.MethodDelete, ``, "id", "d005")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	rec, _ = callJSON(t, h.GetHandler, http.MethodGet, ``, "id", "d005")
+	assert.Equal(t, http.StatusNotFound, rec.Code, "deleted departments are hidden")
+	rec, _ = callJSON(t, h.DeleteHandler, http.MethodDelete, ``, "id", "d005")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec, _ = callJSON(t, h.RestoreHandler, http.MethodPost, ``, "id", "d005")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, []domain.Department{{DeptNo: "d005", DeptName: "Development"}}, repo.departments)
+	rec, _ = callJSON(t, h.RestoreHandler, http.MethodPost, ``, "id", "d005")
+	assert.Equal(t, http.StatusNotFound, rec.Code, "only deleted departments can be restored")
+}
+
+func TestDepartment_AssignManager(t *testing.T) {
+	h, repo := newDepartmentHandler()
+
+	rec, _ := callJSON(t, h.AssignManagerHandler, http.MethodPost, `{"emp_no": 10002, "from_date": "2024-03-01"}`, "id", "d005")
+
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	require.Len(t, repo.managers, 2)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), repo.managers[0].ToDate, "the previous term ends when the new one starts")
+	assert.Equal(t, domain.DeptManager{DeptNo: "d005", EmpNo: 10002,
+		FromDate: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), ToDate: domain.OpenEndDate}, repo.managers[1])
+}
+
+func TestDepartment_AssignManagerRejectsInvalidTerms(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		fields []string
+	}{
+		{"missing fields", `{}`, []string{"emp_no", "from_date"}},
+		{"bad dates", `{"emp_no": 10002, "from_date": "03/01/2024", "to_date": "soon"}`, []string{"from_date", "to_date"}},
+		{"ends before it starts", `{"emp_no": 10002, "from_date": "2024-03-01", "to_date": "2024-02-01"}`, []string{"to_date"}},
+		{"starts before the current term", `{"emp_no": 10002, "from_date": "2019-12-31"}`, []string{"from_date"}},
+		{"unknown employee", `{"emp_no": 404, "from_date": "2024-03-01"}`, []string{"emp_no"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, repo := newDepartmentHandler()
+
+			rec, fields := callJSON(t, h.AssignManagerHandler, http.MethodPost, tt.body, "id", "d005")
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.ElementsMatch(t, tt.fields, fields)
+			assert.Len(t, repo.managers, 1, "nothing is stored")
+		})
+	}
+}
+
+func TestDepartment_TerminateManager(t *testing.T) {
+	h, repo := newDepartmentHandler()
+
+	rec, fields := callJSON(t, h.TerminateManagerHandler, http.MethodPut, `{"to_date": "2019-01-01"}`, "id", "d005", "emp_no", "10001")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, []string{"to_date"}, fields)
+
+	rec, _ = callJSON(t, h.TerminateManagerHandler, http.MethodPut, `{"to_date": "2024-06-30"}`, "id", "d005", "emp_no", "10001")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, time.Date(2024, 6, 30, 0, 0, 0, 0, time.UTC), repo.managers[0].ToDate)
+
+	rec, _ = callJSON(t, h.TerminateManagerHandler, http.MethodPut, `{"to_date": "2024-07-31"}`, "id", "d005", "emp_no", "10001")
+	assert.Equal(t, http.StatusNotFound, rec.Code, "the term has already ended")
+}
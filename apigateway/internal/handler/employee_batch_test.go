@@ -0,0 +1,68 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/handler"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmployeeBatchUpsert_RowStatuses(t *testing.T) {
+	repo := &stubEmployeeRepo{employees: testEmployees()}
+	h := handler.NewEmployeeHandler(service.NewEmployeeService(repo, stubTx{}), nil)
+	body := `{"employees": [
+		{"id": 10001, "first_name": "Georgi", "last_name": "Facello-Smith", "gender": "M", "birth_date": "1980-09-02T00:00:00Z", "hire_date": "2020-01-02T00:00:00Z"},
+		{"id": 20001, "first_name": "Ada", "last_name": "Lovelace", "gender": "f", "birth_date": "1990-12-10T00:00:00Z", "hire_date": "2021-06-01T00:00:00Z"},
+		{"id": 20002, "first_name": "", "last_name": "Nobody", "gender": "X", "birth_date": "1990-12-10T00:00:00Z", "hire_date": "2021-06-01T00:00:00Z"},
+		{"id": 20001, "first_name": "Ada", "last_name": "Again", "gender": "F", "birth_date": "1990-12-10T00:00:00Z", "hire_date": "2021-06-01T00:00:00Z"}
+	]}`
+
+	rec, _ := callJSON(t, h.BatchUpsertHandler, http.MethodPost, body)
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp struct {
+		Data []domain.BatchRowResult
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Data, 4)
+	assert.Equal(t, domain.BatchStatusUpdated, resp.Data[0].Status)
+	assert.Equal(t, domain.BatchStatusCreated, resp.Data[1].Status)
+	assert.Equal(t, domain.BatchStatusInvalid, resp.Data[2].Status)
+	assert.ElementsMatch(t, []string{"first_name", "gender"}, fieldNames(resp.Data[2].Errors))
+	assert.Equal(t, domain.BatchStatusInvalid, resp.Data[3].Status, "repeated ids are rejected")
+	assert.Equal(t, []string{"id"}, fieldNames(resp.Data[3].Errors))
+
+	assert.Equal(t, "Facello-Smith", repo.employees[0].LastName)
+	require.Len(t, repo.employees, 3, "only the valid new employee is added")
+	assert.Equal(t, "F", repo.employees[2].Gender)
+}
+
+func TestEmployeeBatchUpsert_RejectsBatchSize(t *testing.T) {
+	h := handler.NewEmployeeHandler(service.NewEmployeeService(&stubEmployeeRepo{}, stubTx{}), nil)
+	rows := make([]string, domain.MaxEmployeeBatchSize+1)
+	for i := range rows {
+		rows[i] = fmt.Sprintf(`{"id": %d}`, i+1)
+	}
+
+	for _, body := range []string{`{"employees": []}`, `{"employees": [` + strings.Join(rows, ",") + `]}`} {
+		rec, fields := callJSON(t, h.BatchUpsertHandler, http.MethodPost, body)
+
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+		assert.Equal(t, []string{"employees"}, fields)
+	}
+}
+
+func fieldNames(errs []domain.FieldError) []string {
+	var names []string
+	for _, fe := range errs {
+		names = append(names, fe.Field)
+	}
+	return names
+}
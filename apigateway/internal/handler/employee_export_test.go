@@ -0,0 +1,88 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/handler"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/exceltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getEmployeeExport(t *testing.T, repo *stubEmployeeRepo, query string) *httptest.ResponseRecorder {
+	reportSvc := service.NewReportService(nil)
+	reportSvc.Register(service.NewEmployeeListReport(service.NewEmployeeService(repo, stubTx{}), "../../templates/employee_list.yaml"))
+	h := handler.NewReportHandler(reportSvc)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/employees/export"+query, nil)
+	rec := httptest.NewRecorder()
+	require.NoError(t, h.EmployeeExportHandler(e.NewContext(req, rec)))
+	return rec
+}
+
+func TestEmployeeExport(t *testing.T) {
+	repo := &stubEmployeeRepo{employees: testEmployees()}
+
+	rec := getEmployeeExport(t, repo, "")
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, `attachment; filename="employees.xlsx"`, rec.Header().Get(echo.HeaderContentDisposition))
+	f := exceltest.Open(t, rec.Body.Bytes())
+	rows, err := f.GetRows("Employees")
+	require.NoError(t, err)
+	require.Len(t, rows, len(repo.employees)+2, "title, header and one row per employee")
+	assert.Equal(t, []string{"Employee No", "First Name", "Last Name", "Gender", "Birth Date", "Hire Date"}, rows[1])
+	assert.Equal(t, domain.EmployeeFilter{}, repo.filter, "the whole list is exported by default")
+}
+
+func TestEmployeeExport_QueryVariables(t *testing.T) {
+	repo := &stubEmployeeRepo{employees: testEmployees()}
+
+	rec := getEmployeeExport(t, repo, "?limit=50&offset=100")
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, domain.EmployeeFilter{Limit: 50, Offset: 100}, repo.filter)
+}
+
+func TestEmployeeExport_FilterVariables(t *testing.T) {
+	repo := &stubEmployeeRepo{employees: testEmployees()}
+
+	rec := getEmployeeExport(t, repo, "?name=Geo&gender=F&hired_from=1990-01-01&hired_to=1990-12-31&dept_no=d005")
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, domain.EmployeeFilter{
+		NamePrefix: "Geo",
+		Gender:     "F",
+		HiredFrom:  time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
+		HiredTo:    time.Date(1990, 12, 31, 0, 0, 0, 0, time.UTC),
+		DeptNo:     "d005",
+	}, repo.filter)
+}
+
+func TestEmployeeExport_RejectsInvalidQuery(t *testing.T) {
+	repo := &stubEmployeeRepo{employees: testEmployees()}
+
+	rec := getEmployeeExport(t, repo, "?limit=ten&department=d001")
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	var resp struct {
+		Data []struct {
+			Field string `json:"field"`
+		}
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	var fields []string
+	for _, fe := range resp.Data {
+		fields = append(fields, fe.Field)
+	}
+	assert.ElementsMatch(t, []string{"variables.limit", "variables.department"}, fields)
+	assert.Empty(t, rec.Header().Get(echo.HeaderContentDisposition))
+}
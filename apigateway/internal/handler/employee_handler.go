@@ -1,31 +1,34 @@
 package handler
 
 import (
-	"fmt"
+	"database/sql"
+	"errors"
 	"net/http"
-	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
 	"github.com/locvowork/employee_management_sample/apigateway/internal/logger"
 	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
 	"github.com/locvowork/employee_management_sample/apigateway/internal/service/serviceutils"
-	"github.com/locvowork/employee_management_sample/apigateway/pkg/simpleexcel"
 )
 
 type EmployeeHandler struct {
 	svc service.EmployeeService
+	// reports provides the employee_edit layout and rules for imports
+	reports service.ReportService
 }
 
-func NewEmployeeHandler(svc service.EmployeeService) *EmployeeHandler {
-	return &EmployeeHandler{svc: svc}
+func NewEmployeeHandler(svc service.EmployeeService, reports service.ReportService) *EmployeeHandler {
+	return &EmployeeHandler{svc: svc, reports: reports}
 }
 
 func (h *EmployeeHandler) CreateHandler(c echo.Context) error {
 	var req domain.Employee
-	if err := c.Bind(&req); err != nil {
-		return serviceutils.ResponseError(c, http.StatusBadRequest, "Invalid request body", err)
+	if err := serviceutils.BindAndValidate(c, &req); err != nil {
+		return respondValidationError(c, "Invalid employee", err)
 	}
 
 	if err := h.svc.Create(c.Request().Context(), &req); err != nil {
@@ -56,10 +59,13 @@ func (h *EmployeeHandler) UpdateHandler(c echo.Context) error {
 	}
 
 	var req domain.Employee
-	if err := c.Bind(&req); err != nil {
-		return serviceutils.ResponseError(c, http.StatusBadRequest, "Invalid request body", err)
+	if err := serviceutils.Bind(c, &req); err != nil {
+		return respondValidationError(c, "Invalid request body", err)
 	}
 	req.ID = id
+	if err := req.Validate().ErrOrNil(); err != nil {
+		return respondValidationError(c, "Invalid employee", err)
+	}
 
 	if err := h.svc.Update(c.Request().Context(), &req); err != nil {
 		return serviceutils.ResponseError(c, http.StatusInternalServerError, "Failed to update employee", err)
@@ -75,27 +81,47 @@ func (h *EmployeeHandler) DeleteHandler(c echo.Context) error {
 	}
 
 	if err := h.svc.Delete(c.Request().Context(), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return serviceutils.ResponseError(c, http.StatusNotFound, "Employee not found", err)
+		}
 		return serviceutils.ResponseError(c, http.StatusInternalServerError, "Failed to delete employee", err)
 	}
 
 	return serviceutils.ResponseSuccess(c, http.StatusOK, "Employee deleted successfully", nil)
 }
 
-func (h *EmployeeHandler) ListHandler(c echo.Context) error {
-	limit, _ := strconv.Atoi(c.QueryParam("limit"))
-	offset, _ := strconv.Atoi(c.QueryParam("offset"))
+// RestoreHandler handles POST /employees/:id/restore, bringing back a deleted employee
+func (h *EmployeeHandler) RestoreHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return serviceutils.ResponseError(c, http.StatusBadRequest, "Invalid employee ID", err)
+	}
 
-	filter := domain.EmployeeFilter{
-		Limit:  limit,
-		Offset: offset,
+	if err := h.svc.Restore(c.Request().Context(), id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return serviceutils.ResponseError(c, http.StatusNotFound, "Deleted employee not found", err)
+		}
+		return serviceutils.ResponseError(c, http.StatusInternalServerError, "Failed to restore employee", err)
+	}
+
+	return serviceutils.ResponseSuccess(c, http.StatusOK, "Employee restored successfully", nil)
+}
+
+// ListHandler handles GET /employees?limit=&offset=&cursor=&name=&gender=&hired_from=&hired_to=&dept_no=
+// Full pages answer a next_cursor; passing it as cursor lists the following
+// page by keyset instead of offset.
+func (h *EmployeeHandler) ListHandler(c echo.Context) error {
+	filter, verr := employeeFilter(c)
+	if err := verr.ErrOrNil(); err != nil {
+		return respondValidationError(c, "Invalid query parameters", err)
 	}
 
-	employees, err := h.svc.List(c.Request().Context(), filter)
+	page, err := h.svc.ListPage(c.Request().Context(), filter)
 	if err != nil {
-		return serviceutils.ResponseError(c, http.StatusInternalServerError, "Failed to list employees", err)
+		return respondValidationError(c, "Failed to list employees", err)
 	}
 
-	return serviceutils.ResponseSuccess(c, http.StatusOK, "Employees listed successfully", employees)
+	return serviceutils.ResponsePage(c, http.StatusOK, "Employees listed successfully", page.Employees, page.NextCursor)
 }
 
 func (h *EmployeeHandler) ReportHandler(c echo.Context) error {
@@ -112,203 +138,124 @@ func (h *EmployeeHandler) ReportHandler(c echo.Context) error {
 	return serviceutils.ResponseSuccess(c, http.StatusOK, "Employee report generated successfully", report)
 }
 
-// =============================================================================
-// Sample Data For Excel Export
-// =============================================================================
-
-type Product struct {
-	Name      string
-	Price     float64
-	Category  string
-	Available bool
-	Weight    float64
-	Color     string
-	// MetaData  map[string]interface{}
+// ExportStatsHandler handles GET /employees/export-stats. It reports how often
+// the chunked export query was prepared and executed; prepares should stay at
+// one however many chunks and exports ran.
+func (h *EmployeeHandler) ExportStatsHandler(c echo.Context) error {
+	return serviceutils.ResponseSuccess(c, http.StatusOK, "Export statement stats retrieved successfully", h.svc.StatementStats())
 }
 
-type Sale struct {
-	Month  string
-	Amount float64
-	Region string
-	Rep    string
-}
-
-// For the YAML based example
-type ReportEmployee struct {
-	ID        int
-	FirstName string
-	LastName  string
-	BirthDate string
-	HireDate  string
-	Gender    string
-}
-
-func (h *EmployeeHandler) ExportFluentConfigHandler(c echo.Context) error {
-	sampleSales := []Sale{
-		{"January", 5000.0, "East", "Alice"},
-		{"February", 4500.0, "West", "Bob"},
-	}
-	type HiddenSaleData struct {
-		HiddenFieldName  string
-		HiddenFieldValue interface{}
+// ImportHandler handles POST /employees/import. The edited workbook is sent
+// as the "file" field of a multipart form.
+func (h *EmployeeHandler) ImportHandler(c echo.Context) error {
+	fh, err := c.FormFile("file")
+	if err != nil {
+		return serviceutils.ResponseError(c, http.StatusBadRequest, "Missing workbook in form field \"file\"", err)
 	}
-	// Explicitly hidden data for demonstration
-	hiddenData := []HiddenSaleData{
-		{"Region", "North"},
-		{"Rep", "SecretAgent"},
+	file, err := fh.Open()
+	if err != nil {
+		return serviceutils.ResponseError(c, http.StatusBadRequest, "Failed to read uploaded workbook", err)
 	}
+	defer file.Close()
 
-	exporter := simpleexcel.NewDataExporter()
-
-	// Sheet 1
-	sheet1 := exporter.AddSheet("Sales Report")
-
-	// Section 1: Visible Sales Data
-	sheet1.AddSection(&simpleexcel.SectionConfig{
-		Title:      "Visible Sales Data",
-		ShowHeader: true,
-		Data:       sampleSales,
-		Columns: []simpleexcel.ColumnConfig{
-			{FieldName: "Month", Header: "Month", Width: 15, HiddenFieldName: "db_month"},
-			{FieldName: "Region", Header: "Region", Width: 15, HiddenFieldName: "db_region"},
-			{FieldName: "Rep", Header: "Sales Rep", Width: 20, HiddenFieldName: "db_rep"},
-			{FieldName: "Amount", Header: "Sale Amount", Width: 15, HiddenFieldName: "db_amount"},
-		},
-	})
-
-	// Section 2: Hidden Data
-	sheet1.AddSection(&simpleexcel.SectionConfig{
-		Title: "Hidden Data Section",
-		Type:  simpleexcel.SectionTypeHidden,
-		Data:  hiddenData,
-	})
-
-	data, err := exporter.ToBytes()
+	ctx := c.Request().Context()
+	layout, err := h.reports.ImportLayout(ctx, service.EmployeeEditReportID)
+	if err != nil {
+		logger.ErrorLog(ctx, "Failed to load employee import layout: %v", err)
+		return serviceutils.ResponseError(c, http.StatusInternalServerError, "Failed to load import layout", err)
+	}
+	result, err := h.svc.Import(ctx, file, layout)
+	var ierr *domain.ImportError
+	if errors.As(err, &ierr) {
+		return c.JSON(http.StatusUnprocessableEntity, serviceutils.GenericResponse{
+			Success: false,
+			Message: "Workbook rejected, no changes were applied",
+			Data:    ierr.Errors,
+			Error:   ierr.Error(),
+		})
+	}
 	if err != nil {
-		return serviceutils.ResponseError(c, http.StatusInternalServerError, "Failed to generate excel file", err)
+		logger.ErrorLog(ctx, "Failed to import employees: %v", err)
+		return serviceutils.ResponseError(c, http.StatusInternalServerError, "Failed to import employees", err)
 	}
 
-	c.Response().Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
-	c.Response().Header().Set("Content-Disposition", `attachment; filename="fluent_report_with_hidden.xlsx"`)
-	c.Response().Header().Set("Content-Transfer-Encoding", "binary")
+	return serviceutils.ResponseSuccess(c, http.StatusOK, "Employees imported successfully", result)
+}
 
-	_, err = c.Response().Write(data)
-	return err
+type batchUpsertRequest struct {
+	Employees []domain.Employee `json:"employees"`
 }
 
-func (h *EmployeeHandler) ExportFromYAMLHandler(c echo.Context) error {
-	// YAML configuration
-	yamlConfig := ""
-	data, err := os.ReadFile("report_config.yaml")
-	if err != nil {
-		return serviceutils.ResponseError(c, http.StatusInternalServerError, "Failed to read YAML file", err)
-	}
-	yamlConfig = string(data)
-
-	productSectionEditable := []Product{
-		{
-			Name:      "Laptop Pro",
-			Price:     1299.99,
-			Category:  "Electronics",
-			Available: true,
-			Weight:    2.5,
-			Color:     "Silver",
-		},
-		{
-			Name:      "Smartphone X",
-			Price:     899.99,
-			Category:  "Electronics",
-			Available: false,
-			Weight:    0.3,
-			Color:     "Black",
-		},
-	}
-	productSectionOriginal := []Product{
-		{
-			Name:      "Laptop Pro",
-			Price:     1299.99,
-			Category:  "Electronics",
-			Available: true,
-			Weight:    2.5,
-			Color:     "Silver",
-		},
-		{
-			Name:      "Smartphone X",
-			Price:     899.99,
-			Category:  "Electronics",
-			Available: false,
-			Weight:    0.3,
-			Color:     "Black",
-		},
-	}
-	type HiddenSaleData struct {
-		HiddenFieldName  string
-		HiddenFieldValue interface{}
-	}
-	// Explicitly hidden data for demonstration
-	hiddenData := []HiddenSaleData{
-		{"Region", "North"},
-		{"Rep", "SecretAgent"},
+// BatchUpsertHandler handles POST /employees/batch with {"employees": [...]}.
+// Valid employees are created or updated together, the data lists the
+// status of every employee in request order.
+func (h *EmployeeHandler) BatchUpsertHandler(c echo.Context) error {
+	var req batchUpsertRequest
+	if err := serviceutils.Bind(c, &req); err != nil {
+		return respondValidationError(c, "Invalid request body", err)
 	}
 
-	// Convert Data
-	dynamicDataEditable, err := simpleexcel.ConvertToDynamicData(productSectionEditable)
+	results, err := h.svc.BatchUpsert(c.Request().Context(), req.Employees)
 	if err != nil {
-		return serviceutils.ResponseError(c, http.StatusInternalServerError, "Failed to convert dynamic data", err)
+		logger.ErrorLog(c.Request().Context(), "Failed to upsert employees: %v", err)
+		return respondValidationError(c, "Failed to upsert employees", err)
 	}
-	logger.InfoLog(c.Request().Context(), "Dynamic Data Editable: %+v", dynamicDataEditable)
+	return serviceutils.ResponseSuccess(c, http.StatusOK, "Employee batch processed", results)
+}
 
-	dynamicDataOriginal, err := simpleexcel.ConvertToDynamicData(productSectionOriginal)
-	if err != nil {
-		return serviceutils.ResponseError(c, http.StatusInternalServerError, "Failed to convert dynamic data", err)
+// employeeFilter reads the listing query parameters. Malformed values are
+// collected in the returned error, the criteria themselves are checked by
+// the service.
+func employeeFilter(c echo.Context) (domain.EmployeeFilter, *domain.ValidationError) {
+	verr := &domain.ValidationError{}
+	filter := domain.EmployeeFilter{
+		NamePrefix: strings.TrimSpace(c.QueryParam("name")),
+		Gender:     strings.ToUpper(c.QueryParam("gender")),
+		DeptNo:     c.QueryParam("dept_no"),
+	}
+	filter.Limit = parseQueryInt(verr, c, "limit")
+	filter.Offset = parseQueryInt(verr, c, "offset")
+	filter.HiredFrom = parseQueryDate(verr, c, "hired_from")
+	filter.HiredTo = parseQueryDate(verr, c, "hired_to")
+	if cursor := c.QueryParam("cursor"); cursor != "" {
+		id, err := service.ParseEmployeeCursor(cursor)
+		if err != nil {
+			verr.Add("cursor", "is not a cursor returned by this listing")
+		}
+		filter.AfterID = id
 	}
-	logger.InfoLog(c.Request().Context(), "Dynamic Data Original: %+v", dynamicDataOriginal)
+	return filter, verr
+}
 
-	// Initialize exporter with inline config
-	exporter, err := simpleexcel.NewDataExporterFromYamlConfig(yamlConfig)
+// parseQueryInt parses an optional integer query parameter, 0 when absent.
+func parseQueryInt(verr *domain.ValidationError, c echo.Context, name string) int {
+	raw := c.QueryParam(name)
+	if raw == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(raw)
 	if err != nil {
-		return serviceutils.ResponseError(c, http.StatusInternalServerError, "Failed to parse inline report config", err)
+		verr.Add(name, "must be an integer, got %q", raw)
 	}
+	return n
+}
 
-	// Register a simple currency formatter for demonstration
-	exporter.RegisterFormatter("currency", func(v interface{}) interface{} {
-		if val, ok := v.(float64); ok {
-			return fmt.Sprintf("$%.2f", val)
-		}
-		return v
-	})
-
-	// Bind data
-	exporter.
-		BindSectionData("product_section_editable", dynamicDataEditable).
-		BindSectionData("product_section_original", dynamicDataOriginal)
-
-	// Demonstrate Mixed Config: Add a hidden section programmatically to the existing sheet
-	if sheet := exporter.GetSheet("Executive Report"); sheet != nil {
-		sheet.AddSection(&simpleexcel.SectionConfig{
-			Title:      "Additional Hidden Data",
-			Type:       simpleexcel.SectionTypeHidden,
-			Data:       hiddenData,
-			ShowHeader: true,
-			Columns: []simpleexcel.ColumnConfig{
-				{FieldName: "HiddenFieldName", Header: "Field Name", Width: 20},
-				{FieldName: "HiddenFieldValue", Header: "Field Value", Width: 20},
-			},
-		})
+// parseQueryDate parses an optional YYYY-MM-DD query parameter, the zero
+// time when absent.
+func parseQueryDate(verr *domain.ValidationError, c echo.Context, name string) time.Time {
+	raw := c.QueryParam(name)
+	if raw == "" {
+		return time.Time{}
 	}
-
-	// Export to bytes
-	excelBytes, err := exporter.ToBytes()
+	d, err := time.Parse("2006-01-02", raw)
 	if err != nil {
-		return serviceutils.ResponseError(c, http.StatusInternalServerError, "Failed to generate Excel file", err)
+		verr.Add(name, "must be a date (YYYY-MM-DD), got %q", raw)
 	}
+	return d
+}
 
-	// Set headers for file download
-	c.Response().Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
-	c.Response().Header().Set("Content-Disposition", `attachment; filename="executive_report.xlsx"`)
-	c.Response().Header().Set("Content-Length", strconv.Itoa(len(excelBytes)))
-
-	// Write response
-	_, err = c.Response().Write(excelBytes)
-	return err
+// respondValidationError answers err with the status of its kind, see
+// serviceutils.ResponseAppError.
+func respondValidationError(c echo.Context, message string, err error) error {
+	return serviceutils.ResponseAppError(c, message, err)
 }
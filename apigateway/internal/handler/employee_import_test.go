@@ -0,0 +1,260 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/handler"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// stubEmployeeRepo serves a fixed employee list and records batch updates;
+// the other repository methods are unused.
+type stubEmployeeRepo struct {
+	domain.EmployeeRepository
+	employees []domain.Employee
+	managers  []domain.DeptManager
+	updated   []domain.Employee
+	chunks    int
+	filter    domain.EmployeeFilter
+}
+
+func (r *stubEmployeeRepo) GetManagers(ctx context.Context, deptNo string) ([]domain.DeptManager, error) {
+	return r.managers, nil
+}
+
+func (r *stubEmployeeRepo) ListDepartmentEmployees(ctx context.Context, deptNo string) ([]domain.Employee, error) {
+	return r.employees, nil
+}
+
+func (r *stubEmployeeRepo) GetByID(ctx context.Context, id int) (*domain.Employee, error) {
+	for i := range r.employees {
+		if r.employees[i].ID == id {
+			return &r.employees[i], nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *stubEmployeeRepo) List(ctx context.Context, filter domain.EmployeeFilter) ([]domain.Employee, error) {
+	r.filter = filter
+	return r.employees, nil
+}
+
+func (r *stubEmployeeRepo) ListChunks(ctx context.Context, filter domain.EmployeeFilter, chunkSize int, fn func([]domain.Employee) error) error {
+	r.chunks, r.filter = 0, filter
+	for start := 0; start < len(r.employees); start += chunkSize {
+		end := start + chunkSize
+		if end > len(r.employees) {
+			end = len(r.employees)
+		}
+		r.chunks++
+		if err := fn(r.employees[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *stubEmployeeRepo) BatchUpdate(ctx context.Context, employees []domain.Employee) error {
+	for i, e := range employees {
+		if e.ID == 404 {
+			return &domain.BatchItemError{Index: i, Err: sql.ErrNoRows}
+		}
+	}
+	r.updated = employees
+	return nil
+}
+
+func (r *stubEmployeeRepo) BatchUpsert(ctx context.Context, employees []domain.Employee) (map[int]bool, error) {
+	inserted := make(map[int]bool, len(employees))
+	for _, e := range employees {
+		existing, _ := r.GetByID(ctx, e.ID)
+		if existing != nil {
+			*existing = e
+		} else {
+			r.employees = append(r.employees, e)
+		}
+		inserted[e.ID] = existing == nil
+	}
+	return inserted, nil
+}
+
+// stubTx runs functions directly, the stub repositories are not transactional.
+type stubTx struct{}
+
+func (stubTx) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+var editChecksumKey = []byte("test-checksum-key")
+
+func newEditReports(empSvc service.EmployeeService) service.ReportService {
+	reportSvc := service.NewReportService(nil)
+	reportSvc.Register(service.NewEmployeeEditReport(empSvc, "../../templates/employee_edit.yaml", editChecksumKey))
+	return reportSvc
+}
+
+func newImportHandler(empSvc service.EmployeeService) *handler.EmployeeHandler {
+	return handler.NewEmployeeHandler(empSvc, newEditReports(empSvc))
+}
+
+// exportEditWorkbook exports the employee_edit report and returns the workbook bytes.
+func exportEditWorkbook(t *testing.T, empSvc service.EmployeeService) []byte {
+	reportSvc := newEditReports(empSvc)
+	var buf bytes.Buffer
+	require.NoError(t, reportSvc.Generate(context.Background(), &domain.ExportRequest{TemplateID: "employee_edit"}, &buf))
+	return buf.Bytes()
+}
+
+// editWorkbook simulates a user editing cells of the Employees sheet.
+func editWorkbook(t *testing.T, workbook []byte, cells map[string]string) []byte {
+	f, err := excelize.OpenReader(bytes.NewReader(workbook))
+	require.NoError(t, err)
+	defer f.Close()
+	for cell, val := range cells {
+		require.NoError(t, f.SetCellValue("Employees", cell, val))
+	}
+	buf, err := f.WriteToBuffer()
+	require.NoError(t, err)
+	return buf.Bytes()
+}
+
+func postImport(t *testing.T, h *handler.EmployeeHandler, workbook []byte) (*httptest.ResponseRecorder, map[string]interface{}) {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, err := mw.CreateFormFile("file", "employees.xlsx")
+	require.NoError(t, err)
+	_, err = fw.Write(workbook)
+	require.NoError(t, err)
+	require.NoError(t, mw.Close())
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/employees/import", &body)
+	req.Header.Set(echo.HeaderContentType, mw.FormDataContentType())
+	rec := httptest.NewRecorder()
+	require.NoError(t, h.ImportHandler(e.NewContext(req, rec)))
+
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return rec, resp
+}
+
+func testEmployees() []domain.Employee {
+	hired := time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)
+	return []domain.Employee{
+		{ID: 10001, FirstName: "Georgi", LastName: "Facello", Gender: "M", HireDate: hired},
+		{ID: 10002, FirstName: "Bezalel", LastName: "Simmel", Gender: "F", HireDate: hired},
+	}
+}
+
+func TestImportHandler_AppliesEdits(t *testing.T) {
+	repo := &stubEmployeeRepo{employees: testEmployees()}
+	empSvc := service.NewEmployeeService(repo, stubTx{})
+	workbook := editWorkbook(t, exportEditWorkbook(t, empSvc), map[string]string{"C5": "Sim"})
+
+	rec, resp := postImport(t, newImportHandler(empSvc), workbook)
+
+	assert.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, float64(2), resp["Data"].(map[string]interface{})["updated"])
+	require.Len(t, repo.updated, 2)
+	assert.Equal(t, "Facello", repo.updated[0].LastName)
+	assert.Equal(t, "Sim", repo.updated[1].LastName)
+}
+
+func TestImportHandler_RowErrors(t *testing.T) {
+	repo := &stubEmployeeRepo{employees: testEmployees()}
+	empSvc := service.NewEmployeeService(repo, stubTx{})
+	workbook := editWorkbook(t, exportEditWorkbook(t, empSvc), map[string]string{"D4": "X", "B5": ""})
+
+	rec, resp := postImport(t, newImportHandler(empSvc), workbook)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Nil(t, repo.updated, "nothing is applied when a row fails")
+	errs := resp["Data"].([]interface{})
+	require.Len(t, errs, 2)
+	assert.Equal(t, "D4", errs[0].(map[string]interface{})["cell"])
+	assert.Equal(t, "gender", errs[0].(map[string]interface{})["field"])
+	assert.Equal(t, "enum", errs[0].(map[string]interface{})["rule"])
+	assert.Equal(t, "Gender must be M or F", errs[0].(map[string]interface{})["message"])
+	assert.Equal(t, "B5", errs[1].(map[string]interface{})["cell"])
+	assert.Equal(t, "required", errs[1].(map[string]interface{})["rule"])
+}
+
+func TestImportHandler_LengthRule(t *testing.T) {
+	repo := &stubEmployeeRepo{employees: testEmployees()}
+	empSvc := service.NewEmployeeService(repo, stubTx{})
+	workbook := editWorkbook(t, exportEditWorkbook(t, empSvc), map[string]string{"B4": "Maximilianoooooo"})
+
+	rec, resp := postImport(t, newImportHandler(empSvc), workbook)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	errs := resp["Data"].([]interface{})
+	require.Len(t, errs, 1)
+	assert.Equal(t, "max", errs[0].(map[string]interface{})["rule"])
+}
+
+func TestExportEditWorkbook_HasDataValidation(t *testing.T) {
+	empSvc := service.NewEmployeeService(&stubEmployeeRepo{employees: testEmployees()}, stubTx{})
+	f, err := excelize.OpenReader(bytes.NewReader(exportEditWorkbook(t, empSvc)))
+	require.NoError(t, err)
+	defer f.Close()
+
+	dvs, err := f.GetDataValidations("Employees")
+	require.NoError(t, err)
+	types := make(map[string]string)
+	for _, dv := range dvs {
+		types[dv.Sqref] = dv.Type
+	}
+	assert.Equal(t, "list", types["D4:D5"])
+	assert.Equal(t, "textLength", types["B4:B5"])
+}
+
+func TestImportHandler_UnknownEmployee(t *testing.T) {
+	repo := &stubEmployeeRepo{employees: testEmployees()}
+	repo.employees[1].ID = 404
+	empSvc := service.NewEmployeeService(repo, stubTx{})
+
+	rec, resp := postImport(t, newImportHandler(empSvc), exportEditWorkbook(t, empSvc))
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	errs := resp["Data"].([]interface{})
+	require.Len(t, errs, 1)
+	assert.Equal(t, float64(5), errs[0].(map[string]interface{})["row"])
+}
+
+func TestImportHandler_NotAWorkbook(t *testing.T) {
+	empSvc := service.NewEmployeeService(&stubEmployeeRepo{}, stubTx{})
+
+	rec, _ := postImport(t, newImportHandler(empSvc), []byte("name,gender\n"))
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+}
+
+func TestImportHandler_LockedCellEdited(t *testing.T) {
+	repo := &stubEmployeeRepo{employees: testEmployees()}
+	empSvc := service.NewEmployeeService(repo, stubTx{})
+	// A4 holds the locked employee number of the first row
+	workbook := editWorkbook(t, exportEditWorkbook(t, empSvc), map[string]string{"A4": "10002", "B4": "Georg"})
+
+	rec, resp := postImport(t, newImportHandler(empSvc), workbook)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rec.Code)
+	assert.Nil(t, repo.updated)
+	errs := resp["Data"].([]interface{})
+	require.Len(t, errs, 1)
+	assert.Equal(t, "checksum", errs[0].(map[string]interface{})["rule"])
+	assert.Contains(t, errs[0].(map[string]interface{})["message"], `"employees"`)
+}
@@ -0,0 +1,101 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/handler"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func getEmployees(t *testing.T, repo *stubEmployeeRepo, query string) *httptest.ResponseRecorder {
+	h := handler.NewEmployeeHandler(service.NewEmployeeService(repo, stubTx{}), nil)
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/employees"+query, nil)
+	rec := httptest.NewRecorder()
+	require.NoError(t, h.ListHandler(e.NewContext(req, rec)))
+	return rec
+}
+
+func TestEmployeeList_Filters(t *testing.T) {
+	repo := &stubEmployeeRepo{employees: testEmployees()}
+
+	rec := getEmployees(t, repo, "?limit=20&offset=40&name=+Geo+&gender=f&hired_from=1990-01-01&hired_to=1995-12-31&dept_no=d005")
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, domain.EmployeeFilter{
+		Limit:      20,
+		Offset:     40,
+		NamePrefix: "Geo",
+		Gender:     "F",
+		HiredFrom:  time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC),
+		HiredTo:    time.Date(1995, 12, 31, 0, 0, 0, 0, time.UTC),
+		DeptNo:     "d005",
+	}, repo.filter)
+}
+
+func TestEmployeeList_Cursor(t *testing.T) {
+	repo := &stubEmployeeRepo{employees: testEmployees()}
+
+	rec := getEmployees(t, repo, "?limit=2")
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp struct {
+		NextCursor string `json:"next_cursor"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.NotEmpty(t, resp.NextCursor, "a full page has a next page cursor")
+
+	rec = getEmployees(t, repo, "?limit=2&cursor="+resp.NextCursor)
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, domain.EmployeeFilter{Limit: 2, AfterID: 10002}, repo.filter)
+
+	rec = getEmployees(t, repo, "?limit=3")
+
+	resp.NextCursor = ""
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Empty(t, resp.NextCursor, "a short page is the last")
+}
+
+func TestEmployeeList_RejectsInvalidQuery(t *testing.T) {
+	tests := []struct {
+		query  string
+		fields []string
+	}{
+		{"?limit=ten&hired_from=01/02/1990", []string{"limit", "hired_from"}},
+		{"?offset=-1&gender=X", []string{"offset", "gender"}},
+		{"?hired_from=1995-01-01&hired_to=1990-01-01", []string{"hired_to"}},
+		{"?cursor=not-a-cursor", []string{"cursor"}},
+		{"?cursor=" + domain.EncodeCursor(10002) + "&offset=10", []string{"cursor"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			repo := &stubEmployeeRepo{employees: testEmployees()}
+
+			rec := getEmployees(t, repo, tt.query)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			var resp struct {
+				Data []struct {
+					Field string `json:"field"`
+				}
+			}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			var fields []string
+			for _, fe := range resp.Data {
+				fields = append(fields, fe.Field)
+			}
+			assert.ElementsMatch(t, tt.fields, fields)
+			assert.Equal(t, domain.EmployeeFilter{}, repo.filter, "the repository is not queried")
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/apperror"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/logger"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service/serviceutils"
+)
+
+// ErrorHandler is the echo.HTTPErrorHandler of the API. It answers the
+// errors returned by handlers and middleware with the error envelope:
+// echo.HTTPErrors with their status, other errors with the status of their
+// apperror.Kind, see serviceutils.ResponseAppError. Internal errors are
+// logged, recovered panics with their stack.
+func ErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+	ctx := c.Request().Context()
+
+	var herr *echo.HTTPError
+	var perr *panicError
+	var status int
+	var werr error
+	switch {
+	case errors.As(err, &herr):
+		status = herr.Code
+		werr = serviceutils.ResponseError(c, status, fmt.Sprint(herr.Message), herr.Internal)
+	case errors.As(err, &perr):
+		status = http.StatusInternalServerError
+		ctx = logger.WithLogger(ctx, map[string]interface{}{"stack": string(perr.stack)})
+		// The panic value may hold anything, keep it in the logs
+		werr = serviceutils.ResponseError(c, status, http.StatusText(status), nil)
+	default:
+		status = serviceutils.HTTPStatus(apperror.KindOf(err))
+		werr = serviceutils.ResponseAppError(c, http.StatusText(status), err)
+	}
+	if status >= http.StatusInternalServerError {
+		logger.ErrorLog(ctx, "%s %s failed: %v", c.Request().Method, c.Request().URL.Path, err)
+	}
+	if werr != nil {
+		logger.ErrorLog(ctx, "failed to write the error response: %v", werr)
+	}
+}
+
+// panicError is a panic recovered by RecoverMiddleware.
+type panicError struct {
+	value interface{}
+	stack []byte
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.value)
+}
+
+// RecoverMiddleware turns the panics of the next handlers into errors,
+// answered with 500 and logged with the stack of the panic by ErrorHandler.
+// http.ErrAbortHandler is re-panicked to abort the response, as net/http
+// expects.
+func RecoverMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) (err error) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			if r == http.ErrAbortHandler {
+				panic(r)
+			}
+			err = &panicError{value: r, stack: debug.Stack()}
+		}()
+		return next(c)
+	}
+}
@@ -0,0 +1,64 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/apperror"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/handler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{"typed conflict", fmt.Errorf("review: %w", apperror.Conflict("leave_not_pending", "leave request is not pending")), http.StatusConflict, "leave_not_pending"},
+		{"typed not found", apperror.NotFound("report_not_found", "report not found"), http.StatusNotFound, "report_not_found"},
+		{"validation", &domain.ValidationError{Errors: []domain.FieldError{{Field: "name", Message: "is required"}}}, http.StatusBadRequest, apperror.CodeValidation},
+		{"echo not found", echo.ErrNotFound, http.StatusNotFound, "not_found"},
+		{"untyped", fmt.Errorf("connection refused"), http.StatusInternalServerError, apperror.CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+
+			handler.ErrorHandler(tt.err, c)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			var body map[string]interface{}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+			assert.Equal(t, false, body["Success"])
+			assert.Equal(t, tt.wantCode, body["code"])
+		})
+	}
+}
+
+func TestRecoverMiddleware(t *testing.T) {
+	e := echo.New()
+	e.HTTPErrorHandler = handler.ErrorHandler
+	e.Use(handler.RecoverMiddleware)
+	e.GET("/boom", func(c echo.Context) error {
+		panic("secret detail")
+	})
+	rec := httptest.NewRecorder()
+
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/boom", nil))
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, apperror.CodeInternal, body["code"])
+	assert.NotContains(t, rec.Body.String(), "secret detail", "the panic value stays in the logs")
+}
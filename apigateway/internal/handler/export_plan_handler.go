@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/logger"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service/serviceutils"
+)
+
+// maxExportPlanSize bounds the export plans accepted.
+const maxExportPlanSize = 1 << 20
+
+type ExportPlanHandler struct {
+	svc service.ExportPlanService
+}
+
+func NewExportPlanHandler(svc service.ExportPlanService) *ExportPlanHandler {
+	return &ExportPlanHandler{svc: svc}
+}
+
+// RunHandler handles POST /reports/plans/run. The body is the YAML plan; when
+// any step delivers to the plan archive the response is the zip, otherwise
+// the plan result as JSON. An Idempotency-Key header overrides the plan's
+// idempotency_key.
+func (h *ExportPlanHandler) RunHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, maxExportPlanSize+1))
+	if err != nil {
+		return serviceutils.ResponseError(c, http.StatusBadRequest, "Invalid request body", err)
+	}
+	if len(body) > maxExportPlanSize {
+		return serviceutils.ResponseError(c, http.StatusRequestEntityTooLarge, "Export plan too large",
+			fmt.Errorf("export plans are limited to %d bytes", maxExportPlanSize))
+	}
+	plan, err := h.svc.Parse(body)
+	if err != nil {
+		return respondExportError(c, err)
+	}
+	if key := c.Request().Header.Get("Idempotency-Key"); key != "" {
+		plan.IdempotencyKey = key
+	}
+	// Reject bad plans before any step runs
+	if err := h.svc.Validate(ctx, plan); err != nil {
+		return respondExportError(c, err)
+	}
+
+	archived := false
+	for i := range plan.Reports {
+		archived = archived || plan.Reports[i].Archived()
+	}
+	if !archived {
+		result, err := h.svc.Run(ctx, plan, nil)
+		if err != nil {
+			return respondExportError(c, err)
+		}
+		return serviceutils.ResponseSuccess(c, http.StatusOK, "Export plan finished", result)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, "application/zip")
+	c.Response().Header().Set(echo.HeaderContentDisposition,
+		fmt.Sprintf(`attachment; filename="plan_%s.zip"`, time.Now().Format("20060102_150405")))
+	if _, err := h.svc.Run(ctx, plan, c.Response()); err != nil {
+		logger.ErrorLog(ctx, "Failed to run export plan %s: %v", plan.Name, err)
+		if c.Response().Committed {
+			return err
+		}
+		c.Response().Header().Del(echo.HeaderContentDisposition)
+		return respondExportError(c, err)
+	}
+	return nil
+}
+
+// DeliveriesHandler handles GET /reports/plans/runs/:id/deliveries
+func (h *ExportPlanHandler) DeliveriesHandler(c echo.Context) error {
+	deliveries, err := h.svc.Deliveries(c.Request().Context(), c.Param("id"))
+	if err != nil {
+		return serviceutils.ResponseError(c, http.StatusInternalServerError, "Failed to load delivery history", err)
+	}
+	return serviceutils.ResponseSuccess(c, http.StatusOK, "Delivery history retrieved successfully", deliveries)
+}
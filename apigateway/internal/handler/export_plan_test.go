@@ -0,0 +1,403 @@
+package handler_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/handler"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/simpleexcelv2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPlanTemplate = `
+sheets:
+  - name: "People"
+    sections:
+      - id: "people"
+        show_header: true
+        columns:
+          - field_name: "Name"
+            header: "Name"
+          - field_name: "Email"
+            header: "Email"
+`
+
+// memoryBucket keeps the uploaded objects by bucket/key.
+type memoryBucket map[string]*bytes.Buffer
+
+type memoryObject struct{ *bytes.Buffer }
+
+func (memoryObject) Close() error { return nil }
+
+func (m memoryBucket) NewWriter(ctx context.Context, bucket, key string) (io.WriteCloser, error) {
+	buf := &bytes.Buffer{}
+	m[bucket+"/"+key] = buf
+	return memoryObject{buf}, nil
+}
+
+// memoryDeliveries is an in-memory ReportDeliveryRepository.
+type memoryDeliveries struct {
+	mu      sync.Mutex
+	records []domain.ReportDelivery
+}
+
+func (m *memoryDeliveries) Record(ctx context.Context, d *domain.ReportDelivery) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d.ID, d.CreatedAt = len(m.records)+1, time.Now()
+	m.records = append(m.records, *d)
+	return nil
+}
+
+func (m *memoryDeliveries) FindDelivered(ctx context.Context, key string) (*domain.ReportDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, d := range m.records {
+		if d.IdempotencyKey == key && d.Status == domain.DeliveryDelivered {
+			return &d, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (m *memoryDeliveries) LatestDelivered(ctx context.Context, target string) (*domain.ReportDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var latest *domain.ReportDelivery
+	for i, d := range m.records {
+		if d.Target == target && d.Status == domain.DeliveryDelivered && (latest == nil || d.DataAsOf.After(latest.DataAsOf)) {
+			latest = &m.records[i]
+		}
+	}
+	if latest == nil {
+		return nil, sql.ErrNoRows
+	}
+	copied := *latest
+	return &copied, nil
+}
+
+func (m *memoryDeliveries) ListByRun(ctx context.Context, runID string) ([]domain.ReportDelivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []domain.ReportDelivery
+	for _, d := range m.records {
+		if d.RunID == runID {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+func newTestPlanHandler(t *testing.T) (*handler.ExportPlanHandler, memoryBucket, *memoryDeliveries) {
+	path := filepath.Join(t.TempDir(), "people.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testPlanTemplate), 0o644))
+
+	load := func(ctx context.Context, vars simpleexcelv2.VariableValues) (map[string]interface{}, error) {
+		return map[string]interface{}{
+			"people": []testPerson{{Name: "Alice", Email: "alice@example.com"}},
+		}, nil
+	}
+	bucket := memoryBucket{}
+	svc := service.NewReportService(map[string]simpleexcelv2.ObjectWriterProvider{domain.DeliveryS3: bucket})
+	svc.Register(service.ReportDefinition{ID: "people", TemplatePath: path, Load: load})
+	svc.Register(service.ReportDefinition{
+		ID:           "broken",
+		TemplatePath: path,
+		Load: func(ctx context.Context, vars simpleexcelv2.VariableValues) (map[string]interface{}, error) {
+			return nil, errors.New("database is down")
+		},
+	})
+	deliveries := &memoryDeliveries{}
+	return handler.NewExportPlanHandler(service.NewExportPlanService(svc, deliveries)), bucket, deliveries
+}
+
+func postPlan(t *testing.T, h *handler.ExportPlanHandler, body string) *httptest.ResponseRecorder {
+	return postPlanWithKey(t, h, body, "")
+}
+
+func postPlanWithKey(t *testing.T, h *handler.ExportPlanHandler, body, idempotencyKey string) *httptest.ResponseRecorder {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/reports/plans/run", strings.NewReader(body))
+	if idempotencyKey != "" {
+		req.Header.Set("Idempotency-Key", idempotencyKey)
+	}
+	rec := httptest.NewRecorder()
+	require.NoError(t, h.RunHandler(e.NewContext(req, rec)))
+	return rec
+}
+
+func readPlanArchive(t *testing.T, rec *httptest.ResponseRecorder) (map[string][]byte, domain.ExportPlanResult) {
+	zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	require.NoError(t, err)
+	files := make(map[string][]byte)
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		require.NoError(t, err)
+		files[f.Name], err = io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+	}
+	var result domain.ExportPlanResult
+	require.NoError(t, json.Unmarshal(files["plan_result.json"], &result))
+	return files, result
+}
+
+func TestExportPlan_Archive(t *testing.T) {
+	h, _, _ := newTestPlanHandler(t)
+
+	rec := postPlan(t, h, `
+name: monthly
+concurrency: 2
+reports:
+  - id: staff
+    template_id: people
+  - id: staff_csv
+    template_id: people
+    format: csv
+    delivery:
+      type: zip
+      key: exports/staff.csv
+    after: [staff]
+`)
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, "application/zip", rec.Header().Get(echo.HeaderContentType))
+	assert.Contains(t, rec.Header().Get(echo.HeaderContentDisposition), `filename="plan_`)
+
+	files, result := readPlanArchive(t, rec)
+	assert.Len(t, files, 3)
+	assert.NotEmpty(t, files["staff.xlsx"])
+	assert.Contains(t, string(files["exports/staff.csv"]), "Name,Email\nAlice,alice@example.com\n")
+
+	assert.Equal(t, "monthly", result.Name)
+	assert.True(t, result.Succeeded)
+	require.Len(t, result.Steps, 2)
+	assert.Equal(t, domain.PlanStepSucceeded, result.Steps[1].Status)
+	assert.Equal(t, "exports/staff.csv", result.Steps[1].Target)
+	assert.Equal(t, 1, result.Steps[1].Attempts)
+	assert.False(t, result.Steps[1].StartedAt.Before(result.Steps[0].FinishedAt), "runs after its dependency")
+}
+
+func TestExportPlan_SkipsDependentsOfFailedSteps(t *testing.T) {
+	h, _, _ := newTestPlanHandler(t)
+
+	rec := postPlan(t, h, `
+reports:
+  - id: broken
+    template_id: broken
+  - id: summary
+    template_id: people
+    after: [broken]
+  - id: staff
+    template_id: people
+`)
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	files, result := readPlanArchive(t, rec)
+	assert.False(t, result.Succeeded)
+	assert.Equal(t, domain.PlanStepFailed, result.Steps[0].Status)
+	assert.Contains(t, result.Steps[0].Error, "database is down")
+	assert.Equal(t, domain.PlanStepSkipped, result.Steps[1].Status)
+	assert.Equal(t, `step "broken" did not succeed`, result.Steps[1].Error)
+	assert.Equal(t, domain.PlanStepSucceeded, result.Steps[2].Status)
+	assert.NotContains(t, files, "summary.xlsx")
+	assert.Contains(t, files, "staff.xlsx")
+}
+
+func TestExportPlan_RejectsInvalidPlan(t *testing.T) {
+	tests := []struct {
+		name   string
+		plan   string
+		fields []string
+	}{
+		{
+			name:   "malformed",
+			plan:   "reports: [",
+			fields: []string{"plan"},
+		},
+		{
+			name:   "unknown key",
+			plan:   "reportz: []",
+			fields: []string{"plan"},
+		},
+		{
+			name: "cycle",
+			plan: `
+reports:
+  - {id: a, template_id: people, after: [b]}
+  - {id: b, template_id: people, after: [a]}
+`,
+			fields: []string{"reports"},
+		},
+		{
+			name: "bad steps",
+			plan: `
+retries: 9
+reports:
+  - {id: a, template_id: nope}
+  - {id: a, template_id: people, after: [z]}
+  - {id: c, template_id: people, delivery: {type: email}}
+  - {id: d, template_id: people, format: pdf, delivery: {key: a.xlsx}}
+`,
+			fields: []string{
+				"retries",
+				"reports[0].template_id",
+				"reports[1].id",
+				"reports[1].delivery.key",
+				"reports[2].delivery.type",
+				"reports[3].delivery.key",
+				"reports[3].format",
+				"reports[1].after",
+			},
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h, _, _ := newTestPlanHandler(t)
+			rec := postPlan(t, h, tc.plan)
+
+			require.Equal(t, http.StatusBadRequest, rec.Code, rec.Body.String())
+			var resp struct {
+				Data []domain.FieldError `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			fields := make([]string, len(resp.Data))
+			for i, fe := range resp.Data {
+				fields[i] = fe.Field
+			}
+			assert.Equal(t, tc.fields, fields)
+		})
+	}
+}
+
+func TestExportPlan_BucketDeliveryReturnsResult(t *testing.T) {
+	h, bucket, _ := newTestPlanHandler(t)
+
+	rec := postPlan(t, h, `
+reports:
+  - id: staff
+    template_id: people
+    format: csv
+    delivery: {type: s3, bucket: reports, key: staff.csv}
+  - id: broken
+    template_id: broken
+    delivery: {type: s3, bucket: reports, key: broken.xlsx}
+`)
+
+	// Nothing goes to the archive, the result is returned as JSON
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, echo.MIMEApplicationJSONCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+	var resp struct {
+		Data domain.ExportPlanResult `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.False(t, resp.Data.Succeeded)
+	assert.Equal(t, domain.PlanStepSucceeded, resp.Data.Steps[0].Status)
+	assert.Equal(t, "s3://reports/staff.csv", resp.Data.Steps[0].Target)
+	assert.Contains(t, bucket["reports/staff.csv"].String(), "Alice,alice@example.com")
+	assert.Equal(t, domain.PlanStepFailed, resp.Data.Steps[1].Status)
+}
+
+const bucketPlan = `
+reports:
+  - id: staff
+    template_id: people
+    format: csv
+    delivery: {type: s3, bucket: reports, key: staff.csv}
+`
+
+func planResult(t *testing.T, rec *httptest.ResponseRecorder) domain.ExportPlanResult {
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp struct {
+		Data domain.ExportPlanResult `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return resp.Data
+}
+
+func TestExportPlan_IdempotentDelivery(t *testing.T) {
+	h, bucket, _ := newTestPlanHandler(t)
+
+	first := planResult(t, postPlanWithKey(t, h, bucketPlan, "payroll-2024-05"))
+	assert.Equal(t, domain.DeliveryDelivered, first.Steps[0].Delivery)
+
+	// The job is retried: the file is not delivered again
+	delete(bucket, "reports/staff.csv")
+	second := planResult(t, postPlanWithKey(t, h, bucketPlan, "payroll-2024-05"))
+	assert.True(t, second.Succeeded)
+	assert.NotEqual(t, first.RunID, second.RunID)
+	assert.Equal(t, domain.DeliveryDeduplicated, second.Steps[0].Delivery)
+	assert.NotContains(t, bucket, "reports/staff.csv")
+
+	// Another job delivers again
+	third := planResult(t, postPlanWithKey(t, h, bucketPlan, "payroll-2024-06"))
+	assert.Equal(t, domain.DeliveryDelivered, third.Steps[0].Delivery)
+	assert.Contains(t, bucket, "reports/staff.csv")
+}
+
+func TestExportPlan_KeepsNewerArtifacts(t *testing.T) {
+	h, bucket, deliveries := newTestPlanHandler(t)
+	// A later run already delivered fresher data to the target
+	require.NoError(t, deliveries.Record(context.Background(), &domain.ReportDelivery{
+		RunID:    "later-run",
+		StepID:   "staff",
+		Target:   "s3://reports/staff.csv",
+		Status:   domain.DeliveryDelivered,
+		DataAsOf: time.Now().Add(time.Hour),
+	}))
+
+	result := planResult(t, postPlan(t, h, bucketPlan))
+	assert.Equal(t, domain.PlanStepSucceeded, result.Steps[0].Status)
+	assert.Equal(t, domain.DeliverySuperseded, result.Steps[0].Delivery)
+	assert.NotContains(t, bucket, "reports/staff.csv")
+}
+
+func TestExportPlan_DeliveryHistory(t *testing.T) {
+	h, _, _ := newTestPlanHandler(t)
+	result := planResult(t, postPlan(t, h, bucketPlan+`
+  - id: broken
+    template_id: broken
+    delivery: {type: s3, bucket: reports, key: broken.xlsx}
+`))
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/reports/plans/runs/"+result.RunID+"/deliveries", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues(result.RunID)
+	require.NoError(t, h.DeliveriesHandler(c))
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp struct {
+		Data []domain.ReportDelivery `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Data, 2)
+	byStep := map[string]domain.ReportDelivery{}
+	for _, d := range resp.Data {
+		byStep[d.StepID] = d
+	}
+	assert.Equal(t, domain.DeliveryDelivered, byStep["staff"].Status)
+	assert.Equal(t, "s3://reports/staff.csv", byStep["staff"].Target)
+	assert.Len(t, byStep["staff"].IdempotencyKey, 64)
+	assert.Equal(t, domain.DeliveryFailed, byStep["broken"].Status)
+	assert.Contains(t, byStep["broken"].Error, "database is down")
+}
@@ -1,7 +1,6 @@
 package handler
 
 import (
-	"fmt"
 	"net/http"
 	"strconv"
 
@@ -27,7 +26,7 @@ func (h *GCPDemoHandler) CreateTaskListHandler(c echo.Context) error {
 	}
 
 	if err := h.client.CreateTaskList(ctx, &list); err != nil {
-		logger.ErrorLog(ctx, fmt.Sprintf("failed to create task list: %v", err))
+		logger.ErrorLog(ctx, "failed to create task list: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to create task list")
 	}
 
@@ -44,7 +43,7 @@ func (h *GCPDemoHandler) CreateTaskHandler(c echo.Context) error {
 	}
 
 	if err := h.client.CreateTask(ctx, taskListID, &task); err != nil {
-		logger.ErrorLog(ctx, fmt.Sprintf("failed to create task: %v", err))
+		logger.ErrorLog(ctx, "failed to create task: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to create task")
 	}
 
@@ -58,7 +57,7 @@ func (h *GCPDemoHandler) ListTasksHandler(c echo.Context) error {
 
 	tasks, err := h.client.ListTasksByList(ctx, taskListID)
 	if err != nil {
-		logger.ErrorLog(ctx, fmt.Sprintf("failed to list tasks: %v", err))
+		logger.ErrorLog(ctx, "failed to list tasks: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed to list tasks")
 	}
 
@@ -76,7 +75,7 @@ func (h *GCPDemoHandler) ComplexQueryHandler(c echo.Context) error {
 
 	tasks, err := h.client.ListAllTasksComplex(ctx, minPriority, done)
 	if err != nil {
-		logger.ErrorLog(ctx, fmt.Sprintf("failed complex query: %v", err))
+		logger.ErrorLog(ctx, "failed complex query: %v", err)
 		return echo.NewHTTPError(http.StatusInternalServerError, "failed complex query")
 	}
 
@@ -0,0 +1,81 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/handler"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// stubHeadcountRepo counts two departments over any range and records it.
+type stubHeadcountRepo struct {
+	*stubEmployeeRepo
+	from, to time.Time
+}
+
+func (r *stubHeadcountRepo) CountHeadcountByMonth(ctx context.Context, from, to time.Time) ([]domain.HeadcountPoint, error) {
+	r.from, r.to = from, to
+	var points []domain.HeadcountPoint
+	for month, n := from, 0; !month.After(to); month, n = month.AddDate(0, 1, 0), n+1 {
+		points = append(points,
+			domain.HeadcountPoint{Month: month.Format("2006-01"), DeptNo: "d001", DeptName: "Marketing", Headcount: 10 + n},
+			domain.HeadcountPoint{Month: month.Format("2006-01"), DeptNo: "d002", DeptName: "Finance", Headcount: 5},
+		)
+	}
+	return points, nil
+}
+
+func newHeadcountReports(repo *stubHeadcountRepo) service.ReportService {
+	reportSvc := service.NewReportService(nil)
+	reportSvc.Register(service.NewHeadcountTrendReport(service.NewEmployeeService(repo, stubTx{}), "../../templates/headcount_trend.yaml"))
+	return reportSvc
+}
+
+func TestHeadcountTrendReport(t *testing.T) {
+	repo := &stubHeadcountRepo{stubEmployeeRepo: &stubEmployeeRepo{}}
+
+	var buf bytes.Buffer
+	require.NoError(t, newHeadcountReports(repo).Generate(context.Background(), &domain.ExportRequest{
+		TemplateID: service.HeadcountTrendReportID,
+		Variables:  map[string]interface{}{"to": "2024-03-20", "months": 3},
+	}, &buf))
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), repo.from)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), repo.to)
+
+	f, err := excelize.OpenReader(&buf)
+	require.NoError(t, err)
+	defer f.Close()
+	assert.Equal(t, []string{"Trend", "Data"}, f.GetSheetList())
+
+	trend, err := f.GetRows("Trend")
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"Headcount by Department"},
+		{"Month", "Finance", "Marketing"},
+		{"2024-01", "5", "10"},
+		{"2024-02", "5", "11"},
+		{"2024-03", "5", "12"},
+	}, trend)
+
+	data, err := f.GetRows("Data")
+	require.NoError(t, err)
+	require.Len(t, data, 8)
+	assert.Equal(t, []string{"2024-03", "d001", "Marketing", "12"}, data[6])
+}
+
+func TestHeadcountTrendReport_RejectsMonths(t *testing.T) {
+	h := handler.NewReportHandler(newHeadcountReports(&stubHeadcountRepo{stubEmployeeRepo: &stubEmployeeRepo{}}))
+
+	rec := postGenerate(t, h, `{"template_id": "headcount_trend", "variables": {"months": 0}}`)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "must be between 1 and 120, got 0")
+}
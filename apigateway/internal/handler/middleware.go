@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/logger"
+)
+
+// ActorHeader names the caller recorded in the created_by and updated_by
+// audit columns.
+const ActorHeader = "X-Actor"
+
+// ActorMiddleware stores the ActorHeader of the request in its context, see
+// domain.ActorFrom. Requests without it are recorded as domain.SystemActor.
+func ActorMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		actor := strings.TrimSpace(c.Request().Header.Get(ActorHeader))
+		if actor == "" {
+			return next(c)
+		}
+		if len(actor) > domain.MaxActorLength {
+			actor = actor[:domain.MaxActorLength]
+		}
+		ctx := domain.WithActor(c.Request().Context(), actor)
+		c.SetRequest(c.Request().WithContext(ctx))
+		return next(c)
+	}
+}
+
+// TraceparentHeader carries the W3C trace context of the caller.
+const TraceparentHeader = "Traceparent"
+
+// maxRequestIDLength bounds the request IDs taken from callers.
+const maxRequestIDLength = 128
+
+// RequestIDMiddleware logs the lines of the request with its request_id and
+// trace_id, see logger.WithRequestID. The request ID is the X-Request-Id of
+// the caller, or a new one, and is echoed in the response; the trace ID
+// comes from the Traceparent header, when it is valid.
+func RequestIDMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		id := strings.TrimSpace(c.Request().Header.Get(echo.HeaderXRequestID))
+		if id == "" || len(id) > maxRequestIDLength {
+			id = uuid.NewString()
+		}
+		c.Response().Header().Set(echo.HeaderXRequestID, id)
+		ctx := logger.WithRequestID(c.Request().Context(), id)
+		if traceID := traceIDOf(c.Request().Header.Get(TraceparentHeader)); traceID != "" {
+			ctx = logger.WithTraceID(ctx, traceID)
+		}
+		c.SetRequest(c.Request().WithContext(ctx))
+		return next(c)
+	}
+}
+
+// traceIDOf returns the trace ID of a traceparent header,
+// "00-<32 hex trace ID>-<16 hex parent ID>-<2 hex flags>", empty if it is
+// malformed or the ID is all zeros.
+func traceIDOf(traceparent string) string {
+	parts := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 {
+		return ""
+	}
+	traceID := strings.ToLower(parts[1])
+	if strings.Trim(traceID, "0123456789abcdef") != "" || strings.Trim(traceID, "0") == "" {
+		return ""
+	}
+	return traceID
+}
+
+// RequestIDTransport forwards the request ID of the request context, see
+// logger.RequestID, to the services called through Base, so their logs can
+// be joined with ours. Requests setting their own X-Request-Id keep it.
+type RequestIDTransport struct {
+	// Base makes the requests, nil means http.DefaultTransport
+	Base http.RoundTripper
+}
+
+func (t RequestIDTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if id := logger.RequestID(req.Context()); id != "" && req.Header.Get(echo.HeaderXRequestID) == "" {
+		// RoundTrippers must not modify the request
+		req = req.Clone(req.Context())
+		req.Header.Set(echo.HeaderXRequestID, id)
+	}
+	return base.RoundTrip(req)
+}
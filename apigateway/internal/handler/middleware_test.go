@@ -0,0 +1,133 @@
+package handler_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/handler"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/logger"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service/serviceutils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestActorMiddleware(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"no header", "", domain.SystemActor},
+		{"trimmed", "  alice  ", "alice"},
+		{"truncated", strings.Repeat("a", domain.MaxActorLength+10), strings.Repeat("a", domain.MaxActorLength)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set(handler.ActorHeader, tt.header)
+			}
+			c := e.NewContext(req, httptest.NewRecorder())
+
+			var got string
+			err := handler.ActorMiddleware(func(c echo.Context) error {
+				got = domain.ActorFrom(c.Request().Context())
+				return nil
+			})(c)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	tests := []struct {
+		name        string
+		requestID   string
+		traceparent string
+		wantID      string
+		wantTrace   string
+	}{
+		{"caller ids", "req-1", "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", "req-1", "4bf92f3577b34da6a3ce929d0e0e4736"},
+		{"generated id", "", "", "", ""},
+		{"oversized id", strings.Repeat("x", 200), "", "", ""},
+		{"malformed traceparent", "req-2", "00-xyz-00f067aa0ba902b7-01", "req-2", ""},
+		{"zero trace id", "req-3", "00-00000000000000000000000000000000-00f067aa0ba902b7-01", "req-3", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := echo.New()
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.requestID != "" {
+				req.Header.Set(echo.HeaderXRequestID, tt.requestID)
+			}
+			if tt.traceparent != "" {
+				req.Header.Set(handler.TraceparentHeader, tt.traceparent)
+			}
+			rec := httptest.NewRecorder()
+			c := e.NewContext(req, rec)
+
+			var gotID, gotTrace string
+			err := handler.RequestIDMiddleware(func(c echo.Context) error {
+				gotID = logger.RequestID(c.Request().Context())
+				gotTrace = logger.TraceID(c.Request().Context())
+				return nil
+			})(c)
+
+			assert.NoError(t, err)
+			if tt.wantID == "" {
+				assert.Len(t, gotID, 36, "a new UUID")
+				assert.NotEqual(t, tt.requestID, gotID)
+			} else {
+				assert.Equal(t, tt.wantID, gotID)
+			}
+			assert.Equal(t, gotID, rec.Header().Get(echo.HeaderXRequestID))
+			assert.Equal(t, tt.wantTrace, gotTrace)
+		})
+	}
+}
+
+func TestRequestIDMiddleware_InErrorEnvelope(t *testing.T) {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(echo.HeaderXRequestID, "req-9")
+	rec := httptest.NewRecorder()
+
+	err := handler.RequestIDMiddleware(func(c echo.Context) error {
+		return serviceutils.ResponseError(c, http.StatusNotFound, "Employee not found", errors.New("no rows"))
+	})(e.NewContext(req, rec))
+
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"Success":false,"Message":"Employee not found","Data":null,"Error":"no rows","code":"not_found","request_id":"req-9"}`, rec.Body.String())
+}
+
+func TestRequestIDTransport(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get(echo.HeaderXRequestID))
+	}))
+	defer srv.Close()
+	client := &http.Client{Transport: handler.RequestIDTransport{}}
+
+	for _, ctx := range []context.Context{
+		logger.WithRequestID(context.Background(), "req-1"),
+		context.Background(),
+	} {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+		require.NoError(t, err)
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Empty(t, req.Header.Get(echo.HeaderXRequestID), "the request of the caller is left unchanged")
+	}
+
+	assert.Equal(t, []string{"req-1", ""}, got)
+}
@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/config"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/openapi"
+)
+
+// Security schemes of the API document
+const (
+	bearerScheme     = "bearerAuth"
+	apiKeyScheme     = "apiKey"
+	adminTokenScheme = "adminToken"
+)
+
+const xlsxContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// reportSecurity are the credentials accepted by the report endpoints.
+var reportSecurity = []string{bearerScheme, apiKeyScheme}
+
+// OpenAPIHandler serves the API document and a Swagger UI reading it.
+type OpenAPIHandler struct {
+	registry *openapi.Registry
+
+	once sync.Once
+	spec []byte
+	err  error
+}
+
+func NewOpenAPIHandler(registry *openapi.Registry) *OpenAPIHandler {
+	return &OpenAPIHandler{registry: registry}
+}
+
+// SpecHandler handles GET /openapi.json. The document is built from the
+// routes of the server on first request, once every route is registered.
+func (h *OpenAPIHandler) SpecHandler(c echo.Context) error {
+	h.once.Do(func() {
+		var routes []openapi.Route
+		for _, r := range c.Echo().Routes() {
+			routes = append(routes, openapi.Route{Method: r.Method, Path: r.Path, Name: r.Name})
+		}
+		h.spec, h.err = json.Marshal(h.registry.Build(routes))
+	})
+	if h.err != nil {
+		return h.err
+	}
+	return c.JSONBlob(http.StatusOK, h.spec)
+}
+
+// SwaggerUIHandler handles GET /docs
+func (h *OpenAPIHandler) SwaggerUIHandler(c echo.Context) error {
+	return c.HTML(http.StatusOK, swaggerUIPage)
+}
+
+const swaggerUIPage = `<!DOCTYPE html>
+<html>
+<head>
+  <meta charset="utf-8">
+  <title>Employee Management API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>`
+
+// envelope is the schema of serviceutils.GenericResponse carrying data.
+func envelope(data *openapi.Schema) *openapi.Schema {
+	if data == nil {
+		data = &openapi.Schema{Nullable: true}
+	}
+	return &openapi.Schema{Type: "object", Properties: map[string]*openapi.Schema{
+		"Success":     {Type: "boolean"},
+		"Message":     {Type: "string"},
+		"Data":        data,
+		"Error":       {Type: "string"},
+		"code":        {Type: "string", Description: "identifies the error of failed requests, e.g. not_found or leave_not_pending"},
+		"next_cursor": {Type: "string", Description: "continues paginated listings, absent on their last page"},
+		"request_id":  {Type: "string", Description: "identifies failed requests in the logs, the X-Request-Id of the response"},
+	}}
+}
+
+// APIDocs documents the routes of the API. Routes left out still appear in
+// the document, with their path parameters only.
+func APIDocs() *openapi.Registry {
+	r := openapi.NewRegistry("Employee Management API", "1.0")
+	r.Description = "Responses are wrapped in an envelope whose Data holds the result; rejected requests list their field errors as Data."
+	r.Envelope = envelope
+	r.ErrorResponses = map[int]openapi.ErrorResponse{
+		http.StatusBadRequest:          {Description: "Invalid request, Data lists the field errors", Data: []domain.FieldError{}, Only: openapi.WithRequest},
+		http.StatusUnauthorized:        {Description: "Missing or invalid credentials", Only: openapi.WithSecurity},
+		http.StatusForbidden:           {Description: "The credentials lack the required scope", Only: openapi.WithSecurity},
+		http.StatusInternalServerError: {Description: "Unexpected failure"},
+	}
+	r.SecuritySchemes = map[string]openapi.SecurityScheme{
+		bearerScheme:     {Type: "http", Scheme: "bearer", BearerFormat: "JWT", Description: "HS256 token with the caller in sub and space separated scopes in scope"},
+		apiKeyScheme:     {Type: "apiKey", In: "header", Name: APIKeyHeader, Description: "API key issued under /admin/api-keys"},
+		adminTokenScheme: {Type: "apiKey", In: "header", Name: AdminTokenHeader},
+	}
+	admin := []string{adminTokenScheme}
+	dateQuery := func(name, description string) openapi.Param {
+		return openapi.Param{Name: name, Description: description + ", YYYY-MM-DD"}
+	}
+
+	// employees
+	r.Document(http.MethodPost, "/employees", openapi.Operation{Summary: "Create an employee", Request: domain.Employee{}, Status: http.StatusCreated})
+	r.Document(http.MethodGet, "/employees", openapi.Operation{
+		Summary: "List employees", Description: "Pages are continued with the next_cursor of the previous one.",
+		Response: []domain.Employee{},
+		Query: []openapi.Param{
+			{Name: "limit", Type: "integer"}, {Name: "offset", Type: "integer"}, {Name: "cursor"},
+			{Name: "name", Description: "start of the first or last name"}, {Name: "gender", Description: "M or F"},
+			dateQuery("hired_from", "earliest hire date"), dateQuery("hired_to", "latest hire date"), {Name: "dept_no"},
+		},
+	})
+	r.Document(http.MethodGet, "/employees/:id", openapi.Operation{Summary: "Get an employee", Response: domain.Employee{}})
+	r.Document(http.MethodPut, "/employees/:id", openapi.Operation{Summary: "Update an employee", Request: domain.Employee{}})
+	r.Document(http.MethodDelete, "/employees/:id", openapi.Operation{Summary: "Delete an employee", Description: "The employee is hidden until restored."})
+	r.Document(http.MethodPost, "/employees/:id/restore", openapi.Operation{Summary: "Restore a deleted employee"})
+	r.Document(http.MethodPost, "/employees/batch", openapi.Operation{Summary: "Create or update employees", Request: batchUpsertRequest{}, Response: []domain.BatchRowResult{}})
+	r.Document(http.MethodPost, "/employees/import", openapi.Operation{Summary: "Import an edited employee workbook", Description: "The workbook is sent as the file field of a multipart form.", Response: domain.ImportResult{}})
+	r.Document(http.MethodGet, "/employees/export", openapi.Operation{Summary: "Export employees", ContentType: xlsxContentType, Security: reportSecurity})
+	r.Document(http.MethodGet, "/employees/:id/report", openapi.Operation{Summary: "Get the report of an employee", Response: domain.EmployeeReport{}})
+	r.Document(http.MethodGet, "/employees/:id/report.xlsx", openapi.Operation{Summary: "Download the report of an employee", ContentType: xlsxContentType, Security: reportSecurity})
+	r.Document(http.MethodGet, "/employees/:id/annotations", openapi.Operation{Summary: "List the annotations of an employee", Response: []domain.Annotation{}})
+	r.Document(http.MethodPut, "/annotations/:id/status", openapi.Operation{Summary: "Approve or reject an annotation", Request: statusRequest{}})
+
+	// salaries
+	r.Document(http.MethodGet, "/employees/:id/salaries", openapi.Operation{Summary: "List the salary history of an employee", Response: []domain.Salary{}})
+	r.Document(http.MethodGet, "/employees/:id/salaries/current", openapi.Operation{Summary: "Get the current salary of an employee", Response: domain.Salary{}})
+	r.Document(http.MethodPost, "/employees/:id/salaries", openapi.Operation{Summary: "Add a salary", Request: salaryRequest{}, Response: domain.Salary{}, Status: http.StatusCreated})
+	r.Document(http.MethodPut, "/employees/:id/salaries/:from_date", openapi.Operation{Summary: "Change a salary amount", Request: salaryRequest{}})
+	r.Document(http.MethodDelete, "/employees/:id/salaries/:from_date", openapi.Operation{Summary: "Delete a salary"})
+
+	// departments
+	r.Document(http.MethodGet, "/departments", openapi.Operation{Summary: "List departments", Response: []domain.Department{}})
+	r.Document(http.MethodPost, "/departments", openapi.Operation{Summary: "Create a department", Request: departmentRequest{}, Response: domain.Department{}, Status: http.StatusCreated})
+	r.Document(http.MethodGet, "/departments/:id", openapi.Operation{Summary: "Get a department", Response: domain.Department{}})
+	r.Document(http.MethodPut, "/departments/:id", openapi.Operation{Summary: "Rename a department", Request: departmentRequest{}, Response: domain.Department{}})
+	r.Document(http.MethodDelete, "/departments/:id", openapi.Operation{Summary: "Delete a department"})
+	r.Document(http.MethodPost, "/departments/:id/restore", openapi.Operation{Summary: "Restore a deleted department"})
+	r.Document(http.MethodGet, "/departments/:id/managers", openapi.Operation{Summary: "List the managers of a department", Response: []domain.DeptManager{}})
+	r.Document(http.MethodPost, "/departments/:id/managers", openapi.Operation{Summary: "Assign a manager", Request: managerRequest{}, Response: domain.DeptManager{}, Status: http.StatusCreated})
+	r.Document(http.MethodPut, "/departments/:id/managers/:emp_no/terminate", openapi.Operation{Summary: "End the term of a manager", Request: managerRequest{}})
+	r.Document(http.MethodDelete, "/departments/:id/managers/:emp_no", openapi.Operation{Summary: "Delete a manager assignment", Query: []openapi.Param{dateQuery("from_date", "start of the assignment")}})
+	r.Document(http.MethodGet, "/departments/:id/orgchart.xlsx", openapi.Operation{Summary: "Download the org chart of a department", ContentType: xlsxContentType, Security: reportSecurity})
+
+	// attendance
+	attendanceQuery := []openapi.Param{{Name: "emp_no", Type: "integer"}, {Name: "dept_no"}, dateQuery("from", "first day"), dateQuery("to", "last day")}
+	r.Document(http.MethodPost, "/attendance/check-in", openapi.Operation{Summary: "Check in", Request: checkRequest{}})
+	r.Document(http.MethodPost, "/attendance/check-out", openapi.Operation{Summary: "Check out", Request: checkRequest{}})
+	r.Document(http.MethodGet, "/attendance", openapi.Operation{Summary: "List attendance", Query: attendanceQuery, Response: []domain.Attendance{}})
+	r.Document(http.MethodPost, "/attendance/timesheet/import", openapi.Operation{Summary: "Import an edited timesheet workbook", Description: "The workbook is sent as the file field of a multipart form.", Response: domain.ImportResult{}})
+	r.Document(http.MethodPost, "/leaves", openapi.Operation{Summary: "Request leave", Request: leaveRequest{}, Response: domain.LeaveRequest{}, Status: http.StatusCreated})
+	r.Document(http.MethodGet, "/leaves", openapi.Operation{Summary: "List leave requests", Query: append(attendanceQuery, openapi.Param{Name: "status"}), Response: []domain.LeaveRequest{}})
+	r.Document(http.MethodPut, "/leaves/:id/status", openapi.Operation{Summary: "Approve or reject a leave request", Request: statusRequest{}})
+
+	// reports
+	r.Document(http.MethodPost, "/reports/generate", openapi.Operation{
+		Summary: "Generate a report", Description: "Downloads answer the file, other deliveries the delivery target.",
+		Request: domain.ExportRequest{}, Response: deliveryResult{}, Security: reportSecurity,
+	})
+	r.Document(http.MethodGet, "/reports/:id/variables", openapi.Operation{Summary: "List the variables of a report", Response: map[string]interface{}{}, Security: reportSecurity})
+	r.Document(http.MethodPost, "/reports/plans/run", openapi.Operation{
+		Summary: "Run an export plan", Description: "The body is the YAML plan. Plans delivering to the archive answer a zip, others the plan result.",
+		Response: domain.ExportPlanResult{}, Security: reportSecurity,
+	})
+	r.Document(http.MethodGet, "/reports/plans/runs/:id/deliveries", openapi.Operation{Summary: "List the deliveries of a plan run", Response: []domain.ReportDelivery{}, Security: reportSecurity})
+
+	// admin
+	r.Document(http.MethodPost, "/admin/query/export", openapi.Operation{Summary: "Export an ad-hoc query", Request: domain.AdhocQueryRequest{}, ContentType: xlsxContentType, Security: admin})
+	r.Document(http.MethodPost, "/admin/api-keys", openapi.Operation{Summary: "Issue an API key", Description: "The key is only shown in this response.", Request: apiKeyRequest{}, Response: issuedAPIKey{}, Status: http.StatusCreated, Security: admin})
+	r.Document(http.MethodGet, "/admin/api-keys", openapi.Operation{Summary: "List API keys", Response: []domain.APIKey{}, Security: admin})
+	r.Document(http.MethodDelete, "/admin/api-keys/:id", openapi.Operation{Summary: "Revoke an API key", Security: admin})
+	r.Document(http.MethodPost, "/admin/reload", openapi.Operation{Summary: "Reload report templates and settings", Description: "Re-reads every report template and LOG_LEVEL and the report rate limit. Exports already running finish on the template revision they started with.", Response: domain.ReloadResult{}, Security: admin})
+	r.Document(http.MethodGet, "/debug/config", openapi.Operation{Summary: "Dump the configuration", Description: "Each setting with its value and where it came from, secrets redacted.", Response: []config.Setting{}, Security: admin})
+	return r
+}
@@ -0,0 +1,56 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/handler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPI_Spec(t *testing.T) {
+	e := echo.New()
+	empHandler := handler.NewEmployeeHandler(nil, nil)
+	e.GET("/employees/:id", empHandler.GetHandler)
+	e.GET("/employees/export-stats", empHandler.ExportStatsHandler)
+	e.POST("/admin/api-keys", handler.NewAPIKeyHandler(nil).CreateHandler)
+	docs := handler.NewOpenAPIHandler(handler.APIDocs())
+	e.GET("/openapi.json", docs.SpecHandler)
+
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var spec struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]map[string]struct {
+			Summary     string                     `json:"summary"`
+			RequestBody map[string]interface{}     `json:"requestBody"`
+			Responses   map[string]json.RawMessage `json:"responses"`
+			Security    []map[string][]string      `json:"security"`
+		} `json:"paths"`
+		Components struct {
+			Schemas         map[string]json.RawMessage `json:"schemas"`
+			SecuritySchemes map[string]json.RawMessage `json:"securitySchemes"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &spec))
+
+	assert.Equal(t, "3.0.3", spec.OpenAPI)
+	assert.Equal(t, "Get an employee", spec.Paths["/employees/{id}"]["get"].Summary)
+	assert.Equal(t, "EmployeeHandler.ExportStatsHandler", spec.Paths["/employees/export-stats"]["get"].Summary, "undocumented routes are listed")
+	assert.Contains(t, spec.Paths, "/openapi.json")
+
+	create := spec.Paths["/admin/api-keys"]["post"]
+	assert.NotNil(t, create.RequestBody)
+	assert.Contains(t, create.Responses, "201")
+	assert.Contains(t, create.Responses, "400")
+	assert.Equal(t, []map[string][]string{{"adminToken": {}}}, create.Security)
+	assert.Contains(t, spec.Components.Schemas, "Employee")
+	assert.Contains(t, spec.Components.Schemas, "FieldError")
+	assert.Contains(t, spec.Components.SecuritySchemes, "apiKey")
+}
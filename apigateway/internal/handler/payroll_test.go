@@ -0,0 +1,147 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/handler"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// stubPayrollRepo adds departments and salaries to stubEmployeeRepo, every
+// employee belongs to every department.
+type stubPayrollRepo struct {
+	*stubEmployeeRepo
+	departments []domain.Department
+	salaries    []domain.Salary
+}
+
+func (r *stubPayrollRepo) ListDepartments(ctx context.Context) ([]domain.Department, error) {
+	return r.departments, nil
+}
+
+func (r *stubPayrollRepo) ListDepartmentSalaries(ctx context.Context, deptNo string) ([]domain.Salary, error) {
+	return r.salaries, nil
+}
+
+// payrollDays records March 2024 for 10001: every working day worked, the
+// 4th without a check-out, except the 28th and 29th taken as unpaid leave.
+// 10002 has no salary.
+func payrollDays() []domain.Attendance {
+	var days []domain.Attendance
+	for day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC); day.Month() == time.March; day = day.AddDate(0, 0, 1) {
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday {
+			continue
+		}
+		a := domain.Attendance{EmpNo: 10001, WorkDate: day}
+		if day.Day() >= 28 {
+			a.LeaveType = domain.LeaveUnpaid
+		} else {
+			in, out := day.Add(9*time.Hour), day.Add(17*time.Hour)
+			a.CheckIn = &in
+			if day.Day() != 4 {
+				a.CheckOut = &out
+			}
+		}
+		days = append(days, a)
+	}
+	return days
+}
+
+func newPayrollReports() service.ReportService {
+	repo := &stubPayrollRepo{
+		stubEmployeeRepo: &stubEmployeeRepo{employees: testEmployees()},
+		departments:      []domain.Department{{DeptNo: "d001", DeptName: "Marketing"}, {DeptNo: "d002", DeptName: "Finance"}},
+		salaries:         []domain.Salary{{EmployeeID: 10001, Salary: 60000}},
+	}
+	paySvc := service.NewPayrollService(repo, &stubAttendanceRepo{days: payrollDays()}, service.DefaultRates)
+	reportSvc := service.NewReportService(nil)
+	reportSvc.Register(service.NewPayrollSummaryReport(paySvc, "../../templates/payroll_summary.yaml"))
+	return reportSvc
+}
+
+func exportPayroll(t *testing.T, vars map[string]interface{}) *excelize.File {
+	var buf bytes.Buffer
+	require.NoError(t, newPayrollReports().Generate(context.Background(), &domain.ExportRequest{
+		TemplateID: service.PayrollSummaryReportID,
+		Variables:  vars,
+	}, &buf))
+	f, err := excelize.OpenReader(&buf)
+	require.NoError(t, err)
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+func TestPayrollReport_ConvertsAndFlagsExceptions(t *testing.T) {
+	f := exportPayroll(t, map[string]interface{}{"month": "2024-03-15", "currency": "EUR", "dept_no": "d001"})
+
+	assert.Equal(t, []string{"Summary", "Detail", "Exceptions"}, f.GetSheetList())
+
+	// 60000 USD is 55200 EUR a year, 4600 a month; 2 of the 21 working days
+	// are unpaid
+	summary, err := f.GetRows("Summary", excelize.Options{RawCellValue: true})
+	require.NoError(t, err)
+	require.Len(t, summary, 3)
+	assert.Equal(t, []string{"d001", "Marketing", "EUR", "1", "4600", "438.1", "4161.9"}, summary[2])
+
+	detail, err := f.GetRows("Detail", excelize.Options{RawCellValue: true})
+	require.NoError(t, err)
+	require.Len(t, detail, 3)
+	assert.Equal(t, []string{"d001", "Marketing", "10001", "Georgi Facello", "EUR", "55200", "4600", "19", "0", "2", "438.1", "4161.9"}, detail[2])
+
+	exceptions, err := f.GetRows("Exceptions")
+	require.NoError(t, err)
+	var issues []string
+	for _, row := range exceptions[2:] {
+		issues = append(issues, row[1]+": "+row[3])
+	}
+	assert.Equal(t, []string{
+		"10001: days without check-out: 1",
+		"10001: unpaid leave days deducted: 2",
+		"10002: no current salary, left out of the payroll",
+	}, issues)
+
+	styleID, err := f.GetCellStyle("Summary", "E3")
+	require.NoError(t, err)
+	style, err := f.GetStyle(styleID)
+	require.NoError(t, err)
+	require.NotNil(t, style.CustomNumFmt)
+	assert.Contains(t, *style.CustomNumFmt, "#,##0.00_)")
+}
+
+func TestPayrollReport_AllDepartmentsInBaseCurrency(t *testing.T) {
+	f := exportPayroll(t, map[string]interface{}{"month": "2024-03-01"})
+
+	summary, err := f.GetRows("Summary", excelize.Options{RawCellValue: true})
+	require.NoError(t, err)
+	require.Len(t, summary, 4)
+	assert.Equal(t, []string{"d001", "Marketing", "USD", "1", "5000", "476.19", "4523.81"}, summary[2])
+	assert.Equal(t, []string{"d002", "Finance", "USD", "1", "5000", "476.19", "4523.81"}, summary[3])
+}
+
+func TestPayrollReport_RejectsUnknownDepartment(t *testing.T) {
+	h := handler.NewReportHandler(newPayrollReports())
+
+	rec := postGenerate(t, h, `{"template_id": "payroll_summary", "variables": {"month": "2024-03-01", "dept_no": "d999"}}`)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `unknown department \"d999\"`)
+}
+
+func TestStaticRates(t *testing.T) {
+	rates := service.StaticRates{"USD": 1, "EUR": 0.5, "VND": 25000}
+
+	rate, err := rates.Rate(context.Background(), "EUR", "VND")
+	require.NoError(t, err)
+	assert.Equal(t, 50000.0, rate)
+
+	_, err = rates.Rate(context.Background(), "USD", "GBP")
+	assert.ErrorIs(t, err, service.ErrUnknownCurrency)
+}
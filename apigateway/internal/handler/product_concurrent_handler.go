@@ -1,11 +1,14 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
 	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
 )
 
@@ -27,24 +30,36 @@ func NewProductMergeHandler(merger *service.ProductMerger) *ProductMergeHandler
 // @Tags Products
 // @Accept json
 // @Produce json
-// @Success 200 {array} domain.ProductDetailResponse
+// @Param limit query int false "Page size, paginates the listing (default 100, max 1000)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {array} domain.ProductDetailResponse "without limit and cursor, otherwise {items, next_cursor}"
 // @Router /products/details-merged [get]
 func (h *ProductMergeHandler) GetAllProductsWithDetailsMerged(c echo.Context) error {
 	ctx := c.Request().Context()
 	start := time.Now()
 
-	// Get all products
-	products, err := h.merger.ProductRepo.GetAll(ctx)
+	page, err := h.productPage(c)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": err.Error(),
-		})
+		return respondProductPageError(c, err)
+	}
+
+	// Get all products, or the requested page
+	var products []domain.Product
+	if page != nil {
+		products = page.Products
+	} else {
+		products, err = h.merger.ProductRepo.GetAll(ctx)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, map[string]string{
+				"error": err.Error(),
+			})
+		}
 	}
 
 	if len(products) == 0 {
 		duration := time.Since(start)
 		fmt.Printf("[SEQUENTIAL] No products found - Time: %v\n", duration)
-		return c.JSON(http.StatusOK, []interface{}{})
+		return respondProducts(c, page, []domain.ProductDetailResponse{})
 	}
 
 	// Merge using in-memory indexing (single batch)
@@ -58,7 +73,7 @@ func (h *ProductMergeHandler) GetAllProductsWithDetailsMerged(c echo.Context) er
 	duration := time.Since(start)
 	fmt.Printf("[SEQUENTIAL] Merged %d products - Time: %v\n", len(products), duration)
 
-	return c.JSON(http.StatusOK, results)
+	return respondProducts(c, page, results)
 }
 
 // GetAllProductsWithDetailsConcurrent godoc
@@ -67,13 +82,25 @@ func (h *ProductMergeHandler) GetAllProductsWithDetailsMerged(c echo.Context) er
 // @Tags Products
 // @Accept json
 // @Produce json
-// @Success 200 {array} domain.ProductDetailResponse
+// @Param limit query int false "Page size, paginates the listing (default 100, max 1000)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {array} domain.ProductDetailResponse "without limit and cursor, otherwise {items, next_cursor}"
 // @Router /products/details-concurrent [get]
 func (h *ProductMergeHandler) GetAllProductsWithDetailsConcurrent(c echo.Context) error {
 	ctx := c.Request().Context()
 	start := time.Now()
 
-	results, err := h.merger.MergeProductsConcurrent(ctx)
+	page, err := h.productPage(c)
+	if err != nil {
+		return respondProductPageError(c, err)
+	}
+
+	var results []domain.ProductDetailResponse
+	if page != nil {
+		results, err = h.merger.MergeConcurrent(ctx, page.Products)
+	} else {
+		results, err = h.merger.MergeProductsConcurrent(ctx)
+	}
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": err.Error(),
@@ -83,5 +110,58 @@ func (h *ProductMergeHandler) GetAllProductsWithDetailsConcurrent(c echo.Context
 	duration := time.Since(start)
 	fmt.Printf("[CONCURRENT] Merged %d products - Time: %v\n", len(results), duration)
 
-	return c.JSON(http.StatusOK, results)
+	return respondProducts(c, page, results)
+}
+
+// Product listing page sizes
+const (
+	defaultProductPageSize = 100
+	maxProductPageSize     = 1000
+)
+
+// ProductDetailPage is the body of paginated product listings, and the
+// result of the products GraphQL query.
+type ProductDetailPage struct {
+	Items      []domain.ProductDetailResponse `json:"items"`
+	NextCursor string                         `json:"next_cursor,omitempty"`
+}
+
+// productPage fetches the page asked for by the limit and cursor query
+// parameters, or returns nil when neither is set and every product is listed.
+func (h *ProductMergeHandler) productPage(c echo.Context) (*domain.ProductPage, error) {
+	limitParam, cursor := c.QueryParam("limit"), c.QueryParam("cursor")
+	if limitParam == "" && cursor == "" {
+		return nil, nil
+	}
+	limit := defaultProductPageSize
+	if limitParam != "" {
+		n, err := strconv.Atoi(limitParam)
+		if err != nil || n <= 0 || n > maxProductPageSize {
+			verr := &domain.ValidationError{}
+			verr.Add("limit", "must be an integer between 1 and %d, got %q", maxProductPageSize, limitParam)
+			return nil, verr
+		}
+		limit = n
+	}
+	return h.merger.ProductPage(c.Request().Context(), cursor, limit)
+}
+
+// respondProductPageError answers 400 for bad paging parameters and 500
+// for anything else.
+func respondProductPageError(c echo.Context, err error) error {
+	status := http.StatusInternalServerError
+	var verr *domain.ValidationError
+	if errors.As(err, &verr) || errors.Is(err, domain.ErrInvalidCursor) {
+		status = http.StatusBadRequest
+	}
+	return c.JSON(status, map[string]string{"error": err.Error()})
+}
+
+// respondProducts answers the merged products, wrapped with the next cursor
+// for paginated listings.
+func respondProducts(c echo.Context, page *domain.ProductPage, results []domain.ProductDetailResponse) error {
+	if page == nil {
+		return c.JSON(http.StatusOK, results)
+	}
+	return c.JSON(http.StatusOK, ProductDetailPage{Items: results, NextCursor: page.NextCursor})
 }
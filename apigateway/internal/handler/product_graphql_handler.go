@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/graphql"
+)
+
+// ProductGraphQLHandler serves the merged product details over GraphQL, so
+// clients select the fields and countries they need instead of the fixed
+// ProductDetailResponse shape. Datastore is only read when details are
+// selected.
+type ProductGraphQLHandler struct {
+	merger *service.ProductMerger
+	schema *graphql.Schema
+}
+
+// NewProductGraphQLHandler creates a new handler
+func NewProductGraphQLHandler(merger *service.ProductMerger) *ProductGraphQLHandler {
+	h := &ProductGraphQLHandler{merger: merger}
+	countries := graphql.Arg{Name: "countries", Type: "[String!]", Description: "Keeps the details of these countries"}
+	h.schema = &graphql.Schema{Query: map[string]*graphql.Field{
+		"product": {
+			Description: "A product with its details merged from SQL and Datastore, null if there is none",
+			Args:        []graphql.Arg{{Name: "id", Type: "ID!"}, {Name: "brand", Type: "String!"}, countries},
+			Type:        (*domain.ProductDetailResponse)(nil),
+			Resolve:     h.resolveProduct,
+		},
+		"products": {
+			Description: "A page of products ordered by brand and id",
+			Args: []graphql.Arg{
+				{Name: "limit", Type: "Int", Description: fmt.Sprintf("Page size, default %d, max %d", defaultProductPageSize, maxProductPageSize)},
+				{Name: "cursor", Type: "String", Description: "next_cursor of the previous page"},
+				countries,
+			},
+			Type:    (*ProductDetailPage)(nil),
+			Resolve: h.resolveProducts,
+		},
+	}}
+	return h
+}
+
+// QueryHandler handles POST /graphql with a {query, operationName, variables}
+// body, and GET /graphql?query=&operationName=&variables=. Query errors are
+// answered with 200 next to the data, as GraphQL clients expect.
+func (h *ProductGraphQLHandler) QueryHandler(c echo.Context) error {
+	var req graphql.Request
+	if c.Request().Method == http.MethodGet {
+		req.Query = c.QueryParam("query")
+		req.OperationName = c.QueryParam("operationName")
+		if vars := c.QueryParam("variables"); vars != "" {
+			if err := json.Unmarshal([]byte(vars), &req.Variables); err != nil {
+				return c.JSON(http.StatusBadRequest, graphql.Response{Errors: []*graphql.Error{{Message: "variables must be a JSON object"}}})
+			}
+		}
+	} else if err := json.NewDecoder(c.Request().Body).Decode(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, graphql.Response{Errors: []*graphql.Error{{Message: "body must be a JSON object with a query"}}})
+	}
+	if req.Query == "" {
+		return c.JSON(http.StatusBadRequest, graphql.Response{Errors: []*graphql.Error{{Message: "query is required"}}})
+	}
+	return c.JSON(http.StatusOK, h.schema.Execute(c.Request().Context(), req))
+}
+
+// SchemaHandler handles GET /graphql/schema, the schema in SDL
+func (h *ProductGraphQLHandler) SchemaHandler(c echo.Context) error {
+	return c.String(http.StatusOK, h.schema.SDL())
+}
+
+func (h *ProductGraphQLHandler) resolveProduct(ctx context.Context, p graphql.Params) (interface{}, error) {
+	id, err := strconv.ParseInt(p.String("id"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("id must be an integer, got %q", p.String("id"))
+	}
+	product, err := h.merger.ProductRepo.GetByID(ctx, id, p.String("brand"))
+	if errors.Is(err, sql.ErrNoRows) {
+		return (*domain.ProductDetailResponse)(nil), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	results, err := h.merge(ctx, p, "details", []domain.Product{*product})
+	if err != nil {
+		return nil, err
+	}
+	return &results[0], nil
+}
+
+func (h *ProductGraphQLHandler) resolveProducts(ctx context.Context, p graphql.Params) (interface{}, error) {
+	limit := p.Int("limit", defaultProductPageSize)
+	if limit <= 0 || limit > maxProductPageSize {
+		return nil, fmt.Errorf("limit must be between 1 and %d, got %d", maxProductPageSize, limit)
+	}
+	page, err := h.merger.ProductPage(ctx, p.String("cursor"), limit)
+	if err != nil {
+		return nil, err
+	}
+	results, err := h.merge(ctx, p, "items.details", page.Products)
+	if err != nil {
+		return nil, err
+	}
+	return &ProductDetailPage{Items: results, NextCursor: page.NextCursor}, nil
+}
+
+// merge merges the details of products when the details field at path is
+// selected, otherwise it only lists the items.
+func (h *ProductGraphQLHandler) merge(ctx context.Context, p graphql.Params, path string, products []domain.Product) ([]domain.ProductDetailResponse, error) {
+	if len(products) == 0 {
+		return []domain.ProductDetailResponse{}, nil
+	}
+	if !p.Has(path) {
+		results := make([]domain.ProductDetailResponse, len(products))
+		for i, product := range products {
+			results[i].Item = domain.ProductItemDTO{ID: product.ID, Brand: product.Brand}
+		}
+		return results, nil
+	}
+	results, err := h.merger.MergeProductBatch(ctx, products)
+	if err != nil {
+		return nil, err
+	}
+	service.FilterCountries(results, p.Strings("countries"))
+	return results, nil
+}
@@ -0,0 +1,74 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/handler"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func newProductGraphQLServer() *echo.Echo {
+	h := handler.NewProductGraphQLHandler(service.NewProductMerger(nil, nil, nil, 50, 10))
+	e := echo.New()
+	e.GET("/graphql", h.QueryHandler)
+	e.POST("/graphql", h.QueryHandler)
+	e.GET("/graphql/schema", h.SchemaHandler)
+	return e
+}
+
+func TestProductGraphQL_Schema(t *testing.T) {
+	rec := httptest.NewRecorder()
+	newProductGraphQLServer().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/graphql/schema", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "product(id: ID!, brand: String!, \"Keeps the details of these countries\" countries: [String!]): ProductDetailResponse")
+	assert.Contains(t, rec.Body.String(), "type ProductDetailDTO {")
+	assert.Contains(t, rec.Body.String(), "  next_cursor: String!")
+}
+
+func TestProductGraphQL_RejectsInvalidQueries(t *testing.T) {
+	tests := []struct {
+		name   string
+		req    *http.Request
+		status int
+		body   string
+	}{
+		{
+			name:   "unknown field",
+			req:    httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":"{ products { items { price } } }"}`)),
+			status: http.StatusOK,
+			body:   `{"errors":[{"message":"Cannot query field \"price\" on type \"ProductDetailResponse\"","locations":[{"line":1,"column":22}]}]}`,
+		},
+		{
+			name:   "get",
+			req:    httptest.NewRequest(http.MethodGet, "/graphql?query="+"%7B%20product%28brand%3A%20%22x%22%29%20%7B%20item%20%7B%20id%20%7D%20%7D%20%7D", nil),
+			status: http.StatusOK,
+			body:   `{"errors":[{"message":"Field \"product\" argument \"id\" of type \"ID!\" is required, but it was not provided","locations":[{"line":1,"column":3}]}]}`,
+		},
+		{
+			name:   "no query",
+			req:    httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{}`)),
+			status: http.StatusBadRequest,
+			body:   `{"errors":[{"message":"query is required"}]}`,
+		},
+		{
+			name:   "malformed body",
+			req:    httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(`{"query":`)),
+			status: http.StatusBadRequest,
+			body:   `{"errors":[{"message":"body must be a JSON object with a query"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			newProductGraphQLServer().ServeHTTP(rec, tt.req)
+			assert.Equal(t, tt.status, rec.Code)
+			assert.JSONEq(t, tt.body, rec.Body.String())
+		})
+	}
+}
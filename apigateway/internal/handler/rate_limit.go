@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service/serviceutils"
+	"golang.org/x/time/rate"
+)
+
+// Rate limit response headers
+const (
+	RateLimitLimitHeader     = "X-RateLimit-Limit"
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+	RetryAfterHeader         = "Retry-After"
+)
+
+// rateLimitIdleTTL is how long a client is remembered after its last request.
+// Its bucket would be full again by then anyway.
+const rateLimitIdleTTL = 10 * time.Minute
+
+// rateLimitMaxClients caps the clients remembered at once; past it the
+// least recently seen one is forgotten.
+const rateLimitMaxClients = 10000
+
+// RateLimit is the quota of each client, a token bucket refilled with
+// PerMinute tokens a minute holding at most Burst of them.
+type RateLimit struct {
+	PerMinute int
+	Burst     int
+}
+
+// RateLimiter throttles expensive endpoints per client. Requests
+// authenticated by an Authenticator are counted against their caller,
+// others against their IP address.
+type RateLimiter struct {
+	limit RateLimit
+
+	mu        sync.Mutex
+	clients   map[string]*rateLimitClient
+	lastSweep time.Time
+}
+
+type rateLimitClient struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// NewRateLimiter creates a RateLimiter. A Burst below 1 defaults to
+// PerMinute; a PerMinute below 1 disables the limiter.
+func NewRateLimiter(limit RateLimit) *RateLimiter {
+	if limit.Burst < 1 {
+		limit.Burst = limit.PerMinute
+	}
+	return &RateLimiter{limit: limit, clients: make(map[string]*rateLimitClient)}
+}
+
+// SetLimit changes the quota, also of the clients already seen, e.g. when
+// the settings are reloaded. A PerMinute below 1 disables the limiter.
+func (l *RateLimiter) SetLimit(limit RateLimit) {
+	if limit.Burst < 1 {
+		limit.Burst = limit.PerMinute
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = limit
+	now := time.Now()
+	for _, cl := range l.clients {
+		cl.limiter.SetLimitAt(now, rate.Limit(float64(limit.PerMinute)/60))
+		cl.limiter.SetBurstAt(now, limit.Burst)
+	}
+}
+
+// Middleware rejects requests over the quota of their client with 429 and a
+// Retry-After header. Every response carries the quota headers. Register it
+// after the Authenticator so authenticated callers share their quota across
+// addresses.
+func (l *RateLimiter) Middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		now := time.Now()
+		lim, limit := l.client(rateLimitKey(c), now)
+		if lim == nil {
+			return next(c)
+		}
+
+		r := lim.ReserveN(now, 1)
+		delay := r.DelayFrom(now)
+		if delay > 0 {
+			r.CancelAt(now)
+		}
+		h := c.Response().Header()
+		h.Set(RateLimitLimitHeader, strconv.Itoa(limit.Burst))
+		h.Set(RateLimitRemainingHeader, strconv.Itoa(int(math.Max(0, lim.TokensAt(now)))))
+		if delay > 0 {
+			h.Set(RetryAfterHeader, strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+			return serviceutils.ResponseError(c, http.StatusTooManyRequests, "Rate limit exceeded",
+				fmt.Errorf("quota of %d requests per minute used up, retry in %s", limit.PerMinute, delay.Round(time.Second)))
+		}
+		return next(c)
+	}
+}
+
+// client returns the bucket of key and the quota it was filled with, a nil
+// bucket when the limiter is disabled. Idle clients are forgotten now and
+// then.
+func (l *RateLimiter) client(key string, now time.Time) (*rate.Limiter, RateLimit) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.limit.PerMinute < 1 {
+		return nil, l.limit
+	}
+
+	if now.Sub(l.lastSweep) > rateLimitIdleTTL {
+		for k, cl := range l.clients {
+			if now.Sub(cl.lastSeen) > rateLimitIdleTTL {
+				delete(l.clients, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	cl, ok := l.clients[key]
+	if !ok {
+		if len(l.clients) >= rateLimitMaxClients {
+			l.evictOldest()
+		}
+		cl = &rateLimitClient{limiter: rate.NewLimiter(rate.Limit(float64(l.limit.PerMinute)/60), l.limit.Burst)}
+		l.clients[key] = cl
+	}
+	cl.lastSeen = now
+	return cl.limiter, l.limit
+}
+
+// evictOldest forgets the least recently seen client.
+func (l *RateLimiter) evictOldest() {
+	var oldest string
+	var oldestSeen time.Time
+	for k, cl := range l.clients {
+		if oldest == "" || cl.lastSeen.Before(oldestSeen) {
+			oldest, oldestSeen = k, cl.lastSeen
+		}
+	}
+	delete(l.clients, oldest)
+}
+
+// rateLimitKey identifies the client of a request. Its IP comes from the
+// IPExtractor of the server, see ClientIPExtractor.
+
+func rateLimitKey(c echo.Context) string {
+	if caller, ok := c.Get(authCallerKey).(string); ok && caller != "" {
+		return "caller:" + caller
+	}
+	return "ip:" + c.RealIP()
+}
+
+// ClientIPExtractor returns how the server finds the IP address of a client,
+// for the rate limits and the logs. trustedProxies is a comma separated list
+// of the addresses or CIDR ranges of the proxies in front of the server:
+// X-Forwarded-For is only read when the request comes from one of them, and
+// the last address it added that is not one of them is the client's.
+// Without proxies the address of the connection is the client's, clients
+// cannot pick their own address with the headers.
+func ClientIPExtractor(trustedProxies string) (echo.IPExtractor, error) {
+	var options []echo.TrustOption
+	for _, proxy := range strings.Split(trustedProxies, ",") {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q, expected an IP address or a CIDR range", proxy)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				bits = 32
+			}
+			proxy = fmt.Sprintf("%s/%d", proxy, bits)
+		}
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q, expected an IP address or a CIDR range", proxy)
+		}
+		options = append(options, echo.TrustIPRange(ipNet))
+	}
+	if len(options) == 0 {
+		return echo.ExtractIPDirect(), nil
+	}
+	// Only the listed proxies are trusted, not the loopback, link-local and
+	// private ranges Echo trusts by default
+	options = append(options, echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false))
+	return echo.ExtractIPFromXFFHeader(options...), nil
+}
@@ -0,0 +1,161 @@
+package handler_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/handler"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	keySvc := service.NewAPIKeyService(&stubAPIKeyRepo{})
+	key := issueAPIKey(t, handler.NewAPIKeyHandler(keySvc), `{"name":"nightly","scopes":["reports:read"]}`)
+
+	e := echo.New()
+	limiter := handler.NewRateLimiter(handler.RateLimit{PerMinute: 1, Burst: 2})
+	auth := handler.NewAuthenticator(keySvc, "", false)
+	e.GET("/report", func(c echo.Context) error { return c.NoContent(http.StatusOK) },
+		auth.RequireScope("reports:read"), limiter.Middleware)
+
+	call := func(ip, apiKey string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/report", nil)
+		req.RemoteAddr = ip + ":1234"
+		if apiKey != "" {
+			req.Header.Set(handler.APIKeyHeader, apiKey)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("per ip", func(t *testing.T) {
+		for _, remaining := range []string{"1", "0"} {
+			rec := call("10.0.0.1", "")
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, "2", rec.Header().Get(handler.RateLimitLimitHeader))
+			assert.Equal(t, remaining, rec.Header().Get(handler.RateLimitRemainingHeader))
+		}
+
+		rec := call("10.0.0.1", "")
+		assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+		assert.Equal(t, "0", rec.Header().Get(handler.RateLimitRemainingHeader))
+		assert.NotEmpty(t, rec.Header().Get(handler.RetryAfterHeader))
+
+		assert.Equal(t, http.StatusOK, call("10.0.0.2", "").Code, "other clients keep their quota")
+	})
+
+	t.Run("per api key across addresses", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, call("10.0.1.1", key).Code)
+		assert.Equal(t, http.StatusOK, call("10.0.1.2", key).Code)
+		assert.Equal(t, http.StatusTooManyRequests, call("10.0.1.3", key).Code)
+		assert.Equal(t, http.StatusOK, call("10.0.1.3", "").Code, "the address keeps its own quota")
+	})
+}
+
+func TestRateLimiter_Disabled(t *testing.T) {
+	e := echo.New()
+	limiter := handler.NewRateLimiter(handler.RateLimit{})
+	e.GET("/report", func(c echo.Context) error { return c.NoContent(http.StatusOK) }, limiter.Middleware)
+
+	for i := 0; i < 5; i++ {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/report", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Header().Get(handler.RateLimitLimitHeader))
+	}
+}
+
+func TestRateLimiter_SetLimit(t *testing.T) {
+	e := echo.New()
+	limiter := handler.NewRateLimiter(handler.RateLimit{})
+	e.GET("/report", func(c echo.Context) error { return c.NoContent(http.StatusOK) }, limiter.Middleware)
+	call := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/report", nil))
+		return rec
+	}
+	assert.Equal(t, http.StatusOK, call().Code)
+
+	limiter.SetLimit(handler.RateLimit{PerMinute: 1, Burst: 3})
+	rec := call()
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "3", rec.Header().Get(handler.RateLimitLimitHeader))
+
+	// The 2 tokens left are capped to the lower burst of known clients
+	limiter.SetLimit(handler.RateLimit{PerMinute: 1, Burst: 1})
+	assert.Equal(t, http.StatusOK, call().Code)
+	assert.Equal(t, http.StatusTooManyRequests, call().Code)
+}
+
+func TestRateLimiter_ForwardedHeaders(t *testing.T) {
+	newServer := func(trustedProxies string) *echo.Echo {
+		e := echo.New()
+		extractor, err := handler.ClientIPExtractor(trustedProxies)
+		require.NoError(t, err)
+		e.IPExtractor = extractor
+		limiter := handler.NewRateLimiter(handler.RateLimit{PerMinute: 1, Burst: 1})
+		e.GET("/report", func(c echo.Context) error { return c.NoContent(http.StatusOK) }, limiter.Middleware)
+		return e
+	}
+	call := func(e *echo.Echo, remote, forwardedFor string) int {
+		req := httptest.NewRequest(http.MethodGet, "/report", nil)
+		req.RemoteAddr = remote + ":1234"
+		req.Header.Set(echo.HeaderXForwardedFor, forwardedFor)
+		req.Header.Set(echo.HeaderXRealIP, forwardedFor)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	t.Run("spoofed headers share the limit of the connection", func(t *testing.T) {
+		e := newServer("")
+		assert.Equal(t, http.StatusOK, call(e, "203.0.113.7", "198.51.100.1"))
+		assert.Equal(t, http.StatusTooManyRequests, call(e, "203.0.113.7", "198.51.100.2"))
+		assert.Equal(t, http.StatusTooManyRequests, call(e, "203.0.113.7", "198.51.100.3, 10.0.0.1"))
+	})
+
+	t.Run("trusted proxies forward the client", func(t *testing.T) {
+		e := newServer("10.0.0.0/8, 192.0.2.1")
+		assert.Equal(t, http.StatusOK, call(e, "10.0.0.5", "198.51.100.1"))
+		assert.Equal(t, http.StatusOK, call(e, "192.0.2.1", "198.51.100.2"), "another client behind a proxy")
+		assert.Equal(t, http.StatusTooManyRequests, call(e, "10.0.0.6", "198.51.100.1"))
+		// Addresses a client put before the proxy's are not read
+		assert.Equal(t, http.StatusTooManyRequests, call(e, "10.0.0.5", "198.51.100.9, 198.51.100.1"))
+		// Other hosts cannot claim to forward for someone
+		assert.Equal(t, http.StatusOK, call(e, "203.0.113.7", "198.51.100.3"))
+		assert.Equal(t, http.StatusTooManyRequests, call(e, "203.0.113.7", "198.51.100.4"))
+	})
+
+	t.Run("invalid proxies", func(t *testing.T) {
+		_, err := handler.ClientIPExtractor("10.0.0.0/8, proxy.local")
+		assert.ErrorContains(t, err, `invalid trusted proxy "proxy.local"`)
+	})
+}
+
+func TestRateLimiter_ForgetsTheOldestClientsPastTheCap(t *testing.T) {
+	e := echo.New()
+	e.IPExtractor = echo.ExtractIPDirect()
+	limiter := handler.NewRateLimiter(handler.RateLimit{PerMinute: 1, Burst: 1})
+	e.GET("/report", func(c echo.Context) error { return c.NoContent(http.StatusOK) }, limiter.Middleware)
+	call := func(i int) int {
+		req := httptest.NewRequest(http.MethodGet, "/report", nil)
+		req.RemoteAddr = fmt.Sprintf("10.%d.%d.%d:1234", i>>16&255, i>>8&255, i&255)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, call(0))
+	assert.Equal(t, http.StatusTooManyRequests, call(0))
+	for i := 1; i <= 10000; i++ {
+		call(i)
+	}
+	assert.Equal(t, http.StatusOK, call(0), "the oldest client was forgotten")
+	assert.Equal(t, http.StatusTooManyRequests, call(10000), "recent clients are kept")
+}
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service/serviceutils"
+)
+
+// Reloader reloads the report templates and the settings that can change
+// without a restart.
+type Reloader func(ctx context.Context) (*domain.ReloadResult, error)
+
+// ReloadHandler lets admins reload templates and settings in place.
+type ReloadHandler struct {
+	reload Reloader
+}
+
+func NewReloadHandler(reload Reloader) *ReloadHandler {
+	return &ReloadHandler{reload: reload}
+}
+
+// ReloadHandler handles POST /admin/reload. Templates that fail to load are
+// listed with their error, exports already running finish on the revision
+// they started with.
+func (h *ReloadHandler) ReloadHandler(c echo.Context) error {
+	result, err := h.reload(c.Request().Context())
+	if err != nil {
+		return serviceutils.ResponseAppError(c, "Failed to reload", err)
+	}
+	failed := 0
+	for _, t := range result.Templates {
+		if t.Error != "" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return serviceutils.ResponseSuccess(c, http.StatusOK, fmt.Sprintf("Reloaded, %d templates failed to load", failed), result)
+	}
+	return serviceutils.ResponseSuccess(c, http.StatusOK, "Reloaded", result)
+}
@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/apperror"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/logger"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service/serviceutils"
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/simpleexcelv2"
+)
+
+type ReportHandler struct {
+	svc service.ReportService
+}
+
+func NewReportHandler(svc service.ReportService) *ReportHandler {
+	return &ReportHandler{svc: svc}
+}
+
+// GenerateHandler handles POST /reports/generate
+func (h *ReportHandler) GenerateHandler(c echo.Context) error {
+	ctx := c.Request().Context()
+
+	var req domain.ExportRequest
+	if err := serviceutils.Bind(c, &req); err != nil {
+		return respondValidationError(c, "Invalid request body", err)
+	}
+
+	// Reject bad requests up front instead of failing mid-render
+	if err := h.svc.Validate(ctx, &req); err != nil {
+		return respondExportError(c, err)
+	}
+
+	if req.Delivery.Type != domain.DeliveryDownload {
+		var delivered deliveryWarnings
+		if err := h.svc.Generate(ctx, &req, &delivered); err != nil {
+			return respondExportError(c, err)
+		}
+		return serviceutils.ResponseSuccess(c, http.StatusOK, "Report delivered successfully", deliveryResult{
+			DeliveryTarget: req.Delivery,
+			Warnings:       delivered.warnings,
+		})
+	}
+
+	contentType := "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	disposition := "attachment"
+	switch req.Format {
+	case domain.ExportFormatCSV:
+		contentType = "text/csv"
+	case domain.ExportFormatHTML:
+		// Previews are shown in the browser rather than saved
+		contentType = echo.MIMETextHTMLCharsetUTF8
+		disposition = "inline"
+	}
+	return h.download(c, &req, contentType, fmt.Sprintf(`%s; filename="%s.%s"`, disposition, req.TemplateID, req.Format))
+}
+
+// DeptOrgChartHandler handles GET /departments/:id/orgchart.xlsx
+func (h *ReportHandler) DeptOrgChartHandler(c echo.Context) error {
+	deptNo := c.Param("id")
+	req := domain.ExportRequest{
+		TemplateID: service.DeptOrgChartReportID,
+		Format:     domain.ExportFormatXLSX,
+		Variables:  map[string]interface{}{"dept_no": deptNo},
+	}
+	if err := h.svc.Validate(c.Request().Context(), &req); err != nil {
+		return respondExportError(c, err)
+	}
+	return h.download(c, &req, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		fmt.Sprintf(`attachment; filename="orgchart_%s.xlsx"`, url.PathEscape(deptNo)))
+}
+
+// EmployeeReportHandler handles GET /employees/:id/report.xlsx. Managers add
+// notes and goals to the file and upload it to POST /employees/:id/report/import.
+func (h *ReportHandler) EmployeeReportHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return serviceutils.ResponseError(c, http.StatusBadRequest, "Invalid employee ID", err)
+	}
+	req := domain.ExportRequest{
+		TemplateID: service.EmployeeReportID,
+		Format:     domain.ExportFormatXLSX,
+		Variables:  map[string]interface{}{"emp_no": id},
+	}
+	if err := h.svc.Validate(c.Request().Context(), &req); err != nil {
+		return respondExportError(c, err)
+	}
+	return h.download(c, &req, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		fmt.Sprintf(`attachment; filename="employee_%d_report.xlsx"`, id))
+}
+
+// EmployeeExportHandler handles GET /employees/export. The employee list is
+// streamed from the database into the employee_list template; query parameters
+// set the template variables, e.g. ?limit=500&offset=1000&locale=de-DE. Unlike
+// the template default, the export covers every employee unless limit is set.
+func (h *ReportHandler) EmployeeExportHandler(c echo.Context) error {
+	vars := map[string]interface{}{"limit": 0}
+	for name, values := range c.QueryParams() {
+		vars[name] = values[0]
+	}
+	req := domain.ExportRequest{
+		TemplateID: service.EmployeeListReportID,
+		Format:     domain.ExportFormatXLSX,
+		Variables:  vars,
+	}
+	if err := h.svc.Validate(c.Request().Context(), &req); err != nil {
+		return respondExportError(c, err)
+	}
+	return h.download(c, &req, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		`attachment; filename="employees.xlsx"`)
+}
+
+// download writes the generated report to the response. Errors raised before
+// anything was written are reported as JSON.
+func (h *ReportHandler) download(c echo.Context, req *domain.ExportRequest, contentType, disposition string) error {
+	ctx := c.Request().Context()
+	c.Response().Header().Set(echo.HeaderContentType, contentType)
+	c.Response().Header().Set(echo.HeaderContentDisposition, disposition)
+
+	if err := h.svc.Generate(ctx, req, exportResponse{c.Response()}); err != nil {
+		logger.ErrorLog(ctx, "Failed to generate report %s: %v", req.TemplateID, err)
+		if c.Response().Committed {
+			return err
+		}
+		c.Response().Header().Del(echo.HeaderContentDisposition)
+		return respondExportError(c, err)
+	}
+	return nil
+}
+
+// Export warnings of downloads are listed in response headers: the count,
+// and the first maxWarningHeaders warnings one header each, formatted as
+// "<kind>: <warning>".
+const (
+	ExportWarningCountHeader = "X-Export-Warning-Count"
+	ExportWarningHeader      = "X-Export-Warning"
+	maxWarningHeaders        = 20
+)
+
+// exportResponse sets the export warnings as headers before the file is
+// written, see service.ExportWarningSink.
+type exportResponse struct {
+	*echo.Response
+}
+
+func (r exportResponse) ExportWarnings(warnings []simpleexcelv2.Warning) {
+	if len(warnings) == 0 {
+		return
+	}
+	r.Header().Set(ExportWarningCountHeader, strconv.Itoa(len(warnings)))
+	for i, w := range warnings {
+		if i == maxWarningHeaders {
+			break
+		}
+		r.Header().Add(ExportWarningHeader, fmt.Sprintf("%s: %s", w.Kind, w))
+	}
+}
+
+// deliveryWarnings collects the export warnings of a bucket delivery,
+// nothing is written to it.
+type deliveryWarnings struct {
+	warnings []simpleexcelv2.Warning
+}
+
+func (d *deliveryWarnings) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (d *deliveryWarnings) ExportWarnings(warnings []simpleexcelv2.Warning) {
+	d.warnings = warnings
+}
+
+// deliveryResult is the response of a bucket delivery.
+type deliveryResult struct {
+	*domain.DeliveryTarget
+	Warnings []simpleexcelv2.Warning `json:"warnings,omitempty"`
+}
+
+// VariablesHandler handles GET /reports/:id/variables
+func (h *ReportHandler) VariablesHandler(c echo.Context) error {
+	vars, err := h.svc.Variables(c.Request().Context(), c.Param("id"))
+	if errors.Is(err, service.ErrReportNotFound) {
+		return serviceutils.ResponseAppError(c, "Report template not found", err)
+	}
+	if err != nil {
+		return serviceutils.ResponseAppError(c, "Failed to load report variables", err)
+	}
+	return serviceutils.ResponseSuccess(c, http.StatusOK, "Report variables retrieved successfully", vars)
+}
+
+// maxTemplateSize bounds the templates accepted for validation.
+const maxTemplateSize = 1 << 20
+
+// ValidateTemplateHandler handles POST /reports/templates/validate. The body
+// is the raw YAML template, possibly incomplete; the diagnostics are returned
+// with a 200 whether or not the template is valid.
+func (h *ReportHandler) ValidateTemplateHandler(c echo.Context) error {
+	body, err := io.ReadAll(io.LimitReader(c.Request().Body, maxTemplateSize+1))
+	if err != nil {
+		return serviceutils.ResponseError(c, http.StatusBadRequest, "Invalid request body", err)
+	}
+	if len(body) > maxTemplateSize {
+		return serviceutils.ResponseError(c, http.StatusRequestEntityTooLarge, "Template too large",
+			fmt.Errorf("templates are limited to %d bytes", maxTemplateSize))
+	}
+	result := h.svc.ValidateTemplate(c.Request().Context(), string(body))
+	return serviceutils.ResponseSuccess(c, http.StatusOK, "Template validated", result)
+}
+
+// respondExportError maps validation failures to 400 with field-level
+// details, unknown reports to 404.
+func respondExportError(c echo.Context, err error) error {
+	switch apperror.KindOf(err) {
+	case apperror.KindValidation:
+		return serviceutils.ResponseAppError(c, "Invalid export request", err)
+	case apperror.KindNotFound:
+		return serviceutils.ResponseAppError(c, "Report template not found", err)
+	}
+	return serviceutils.ResponseAppError(c, "Failed to generate report", err)
+}
@@ -0,0 +1,382 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/handler"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/exceltest"
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/simpleexcelv2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+const testReportTemplate = `
+name: "People"
+variables:
+  department: string
+  limit: int
+  hired_after: date
+sheets:
+  - name: "People"
+    sections:
+      - id: "people"
+        show_header: true
+        columns:
+          - field_name: "Name"
+            header: "Name"
+          - field_name: "Email"
+            header: "Email"
+`
+
+type testPerson struct {
+	Name  string
+	Email string
+}
+
+func newTestReportHandler(t *testing.T) (*handler.ReportHandler, *map[string]interface{}) {
+	path := filepath.Join(t.TempDir(), "people.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testReportTemplate), 0o644))
+
+	var gotVars map[string]interface{}
+	svc := service.NewReportService(nil)
+	svc.Register(service.ReportDefinition{
+		ID:           "people",
+		TemplatePath: path,
+		Load: func(ctx context.Context, vars simpleexcelv2.VariableValues) (map[string]interface{}, error) {
+			gotVars = vars
+			return map[string]interface{}{
+				"people": []testPerson{{Name: "Alice", Email: "alice@example.com"}},
+			}, nil
+		},
+	})
+	return handler.NewReportHandler(svc), &gotVars
+}
+
+func postGenerate(t *testing.T, h *handler.ReportHandler, body string) *httptest.ResponseRecorder {
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/reports/generate", strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	require.NoError(t, h.GenerateHandler(e.NewContext(req, rec)))
+	return rec
+}
+
+func TestReportGenerate_Download(t *testing.T) {
+	h, gotVars := newTestReportHandler(t)
+
+	rec := postGenerate(t, h, `{
+		"template_id": "people",
+		"variables": {"department": "d001", "limit": 10, "hired_after": "2020-01-31"},
+		"masking": {"mode": "partial", "columns": ["Email"]}
+	}`)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", rec.Header().Get(echo.HeaderContentType))
+	assert.Contains(t, rec.Header().Get(echo.HeaderContentDisposition), "people.xlsx")
+	exceltest.AssertRows(t, rec.Body.Bytes(), "People", [][]string{
+		{"Name", "Email"},
+		{"Alice", "*************.com"},
+	})
+	assert.Equal(t, 10, (*gotVars)["limit"])
+	assert.Equal(t, "d001", (*gotVars)["department"])
+	assert.Equal(t, "1", rec.Header().Get(handler.ExportWarningCountHeader))
+	assert.Equal(t, []string{"masked_column: sheet People, section people, field Email: values are masked"},
+		rec.Header().Values(handler.ExportWarningHeader))
+}
+
+func TestReportGenerate_PartialMaskMultibyte(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "people.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testReportTemplate), 0o644))
+	svc := service.NewReportService(nil)
+	svc.Register(service.ReportDefinition{
+		ID:           "people",
+		TemplatePath: path,
+		Load: func(ctx context.Context, vars simpleexcelv2.VariableValues) (map[string]interface{}, error) {
+			return map[string]interface{}{
+				"people": []testPerson{{Name: "Nguyễn Văn Đức", Email: "đức@ví.dụ"}},
+			}, nil
+		},
+	})
+
+	rec := postGenerate(t, handler.NewReportHandler(svc), `{
+		"template_id": "people",
+		"variables": {"department": "d001", "limit": 10, "hired_after": "2020-01-31"},
+		"masking": {"mode": "partial", "columns": ["Name", "Email"]}
+	}`)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	exceltest.AssertRows(t, rec.Body.Bytes(), "People", [][]string{
+		{"Name", "Email"},
+		{"********** Đức", "*****í.dụ"},
+	})
+}
+
+func TestReportGenerate_BucketDeliveryWarnings(t *testing.T) {
+	// The people section is optional and left without data
+	tmpl := strings.Replace(testReportTemplate, "show_header: true", "show_header: true\n        optional: true", 1)
+	path := filepath.Join(t.TempDir(), "people.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(tmpl), 0o644))
+	bucket := memoryBucket{}
+	svc := service.NewReportService(map[string]simpleexcelv2.ObjectWriterProvider{domain.DeliveryS3: bucket})
+	svc.Register(service.ReportDefinition{
+		ID:           "people",
+		TemplatePath: path,
+		Load: func(ctx context.Context, vars simpleexcelv2.VariableValues) (map[string]interface{}, error) {
+			return map[string]interface{}{}, nil
+		},
+	})
+
+	rec := postGenerate(t, handler.NewReportHandler(svc), `{
+		"template_id": "people",
+		"variables": {"department": "d001", "limit": 10, "hired_after": "2020-01-31"},
+		"delivery": {"type": "s3", "bucket": "reports", "key": "people.xlsx"},
+		"masking": {"columns": ["Email"]}
+	}`)
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var resp struct {
+		Data struct {
+			Key      string                  `json:"key"`
+			Warnings []simpleexcelv2.Warning `json:"warnings"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, "people.xlsx", resp.Data.Key)
+	assert.Equal(t, []simpleexcelv2.Warning{
+		{Kind: simpleexcelv2.WarningMaskedColumn, Sheet: "People", Section: "people", Field: "Email", Message: "values are masked"},
+		{Kind: simpleexcelv2.WarningUnboundSection, Sheet: "People", Section: "people", Message: "no data is bound, the section has no rows"},
+	}, resp.Data.Warnings)
+	assert.Contains(t, bucket, "reports/people.xlsx")
+}
+
+func TestReportGenerate_HTMLPreview(t *testing.T) {
+	h, _ := newTestReportHandler(t)
+
+	rec := postGenerate(t, h, `{
+		"template_id": "people",
+		"format": "html",
+		"variables": {"department": "d001", "limit": 10, "hired_after": "2020-01-31"}
+	}`)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, echo.MIMETextHTMLCharsetUTF8, rec.Header().Get(echo.HeaderContentType))
+	assert.True(t, strings.HasPrefix(rec.Header().Get(echo.HeaderContentDisposition), "inline"))
+	assert.Contains(t, rec.Body.String(), "<td>alice@example.com</td>")
+}
+
+func TestReportGenerate_RejectsInvalidRequest(t *testing.T) {
+	h, _ := newTestReportHandler(t)
+
+	tests := []struct {
+		name   string
+		body   string
+		fields []string
+	}{
+		{
+			name:   "unknown template",
+			body:   `{"template_id": "payroll"}`,
+			fields: []string{"template_id"},
+		},
+		{
+			name:   "missing and mistyped variables",
+			body:   `{"template_id": "people", "variables": {"limit": 2.5, "hired_after": "31/01/2020", "dept": "x"}}`,
+			fields: []string{"variables.department", "variables.hired_after", "variables.limit", "variables.dept"},
+		},
+		{
+			name:   "bad format and delivery",
+			body:   `{"template_id": "people", "variables": {"department": "d", "limit": 1, "hired_after": "2020-01-01"}, "format": "pdf", "delivery": {"type": "s3"}}`,
+			fields: []string{"format", "delivery.bucket", "delivery.key", "delivery.type"},
+		},
+		{
+			name:   "masking unknown column",
+			body:   `{"template_id": "people", "variables": {"department": "d", "limit": 1, "hired_after": "2020-01-01"}, "masking": {"columns": ["Salary"]}}`,
+			fields: []string{"masking.columns"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := postGenerate(t, h, tt.body)
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+			var resp struct {
+				Success bool
+				Data    []struct {
+					Field   string `json:"field"`
+					Message string `json:"message"`
+				}
+			}
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+			assert.False(t, resp.Success)
+
+			var fields []string
+			for _, fe := range resp.Data {
+				fields = append(fields, fe.Field)
+			}
+			assert.ElementsMatch(t, tt.fields, fields)
+		})
+	}
+}
+
+func TestReportVariables(t *testing.T) {
+	h, _ := newTestReportHandler(t)
+	e := echo.New()
+
+	req := httptest.NewRequest(http.MethodGet, "/reports/people/variables", nil)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetParamNames("id")
+	c.SetParamValues("people")
+	require.NoError(t, h.VariablesHandler(c))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var resp struct {
+		Data []simpleexcelv2.VariableDecl
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Len(t, resp.Data, 3)
+	assert.Equal(t, "department", resp.Data[0].Name)
+	assert.Equal(t, "date", resp.Data[2].Type)
+
+	rec = httptest.NewRecorder()
+	c = e.NewContext(httptest.NewRequest(http.MethodGet, "/reports/payroll/variables", nil), rec)
+	c.SetParamNames("id")
+	c.SetParamValues("payroll")
+	require.NoError(t, h.VariablesHandler(c))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestReportValidateTemplate(t *testing.T) {
+	h, _ := newTestReportHandler(t)
+	e := echo.New()
+
+	post := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/reports/templates/validate", strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, "application/yaml")
+		rec := httptest.NewRecorder()
+		require.NoError(t, h.ValidateTemplateHandler(e.NewContext(req, rec)))
+		return rec
+	}
+
+	rec := post("sheets:\n  - name: \"Staff\"\n    sectons:\n")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var resp struct {
+		Data simpleexcelv2.TemplateValidation
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.True(t, resp.Data.Valid, "unknown keys are warnings")
+	require.Len(t, resp.Data.Diagnostics, 1)
+	assert.Equal(t, simpleexcelv2.TemplateDiagnostic{
+		Severity:   simpleexcelv2.SeverityWarning,
+		Line:       3,
+		Column:     5,
+		Path:       "sheets[0].sectons",
+		Message:    `unknown field "sectons", it is ignored`,
+		Suggestion: "sections",
+	}, resp.Data.Diagnostics[0])
+
+	rec = post(strings.Repeat("#", 1<<20+1))
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+}
+
+func TestReportGenerate_StreamsEmployeeListInChunks(t *testing.T) {
+	employees := make([]domain.Employee, 2500)
+	for i := range employees {
+		employees[i] = domain.Employee{ID: i + 1, FirstName: "First", LastName: "Last", Gender: "F"}
+	}
+	repo := &stubEmployeeRepo{employees: employees}
+	svc := service.NewReportService(nil)
+	svc.Register(service.NewEmployeeListReport(service.NewEmployeeService(repo, stubTx{}), "../../templates/employee_list.yaml"))
+
+	rec := postGenerate(t, handler.NewReportHandler(svc), `{"template_id": "employee_list"}`)
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, 3, repo.chunks)
+	f, err := excelize.OpenReader(bytes.NewReader(rec.Body.Bytes()))
+	require.NoError(t, err)
+	defer f.Close()
+	last, err := f.GetCellValue("Employees", "A2502", excelize.Options{RawCellValue: true})
+	require.NoError(t, err)
+	assert.Equal(t, "2500", last)
+}
+
+func TestReportGenerate_DeptManagerTimeline(t *testing.T) {
+	date := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	repo := &stubEmployeeRepo{managers: []domain.DeptManager{
+		{DeptNo: "d001", EmpNo: 110039, FromDate: date("1991-10-01"), ToDate: date("9999-01-01")},
+		{DeptNo: "d001", EmpNo: 110022, FromDate: date("1985-01-01"), ToDate: date("1991-10-01")},
+	}}
+	svc := service.NewReportService(nil)
+	svc.Register(service.NewDeptManagerTimelineReport(service.NewEmployeeService(repo, stubTx{}), "../../templates/dept_manager_timeline.yaml"))
+
+	rec := postGenerate(t, handler.NewReportHandler(svc), `{"template_id": "dept_manager_timeline", "variables": {"dept_no": "d001"}}`)
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	f, err := excelize.OpenReader(bytes.NewReader(rec.Body.Bytes()))
+	require.NoError(t, err)
+	defer f.Close()
+	rows, err := f.GetRows("Managers")
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+	assert.Equal(t, []string{"Manager", "From", "To", "1985", "1986"}, rows[1][:5])
+	assert.Equal(t, "110022", rows[3][0])
+	assert.Equal(t, "1991-10-01", rows[3][2])
+}
+
+func TestDeptOrgChart(t *testing.T) {
+	date := func(s string) time.Time {
+		d, _ := time.Parse("2006-01-02", s)
+		return d
+	}
+	repo := &stubEmployeeRepo{
+		managers: []domain.DeptManager{
+			{DeptNo: "d001", EmpNo: 2, FromDate: date("1991-10-01"), ToDate: date("9999-01-01")},
+			{DeptNo: "d001", EmpNo: 1, FromDate: date("1985-01-01"), ToDate: date("1991-10-01")},
+		},
+		employees: []domain.Employee{
+			{ID: 1, FirstName: "Ann", LastName: "Lee"},
+			{ID: 2, FirstName: "Bob", LastName: "Kim"},
+			{ID: 3, FirstName: "Cat", LastName: "Ng"},
+		},
+	}
+	svc := service.NewReportService(nil)
+	svc.Register(service.NewDeptOrgChartReport(service.NewEmployeeService(repo, stubTx{}), "../../templates/dept_orgchart.yaml"))
+	h := handler.NewReportHandler(svc)
+
+	e := echo.New()
+	rec := httptest.NewRecorder()
+	c := e.NewContext(httptest.NewRequest(http.MethodGet, "/departments/d001/orgchart.xlsx", nil), rec)
+	c.SetParamNames("id")
+	c.SetParamValues("d001")
+	require.NoError(t, h.DeptOrgChartHandler(c))
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, `attachment; filename="orgchart_d001.xlsx"`, rec.Header().Get(echo.HeaderContentDisposition))
+	f, err := excelize.OpenReader(bytes.NewReader(rec.Body.Bytes()))
+	require.NoError(t, err)
+	defer f.Close()
+	rows, err := f.GetRows("Org Chart")
+	require.NoError(t, err)
+	require.Len(t, rows, 5)
+	assert.Equal(t, []string{"Bob Kim", "2"}, rows[2], "the current manager heads the chart")
+	assert.Equal(t, "Ann Lee", rows[3][0])
+	level, err := f.GetRowOutlineLevel("Org Chart", 4)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(1), level)
+}
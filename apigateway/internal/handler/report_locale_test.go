@@ -0,0 +1,63 @@
+package handler_test
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/handler"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/simpleexcelv2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testLocaleTemplate = `
+name: "Hires"
+variables:
+  locale:
+    type: string
+    default: "en-US"
+sheets:
+  - name: "Hires"
+    sections:
+      - id: "hires"
+        show_header: true
+        columns:
+          - field_name: "Name"
+            header: "Name"
+          - field_name: "Salary"
+            header: "Salary"
+          - field_name: "HireDate"
+            header: "Hire Date"
+`
+
+type testHire struct {
+	Name     string
+	Salary   float64
+	HireDate time.Time
+}
+
+func TestReportGenerate_AppliesLocale(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "hires.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testLocaleTemplate), 0o644))
+	svc := service.NewReportService(nil)
+	svc.Register(service.ReportDefinition{
+		ID:           "hires",
+		TemplatePath: path,
+		Load: func(ctx context.Context, vars simpleexcelv2.VariableValues) (map[string]interface{}, error) {
+			return map[string]interface{}{
+				"hires": []testHire{{Name: "Alice", Salary: 1234.5, HireDate: time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC)}},
+			}, nil
+		},
+	})
+	h := handler.NewReportHandler(svc)
+
+	rec := postGenerate(t, h, `{"template_id": "hires", "format": "csv", "variables": {"locale": "de-DE"}}`)
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Contains(t, rec.Body.String(), `Alice,"1.234,50",31.01.2020`)
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service/serviceutils"
+)
+
+type SalaryHandler struct {
+	svc service.SalaryService
+}
+
+func NewSalaryHandler(svc service.SalaryService) *SalaryHandler {
+	return &SalaryHandler{svc: svc}
+}
+
+type salaryRequest struct {
+	Salary   int    `json:"salary"`
+	FromDate string `json:"from_date"`
+	ToDate   string `json:"to_date"`
+}
+
+// HistoryHandler handles GET /employees/:id/salaries
+func (h *SalaryHandler) HistoryHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return serviceutils.ResponseError(c, http.StatusBadRequest, "Invalid employee ID", err)
+	}
+
+	salaries, err := h.svc.History(c.Request().Context(), id)
+	if err != nil {
+		return respondSalaryError(c, "Failed to list salaries", err)
+	}
+	return serviceutils.ResponseSuccess(c, http.StatusOK, "Salaries listed successfully", salaries)
+}
+
+// CurrentHandler handles GET /employees/:id/salaries/current
+func (h *SalaryHandler) CurrentHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return serviceutils.ResponseError(c, http.StatusBadRequest, "Invalid employee ID", err)
+	}
+
+	salary, err := h.svc.Current(c.Request().Context(), id)
+	if err != nil {
+		return respondSalaryError(c, "Failed to get current salary", err)
+	}
+	return serviceutils.ResponseSuccess(c, http.StatusOK, "Current salary retrieved successfully", salary)
+}
+
+// AddHandler handles POST /employees/:id/salaries with {"salary", "from_date",
+// "to_date"}. Without to_date the salary becomes the current one and the
+// previous current salary ends on from_date.
+func (h *SalaryHandler) AddHandler(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return serviceutils.ResponseError(c, http.StatusBadRequest, "Invalid employee ID", err)
+	}
+	var req salaryRequest
+	if err := serviceutils.Bind(c, &req); err != nil {
+		return respondValidationError(c, "Invalid request body", err)
+	}
+
+	s := domain.Salary{EmployeeID: id, Salary: req.Salary}
+	verr := &domain.ValidationError{}
+	s.FromDate = parseDateField(verr, "from_date", req.FromDate)
+	s.ToDate = parseDateField(verr, "to_date", req.ToDate)
+	if err := verr.ErrOrNil(); err != nil {
+		return respondSalaryError(c, "Failed to add salary", err)
+	}
+
+	if err := h.svc.Add(c.Request().Context(), &s); err != nil {
+		return respondSalaryError(c, "Failed to add salary", err)
+	}
+	return serviceutils.ResponseSuccess(c, http.StatusCreated, "Salary added successfully", s)
+}
+
+// UpdateHandler handles PUT /employees/:id/salaries/:from_date with {"salary"}
+func (h *SalaryHandler) UpdateHandler(c echo.Context) error {
+	id, fromDate, err := salaryKey(c)
+	if err != nil {
+		return respondSalaryError(c, "Failed to update salary", err)
+	}
+	var req salaryRequest
+	if err := serviceutils.Bind(c, &req); err != nil {
+		return respondValidationError(c, "Invalid request body", err)
+	}
+
+	if err := h.svc.UpdateAmount(c.Request().Context(), id, fromDate, req.Salary); err != nil {
+		return respondSalaryError(c, "Failed to update salary", err)
+	}
+	return serviceutils.ResponseSuccess(c, http.StatusOK, "Salary updated successfully", nil)
+}
+
+// DeleteHandler handles DELETE /employees/:id/salaries/:from_date
+func (h *SalaryHandler) DeleteHandler(c echo.Context) error {
+	id, fromDate, err := salaryKey(c)
+	if err != nil {
+		return respondSalaryError(c, "Failed to delete salary", err)
+	}
+
+	if err := h.svc.Delete(c.Request().Context(), id, fromDate); err != nil {
+		return respondSalaryError(c, "Failed to delete salary", err)
+	}
+	return serviceutils.ResponseSuccess(c, http.StatusOK, "Salary deleted successfully", nil)
+}
+
+// salaryKey reads the employee id and from_date path parameters.
+func salaryKey(c echo.Context) (int, time.Time, error) {
+	verr := &domain.ValidationError{}
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		verr.Add("id", "must be an employee number, got %q", c.Param("id"))
+	}
+	fromDate := parseDateField(verr, "from_date", c.Param("from_date"))
+	return id, fromDate, verr.ErrOrNil()
+}
+
+// respondSalaryError answers err with the status of its kind, naming
+// missing employees or salaries.
+func respondSalaryError(c echo.Context, message string, err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		message = "Employee or salary not found"
+	}
+	return serviceutils.ResponseAppError(c, message, err)
+}
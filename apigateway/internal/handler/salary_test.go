@@ -0,0 +1,196 @@
+package handler_test
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/handler"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubSalaryRepo keeps salary records in memory. It logs the calls of Add,
+// marking those made in a recordingTx.
+type stubSalaryRepo struct {
+	salaries []domain.Salary
+	calls    []string
+}
+
+type inTxKey struct{}
+
+// recordingTx runs functions directly with a context marking them in a
+// transaction.
+type recordingTx struct{}
+
+func (recordingTx) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(context.WithValue(ctx, inTxKey{}, true))
+}
+
+func (r *stubSalaryRepo) log(ctx context.Context, call string) {
+	if ctx.Value(inTxKey{}) != nil {
+		call += " in tx"
+	}
+	r.calls = append(r.calls, call)
+}
+
+func (r *stubSalaryRepo) LockSalaries(ctx context.Context, empNo int) error {
+	r.log(ctx, "lock")
+	return nil
+}
+
+func (r *stubSalaryRepo) ListSalaries(ctx context.Context, empNo int) ([]domain.Salary, error) {
+	r.log(ctx, "list")
+	var salaries []domain.Salary
+	for i := len(r.salaries) - 1; i >= 0; i-- {
+		if r.salaries[i].EmployeeID == empNo {
+			salaries = append(salaries, r.salaries[i])
+		}
+	}
+	return salaries, nil
+}
+
+func (r *stubSalaryRepo) GetCurrentSalary(ctx context.Context, empNo int) (*domain.Salary, error) {
+	for _, s := range r.salaries {
+		if s.EmployeeID == empNo && s.IsCurrent() {
+			return &s, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (r *stubSalaryRepo) AddSalary(ctx context.Context, s *domain.Salary) error {
+	r.log(ctx, "add")
+	if s.IsCurrent() {
+		for i := range r.salaries {
+			if r.salaries[i].EmployeeID == s.EmployeeID && r.salaries[i].IsCurrent() {
+				r.salaries[i].ToDate = s.FromDate
+			}
+		}
+	}
+	r.salaries = append(r.salaries, *s)
+	return nil
+}
+
+func (r *stubSalaryRepo) UpdateSalaryAmount(ctx context.Context, empNo int, fromDate time.Time, amount int) error {
+	for i := range r.salaries {
+		if s := &r.salaries[i]; s.EmployeeID == empNo && s.FromDate.Equal(fromDate) {
+			s.Salary = amount
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func (r *stubSalaryRepo) DeleteSalary(ctx context.Context, empNo int, fromDate time.Time) error {
+	for i, s := range r.salaries {
+		if s.EmployeeID == empNo && s.FromDate.Equal(fromDate) {
+			r.salaries = append(r.salaries[:i], r.salaries[i+1:]...)
+			return nil
+		}
+	}
+	return sql.ErrNoRows
+}
+
+func newSalaryHandler() (*handler.SalaryHandler, *stubSalaryRepo) {
+	repo := &stubSalaryRepo{salaries: []domain.Salary{
+		{EmployeeID: 10001, Salary: 60000, FromDate: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), ToDate: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{EmployeeID: 10001, Salary: 65000, FromDate: time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC), ToDate: domain.OpenEndDate},
+	}}
+	empRepo := &stubEmployeeRepo{employees: testEmployees()}
+	return handler.NewSalaryHandler(service.NewSalaryService(repo, empRepo, recordingTx{})), repo
+}
+
+func TestSalary_AddEndsCurrentSalary(t *testing.T) {
+	h, repo := newSalaryHandler()
+
+	rec, _ := callJSON(t, h.AddHandler, http.MethodPost, `{"salary": 70000, "from_date": "2024-03-01"}`, "id", "10001")
+
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	require.Len(t, repo.salaries, 3)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), repo.salaries[1].ToDate, "the previous salary ends when the new one starts")
+	assert.Equal(t, domain.Salary{EmployeeID: 10001, Salary: 70000,
+		FromDate: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), ToDate: domain.OpenEndDate}, repo.salaries[2])
+}
+
+func TestSalary_AddChecksOverlapsUnderTheLock(t *testing.T) {
+	h, repo := newSalaryHandler()
+
+	rec, _ := callJSON(t, h.AddHandler, http.MethodPost, `{"salary": 70000, "from_date": "2024-03-01"}`, "id", "10001")
+
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Equal(t, []string{"lock in tx", "list in tx", "add in tx"}, repo.calls,
+		"the history is locked before it is checked, and stored in the same transaction")
+}
+
+func TestSalary_AddRejectsInvalidRanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		id     string
+		body   string
+		fields []string
+	}{
+		{"missing fields", "10001", `{}`, []string{"salary", "from_date"}},
+		{"bad dates", "10001", `{"salary": 70000, "from_date": "03/01/2024", "to_date": "soon"}`, []string{"from_date", "to_date"}},
+		{"ends before it starts", "10001", `{"salary": 70000, "from_date": "2024-03-01", "to_date": "2024-02-01"}`, []string{"to_date"}},
+		{"overlaps a past salary", "10001", `{"salary": 70000, "from_date": "2021-06-01", "to_date": "2021-09-01"}`, []string{"from_date"}},
+		{"starts before the current salary", "10001", `{"salary": 70000, "from_date": "2021-12-31"}`, []string{"from_date"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, repo := newSalaryHandler()
+
+			rec, fields := callJSON(t, h.AddHandler, http.MethodPost, tt.body, "id", tt.id)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+			assert.ElementsMatch(t, tt.fields, fields)
+			assert.Len(t, repo.salaries, 2, "nothing is stored")
+		})
+	}
+}
+
+func TestSalary_AddBeforeHistory(t *testing.T) {
+	h, repo := newSalaryHandler()
+
+	rec, _ := callJSON(t, h.AddHandler, http.MethodPost, `{"salary": 50000, "from_date": "2018-01-01", "to_date": "2020-01-01"}`, "id", "10001")
+
+	require.Equal(t, http.StatusCreated, rec.Code, rec.Body.String())
+	assert.Equal(t, domain.OpenEndDate, repo.salaries[1].ToDate, "a closed range leaves the current salary open")
+}
+
+func TestSalary_Current(t *testing.T) {
+	h, _ := newSalaryHandler()
+
+	rec, _ := callJSON(t, h.CurrentHandler, http.MethodGet, ``, "id", "10001")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"salary":65000`)
+
+	rec, _ = callJSON(t, h.CurrentHandler, http.MethodGet, ``, "id", "10002")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec, _ = callJSON(t, h.HistoryHandler, http.MethodGet, ``, "id", "404")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestSalary_UpdateAndDelete(t *testing.T) {
+	h, repo := newSalaryHandler()
+
+	rec, _ := callJSON(t, h.UpdateHandler, http.MethodPut, `{"salary": 66000}`, "id", "10001", "from_date", "2022-01-01")
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	assert.Equal(t, 66000, repo.salaries[1].Salary)
+
+	rec, fields := callJSON(t, h.UpdateHandler, http.MethodPut, `{"salary": 0}`, "id", "10001", "from_date", "2022-01-01")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, []string{"salary"}, fields)
+
+	rec, _ = callJSON(t, h.DeleteHandler, http.MethodDelete, ``, "id", "10001", "from_date", "2023-01-01")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec, _ = callJSON(t, h.DeleteHandler, http.MethodDelete, ``, "id", "10001", "from_date", "2020-01-01")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Len(t, repo.salaries, 1)
+}
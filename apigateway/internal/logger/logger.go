@@ -5,49 +5,142 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"strings"
 	"sync"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
 
+// Output formats of InitLogging.
+const (
+	FormatJSON    = "json"    // one JSON object per line, the default
+	FormatConsole = "console" // colored, human readable lines for development
+)
+
+// Config configures InitLogging.
+type Config struct {
+	// FilePath also writes the logs to this file, empty for stdout only
+	FilePath string
+	// Rotation rotates and cleans up the file
+	Rotation Rotation
+	// Level is the minimum level logged: trace, debug, info (the default),
+	// warn or error
+	Level string
+	// Format is FormatJSON or FormatConsole, empty means FormatJSON. The
+	// file is always written as JSON.
+	Format string
+}
+
 var globalLogger zerolog.Logger
 
+// logFile is the file InitLogging writes to, nil for stdout only.
+var logFile *rotatingFile
+
 // InitLogging configures the global zerolog logger.
 var once sync.Once
 
-func InitLogging(logFilePath string) {
+func InitLogging(cfg Config) error {
+	if err := SetLevel(cfg.Level); err != nil {
+		return err
+	}
+	var stdout io.Writer
+	switch cfg.Format {
+	case "", FormatJSON:
+		stdout = os.Stdout
+	case FormatConsole:
+		stdout = zerolog.ConsoleWriter{Out: os.Stdout}
+	default:
+		return fmt.Errorf("invalid log format %q, expected %q or %q", cfg.Format, FormatJSON, FormatConsole)
+	}
+
 	once.Do(func() {
 		var writers []io.Writer
-		writers = append(writers, os.Stdout)
+		writers = append(writers, stdout)
 
-		if logFilePath != "" {
-			file, err := os.OpenFile(logFilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0664)
+		if cfg.FilePath != "" {
+			file, err := newRotatingFile(cfg.FilePath, cfg.Rotation)
 			if err != nil {
 				// Fallback to stdout only if file cannot be opened
 				// We can't use the logger yet, so just print to stderr
 				os.Stderr.WriteString("Failed to open log file: " + err.Error() + "\n")
 			} else {
 				writers = append(writers, file)
+				logFile = file
 			}
 		}
 
 		multi := zerolog.MultiLevelWriter(writers...)
 		logger := zerolog.New(multi).With().Timestamp().Logger()
-		logger = logger.Level(zerolog.InfoLevel)
 		globalLogger = logger
 		// Set the global logger used by the zerolog/log package for convenience.
 		log.Logger = logger
 	})
+	return nil
+}
+
+// Close closes the log file once the rotated files being compressed are
+// done. Later logs only go to stdout.
+func Close() error {
+	if logFile == nil {
+		return nil
+	}
+	return logFile.Close()
+}
+
+// SetLevel changes the minimum level logged, see Config.Level. It applies
+// to the loggers of requests already running too.
+func SetLevel(level string) error {
+	l := zerolog.InfoLevel
+	if level != "" {
+		var err error
+		if l, err = zerolog.ParseLevel(strings.ToLower(level)); err != nil {
+			return fmt.Errorf("invalid log level %q: %w", level, err)
+		}
+	}
+	zerolog.SetGlobalLevel(l)
+	return nil
 }
 
-// WithLogger returns a new context containing the logger with additional fields.
+type contextKey int
+
+const (
+	requestIDKey contextKey = iota
+	traceIDKey
+)
+
+// WithLogger returns a new context containing the logger with additional
+// fields, added to the fields already in ctx.
 func WithLogger(ctx context.Context, fields map[string]interface{}) context.Context {
-	// Attach fields to the logger and store in context.
-	l := globalLogger.With().Fields(fields).Logger()
+	l := getLogger(ctx).With().Fields(fields).Logger()
 	return l.WithContext(ctx)
 }
 
+// WithRequestID returns ctx logging the request_id field, see RequestID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	ctx = context.WithValue(ctx, requestIDKey, id)
+	return WithLogger(ctx, map[string]interface{}{"request_id": id})
+}
+
+// RequestID returns the ID of the request ctx serves, empty if it has none.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// WithTraceID returns ctx logging the trace_id field, see TraceID.
+func WithTraceID(ctx context.Context, id string) context.Context {
+	ctx = context.WithValue(ctx, traceIDKey, id)
+	return WithLogger(ctx, map[string]interface{}{"trace_id": id})
+}
+
+// TraceID returns the ID of the distributed trace ctx belongs to, empty if
+// it has none.
+func TraceID(ctx context.Context) string {
+	id, _ := ctx.Value(traceIDKey).(string)
+	return id
+}
+
 // getLogger extracts the zerolog logger from the context, falling back to the global logger.
 func getLogger(ctx context.Context) *zerolog.Logger {
 	l := zerolog.Ctx(ctx)
@@ -60,7 +153,6 @@ func getLogger(ctx context.Context) *zerolog.Logger {
 
 // DebugLog logs a debug level message.
 func DebugLog(ctx context.Context, msg string, args ...interface{}) {
-	fmt.Printf(msg+"\n", args)
 	getLogger(ctx).Debug().Msgf(msg, args...)
 }
 
@@ -80,7 +172,7 @@ func ErrorLog(ctx context.Context, msg string, args ...interface{}) {
 	if len(args) > 0 {
 		// If the first argument is an error, log it with Err for structured output
 		if err, ok := args[0].(error); ok {
-			l.Error().Err(err).Msg(msg)
+			l.Error().Err(err).Msgf(msg, args...)
 		} else {
 			l.Error().Msgf(msg, args...)
 		}
@@ -0,0 +1,237 @@
+package logger
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rotation configures the rotation of the log file. The zero value appends
+// to the file forever.
+type Rotation struct {
+	// MaxSize rotates the file before it grows past this many bytes, 0 for
+	// no limit
+	MaxSize int64
+	// Interval rotates the file when a multiple of Interval since the zero
+	// time passes, e.g. at midnight UTC for 24h, 0 for never
+	Interval time.Duration
+	// MaxAge removes rotated files older than this, 0 keeps them
+	MaxAge time.Duration
+	// MaxBackups keeps at most this many rotated files, 0 keeps them all
+	MaxBackups int
+	// Compress gzips the rotated files
+	Compress bool
+}
+
+// backupTimeFormat stamps the rotated files, sortable and without colons
+// for Windows.
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// rotatingFile is an io.Writer appending to a log file, moving it aside to
+// <name>-<time><ext> when it is full or its interval passed. Rotated files
+// are compressed and removed in the background, one cleanup at a time.
+type rotatingFile struct {
+	path string
+	rot  Rotation
+	now  func() time.Time
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	closed bool
+	// period is the start of the interval the file is written in
+	period time.Time
+
+	cleanupMu sync.Mutex
+	cleanups  sync.WaitGroup
+}
+
+func newRotatingFile(path string, rot Rotation) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, rot: rot, now: time.Now}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	r.cleanup()
+	return r, nil
+}
+
+// open opens the log file for appending. An existing file continues the
+// interval of its last write.
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0664)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	r.file, r.size = file, info.Size()
+	r.period = r.periodOf(r.now())
+	if info.Size() > 0 {
+		r.period = r.periodOf(info.ModTime())
+	}
+	return nil
+}
+
+func (r *rotatingFile) periodOf(t time.Time) time.Time {
+	if r.rot.Interval <= 0 {
+		return time.Time{}
+	}
+	return t.UTC().Truncate(r.rot.Interval)
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		// Dropped, the other writers of the logger still get p
+		return len(p), nil
+	}
+	full := r.rot.MaxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.rot.MaxSize
+	if full || !r.periodOf(r.now()).Equal(r.period) {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// rotate moves the file aside and opens a new one, the caller holds mu.
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	ext := filepath.Ext(r.path)
+	backup := strings.TrimSuffix(r.path, ext) + "-" + r.now().UTC().Format(backupTimeFormat) + ext
+	if err := os.Rename(r.path, backup); err != nil {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	if err := r.open(); err != nil {
+		return err
+	}
+	r.cleanup()
+	return nil
+}
+
+// cleanup compresses and removes the rotated files in the background.
+func (r *rotatingFile) cleanup() {
+	if !r.rot.Compress && r.rot.MaxAge <= 0 && r.rot.MaxBackups <= 0 {
+		return
+	}
+	r.cleanups.Add(1)
+	go func() {
+		defer r.cleanups.Done()
+		r.cleanupMu.Lock()
+		defer r.cleanupMu.Unlock()
+		if err := r.removeAndCompress(); err != nil {
+			// The logger writes here, report to stderr instead
+			os.Stderr.WriteString("Failed to clean up rotated log files: " + err.Error() + "\n")
+		}
+	}()
+}
+
+type backupFile struct {
+	path string
+	time time.Time
+}
+
+// backups lists the rotated files of the log, the newest first.
+func (r *rotatingFile) backups() ([]backupFile, error) {
+	dir := filepath.Dir(r.path)
+	ext := filepath.Ext(r.path)
+	prefix := strings.TrimSuffix(filepath.Base(r.path), ext) + "-"
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var backups []backupFile
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimSuffix(name, ".gz"), ext)
+		t, err := time.Parse(backupTimeFormat, strings.TrimPrefix(stamp, prefix))
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backupFile{path: filepath.Join(dir, name), time: t})
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].time.After(backups[j].time) })
+	return backups, nil
+}
+
+func (r *rotatingFile) removeAndCompress() error {
+	backups, err := r.backups()
+	if err != nil {
+		return err
+	}
+	cutoff := r.now().Add(-r.rot.MaxAge)
+	for i, b := range backups {
+		if (r.rot.MaxBackups > 0 && i >= r.rot.MaxBackups) || (r.rot.MaxAge > 0 && b.time.Before(cutoff)) {
+			if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			continue
+		}
+		if r.rot.Compress && !strings.HasSuffix(b.path, ".gz") {
+			if err := compressFile(b.path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// compressFile replaces path with path.gz.
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0664)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		dst.Close()
+		os.Remove(path + ".gz")
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		os.Remove(path + ".gz")
+		return err
+	}
+	src.Close()
+	return os.Remove(path)
+}
+
+// Close closes the file once the running cleanups finished, later writes
+// are dropped.
+func (r *rotatingFile) Close() error {
+	r.cleanups.Wait()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return nil
+	}
+	r.closed = true
+	return r.file.Close()
+}
@@ -0,0 +1,132 @@
+package logger
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// clock is a settable time source.
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time { return c.t }
+
+func newTestFile(t *testing.T, rot Rotation, c *clock) (*rotatingFile, string) {
+	t.Helper()
+	dir := t.TempDir()
+	r := &rotatingFile{path: filepath.Join(dir, "app.log"), rot: rot, now: c.now}
+	require.NoError(t, r.open())
+	t.Cleanup(func() { r.Close() })
+	return r, dir
+}
+
+func files(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestRotatingFile_RotatesPastMaxSize(t *testing.T) {
+	c := &clock{t: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)}
+	r, dir := newTestFile(t, Rotation{MaxSize: 10}, c)
+
+	for _, line := range []string{"first\n", "second\n", "3\n", "fourth line\n"} {
+		_, err := r.Write([]byte(line))
+		require.NoError(t, err)
+		c.t = c.t.Add(time.Second)
+	}
+
+	assert.Equal(t, []string{"app-2024-05-01T10-00-01.000.log", "app-2024-05-01T10-00-03.000.log", "app.log"}, files(t, dir))
+	content, err := os.ReadFile(filepath.Join(dir, "app-2024-05-01T10-00-01.000.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "first\n", string(content))
+	content, err = os.ReadFile(filepath.Join(dir, "app.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "fourth line\n", string(content), "a line larger than MaxSize still goes to a file of its own")
+}
+
+func TestRotatingFile_RotatesEveryInterval(t *testing.T) {
+	c := &clock{t: time.Date(2024, 5, 1, 23, 59, 0, 0, time.UTC)}
+	r, dir := newTestFile(t, Rotation{Interval: 24 * time.Hour}, c)
+
+	_, err := r.Write([]byte("may 1\n"))
+	require.NoError(t, err)
+	c.t = c.t.Add(30 * time.Second)
+	_, err = r.Write([]byte("still may 1\n"))
+	require.NoError(t, err)
+	c.t = c.t.Add(time.Minute)
+	_, err = r.Write([]byte("may 2\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"app-2024-05-02T00-00-30.000.log", "app.log"}, files(t, dir))
+}
+
+func TestRotatingFile_CompressesAndRemovesBackups(t *testing.T) {
+	c := &clock{t: time.Date(2024, 5, 10, 0, 0, 0, 0, time.UTC)}
+	dir := t.TempDir()
+	for _, name := range []string{"app-2024-05-01T00-00-00.000.log.gz", "app-2024-05-08T00-00-00.000.log", "app-2024-05-09T00-00-00.000.log", "other.log"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(name), 0664))
+	}
+	r := &rotatingFile{path: filepath.Join(dir, "app.log"), rot: Rotation{MaxAge: 5 * 24 * time.Hour, MaxBackups: 2, Compress: true}, now: c.now}
+	require.NoError(t, r.open())
+	_, err := r.Write([]byte("line\n"))
+	require.NoError(t, err)
+	require.NoError(t, r.rotate())
+	require.NoError(t, r.Close())
+
+	// the oldest is past MaxAge, the 2024-05-08 one beyond MaxBackups
+	assert.Equal(t, []string{"app-2024-05-09T00-00-00.000.log.gz", "app-2024-05-10T00-00-00.000.log.gz", "app.log", "other.log"}, files(t, dir))
+	f, err := os.Open(filepath.Join(dir, "app-2024-05-10T00-00-00.000.log.gz"))
+	require.NoError(t, err)
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	require.NoError(t, err)
+	content, err := io.ReadAll(gz)
+	require.NoError(t, err)
+	assert.Equal(t, "line\n", string(content))
+}
+
+func TestRotatingFile_ContinuesExistingFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	require.NoError(t, os.WriteFile(path, []byte("yesterday\n"), 0664))
+	yesterday := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, os.Chtimes(path, yesterday, yesterday))
+
+	c := &clock{t: yesterday.Add(24 * time.Hour)}
+	r := &rotatingFile{path: path, rot: Rotation{Interval: 24 * time.Hour}, now: c.now}
+	require.NoError(t, r.open())
+	t.Cleanup(func() { r.Close() })
+	_, err := r.Write([]byte("today\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"app-2024-05-02T12-00-00.000.log", "app.log"}, files(t, dir))
+}
+
+func TestRotatingFile_DropsWritesAfterClose(t *testing.T) {
+	c := &clock{t: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)}
+	r, dir := newTestFile(t, Rotation{}, c)
+	_, err := r.Write([]byte("before\n"))
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+
+	n, err := r.Write([]byte("after\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 6, n)
+	content, err := os.ReadFile(filepath.Join(dir, "app.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "before\n", string(content))
+}
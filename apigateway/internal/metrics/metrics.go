@@ -0,0 +1,135 @@
+// Package metrics exports the Prometheus metrics of the gateway: HTTP
+// requests, database calls, report exports and dataflow stages.
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/dataflow/promobserver"
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/simpleexcelv2"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Namespace prefixes the names of the metrics.
+const Namespace = "apigateway"
+
+// unmatchedRoute labels the requests matching no route, keeping their paths
+// out of the labels.
+const unmatchedRoute = "unmatched"
+
+// Metrics holds the collectors of the gateway:
+//
+//	apigateway_http_request_duration_seconds{method,route,status}  time per request
+//	apigateway_db_query_duration_seconds{operation,result}        time per database call, see database.QueryObserver
+//	apigateway_export_duration_seconds{report,format}             time per report export
+//	apigateway_export_rows_total{report,format}                   rows exported
+//	apigateway_export_rows_per_second{report,format}              rows per second of each export
+//	apigateway_export_bytes{report,format}                        size of each export
+//	apigateway_dataflow_*{stage}                                  see promobserver.Observer
+type Metrics struct {
+	// Dataflow observes the stages of the dataflow pipelines
+	Dataflow *promobserver.Observer
+
+	requests            *prometheus.HistogramVec
+	queries             *prometheus.HistogramVec
+	exportDuration      *prometheus.HistogramVec
+	exportRows          *prometheus.CounterVec
+	exportRowsPerSecond *prometheus.HistogramVec
+	exportBytes         *prometheus.HistogramVec
+}
+
+// New creates the metrics and registers them with reg.
+func New(reg prometheus.Registerer) (*Metrics, error) {
+	dataflow, err := promobserver.New(reg, Namespace)
+	if err != nil {
+		return nil, err
+	}
+	exportLabels := []string{"report", "format"}
+	m := &Metrics{
+		Dataflow: dataflow,
+		requests: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace, Subsystem: "http", Name: "request_duration_seconds",
+			Help:    "Time to serve HTTP requests, by route and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		queries: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace, Subsystem: "db", Name: "query_duration_seconds",
+			Help:    "Time of database calls, by operation and result.",
+			Buckets: prometheus.ExponentialBuckets(0.0005, 4, 10),
+		}, []string{"operation", "result"}),
+		exportDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace, Subsystem: "export", Name: "duration_seconds",
+			Help:    "Time to export a report.",
+			Buckets: prometheus.ExponentialBuckets(0.05, 2, 12),
+		}, exportLabels),
+		exportRows: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: Namespace, Subsystem: "export", Name: "rows_total",
+			Help: "Rows exported.",
+		}, exportLabels),
+		exportRowsPerSecond: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace, Subsystem: "export", Name: "rows_per_second",
+			Help:    "Rows per second of each report export.",
+			Buckets: prometheus.ExponentialBuckets(100, 4, 8),
+		}, exportLabels),
+		exportBytes: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: Namespace, Subsystem: "export", Name: "bytes",
+			Help:    "Size of each report export.",
+			Buckets: prometheus.ExponentialBuckets(4096, 4, 10),
+		}, exportLabels),
+	}
+	for _, c := range []prometheus.Collector{m.requests, m.queries, m.exportDuration, m.exportRows, m.exportRowsPerSecond, m.exportBytes} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Middleware times the requests, labelled by their route pattern rather than
+// their path, requests matching no route as "unmatched". Handler errors are
+// counted with the status the error handler will answer with.
+func (m *Metrics) Middleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		start := time.Now()
+		err := next(c)
+
+		status := c.Response().Status
+		if err != nil && !c.Response().Committed {
+			status = http.StatusInternalServerError
+			var he *echo.HTTPError
+			if errors.As(err, &he) {
+				status = he.Code
+			}
+		}
+		route := c.Path()
+		if route == "" || errors.Is(err, echo.ErrNotFound) {
+			route = unmatchedRoute
+		}
+		m.requests.WithLabelValues(c.Request().Method, route, strconv.Itoa(status)).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// ObserveQuery records a database call, it is a database.QueryObserver.
+func (m *Metrics) ObserveQuery(operation string, d time.Duration, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	m.queries.WithLabelValues(operation, result).Observe(d.Seconds())
+}
+
+// ObserveExport records a finished report export, it is a
+// service.ExportObserver.
+func (m *Metrics) ObserveExport(reportID, format string, r *simpleexcelv2.ExportResult) {
+	m.exportDuration.WithLabelValues(reportID, format).Observe(r.Duration.Seconds())
+	m.exportRows.WithLabelValues(reportID, format).Add(float64(r.Rows))
+	m.exportBytes.WithLabelValues(reportID, format).Observe(float64(r.Bytes))
+	if r.Duration > 0 {
+		m.exportRowsPerSecond.WithLabelValues(reportID, format).Observe(float64(r.Rows) / r.Duration.Seconds())
+	}
+}
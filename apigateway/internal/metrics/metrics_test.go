@@ -0,0 +1,98 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/simpleexcelv2"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMetrics(t *testing.T) (*Metrics, *prometheus.Registry) {
+	reg := prometheus.NewRegistry()
+	m, err := New(reg)
+	require.NoError(t, err)
+	return m, reg
+}
+
+// samples returns the metrics of the family name by their label values,
+// sorted by label name and joined with commas.
+func samples(t *testing.T, reg *prometheus.Registry, name string) map[string]*dto.Metric {
+	t.Helper()
+	families, err := reg.Gather()
+	require.NoError(t, err)
+	out := make(map[string]*dto.Metric)
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			key := ""
+			for i, l := range m.GetLabel() {
+				if i > 0 {
+					key += ","
+				}
+				key += l.GetValue()
+			}
+			out[key] = m
+		}
+	}
+	return out
+}
+
+func TestMiddleware_LabelsRequestsByRoute(t *testing.T) {
+	m, reg := newMetrics(t)
+	e := echo.New()
+	e.Use(m.Middleware)
+	e.GET("/employees/:id", func(c echo.Context) error {
+		if c.Param("id") == "0" {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid id")
+		}
+		if c.Param("id") == "9" {
+			return errors.New("boom")
+		}
+		return c.NoContent(http.StatusOK)
+	})
+
+	for _, path := range []string{"/employees/1", "/employees/2", "/employees/0", "/employees/9", "/nope/1"} {
+		e.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+
+	got := samples(t, reg, "apigateway_http_request_duration_seconds")
+	require.Len(t, got, 4)
+	assert.EqualValues(t, 2, got["GET,/employees/:id,200"].GetHistogram().GetSampleCount())
+	assert.EqualValues(t, 1, got["GET,/employees/:id,400"].GetHistogram().GetSampleCount())
+	assert.EqualValues(t, 1, got["GET,/employees/:id,500"].GetHistogram().GetSampleCount())
+	assert.EqualValues(t, 1, got["GET,unmatched,404"].GetHistogram().GetSampleCount())
+}
+
+func TestObserveQuery(t *testing.T) {
+	m, reg := newMetrics(t)
+	m.ObserveQuery("query", 2*time.Millisecond, nil)
+	m.ObserveQuery("query", time.Millisecond, nil)
+	m.ObserveQuery("exec", time.Millisecond, errors.New("deadlock"))
+
+	got := samples(t, reg, "apigateway_db_query_duration_seconds")
+	assert.EqualValues(t, 2, got["query,ok"].GetHistogram().GetSampleCount())
+	assert.InDelta(t, 0.003, got["query,ok"].GetHistogram().GetSampleSum(), 1e-9)
+	assert.EqualValues(t, 1, got["exec,error"].GetHistogram().GetSampleCount())
+}
+
+func TestObserveExport(t *testing.T) {
+	m, reg := newMetrics(t)
+	m.ObserveExport("employee_list", "xlsx", &simpleexcelv2.ExportResult{Rows: 500, Bytes: 8192, Duration: 250 * time.Millisecond})
+	m.ObserveExport("employee_list", "xlsx", &simpleexcelv2.ExportResult{Rows: 100, Bytes: 2048})
+
+	assert.EqualValues(t, 600, samples(t, reg, "apigateway_export_rows_total")["xlsx,employee_list"].GetCounter().GetValue())
+	assert.EqualValues(t, 10240, samples(t, reg, "apigateway_export_bytes")["xlsx,employee_list"].GetHistogram().GetSampleSum())
+	rate := samples(t, reg, "apigateway_export_rows_per_second")["xlsx,employee_list"].GetHistogram()
+	assert.EqualValues(t, 1, rate.GetSampleCount(), "exports without a duration have no rate")
+	assert.InDelta(t, 2000, rate.GetSampleSum(), 1e-9)
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/database/dbtx"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+)
+
+type adhocQueryRepository struct {
+	db   *sql.DB
+	role string
+}
+
+// NewAdhocQueryRepository creates a new instance of AdhocQueryRepository.
+// Queries run as role, which should only be granted SELECT on the tables
+// analysts may read; an empty role keeps the privileges of the connection.
+func NewAdhocQueryRepository(db *sql.DB, role string) domain.AdhocQueryRepository {
+	return &adhocQueryRepository{db: db, role: role}
+}
+
+// readOnly runs fn in a read-only transaction that is always rolled back, so
+// a statement slipping past validation still cannot write. The transaction
+// switches to the query role and applies the statement timeout first. It
+// runs on the read replica when there is one.
+func (r *adhocQueryRepository) readOnly(ctx context.Context, timeout time.Duration, fn func(tx *sql.Tx) error) error {
+	tx, err := dbtx.BeginRead(ctx, r.db, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if r.role != "" {
+		if _, err := tx.ExecContext(ctx, "SET LOCAL ROLE "+pq.QuoteIdentifier(r.role)); err != nil {
+			return fmt.Errorf("failed to switch to ad-hoc query role: %w", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", timeout.Milliseconds())); err != nil {
+		return err
+	}
+	return rejectedQuery(fn(tx))
+}
+
+// rejectedQuery wraps the errors the query is to blame for in
+// domain.ErrQueryRejected: data exceptions, syntax and access errors, writes
+// in the read-only transaction and statement timeouts.
+func rejectedQuery(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	switch {
+	case pqErr.Code.Class() == "22", pqErr.Code.Class() == "42",
+		pqErr.Code == "25006", // read_only_sql_transaction
+		pqErr.Code == "57014": // query_canceled, by statement_timeout
+		return fmt.Errorf("%w: %s", domain.ErrQueryRejected, pqErr.Message)
+	}
+	return err
+}
+
+func (r *adhocQueryRepository) EstimateCost(ctx context.Context, query string, timeout time.Duration) (float64, error) {
+	var cost float64
+	err := r.readOnly(ctx, timeout, func(tx *sql.Tx) error {
+		var raw []byte
+		if err := tx.QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) "+query).Scan(&raw); err != nil {
+			return err
+		}
+		var plans []struct {
+			Plan struct {
+				TotalCost float64 `json:"Total Cost"`
+			} `json:"Plan"`
+		}
+		if err := json.Unmarshal(raw, &plans); err != nil {
+			return fmt.Errorf("failed to parse query plan: %w", err)
+		}
+		if len(plans) == 0 {
+			return fmt.Errorf("empty query plan")
+		}
+		cost = plans[0].Plan.TotalCost
+		return nil
+	})
+	return cost, err
+}
+
+func (r *adhocQueryRepository) Query(ctx context.Context, query string, limit int, timeout time.Duration, onColumns func(columns, types []string) error, onRow func(row []interface{}) error) error {
+	return r.readOnly(ctx, timeout, func(tx *sql.Tx) error {
+		// The subquery takes a single statement and caps the rows, the line
+		// break ends a trailing comment of the query
+		rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT * FROM (%s\n) AS adhoc LIMIT %d", query, limit))
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		colTypes, err := rows.ColumnTypes()
+		if err != nil {
+			return err
+		}
+		columns := make([]string, len(colTypes))
+		types := make([]string, len(colTypes))
+		for i, ct := range colTypes {
+			columns[i], types[i] = ct.Name(), ct.DatabaseTypeName()
+		}
+		if err := onColumns(columns, types); err != nil {
+			return err
+		}
+		for rows.Next() {
+			row := make([]interface{}, len(columns))
+			ptrs := make([]interface{}, len(columns))
+			for i := range row {
+				ptrs[i] = &row[i]
+			}
+			if err := rows.Scan(ptrs...); err != nil {
+				return err
+			}
+			for i, v := range row {
+				// numeric and text-like types come back as bytes
+				if b, ok := v.([]byte); ok {
+					row[i] = string(b)
+				}
+			}
+			if err := onRow(row); err != nil {
+				return err
+			}
+		}
+		return rows.Err()
+	})
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// adhocDriver records the statements run on it and answers EXPLAIN with a
+// fixed plan and other queries with a single "n" column.
+type adhocDriver struct {
+	mu    sync.Mutex
+	stmts []string
+}
+
+func (d *adhocDriver) Open(name string) (driver.Conn, error) { return &adhocConn{d: d}, nil }
+
+func (d *adhocDriver) record(query string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stmts = append(d.stmts, query)
+}
+
+type adhocConn struct{ d *adhocDriver }
+
+func (c *adhocConn) Prepare(query string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c *adhocConn) Close() error                              { return nil }
+func (c *adhocConn) Begin() (driver.Tx, error)                 { return c, nil }
+func (c *adhocConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if opts.ReadOnly {
+		c.d.record("BEGIN READ ONLY")
+	} else {
+		c.d.record("BEGIN")
+	}
+	return c, nil
+}
+func (c *adhocConn) Commit() error   { c.d.record("COMMIT"); return nil }
+func (c *adhocConn) Rollback() error { c.d.record("ROLLBACK"); return nil }
+
+func (c *adhocConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.d.record(query)
+	return driver.RowsAffected(0), nil
+}
+
+func (c *adhocConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.d.record(query)
+	if strings.HasPrefix(query, "EXPLAIN") {
+		return &adhocRows{column: "QUERY PLAN", values: []driver.Value{[]byte(`[{"Plan": {"Total Cost": 42.5}}]`)}}, nil
+	}
+	return &adhocRows{column: "n", values: []driver.Value{int64(1), int64(2)}}, nil
+}
+
+type adhocRows struct {
+	column string
+	values []driver.Value
+	pos    int
+}
+
+func (r *adhocRows) Columns() []string { return []string{r.column} }
+func (r *adhocRows) Close() error      { return nil }
+func (r *adhocRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.values) {
+		return io.EOF
+	}
+	dest[0] = r.values[r.pos]
+	r.pos++
+	return nil
+}
+
+type adhocConnector struct{ d *adhocDriver }
+
+func (c adhocConnector) Connect(context.Context) (driver.Conn, error) { return c.d.Open("") }
+func (c adhocConnector) Driver() driver.Driver                        { return c.d }
+
+func newAdhocRepo(t *testing.T, role string) (*adhocQueryRepository, *adhocDriver) {
+	d := &adhocDriver{}
+	db := sql.OpenDB(adhocConnector{d})
+	t.Cleanup(func() { db.Close() })
+	return NewAdhocQueryRepository(db, role).(*adhocQueryRepository), d
+}
+
+func TestAdhocEstimateCost_RoleAndTimeout(t *testing.T) {
+	repo, d := newAdhocRepo(t, "adhoc_reader")
+
+	cost, err := repo.EstimateCost(context.Background(), "SELECT 1", 2*time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, 42.5, cost)
+	assert.Equal(t, []string{
+		"BEGIN READ ONLY",
+		`SET LOCAL ROLE "adhoc_reader"`,
+		"SET LOCAL statement_timeout = 2000",
+		"EXPLAIN (FORMAT JSON) SELECT 1",
+		"ROLLBACK",
+	}, d.stmts, "the plan is made as the query role and under the timeout")
+}
+
+func TestAdhocQuery_RoleAndTimeout(t *testing.T) {
+	repo, d := newAdhocRepo(t, "adhoc_reader")
+
+	var rows [][]interface{}
+	err := repo.Query(context.Background(), "SELECT n FROM t", 10, time.Second,
+		func(columns, types []string) error { return nil },
+		func(row []interface{}) error {
+			rows = append(rows, row)
+			return nil
+		})
+	require.NoError(t, err)
+	assert.Equal(t, [][]interface{}{{int64(1)}, {int64(2)}}, rows)
+	require.Len(t, d.stmts, 5)
+	assert.Equal(t, `SET LOCAL ROLE "adhoc_reader"`, d.stmts[1])
+	assert.Equal(t, "SET LOCAL statement_timeout = 1000", d.stmts[2])
+	assert.Equal(t, "ROLLBACK", d.stmts[4])
+}
+
+func TestAdhocQuery_NoRole(t *testing.T) {
+	repo, d := newAdhocRepo(t, "")
+
+	_, err := repo.EstimateCost(context.Background(), "SELECT 1", time.Second)
+	require.NoError(t, err)
+	for _, stmt := range d.stmts {
+		assert.NotContains(t, stmt, "ROLE")
+	}
+}
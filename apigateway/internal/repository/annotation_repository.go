@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/database/dbtx"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/repository/builder"
+)
+
+var annotationTable = "employees.employee_annotation"
+
+type annotationRepository struct {
+	db *sql.DB
+}
+
+// NewAnnotationRepository creates a new instance of AnnotationRepository
+func NewAnnotationRepository(db *sql.DB) domain.AnnotationRepository {
+	return &annotationRepository{db: db}
+}
+
+func (r *annotationRepository) CreateAnnotations(ctx context.Context, annotations []domain.Annotation) error {
+	return dbtx.WithinTx(ctx, r.db, func(ctx context.Context) error {
+		tx := dbtx.Conn(ctx, r.db)
+		for i := range annotations {
+			a := &annotations[i]
+			b := builder.NewSQLBuilder()
+			query, args := b.Insert(annotationTable, "emp_no", "kind", "text", "status").
+				Values(a.EmpNo, a.Kind, a.Text, a.Status).
+				Returning("id", "created_at").
+				Build()
+
+			if err := tx.QueryRowContext(ctx, query, args...).Scan(&a.ID, &a.CreatedAt); err != nil {
+				return &domain.BatchItemError{Index: i, Err: err}
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *annotationRepository) GetAnnotation(ctx context.Context, id int) (*domain.Annotation, error) {
+	b := builder.NewSQLBuilder()
+	query, args := b.Select("id", "emp_no", "kind", "text", "status", "created_at").
+		From(annotationTable).
+		Where("id = ?", id).
+		Build()
+
+	row := dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...)
+	var a domain.Annotation
+	if err := row.Scan(&a.ID, &a.EmpNo, &a.Kind, &a.Text, &a.Status, &a.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+func (r *annotationRepository) ListAnnotations(ctx context.Context, empNo int) ([]domain.Annotation, error) {
+	b := builder.NewSQLBuilder()
+	query, args := b.Select("id", "emp_no", "kind", "text", "status", "created_at").
+		From(annotationTable).
+		Where("emp_no = ?", empNo).
+		OrderBy("id").
+		Build()
+
+	rows, err := dbtx.Conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var annotations []domain.Annotation
+	for rows.Next() {
+		var a domain.Annotation
+		if err := rows.Scan(&a.ID, &a.EmpNo, &a.Kind, &a.Text, &a.Status, &a.CreatedAt); err != nil {
+			return nil, err
+		}
+		annotations = append(annotations, a)
+	}
+	return annotations, rows.Err()
+}
+
+// SetAnnotationStatus returns sql.ErrNoRows for unknown annotations.
+func (r *annotationRepository) SetAnnotationStatus(ctx context.Context, id int, status string) error {
+	b := builder.NewSQLBuilder()
+	query, args := b.Update(annotationTable).
+		Set("status", status).
+		Where("id = ?", id).
+		Returning("id").
+		Build()
+
+	return dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&id)
+}
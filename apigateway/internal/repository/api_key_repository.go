@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/database/dbtx"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/repository/builder"
+)
+
+var apiKeyTable = "employees.api_key"
+
+var apiKeyColumns = []string{"id", "name", "prefix", "scopes", "created_by", "created_at", "expires_at", "last_used_at", "revoked_at"}
+
+type apiKeyRepository struct {
+	db *sql.DB
+}
+
+// NewAPIKeyRepository creates a new instance of APIKeyRepository
+func NewAPIKeyRepository(db *sql.DB) domain.APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+func (r *apiKeyRepository) CreateAPIKey(ctx context.Context, k *domain.APIKey, hash string) error {
+	b := builder.NewSQLBuilder()
+	query, args := b.Insert(apiKeyTable, "name", "prefix", "key_hash", "scopes", "created_by", "expires_at").
+		Values(k.Name, k.Prefix, hash, strings.Join(k.Scopes, " "), k.CreatedBy, k.ExpiresAt).
+		Returning("id", "created_at").
+		Build()
+
+	return dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&k.ID, &k.CreatedAt)
+}
+
+func (r *apiKeyRepository) FindAPIKey(ctx context.Context, hash string) (*domain.APIKey, error) {
+	b := builder.NewSQLBuilder()
+	query, args := b.Select(apiKeyColumns...).
+		From(apiKeyTable).
+		Where("key_hash = ?", hash).
+		Build()
+	return scanAPIKey(dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...))
+}
+
+func (r *apiKeyRepository) ListAPIKeys(ctx context.Context) ([]domain.APIKey, error) {
+	b := builder.NewSQLBuilder()
+	query, args := b.Select(apiKeyColumns...).
+		From(apiKeyTable).
+		OrderBy("id DESC").
+		Build()
+
+	rows, err := dbtx.Conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []domain.APIKey
+	for rows.Next() {
+		k, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *k)
+	}
+	return keys, rows.Err()
+}
+
+func (r *apiKeyRepository) RevokeAPIKey(ctx context.Context, id int, at time.Time) error {
+	b := builder.NewSQLBuilder()
+	query, args := b.Update(apiKeyTable).
+		Set("revoked_at", at).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Build()
+
+	return execOne(ctx, r.db, query, args...)
+}
+
+func (r *apiKeyRepository) TouchAPIKey(ctx context.Context, id int, at time.Time) error {
+	b := builder.NewSQLBuilder()
+	query, args := b.Update(apiKeyTable).
+		Set("last_used_at", at).
+		Where("id = ?", id).
+		Build()
+
+	return execOne(ctx, r.db, query, args...)
+}
+
+// scanAPIKey scans the apiKeyColumns of a row.
+func scanAPIKey(row interface {
+	Scan(dest ...interface{}) error
+}) (*domain.APIKey, error) {
+	var k domain.APIKey
+	var scopes string
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+	if err := row.Scan(&k.ID, &k.Name, &k.Prefix, &scopes, &k.CreatedBy, &k.CreatedAt, &expiresAt, &lastUsedAt, &revokedAt); err != nil {
+		return nil, err
+	}
+	k.Scopes = strings.Fields(scopes)
+	k.ExpiresAt = nullTimePtr(expiresAt)
+	k.LastUsedAt = nullTimePtr(lastUsedAt)
+	k.RevokedAt = nullTimePtr(revokedAt)
+	return &k, nil
+}
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -0,0 +1,238 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/database/dbtx"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/repository/builder"
+)
+
+var (
+	attendanceTable   = "employees.attendance"
+	leaveRequestTable = "employees.leave_request"
+)
+
+type attendanceRepository struct {
+	db *sql.DB
+}
+
+// NewAttendanceRepository creates a new instance of AttendanceRepository
+func NewAttendanceRepository(db *sql.DB) domain.AttendanceRepository {
+	return &attendanceRepository{db: db}
+}
+
+// workDate is the attendance day of a timestamp.
+func workDate(at time.Time) time.Time {
+	return time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func (r *attendanceRepository) CheckIn(ctx context.Context, empNo int, at time.Time) error {
+	b := builder.NewSQLBuilder()
+	query, args := b.Insert(attendanceTable, "emp_no", "work_date", "check_in").
+		Values(empNo, workDate(at), at).
+		OnConflict("(emp_no, work_date)", "DO UPDATE SET check_in = COALESCE("+attendanceTable+".check_in, EXCLUDED.check_in)").
+		Build()
+
+	_, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, args...)
+	return err
+}
+
+func (r *attendanceRepository) CheckOut(ctx context.Context, empNo int, at time.Time) error {
+	b := builder.NewSQLBuilder()
+	query, args := b.Update(attendanceTable).
+		Set("check_out", at).
+		Where("emp_no = ? AND work_date = ? AND check_in IS NOT NULL", empNo, workDate(at)).
+		Build()
+
+	res, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *attendanceRepository) ListAttendance(ctx context.Context, filter domain.AttendanceFilter) ([]domain.Attendance, error) {
+	b := builder.NewSQLBuilder()
+	b.Select("a.emp_no", "a.work_date", "a.check_in", "a.check_out", "COALESCE(a.leave_type, '')").
+		From(attendanceTable + " a")
+	if filter.DeptNo != "" {
+		b.Join("INNER", deptEmpTable+" de", "de.emp_no = a.emp_no").
+			Where("de.dept_no = ? AND de.to_date = ?", filter.DeptNo, "9999-01-01")
+	}
+	if filter.EmpNo > 0 {
+		b.Where("a.emp_no = ?", filter.EmpNo)
+	}
+	if !filter.From.IsZero() {
+		b.Where("a.work_date >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		b.Where("a.work_date <= ?", filter.To)
+	}
+	query, args := b.OrderBy("a.emp_no ASC, a.work_date ASC").Build()
+
+	rows, err := dbtx.Read(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []domain.Attendance
+	for rows.Next() {
+		var a domain.Attendance
+		var checkIn, checkOut sql.NullTime
+		if err := rows.Scan(&a.EmpNo, &a.WorkDate, &checkIn, &checkOut, &a.LeaveType); err != nil {
+			return nil, err
+		}
+		if checkIn.Valid {
+			a.CheckIn = &checkIn.Time
+		}
+		if checkOut.Valid {
+			a.CheckOut = &checkOut.Time
+		}
+		days = append(days, a)
+	}
+	return days, rows.Err()
+}
+
+// SaveAttendance upserts every day inside one transaction. Days without
+// check-in, check-out or leave are deleted so blank timesheet rows don't
+// fill the table.
+func (r *attendanceRepository) SaveAttendance(ctx context.Context, days []domain.Attendance) error {
+	return dbtx.WithinTx(ctx, r.db, func(ctx context.Context) error {
+		tx := dbtx.Conn(ctx, r.db)
+		for i, a := range days {
+			if a.CheckIn == nil && a.CheckOut == nil && a.LeaveType == "" {
+				b := builder.NewSQLBuilder()
+				query, args := b.Delete(attendanceTable).
+					Where("emp_no = ? AND work_date = ?", a.EmpNo, a.WorkDate).
+					Build()
+				if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+					return &domain.BatchItemError{Index: i, Err: err}
+				}
+				continue
+			}
+
+			var leaveType interface{}
+			if a.LeaveType != "" {
+				leaveType = a.LeaveType
+			}
+			b := builder.NewSQLBuilder()
+			query, args := b.Insert(attendanceTable, "emp_no", "work_date", "check_in", "check_out", "leave_type").
+				Values(a.EmpNo, a.WorkDate, a.CheckIn, a.CheckOut, leaveType).
+				OnConflict("(emp_no, work_date)", "DO UPDATE SET check_in = EXCLUDED.check_in, check_out = EXCLUDED.check_out, leave_type = EXCLUDED.leave_type").
+				Build()
+
+			if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+				return &domain.BatchItemError{Index: i, Err: err}
+			}
+		}
+
+		return nil
+	})
+}
+
+func (r *attendanceRepository) CreateLeave(ctx context.Context, l *domain.LeaveRequest) error {
+	b := builder.NewSQLBuilder()
+	query, args := b.Insert(leaveRequestTable, "emp_no", "from_date", "to_date", "leave_type", "status", "reason").
+		Values(l.EmpNo, l.FromDate, l.ToDate, l.LeaveType, l.Status, l.Reason).
+		Returning("id").
+		Build()
+
+	return dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&l.ID)
+}
+
+func (r *attendanceRepository) GetLeave(ctx context.Context, id int) (*domain.LeaveRequest, error) {
+	b := builder.NewSQLBuilder()
+	query, args := b.Select("id", "emp_no", "from_date", "to_date", "leave_type", "status", "reason").
+		From(leaveRequestTable).
+		Where("id = ?", id).
+		Build()
+
+	row := dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...)
+	var l domain.LeaveRequest
+	if err := row.Scan(&l.ID, &l.EmpNo, &l.FromDate, &l.ToDate, &l.LeaveType, &l.Status, &l.Reason); err != nil {
+		return nil, err
+	}
+	return &l, nil
+}
+
+func (r *attendanceRepository) ListLeaves(ctx context.Context, filter domain.AttendanceFilter) ([]domain.LeaveRequest, error) {
+	b := builder.NewSQLBuilder()
+	b.Select("l.id", "l.emp_no", "l.from_date", "l.to_date", "l.leave_type", "l.status", "l.reason").
+		From(leaveRequestTable + " l")
+	if filter.DeptNo != "" {
+		b.Join("INNER", deptEmpTable+" de", "de.emp_no = l.emp_no").
+			Where("de.dept_no = ? AND de.to_date = ?", filter.DeptNo, "9999-01-01")
+	}
+	if filter.EmpNo > 0 {
+		b.Where("l.emp_no = ?", filter.EmpNo)
+	}
+	if filter.Status != "" {
+		b.Where("l.status = ?", filter.Status)
+	}
+	// Overlap with [From, To]
+	if !filter.From.IsZero() {
+		b.Where("l.to_date >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		b.Where("l.from_date <= ?", filter.To)
+	}
+	query, args := b.OrderBy("l.from_date DESC, l.id DESC").Build()
+
+	rows, err := dbtx.Conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var leaves []domain.LeaveRequest
+	for rows.Next() {
+		var l domain.LeaveRequest
+		if err := rows.Scan(&l.ID, &l.EmpNo, &l.FromDate, &l.ToDate, &l.LeaveType, &l.Status, &l.Reason); err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, l)
+	}
+	return leaves, rows.Err()
+}
+
+// SetLeaveStatus updates the request and, when approving, stamps its leave
+// type on every day it covers, in one transaction.
+func (r *attendanceRepository) SetLeaveStatus(ctx context.Context, id int, status string) error {
+	return dbtx.WithinTx(ctx, r.db, func(ctx context.Context) error {
+		tx := dbtx.Conn(ctx, r.db)
+		b := builder.NewSQLBuilder()
+		query, args := b.Update(leaveRequestTable).
+			Set("status", status).
+			Where("id = ?", id).
+			Returning("emp_no", "from_date", "to_date", "leave_type").
+			Build()
+
+		var l domain.LeaveRequest
+		if err := tx.QueryRowContext(ctx, query, args...).Scan(&l.EmpNo, &l.FromDate, &l.ToDate, &l.LeaveType); err != nil {
+			return err
+		}
+
+		if status == domain.LeaveApproved {
+			for day := l.FromDate; !day.After(l.ToDate); day = day.AddDate(0, 0, 1) {
+				b := builder.NewSQLBuilder()
+				query, args := b.Insert(attendanceTable, "emp_no", "work_date", "leave_type").
+					Values(l.EmpNo, day, l.LeaveType).
+					OnConflict("(emp_no, work_date)", "DO UPDATE SET leave_type = EXCLUDED.leave_type").
+					Build()
+				if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+					return fmt.Errorf("failed to mark %s as leave: %w", day.Format("2006-01-02"), err)
+				}
+			}
+		}
+
+		return nil
+	})
+}
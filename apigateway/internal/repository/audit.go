@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/repository/builder"
+)
+
+// The employee and department tables are audited: rows record who created
+// and last changed them, and deleting a row only sets its deleted_at. Every
+// query on them goes through notDeleted so deleted rows stay hidden until
+// they are restored.
+
+// notDeleted adds the condition hiding soft deleted rows. alias qualifies
+// the column in joins and may be empty.
+func notDeleted(b *builder.SQLBuilder, alias string) *builder.SQLBuilder {
+	if alias != "" {
+		return b.Where(alias + ".deleted_at IS NULL")
+	}
+	return b.Where("deleted_at IS NULL")
+}
+
+// softDelete marks the live rows of table matching where as deleted,
+// returning sql.ErrNoRows if there are none.
+func softDelete(ctx context.Context, db *sql.DB, table, where string, args ...interface{}) error {
+	b := builder.NewSQLBuilder()
+	query, args := b.Update(table).
+		Set("deleted_at", time.Now()).
+		Set("updated_by", domain.ActorFrom(ctx)).
+		Where(where, args...).
+		Where("deleted_at IS NULL").
+		Build()
+
+	return execOne(ctx, db, query, args...)
+}
+
+// restoreDeleted brings back the deleted rows of table matching where,
+// returning sql.ErrNoRows if there are none.
+func restoreDeleted(ctx context.Context, db *sql.DB, table, where string, args ...interface{}) error {
+	b := builder.NewSQLBuilder()
+	query, args := b.Update(table).
+		Set("deleted_at", nil).
+		Set("updated_by", domain.ActorFrom(ctx)).
+		Where(where, args...).
+		Where("deleted_at IS NOT NULL").
+		Build()
+
+	return execOne(ctx, db, query, args...)
+}
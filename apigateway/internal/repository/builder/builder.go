@@ -2,15 +2,23 @@ package builder
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
 )
 
 // SQLBuilder helps construct SQL queries dynamically.
 type SQLBuilder struct {
-	table      string
-	columns    []string
-	values     []interface{}
-	where      []string
+	table   string
+	columns []string
+	values  [][]interface{}
+	// conditions form the WHERE clause, in the order they were added
+	conditions []condition
+	distinct   bool
+	groupBy    []string
+	having     []condition
+	// fromSub is the subquery of FromSubquery, aliased as table
+	fromSub *SQLBuilder
+	// args are the arguments of the SET or VALUES clauses
 	args       []interface{}
 	joins      []string
 	orderBy    []string
@@ -21,35 +29,34 @@ type SQLBuilder struct {
 	isUpdate   bool
 	isDelete   bool
 	isSelect   bool
-	// New fields for enhancements
-	orConditions  []orCondition
-	whereGroups   []whereGroup
-	rawConditions []rawCondition
 	// New field for Upsert
+	conflictTarget string
 	onConflict     string
 	onConflictArgs []interface{}
+	returning      []string
+	dialect        Dialect
 }
 
-// orCondition represents an OR condition
-type orCondition struct {
-	condition string
-	args      []interface{}
+// condition is one term of a WHERE clause: a predicate with ? placeholders,
+// or a parenthesized group of conditions. It is joined to the term before it
+// with AND, or with OR when or is set; as in SQL, AND binds tighter than OR.
+type condition struct {
+	or    bool
+	sql   string
+	args  []interface{}
+	group *SQLBuilder
+	// sub is a subquery written in parentheses after sql, such as EXISTS
+	sub *SQLBuilder
 }
 
-// whereGroup represents a grouped (parenthesized) condition
-type whereGroup struct {
-	builder *SQLBuilder
-}
-
-// rawCondition represents a raw SQL condition
-type rawCondition struct {
-	sql  string
-	args []interface{}
-}
-
-// NewSQLBuilder creates a new instance of SQLBuilder.
-func NewSQLBuilder() *SQLBuilder {
-	return &SQLBuilder{}
+// NewSQLBuilder creates a new instance of SQLBuilder, for Postgres unless
+// WithDialect says otherwise.
+func NewSQLBuilder(opts ...Option) *SQLBuilder {
+	b := &SQLBuilder{}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
 }
 
 // Select specifies the columns to retrieve.
@@ -59,6 +66,22 @@ func (b *SQLBuilder) Select(cols ...string) *SQLBuilder {
 	return b
 }
 
+// Distinct makes a SELECT return distinct rows.
+func (b *SQLBuilder) Distinct() *SQLBuilder {
+	b.distinct = true
+	return b
+}
+
+// Count returns the COUNT aggregate of expr, for Select.
+func Count(expr string) string {
+	return "COUNT(" + expr + ")"
+}
+
+// Sum returns the SUM aggregate of expr, for Select.
+func Sum(expr string) string {
+	return "SUM(" + expr + ")"
+}
+
 // Insert specifies the table and columns for insertion.
 func (b *SQLBuilder) Insert(table string, cols ...string) *SQLBuilder {
 	b.isInsert = true
@@ -87,6 +110,14 @@ func (b *SQLBuilder) From(table string) *SQLBuilder {
 	return b
 }
 
+// FromSubquery selects from the rows of sub, named alias. The placeholders
+// of sub are renumbered to follow the query's.
+func (b *SQLBuilder) FromSubquery(sub *SQLBuilder, alias string) *SQLBuilder {
+	b.fromSub = sub
+	b.table = alias
+	return b
+}
+
 // Set specifies the columns and values for update.
 func (b *SQLBuilder) Set(col string, val interface{}) *SQLBuilder {
 	b.updateCols = append(b.updateCols, col)
@@ -94,17 +125,67 @@ func (b *SQLBuilder) Set(col string, val interface{}) *SQLBuilder {
 	return b
 }
 
-// Values specifies the values for insertion.
+// Values specifies the values for insertion. Call it once per row to insert
+// several rows with a single statement.
 func (b *SQLBuilder) Values(vals ...interface{}) *SQLBuilder {
-	b.values = vals
+	b.values = append(b.values, vals)
 	b.args = append(b.args, vals...)
 	return b
 }
 
-// Where adds a condition to the query.
+// ValuesBatch adds each of rows as Values does.
+func (b *SQLBuilder) ValuesBatch(rows [][]interface{}) *SQLBuilder {
+	for _, row := range rows {
+		b.Values(row...)
+	}
+	return b
+}
+
+// Where adds a condition joined to the previous ones with AND.
 func (b *SQLBuilder) Where(condition string, args ...interface{}) *SQLBuilder {
-	b.where = append(b.where, condition)
-	b.args = append(b.args, args...)
+	return b.addCondition(false, condition, args)
+}
+
+func (b *SQLBuilder) addCondition(or bool, sql string, args []interface{}) *SQLBuilder {
+	b.conditions = append(b.conditions, condition{or: or, sql: sql, args: args})
+	return b
+}
+
+// WhereIn adds a "col IN (...)" condition with one placeholder per element
+// of values, a slice or array. An empty slice matches no row.
+func (b *SQLBuilder) WhereIn(col string, values interface{}) *SQLBuilder {
+	v := reflect.ValueOf(values)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return b.Where(col+" IN (?)", values)
+	}
+	if v.Len() == 0 {
+		// "IN ()" is a syntax error
+		return b.Where("1 = 0")
+	}
+	args := make([]interface{}, v.Len())
+	for i := range args {
+		args[i] = v.Index(i).Interface()
+	}
+	return b.Where(col+" IN (?"+strings.Repeat(", ?", len(args)-1)+")", args...)
+}
+
+// GroupBy adds columns to the GROUP BY clause.
+func (b *SQLBuilder) GroupBy(cols ...string) *SQLBuilder {
+	b.groupBy = append(b.groupBy, cols...)
+	return b
+}
+
+// Having adds a condition on the groups joined to the previous ones with
+// AND. Its placeholders are numbered after those of the WHERE clause.
+func (b *SQLBuilder) Having(cond string, args ...interface{}) *SQLBuilder {
+	b.having = append(b.having, condition{sql: cond, args: args})
+	return b
+}
+
+// OrHaving adds a condition on the groups joined to the previous ones with
+// OR.
+func (b *SQLBuilder) OrHaving(cond string, args ...interface{}) *SQLBuilder {
+	b.having = append(b.having, condition{or: true, sql: cond, args: args})
 	return b
 }
 
@@ -132,42 +213,74 @@ func (b *SQLBuilder) Offset(offset int) *SQLBuilder {
 	return b
 }
 
-// Or adds an OR condition to the query.
+// Or adds a condition joined to the previous ones with OR.
 func (b *SQLBuilder) Or(condition string, args ...interface{}) *SQLBuilder {
-	b.orConditions = append(b.orConditions, orCondition{
-		condition: condition,
-		args:      args,
-	})
-	return b
+	return b.addCondition(true, condition, args)
 }
 
-// WhereGroup adds a grouped (parenthesized) WHERE condition.
-// The provided function receives a new SQLBuilder for building the grouped conditions.
+// WhereGroup is AndWhereGroup.
 func (b *SQLBuilder) WhereGroup(fn func(*SQLBuilder) *SQLBuilder) *SQLBuilder {
-	groupBuilder := NewSQLBuilder()
-	groupBuilder = fn(groupBuilder)
-	b.whereGroups = append(b.whereGroups, whereGroup{
-		builder: groupBuilder,
-	})
+	return b.AndWhereGroup(fn)
+}
+
+// AndWhereGroup adds the conditions fn adds to a new SQLBuilder, in
+// parentheses, joined to the previous ones with AND.
+func (b *SQLBuilder) AndWhereGroup(fn func(*SQLBuilder) *SQLBuilder) *SQLBuilder {
+	return b.addGroup(false, fn)
+}
+
+// OrWhereGroup adds the conditions fn adds to a new SQLBuilder, in
+// parentheses, joined to the previous ones with OR.
+func (b *SQLBuilder) OrWhereGroup(fn func(*SQLBuilder) *SQLBuilder) *SQLBuilder {
+	return b.addGroup(true, fn)
+}
+
+func (b *SQLBuilder) addGroup(or bool, fn func(*SQLBuilder) *SQLBuilder) *SQLBuilder {
+	group := fn(NewSQLBuilder())
+	// An empty group would render as "()"
+	if len(group.conditions) > 0 {
+		b.conditions = append(b.conditions, condition{or: or, group: group})
+	}
 	return b
 }
 
-// WhereRaw adds a raw SQL condition with arguments.
-func (b *SQLBuilder) WhereRaw(sql string, args ...interface{}) *SQLBuilder {
-	b.rawConditions = append(b.rawConditions, rawCondition{
-		sql:  sql,
-		args: args,
-	})
+// WhereExists adds an "EXISTS (sub)" condition joined to the previous ones
+// with AND. The placeholders of sub are renumbered to follow the query's.
+func (b *SQLBuilder) WhereExists(sub *SQLBuilder) *SQLBuilder {
+	b.conditions = append(b.conditions, condition{sql: "EXISTS ", sub: sub})
+	return b
+}
+
+// WhereNotExists adds a "NOT EXISTS (sub)" condition, see WhereExists.
+func (b *SQLBuilder) WhereNotExists(sub *SQLBuilder) *SQLBuilder {
+	b.conditions = append(b.conditions, condition{sql: "NOT EXISTS ", sub: sub})
 	return b
 }
 
-// OnConflict adds an ON CONFLICT clause to the query.
-func (b *SQLBuilder) OnConflict(clause string, args ...interface{}) *SQLBuilder {
-	b.onConflict = clause
+// WhereRaw adds a raw SQL condition with arguments, joined to the previous
+// ones with AND. Wrap it in parentheses when it contains an OR.
+func (b *SQLBuilder) WhereRaw(sql string, args ...interface{}) *SQLBuilder {
+	return b.addCondition(false, sql, args)
+}
+
+// OnConflict adds an "ON CONFLICT target action" clause to an INSERT, such
+// as OnConflict("(id)", "DO UPDATE SET name = EXCLUDED.name"). target may be
+// empty for "DO NOTHING"; the ? placeholders of action are numbered after
+// the values.
+func (b *SQLBuilder) OnConflict(target, action string, args ...interface{}) *SQLBuilder {
+	b.conflictTarget = target
+	b.onConflict = action
 	b.onConflictArgs = args
 	return b
 }
 
+// Returning adds a RETURNING clause to an INSERT, UPDATE or DELETE, for the
+// generated ids or the rows changed.
+func (b *SQLBuilder) Returning(cols ...string) *SQLBuilder {
+	b.returning = cols
+	return b
+}
+
 // BuildSafe constructs the final SQL string and arguments with safety validation.
 // Returns an error if the number of placeholders doesn't match the number of arguments.
 func (b *SQLBuilder) BuildSafe() (string, []interface{}, error) {
@@ -175,14 +288,7 @@ func (b *SQLBuilder) BuildSafe() (string, []interface{}, error) {
 
 	// Count the number of placeholder markers in the generated SQL
 	// Since Build() replaces "?" with "$1", "$2", etc., we count those
-	placeholderCount := 0
-	for i := 1; i <= len(args)+10; i++ { // Check up to a reasonable limit
-		if strings.Contains(sql, fmt.Sprintf("$%d", i)) {
-			placeholderCount++
-		} else if i > len(args) {
-			break
-		}
-	}
+	placeholderCount := b.dialect.countPlaceholders(sql, len(args))
 
 	if placeholderCount != len(args) {
 		return "", nil, fmt.Errorf("placeholder count (%d) does not match argument count (%d)", placeholderCount, len(args))
@@ -193,12 +299,30 @@ func (b *SQLBuilder) BuildSafe() (string, []interface{}, error) {
 
 // Build constructs the final SQL string and arguments, ready for DB prepared statements
 func (b *SQLBuilder) Build() (string, []interface{}) {
+	query, args := b.build(0, b.dialect)
+	if b.dialect == Named {
+		args = nameArgs(args)
+	}
+	return query, args
+}
+
+// build is Build in dialect d with the placeholders numbered after the first
+// offset, for subqueries.
+func (b *SQLBuilder) build(offset int, d Dialect) (string, []interface{}) {
 	var sb strings.Builder
+	args := append([]interface{}(nil), b.args...)
 
 	if b.isSelect {
 		sb.WriteString("SELECT ")
+		if b.distinct {
+			sb.WriteString("DISTINCT ")
+		}
 		sb.WriteString(strings.Join(b.columns, ", "))
 		sb.WriteString(" FROM ")
+		if b.fromSub != nil {
+			args = writeSubquery(&sb, b.fromSub, d, offset, args)
+			sb.WriteString(" AS ")
+		}
 		sb.WriteString(b.table)
 		for _, join := range b.joins {
 			sb.WriteString(" ")
@@ -209,28 +333,38 @@ func (b *SQLBuilder) Build() (string, []interface{}) {
 		sb.WriteString(b.table)
 		sb.WriteString(" (")
 		sb.WriteString(strings.Join(b.columns, ", "))
-		sb.WriteString(") VALUES (")
-		placeholders := make([]string, len(b.values))
-		for i := range b.values {
-			placeholders[i] = fmt.Sprintf("$%d", i+1)
+		sb.WriteString(") VALUES ")
+		argIndex := offset + 1
+		rows := make([]string, len(b.values))
+		for r, vals := range b.values {
+			placeholders := make([]string, len(vals))
+			for i := range vals {
+				placeholders[i] = d.placeholder(argIndex)
+				argIndex++
+			}
+			rows[r] = "(" + strings.Join(placeholders, ", ") + ")"
 		}
-		sb.WriteString(strings.Join(placeholders, ", "))
-		sb.WriteString(")")
+		sb.WriteString(strings.Join(rows, ", "))
 
 		if b.onConflict != "" {
 			sb.WriteString(" ON CONFLICT ")
-			sb.WriteString(b.onConflict)
-			b.args = append(b.args, b.onConflictArgs...)
+			if b.conflictTarget != "" {
+				sb.WriteString(b.conflictTarget)
+				sb.WriteString(" ")
+			}
+			writePlaceholders(&sb, b.onConflict, d, offset+len(args))
+			args = append(args, b.onConflictArgs...)
 		}
+		b.writeReturning(&sb)
 
-		return sb.String(), b.args
+		return sb.String(), args
 	} else if b.isUpdate {
 		sb.WriteString("UPDATE ")
 		sb.WriteString(b.table)
 		sb.WriteString(" SET ")
 		setClauses := make([]string, len(b.updateCols))
 		for i, col := range b.updateCols {
-			setClauses[i] = fmt.Sprintf("%s = $%d", col, i+1)
+			setClauses[i] = col + " = " + d.placeholder(offset+i+1)
 		}
 		sb.WriteString(strings.Join(setClauses, ", "))
 	} else if b.isDelete {
@@ -238,129 +372,21 @@ func (b *SQLBuilder) Build() (string, []interface{}) {
 		sb.WriteString(b.table)
 	}
 
-	// Build WHERE clause with all condition types
-	hasWhere := len(b.where) > 0 || len(b.orConditions) > 0 || len(b.whereGroups) > 0 || len(b.rawConditions) > 0
-
-	if hasWhere {
+	if len(b.conditions) > 0 {
+		// Placeholders of UPDATE follow those of its SET clause
 		sb.WriteString(" WHERE ")
-
-		// Adjust placeholders for WHERE clause if needed (for Update, offset by set args)
-		offset := 0
-		if b.isUpdate {
-			offset = len(b.updateCols)
-		}
-
-		argIndex := offset + 1
-		var conditions []string
-
-		// Process regular WHERE conditions (combined with AND)
-		if len(b.where) > 0 {
-			whereClause := strings.Join(b.where, " AND ")
-			finalWhere := ""
-			parts := strings.Split(whereClause, "?")
-			for i, part := range parts {
-				finalWhere += part
-				if i < len(parts)-1 {
-					finalWhere += fmt.Sprintf("$%d", argIndex)
-					argIndex++
-				}
-			}
-			conditions = append(conditions, finalWhere)
-		}
-
-		// Process grouped WHERE conditions (parenthesized)
-		for _, group := range b.whereGroups {
-			if len(group.builder.where) > 0 || len(group.builder.orConditions) > 0 || len(group.builder.rawConditions) > 0 {
-				var groupConditions []string
-
-				// Process regular WHERE in group
-				if len(group.builder.where) > 0 {
-					whereClause := strings.Join(group.builder.where, " AND ")
-					finalWhere := ""
-					parts := strings.Split(whereClause, "?")
-					for i, part := range parts {
-						finalWhere += part
-						if i < len(parts)-1 {
-							finalWhere += fmt.Sprintf("$%d", argIndex)
-							argIndex++
-						}
-					}
-					groupConditions = append(groupConditions, finalWhere)
-				}
-
-				// Append group where args to main args
-				b.args = append(b.args, group.builder.args...)
-
-				// Process OR conditions in group
-				for _, orCond := range group.builder.orConditions {
-					finalOr := ""
-					parts := strings.Split(orCond.condition, "?")
-					for i, part := range parts {
-						finalOr += part
-						if i < len(parts)-1 {
-							finalOr += fmt.Sprintf("$%d", argIndex)
-							argIndex++
-						}
-					}
-					groupConditions = append(groupConditions, finalOr)
-					// Append OR condition args to main args
-					b.args = append(b.args, orCond.args...)
-				}
-
-				// Process raw conditions in group
-				for _, rawCond := range group.builder.rawConditions {
-					finalRaw := ""
-					parts := strings.Split(rawCond.sql, "?")
-					for i, part := range parts {
-						finalRaw += part
-						if i < len(parts)-1 {
-							finalRaw += fmt.Sprintf("$%d", argIndex)
-							argIndex++
-						}
-					}
-					groupConditions = append(groupConditions, finalRaw)
-					// Append raw condition args to main args
-					b.args = append(b.args, rawCond.args...)
-				}
-
-				if len(groupConditions) > 0 {
-					// Join with OR since groups can contain mixed WHERE and OR conditions
-					groupClause := "(" + strings.Join(groupConditions, " OR ") + ")"
-					conditions = append(conditions, groupClause)
-				}
-			}
-		}
-
-		// Process OR conditions
-		for _, orCond := range b.orConditions {
-			finalOr := ""
-			parts := strings.Split(orCond.condition, "?")
-			for i, part := range parts {
-				finalOr += part
-				if i < len(parts)-1 {
-					finalOr += fmt.Sprintf("$%d", argIndex)
-					argIndex++
-				}
-			}
-			conditions = append(conditions, finalOr)
-		}
-
-		// Process raw conditions
-		for _, rawCond := range b.rawConditions {
-			finalRaw := ""
-			parts := strings.Split(rawCond.sql, "?")
-			for i, part := range parts {
-				finalRaw += part
-				if i < len(parts)-1 {
-					finalRaw += fmt.Sprintf("$%d", argIndex)
-					argIndex++
-				}
-			}
-			conditions = append(conditions, finalRaw)
-		}
-
-		// Combine all conditions with OR
-		sb.WriteString(strings.Join(conditions, " OR "))
+		args = writeConditions(&sb, b.conditions, d, offset, args)
+	}
+	if len(b.groupBy) > 0 {
+		sb.WriteString(" GROUP BY ")
+		sb.WriteString(strings.Join(b.groupBy, ", "))
+	}
+	if len(b.having) > 0 {
+		sb.WriteString(" HAVING ")
+		args = writeConditions(&sb, b.having, d, offset, args)
+	}
+	if b.isUpdate || b.isDelete {
+		b.writeReturning(&sb)
 	}
 
 	if len(b.orderBy) > 0 {
@@ -376,15 +402,60 @@ func (b *SQLBuilder) Build() (string, []interface{}) {
 		sb.WriteString(fmt.Sprintf(" OFFSET %d", b.offset))
 	}
 
-	// Append args from OR conditions
-	for _, orCond := range b.orConditions {
-		b.args = append(b.args, orCond.args...)
+	return sb.String(), args
+}
+
+// writeConditions writes conds to sb in dialect d, numbering their
+// placeholders after offset and args, and returns args followed by theirs.
+func writeConditions(sb *strings.Builder, conds []condition, d Dialect, offset int, args []interface{}) []interface{} {
+	for i, c := range conds {
+		if i > 0 {
+			if c.or {
+				sb.WriteString(" OR ")
+			} else {
+				sb.WriteString(" AND ")
+			}
+		}
+		if c.group != nil {
+			sb.WriteString("(")
+			args = writeConditions(sb, c.group.conditions, d, offset, args)
+			sb.WriteString(")")
+			continue
+		}
+		writePlaceholders(sb, c.sql, d, offset+len(args))
+		args = append(args, c.args...)
+		if c.sub != nil {
+			args = writeSubquery(sb, c.sub, d, offset, args)
+		}
 	}
+	return args
+}
+
+// writeSubquery writes sub to sb in parentheses in dialect d, numbering its
+// placeholders after offset and args, and returns args followed by its own.
+func writeSubquery(sb *strings.Builder, sub *SQLBuilder, d Dialect, offset int, args []interface{}) []interface{} {
+	query, subArgs := sub.build(offset+len(args), d)
+	sb.WriteString("(")
+	sb.WriteString(query)
+	sb.WriteString(")")
+	return append(args, subArgs...)
+}
 
-	// Append args from raw conditions
-	for _, rawCond := range b.rawConditions {
-		b.args = append(b.args, rawCond.args...)
+// writePlaceholders writes sql to sb with its ? placeholders written in
+// dialect d, numbered after the first n.
+func writePlaceholders(sb *strings.Builder, sql string, d Dialect, n int) {
+	parts := strings.Split(sql, "?")
+	for i, part := range parts {
+		sb.WriteString(part)
+		if i < len(parts)-1 {
+			sb.WriteString(d.placeholder(n + i + 1))
+		}
 	}
+}
 
-	return sb.String(), b.args
+func (b *SQLBuilder) writeReturning(sb *strings.Builder) {
+	if len(b.returning) > 0 {
+		sb.WriteString(" RETURNING ")
+		sb.WriteString(strings.Join(b.returning, ", "))
+	}
 }
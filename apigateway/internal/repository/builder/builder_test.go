@@ -1,6 +1,7 @@
 package builder
 
 import (
+	"database/sql"
 	"strings"
 	"testing"
 	"time"
@@ -31,6 +32,30 @@ func TestSQLBuilder(t *testing.T) {
 		}
 	})
 
+	t.Run("WhereIn", func(t *testing.T) {
+		b := NewSQLBuilder()
+		query, args := b.Select("id").From("feature").Where("id > ?", 10).WhereIn("brand", []string{"Apple", "Sony"}).Build()
+		expected := "SELECT id FROM feature WHERE id > $1 AND brand IN ($2, $3)"
+		if query != expected {
+			t.Errorf("expected %s, got %s", expected, query)
+		}
+		if len(args) != 3 || args[1] != "Apple" || args[2] != "Sony" {
+			t.Errorf("expected args [10 Apple Sony], got %v", args)
+		}
+	})
+
+	t.Run("WhereIn empty slice", func(t *testing.T) {
+		b := NewSQLBuilder()
+		query, args := b.Select("id").From("feature").WhereIn("brand", []string{}).Build()
+		expected := "SELECT id FROM feature WHERE 1 = 0"
+		if query != expected {
+			t.Errorf("expected %s, got %s", expected, query)
+		}
+		if len(args) != 0 {
+			t.Errorf("expected no args, got %v", args)
+		}
+	})
+
 	t.Run("Update", func(t *testing.T) {
 		b := NewSQLBuilder()
 		query, args := b.Update("users").Set("name", "Bob").Where("id = ?", 1).Build()
@@ -134,9 +159,9 @@ func TestSQLBuilderEnhancements(t *testing.T) {
 			Or("dept_no = ?", "d002").
 			Build()
 
-		// Should have WHERE clause with AND condition followed by OR conditions
-		if !strings.Contains(query, "WHERE") {
-			t.Errorf("expected WHERE clause in %s", query)
+		expected := "SELECT * FROM employees WHERE status = $1 OR dept_no = $2 OR dept_no = $3"
+		if query != expected {
+			t.Errorf("expected %s, got %s", expected, query)
 		}
 		if len(args) != 3 {
 			t.Errorf("expected 3 args, got %d: %v", len(args), args)
@@ -151,8 +176,9 @@ func TestSQLBuilderEnhancements(t *testing.T) {
 			WhereRaw("hire_date < ?", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)).
 			Build()
 
-		if !strings.Contains(query, "WHERE") {
-			t.Errorf("expected WHERE clause in %s", query)
+		expected := "SELECT * FROM employees WHERE salary > $1 AND hire_date < $2"
+		if query != expected {
+			t.Errorf("expected %s, got %s", expected, query)
 		}
 		if len(args) != 2 {
 			t.Errorf("expected 2 args, got %d: %v", len(args), args)
@@ -171,16 +197,98 @@ func TestSQLBuilderEnhancements(t *testing.T) {
 			}).
 			Build()
 
-		// Verify the query structure
-		if !strings.Contains(query, "WHERE") {
-			t.Errorf("expected WHERE clause in %s", query)
+		expected := "SELECT * FROM employees WHERE status = $1 AND (dept_no = $2 OR dept_no = $3)"
+		if query != expected {
+			t.Errorf("expected %s, got %s", expected, query)
 		}
-		// Should have status condition OR grouped conditions
 		if len(args) != 3 {
 			t.Errorf("expected 3 args, got %d: %v", len(args), args)
 		}
 	})
 
+	t.Run("Where chains with AND", func(t *testing.T) {
+		b := NewSQLBuilder()
+		query, args := b.Select("*").
+			From("employees").
+			Where("status = ?", "active").
+			WhereIn("dept_no", []string{"d001", "d002"}).
+			WhereRaw("salary > ?", 50000).
+			Build()
+
+		expected := "SELECT * FROM employees WHERE status = $1 AND dept_no IN ($2, $3) AND salary > $4"
+		if query != expected {
+			t.Errorf("expected %s, got %s", expected, query)
+		}
+		if len(args) != 4 || args[0] != "active" || args[1] != "d001" || args[2] != "d002" || args[3] != 50000 {
+			t.Errorf("expected args [active d001 d002 50000], got %v", args)
+		}
+	})
+
+	t.Run("OrWhereGroup and AndWhereGroup", func(t *testing.T) {
+		b := NewSQLBuilder()
+		query, args := b.Select("*").
+			From("employees").
+			Where("status = ?", "active").
+			AndWhereGroup(func(g *SQLBuilder) *SQLBuilder {
+				return g.Where("gender = ?", "F").Or("hire_date > ?", "2020-01-01")
+			}).
+			OrWhereGroup(func(g *SQLBuilder) *SQLBuilder {
+				return g.Where("title = ?", "Manager").
+					OrWhereGroup(func(g *SQLBuilder) *SQLBuilder {
+						return g.Where("salary > ?", 100000).Where("dept_no = ?", "d005")
+					})
+			}).
+			Build()
+
+		expected := "SELECT * FROM employees WHERE status = $1 AND (gender = $2 OR hire_date > $3) OR (title = $4 OR (salary > $5 AND dept_no = $6))"
+		if query != expected {
+			t.Errorf("expected %s, got %s", expected, query)
+		}
+		if len(args) != 6 || args[0] != "active" || args[3] != "Manager" || args[5] != "d005" {
+			t.Errorf("expected args [active F 2020-01-01 Manager 100000 d005], got %v", args)
+		}
+	})
+
+	t.Run("Empty group is skipped", func(t *testing.T) {
+		b := NewSQLBuilder()
+		query, _ := b.Select("*").
+			From("employees").
+			Where("status = ?", "active").
+			OrWhereGroup(func(g *SQLBuilder) *SQLBuilder { return g }).
+			Build()
+
+		expected := "SELECT * FROM employees WHERE status = $1"
+		if query != expected {
+			t.Errorf("expected %s, got %s", expected, query)
+		}
+	})
+
+	t.Run("Update numbers WHERE placeholders after SET", func(t *testing.T) {
+		b := NewSQLBuilder().
+			Update("employees").
+			Set("status", "inactive").
+			Set("updated_by", "admin").
+			Where("id = ?", 7).
+			OrWhereGroup(func(g *SQLBuilder) *SQLBuilder {
+				return g.Where("dept_no = ?", "d001").WhereRaw("to_date < ?", "2020-01-01")
+			})
+		query, args := b.Build()
+
+		expected := "UPDATE employees SET status = $1, updated_by = $2 WHERE id = $3 OR (dept_no = $4 AND to_date < $5)"
+		if query != expected {
+			t.Errorf("expected %s, got %s", expected, query)
+		}
+		if len(args) != 5 || args[0] != "inactive" || args[2] != 7 || args[4] != "2020-01-01" {
+			t.Errorf("expected args [inactive admin 7 d001 2020-01-01], got %v", args)
+		}
+
+		// Building again returns the same query and arguments
+		query2, args2 := b.Build()
+		if query2 != query || len(args2) != len(args) {
+			t.Errorf("expected %s with %d args on the second Build, got %s with %v", query, len(args), query2, args2)
+		}
+	})
+
 	t.Run("Update with Or conditions", func(t *testing.T) {
 		b := NewSQLBuilder()
 		query, args := b.Update("employees").
@@ -217,7 +325,7 @@ func TestSQLBuilderEnhancements(t *testing.T) {
 		b := NewSQLBuilder()
 		query, args := b.Insert("employees", "emp_no", "first_name", "last_name").
 			Values(1001, "John", "Doe").
-			OnConflict("(emp_no) DO UPDATE SET first_name = EXCLUDED.first_name, last_name = EXCLUDED.last_name").
+			OnConflict("(emp_no)", "DO UPDATE SET first_name = EXCLUDED.first_name, last_name = EXCLUDED.last_name").
 			Build()
 
 		expected := "INSERT INTO employees (emp_no, first_name, last_name) VALUES ($1, $2, $3) ON CONFLICT (emp_no) DO UPDATE SET first_name = EXCLUDED.first_name, last_name = EXCLUDED.last_name"
@@ -228,4 +336,231 @@ func TestSQLBuilderEnhancements(t *testing.T) {
 			t.Errorf("expected args [1001 John Doe], got %v", args)
 		}
 	})
+
+	t.Run("Multi-row Upsert", func(t *testing.T) {
+		b := NewSQLBuilder()
+		query, args := b.Insert("employees", "emp_no", "first_name").
+			Values(1001, "John").
+			Values(1002, "Jane").
+			OnConflict("(emp_no)", "DO UPDATE SET first_name = EXCLUDED.first_name").
+			Build()
+
+		expected := "INSERT INTO employees (emp_no, first_name) VALUES ($1, $2), ($3, $4) ON CONFLICT (emp_no) DO UPDATE SET first_name = EXCLUDED.first_name"
+		if query != expected {
+			t.Errorf("expected %s, got %s", expected, query)
+		}
+		if len(args) != 4 || args[0] != 1001 || args[1] != "John" || args[2] != 1002 || args[3] != "Jane" {
+			t.Errorf("expected args [1001 John 1002 Jane], got %v", args)
+		}
+	})
+
+	t.Run("Upsert with arguments and RETURNING", func(t *testing.T) {
+		b := NewSQLBuilder()
+		query, args := b.Insert("employees", "emp_no", "first_name").
+			ValuesBatch([][]interface{}{{1001, "John"}, {1002, "Jane"}}).
+			OnConflict("(emp_no)", "DO UPDATE SET first_name = EXCLUDED.first_name, updated_by = ?", "admin").
+			Returning("emp_no", "xmax = 0").
+			Build()
+
+		expected := "INSERT INTO employees (emp_no, first_name) VALUES ($1, $2), ($3, $4) ON CONFLICT (emp_no) DO UPDATE SET first_name = EXCLUDED.first_name, updated_by = $5 RETURNING emp_no, xmax = 0"
+		if query != expected {
+			t.Errorf("expected %s, got %s", expected, query)
+		}
+		if len(args) != 5 || args[4] != "admin" {
+			t.Errorf("expected args [1001 John 1002 Jane admin], got %v", args)
+		}
+	})
+
+	t.Run("OnConflict without target", func(t *testing.T) {
+		b := NewSQLBuilder()
+		query, _ := b.Insert("departments", "dept_no").
+			Values("d001").
+			OnConflict("", "DO NOTHING").
+			Build()
+
+		expected := "INSERT INTO departments (dept_no) VALUES ($1) ON CONFLICT DO NOTHING"
+		if query != expected {
+			t.Errorf("expected %s, got %s", expected, query)
+		}
+	})
+
+	t.Run("Update and Delete with RETURNING", func(t *testing.T) {
+		query, args := NewSQLBuilder().Update("leave_request").
+			Set("status", "approved").
+			Where("id = ?", 3).
+			Returning("emp_no", "from_date").
+			Build()
+
+		expected := "UPDATE leave_request SET status = $1 WHERE id = $2 RETURNING emp_no, from_date"
+		if query != expected {
+			t.Errorf("expected %s, got %s", expected, query)
+		}
+		if len(args) != 2 {
+			t.Errorf("expected 2 args, got %d: %v", len(args), args)
+		}
+
+		query, _ = NewSQLBuilder().Delete("annotation").Where("id = ?", 3).Returning("id").Build()
+		expected = "DELETE FROM annotation WHERE id = $1 RETURNING id"
+		if query != expected {
+			t.Errorf("expected %s, got %s", expected, query)
+		}
+	})
+
+	t.Run("GroupBy and Having number placeholders after WHERE", func(t *testing.T) {
+		b := NewSQLBuilder()
+		query, args := b.Select("dept_no", Count("*"), Sum("salary")).
+			From("employees.salary").
+			Where("to_date > ?", "2020-01-01").
+			GroupBy("dept_no").
+			Having(Count("*")+" > ?", 10).
+			OrHaving(Sum("salary")+" > ?", 1000000).
+			OrderBy("dept_no").
+			Build()
+
+		expected := "SELECT dept_no, COUNT(*), SUM(salary) FROM employees.salary WHERE to_date > $1 GROUP BY dept_no HAVING COUNT(*) > $2 OR SUM(salary) > $3 ORDER BY dept_no"
+		if query != expected {
+			t.Errorf("expected %s, got %s", expected, query)
+		}
+		if len(args) != 3 || args[0] != "2020-01-01" || args[1] != 10 || args[2] != 1000000 {
+			t.Errorf("expected args [2020-01-01 10 1000000], got %v", args)
+		}
+	})
+
+	t.Run("Distinct", func(t *testing.T) {
+		b := NewSQLBuilder()
+		query, _ := b.Select("brand").Distinct().From("feature").Build()
+
+		expected := "SELECT DISTINCT brand FROM feature"
+		if query != expected {
+			t.Errorf("expected %s, got %s", expected, query)
+		}
+	})
+
+	t.Run("WhereExists renumbers the subquery placeholders", func(t *testing.T) {
+		sub := NewSQLBuilder().Select("1").
+			From("feature f").
+			Where("f.id = p.id AND f.brand = p.brand").
+			Where("f.country = ?", "Japan")
+		b := NewSQLBuilder()
+		query, args := b.Select("p.id", "p.brand").
+			From("product p").
+			Where("p.brand = ?", "Sony").
+			WhereExists(sub).
+			Where("p.revision > ?", 0).
+			Build()
+
+		expected := "SELECT p.id, p.brand FROM product p WHERE p.brand = $1 AND EXISTS (SELECT 1 FROM feature f WHERE f.id = p.id AND f.brand = p.brand AND f.country = $2) AND p.revision > $3"
+		if query != expected {
+			t.Errorf("expected %s, got %s", expected, query)
+		}
+		if len(args) != 3 || args[0] != "Sony" || args[1] != "Japan" || args[2] != 0 {
+			t.Errorf("expected args [Sony Japan 0], got %v", args)
+		}
+
+		// The subquery can be built again on its own
+		subQuery, _ := sub.Build()
+		if subQuery != "SELECT 1 FROM feature f WHERE f.id = p.id AND f.brand = p.brand AND f.country = $1" {
+			t.Errorf("expected the subquery numbered from $1, got %s", subQuery)
+		}
+	})
+
+	t.Run("WhereNotExists in UPDATE", func(t *testing.T) {
+		b := NewSQLBuilder()
+		query, args := b.Update("product").
+			Set("revision", 0).
+			WhereNotExists(NewSQLBuilder().Select("1").From("feature").Where("feature.id = product.id").Where("country = ?", "USA")).
+			Build()
+
+		expected := "UPDATE product SET revision = $1 WHERE NOT EXISTS (SELECT 1 FROM feature WHERE feature.id = product.id AND country = $2)"
+		if query != expected {
+			t.Errorf("expected %s, got %s", expected, query)
+		}
+		if len(args) != 2 {
+			t.Errorf("expected 2 args, got %d: %v", len(args), args)
+		}
+	})
+
+	t.Run("FromSubquery", func(t *testing.T) {
+		sub := NewSQLBuilder().Select("brand", Count("*")+" AS n").
+			From("feature").
+			Where("country = ?", "Japan").
+			GroupBy("brand")
+		b := NewSQLBuilder()
+		query, args := b.Select("brand", "n").
+			FromSubquery(sub, "counts").
+			Where("n > ?", 5).
+			Build()
+
+		expected := "SELECT brand, n FROM (SELECT brand, COUNT(*) AS n FROM feature WHERE country = $1 GROUP BY brand) AS counts WHERE n > $2"
+		if query != expected {
+			t.Errorf("expected %s, got %s", expected, query)
+		}
+		if len(args) != 2 || args[0] != "Japan" || args[1] != 5 {
+			t.Errorf("expected args [Japan 5], got %v", args)
+		}
+	})
+}
+
+func TestSQLBuilderDialects(t *testing.T) {
+	newQuery := func(opts ...Option) *SQLBuilder {
+		return NewSQLBuilder(opts...).Update("product").
+			Set("revision", 2).
+			Where("brand = ?", "Sony").
+			WhereExists(NewSQLBuilder().Select("1").From("feature").Where("feature.id = product.id").Where("country = ?", "Japan"))
+	}
+
+	t.Run("Postgres by default", func(t *testing.T) {
+		query, args := newQuery().Build()
+		expected := "UPDATE product SET revision = $1 WHERE brand = $2 AND EXISTS (SELECT 1 FROM feature WHERE feature.id = product.id AND country = $3)"
+		if query != expected {
+			t.Errorf("expected %s, got %s", expected, query)
+		}
+		if len(args) != 3 || args[0] != 2 || args[2] != "Japan" {
+			t.Errorf("expected args [2 Sony Japan], got %v", args)
+		}
+	})
+
+	t.Run("MySQL", func(t *testing.T) {
+		query, args := newQuery(WithDialect(MySQL)).Build()
+		expected := "UPDATE product SET revision = ? WHERE brand = ? AND EXISTS (SELECT 1 FROM feature WHERE feature.id = product.id AND country = ?)"
+		if query != expected {
+			t.Errorf("expected %s, got %s", expected, query)
+		}
+		if len(args) != 3 || args[1] != "Sony" {
+			t.Errorf("expected args [2 Sony Japan], got %v", args)
+		}
+	})
+
+	t.Run("Named", func(t *testing.T) {
+		query, args := newQuery(WithDialect(Named)).Build()
+		expected := "UPDATE product SET revision = :p1 WHERE brand = :p2 AND EXISTS (SELECT 1 FROM feature WHERE feature.id = product.id AND country = :p3)"
+		if query != expected {
+			t.Errorf("expected %s, got %s", expected, query)
+		}
+		if len(args) != 3 || args[1] != sql.Named("p2", "Sony") {
+			t.Errorf("expected named args [p1=2 p2=Sony p3=Japan], got %v", args)
+		}
+	})
+
+	t.Run("BuildNamed", func(t *testing.T) {
+		query, args := newQuery().BuildNamed()
+		if !strings.HasPrefix(query, "UPDATE product SET revision = :p1 WHERE brand = :p2") {
+			t.Errorf("expected :pN placeholders, got %s", query)
+		}
+		if len(args) != 3 || args["p1"] != 2 || args["p2"] != "Sony" || args["p3"] != "Japan" {
+			t.Errorf("expected args map[p1:2 p2:Sony p3:Japan], got %v", args)
+		}
+	})
+
+	t.Run("BuildSafe counts the placeholders of the dialect", func(t *testing.T) {
+		for _, d := range []Dialect{Postgres, MySQL, Named} {
+			if _, _, err := newQuery(WithDialect(d)).BuildSafe(); err != nil {
+				t.Errorf("dialect %d: expected no error, got %v", d, err)
+			}
+			_, _, err := NewSQLBuilder(WithDialect(d)).Select("*").From("employees").Where("emp_no = ? AND gender = ?", 1001).BuildSafe()
+			if err == nil {
+				t.Errorf("dialect %d: expected a placeholder count error", d)
+			}
+		}
+	})
 }
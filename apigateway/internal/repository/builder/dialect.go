@@ -0,0 +1,86 @@
+package builder
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// Dialect is how a query numbers its placeholders. The ? placeholders given
+// to the builder are written in the dialect of the query by Build; the rest
+// of the SQL is written as given.
+type Dialect int
+
+const (
+	// Postgres writes $1, $2, ... It is the default.
+	Postgres Dialect = iota
+	// MySQL keeps the ? placeholders.
+	MySQL
+	// Named writes :p1, :p2, ... and Build returns the arguments as
+	// sql.NamedArg, see also BuildNamed.
+	Named
+)
+
+// Option configures a SQLBuilder.
+type Option func(*SQLBuilder)
+
+// WithDialect sets the dialect of the queries of the builder. Subqueries are
+// written in the dialect of the query embedding them.
+func WithDialect(d Dialect) Option {
+	return func(b *SQLBuilder) {
+		b.dialect = d
+	}
+}
+
+// placeholder returns the nth placeholder, from 1.
+func (d Dialect) placeholder(n int) string {
+	switch d {
+	case MySQL:
+		return "?"
+	case Named:
+		return fmt.Sprintf(":%s", namedArg(n))
+	default:
+		return fmt.Sprintf("$%d", n)
+	}
+}
+
+// countPlaceholders counts the placeholders of query, expecting about n.
+func (d Dialect) countPlaceholders(query string, n int) int {
+	if d == MySQL {
+		return strings.Count(query, "?")
+	}
+	count := 0
+	for i := 1; i <= n+10; i++ { // Check up to a reasonable limit
+		if strings.Contains(query, d.placeholder(i)) {
+			count++
+		} else if i > n {
+			break
+		}
+	}
+	return count
+}
+
+// namedArg is the name of the nth argument of Named queries.
+func namedArg(n int) string {
+	return fmt.Sprintf("p%d", n)
+}
+
+// nameArgs returns args as the sql.NamedArg of their placeholders.
+func nameArgs(args []interface{}) []interface{} {
+	named := make([]interface{}, len(args))
+	for i, arg := range args {
+		named[i] = sql.Named(namedArg(i+1), arg)
+	}
+	return named
+}
+
+// BuildNamed builds the query in the Named dialect and returns its arguments
+// by name, for sqlx named execution.
+func (b *SQLBuilder) BuildNamed() (string, map[string]interface{}) {
+	query, args := b.build(0, Named)
+	named := make(map[string]interface{}, len(args))
+	for i, arg := range args {
+		named[namedArg(i+1)] = arg
+	}
+	return query, named
+}
@@ -102,8 +102,11 @@ func Example_combinedConditions() {
 				Where("dept_no = ?", "d001").
 				Or("dept_no = ?", "d002")
 		}).
-		Or("salary > ?", 100000).
-		WhereRaw("performance_score >= ?", 4.5).
+		OrWhereGroup(func(g *builder.SQLBuilder) *builder.SQLBuilder {
+			return g.
+				Where("salary > ?", 100000).
+				WhereRaw("performance_score >= ?", 4.5)
+		}).
 		OrderBy("emp_no DESC").
 		Limit(10)
 
@@ -115,7 +118,7 @@ func Example_combinedConditions() {
 	// Output:
 	// Complex query built successfully
 	// Number of conditions: 5
-	// SQL: SELECT * FROM employees WHERE status = $1 OR (dept_no = $2 OR dept_no = $3) OR salary > $4 OR performance_score >= $5 ORDER BY emp_no DESC LIMIT 10
+	// SQL: SELECT * FROM employees WHERE status = $1 AND (dept_no = $2 OR dept_no = $3) OR (salary > $4 AND performance_score >= $5) ORDER BY emp_no DESC LIMIT 10
 }
 
 // Example6_UpdateWithOr demonstrates using Or() with UPDATE queries
@@ -150,13 +153,29 @@ func Example_deleteWithWhereRaw() {
 	// Number of args: 0
 }
 
+// Example_whereIn demonstrates WhereIn expanding a slice into placeholders
+func Example_whereIn() {
+	qb := builder.NewSQLBuilder().
+		Select("id", "brand").
+		From("feature").
+		WhereIn("brand", []string{"Apple", "Samsung", "Sony"})
+
+	sql, args := qb.Build()
+	fmt.Println("SQL:", sql)
+	fmt.Printf("Args: %v\n", args)
+
+	// Output:
+	// SQL: SELECT id, brand FROM feature WHERE brand IN ($1, $2, $3)
+	// Args: [Apple Samsung Sony]
+}
+
 // Example8_Upsert demonstrates using OnConflict() for upsert operations
 func Example_upsert() {
 	// Upsert with composite primary key
 	qb := builder.NewSQLBuilder().
 		Insert("dept_emp", "emp_no", "dept_no", "from_date", "to_date").
 		Values(10001, "d005", "2023-01-01", "9999-01-01").
-		OnConflict("(emp_no, dept_no) DO UPDATE SET from_date = EXCLUDED.from_date, to_date = EXCLUDED.to_date")
+		OnConflict("(emp_no, dept_no)", "DO UPDATE SET from_date = EXCLUDED.from_date, to_date = EXCLUDED.to_date")
 
 	sql, args := qb.Build()
 	fmt.Println("SQL:", sql)
@@ -167,6 +186,78 @@ func Example_upsert() {
 	// Args: [10001 d005 2023-01-01 9999-01-01]
 }
 
+// Example_groupBy demonstrates aggregating with GroupBy() and Having()
+func Example_groupBy() {
+	qb := builder.NewSQLBuilder().
+		Select("dept_no", builder.Count("*")).
+		From("dept_emp").
+		Where("to_date = ?", "9999-01-01").
+		GroupBy("dept_no").
+		Having(builder.Count("*")+" >= ?", 100)
+
+	sql, args := qb.Build()
+	fmt.Println("SQL:", sql)
+	fmt.Printf("Args: %v\n", args)
+
+	// Output:
+	// SQL: SELECT dept_no, COUNT(*) FROM dept_emp WHERE to_date = $1 GROUP BY dept_no HAVING COUNT(*) >= $2
+	// Args: [9999-01-01 100]
+}
+
+// Example_whereExists demonstrates embedding a builder as an EXISTS subquery
+func Example_whereExists() {
+	qb := builder.NewSQLBuilder().
+		Select("p.id", "p.brand").
+		From("product p").
+		Where("p.brand = ?", "Sony").
+		WhereExists(builder.NewSQLBuilder().
+			Select("1").
+			From("feature f").
+			Where("f.id = p.id AND f.brand = p.brand").
+			Where("f.country = ?", "Japan"))
+
+	sql, args := qb.Build()
+	fmt.Println("SQL:", sql)
+	fmt.Printf("Args: %v\n", args)
+
+	// Output:
+	// SQL: SELECT p.id, p.brand FROM product p WHERE p.brand = $1 AND EXISTS (SELECT 1 FROM feature f WHERE f.id = p.id AND f.brand = p.brand AND f.country = $2)
+	// Args: [Sony Japan]
+}
+
+// Example_mySQLDialect demonstrates building a query for MySQL
+func Example_mySQLDialect() {
+	qb := builder.NewSQLBuilder(builder.WithDialect(builder.MySQL)).
+		Select("id", "brand").
+		From("product").
+		WhereIn("brand", []string{"Apple", "Sony"}).
+		Limit(10)
+
+	sql, args := qb.Build()
+	fmt.Println("SQL:", sql)
+	fmt.Printf("Args: %v\n", args)
+
+	// Output:
+	// SQL: SELECT id, brand FROM product WHERE brand IN (?, ?) LIMIT 10
+	// Args: [Apple Sony]
+}
+
+// Example_returning demonstrates retrieving the generated id of an INSERT
+func Example_returning() {
+	qb := builder.NewSQLBuilder().
+		Insert("leave_request", "emp_no", "leave_type").
+		Values(10001, "annual").
+		Returning("id", "created_at")
+
+	sql, args := qb.Build()
+	fmt.Println("SQL:", sql)
+	fmt.Printf("Args: %v\n", args)
+
+	// Output:
+	// SQL: INSERT INTO leave_request (emp_no, leave_type) VALUES ($1, $2) RETURNING id, created_at
+	// Args: [10001 annual]
+}
+
 // Example9_PreparedStatementSelect demonstrates preparing a SELECT statement with placeholders.
 func Example_preparedStatementSelect() {
 	qb := builder.NewSQLBuilder().
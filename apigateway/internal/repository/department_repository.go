@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/database/dbtx"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/repository/builder"
+)
+
+type departmentRepository struct {
+	db *sql.DB
+}
+
+// NewDepartmentRepository creates a new instance of DepartmentRepository
+func NewDepartmentRepository(db *sql.DB) domain.DepartmentRepository {
+	return &departmentRepository{db: db}
+}
+
+func (r *departmentRepository) ListDepartments(ctx context.Context) ([]domain.Department, error) {
+	b := builder.NewSQLBuilder()
+	query, args := notDeleted(b.Select("dept_no", "dept_name").
+		From(departmentTable).
+		OrderBy("dept_no ASC"), "").
+		Build()
+
+	rows, err := dbtx.Conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var departments []domain.Department
+	for rows.Next() {
+		var d domain.Department
+		if err := rows.Scan(&d.DeptNo, &d.DeptName); err != nil {
+			return nil, err
+		}
+		departments = append(departments, d)
+	}
+	return departments, rows.Err()
+}
+
+func (r *departmentRepository) GetDepartment(ctx context.Context, deptNo string) (*domain.Department, error) {
+	b := builder.NewSQLBuilder()
+	query, args := notDeleted(b.Select("dept_no", "dept_name").
+		From(departmentTable).
+		Where("dept_no = ?", deptNo), "").
+		Build()
+
+	var d domain.Department
+	if err := dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&d.DeptNo, &d.DeptName); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// CreateDepartment replaces a deleted department with the same number. The
+// conflict clause skips live departments, reported as sql.ErrNoRows.
+func (r *departmentRepository) CreateDepartment(ctx context.Context, d *domain.Department) error {
+	actor := domain.ActorFrom(ctx)
+	b := builder.NewSQLBuilder()
+	query, args := b.Insert(departmentTable, "dept_no", "dept_name", "created_by", "updated_by").
+		Values(d.DeptNo, d.DeptName, actor, actor).
+		OnConflict("(dept_no)", "DO UPDATE SET dept_name = EXCLUDED.dept_name, created_by = EXCLUDED.created_by, updated_by = EXCLUDED.updated_by, deleted_at = NULL WHERE "+departmentTable+".deleted_at IS NOT NULL").
+		Build()
+
+	return execOne(ctx, r.db, query, args...)
+}
+
+func (r *departmentRepository) UpdateDepartment(ctx context.Context, d *domain.Department) error {
+	b := builder.NewSQLBuilder()
+	query, args := notDeleted(b.Update(departmentTable).
+		Set("dept_name", d.DeptName).
+		Set("updated_by", domain.ActorFrom(ctx)).
+		Where("dept_no = ?", d.DeptNo), "").
+		Build()
+
+	return execOne(ctx, r.db, query, args...)
+}
+
+func (r *departmentRepository) DeleteDepartment(ctx context.Context, deptNo string) error {
+	return softDelete(ctx, r.db, departmentTable, "dept_no = ?", deptNo)
+}
+
+func (r *departmentRepository) RestoreDepartment(ctx context.Context, deptNo string) error {
+	return restoreDeleted(ctx, r.db, departmentTable, "dept_no = ?", deptNo)
+}
+
+func (r *departmentRepository) ListManagers(ctx context.Context, deptNo string) ([]domain.DeptManager, error) {
+	b := builder.NewSQLBuilder()
+	query, args := b.Select("dept_no", "emp_no", "from_date", "to_date").
+		From(deptManagerTable).
+		Where("dept_no = ?", deptNo).
+		OrderBy("from_date DESC").
+		Build()
+
+	rows, err := dbtx.Conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var managers []domain.DeptManager
+	for rows.Next() {
+		var dm domain.DeptManager
+		if err := rows.Scan(&dm.DeptNo, &dm.EmpNo, &dm.FromDate, &dm.ToDate); err != nil {
+			return nil, err
+		}
+		managers = append(managers, dm)
+	}
+	return managers, rows.Err()
+}
+
+func (r *departmentRepository) AssignManager(ctx context.Context, m *domain.DeptManager) error {
+	return dbtx.WithinTx(ctx, r.db, func(ctx context.Context) error {
+		tx := dbtx.Conn(ctx, r.db)
+		b := builder.NewSQLBuilder()
+		query, args := b.Update(deptManagerTable).
+			Set("to_date", m.FromDate).
+			Where("dept_no = ? AND to_date = ?", m.DeptNo, "9999-01-01").
+			Build()
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("failed to end current manager of %s: %w", m.DeptNo, err)
+		}
+
+		b = builder.NewSQLBuilder()
+		query, args = b.Insert(deptManagerTable, "dept_no", "emp_no", "from_date", "to_date").
+			Values(m.DeptNo, m.EmpNo, m.FromDate, m.ToDate).
+			Build()
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("failed to assign manager of %s: %w", m.DeptNo, err)
+		}
+
+		return nil
+	})
+}
+
+func (r *departmentRepository) TerminateManager(ctx context.Context, deptNo string, empNo int, toDate time.Time) error {
+	b := builder.NewSQLBuilder()
+	query, args := b.Update(deptManagerTable).
+		Set("to_date", toDate).
+		Where("dept_no = ? AND emp_no = ? AND to_date = ?", deptNo, empNo, "9999-01-01").
+		Build()
+
+	return execOne(ctx, r.db, query, args...)
+}
+
+func (r *departmentRepository) DeleteManager(ctx context.Context, deptNo string, empNo int, fromDate time.Time) error {
+	b := builder.NewSQLBuilder()
+	query, args := b.Delete(deptManagerTable).
+		Where("dept_no = ? AND emp_no = ? AND from_date = ?", deptNo, empNo, fromDate).
+		Build()
+
+	return execOne(ctx, r.db, query, args...)
+}
+
+// execOne runs a statement expected to affect a row, returning sql.ErrNoRows
+// when it affected none.
+func execOne(ctx context.Context, db *sql.DB, query string, args ...interface{}) error {
+	res, err := dbtx.Conn(ctx, db).ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -3,9 +3,16 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/locvowork/employee_management_sample/apigateway/internal/database/dbtx"
 	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
 	"github.com/locvowork/employee_management_sample/apigateway/internal/repository/builder"
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/faultinject"
 )
 
 var (
@@ -13,10 +20,21 @@ var (
 	salaryTable      = "employees.salary"
 	deptEmpTable     = "employees.dept_emp"
 	deptManagerTable = "employees.dept_manager"
+	departmentTable  = "employees.department"
 )
 
+// FaultEmployeeChunkQuery delays or fails the ListChunks page queries, see
+// package faultinject.
+const FaultEmployeeChunkQuery = "repository.employee_chunk_query"
+
 type employeeRepository struct {
 	db *sql.DB
+
+	// chunkStmt is the prepared ListChunks page query, shared by all exports
+	stmtMu    sync.Mutex
+	chunkStmt *sql.Stmt
+	prepares  int64
+	execs     int64
 }
 
 // NewEmployeeRepository creates a new instance of EmployeeRepository
@@ -25,34 +43,40 @@ func NewEmployeeRepository(db *sql.DB) domain.EmployeeRepository {
 }
 
 func (r *employeeRepository) Create(ctx context.Context, e *domain.Employee) error {
+	actor := domain.ActorFrom(ctx)
 	b := builder.NewSQLBuilder()
-	query, args := b.Insert(employeeTable, "id", "birth_date", "first_name", "last_name", "gender", "hire_date").
-		Values(e.ID, e.BirthDate, e.FirstName, e.LastName, e.Gender, e.HireDate).
+	query, args := b.Insert(employeeTable, "id", "birth_date", "first_name", "last_name", "gender", "hire_date", "created_by", "updated_by").
+		Values(e.ID, e.BirthDate, e.FirstName, e.LastName, e.Gender, e.HireDate, actor, actor).
 		Build()
 
-	_, err := r.db.ExecContext(ctx, query, args...)
+	_, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, args...)
 	return err
 }
 
+// employeeUpsertAction overwrites an existing employee, restoring it if it
+// was deleted; created_by keeps its original value.
+const employeeUpsertAction = "DO UPDATE SET birth_date = EXCLUDED.birth_date, first_name = EXCLUDED.first_name, last_name = EXCLUDED.last_name, gender = EXCLUDED.gender, hire_date = EXCLUDED.hire_date, updated_by = EXCLUDED.updated_by, deleted_at = NULL"
+
 func (r *employeeRepository) Upsert(ctx context.Context, e *domain.Employee) error {
+	actor := domain.ActorFrom(ctx)
 	b := builder.NewSQLBuilder()
-	query, args := b.Insert(employeeTable, "id", "birth_date", "first_name", "last_name", "gender", "hire_date").
-		Values(e.ID, e.BirthDate, e.FirstName, e.LastName, e.Gender, e.HireDate).
-		OnConflict("(id) DO UPDATE SET birth_date = EXCLUDED.birth_date, first_name = EXCLUDED.first_name, last_name = EXCLUDED.last_name, gender = EXCLUDED.gender, hire_date = EXCLUDED.hire_date").
+	query, args := b.Insert(employeeTable, "id", "birth_date", "first_name", "last_name", "gender", "hire_date", "created_by", "updated_by").
+		Values(e.ID, e.BirthDate, e.FirstName, e.LastName, e.Gender, e.HireDate, actor, actor).
+		OnConflict("(id)", employeeUpsertAction).
 		Build()
 
-	_, err := r.db.ExecContext(ctx, query, args...)
+	_, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, args...)
 	return err
 }
 
 func (r *employeeRepository) GetByID(ctx context.Context, id int) (*domain.Employee, error) {
 	b := builder.NewSQLBuilder()
-	query, args := b.Select("id", "birth_date", "first_name", "last_name", "gender", "hire_date").
+	query, args := notDeleted(b.Select("id", "birth_date", "first_name", "last_name", "gender", "hire_date").
 		From(employeeTable).
-		Where("id = ?", id).
+		Where("id = ?", id), "").
 		Build()
 
-	row := r.db.QueryRowContext(ctx, query, args...)
+	row := dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...)
 	var e domain.Employee
 	if err := row.Scan(&e.ID, &e.BirthDate, &e.FirstName, &e.LastName, &e.Gender, &e.HireDate); err != nil {
 		return nil, err
@@ -62,25 +86,81 @@ func (r *employeeRepository) GetByID(ctx context.Context, id int) (*domain.Emplo
 
 func (r *employeeRepository) Update(ctx context.Context, e *domain.Employee) error {
 	b := builder.NewSQLBuilder()
-	query, args := b.Update(employeeTable).
+	query, args := notDeleted(b.Update(employeeTable).
 		Set("first_name", e.FirstName).
 		Set("last_name", e.LastName).
 		Set("gender", e.Gender).
-		Where("id = ?", e.ID).
+		Set("updated_by", domain.ActorFrom(ctx)).
+		Where("id = ?", e.ID), "").
 		Build()
 
-	_, err := r.db.ExecContext(ctx, query, args...)
+	_, err := dbtx.Conn(ctx, r.db).ExecContext(ctx, query, args...)
 	return err
 }
 
+// BatchUpdate updates the same columns as Update for every employee inside
+// one transaction.
+func (r *employeeRepository) BatchUpdate(ctx context.Context, employees []domain.Employee) error {
+	return dbtx.WithinTx(ctx, r.db, func(ctx context.Context) error {
+		tx := dbtx.Conn(ctx, r.db)
+		actor := domain.ActorFrom(ctx)
+		for i, e := range employees {
+			b := builder.NewSQLBuilder()
+			query, args := notDeleted(b.Update(employeeTable).
+				Set("first_name", e.FirstName).
+				Set("last_name", e.LastName).
+				Set("gender", e.Gender).
+				Set("updated_by", actor).
+				Where("id = ?", e.ID), "").
+				Build()
+
+			res, err := tx.ExecContext(ctx, query, args...)
+			if err != nil {
+				return &domain.BatchItemError{Index: i, Err: err}
+			}
+			if n, err := res.RowsAffected(); err == nil && n == 0 {
+				return &domain.BatchItemError{Index: i, Err: sql.ErrNoRows}
+			}
+		}
+
+		return nil
+	})
+}
+
+// BatchUpsert runs one multi-row INSERT ... ON CONFLICT. xmax is zero on the
+// rows the statement inserted and set on the ones it updated.
+func (r *employeeRepository) BatchUpsert(ctx context.Context, employees []domain.Employee) (map[int]bool, error) {
+	actor := domain.ActorFrom(ctx)
+	b := builder.NewSQLBuilder().Insert(employeeTable, "id", "birth_date", "first_name", "last_name", "gender", "hire_date", "created_by", "updated_by")
+	for _, e := range employees {
+		b.Values(e.ID, e.BirthDate, e.FirstName, e.LastName, e.Gender, e.HireDate, actor, actor)
+	}
+	query, args := b.OnConflict("(id)", employeeUpsertAction).Returning("id", "xmax = 0").Build()
+
+	rows, err := dbtx.Conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	inserted := make(map[int]bool, len(employees))
+	for rows.Next() {
+		var id int
+		var created bool
+		if err := rows.Scan(&id, &created); err != nil {
+			return nil, err
+		}
+		inserted[id] = created
+	}
+	return inserted, rows.Err()
+}
+
 func (r *employeeRepository) Delete(ctx context.Context, id int) error {
-	b := builder.NewSQLBuilder()
-	query, args := b.Delete(employeeTable).
-		Where("id = ?", id).
-		Build()
+	return softDelete(ctx, r.db, employeeTable, "id = ?", id)
+}
 
-	_, err := r.db.ExecContext(ctx, query, args...)
-	return err
+func (r *employeeRepository) Restore(ctx context.Context, id int) error {
+	return restoreDeleted(ctx, r.db, employeeTable, "id = ?", id)
 }
 
 func (r *employeeRepository) List(ctx context.Context, filter domain.EmployeeFilter) ([]domain.Employee, error) {
@@ -88,6 +168,12 @@ func (r *employeeRepository) List(ctx context.Context, filter domain.EmployeeFil
 	b.Select("id", "birth_date", "first_name", "last_name", "gender", "hire_date").
 		From(employeeTable).
 		OrderBy("id ASC")
+	notDeleted(b, "")
+	whereEmployeeFilter(b, filter)
+	if filter.AfterID > 0 {
+		// Keyset pagination, stays fast however deep the page
+		b.Where("id > ?", filter.AfterID)
+	}
 
 	if filter.Limit > 0 {
 		b.Limit(filter.Limit)
@@ -97,7 +183,7 @@ func (r *employeeRepository) List(ctx context.Context, filter domain.EmployeeFil
 	}
 
 	query, args := b.Build()
-	rows, err := r.db.QueryContext(ctx, query, args...)
+	rows, err := dbtx.Read(ctx, r.db).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -114,6 +200,165 @@ func (r *employeeRepository) List(ctx context.Context, filter domain.EmployeeFil
 	return employees, nil
 }
 
+func (r *employeeRepository) ListChunks(ctx context.Context, filter domain.EmployeeFilter, chunkSize int, fn func([]domain.Employee) error) error {
+	if chunkSize <= 0 {
+		return fmt.Errorf("chunk size must be positive, got %d", chunkSize)
+	}
+	// Unfiltered exports share the prepared page query, filtered ones build
+	// their page query each time. The statement is prepared on the primary,
+	// exports read from the replica build theirs too
+	query := r.filteredChunk(filter)
+	if !filter.HasCriteria() && !dbtx.OnReplica(ctx, r.db) {
+		stmt, err := r.chunkStatement(ctx)
+		if err != nil {
+			return err
+		}
+		query = func(ctx context.Context, lastID, size, offset int) (rows *sql.Rows, err error) {
+			err = dbtx.Retry(ctx, r.db, func() error {
+				rows, err = dbtx.Stmt(ctx, r.db, stmt).QueryContext(ctx, lastID, size, offset)
+				return err
+			})
+			return rows, err
+		}
+	}
+
+	// Keyset pagination: each chunk starts after the last id seen, the
+	// offset only applies to the first one
+	lastID, offset, remaining := filter.AfterID, filter.Offset, filter.Limit
+	for {
+		size := chunkSize
+		if filter.Limit > 0 && remaining < size {
+			size = remaining
+		}
+		if size == 0 {
+			return nil
+		}
+
+		// A fault here stands for a slow or failing chunk query
+		if err := faultinject.Hit(ctx, FaultEmployeeChunkQuery); err != nil {
+			return fmt.Errorf("failed to fetch employees after id %d: %w", lastID, err)
+		}
+		atomic.AddInt64(&r.execs, 1)
+		rows, err := query(ctx, lastID, size, offset)
+		if err != nil {
+			return fmt.Errorf("failed to fetch employees after id %d: %w", lastID, err)
+		}
+		chunk := make([]domain.Employee, 0, size)
+		for rows.Next() {
+			var e domain.Employee
+			if err := rows.Scan(&e.ID, &e.BirthDate, &e.FirstName, &e.LastName, &e.Gender, &e.HireDate); err != nil {
+				rows.Close()
+				return err
+			}
+			chunk = append(chunk, e)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(chunk) == 0 {
+			return nil
+		}
+
+		if err := fn(chunk); err != nil {
+			return err
+		}
+		if len(chunk) < size {
+			return nil
+		}
+		lastID, offset = chunk[len(chunk)-1].ID, 0
+		remaining -= len(chunk)
+	}
+}
+
+// filteredChunk returns the ListChunks page query for a filter with criteria.
+func (r *employeeRepository) filteredChunk(filter domain.EmployeeFilter) func(ctx context.Context, lastID, size, offset int) (*sql.Rows, error) {
+	return func(ctx context.Context, lastID, size, offset int) (*sql.Rows, error) {
+		b := builder.NewSQLBuilder()
+		b.Select("id", "birth_date", "first_name", "last_name", "gender", "hire_date").
+			From(employeeTable).
+			Where("id > ?", lastID).
+			OrderBy("id ASC").
+			Limit(size).
+			Offset(offset)
+		notDeleted(b, "")
+		whereEmployeeFilter(b, filter)
+		query, args := b.Build()
+		return dbtx.Read(ctx, r.db).QueryContext(ctx, query, args...)
+	}
+}
+
+// whereEmployeeFilter adds the criteria of filter to a query on the employee table.
+func whereEmployeeFilter(b *builder.SQLBuilder, filter domain.EmployeeFilter) *builder.SQLBuilder {
+	if filter.NamePrefix != "" {
+		prefix := likePrefix(filter.NamePrefix)
+		b.Where("(first_name ILIKE ? OR last_name ILIKE ?)", prefix, prefix)
+	}
+	if filter.Gender != "" {
+		b.Where("gender = ?", filter.Gender)
+	}
+	if !filter.HiredFrom.IsZero() {
+		b.Where("hire_date >= ?", filter.HiredFrom)
+	}
+	if !filter.HiredTo.IsZero() {
+		b.Where("hire_date <= ?", filter.HiredTo)
+	}
+	if filter.DeptNo != "" {
+		b.WhereExists(builder.NewSQLBuilder().Select("1").
+			From(deptEmpTable+" de").
+			Where("de.emp_no = "+employeeTable+".id").
+			Where("de.dept_no = ?", filter.DeptNo).
+			Where("de.to_date = ?", "9999-01-01"))
+	}
+	return b
+}
+
+// likePrefix returns the LIKE pattern matching values starting with prefix,
+// its wildcards taken literally.
+func likePrefix(prefix string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(prefix) + "%"
+}
+
+func (r *employeeRepository) PrepareStatements(ctx context.Context) error {
+	_, err := r.chunkStatement(ctx)
+	return err
+}
+
+func (r *employeeRepository) StatementStats() domain.StatementStats {
+	return domain.StatementStats{
+		Prepares: atomic.LoadInt64(&r.prepares),
+		Execs:    atomic.LoadInt64(&r.execs),
+	}
+}
+
+// chunkStatement returns the ListChunks page query, preparing it on first use.
+// *sql.Stmt is safe for concurrent use and re-prepares itself on new pool
+// connections, so one statement serves every export.
+func (r *employeeRepository) chunkStatement(ctx context.Context) (*sql.Stmt, error) {
+	r.stmtMu.Lock()
+	defer r.stmtMu.Unlock()
+	if r.chunkStmt != nil {
+		return r.chunkStmt, nil
+	}
+
+	b := builder.NewSQLBuilder()
+	query, _ := notDeleted(b.Select("id", "birth_date", "first_name", "last_name", "gender", "hire_date").
+		From(employeeTable).
+		Where("id > ?", 0).
+		OrderBy("id ASC"), "").
+		Build()
+	// The builder inlines LIMIT/OFFSET, bind them instead so the statement is reusable
+	query += " LIMIT $2 OFFSET $3"
+
+	atomic.AddInt64(&r.prepares, 1)
+	stmt, err := r.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare employee chunk query: %w", err)
+	}
+	r.chunkStmt = stmt
+	return stmt, nil
+}
+
 func (r *employeeRepository) GetCurrentSalary(ctx context.Context, empID int) (*domain.Salary, error) {
 	// Business logic: Current salary has to_date = '9999-01-01'
 	b := builder.NewSQLBuilder()
@@ -122,7 +367,7 @@ func (r *employeeRepository) GetCurrentSalary(ctx context.Context, empID int) (*
 		Where("employee_id = ? AND to_date = ?", empID, "9999-01-01").
 		Build()
 
-	row := r.db.QueryRowContext(ctx, query, args...)
+	row := dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...)
 	var s domain.Salary
 	if err := row.Scan(&s.EmployeeID, &s.Salary, &s.FromDate, &s.ToDate); err != nil {
 		return nil, err
@@ -138,7 +383,7 @@ func (r *employeeRepository) GetDepartmentHistory(ctx context.Context, empID int
 		OrderBy("from_date DESC").
 		Build()
 
-	rows, err := r.db.QueryContext(ctx, query, args...)
+	rows, err := dbtx.Conn(ctx, r.db).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -163,7 +408,7 @@ func (r *employeeRepository) GetManagers(ctx context.Context, deptNo string) ([]
 		OrderBy("from_date DESC").
 		Build()
 
-	rows, err := r.db.QueryContext(ctx, query, args...)
+	rows, err := dbtx.Conn(ctx, r.db).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -180,6 +425,122 @@ func (r *employeeRepository) GetManagers(ctx context.Context, deptNo string) ([]
 	return managers, nil
 }
 
+func (r *employeeRepository) ListDepartmentEmployees(ctx context.Context, deptNo string) ([]domain.Employee, error) {
+	b := builder.NewSQLBuilder()
+	b.Select("e.id", "e.birth_date", "e.first_name", "e.last_name", "e.gender", "e.hire_date").
+		From(employeeTable+" e").
+		Join("INNER", deptEmpTable+" de", "de.emp_no = e.id").
+		Where("de.dept_no = ? AND de.to_date = ?", deptNo, "9999-01-01").
+		OrderBy("e.id ASC")
+	query, args := notDeleted(b, "e").Build()
+
+	rows, err := dbtx.Read(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var employees []domain.Employee
+	for rows.Next() {
+		var e domain.Employee
+		if err := rows.Scan(&e.ID, &e.BirthDate, &e.FirstName, &e.LastName, &e.Gender, &e.HireDate); err != nil {
+			return nil, err
+		}
+		employees = append(employees, e)
+	}
+	return employees, nil
+}
+
+func (r *employeeRepository) ListDepartmentSalaries(ctx context.Context, deptNo string) ([]domain.Salary, error) {
+	b := builder.NewSQLBuilder()
+	b.Select("s.employee_id", "s.salary", "s.from_date", "s.to_date").
+		From(salaryTable+" s").
+		Join("INNER", deptEmpTable+" de", "de.emp_no = s.employee_id").
+		Join("INNER", employeeTable+" e", "e.id = s.employee_id").
+		Where("de.dept_no = ? AND de.to_date = ? AND s.to_date = ?", deptNo, "9999-01-01", "9999-01-01").
+		OrderBy("s.employee_id ASC")
+	query, args := notDeleted(b, "e").Build()
+
+	rows, err := dbtx.Read(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var salaries []domain.Salary
+	for rows.Next() {
+		var s domain.Salary
+		if err := rows.Scan(&s.EmployeeID, &s.Salary, &s.FromDate, &s.ToDate); err != nil {
+			return nil, err
+		}
+		salaries = append(salaries, s)
+	}
+	return salaries, rows.Err()
+}
+
+func (r *employeeRepository) ListDepartments(ctx context.Context) ([]domain.Department, error) {
+	b := builder.NewSQLBuilder()
+	query, args := notDeleted(b.Select("dept_no", "dept_name").
+		From(departmentTable).
+		OrderBy("dept_no ASC"), "").
+		Build()
+
+	rows, err := dbtx.Read(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var departments []domain.Department
+	for rows.Next() {
+		var d domain.Department
+		if err := rows.Scan(&d.DeptNo, &d.DeptName); err != nil {
+			return nil, err
+		}
+		departments = append(departments, d)
+	}
+	return departments, rows.Err()
+}
+
+// headcountByMonthQuery counts the dept_emp rows of employees not deleted
+// open on the last day of each month. The employee is joined inside the outer
+// join, so departments left without anyone still count 0. The month series
+// and the joins on it are beyond the SQL builder, so the query is written by
+// hand.
+var headcountByMonthQuery = `
+		SELECT to_char(m.month, 'YYYY-MM'), d.dept_no, d.dept_name, COUNT(de.emp_no)
+		FROM generate_series(date_trunc('month', $1::date), date_trunc('month', $2::date), interval '1 month') AS m(month)
+		CROSS JOIN ` + departmentTable + ` d
+		LEFT JOIN (` + deptEmpTable + ` de
+			JOIN ` + employeeTable + ` e ON e.id = de.emp_no AND e.deleted_at IS NULL)
+			ON de.dept_no = d.dept_no
+			AND de.from_date <= (m.month + interval '1 month - 1 day')::date
+			AND de.to_date > (m.month + interval '1 month - 1 day')::date
+		WHERE d.deleted_at IS NULL
+		GROUP BY m.month, d.dept_no, d.dept_name
+		ORDER BY m.month ASC, d.dept_no ASC
+	`
+
+// CountHeadcountByMonth counts the employees of every department on the last
+// day of each month, see headcountByMonthQuery.
+func (r *employeeRepository) CountHeadcountByMonth(ctx context.Context, from, to time.Time) ([]domain.HeadcountPoint, error) {
+	rows, err := dbtx.Read(ctx, r.db).QueryContext(ctx, headcountByMonthQuery, from.Format("2006-01-02"), to.Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var points []domain.HeadcountPoint
+	for rows.Next() {
+		var p domain.HeadcountPoint
+		if err := rows.Scan(&p.Month, &p.DeptNo, &p.DeptName, &p.Headcount); err != nil {
+			return nil, err
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
 func (r *employeeRepository) GetTitle(ctx context.Context, empID int) (*domain.Title, error) {
 	b := builder.NewSQLBuilder()
 	query, args := b.Select("emp_no", "title", "from_date", "to_date").
@@ -187,7 +548,7 @@ func (r *employeeRepository) GetTitle(ctx context.Context, empID int) (*domain.T
 		Where("emp_no = ? AND to_date = ?", empID, "9999-01-01").
 		Build()
 
-	row := r.db.QueryRowContext(ctx, query, args...)
+	row := dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...)
 	var t domain.Title
 	if err := row.Scan(&t.EmpNo, &t.Title, &t.FromDate, &t.ToDate); err != nil {
 		return nil, err
@@ -0,0 +1,32 @@
+//go:build faultinject
+
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/faultinject"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListChunks_SlowQueryTimesOut(t *testing.T) {
+	// The first page is fast, the second one hangs
+	defer faultinject.Enable(FaultEmployeeChunkQuery, faultinject.Fault{Delay: time.Minute, Skip: 1})()
+	repo, d := newChunkRepo(t, []int{1, 2, 3, 4, 5})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	var chunks int
+	err := repo.ListChunks(ctx, domain.EmployeeFilter{}, 2, func(chunk []domain.Employee) error {
+		chunks++
+		return nil
+	})
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Contains(t, err.Error(), "after id 2")
+	assert.Equal(t, 1, chunks)
+	assert.Len(t, d.queries, 1)
+}
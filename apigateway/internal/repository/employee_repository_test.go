@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/repository/builder"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// chunkDriver serves "SELECT ... WHERE id > $1 ... LIMIT $2 OFFSET $3" from
+// an in-memory list of ids and records what the driver was asked to do.
+type chunkDriver struct {
+	mu       sync.Mutex
+	ids      []int
+	prepared []string
+	queries  [][]driver.Value
+}
+
+func (d *chunkDriver) Open(name string) (driver.Conn, error) { return &chunkConn{d: d}, nil }
+
+type chunkConn struct{ d *chunkDriver }
+
+func (c *chunkConn) Prepare(query string) (driver.Stmt, error) {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+	c.d.prepared = append(c.d.prepared, query)
+	return &chunkStmt{d: c.d}, nil
+}
+func (c *chunkConn) Close() error              { return nil }
+func (c *chunkConn) Begin() (driver.Tx, error) { return nil, driver.ErrSkip }
+
+type chunkStmt struct{ d *chunkDriver }
+
+func (s *chunkStmt) Close() error  { return nil }
+func (s *chunkStmt) NumInput() int { return 3 }
+func (s *chunkStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, driver.ErrSkip
+}
+func (s *chunkStmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.d.mu.Lock()
+	defer s.d.mu.Unlock()
+	s.d.queries = append(s.d.queries, args)
+	after, limit, offset := args[0].(int64), args[1].(int64), args[2].(int64)
+	var ids []int
+	for _, id := range s.d.ids {
+		if int64(id) > after {
+			ids = append(ids, id)
+		}
+	}
+	if int(offset) < len(ids) {
+		ids = ids[offset:]
+	} else {
+		ids = nil
+	}
+	if int(limit) < len(ids) {
+		ids = ids[:limit]
+	}
+	return &chunkRows{ids: ids}, nil
+}
+
+type chunkRows struct {
+	ids []int
+	pos int
+}
+
+func (r *chunkRows) Columns() []string {
+	return []string{"id", "birth_date", "first_name", "last_name", "gender", "hire_date"}
+}
+func (r *chunkRows) Close() error { return nil }
+func (r *chunkRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.ids) {
+		return io.EOF
+	}
+	now := time.Now()
+	dest[0], dest[1], dest[2], dest[3], dest[4], dest[5] = int64(r.ids[r.pos]), now, "First", "Last", "M", now
+	r.pos++
+	return nil
+}
+
+func newChunkRepo(t *testing.T, ids []int) (domain.EmployeeRepository, *chunkDriver) {
+	d := &chunkDriver{ids: ids}
+	db := sql.OpenDB(connector{d})
+	t.Cleanup(func() { db.Close() })
+	return NewEmployeeRepository(db), d
+}
+
+type connector struct{ d *chunkDriver }
+
+func (c connector) Connect(context.Context) (driver.Conn, error) { return c.d.Open("") }
+func (c connector) Driver() driver.Driver                        { return c.d }
+
+func TestListChunks_ReusesPreparedStatement(t *testing.T) {
+	repo, d := newChunkRepo(t, []int{1, 2, 3, 5, 8, 13, 21})
+
+	var got [][]int
+	for i := 0; i < 2; i++ {
+		got = nil
+		err := repo.ListChunks(context.Background(), domain.EmployeeFilter{Offset: 1}, 2, func(chunk []domain.Employee) error {
+			ids := make([]int, len(chunk))
+			for j, e := range chunk {
+				ids[j] = e.ID
+			}
+			got = append(got, ids)
+			return nil
+		})
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, [][]int{{2, 3}, {5, 8}, {13, 21}}, got)
+	assert.Len(t, d.prepared, 1, "the page query is prepared once for all chunks and exports")
+	assert.Equal(t, domain.StatementStats{Prepares: 1, Execs: 8}, repo.StatementStats())
+	// Keyset pagination, the offset only applies to the first chunk
+	assert.Equal(t, []driver.Value{int64(0), int64(2), int64(1)}, d.queries[0])
+	assert.Equal(t, []driver.Value{int64(3), int64(2), int64(0)}, d.queries[1])
+}
+
+func TestListChunks_Limit(t *testing.T) {
+	repo, _ := newChunkRepo(t, []int{1, 2, 3, 4, 5})
+
+	var total int
+	err := repo.ListChunks(context.Background(), domain.EmployeeFilter{Limit: 3}, 2, func(chunk []domain.Employee) error {
+		total += len(chunk)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+}
+
+func TestWhereEmployeeFilter(t *testing.T) {
+	from := time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := builder.NewSQLBuilder()
+	b.Select("id").From(employeeTable)
+	whereEmployeeFilter(b, domain.EmployeeFilter{NamePrefix: "Ge_", Gender: "F", HiredFrom: from, DeptNo: "d005"})
+
+	query, args := b.Build()
+	assert.Equal(t, "SELECT id FROM employees.employee WHERE (first_name ILIKE $1 OR last_name ILIKE $2) AND gender = $3 AND hire_date >= $4"+
+		" AND EXISTS (SELECT 1 FROM employees.dept_emp de WHERE de.emp_no = employees.employee.id AND de.dept_no = $5 AND de.to_date = $6)", query)
+	assert.Equal(t, []interface{}{`Ge\_%`, `Ge\_%`, "F", from, "d005", "9999-01-01"}, args)
+}
+
+func TestHeadcountByMonthQuery_SkipsDeletedEmployees(t *testing.T) {
+	query := strings.Join(strings.Fields(headcountByMonthQuery), " ")
+
+	// The employee is joined inside the outer join: a deleted employee drops
+	// their dept_emp row, not the department from the month.
+	assert.Contains(t, query, "LEFT JOIN (employees.dept_emp de JOIN employees.employee e ON e.id = de.emp_no AND e.deleted_at IS NULL) ON de.dept_no = d.dept_no")
+	assert.Contains(t, query, "COUNT(de.emp_no)")
+	assert.Contains(t, query, "WHERE d.deleted_at IS NULL GROUP BY")
+}
@@ -4,9 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"strings"
 
 	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/repository/builder"
 )
 
 // FeatureRepository manages feature database operations
@@ -56,20 +56,12 @@ func (r *FeatureRepository) GetByBrands(ctx context.Context, brands []string) ([
 		return []domain.Feature{}, nil
 	}
 
-	// Build placeholders for IN clause: ($1, $2, $3, ...)
-	placeholders := make([]string, len(brands))
-	args := make([]interface{}, len(brands))
-	for i, brand := range brands {
-		placeholders[i] = fmt.Sprintf("$%d", i+1)
-		args[i] = brand
-	}
-
-	query := fmt.Sprintf(`
-		SELECT id, brand, country, content, sub_number
-		FROM feature
-		WHERE brand IN (%s)
-		ORDER BY brand, id, country, sub_number
-	`, strings.Join(placeholders, ","))
+	query, args := builder.NewSQLBuilder().
+		Select("id", "brand", "country", "content", "sub_number").
+		From("feature").
+		WhereIn("brand", brands).
+		OrderBy("brand, id, country, sub_number").
+		Build()
 
 	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
@@ -192,10 +184,10 @@ func (r *FeatureRepository) GetByProduct(ctx context.Context, id int64, brand, c
 
 // Count returns the total number of features
 func (r *FeatureRepository) Count(ctx context.Context) (int, error) {
-	query := "SELECT COUNT(*) FROM feature"
+	query, args := builder.NewSQLBuilder().Select(builder.Count("*")).From("feature").Build()
 
 	var count int
-	err := r.db.QueryRowContext(ctx, query).Scan(&count)
+	err := r.db.QueryRowContext(ctx, query, args...).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to count features: %w", err)
 	}
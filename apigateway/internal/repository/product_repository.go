@@ -46,7 +46,7 @@ func (r *ProductRepository) GetByID(ctx context.Context, id int64, brand string)
 	err := r.db.QueryRowContext(ctx, query, id, brand).Scan(&product.ID, &product.Brand, &product.Revision)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("product not found")
+			return nil, fmt.Errorf("product not found: %w", err)
 		}
 		return nil, fmt.Errorf("failed to get product: %w", err)
 	}
@@ -115,6 +115,50 @@ func (r *ProductRepository) GetAll(ctx context.Context) ([]domain.Product, error
 	return products, nil
 }
 
+// ListPage retrieves at most limit products ordered by brand and id, starting
+// after the (brand, id) key of after when it is not nil
+func (r *ProductRepository) ListPage(ctx context.Context, after *domain.Product, limit int) ([]domain.Product, error) {
+	query := `
+		SELECT id, brand, revision
+		FROM product
+		ORDER BY brand, id
+		LIMIT $1
+	`
+	args := []interface{}{limit}
+	if after != nil {
+		// Row comparison matches the sort order and uses the (brand, id) key
+		query = `
+		SELECT id, brand, revision
+		FROM product
+		WHERE (brand, id) > ($2, $3)
+		ORDER BY brand, id
+		LIMIT $1
+	`
+		args = append(args, after.Brand, after.ID)
+	}
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product page: %w", err)
+	}
+	defer rows.Close()
+
+	var products []domain.Product
+	for rows.Next() {
+		var product domain.Product
+		if err := rows.Scan(&product.ID, &product.Brand, &product.Revision); err != nil {
+			return nil, fmt.Errorf("failed to scan product: %w", err)
+		}
+		products = append(products, product)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("row iteration error: %w", err)
+	}
+
+	return products, nil
+}
+
 // UpdateRevision increments the revision for a product
 func (r *ProductRepository) UpdateRevision(ctx context.Context, id int64, brand string) error {
 	query := `
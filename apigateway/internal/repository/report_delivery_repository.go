@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/database/dbtx"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/repository/builder"
+)
+
+var reportDeliveryTable = "employees.report_delivery"
+
+var reportDeliveryColumns = []string{"id", "run_id", "step_id", "idempotency_key", "target", "attempt", "status", "error", "data_as_of", "created_at"}
+
+type reportDeliveryRepository struct {
+	db *sql.DB
+}
+
+// NewReportDeliveryRepository creates a new instance of ReportDeliveryRepository
+func NewReportDeliveryRepository(db *sql.DB) domain.ReportDeliveryRepository {
+	return &reportDeliveryRepository{db: db}
+}
+
+func (r *reportDeliveryRepository) Record(ctx context.Context, d *domain.ReportDelivery) error {
+	b := builder.NewSQLBuilder()
+	query, args := b.Insert(reportDeliveryTable, "run_id", "step_id", "idempotency_key", "target", "attempt", "status", "error", "data_as_of").
+		Values(d.RunID, d.StepID, d.IdempotencyKey, d.Target, d.Attempt, d.Status, d.Error, d.DataAsOf).
+		Returning("id", "created_at").
+		Build()
+
+	return dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&d.ID, &d.CreatedAt)
+}
+
+func (r *reportDeliveryRepository) FindDelivered(ctx context.Context, idempotencyKey string) (*domain.ReportDelivery, error) {
+	b := builder.NewSQLBuilder()
+	query, args := b.Select(reportDeliveryColumns...).
+		From(reportDeliveryTable).
+		Where("idempotency_key = ? AND status = ?", idempotencyKey, domain.DeliveryDelivered).
+		OrderBy("id ASC").
+		Limit(1).
+		Build()
+	return scanReportDelivery(dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...))
+}
+
+func (r *reportDeliveryRepository) LatestDelivered(ctx context.Context, target string) (*domain.ReportDelivery, error) {
+	b := builder.NewSQLBuilder()
+	query, args := b.Select(reportDeliveryColumns...).
+		From(reportDeliveryTable).
+		Where("target = ? AND status = ?", target, domain.DeliveryDelivered).
+		OrderBy("data_as_of DESC, id DESC").
+		Limit(1).
+		Build()
+	return scanReportDelivery(dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...))
+}
+
+func (r *reportDeliveryRepository) ListByRun(ctx context.Context, runID string) ([]domain.ReportDelivery, error) {
+	b := builder.NewSQLBuilder()
+	query, args := b.Select(reportDeliveryColumns...).
+		From(reportDeliveryTable).
+		Where("run_id = ?", runID).
+		OrderBy("id ASC").
+		Build()
+
+	rows, err := dbtx.Conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var deliveries []domain.ReportDelivery
+	for rows.Next() {
+		d, err := scanReportDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, rows.Err()
+}
+
+// scanReportDelivery scans the reportDeliveryColumns of a row.
+func scanReportDelivery(row interface {
+	Scan(dest ...interface{}) error
+}) (*domain.ReportDelivery, error) {
+	var d domain.ReportDelivery
+	if err := row.Scan(&d.ID, &d.RunID, &d.StepID, &d.IdempotencyKey, &d.Target, &d.Attempt, &d.Status, &d.Error, &d.DataAsOf, &d.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/database/dbtx"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/repository/builder"
+)
+
+type salaryRepository struct {
+	db *sql.DB
+}
+
+// NewSalaryRepository creates a new instance of SalaryRepository
+func NewSalaryRepository(db *sql.DB) domain.SalaryRepository {
+	return &salaryRepository{db: db}
+}
+
+func (r *salaryRepository) ListSalaries(ctx context.Context, empNo int) ([]domain.Salary, error) {
+	b := builder.NewSQLBuilder()
+	query, args := b.Select("employee_id", "salary", "from_date", "to_date").
+		From(salaryTable).
+		Where("employee_id = ?", empNo).
+		OrderBy("from_date DESC").
+		Build()
+
+	rows, err := dbtx.Conn(ctx, r.db).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var salaries []domain.Salary
+	for rows.Next() {
+		var s domain.Salary
+		if err := rows.Scan(&s.EmployeeID, &s.Salary, &s.FromDate, &s.ToDate); err != nil {
+			return nil, err
+		}
+		salaries = append(salaries, s)
+	}
+	return salaries, rows.Err()
+}
+
+func (r *salaryRepository) GetCurrentSalary(ctx context.Context, empNo int) (*domain.Salary, error) {
+	b := builder.NewSQLBuilder()
+	query, args := b.Select("employee_id", "salary", "from_date", "to_date").
+		From(salaryTable).
+		Where("employee_id = ? AND to_date = ?", empNo, "9999-01-01").
+		Build()
+
+	var s domain.Salary
+	if err := dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query, args...).Scan(&s.EmployeeID, &s.Salary, &s.FromDate, &s.ToDate); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// LockSalaries locks the employee row rather than the salary rows, which
+// also holds back the first salary of an employee without any yet.
+func (r *salaryRepository) LockSalaries(ctx context.Context, empNo int) error {
+	b := builder.NewSQLBuilder()
+	query, args := b.Select("id").
+		From(employeeTable).
+		Where("id = ?", empNo).
+		Build()
+
+	var id int
+	return dbtx.Conn(ctx, r.db).QueryRowContext(ctx, query+" FOR UPDATE", args...).Scan(&id)
+}
+
+func (r *salaryRepository) AddSalary(ctx context.Context, s *domain.Salary) error {
+	return dbtx.WithinTx(ctx, r.db, func(ctx context.Context) error {
+		tx := dbtx.Conn(ctx, r.db)
+		if s.IsCurrent() {
+			b := builder.NewSQLBuilder()
+			query, args := b.Update(salaryTable).
+				Set("to_date", s.FromDate).
+				Where("employee_id = ? AND to_date = ?", s.EmployeeID, "9999-01-01").
+				Build()
+			if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+				return fmt.Errorf("failed to end current salary of %d: %w", s.EmployeeID, err)
+			}
+		}
+
+		b := builder.NewSQLBuilder()
+		query, args := b.Insert(salaryTable, "employee_id", "salary", "from_date", "to_date").
+			Values(s.EmployeeID, s.Salary, s.FromDate, s.ToDate).
+			Build()
+		if _, err := tx.ExecContext(ctx, query, args...); err != nil {
+			return fmt.Errorf("failed to add salary of %d: %w", s.EmployeeID, err)
+		}
+
+		return nil
+	})
+}
+
+func (r *salaryRepository) UpdateSalaryAmount(ctx context.Context, empNo int, fromDate time.Time, amount int) error {
+	b := builder.NewSQLBuilder()
+	query, args := b.Update(salaryTable).
+		Set("salary", amount).
+		Where("employee_id = ? AND from_date = ?", empNo, fromDate).
+		Build()
+
+	return execOne(ctx, r.db, query, args...)
+}
+
+func (r *salaryRepository) DeleteSalary(ctx context.Context, empNo int, fromDate time.Time) error {
+	b := builder.NewSQLBuilder()
+	query, args := b.Delete(salaryTable).
+		Where("employee_id = ? AND from_date = ?", empNo, fromDate).
+		Build()
+
+	return execOne(ctx, r.db, query, args...)
+}
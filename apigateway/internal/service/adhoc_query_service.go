@@ -0,0 +1,315 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/simpleexcelv2"
+)
+
+// AdhocQueryLimits are the guardrails of ad-hoc query exports.
+type AdhocQueryLimits struct {
+	// MaxRows rejects queries returning more rows
+	MaxRows int
+	// MaxCost rejects queries the planner estimates to cost more
+	MaxCost float64
+	// Timeout is the statement timeout of the query
+	Timeout time.Duration
+}
+
+// DefaultAdhocQueryLimits are used for limits left at zero.
+var DefaultAdhocQueryLimits = AdhocQueryLimits{
+	MaxRows: 100000,
+	MaxCost: 1e7,
+	Timeout: 30 * time.Second,
+}
+
+// adhocQueryBatchSize is the number of rows passed to the streamer at once.
+const adhocQueryBatchSize = 500
+
+// adhocSectionID is the section the query rows are streamed to.
+const adhocSectionID = "rows"
+
+type AdhocQueryService interface {
+	// Export runs a read-only SELECT and streams its rows to w as a
+	// workbook. Queries that are not a single SELECT, that the planner
+	// estimates too costly, that fail, time out or return too many rows are
+	// rejected with a *domain.ValidationError before anything is written.
+	Export(ctx context.Context, req *domain.AdhocQueryRequest, w io.Writer) error
+}
+
+type adhocQueryService struct {
+	repo   domain.AdhocQueryRepository
+	limits AdhocQueryLimits
+}
+
+func NewAdhocQueryService(repo domain.AdhocQueryRepository, limits AdhocQueryLimits) AdhocQueryService {
+	if limits.MaxRows <= 0 {
+		limits.MaxRows = DefaultAdhocQueryLimits.MaxRows
+	}
+	if limits.MaxCost <= 0 {
+		limits.MaxCost = DefaultAdhocQueryLimits.MaxCost
+	}
+	if limits.Timeout <= 0 {
+		limits.Timeout = DefaultAdhocQueryLimits.Timeout
+	}
+	return &adhocQueryService{repo: repo, limits: limits}
+}
+
+func (s *adhocQueryService) Export(ctx context.Context, req *domain.AdhocQueryRequest, w io.Writer) error {
+	verr := &domain.ValidationError{}
+	query, err := readOnlyQuery(req.SQL)
+	if err != nil {
+		verr.Add("sql", "%v", err)
+	}
+	for i, col := range req.Columns {
+		if col.Field == "" {
+			verr.Add(fmt.Sprintf("columns[%d].field", i), "is required")
+		}
+	}
+	if err := verr.ErrOrNil(); err != nil {
+		return err
+	}
+
+	cost, err := s.repo.EstimateCost(ctx, query, s.limits.Timeout)
+	if err != nil {
+		return rejectQuery(err)
+	}
+	if cost > s.limits.MaxCost {
+		verr.Add("sql", "estimated cost %.0f exceeds the limit of %.0f, add filters or a LIMIT", cost, s.limits.MaxCost)
+		return verr
+	}
+
+	var streamer *simpleexcelv2.Streamer
+	var batch []map[string]interface{}
+	var columns []string
+	rows := 0
+	err = s.repo.Query(ctx, query, s.limits.MaxRows+1, s.limits.Timeout,
+		func(names, types []string) error {
+			columns = uniqueColumns(names)
+			exporter, err := s.exporter(req, columns, types)
+			if err != nil {
+				return err
+			}
+			streamer, err = exporter.StartStream(w)
+			return err
+		},
+		func(row []interface{}) error {
+			rows++
+			if rows > s.limits.MaxRows {
+				verr.Add("sql", "returns more than %d rows, add filters or a LIMIT", s.limits.MaxRows)
+				return verr
+			}
+			item := make(map[string]interface{}, len(columns))
+			for i, v := range row {
+				item[columns[i]] = v
+			}
+			batch = append(batch, item)
+			if len(batch) < adhocQueryBatchSize {
+				return nil
+			}
+			err := streamer.Write(adhocSectionID, batch)
+			batch = nil
+			return err
+		})
+	if err == nil && len(batch) > 0 {
+		err = streamer.Write(adhocSectionID, batch)
+	}
+	if err != nil {
+		if streamer != nil {
+			streamer.Abort(err)
+		}
+		return rejectQuery(err)
+	}
+	if streamer == nil {
+		return fmt.Errorf("query returned no result columns")
+	}
+	return streamer.Close()
+}
+
+// exporter builds the single sheet export of the result columns, with the
+// request's column overrides.
+func (s *adhocQueryService) exporter(req *domain.AdhocQueryRequest, columns, types []string) (*simpleexcelv2.ExcelDataExporter, error) {
+	overrides := make(map[string]domain.AdhocColumn, len(req.Columns))
+	for _, col := range req.Columns {
+		overrides[col.Field] = col
+	}
+	cols := make([]simpleexcelv2.ColumnConfig, len(columns))
+	for i, name := range columns {
+		col := simpleexcelv2.ColumnConfig{FieldName: name, Header: name, Width: 20, Type: adhocColumnType(types[i]), DateFormat: "yyyy-mm-dd"}
+		if o, ok := overrides[name]; ok {
+			if o.Header != "" {
+				col.Header = o.Header
+			}
+			if o.Width > 0 {
+				col.Width = o.Width
+			}
+			if o.Format != "" {
+				col.Format, col.DateFormat = o.Format, o.Format
+			}
+			delete(overrides, name)
+		}
+		cols[i] = col
+	}
+	if len(overrides) > 0 {
+		verr := &domain.ValidationError{}
+		for i, col := range req.Columns {
+			if _, ok := overrides[col.Field]; ok {
+				verr.Add(fmt.Sprintf("columns[%d].field", i), "%q is not a result column", col.Field)
+			}
+		}
+		return nil, verr
+	}
+
+	sec := &simpleexcelv2.SectionConfig{ID: adhocSectionID, ShowHeader: true, Columns: cols}
+	if req.Title != "" {
+		sec.Title = req.Title
+	}
+	exporter := simpleexcelv2.NewExcelDataExporter()
+	exporter.AddSheet("Query").AddSection(sec)
+	return exporter, nil
+}
+
+// adhocColumnType maps a PostgreSQL result type to the column value type.
+// Numeric and text types are scanned as strings and stay untyped.
+func adhocColumnType(dbType string) string {
+	switch dbType {
+	case "DATE", "TIMESTAMP", "TIMESTAMPTZ":
+		return simpleexcelv2.ColumnTypeDate
+	case "INT2", "INT4", "INT8":
+		return simpleexcelv2.ColumnTypeInt
+	case "FLOAT4", "FLOAT8":
+		return simpleexcelv2.ColumnTypeFloat
+	}
+	return ""
+}
+
+// rejectQuery reports the errors the query is to blame for as validation
+// errors on its sql field.
+func rejectQuery(err error) error {
+	if errors.Is(err, domain.ErrQueryRejected) {
+		verr := &domain.ValidationError{}
+		verr.Add("sql", "%v", err)
+		return verr
+	}
+	return err
+}
+
+// uniqueColumns suffixes repeated result column names, e.g. the two "id"
+// columns of a join become "id" and "id_2".
+func uniqueColumns(names []string) []string {
+	seen := make(map[string]int, len(names))
+	out := make([]string, len(names))
+	for i, name := range names {
+		seen[name]++
+		out[i] = name
+		if n := seen[name]; n > 1 {
+			out[i] = fmt.Sprintf("%s_%d", name, n)
+		}
+	}
+	return out
+}
+
+// adhocForbiddenWords are the keywords rejected anywhere in an ad-hoc query.
+// The read-only transaction is the actual guard, this gives a clear error.
+var adhocForbiddenWords = map[string]bool{
+	"INSERT": true, "UPDATE": true, "DELETE": true, "MERGE": true,
+	"CREATE": true, "DROP": true, "ALTER": true, "TRUNCATE": true,
+	"GRANT": true, "REVOKE": true, "COPY": true, "CALL": true,
+	"DO": true, "LOCK": true, "VACUUM": true, "INTO": true,
+}
+
+// readOnlyQuery checks that sql is a single SELECT (or WITH ... SELECT)
+// statement and returns it without its trailing semicolon. Words inside
+// string literals, quoted identifiers and comments are ignored.
+func readOnlyQuery(sql string) (string, error) {
+	query := strings.TrimSpace(sql)
+	if query == "" {
+		return "", errors.New("is required")
+	}
+
+	var words []string
+	end := len(query)
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"':
+			// literal or quoted identifier, doubled quotes escape
+			j := i + 1
+			for j < len(query) {
+				if query[j] == c {
+					if j+1 < len(query) && query[j+1] == c {
+						j += 2
+						continue
+					}
+					break
+				}
+				j++
+			}
+			if j >= len(query) {
+				return "", errors.New("has an unterminated quote")
+			}
+			i = j + 1
+		case strings.HasPrefix(query[i:], "--"):
+			j := strings.IndexByte(query[i:], '\n')
+			if j < 0 {
+				j = len(query) - i
+			}
+			i += j
+		case strings.HasPrefix(query[i:], "/*"):
+			j := strings.Index(query[i+2:], "*/")
+			if j < 0 {
+				return "", errors.New("has an unterminated comment")
+			}
+			i += j + 4
+		case c == '$':
+			// dollar quoted string, $$...$$ or $tag$...$tag$
+			j := strings.IndexByte(query[i+1:], '$')
+			if j < 0 || strings.IndexFunc(query[i+1:i+1+j], func(r rune) bool { return !isWordRune(r) }) >= 0 {
+				i++
+				continue
+			}
+			tag := query[i : i+j+2]
+			k := strings.Index(query[i+len(tag):], tag)
+			if k < 0 {
+				return "", errors.New("has an unterminated dollar quote")
+			}
+			i += len(tag) + k + len(tag)
+		case c == ';':
+			if strings.TrimSpace(query[i+1:]) != "" {
+				return "", errors.New("must be a single statement")
+			}
+			end = i
+			i = len(query)
+		case isWordRune(rune(c)):
+			j := i
+			for j < len(query) && isWordRune(rune(query[j])) {
+				j++
+			}
+			words = append(words, strings.ToUpper(query[i:j]))
+			i = j
+		default:
+			i++
+		}
+	}
+
+	if len(words) == 0 || (words[0] != "SELECT" && words[0] != "WITH") {
+		return "", errors.New("must be a SELECT statement")
+	}
+	for _, w := range words {
+		if adhocForbiddenWords[w] {
+			return "", fmt.Errorf("must be read-only, %s is not allowed (quote identifiers named like it)", w)
+		}
+	}
+	return strings.TrimSpace(query[:end]), nil
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
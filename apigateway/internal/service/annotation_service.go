@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/apperror"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/simpleexcelv2"
+)
+
+// ErrAnnotationNotPending is returned when reviewing an annotation that was
+// already approved or rejected.
+var ErrAnnotationNotPending = apperror.Conflict("annotation_not_pending", "annotation is not pending")
+
+// Hidden field names the employee_report template writes above the section
+// managers add annotations in. emp_no identifies the employee and is locked.
+const (
+	annotationFieldEmpNo = "emp_no"
+	annotationFieldKind  = "kind"
+	annotationFieldText  = "text"
+)
+
+var annotationRequiredFields = []string{annotationFieldEmpNo, annotationFieldKind, annotationFieldText}
+
+type AnnotationService interface {
+	// List returns the annotations of an employee, oldest first.
+	List(ctx context.Context, empNo int) ([]domain.Annotation, error)
+	// Import stores the notes and goals added to a workbook exported with the
+	// employee_report report of empNo as pending annotations, in a single
+	// transaction. Rejected files return a *domain.ImportError and change nothing.
+	Import(ctx context.Context, empNo int, r io.Reader, layout *ImportLayout) (*domain.ImportResult, error)
+	// Review approves or rejects a pending annotation.
+	Review(ctx context.Context, id int, status string) error
+}
+
+type annotationService struct {
+	repo domain.AnnotationRepository
+	tx   domain.TxManager
+}
+
+func NewAnnotationService(repo domain.AnnotationRepository, tx domain.TxManager) AnnotationService {
+	return &annotationService{repo: repo, tx: tx}
+}
+
+func (s *annotationService) List(ctx context.Context, empNo int) ([]domain.Annotation, error) {
+	return s.repo.ListAnnotations(ctx, empNo)
+}
+
+func (s *annotationService) Review(ctx context.Context, id int, status string) error {
+	if status != domain.AnnotationApproved && status != domain.AnnotationRejected {
+		verr := &domain.ValidationError{}
+		verr.Add("status", "must be %q or %q, got %q", domain.AnnotationApproved, domain.AnnotationRejected, status)
+		return verr
+	}
+	a, err := s.repo.GetAnnotation(ctx, id)
+	if err != nil {
+		return err
+	}
+	if a.Status != domain.AnnotationPending {
+		return ErrAnnotationNotPending
+	}
+	return s.repo.SetAnnotationStatus(ctx, id, status)
+}
+
+func (s *annotationService) Import(ctx context.Context, empNo int, r io.Reader, layout *ImportLayout) (*domain.ImportResult, error) {
+	ierr := &domain.ImportError{}
+
+	var opts []simpleexcelv2.ReadOption
+	if layout != nil && len(layout.ChecksumKey) > 0 {
+		opts = append(opts, simpleexcelv2.WithChecksumKey(layout.ChecksumKey))
+	}
+	sections, err := simpleexcelv2.ReadEditableSections(r, opts...)
+	var tamper *simpleexcelv2.TamperError
+	if errors.As(err, &tamper) {
+		if tamper.Missing {
+			ierr.Add(domain.ImportRowError{Rule: importRuleChecksum, Message: "workbook has no checksum, export it with the employee_report report"})
+		}
+		for _, id := range tamper.Sections {
+			ierr.Add(domain.ImportRowError{Rule: importRuleChecksum, Message: fmt.Sprintf("read-only cells of section %q were modified, export the report again", id)})
+		}
+		return nil, ierr
+	} else if err != nil {
+		ierr.Add(domain.ImportRowError{Message: fmt.Sprintf("not a valid xlsx workbook: %v", err)})
+		return nil, ierr
+	}
+
+	ruleProblems := make(map[string][]simpleexcelv2.CellProblem)
+	if layout != nil && layout.Template != nil {
+		for _, p := range layout.Template.ValidateImport(sections).Problems {
+			key := importRowKey(p.SectionID, p.Row)
+			ruleProblems[key] = append(ruleProblems[key], p)
+		}
+	}
+
+	var annotations []domain.Annotation
+	found := false
+	for _, sec := range sections {
+		if !containsField(sec.Fields, annotationFieldText) {
+			continue
+		}
+		found = true
+		missing := false
+		for _, field := range annotationRequiredFields {
+			if !containsField(sec.Fields, field) {
+				ierr.Add(domain.ImportRowError{Sheet: sec.Sheet, Field: field, Message: fmt.Sprintf("section %q has no %s column", sec.ID, field)})
+				missing = true
+			}
+		}
+		if missing {
+			continue
+		}
+
+		for _, row := range sec.Rows {
+			if problems := ruleProblems[importRowKey(sec.ID, row.Row)]; len(problems) > 0 {
+				for _, p := range problems {
+					ierr.Add(domain.ImportRowError{Sheet: p.Sheet, Row: p.Row, Cell: p.Cell, Field: p.Field, Rule: p.Rule, Message: p.Message})
+				}
+				continue
+			}
+			a, ok := parseAnnotationRow(sec.Sheet, row, empNo, ierr)
+			if ok && a != nil {
+				annotations = append(annotations, *a)
+			}
+		}
+	}
+	if !found {
+		ierr.Add(domain.ImportRowError{Message: "workbook has no editable annotation section, export it with the employee_report report"})
+	}
+	if err := ierr.ErrOrNil(); err != nil {
+		return nil, err
+	}
+
+	if len(annotations) > 0 {
+		err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+			return s.repo.CreateAnnotations(ctx, annotations)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to store annotations: %w", err)
+		}
+	}
+	return &domain.ImportResult{Updated: len(annotations)}, nil
+}
+
+// parseAnnotationRow converts a row of the annotation section. Rows the
+// manager left without kind and text are unused slots and give nil; every
+// problem is recorded in ierr and false is returned if the row is invalid.
+func parseAnnotationRow(sheet string, row simpleexcelv2.ImportedRow, empNo int, ierr *domain.ImportError) (*domain.Annotation, bool) {
+	ok := true
+	fail := func(field, format string, args ...interface{}) {
+		ierr.Add(domain.ImportRowError{Sheet: sheet, Row: row.Row, Cell: row.Cells[field], Field: field, Message: fmt.Sprintf(format, args...)})
+		ok = false
+	}
+
+	kind := strings.ToLower(strings.TrimSpace(row.Values[annotationFieldKind]))
+	text := strings.TrimSpace(row.Values[annotationFieldText])
+	if kind == "" && text == "" {
+		return nil, true
+	}
+
+	rawEmpNo := strings.TrimSpace(row.Values[annotationFieldEmpNo])
+	if n, err := strconv.Atoi(rawEmpNo); err != nil || n != empNo {
+		fail(annotationFieldEmpNo, "workbook belongs to employee %q, upload it to the report of that employee", rawEmpNo)
+	}
+	if kind == "" {
+		fail(annotationFieldKind, "is required with a text")
+	} else if !domain.ValidAnnotationKind(kind) {
+		fail(annotationFieldKind, "must be %s or %s, got %q", domain.AnnotationNote, domain.AnnotationGoal, row.Values[annotationFieldKind])
+	}
+	if text == "" {
+		fail(annotationFieldText, "is required")
+	}
+	return &domain.Annotation{EmpNo: empNo, Kind: kind, Text: text, Status: domain.AnnotationPending}, ok
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/simpleexcelv2"
+)
+
+// Hidden field names the attendance_timesheet template writes above the
+// open period. emp_no and work_date identify the day and are locked.
+const (
+	timesheetFieldEmpNo     = "emp_no"
+	timesheetFieldWorkDate  = "work_date"
+	timesheetFieldCheckIn   = "check_in"
+	timesheetFieldCheckOut  = "check_out"
+	timesheetFieldLeaveType = "leave_type"
+)
+
+var timesheetRequiredFields = []string{timesheetFieldEmpNo, timesheetFieldWorkDate, timesheetFieldCheckIn, timesheetFieldCheckOut, timesheetFieldLeaveType}
+
+// importRuleClosedPeriod is the rule reported for days outside the open period.
+const importRuleClosedPeriod = "closed_period"
+
+// clockLayouts are the accepted check-in/out formats of timesheet cells.
+var clockLayouts = []string{"15:04", "15:04:05", "3:04 PM", "3:04PM"}
+
+func (s *attendanceService) ImportTimesheet(ctx context.Context, r io.Reader, layout *ImportLayout) (*domain.ImportResult, error) {
+	ierr := &domain.ImportError{}
+
+	var opts []simpleexcelv2.ReadOption
+	if layout != nil && len(layout.ChecksumKey) > 0 {
+		opts = append(opts, simpleexcelv2.WithChecksumKey(layout.ChecksumKey))
+	}
+	sections, err := simpleexcelv2.ReadEditableSections(r, opts...)
+	var tamper *simpleexcelv2.TamperError
+	if errors.As(err, &tamper) {
+		if tamper.Missing {
+			ierr.Add(domain.ImportRowError{Rule: importRuleChecksum, Message: "workbook has no checksum, export it with the attendance_timesheet report"})
+		}
+		for _, id := range tamper.Sections {
+			ierr.Add(domain.ImportRowError{Rule: importRuleChecksum, Message: fmt.Sprintf("read-only cells of section %q were modified, export the timesheet again", id)})
+		}
+		return nil, ierr
+	} else if err != nil {
+		ierr.Add(domain.ImportRowError{Message: fmt.Sprintf("not a valid xlsx workbook: %v", err)})
+		return nil, ierr
+	}
+
+	ruleProblems := make(map[string][]simpleexcelv2.CellProblem)
+	if layout != nil && layout.Template != nil {
+		for _, p := range layout.Template.ValidateImport(sections).Problems {
+			key := importRowKey(p.SectionID, p.Row)
+			ruleProblems[key] = append(ruleProblems[key], p)
+		}
+	}
+
+	openStart := s.openPeriodStart()
+	var days []domain.Attendance
+	seen := make(map[string]int)
+	found := false
+	for _, sec := range sections {
+		if !containsField(sec.Fields, timesheetFieldWorkDate) {
+			continue
+		}
+		found = true
+		missing := false
+		for _, field := range timesheetRequiredFields {
+			if !containsField(sec.Fields, field) {
+				ierr.Add(domain.ImportRowError{Sheet: sec.Sheet, Field: field, Message: fmt.Sprintf("section %q has no %s column", sec.ID, field)})
+				missing = true
+			}
+		}
+		if missing {
+			continue
+		}
+
+		for _, row := range sec.Rows {
+			if problems := ruleProblems[importRowKey(sec.ID, row.Row)]; len(problems) > 0 {
+				for _, p := range problems {
+					ierr.Add(domain.ImportRowError{Sheet: p.Sheet, Row: p.Row, Cell: p.Cell, Field: p.Field, Rule: p.Rule, Message: p.Message})
+				}
+				continue
+			}
+			day, ok := parseTimesheetRow(sec.Sheet, row, ierr)
+			if !ok {
+				continue
+			}
+			if day.WorkDate.Before(openStart) {
+				ierr.Add(domain.ImportRowError{Sheet: sec.Sheet, Row: row.Row, Cell: row.Cells[timesheetFieldWorkDate], Field: timesheetFieldWorkDate, Rule: importRuleClosedPeriod,
+					Message: fmt.Sprintf("%s belongs to a closed period, only days from %s can be edited", day.WorkDate.Format("2006-01-02"), openStart.Format("2006-01-02"))})
+				continue
+			}
+			key := attendanceKey(day.EmpNo, day.WorkDate)
+			if prev, dup := seen[key]; dup {
+				ierr.Add(domain.ImportRowError{Sheet: sec.Sheet, Row: row.Row, Field: timesheetFieldWorkDate,
+					Message: fmt.Sprintf("employee %d on %s already appears on row %d", day.EmpNo, day.WorkDate.Format("2006-01-02"), prev)})
+				continue
+			}
+			seen[key] = row.Row
+			days = append(days, day)
+		}
+	}
+	if !found {
+		ierr.Add(domain.ImportRowError{Message: "workbook has no editable timesheet section, export it with the attendance_timesheet report"})
+	}
+	if err := ierr.ErrOrNil(); err != nil {
+		return nil, err
+	}
+
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		return s.repo.SaveAttendance(ctx, days)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to apply timesheet: %w", err)
+	}
+	return &domain.ImportResult{Updated: len(days)}, nil
+}
+
+// parseTimesheetRow converts the cells of a timesheet row that passed the
+// template rules; every problem is recorded in ierr and false is returned
+// if the row is invalid.
+func parseTimesheetRow(sheet string, row simpleexcelv2.ImportedRow, ierr *domain.ImportError) (domain.Attendance, bool) {
+	ok := true
+	fail := func(field, format string, args ...interface{}) {
+		ierr.Add(domain.ImportRowError{Sheet: sheet, Row: row.Row, Cell: row.Cells[field], Field: field, Message: fmt.Sprintf(format, args...)})
+		ok = false
+	}
+
+	var a domain.Attendance
+	rawEmpNo := strings.TrimSpace(row.Values[timesheetFieldEmpNo])
+	if empNo, err := strconv.Atoi(rawEmpNo); err != nil || empNo <= 0 {
+		fail(timesheetFieldEmpNo, "must be a positive integer, got %q", rawEmpNo)
+	} else {
+		a.EmpNo = empNo
+	}
+	rawDate := strings.TrimSpace(row.Values[timesheetFieldWorkDate])
+	day, err := time.Parse("2006-01-02", rawDate)
+	if err != nil {
+		fail(timesheetFieldWorkDate, "must be a date (YYYY-MM-DD), got %q", rawDate)
+		return a, false
+	}
+	a.WorkDate = day
+
+	if a.CheckIn, err = parseClock(row.Values[timesheetFieldCheckIn], day); err != nil {
+		fail(timesheetFieldCheckIn, "%v", err)
+	}
+	if a.CheckOut, err = parseClock(row.Values[timesheetFieldCheckOut], day); err != nil {
+		fail(timesheetFieldCheckOut, "%v", err)
+	}
+	if a.CheckIn != nil && a.CheckOut != nil && a.CheckOut.Before(*a.CheckIn) {
+		fail(timesheetFieldCheckOut, "must not be before the check-in")
+	}
+	if a.CheckOut != nil && a.CheckIn == nil {
+		fail(timesheetFieldCheckIn, "is required with a check-out")
+	}
+
+	a.LeaveType = strings.ToLower(strings.TrimSpace(row.Values[timesheetFieldLeaveType]))
+	if a.LeaveType != "" && !domain.ValidLeaveType(a.LeaveType) {
+		fail(timesheetFieldLeaveType, "must be %s, %s or %s, got %q", domain.LeaveAnnual, domain.LeaveSick, domain.LeaveUnpaid, row.Values[timesheetFieldLeaveType])
+	}
+	return a, ok
+}
+
+// parseClock reads a time of day on day. Cells hold the exported text, e.g.
+// "09:00", or an Excel time (a fraction of a day) when the user typed a time
+// Excel recognized. Blank cells give nil.
+func parseClock(raw string, day time.Time) (*time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	var offset time.Duration
+	if f, err := strconv.ParseFloat(raw, 64); err == nil {
+		if f < 0 || f >= 1 {
+			return nil, fmt.Errorf("must be a time of day (HH:MM), got %q", raw)
+		}
+		offset = time.Duration(math.Round(f*24*60)) * time.Minute
+	} else {
+		parsed := false
+		for _, layout := range clockLayouts {
+			if t, err := time.Parse(layout, strings.ToUpper(raw)); err == nil {
+				offset = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute + time.Duration(t.Second())*time.Second
+				parsed = true
+				break
+			}
+		}
+		if !parsed {
+			return nil, fmt.Errorf("must be a time of day (HH:MM), got %q", raw)
+		}
+	}
+	t := day.Add(offset)
+	return &t, nil
+}
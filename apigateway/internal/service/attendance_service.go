@@ -0,0 +1,153 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/apperror"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+)
+
+// ErrNotCheckedIn is returned when checking out without a check-in that day.
+var ErrNotCheckedIn = apperror.Conflict("not_checked_in", "employee has not checked in today")
+
+// ErrLeaveNotPending is returned when reviewing a leave request that was
+// already approved or rejected.
+var ErrLeaveNotPending = apperror.Conflict("leave_not_pending", "leave request is not pending")
+
+// maxTimesheetDays caps the date range of a timesheet export.
+const maxTimesheetDays = 93
+
+type AttendanceService interface {
+	CheckIn(ctx context.Context, empNo int) error
+	// CheckOut returns ErrNotCheckedIn without a check-in today.
+	CheckOut(ctx context.Context, empNo int) error
+	ListAttendance(ctx context.Context, filter domain.AttendanceFilter) ([]domain.Attendance, error)
+	// RequestLeave validates and stores a pending leave request, returning a
+	// *domain.ValidationError for invalid requests.
+	RequestLeave(ctx context.Context, l *domain.LeaveRequest) error
+	ListLeaves(ctx context.Context, filter domain.AttendanceFilter) ([]domain.LeaveRequest, error)
+	// ReviewLeave approves or rejects a pending request. Approved leave is
+	// written to the attendance days it covers.
+	ReviewLeave(ctx context.Context, id int, status string) error
+	// Timesheet returns one row per current employee of the department and
+	// day between from and to, split into the days of closed periods, which
+	// are exported read-only, and the days of the open period.
+	Timesheet(ctx context.Context, deptNo string, from, to time.Time) (closed, open []domain.TimesheetRow, err error)
+	// ImportTimesheet applies the open period of a workbook exported with the
+	// attendance_timesheet report, in a single transaction. Rejected files
+	// return a *domain.ImportError and change nothing.
+	ImportTimesheet(ctx context.Context, r io.Reader, layout *ImportLayout) (*domain.ImportResult, error)
+}
+
+type attendanceService struct {
+	repo    domain.AttendanceRepository
+	empRepo domain.EmployeeRepository
+	tx      domain.TxManager
+	now     func() time.Time
+}
+
+func NewAttendanceService(repo domain.AttendanceRepository, empRepo domain.EmployeeRepository, tx domain.TxManager) AttendanceService {
+	return &attendanceService{repo: repo, empRepo: empRepo, tx: tx, now: time.Now}
+}
+
+// openPeriodStart is the first day that can still be edited: attendance
+// periods are calendar months and only the current one is open.
+func (s *attendanceService) openPeriodStart() time.Time {
+	now := s.now()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+func (s *attendanceService) CheckIn(ctx context.Context, empNo int) error {
+	return s.repo.CheckIn(ctx, empNo, s.now())
+}
+
+func (s *attendanceService) CheckOut(ctx context.Context, empNo int) error {
+	err := s.repo.CheckOut(ctx, empNo, s.now())
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotCheckedIn
+	}
+	return err
+}
+
+func (s *attendanceService) ListAttendance(ctx context.Context, filter domain.AttendanceFilter) ([]domain.Attendance, error) {
+	return s.repo.ListAttendance(ctx, filter)
+}
+
+func (s *attendanceService) RequestLeave(ctx context.Context, l *domain.LeaveRequest) error {
+	if err := l.Validate().ErrOrNil(); err != nil {
+		return err
+	}
+	l.Status = domain.LeavePending
+	return s.repo.CreateLeave(ctx, l)
+}
+
+func (s *attendanceService) ListLeaves(ctx context.Context, filter domain.AttendanceFilter) ([]domain.LeaveRequest, error) {
+	return s.repo.ListLeaves(ctx, filter)
+}
+
+func (s *attendanceService) ReviewLeave(ctx context.Context, id int, status string) error {
+	if status != domain.LeaveApproved && status != domain.LeaveRejected {
+		verr := &domain.ValidationError{}
+		verr.Add("status", "must be %q or %q, got %q", domain.LeaveApproved, domain.LeaveRejected, status)
+		return verr
+	}
+	l, err := s.repo.GetLeave(ctx, id)
+	if err != nil {
+		return err
+	}
+	if l.Status != domain.LeavePending {
+		return ErrLeaveNotPending
+	}
+	return s.repo.SetLeaveStatus(ctx, id, status)
+}
+
+func (s *attendanceService) Timesheet(ctx context.Context, deptNo string, from, to time.Time) ([]domain.TimesheetRow, []domain.TimesheetRow, error) {
+	verr := &domain.ValidationError{}
+	if to.Before(from) {
+		verr.Add("variables.to", "must not be before from")
+	} else if days := int(to.Sub(from).Hours()/24) + 1; days > maxTimesheetDays {
+		verr.Add("variables.to", "timesheets cover at most %d days, got %d", maxTimesheetDays, days)
+	}
+	if err := verr.ErrOrNil(); err != nil {
+		return nil, nil, err
+	}
+
+	employees, err := s.empRepo.ListDepartmentEmployees(ctx, deptNo)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load employees of %s: %w", deptNo, err)
+	}
+	days, err := s.repo.ListAttendance(ctx, domain.AttendanceFilter{DeptNo: deptNo, From: from, To: to})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load attendance of %s: %w", deptNo, err)
+	}
+	recorded := make(map[string]domain.Attendance, len(days))
+	for _, a := range days {
+		recorded[attendanceKey(a.EmpNo, a.WorkDate)] = a
+	}
+
+	openStart := s.openPeriodStart()
+	var closed, open []domain.TimesheetRow
+	for _, e := range employees {
+		for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+			row := domain.TimesheetRow{EmpNo: e.ID, Name: e.FirstName + " " + e.LastName, WorkDate: day}
+			if a, ok := recorded[attendanceKey(e.ID, day)]; ok {
+				row.CheckIn, row.CheckOut, row.LeaveType = a.CheckIn, a.CheckOut, a.LeaveType
+			}
+			if day.Before(openStart) {
+				closed = append(closed, row)
+			} else {
+				open = append(open, row)
+			}
+		}
+	}
+	return closed, open, nil
+}
+
+func attendanceKey(empNo int, day time.Time) string {
+	return fmt.Sprintf("%d/%s", empNo, day.Format("2006-01-02"))
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/apperror"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+)
+
+// ErrDepartmentExists is returned when creating a department whose number
+// is taken.
+var ErrDepartmentExists = apperror.Conflict("department_exists", "department already exists")
+
+type DepartmentService interface {
+	List(ctx context.Context) ([]domain.Department, error)
+	// Get returns the department, sql.ErrNoRows if it does not exist.
+	Get(ctx context.Context, deptNo string) (*domain.Department, error)
+	Create(ctx context.Context, d *domain.Department) error
+	Update(ctx context.Context, d *domain.Department) error
+	// Delete hides the department until it is restored, its manager
+	// assignments are kept.
+	Delete(ctx context.Context, deptNo string) error
+	// Restore brings back a deleted department, sql.ErrNoRows if there is none.
+	Restore(ctx context.Context, deptNo string) error
+	// ListManagers returns the manager assignments of a department, most
+	// recent first.
+	ListManagers(ctx context.Context, deptNo string) ([]domain.DeptManager, error)
+	// AssignManager makes m.EmpNo the manager of m.DeptNo from m.FromDate,
+	// ending the term of the current manager that day. A zero m.ToDate
+	// leaves the term open. The new term must start after the current one.
+	AssignManager(ctx context.Context, m *domain.DeptManager) error
+	// TerminateManager ends the current term of empNo as manager of deptNo
+	// on toDate, which must be after the term started.
+	TerminateManager(ctx context.Context, deptNo string, empNo int, toDate time.Time) error
+	// DeleteManager removes an assignment recorded by mistake.
+	DeleteManager(ctx context.Context, deptNo string, empNo int, fromDate time.Time) error
+}
+
+type departmentService struct {
+	repo    domain.DepartmentRepository
+	empRepo domain.EmployeeRepository
+}
+
+func NewDepartmentService(repo domain.DepartmentRepository, empRepo domain.EmployeeRepository) DepartmentService {
+	return &departmentService{repo: repo, empRepo: empRepo}
+}
+
+func (s *departmentService) List(ctx context.Context) ([]domain.Department, error) {
+	return s.repo.ListDepartments(ctx)
+}
+
+func (s *departmentService) Get(ctx context.Context, deptNo string) (*domain.Department, error) {
+	return s.repo.GetDepartment(ctx, deptNo)
+}
+
+func (s *departmentService) Create(ctx context.Context, d *domain.Department) error {
+	if err := d.Validate().ErrOrNil(); err != nil {
+		return err
+	}
+	_, err := s.repo.GetDepartment(ctx, d.DeptNo)
+	if err == nil {
+		return ErrDepartmentExists
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if err := s.repo.CreateDepartment(ctx, d); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			// Created concurrently since the check above
+			return ErrDepartmentExists
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *departmentService) Update(ctx context.Context, d *domain.Department) error {
+	if err := d.Validate().ErrOrNil(); err != nil {
+		return err
+	}
+	return s.repo.UpdateDepartment(ctx, d)
+}
+
+func (s *departmentService) Delete(ctx context.Context, deptNo string) error {
+	return s.repo.DeleteDepartment(ctx, deptNo)
+}
+
+func (s *departmentService) Restore(ctx context.Context, deptNo string) error {
+	return s.repo.RestoreDepartment(ctx, deptNo)
+}
+
+func (s *departmentService) ListManagers(ctx context.Context, deptNo string) ([]domain.DeptManager, error) {
+	if _, err := s.repo.GetDepartment(ctx, deptNo); err != nil {
+		return nil, err
+	}
+	return s.repo.ListManagers(ctx, deptNo)
+}
+
+func (s *departmentService) AssignManager(ctx context.Context, m *domain.DeptManager) error {
+	verr := m.Validate()
+	if err := verr.ErrOrNil(); err != nil {
+		return err
+	}
+	if m.ToDate.IsZero() {
+		m.ToDate = domain.OpenEndDate
+	}
+	if _, err := s.repo.GetDepartment(ctx, m.DeptNo); err != nil {
+		return err
+	}
+	if _, err := s.empRepo.GetByID(ctx, m.EmpNo); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			verr.Add("emp_no", "employee %d does not exist", m.EmpNo)
+			return verr
+		}
+		return fmt.Errorf("failed to load employee %d: %w", m.EmpNo, err)
+	}
+
+	managers, err := s.repo.ListManagers(ctx, m.DeptNo)
+	if err != nil {
+		return fmt.Errorf("failed to load managers of %s: %w", m.DeptNo, err)
+	}
+	// Terms may not overlap, the new one has to start after every recorded
+	// one started and after the ended ones ended
+	for _, cur := range managers {
+		if !m.FromDate.After(cur.FromDate) {
+			verr.Add("from_date", "must be after %s, when manager %d started", cur.FromDate.Format("2006-01-02"), cur.EmpNo)
+			break
+		}
+		if !cur.IsCurrent() && m.FromDate.Before(cur.ToDate) {
+			verr.Add("from_date", "must not be before %s, when manager %d left", cur.ToDate.Format("2006-01-02"), cur.EmpNo)
+			break
+		}
+	}
+	if err := verr.ErrOrNil(); err != nil {
+		return err
+	}
+	return s.repo.AssignManager(ctx, m)
+}
+
+func (s *departmentService) TerminateManager(ctx context.Context, deptNo string, empNo int, toDate time.Time) error {
+	verr := &domain.ValidationError{}
+	if toDate.IsZero() {
+		verr.Add("to_date", "is required")
+		return verr
+	}
+	managers, err := s.repo.ListManagers(ctx, deptNo)
+	if err != nil {
+		return fmt.Errorf("failed to load managers of %s: %w", deptNo, err)
+	}
+	for _, cur := range managers {
+		if cur.EmpNo != empNo || !cur.IsCurrent() {
+			continue
+		}
+		if !toDate.After(cur.FromDate) {
+			verr.Add("to_date", "must be after %s, when the term started", cur.FromDate.Format("2006-01-02"))
+			return verr
+		}
+		return s.repo.TerminateManager(ctx, deptNo, empNo, toDate)
+	}
+	return sql.ErrNoRows
+}
+
+func (s *departmentService) DeleteManager(ctx context.Context, deptNo string, empNo int, fromDate time.Time) error {
+	return s.repo.DeleteManager(ctx, deptNo, empNo, fromDate)
+}
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+)
+
+func (s *employeeService) BatchUpsert(ctx context.Context, employees []domain.Employee) ([]domain.BatchRowResult, error) {
+	if len(employees) == 0 || len(employees) > domain.MaxEmployeeBatchSize {
+		verr := &domain.ValidationError{}
+		verr.Add("employees", "must hold 1 to %d employees, got %d", domain.MaxEmployeeBatchSize, len(employees))
+		return nil, verr
+	}
+
+	results := make([]domain.BatchRowResult, len(employees))
+	valid := make([]domain.Employee, 0, len(employees))
+	seen := make(map[int]int, len(employees))
+	for i := range employees {
+		e := employees[i]
+		e.Gender = strings.ToUpper(strings.TrimSpace(e.Gender))
+		results[i] = domain.BatchRowResult{Index: i, ID: e.ID}
+
+		verr := e.Validate()
+		if prev, dup := seen[e.ID]; dup && e.ID > 0 {
+			verr.Add("id", "employee %d already appears at index %d", e.ID, prev)
+		}
+		if len(verr.Errors) > 0 {
+			results[i].Status = domain.BatchStatusInvalid
+			results[i].Errors = verr.Errors
+			continue
+		}
+		seen[e.ID] = i
+		valid = append(valid, e)
+	}
+	if len(valid) == 0 {
+		return results, nil
+	}
+
+	var inserted map[int]bool
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		inserted, err = s.repo.BatchUpsert(ctx, valid)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to upsert %d employees: %w", len(valid), err)
+	}
+	for i := range results {
+		if results[i].Status == domain.BatchStatusInvalid {
+			continue
+		}
+		results[i].Status = domain.BatchStatusUpdated
+		if inserted[results[i].ID] {
+			results[i].Status = domain.BatchStatusCreated
+		}
+	}
+	return results, nil
+}
@@ -0,0 +1,179 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/simpleexcelv2"
+)
+
+// Hidden field names the employee_edit template writes above its header.
+// id identifies the row and is locked, the others are editable.
+const (
+	importFieldID        = "id"
+	importFieldFirstName = "first_name"
+	importFieldLastName  = "last_name"
+	importFieldGender    = "gender"
+)
+
+var importRequiredFields = []string{importFieldID, importFieldFirstName, importFieldLastName, importFieldGender}
+
+// importRuleChecksum is the rule reported when locked cells were modified.
+const importRuleChecksum = "checksum"
+
+// importedEmployee remembers where an employee came from for error reporting.
+type importedEmployee struct {
+	emp   domain.Employee
+	sheet string
+	row   int
+}
+
+func (s *employeeService) Import(ctx context.Context, r io.Reader, layout *ImportLayout) (*domain.ImportResult, error) {
+	ierr := &domain.ImportError{}
+
+	var opts []simpleexcelv2.ReadOption
+	if layout != nil && len(layout.ChecksumKey) > 0 {
+		opts = append(opts, simpleexcelv2.WithChecksumKey(layout.ChecksumKey))
+	}
+	sections, err := simpleexcelv2.ReadEditableSections(r, opts...)
+	var tamper *simpleexcelv2.TamperError
+	if errors.As(err, &tamper) {
+		if tamper.Missing {
+			ierr.Add(domain.ImportRowError{Rule: importRuleChecksum, Message: "workbook has no checksum, export it with the employee_edit report"})
+		}
+		for _, id := range tamper.Sections {
+			ierr.Add(domain.ImportRowError{Rule: importRuleChecksum, Message: fmt.Sprintf("read-only cells of section %q were modified, export the report again", id)})
+		}
+		return nil, ierr
+	} else if err != nil {
+		ierr.Add(domain.ImportRowError{Message: fmt.Sprintf("not a valid xlsx workbook: %v", err)})
+		return nil, ierr
+	}
+
+	// Template rules, grouped by row so errors come out in sheet order
+	ruleProblems := make(map[string][]simpleexcelv2.CellProblem)
+	if layout != nil && layout.Template != nil {
+		for _, p := range layout.Template.ValidateImport(sections).Problems {
+			key := importRowKey(p.SectionID, p.Row)
+			ruleProblems[key] = append(ruleProblems[key], p)
+		}
+	}
+
+	var rows []importedEmployee
+	seen := make(map[int]int)
+	found := false
+	for _, sec := range sections {
+		if !containsField(sec.Fields, importFieldID) {
+			continue
+		}
+		found = true
+		missing := false
+		for _, field := range importRequiredFields {
+			if !containsField(sec.Fields, field) {
+				ierr.Add(domain.ImportRowError{Sheet: sec.Sheet, Field: field, Message: fmt.Sprintf("section %q has no %s column", sec.ID, field)})
+				missing = true
+			}
+		}
+		if missing {
+			continue
+		}
+
+		for _, row := range sec.Rows {
+			if problems := ruleProblems[importRowKey(sec.ID, row.Row)]; len(problems) > 0 {
+				for _, p := range problems {
+					ierr.Add(domain.ImportRowError{Sheet: p.Sheet, Row: p.Row, Cell: p.Cell, Field: p.Field, Rule: p.Rule, Message: p.Message})
+				}
+				continue
+			}
+			emp, ok := parseImportedEmployee(sec.Sheet, row, ierr)
+			if !ok {
+				continue
+			}
+			if prev, dup := seen[emp.ID]; dup {
+				ierr.Add(domain.ImportRowError{Sheet: sec.Sheet, Row: row.Row, Cell: row.Cells[importFieldID], Field: importFieldID,
+					Message: fmt.Sprintf("employee %d already appears on row %d", emp.ID, prev)})
+				continue
+			}
+			seen[emp.ID] = row.Row
+			rows = append(rows, importedEmployee{emp: emp, sheet: sec.Sheet, row: row.Row})
+		}
+	}
+	if !found {
+		ierr.Add(domain.ImportRowError{Message: "workbook has no editable employee section, export it with the employee_edit report"})
+	}
+	if err := ierr.ErrOrNil(); err != nil {
+		return nil, err
+	}
+
+	employees := make([]domain.Employee, len(rows))
+	for i, row := range rows {
+		employees[i] = row.emp
+	}
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		return s.repo.BatchUpdate(ctx, employees)
+	})
+	if err != nil {
+		var itemErr *domain.BatchItemError
+		if errors.As(err, &itemErr) && errors.Is(err, sql.ErrNoRows) {
+			row := rows[itemErr.Index]
+			ierr.Add(domain.ImportRowError{Sheet: row.sheet, Row: row.row, Field: importFieldID,
+				Message: fmt.Sprintf("employee %d does not exist", row.emp.ID)})
+			return nil, ierr
+		}
+		return nil, fmt.Errorf("failed to apply import: %w", err)
+	}
+	return &domain.ImportResult{Updated: len(employees)}, nil
+}
+
+func importRowKey(sectionID string, row int) string {
+	return fmt.Sprintf("%s/%d", sectionID, row)
+}
+
+// parseImportedEmployee converts the cells of a row that passed the template
+// rules. The checks here back up the database constraints in case the
+// template doesn't declare them; every problem is recorded in ierr and false
+// is returned if the row is invalid.
+func parseImportedEmployee(sheet string, row simpleexcelv2.ImportedRow, ierr *domain.ImportError) (domain.Employee, bool) {
+	ok := true
+	fail := func(field, format string, args ...interface{}) {
+		ierr.Add(domain.ImportRowError{Sheet: sheet, Row: row.Row, Cell: row.Cells[field], Field: field, Message: fmt.Sprintf(format, args...)})
+		ok = false
+	}
+
+	var emp domain.Employee
+	rawID := strings.TrimSpace(row.Values[importFieldID])
+	if id, err := strconv.Atoi(rawID); err != nil || id <= 0 {
+		fail(importFieldID, "must be a positive integer, got %q", rawID)
+	} else {
+		emp.ID = id
+	}
+
+	emp.FirstName = strings.TrimSpace(row.Values[importFieldFirstName])
+	if emp.FirstName == "" {
+		fail(importFieldFirstName, "is required")
+	}
+	emp.LastName = strings.TrimSpace(row.Values[importFieldLastName])
+	if emp.LastName == "" {
+		fail(importFieldLastName, "is required")
+	}
+	emp.Gender = strings.ToUpper(strings.TrimSpace(row.Values[importFieldGender]))
+	if emp.Gender != "M" && emp.Gender != "F" {
+		fail(importFieldGender, "must be M or F, got %q", row.Values[importFieldGender])
+	}
+	return emp, ok
+}
+
+func containsField(fields []string, name string) bool {
+	for _, f := range fields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io"
+	"sort"
+	"time"
 
 	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
 )
@@ -12,17 +15,56 @@ type EmployeeService interface {
 	Create(ctx context.Context, req *domain.Employee) error
 	Get(ctx context.Context, id int) (*domain.Employee, error)
 	Update(ctx context.Context, req *domain.Employee) error
+	// Delete hides the employee until it is restored, sql.ErrNoRows if
+	// there is no such employee.
 	Delete(ctx context.Context, id int) error
+	// Restore brings back a deleted employee, sql.ErrNoRows if there is none.
+	Restore(ctx context.Context, id int) error
+	// List returns the employees matching filter, ordered by id. Invalid
+	// criteria return a *domain.ValidationError.
 	List(ctx context.Context, filter domain.EmployeeFilter) ([]domain.Employee, error)
+	// ListPage is List with the cursor of the next page, set when the page
+	// is full (filter.Limit > 0). Pass it back with ParseEmployeeCursor.
+	ListPage(ctx context.Context, filter domain.EmployeeFilter) (*domain.EmployeePage, error)
 	GetReport(ctx context.Context, id int) (*domain.EmployeeReport, error)
+	// ListManagers returns the managers of a department, most recent first.
+	ListManagers(ctx context.Context, deptNo string) ([]domain.DeptManager, error)
+	// OrgChart returns the current employees of a department paired with the
+	// manager they report to, the department manager first.
+	OrgChart(ctx context.Context, deptNo string) ([]domain.OrgChartEntry, error)
+	// HeadcountTrend returns the month-end headcount of every department over
+	// the months months ending with the month of to. Out of range months
+	// return a *domain.ValidationError.
+	HeadcountTrend(ctx context.Context, to time.Time, months int) ([]domain.HeadcountPoint, error)
+	// UpcomingCelebrations returns the birthdays and work anniversaries of the
+	// days days starting with from, ordered by date. Out of range days return
+	// a *domain.ValidationError.
+	UpcomingCelebrations(ctx context.Context, from time.Time, days int) ([]domain.Celebration, error)
+	// Import applies the edits of a workbook exported with the employee_edit
+	// report. Every row is validated first, against the layout's column rules
+	// and checksum when a layout is given, and all updates run in a single
+	// transaction; rejected files return a *domain.ImportError and change nothing.
+	Import(ctx context.Context, r io.Reader, layout *ImportLayout) (*domain.ImportResult, error)
+	// BatchUpsert creates or updates up to domain.MaxEmployeeBatchSize
+	// employees with a single statement and returns the status of each one.
+	// Invalid and repeated employees are reported and skipped; an empty or
+	// oversized batch returns a *domain.ValidationError.
+	BatchUpsert(ctx context.Context, employees []domain.Employee) ([]domain.BatchRowResult, error)
+	// ListChunks pages through employees for large exports, see
+	// domain.EmployeeRepository.ListChunks.
+	ListChunks(ctx context.Context, filter domain.EmployeeFilter, chunkSize int, fn func([]domain.Employee) error) error
+	// PrepareExport prepares the export queries so the first export doesn't pay for it.
+	PrepareExport(ctx context.Context) error
+	StatementStats() domain.StatementStats
 }
 
 type employeeService struct {
 	repo domain.EmployeeRepository
+	tx   domain.TxManager
 }
 
-func NewEmployeeService(repo domain.EmployeeRepository) EmployeeService {
-	return &employeeService{repo: repo}
+func NewEmployeeService(repo domain.EmployeeRepository, tx domain.TxManager) EmployeeService {
+	return &employeeService{repo: repo, tx: tx}
 }
 
 func (s *employeeService) Create(ctx context.Context, req *domain.Employee) error {
@@ -41,10 +83,169 @@ func (s *employeeService) Delete(ctx context.Context, id int) error {
 	return s.repo.Delete(ctx, id)
 }
 
+func (s *employeeService) Restore(ctx context.Context, id int) error {
+	return s.repo.Restore(ctx, id)
+}
+
 func (s *employeeService) List(ctx context.Context, filter domain.EmployeeFilter) ([]domain.Employee, error) {
+	if err := filter.Validate().ErrOrNil(); err != nil {
+		return nil, err
+	}
 	return s.repo.List(ctx, filter)
 }
 
+func (s *employeeService) ListPage(ctx context.Context, filter domain.EmployeeFilter) (*domain.EmployeePage, error) {
+	employees, err := s.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	page := &domain.EmployeePage{Employees: employees}
+	if filter.Limit > 0 && len(employees) == filter.Limit {
+		page.NextCursor = domain.EncodeCursor(employees[len(employees)-1].ID)
+	}
+	return page, nil
+}
+
+// ParseEmployeeCursor returns the AfterID of a cursor made by ListPage.
+func ParseEmployeeCursor(cursor string) (int, error) {
+	var id int
+	if err := domain.DecodeCursor(cursor, &id); err != nil || id <= 0 {
+		return 0, domain.ErrInvalidCursor
+	}
+	return id, nil
+}
+
+func (s *employeeService) ListManagers(ctx context.Context, deptNo string) ([]domain.DeptManager, error) {
+	return s.repo.GetManagers(ctx, deptNo)
+}
+
+// maxHeadcountMonths caps the headcount trend at ten years.
+const maxHeadcountMonths = 120
+
+func (s *employeeService) HeadcountTrend(ctx context.Context, to time.Time, months int) ([]domain.HeadcountPoint, error) {
+	if months < 1 || months > maxHeadcountMonths {
+		verr := &domain.ValidationError{}
+		verr.Add("variables.months", "must be between 1 and %d, got %d", maxHeadcountMonths, months)
+		return nil, verr
+	}
+	last := time.Date(to.Year(), to.Month(), 1, 0, 0, 0, 0, time.UTC)
+	first := last.AddDate(0, 1-months, 0)
+	points, err := s.repo.CountHeadcountByMonth(ctx, first, last)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count headcount: %w", err)
+	}
+	return points, nil
+}
+
+// maxCelebrationDays caps the celebration window at a year.
+const maxCelebrationDays = 366
+
+// celebrationChunkSize is the page size used to scan employees for celebrations.
+const celebrationChunkSize = 1000
+
+func (s *employeeService) UpcomingCelebrations(ctx context.Context, from time.Time, days int) ([]domain.Celebration, error) {
+	if days < 1 || days > maxCelebrationDays {
+		verr := &domain.ValidationError{}
+		verr.Add("variables.days", "must be between 1 and %d, got %d", maxCelebrationDays, days)
+		return nil, verr
+	}
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, days-1)
+
+	var celebrations []domain.Celebration
+	err := s.repo.ListChunks(ctx, domain.EmployeeFilter{}, celebrationChunkSize, func(employees []domain.Employee) error {
+		for _, e := range employees {
+			name := e.FirstName + " " + e.LastName
+			if date, years, ok := nextCelebration(e.BirthDate, from, to); ok {
+				celebrations = append(celebrations, domain.Celebration{EmpNo: e.ID, Name: name, Kind: domain.CelebrationBirthday, Date: date, Years: years})
+			}
+			if date, years, ok := nextCelebration(e.HireDate, from, to); ok {
+				celebrations = append(celebrations, domain.Celebration{EmpNo: e.ID, Name: name, Kind: domain.CelebrationAnniversary, Date: date, Years: years})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan employees: %w", err)
+	}
+	sort.SliceStable(celebrations, func(i, j int) bool {
+		if !celebrations[i].Date.Equal(celebrations[j].Date) {
+			return celebrations[i].Date.Before(celebrations[j].Date)
+		}
+		return celebrations[i].EmpNo < celebrations[j].EmpNo
+	})
+	return celebrations, nil
+}
+
+// nextCelebration returns the first yearly return of since between from and
+// to, inclusive, and the years since then. February 29 falls on March 1 in
+// common years.
+func nextCelebration(since, from, to time.Time) (time.Time, int, bool) {
+	if since.IsZero() {
+		return time.Time{}, 0, false
+	}
+	for year := from.Year(); year <= to.Year(); year++ {
+		date := time.Date(year, since.Month(), since.Day(), 0, 0, 0, 0, time.UTC)
+		years := year - since.Year()
+		if years > 0 && !date.Before(from) && !date.After(to) {
+			return date, years, true
+		}
+	}
+	return time.Time{}, 0, false
+}
+
+func (s *employeeService) OrgChart(ctx context.Context, deptNo string) ([]domain.OrgChartEntry, error) {
+	managers, err := s.repo.GetManagers(ctx, deptNo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load managers of %s: %w", deptNo, err)
+	}
+	staff, err := s.repo.ListDepartmentEmployees(ctx, deptNo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load employees of %s: %w", deptNo, err)
+	}
+
+	// Managers are ordered most recent first, the current one has an open to_date
+	managerNo := 0
+	for _, m := range managers {
+		if m.ToDate.Year() >= 9999 {
+			managerNo = m.EmpNo
+			break
+		}
+	}
+
+	entries := make([]domain.OrgChartEntry, 0, len(staff)+1)
+	if managerNo != 0 {
+		mgr, err := s.repo.GetByID(ctx, managerNo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load manager %d: %w", managerNo, err)
+		}
+		entries = append(entries, orgChartEntry(mgr, 0))
+	}
+	for i := range staff {
+		if staff[i].ID == managerNo {
+			continue
+		}
+		entries = append(entries, orgChartEntry(&staff[i], managerNo))
+	}
+	return entries, nil
+}
+
+func orgChartEntry(e *domain.Employee, managerNo int) domain.OrgChartEntry {
+	return domain.OrgChartEntry{EmpNo: e.ID, ManagerNo: managerNo, Name: e.FirstName + " " + e.LastName}
+}
+
+func (s *employeeService) ListChunks(ctx context.Context, filter domain.EmployeeFilter, chunkSize int, fn func([]domain.Employee) error) error {
+	return s.repo.ListChunks(ctx, filter, chunkSize, fn)
+}
+
+func (s *employeeService) PrepareExport(ctx context.Context) error {
+	return s.repo.PrepareStatements(ctx)
+}
+
+func (s *employeeService) StatementStats() domain.StatementStats {
+	return s.repo.StatementStats()
+}
+
 func (s *employeeService) GetReport(ctx context.Context, id int) (*domain.EmployeeReport, error) {
 	emp, err := s.repo.GetByID(ctx, id)
 	if err != nil {
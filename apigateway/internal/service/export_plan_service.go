@@ -0,0 +1,445 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/logger"
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/dataflow"
+	"gopkg.in/yaml.v2"
+)
+
+// Export plan limits
+const (
+	maxExportPlanConcurrency = 8
+	maxExportPlanRetries     = 5
+	maxIdempotencyKeyLen     = 255
+)
+
+// exportPlanResultEntry is the archive entry holding the plan result.
+const exportPlanResultEntry = "plan_result.json"
+
+// exportPlanRetryBackoff is the wait before retrying a failed step.
+var exportPlanRetryBackoff = func(attempt int) time.Duration {
+	return time.Duration(attempt) * time.Second
+}
+
+type ExportPlanService interface {
+	// Parse decodes a YAML export plan. Malformed documents are reported as
+	// a *domain.ValidationError.
+	Parse(data []byte) (*domain.ExportPlan, error)
+	// Validate checks the plan and the report request of every step without
+	// running anything, returning a *domain.ValidationError listing every
+	// problem found.
+	Validate(ctx context.Context, plan *domain.ExportPlan) error
+	// Run executes the steps in dependency order, each once the steps it
+	// runs after succeeded; dependents of failed steps are skipped. Failed
+	// steps don't fail the run, they are reported in the result. Files
+	// delivered to the plan archive are written to w as a zip, along with
+	// the result, once every step finished; w is unused when no step is.
+	//
+	// Bucket deliveries are recorded under an idempotency key derived from
+	// the plan's key and the step ID. A step already delivered under its key
+	// is not delivered again, and a target holding a file generated from
+	// newer data than the run's is left alone.
+	Run(ctx context.Context, plan *domain.ExportPlan, w io.Writer) (*domain.ExportPlanResult, error)
+	// Deliveries returns the delivery history of a run.
+	Deliveries(ctx context.Context, runID string) ([]domain.ReportDelivery, error)
+}
+
+type exportPlanService struct {
+	reports    ReportService
+	deliveries domain.ReportDeliveryRepository
+
+	// targetLocks serializes deliveries to the same target
+	mu          sync.Mutex
+	targetLocks map[string]*sync.Mutex
+}
+
+func NewExportPlanService(reports ReportService, deliveries domain.ReportDeliveryRepository) ExportPlanService {
+	return &exportPlanService{
+		reports:     reports,
+		deliveries:  deliveries,
+		targetLocks: make(map[string]*sync.Mutex),
+	}
+}
+
+func (s *exportPlanService) Parse(data []byte) (*domain.ExportPlan, error) {
+	var plan domain.ExportPlan
+	if err := yaml.UnmarshalStrict(data, &plan); err != nil {
+		verr := &domain.ValidationError{}
+		verr.Add("plan", "%v", err)
+		return nil, verr
+	}
+	return &plan, nil
+}
+
+func (s *exportPlanService) Validate(ctx context.Context, plan *domain.ExportPlan) error {
+	_, err := s.schedule(ctx, plan)
+	return err
+}
+
+// schedule validates the plan and groups its steps into waves: each wave
+// only runs after steps of earlier waves.
+func (s *exportPlanService) schedule(ctx context.Context, plan *domain.ExportPlan) ([][]int, error) {
+	verr := &domain.ValidationError{}
+	if len(plan.Reports) == 0 {
+		verr.Add("reports", "must list at least one report")
+	}
+	if plan.Concurrency < 0 || plan.Concurrency > maxExportPlanConcurrency {
+		verr.Add("concurrency", "must be between 1 and %d, got %d", maxExportPlanConcurrency, plan.Concurrency)
+	}
+	if plan.Retries < 0 || plan.Retries > maxExportPlanRetries {
+		verr.Add("retries", "must be between 0 and %d, got %d", maxExportPlanRetries, plan.Retries)
+	}
+	if len(plan.IdempotencyKey) > maxIdempotencyKeyLen {
+		verr.Add("idempotency_key", "must be at most %d characters", maxIdempotencyKeyLen)
+	}
+
+	index := make(map[string]int, len(plan.Reports))
+	entries := make(map[string]int)
+	for i := range plan.Reports {
+		step := &plan.Reports[i]
+		field := fmt.Sprintf("reports[%d]", i)
+		if step.ID == "" {
+			verr.Add(field+".id", "is required")
+		} else if j, dup := index[step.ID]; dup {
+			verr.Add(field+".id", "%q is already used by reports[%d]", step.ID, j)
+		} else {
+			index[step.ID] = i
+		}
+
+		if step.Delivery != nil {
+			switch step.Delivery.Type {
+			case "", domain.DeliveryZip, domain.DeliveryS3, domain.DeliveryGCS:
+			case "email":
+				verr.Add(field+".delivery.type", "email delivery is not available on this server")
+				continue
+			default:
+				verr.Add(field+".delivery.type", "must be one of %q, %q or %q, got %q", domain.DeliveryZip, domain.DeliveryS3, domain.DeliveryGCS, step.Delivery.Type)
+				continue
+			}
+		}
+		if step.Archived() {
+			name := archiveEntry(step)
+			if j, dup := entries[name]; dup {
+				verr.Add(field+".delivery.key", "archive entry %q is already used by reports[%d]", name, j)
+			}
+			entries[name] = i
+		}
+
+		err := s.reports.Validate(ctx, step.ExportRequest())
+		var stepErr *domain.ValidationError
+		if errors.As(err, &stepErr) {
+			for _, fe := range stepErr.Errors {
+				verr.Add(field+"."+fe.Field, "%s", fe.Message)
+			}
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	for i, step := range plan.Reports {
+		for _, dep := range step.After {
+			if dep == step.ID {
+				verr.Add(fmt.Sprintf("reports[%d].after", i), "a step cannot run after itself")
+			} else if _, ok := index[dep]; !ok {
+				verr.Add(fmt.Sprintf("reports[%d].after", i), "unknown step %q", dep)
+			}
+		}
+	}
+	if err := verr.ErrOrNil(); err != nil {
+		return nil, err
+	}
+
+	// Kahn's algorithm, one wave per round
+	placed := make([]bool, len(plan.Reports))
+	var waves [][]int
+	for done := 0; done < len(plan.Reports); {
+		var wave []int
+		for i, step := range plan.Reports {
+			if placed[i] {
+				continue
+			}
+			ready := true
+			for _, dep := range step.After {
+				if !placed[index[dep]] {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				wave = append(wave, i)
+			}
+		}
+		if len(wave) == 0 {
+			var cycle []string
+			for i, step := range plan.Reports {
+				if !placed[i] {
+					cycle = append(cycle, step.ID)
+				}
+			}
+			verr.Add("reports", "steps %s run after each other in a cycle", strings.Join(cycle, ", "))
+			return nil, verr
+		}
+		// Mark after the round so a wave never depends on itself
+		for _, i := range wave {
+			placed[i] = true
+		}
+		done += len(wave)
+		waves = append(waves, wave)
+	}
+	return waves, nil
+}
+
+func (s *exportPlanService) Run(ctx context.Context, plan *domain.ExportPlan, w io.Writer) (*domain.ExportPlanResult, error) {
+	waves, err := s.schedule(ctx, plan)
+	if err != nil {
+		return nil, err
+	}
+
+	run := planRun{id: uuid.NewString(), key: plan.IdempotencyKey, asOf: time.Now()}
+	if run.key == "" {
+		// Without a key only retries within the run are deduplicated
+		run.key = run.id
+	}
+	index := make(map[string]int, len(plan.Reports))
+	result := &domain.ExportPlanResult{RunID: run.id, Name: plan.Name, Steps: make([]domain.ExportPlanStepResult, len(plan.Reports))}
+	for i, step := range plan.Reports {
+		index[step.ID] = i
+		result.Steps[i].ID = step.ID
+	}
+	files := make([]*bytes.Buffer, len(plan.Reports))
+
+	opts := []dataflow.Option{
+		dataflow.WithWorkers(max(plan.Concurrency, 1)),
+		// Failures are recorded in the step result, keep running the others
+		dataflow.WithErrorHandler(func(error) bool { return true }),
+	}
+	if plan.Retries > 0 {
+		opts = append(opts, dataflow.WithRetry(plan.Retries, exportPlanRetryBackoff))
+	}
+
+	for _, wave := range waves {
+		var runnable []interface{}
+		for _, i := range wave {
+			res := &result.Steps[i]
+			for _, dep := range plan.Reports[i].After {
+				if result.Steps[index[dep]].Status != domain.PlanStepSucceeded {
+					res.Status = domain.PlanStepSkipped
+					res.Error = fmt.Sprintf("step %q did not succeed", dep)
+					break
+				}
+			}
+			if res.Status == "" {
+				runnable = append(runnable, i)
+			}
+		}
+
+		// Each worker only touches the result and file of its own step
+		err := dataflow.ForEach(ctx, dataflow.From(ctx, runnable...), func(item interface{}) error {
+			i := item.(int)
+			return s.runStep(ctx, run, &plan.Reports[i], &result.Steps[i], &files[i])
+		}, opts...)
+		if err != nil {
+			// Cancelled, steps that did not finish are failed
+			for _, i := range wave {
+				if res := &result.Steps[i]; res.Status == "" {
+					res.Status = domain.PlanStepFailed
+					res.Error = err.Error()
+				}
+			}
+		}
+	}
+
+	result.Succeeded = true
+	for _, res := range result.Steps {
+		if res.Status != domain.PlanStepSucceeded {
+			result.Succeeded = false
+		}
+	}
+	logger.InfoLog(ctx, "export plan %s (run %s) finished, succeeded: %t", plan.Name, run.id, result.Succeeded)
+
+	if !planArchived(plan) {
+		return result, nil
+	}
+	return result, writePlanArchive(w, result, files)
+}
+
+// planRun identifies a run of a plan in the delivery history.
+type planRun struct {
+	id   string
+	key  string    // idempotency key of the plan, the run ID when unset
+	asOf time.Time // when the run started reading data
+}
+
+// stepKey is the idempotency key of a step's deliveries.
+func (r planRun) stepKey(step *domain.ExportPlanStep) string {
+	sum := sha256.Sum256([]byte(r.key + "\x00" + step.ID))
+	return hex.EncodeToString(sum[:])
+}
+
+// runStep generates the report of a step, into a buffer for the archive.
+func (s *exportPlanService) runStep(ctx context.Context, run planRun, step *domain.ExportPlanStep, res *domain.ExportPlanStepResult, file **bytes.Buffer) error {
+	res.Attempts++
+	if res.StartedAt.IsZero() {
+		res.StartedAt = time.Now()
+	}
+	if !step.Archived() {
+		return s.deliverStep(ctx, run, step, res)
+	}
+
+	buf := new(bytes.Buffer)
+	err := s.reports.Generate(ctx, step.ExportRequest(), buf)
+	res.FinishedAt = time.Now()
+	if err != nil {
+		logger.WarnLog(ctx, "export plan step %s (attempt %d) failed: %v", step.ID, res.Attempts, err)
+		res.Status, res.Error = domain.PlanStepFailed, err.Error()
+		return err
+	}
+	res.Status, res.Error, res.Target = domain.PlanStepSucceeded, "", archiveEntry(step)
+	*file = buf
+	return nil
+}
+
+// deliverStep generates the report of a step into its bucket, unless it was
+// already delivered under its idempotency key or the target holds newer data.
+func (s *exportPlanService) deliverStep(ctx context.Context, run planRun, step *domain.ExportPlanStep, res *domain.ExportPlanStepResult) error {
+	d := &domain.ReportDelivery{
+		RunID:          run.id,
+		StepID:         step.ID,
+		IdempotencyKey: run.stepKey(step),
+		Target:         fmt.Sprintf("%s://%s/%s", step.Delivery.Type, step.Delivery.Bucket, step.Delivery.Key),
+		Attempt:        res.Attempts,
+		DataAsOf:       run.asOf,
+	}
+	lock := s.targetLock(d.Target)
+	lock.Lock()
+	defer lock.Unlock()
+
+	err := s.checkDelivery(ctx, d)
+	if err == nil && d.Status == "" {
+		err = s.reports.Generate(ctx, step.ExportRequest(), nil)
+		d.Status = domain.DeliveryDelivered
+	}
+	if err != nil {
+		d.Status, d.Error = domain.DeliveryFailed, err.Error()
+	}
+	if recErr := s.deliveries.Record(ctx, d); recErr != nil {
+		// The file is out either way, only the history misses it
+		logger.ErrorLog(ctx, "Failed to record delivery of export plan step %s: %v", step.ID, recErr)
+	}
+
+	res.FinishedAt = time.Now()
+	res.Delivery = d.Status
+	if err != nil {
+		logger.WarnLog(ctx, "export plan step %s (attempt %d) failed: %v", step.ID, res.Attempts, err)
+		res.Status, res.Error = domain.PlanStepFailed, err.Error()
+		return err
+	}
+	res.Status, res.Error, res.Target = domain.PlanStepSucceeded, "", d.Target
+	return nil
+}
+
+// checkDelivery sets the status of d when it must not be delivered.
+func (s *exportPlanService) checkDelivery(ctx context.Context, d *domain.ReportDelivery) error {
+	prev, err := s.deliveries.FindDelivered(ctx, d.IdempotencyKey)
+	if err == nil {
+		logger.InfoLog(ctx, "export plan step %s was delivered to %s by run %s, not delivering again", d.StepID, prev.Target, prev.RunID)
+		d.Status = domain.DeliveryDeduplicated
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	latest, err := s.deliveries.LatestDelivered(ctx, d.Target)
+	if err == nil && latest.DataAsOf.After(d.DataAsOf) {
+		logger.InfoLog(ctx, "%s holds newer data from run %s, not overwriting it", d.Target, latest.RunID)
+		d.Status = domain.DeliverySuperseded
+		return nil
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	return nil
+}
+
+// targetLock returns the lock of a delivery target.
+func (s *exportPlanService) targetLock(target string) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	lock, ok := s.targetLocks[target]
+	if !ok {
+		lock = &sync.Mutex{}
+		s.targetLocks[target] = lock
+	}
+	return lock
+}
+
+func (s *exportPlanService) Deliveries(ctx context.Context, runID string) ([]domain.ReportDelivery, error) {
+	return s.deliveries.ListByRun(ctx, runID)
+}
+
+// writePlanArchive writes the archived files, in plan order, and the result.
+func writePlanArchive(w io.Writer, result *domain.ExportPlanResult, files []*bytes.Buffer) error {
+	zw := zip.NewWriter(w)
+	for i, buf := range files {
+		if buf == nil {
+			continue
+		}
+		f, err := zw.Create(result.Steps[i].Target)
+		if err != nil {
+			return err
+		}
+		if _, err := buf.WriteTo(f); err != nil {
+			return err
+		}
+	}
+	f, err := zw.Create(exportPlanResultEntry)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(result); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// planArchived reports whether any step of the plan goes to the archive.
+func planArchived(plan *domain.ExportPlan) bool {
+	for i := range plan.Reports {
+		if plan.Reports[i].Archived() {
+			return true
+		}
+	}
+	return false
+}
+
+// archiveEntry names the archive entry of a step: its delivery key, or its
+// ID with the format as extension.
+func archiveEntry(step *domain.ExportPlanStep) string {
+	if step.Delivery != nil && step.Delivery.Key != "" {
+		return step.Delivery.Key
+	}
+	format := strings.ToLower(strings.TrimSpace(step.Format))
+	if format == "" {
+		format = domain.ExportFormatXLSX
+	}
+	return step.ID + "." + format
+}
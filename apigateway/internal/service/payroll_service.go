@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+)
+
+// ErrUnknownCurrency is returned by a RatesProvider without a rate for a currency.
+var ErrUnknownCurrency = errors.New("unknown currency")
+
+// RatesProvider converts between currencies.
+type RatesProvider interface {
+	// Rate returns the amount of to that one unit of from buys, or an error
+	// wrapping ErrUnknownCurrency.
+	Rate(ctx context.Context, from, to string) (float64, error)
+}
+
+// StaticRates is a RatesProvider over fixed rates, given as units of each
+// currency per one unit of domain.PayrollBaseCurrency.
+type StaticRates map[string]float64
+
+// DefaultRates are the fallback rates used when no live provider is configured.
+var DefaultRates = StaticRates{
+	"USD": 1,
+	"EUR": 0.92,
+	"VND": 25400,
+}
+
+func (r StaticRates) Rate(ctx context.Context, from, to string) (float64, error) {
+	if from == to {
+		return 1, nil
+	}
+	fromRate, ok := r[from]
+	if !ok || fromRate <= 0 {
+		return 0, fmt.Errorf("%w %q", ErrUnknownCurrency, from)
+	}
+	toRate, ok := r[to]
+	if !ok || toRate <= 0 {
+		return 0, fmt.Errorf("%w %q", ErrUnknownCurrency, to)
+	}
+	return toRate / fromRate, nil
+}
+
+// currencyDecimals are the minor units payroll amounts are rounded to, two
+// unless listed.
+var currencyDecimals = map[string]int{
+	"VND": 0,
+	"JPY": 0,
+}
+
+type PayrollService interface {
+	// Payroll computes the pay of the month containing month, converted to
+	// currency, for one department or all of them when deptNo is empty.
+	// Currencies without a rate return a *domain.ValidationError.
+	Payroll(ctx context.Context, month time.Time, currency, deptNo string) (*domain.Payroll, error)
+}
+
+type payrollService struct {
+	empRepo domain.EmployeeRepository
+	attRepo domain.AttendanceRepository
+	rates   RatesProvider
+	now     func() time.Time
+}
+
+func NewPayrollService(empRepo domain.EmployeeRepository, attRepo domain.AttendanceRepository, rates RatesProvider) PayrollService {
+	return &payrollService{empRepo: empRepo, attRepo: attRepo, rates: rates, now: time.Now}
+}
+
+func (s *payrollService) Payroll(ctx context.Context, month time.Time, currency, deptNo string) (*domain.Payroll, error) {
+	rate, err := s.rates.Rate(ctx, domain.PayrollBaseCurrency, currency)
+	if errors.Is(err, ErrUnknownCurrency) {
+		verr := &domain.ValidationError{}
+		verr.Add("variables.currency", "no exchange rate for %q", currency)
+		return nil, verr
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get %s rate: %w", currency, err)
+	}
+
+	departments, err := s.empRepo.ListDepartments(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load departments: %w", err)
+	}
+
+	from := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, -1)
+	payroll := &domain.Payroll{Month: from, Currency: currency}
+	found := false
+	for _, dept := range departments {
+		if deptNo != "" && dept.DeptNo != deptNo {
+			continue
+		}
+		found = true
+		if err := s.addDepartment(ctx, payroll, dept, from, to, rate); err != nil {
+			return nil, err
+		}
+	}
+	if !found && deptNo != "" {
+		verr := &domain.ValidationError{}
+		verr.Add("variables.dept_no", "unknown department %q", deptNo)
+		return nil, verr
+	}
+	return payroll, nil
+}
+
+// addDepartment appends the lines, exceptions and total of one department.
+func (s *payrollService) addDepartment(ctx context.Context, payroll *domain.Payroll, dept domain.Department, from, to time.Time, rate float64) error {
+	employees, err := s.empRepo.ListDepartmentEmployees(ctx, dept.DeptNo)
+	if err != nil {
+		return fmt.Errorf("failed to load employees of %s: %w", dept.DeptNo, err)
+	}
+	salaries, err := s.empRepo.ListDepartmentSalaries(ctx, dept.DeptNo)
+	if err != nil {
+		return fmt.Errorf("failed to load salaries of %s: %w", dept.DeptNo, err)
+	}
+	days, err := s.attRepo.ListAttendance(ctx, domain.AttendanceFilter{DeptNo: dept.DeptNo, From: from, To: to})
+	if err != nil {
+		return fmt.Errorf("failed to load attendance of %s: %w", dept.DeptNo, err)
+	}
+
+	salaryOf := make(map[int]int, len(salaries))
+	for _, sal := range salaries {
+		salaryOf[sal.EmployeeID] = sal.Salary
+	}
+	daysOf := make(map[int][]domain.Attendance)
+	for _, a := range days {
+		daysOf[a.EmpNo] = append(daysOf[a.EmpNo], a)
+	}
+
+	// Days after today are not worked yet, they are not reported missing
+	lastDay := to
+	if today := s.now(); today.Before(lastDay) {
+		lastDay = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	workingDays := countWorkingDays(from, to)
+	decimals, ok := currencyDecimals[payroll.Currency]
+	if !ok {
+		decimals = 2
+	}
+
+	total := domain.PayrollDepartment{DeptNo: dept.DeptNo, DeptName: dept.DeptName, Currency: payroll.Currency}
+	for _, e := range employees {
+		name := e.FirstName + " " + e.LastName
+		flag := func(format string, args ...interface{}) {
+			payroll.Exceptions = append(payroll.Exceptions, domain.PayrollException{EmpNo: e.ID, Name: name, DeptNo: dept.DeptNo, Issue: fmt.Sprintf(format, args...)})
+		}
+
+		salary, ok := salaryOf[e.ID]
+		if !ok {
+			flag("no current salary, left out of the payroll")
+			continue
+		}
+
+		line := domain.PayrollLine{
+			EmpNo:        e.ID,
+			Name:         name,
+			DeptNo:       dept.DeptNo,
+			DeptName:     dept.DeptName,
+			Currency:     payroll.Currency,
+			AnnualSalary: roundAmount(float64(salary)*rate, decimals),
+		}
+		line.GrossPay = roundAmount(float64(salary)*rate/12, decimals)
+
+		recorded := make(map[string]bool)
+		missingCheckOut := 0
+		for _, a := range daysOf[e.ID] {
+			recorded[a.WorkDate.Format("2006-01-02")] = true
+			switch {
+			case a.LeaveType == domain.LeaveUnpaid:
+				line.UnpaidDays++
+			case a.LeaveType != "":
+				line.PaidLeaveDays++
+			case a.CheckIn != nil:
+				line.WorkedDays++
+				if a.CheckOut == nil {
+					missingCheckOut++
+				}
+			}
+		}
+		if workingDays > 0 {
+			line.Deduction = roundAmount(line.GrossPay*float64(line.UnpaidDays)/float64(workingDays), decimals)
+		}
+		line.NetPay = roundAmount(line.GrossPay-line.Deduction, decimals)
+
+		missing := 0
+		for day := from; !day.After(lastDay); day = day.AddDate(0, 0, 1) {
+			if isWorkingDay(day) && !recorded[day.Format("2006-01-02")] {
+				missing++
+			}
+		}
+		if missing > 0 {
+			flag("working days without attendance or leave: %d", missing)
+		}
+		if missingCheckOut > 0 {
+			flag("days without check-out: %d", missingCheckOut)
+		}
+		if line.UnpaidDays > 0 {
+			flag("unpaid leave days deducted: %d", line.UnpaidDays)
+		}
+
+		payroll.Lines = append(payroll.Lines, line)
+		total.Headcount++
+		total.GrossPay += line.GrossPay
+		total.Deductions += line.Deduction
+		total.NetPay += line.NetPay
+	}
+
+	total.GrossPay = roundAmount(total.GrossPay, decimals)
+	total.Deductions = roundAmount(total.Deductions, decimals)
+	total.NetPay = roundAmount(total.NetPay, decimals)
+	payroll.Departments = append(payroll.Departments, total)
+	return nil
+}
+
+func isWorkingDay(day time.Time) bool {
+	return day.Weekday() != time.Saturday && day.Weekday() != time.Sunday
+}
+
+// countWorkingDays counts the weekdays between from and to, inclusive.
+func countWorkingDays(from, to time.Time) int {
+	n := 0
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		if isWorkingDay(day) {
+			n++
+		}
+	}
+	return n
+}
+
+func roundAmount(v float64, decimals int) float64 {
+	p := math.Pow10(decimals)
+	return math.Round(v*p) / p
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"strings"
 	"sync"
 
 	"github.com/locvowork/employee_management_sample/apigateway/internal/database"
@@ -232,7 +233,36 @@ func (pm *ProductMerger) MergeProductsConcurrent(
 	if err != nil {
 		return nil, fmt.Errorf("failed to get products: %w", err)
 	}
+	return pm.MergeConcurrent(ctx, products)
+}
+
+// ProductPage returns limit products ordered by brand and id, continuing
+// after cursor when it is not empty. A full page carries the next cursor.
+func (pm *ProductMerger) ProductPage(ctx context.Context, cursor string, limit int) (*domain.ProductPage, error) {
+	var after *domain.Product
+	if cursor != "" {
+		after = &domain.Product{}
+		if err := domain.DecodeCursor(cursor, &after.Brand, &after.ID); err != nil {
+			return nil, err
+		}
+	}
+	products, err := pm.ProductRepo.ListPage(ctx, after, limit)
+	if err != nil {
+		return nil, err
+	}
+	page := &domain.ProductPage{Products: products}
+	if len(products) == limit {
+		last := products[len(products)-1]
+		page.NextCursor = domain.EncodeCursor(last.Brand, last.ID)
+	}
+	return page, nil
+}
 
+// MergeConcurrent merges the given products concurrently using fan-in/fan-out
+func (pm *ProductMerger) MergeConcurrent(
+	ctx context.Context,
+	products []domain.Product,
+) ([]domain.ProductDetailResponse, error) {
 	if len(products) == 0 {
 		return []domain.ProductDetailResponse{}, nil
 	}
@@ -373,3 +403,23 @@ func splitIntoBatches(products []domain.Product, batchSize int) [][]domain.Produ
 
 	return batches
 }
+
+// FilterCountries keeps the details of the given countries, compared ignoring
+// case. No countries keeps every detail.
+func FilterCountries(results []domain.ProductDetailResponse, countries []string) {
+	if len(countries) == 0 {
+		return
+	}
+	for i := range results {
+		kept := results[i].Details[:0]
+		for _, d := range results[i].Details {
+			for _, c := range countries {
+				if strings.EqualFold(d.Country, c) {
+					kept = append(kept, d)
+					break
+				}
+			}
+		}
+		results[i].Details = kept
+	}
+}
@@ -0,0 +1,799 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/apperror"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/logger"
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/simpleexcelv2"
+	"github.com/locvowork/employee_management_sample/apigateway/templates"
+)
+
+// ErrReportNotFound is returned when no report is registered under the requested ID.
+var ErrReportNotFound = apperror.NotFound("report_not_found", "report template not found")
+
+// ReportDataLoader fetches section data for a report, keyed by section ID.
+// vars holds the request variables converted to their declared types, with
+// defaults applied for omitted optional variables.
+type ReportDataLoader func(ctx context.Context, vars simpleexcelv2.VariableValues) (map[string]interface{}, error)
+
+// ReportStreamLoader feeds section data in batches through emit, in the
+// order the sections appear in the template, so big reports never sit in
+// memory as a whole.
+type ReportStreamLoader func(ctx context.Context, vars simpleexcelv2.VariableValues, emit func(sectionID string, batch interface{}) error) error
+
+// ReportDefinition ties a YAML template to the loader that feeds its sections.
+type ReportDefinition struct {
+	ID           string
+	TemplatePath string
+	Load         ReportDataLoader
+	// Stream is optional and replaces Load for xlsx output. Streamed
+	// workbooks have no hidden field-name rows, so leave it unset for
+	// reports that are imported back.
+	Stream ReportStreamLoader
+	// Warm is optional and runs once at startup, e.g. to prepare the
+	// statements Load uses.
+	Warm func(ctx context.Context) error
+	// ChecksumKey is optional and signs the locked cells of buffered xlsx
+	// output, so imports can reject workbooks whose read-only data was edited.
+	ChecksumKey []byte
+}
+
+// ImportLayout describes how workbooks of a report are read back.
+type ImportLayout struct {
+	// Template supplies the column validation rules
+	Template *simpleexcelv2.ReportTemplate
+	// ChecksumKey verifies the locked cells when set
+	ChecksumKey []byte
+}
+
+type ReportService interface {
+	Register(def ReportDefinition)
+	// Validate rejects requests that would fail mid-render, returning a
+	// *domain.ValidationError listing every problem found.
+	Validate(ctx context.Context, req *domain.ExportRequest) error
+	// Generate renders the report. For download delivery the file is written to w,
+	// otherwise it is uploaded to the requested bucket and w is unused. When w
+	// is an ExportWarningSink it receives the export warnings.
+	Generate(ctx context.Context, req *domain.ExportRequest, w io.Writer) error
+	// Variables returns the parameter declarations of a template, used by the
+	// UI to build the report form.
+	Variables(ctx context.Context, templateID string) (simpleexcelv2.VariableDecls, error)
+	// Template returns the parsed template of a report, e.g. as the layout
+	// and validation rules for importing its workbooks. It must not be modified.
+	Template(ctx context.Context, templateID string) (*simpleexcelv2.ReportTemplate, error)
+	// ImportLayout returns the template and checksum key used to read
+	// edited workbooks of a report back.
+	ImportLayout(ctx context.Context, templateID string) (*ImportLayout, error)
+	// WarmUp compiles every registered template into the cache and runs the
+	// definitions' Warm hooks, so broken templates fail at startup.
+	WarmUp(ctx context.Context) error
+	// ValidateTemplate checks a template being edited, possibly incomplete,
+	// and returns its problems positioned for the editor.
+	ValidateTemplate(ctx context.Context, yamlConfig string) *simpleexcelv2.TemplateValidation
+	// ReloadTemplates re-reads the template of every report, even files
+	// edited within the same modification time. Exports already running
+	// finish on the revision they started with.
+	ReloadTemplates(ctx context.Context) []domain.TemplateRevision
+}
+
+type reportService struct {
+	definitions map[string]ReportDefinition
+	// storage holds object storage providers by delivery type (s3, gcs)
+	storage map[string]simpleexcelv2.ObjectWriterProvider
+	// templates caches parsed templates, re-reading files only when they
+	// change. Templates missing on disk come from the embedded defaults.
+	templates *simpleexcelv2.TemplateCache
+	// scratchDir and scratchQuota are the spool space of xlsx exports,
+	// see WithExportScratch
+	scratchDir   string
+	scratchQuota int64
+	// observe is called with the statistics of every finished export, see
+	// WithExportObserver
+	observe ExportObserver
+}
+
+// ReportServiceOption configures NewReportService.
+type ReportServiceOption func(*reportService)
+
+// WithExportScratch spools every xlsx export to a directory of its own under
+// dir, failing exports that spool more than quota bytes (0 for no limit).
+// The directory is removed once the export finishes, fails or its request
+// is cancelled.
+func WithExportScratch(dir string, quota int64) ReportServiceOption {
+	return func(s *reportService) {
+		s.scratchDir = dir
+		s.scratchQuota = quota
+	}
+}
+
+// ExportObserver receives the statistics of a finished report export, e.g.
+// to export them as metrics. format is the requested ExportFormat.
+type ExportObserver func(reportID, format string, result *simpleexcelv2.ExportResult)
+
+// WithExportObserver reports every finished export to observe. HTML previews
+// have no statistics and are not reported.
+func WithExportObserver(observe ExportObserver) ReportServiceOption {
+	return func(s *reportService) {
+		s.observe = observe
+	}
+}
+
+func NewReportService(storage map[string]simpleexcelv2.ObjectWriterProvider, opts ...ReportServiceOption) ReportService {
+	if storage == nil {
+		storage = make(map[string]simpleexcelv2.ObjectWriterProvider)
+	}
+	cache := simpleexcelv2.NewTemplateCache()
+	cache.SetFallback(templates.FS())
+	s := &reportService{
+		definitions: make(map[string]ReportDefinition),
+		storage:     storage,
+		templates:   cache,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *reportService) Register(def ReportDefinition) {
+	s.definitions[def.ID] = def
+}
+
+func (s *reportService) Validate(ctx context.Context, req *domain.ExportRequest) error {
+	_, _, _, err := s.prepare(req)
+	return err
+}
+
+func (s *reportService) Variables(ctx context.Context, templateID string) (simpleexcelv2.VariableDecls, error) {
+	tmpl, err := s.Template(ctx, templateID)
+	if err != nil {
+		return nil, err
+	}
+	return tmpl.Variables, nil
+}
+
+func (s *reportService) Template(ctx context.Context, templateID string) (*simpleexcelv2.ReportTemplate, error) {
+	def, ok := s.definitions[templateID]
+	if !ok {
+		return nil, ErrReportNotFound
+	}
+	return s.templates.Load(def.TemplatePath)
+}
+
+func (s *reportService) ImportLayout(ctx context.Context, templateID string) (*ImportLayout, error) {
+	tmpl, err := s.Template(ctx, templateID)
+	if err != nil {
+		return nil, err
+	}
+	return &ImportLayout{Template: tmpl, ChecksumKey: s.definitions[templateID].ChecksumKey}, nil
+}
+
+func (s *reportService) ValidateTemplate(ctx context.Context, yamlConfig string) *simpleexcelv2.TemplateValidation {
+	return simpleexcelv2.ValidateTemplate(yamlConfig)
+}
+
+func (s *reportService) WarmUp(ctx context.Context) error {
+	for _, id := range s.templateIDs() {
+		def := s.definitions[id]
+		if _, err := s.templates.Load(def.TemplatePath); err != nil {
+			return fmt.Errorf("report %s: %w", id, err)
+		}
+		if def.Warm != nil {
+			if err := def.Warm(ctx); err != nil {
+				return fmt.Errorf("report %s: warm up: %w", id, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *reportService) ReloadTemplates(ctx context.Context) []domain.TemplateRevision {
+	ids := s.templateIDs()
+	revisions := make([]domain.TemplateRevision, 0, len(ids))
+	for _, id := range ids {
+		path := s.definitions[id].TemplatePath
+		previous := s.templates.Revision(path)
+		s.templates.Invalidate(path)
+		rev := domain.TemplateRevision{ReportID: id, Path: path}
+		if tmpl, err := s.templates.Load(path); err != nil {
+			rev.Error = err.Error()
+		} else {
+			rev.Revision = tmpl.Revision
+		}
+		rev.Changed = rev.Revision != previous
+		revisions = append(revisions, rev)
+	}
+	return revisions
+}
+
+func (s *reportService) Generate(ctx context.Context, req *domain.ExportRequest, w io.Writer) error {
+	def, exporter, vars, err := s.prepare(req)
+	if err != nil {
+		return err
+	}
+	registerReportFormatters(exporter)
+	exporter.SetChecksumKey(def.ChecksumKey).SetScratch(s.scratchDir, s.scratchQuota)
+	var masked []simpleexcelv2.Warning
+	if req.Masking != nil {
+		masked = applyMasking(exporter.Template(), req.Masking)
+	}
+
+	sink, _ := w.(ExportWarningSink)
+	report := func() {
+		sink.ExportWarnings(append(masked, exporter.Warnings()...))
+	}
+	if sink != nil && req.Delivery.Type == domain.DeliveryDownload {
+		w = &warningWriter{w: w, report: report}
+	}
+	if err := s.generate(ctx, def, exporter, vars, req, w); err != nil {
+		return err
+	}
+	if sink != nil && req.Delivery.Type != domain.DeliveryDownload {
+		report()
+	}
+	return nil
+}
+
+func (s *reportService) generate(ctx context.Context, def ReportDefinition, exporter *simpleexcelv2.ExcelDataExporter, vars simpleexcelv2.VariableValues, req *domain.ExportRequest, w io.Writer) error {
+	if def.Stream != nil && req.Format == domain.ExportFormatXLSX {
+		return s.streamReport(ctx, def, exporter, vars, req.Delivery, w)
+	}
+
+	data, err := def.Load(ctx, vars)
+	if err != nil {
+		return fmt.Errorf("failed to load data for report %s: %w", def.ID, err)
+	}
+	if err := exporter.BindAll(data); err != nil {
+		return fmt.Errorf("report %s: %w", def.ID, err)
+	}
+
+	if req.Delivery.Type == domain.DeliveryDownload {
+		if err := writeReport(ctx, exporter, req.Format, w); err != nil {
+			return err
+		}
+		s.logExport(ctx, def.ID, req.Format, exporter)
+		return nil
+	}
+
+	provider := s.storage[req.Delivery.Type]
+	if req.Format == domain.ExportFormatXLSX {
+		if err := exporter.SetObjectStorage(provider).ExportToObjectStorage(ctx, req.Delivery.Bucket, req.Delivery.Key); err != nil {
+			return err
+		}
+		s.logExport(ctx, def.ID, req.Format, exporter)
+		return nil
+	}
+	ow, err := provider.NewWriter(ctx, req.Delivery.Bucket, req.Delivery.Key)
+	if err != nil {
+		return fmt.Errorf("failed to open %s/%s: %w", req.Delivery.Bucket, req.Delivery.Key, err)
+	}
+	if err := writeReport(ctx, exporter, req.Format, ow); err != nil {
+		if a, ok := ow.(interface{ CloseWithError(error) error }); ok {
+			a.CloseWithError(err)
+		} else {
+			ow.Close()
+		}
+		return err
+	}
+	if err := ow.Close(); err != nil {
+		return err
+	}
+	s.logExport(ctx, def.ID, req.Format, exporter)
+	return nil
+}
+
+// ExportWarningSink is implemented by the writers passed to Generate that
+// surface the export warnings to the client, e.g. as response headers.
+// ExportWarnings is called once with the warnings of the exporter and the
+// columns masked by the request: before the first byte of a download is
+// written, or once a bucket delivery finished.
+type ExportWarningSink interface {
+	ExportWarnings(warnings []simpleexcelv2.Warning)
+}
+
+// warningWriter reports the export warnings before the first write, the
+// exporters only write once the workbook is complete.
+type warningWriter struct {
+	w        io.Writer
+	report   func()
+	reported bool
+}
+
+func (ww *warningWriter) Write(p []byte) (int, error) {
+	if !ww.reported {
+		ww.reported = true
+		ww.report()
+	}
+	return ww.w.Write(p)
+}
+
+// streamReport writes the workbook section by section as def.Stream emits
+// batches, to w or to the object storage target.
+func (s *reportService) streamReport(ctx context.Context, def ReportDefinition, exporter *simpleexcelv2.ExcelDataExporter, vars simpleexcelv2.VariableValues, target *domain.DeliveryTarget, w io.Writer) error {
+	var streamer *simpleexcelv2.Streamer
+	var err error
+	if target.Type == domain.DeliveryDownload {
+		streamer, err = exporter.StartStreamContext(ctx, w)
+	} else {
+		streamer, err = exporter.SetObjectStorage(s.storage[target.Type]).StartStreamToObjectStorage(ctx, target.Bucket, target.Key)
+	}
+	if err != nil {
+		return fmt.Errorf("report %s: %w", def.ID, err)
+	}
+
+	if err := def.Stream(ctx, vars, streamer.Write); err != nil {
+		streamer.Abort(err)
+		return fmt.Errorf("failed to load data for report %s: %w", def.ID, err)
+	}
+	if err := streamer.Close(); err != nil {
+		return err
+	}
+	s.logExport(ctx, def.ID, domain.ExportFormatXLSX, exporter)
+	return nil
+}
+
+// logExport logs the statistics of a finished report export and reports
+// them to the observer. HTML previews have none.
+func (s *reportService) logExport(ctx context.Context, reportID, format string, exporter *simpleexcelv2.ExcelDataExporter) {
+	r := exporter.Result()
+	if r == nil {
+		return
+	}
+	if s.observe != nil {
+		s.observe(reportID, format, r)
+	}
+	logger.InfoLog(ctx, "report %s (template %s): %d sheets, %d sections, %d rows, %d cells, %d bytes (%d scratch) in %s",
+		reportID, exporter.Template().Revision, r.Sheets, r.Sections, r.Rows, r.Cells, r.Bytes, r.ScratchBytes, r.Duration)
+	for _, w := range r.Warnings {
+		logger.WarnLog(ctx, "report %s: %s", reportID, w)
+	}
+}
+
+// prepare validates the request against its template and returns the
+// exporter, with variables applied, and the typed variables.
+func (s *reportService) prepare(req *domain.ExportRequest) (ReportDefinition, *simpleexcelv2.ExcelDataExporter, simpleexcelv2.VariableValues, error) {
+	req.Normalize()
+	verr := req.Validate()
+
+	if req.Delivery != nil && (req.Delivery.Type == domain.DeliveryS3 || req.Delivery.Type == domain.DeliveryGCS) {
+		if s.storage[req.Delivery.Type] == nil {
+			verr.Add("delivery.type", "%s delivery is not configured on this server", req.Delivery.Type)
+		}
+	}
+
+	def, ok := s.definitions[req.TemplateID]
+	if !ok {
+		if req.TemplateID != "" {
+			verr.Add("template_id", "unknown template %q, available: %s", req.TemplateID, strings.Join(s.templateIDs(), ", "))
+		}
+		return def, nil, nil, verr.ErrOrNil()
+	}
+
+	exporter, err := s.templates.NewExporterFromFile(def.TemplatePath)
+	if err != nil {
+		return def, nil, nil, err
+	}
+	tmpl := exporter.Template()
+
+	vars, err := exporter.ResolveVariables(req.Variables)
+	var varErr *simpleexcelv2.VariableError
+	if errors.As(err, &varErr) {
+		for _, p := range varErr.Problems {
+			verr.Add("variables."+p.Name, "%s", p.Message)
+		}
+	} else if err != nil {
+		return def, nil, nil, err
+	}
+
+	if req.Masking != nil {
+		known := templateFields(tmpl)
+		for _, col := range req.Masking.Columns {
+			if !known[col] {
+				verr.Add("masking.columns", "column %q is not declared by template %q", col, req.TemplateID)
+			}
+		}
+	}
+
+	if err := verr.ErrOrNil(); err != nil {
+		return def, nil, nil, err
+	}
+	return def, exporter, vars, nil
+}
+
+func (s *reportService) templateIDs() []string {
+	ids := make([]string, 0, len(s.definitions))
+	for id := range s.definitions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+func writeReport(ctx context.Context, exporter *simpleexcelv2.ExcelDataExporter, format string, w io.Writer) error {
+	switch format {
+	case domain.ExportFormatCSV:
+		return exporter.ToCSV(w)
+	case domain.ExportFormatHTML:
+		return exporter.RenderHTML(ctx, w)
+	}
+	return exporter.ToWriterContext(ctx, w)
+}
+
+// registerReportFormatters registers the formatters report templates may reference.
+func registerReportFormatters(exporter *simpleexcelv2.ExcelDataExporter) {
+	exporter.RegisterFormatter("date", func(v interface{}) interface{} {
+		if t, ok := v.(time.Time); ok {
+			return t.Format("2006-01-02")
+		}
+		return v
+	})
+}
+
+// templateFields returns every column field name declared in the template.
+func templateFields(tmpl *simpleexcelv2.ReportTemplate) map[string]bool {
+	fields := make(map[string]bool)
+	for _, sheet := range tmpl.Sheets {
+		for _, sec := range sheet.Sections {
+			for _, col := range sec.Columns {
+				fields[col.FieldName] = true
+			}
+		}
+	}
+	return fields
+}
+
+// applyMasking installs a masking formatter on every matching column and
+// returns a warning for each.
+func applyMasking(tmpl *simpleexcelv2.ReportTemplate, policy *domain.MaskingPolicy) []simpleexcelv2.Warning {
+	var warnings []simpleexcelv2.Warning
+	masked := make(map[string]bool, len(policy.Columns))
+	for _, col := range policy.Columns {
+		masked[col] = true
+	}
+	for i := range tmpl.Sheets {
+		for j := range tmpl.Sheets[i].Sections {
+			sec := &tmpl.Sheets[i].Sections[j]
+			for k := range sec.Columns {
+				if masked[sec.Columns[k].FieldName] {
+					mode := policy.Mode
+					sec.Columns[k].Formatter = func(v interface{}) interface{} {
+						return maskValue(mode, v)
+					}
+					warnings = append(warnings, simpleexcelv2.Warning{
+						Kind:    simpleexcelv2.WarningMaskedColumn,
+						Sheet:   tmpl.Sheets[i].Name,
+						Section: sec.ID,
+						Field:   sec.Columns[k].FieldName,
+						Message: "values are masked",
+					})
+				}
+			}
+		}
+	}
+	return warnings
+}
+
+func maskValue(mode string, v interface{}) interface{} {
+	// Count characters, not bytes, so multi-byte names are not cut mid-rune
+	s := []rune(fmt.Sprintf("%v", v))
+	if mode == domain.MaskPartial && len(s) > 4 {
+		return strings.Repeat("*", len(s)-4) + string(s[len(s)-4:])
+	}
+	return "****"
+}
+
+// employeeExportChunkSize is the number of rows fetched per query when
+// streaming employee exports.
+const employeeExportChunkSize = 1000
+
+// EmployeeListReportID identifies the employee list export.
+const EmployeeListReportID = "employee_list"
+
+// NewEmployeeListReport defines the employee list report backed by
+// EmployeeService. xlsx exports are streamed in chunks through a statement
+// prepared once at warm-up.
+func NewEmployeeListReport(empSvc EmployeeService, templatePath string) ReportDefinition {
+	return ReportDefinition{
+		ID:           EmployeeListReportID,
+		TemplatePath: templatePath,
+		Load:         loadEmployees(empSvc),
+		Stream: func(ctx context.Context, vars simpleexcelv2.VariableValues, emit func(string, interface{}) error) error {
+			return empSvc.ListChunks(ctx, employeeFilter(vars), employeeExportChunkSize, func(chunk []domain.Employee) error {
+				return emit("employees", chunk)
+			})
+		},
+		Warm: empSvc.PrepareExport,
+	}
+}
+
+// EmployeeEditReportID identifies the editable employee export.
+const EmployeeEditReportID = "employee_edit"
+
+// NewEmployeeEditReport defines the editable employee export whose edited
+// file is applied back with EmployeeService.Import. A non-empty checksumKey
+// makes imports reject files whose locked cells were changed.
+func NewEmployeeEditReport(empSvc EmployeeService, templatePath string, checksumKey []byte) ReportDefinition {
+	return ReportDefinition{
+		ID:           EmployeeEditReportID,
+		TemplatePath: templatePath,
+		Load:         loadEmployees(empSvc),
+		ChecksumKey:  checksumKey,
+	}
+}
+
+// loadEmployees feeds the "employees" section, filtered and paginated by the
+// template variables (see employeeFilter).
+func loadEmployees(empSvc EmployeeService) ReportDataLoader {
+	return func(ctx context.Context, vars simpleexcelv2.VariableValues) (map[string]interface{}, error) {
+		employees, err := empSvc.List(ctx, employeeFilter(vars))
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{"employees": employees}, nil
+	}
+}
+
+// employeeFilter maps the employee list variables to the filter, using the
+// names of the GET /employees query parameters.
+func employeeFilter(vars simpleexcelv2.VariableValues) domain.EmployeeFilter {
+	filter := domain.EmployeeFilter{}
+	if v, ok := vars["limit"].(int); ok {
+		filter.Limit = v
+	}
+	if v, ok := vars["offset"].(int); ok {
+		filter.Offset = v
+	}
+	if v, ok := vars["name"].(string); ok {
+		filter.NamePrefix = v
+	}
+	if v, ok := vars["gender"].(string); ok {
+		filter.Gender = v
+	}
+	if v, ok := vars["hired_from"].(time.Time); ok {
+		filter.HiredFrom = v
+	}
+	if v, ok := vars["hired_to"].(time.Time); ok {
+		filter.HiredTo = v
+	}
+	if v, ok := vars["dept_no"].(string); ok {
+		filter.DeptNo = v
+	}
+	return filter
+}
+
+// EmployeeReportID identifies the report workbook of one employee.
+const EmployeeReportID = "employee_report"
+
+// employeeReportSlots is the number of blank rows the employee report offers
+// for new notes and goals.
+const employeeReportSlots = 10
+
+// Rows of the employee report sections, holding only the exported columns.
+type (
+	reportSummaryItem struct {
+		Item  string
+		Value interface{}
+	}
+	reportDepartment struct {
+		DeptNo   string
+		FromDate time.Time
+		ToDate   time.Time
+	}
+	reportAnnotation struct {
+		Kind      string
+		Text      string
+		Status    string
+		CreatedAt time.Time
+	}
+	annotationSlot struct {
+		Kind  string
+		Text  string
+		EmpNo int
+	}
+)
+
+// NewEmployeeReport defines the report of the employee given by the emp_no
+// variable: a read-only summary, department history and the annotations so
+// far, and blank rows managers fill with notes and goals. The edited file is
+// stored back with AnnotationService.Import for review. A non-empty
+// checksumKey makes imports reject files whose locked cells were changed.
+func NewEmployeeReport(empSvc EmployeeService, annSvc AnnotationService, templatePath string, checksumKey []byte) ReportDefinition {
+	return ReportDefinition{
+		ID:           EmployeeReportID,
+		TemplatePath: templatePath,
+		Load: func(ctx context.Context, vars simpleexcelv2.VariableValues) (map[string]interface{}, error) {
+			empNo, _ := vars["emp_no"].(int)
+			report, err := empSvc.GetReport(ctx, empNo)
+			if err != nil {
+				return nil, err
+			}
+			annotations, err := annSvc.List(ctx, empNo)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get annotations: %w", err)
+			}
+
+			departments := make([]reportDepartment, len(report.DepartmentHistory))
+			for i, d := range report.DepartmentHistory {
+				departments[i] = reportDepartment{DeptNo: d.DeptNo, FromDate: d.FromDate, ToDate: d.ToDate}
+			}
+			rows := make([]reportAnnotation, len(annotations))
+			for i, a := range annotations {
+				rows[i] = reportAnnotation{Kind: a.Kind, Text: a.Text, Status: a.Status, CreatedAt: a.CreatedAt}
+			}
+			slots := make([]annotationSlot, employeeReportSlots)
+			for i := range slots {
+				slots[i].EmpNo = empNo
+			}
+			return map[string]interface{}{
+				"summary":         employeeReportSummary(report),
+				"departments":     departments,
+				"annotations":     rows,
+				"new_annotations": slots,
+			}, nil
+		},
+		ChecksumKey: checksumKey,
+	}
+}
+
+// employeeReportSummary lists the figures of the report summary section.
+func employeeReportSummary(r *domain.EmployeeReport) []reportSummaryItem {
+	emp := r.Employee
+	return []reportSummaryItem{
+		{Item: "Employee No", Value: emp.ID},
+		{Item: "Name", Value: emp.FirstName + " " + emp.LastName},
+		{Item: "Gender", Value: emp.Gender},
+		{Item: "Birth Date", Value: emp.BirthDate.Format("2006-01-02")},
+		{Item: "Hire Date", Value: emp.HireDate.Format("2006-01-02")},
+		{Item: "Title", Value: r.CurrentTitle.Title},
+		{Item: "Salary", Value: r.CurrentSalary.Salary},
+	}
+}
+
+// NewDeptManagerTimelineReport defines the manager timeline of the department
+// given by the dept_no variable, drawn as a gantt section.
+func NewDeptManagerTimelineReport(empSvc EmployeeService, templatePath string) ReportDefinition {
+	return ReportDefinition{
+		ID:           "dept_manager_timeline",
+		TemplatePath: templatePath,
+		Load: func(ctx context.Context, vars simpleexcelv2.VariableValues) (map[string]interface{}, error) {
+			deptNo, _ := vars["dept_no"].(string)
+			managers, err := empSvc.ListManagers(ctx, deptNo)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"managers": managers}, nil
+		},
+	}
+}
+
+// DeptOrgChartReportID identifies the department org chart.
+const DeptOrgChartReportID = "dept_orgchart"
+
+// NewDeptOrgChartReport defines the org chart of the department given by the
+// dept_no variable, drawn as an org_chart section.
+func NewDeptOrgChartReport(empSvc EmployeeService, templatePath string) ReportDefinition {
+	return ReportDefinition{
+		ID:           DeptOrgChartReportID,
+		TemplatePath: templatePath,
+		Load: func(ctx context.Context, vars simpleexcelv2.VariableValues) (map[string]interface{}, error) {
+			deptNo, _ := vars["dept_no"].(string)
+			entries, err := empSvc.OrgChart(ctx, deptNo)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"orgchart": entries}, nil
+		},
+	}
+}
+
+// AttendanceTimesheetReportID identifies the editable attendance timesheet.
+const AttendanceTimesheetReportID = "attendance_timesheet"
+
+// NewAttendanceTimesheetReport defines the timesheet of the department given
+// by the dept_no variable between the from and to dates. Days of closed
+// periods are exported locked, those of the open period are applied back
+// with AttendanceService.ImportTimesheet. A non-empty checksumKey makes
+// imports reject files whose locked cells were changed.
+func NewAttendanceTimesheetReport(attSvc AttendanceService, templatePath string, checksumKey []byte) ReportDefinition {
+	return ReportDefinition{
+		ID:           AttendanceTimesheetReportID,
+		TemplatePath: templatePath,
+		Load: func(ctx context.Context, vars simpleexcelv2.VariableValues) (map[string]interface{}, error) {
+			deptNo, _ := vars["dept_no"].(string)
+			from, _ := vars["from"].(time.Time)
+			to, _ := vars["to"].(time.Time)
+			closed, open, err := attSvc.Timesheet(ctx, deptNo, from, to)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"closed": closed, "open": open}, nil
+		},
+		ChecksumKey: checksumKey,
+	}
+}
+
+// PayrollSummaryReportID identifies the monthly payroll workbook.
+const PayrollSummaryReportID = "payroll_summary"
+
+// NewPayrollSummaryReport defines the payroll of the month given by the month
+// variable in the currency variable, with a summary, a per-department detail
+// and an exceptions sheet. The optional dept_no variable limits it to one
+// department.
+func NewPayrollSummaryReport(paySvc PayrollService, templatePath string) ReportDefinition {
+	return ReportDefinition{
+		ID:           PayrollSummaryReportID,
+		TemplatePath: templatePath,
+		Load: func(ctx context.Context, vars simpleexcelv2.VariableValues) (map[string]interface{}, error) {
+			month, _ := vars["month"].(time.Time)
+			currency, _ := vars["currency"].(string)
+			deptNo, _ := vars["dept_no"].(string)
+			payroll, err := paySvc.Payroll(ctx, month, currency, deptNo)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{
+				"departments": payroll.Departments,
+				"lines":       payroll.Lines,
+				"exceptions":  payroll.Exceptions,
+			}, nil
+		},
+	}
+}
+
+// HeadcountTrendReportID identifies the monthly headcount trend workbook.
+const HeadcountTrendReportID = "headcount_trend"
+
+// NewHeadcountTrendReport defines the month-end headcount of every department
+// over the months variable months ending with the to date, today by default,
+// drawn as one line chart per department next to a data sheet.
+func NewHeadcountTrendReport(empSvc EmployeeService, templatePath string) ReportDefinition {
+	return ReportDefinition{
+		ID:           HeadcountTrendReportID,
+		TemplatePath: templatePath,
+		Load: func(ctx context.Context, vars simpleexcelv2.VariableValues) (map[string]interface{}, error) {
+			to, _ := vars["to"].(time.Time)
+			if to.IsZero() {
+				to = time.Now()
+			}
+			months, _ := vars["months"].(int)
+			points, err := empSvc.HeadcountTrend(ctx, to, months)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"trend": points, "data": points}, nil
+		},
+	}
+}
+
+// UpcomingCelebrationsReportID identifies the birthday and work anniversary list.
+const UpcomingCelebrationsReportID = "upcoming_celebrations"
+
+// NewUpcomingCelebrationsReport defines the birthdays and work anniversaries
+// of the days variable days starting with the from date, today by default.
+// It is small enough to send to HR as an attachment.
+func NewUpcomingCelebrationsReport(empSvc EmployeeService, templatePath string) ReportDefinition {
+	return ReportDefinition{
+		ID:           UpcomingCelebrationsReportID,
+		TemplatePath: templatePath,
+		Load: func(ctx context.Context, vars simpleexcelv2.VariableValues) (map[string]interface{}, error) {
+			from, _ := vars["from"].(time.Time)
+			if from.IsZero() {
+				from = time.Now()
+			}
+			days, _ := vars["days"].(int)
+			celebrations, err := empSvc.UpcomingCelebrations(ctx, from, days)
+			if err != nil {
+				return nil, err
+			}
+			return map[string]interface{}{"celebrations": celebrations}, nil
+		},
+	}
+}
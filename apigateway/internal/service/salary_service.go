@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+)
+
+type SalaryService interface {
+	// History returns the salaries of an employee, most recent first.
+	// Unknown employees return sql.ErrNoRows.
+	History(ctx context.Context, empNo int) ([]domain.Salary, error)
+	// Current returns the salary in effect, sql.ErrNoRows if there is none.
+	Current(ctx context.Context, empNo int) (*domain.Salary, error)
+	// Add records a salary effective from s.FromDate. A zero s.ToDate makes
+	// it the current salary, ending the previous current one that day.
+	// Ranges overlapping other salaries return a *domain.ValidationError.
+	Add(ctx context.Context, s *domain.Salary) error
+	// UpdateAmount corrects the amount of the salary starting on fromDate.
+	UpdateAmount(ctx context.Context, empNo int, fromDate time.Time, amount int) error
+	// Delete removes the salary starting on fromDate.
+	Delete(ctx context.Context, empNo int, fromDate time.Time) error
+}
+
+type salaryService struct {
+	repo    domain.SalaryRepository
+	empRepo domain.EmployeeRepository
+	tx      domain.TxManager
+}
+
+func NewSalaryService(repo domain.SalaryRepository, empRepo domain.EmployeeRepository, tx domain.TxManager) SalaryService {
+	return &salaryService{repo: repo, empRepo: empRepo, tx: tx}
+}
+
+func (s *salaryService) History(ctx context.Context, empNo int) ([]domain.Salary, error) {
+	if _, err := s.empRepo.GetByID(ctx, empNo); err != nil {
+		return nil, err
+	}
+	return s.repo.ListSalaries(ctx, empNo)
+}
+
+func (s *salaryService) Current(ctx context.Context, empNo int) (*domain.Salary, error) {
+	return s.repo.GetCurrentSalary(ctx, empNo)
+}
+
+func (s *salaryService) Add(ctx context.Context, sal *domain.Salary) error {
+	verr := sal.Validate()
+	if err := verr.ErrOrNil(); err != nil {
+		return err
+	}
+	if sal.ToDate.IsZero() {
+		sal.ToDate = domain.OpenEndDate
+	}
+	if _, err := s.empRepo.GetByID(ctx, sal.EmployeeID); err != nil {
+		return err
+	}
+
+	// The history is checked and changed under its lock, or two concurrent
+	// adds could both pass the check and store overlapping salaries.
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.repo.LockSalaries(ctx, sal.EmployeeID); err != nil {
+			return fmt.Errorf("failed to lock salaries of %d: %w", sal.EmployeeID, err)
+		}
+		history, err := s.repo.ListSalaries(ctx, sal.EmployeeID)
+		if err != nil {
+			return fmt.Errorf("failed to load salaries of %d: %w", sal.EmployeeID, err)
+		}
+		for _, cur := range history {
+			// A new current salary ends the previous one, if it starts later
+			if cur.IsCurrent() && sal.IsCurrent() && sal.FromDate.After(cur.FromDate) {
+				continue
+			}
+			if sal.Overlaps(cur) {
+				verr.Add("from_date", "overlaps the salary from %s to %s", cur.FromDate.Format("2006-01-02"), cur.ToDate.Format("2006-01-02"))
+				return verr
+			}
+		}
+		return s.repo.AddSalary(ctx, sal)
+	})
+}
+
+func (s *salaryService) UpdateAmount(ctx context.Context, empNo int, fromDate time.Time, amount int) error {
+	if amount <= 0 {
+		verr := &domain.ValidationError{}
+		verr.Add("salary", "must be positive, got %d", amount)
+		return verr
+	}
+	return s.repo.UpdateSalaryAmount(ctx, empNo, fromDate, amount)
+}
+
+func (s *salaryService) Delete(ctx context.Context, empNo int, fromDate time.Time) error {
+	return s.repo.DeleteSalary(ctx, empNo, fromDate)
+}
@@ -1,7 +1,14 @@
 package serviceutils
 
 import (
+	"errors"
+	"net/http"
+	"strings"
+
 	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/apperror"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/logger"
 )
 
 type GenericResponse struct {
@@ -9,6 +16,13 @@ type GenericResponse struct {
 	Message string
 	Data    interface{}
 	Error   string
+	// NextCursor continues paginated listings, empty on their last page
+	NextCursor string `json:"next_cursor,omitempty"`
+	// Code identifies the error of failed requests, see apperror.CodeOf
+	Code string `json:"code,omitempty"`
+	// RequestID identifies the failed request in the logs, see
+	// logger.RequestID
+	RequestID string `json:"request_id,omitempty"`
 }
 
 func SuccessJSON(data interface{}, msg string) GenericResponse {
@@ -34,13 +48,71 @@ func ResponseSuccess(c echo.Context, code int, msg string, data interface{}) err
 	})
 }
 
+// ResponsePage answers one page of a listing with the cursor of the next.
+func ResponsePage(c echo.Context, code int, msg string, data interface{}, nextCursor string) error {
+	return c.JSON(code, GenericResponse{
+		Success:    true,
+		Message:    msg,
+		Data:       data,
+		NextCursor: nextCursor,
+	})
+}
+
+// ResponseError answers code with the error envelope. Its Code is the one
+// of a typed err, otherwise derived from the status, e.g. "not_found".
 func ResponseError(c echo.Context, code int, msg string, err error) error {
 	resp := GenericResponse{
-		Success: false,
-		Message: msg,
+		Success:   false,
+		Message:   msg,
+		Code:      StatusCode(code),
+		RequestID: logger.RequestID(c.Request().Context()),
 	}
 	if err != nil {
 		resp.Error = err.Error()
+		var aerr *apperror.Error
+		if errors.As(err, &aerr) && aerr.Code != "" {
+			resp.Code = aerr.Code
+		}
 	}
 	return c.JSON(code, resp)
 }
+
+// ResponseAppError answers err with the status of its apperror.Kind:
+// validation errors with 400 and their field errors as data, missing
+// records with 404, conflicts with 409 and anything else with 500.
+func ResponseAppError(c echo.Context, msg string, err error) error {
+	var verr *domain.ValidationError
+	if errors.As(err, &verr) {
+		return ResponseValidationError(c, msg, verr)
+	}
+	return ResponseError(c, HTTPStatus(apperror.KindOf(err)), msg, err)
+}
+
+// HTTPStatus is the status answering errors of kind.
+func HTTPStatus(kind apperror.Kind) int {
+	switch kind {
+	case apperror.KindNotFound:
+		return http.StatusNotFound
+	case apperror.KindConflict:
+		return http.StatusConflict
+	case apperror.KindValidation:
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// StatusCode is the error code of an HTTP status without a more specific
+// one, its status text in snake case, e.g. "too_many_requests".
+func StatusCode(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return apperror.CodeValidation
+	case http.StatusInternalServerError:
+		return apperror.CodeInternal
+	}
+	text := http.StatusText(status)
+	if text == "" {
+		return apperror.CodeInternal
+	}
+	return strings.ReplaceAll(strings.ToLower(text), " ", "_")
+}
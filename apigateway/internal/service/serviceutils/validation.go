@@ -0,0 +1,70 @@
+package serviceutils
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/apperror"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/domain"
+	"github.com/locvowork/employee_management_sample/apigateway/internal/logger"
+)
+
+// Validatable is implemented by request bodies that check their own fields.
+type Validatable interface {
+	Validate() *domain.ValidationError
+}
+
+// Bind binds the request into req. Malformed bodies are reported as a
+// *domain.ValidationError, on the mistyped field when it is known and on
+// "body" otherwise.
+func Bind(c echo.Context, req interface{}) error {
+	err := c.Bind(req)
+	if err == nil {
+		return nil
+	}
+
+	verr := &domain.ValidationError{}
+	var typeErr *json.UnmarshalTypeError
+	var syntaxErr *json.SyntaxError
+	switch {
+	case errors.As(err, &typeErr) && typeErr.Field != "":
+		verr.Add(typeErr.Field, "must be a %s, got %s", typeErr.Type, typeErr.Value)
+	case errors.As(err, &syntaxErr):
+		verr.Add("body", "is not valid JSON: %v", syntaxErr)
+	default:
+		var herr *echo.HTTPError
+		if errors.As(err, &herr) {
+			verr.Add("body", "%v", herr.Message)
+		} else {
+			verr.Add("body", "%v", err)
+		}
+	}
+	return verr
+}
+
+// BindAndValidate binds the request into req like Bind and, when req is
+// Validatable, validates it.
+func BindAndValidate(c echo.Context, req interface{}) error {
+	if err := Bind(c, req); err != nil {
+		return err
+	}
+	if v, ok := req.(Validatable); ok {
+		return v.Validate().ErrOrNil()
+	}
+	return nil
+}
+
+// ResponseValidationError answers 400 with the field errors as data, the
+// envelope of every rejected request.
+func ResponseValidationError(c echo.Context, msg string, verr *domain.ValidationError) error {
+	return c.JSON(http.StatusBadRequest, GenericResponse{
+		Success:   false,
+		Message:   msg,
+		Data:      verr.Errors,
+		Error:     verr.Error(),
+		Code:      apperror.CodeValidation,
+		RequestID: logger.RequestID(c.Request().Context()),
+	})
+}
@@ -2,6 +2,9 @@ package main
 
 import (
 	"context"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/locvowork/employee_management_sample/apigateway/internal/bootstrap"
 	"github.com/locvowork/employee_management_sample/apigateway/internal/logger"
@@ -15,7 +18,10 @@ func main() {
 		panic(err)
 	}
 
-	if err := app.Run(); err != nil {
+	// Interrupts and SIGTERM shut the app down gracefully, see App.Shutdown
+	runCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := app.Run(runCtx); err != nil {
 		logger.ErrorLog(ctx, "Application failed: %v", err)
 		panic(err)
 	}
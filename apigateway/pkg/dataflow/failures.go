@@ -0,0 +1,95 @@
+package dataflow
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Failure is an item a stage gave up on and the error of its last attempt.
+type Failure struct {
+	Item     interface{}
+	Err      error
+	Attempts int
+}
+
+// Failures collects the failures of the stages it is passed to with
+// WithFailures. It is safe for concurrent use, the zero value is empty.
+type Failures struct {
+	mu   sync.Mutex
+	list []Failure
+}
+
+func (f *Failures) add(failure Failure) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.list = append(f.list, failure)
+}
+
+// List returns the failures recorded so far, in the order they happened.
+func (f *Failures) List() []Failure {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Failure(nil), f.list...)
+}
+
+// Len returns the number of failures recorded so far.
+func (f *Failures) Len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.list)
+}
+
+// Err returns a *PartialFailureError summarizing the failures, nil when
+// there are none.
+func (f *Failures) Err() error {
+	list := f.List()
+	if len(list) == 0 {
+		return nil
+	}
+	return &PartialFailureError{Failures: list}
+}
+
+// PartialFailureError reports the items a pipeline skipped.
+type PartialFailureError struct {
+	Failures []Failure
+}
+
+func (e *PartialFailureError) Error() string {
+	first := e.Failures[0]
+	if len(e.Failures) == 1 {
+		return fmt.Sprintf("1 item failed: %v: %v", first.Item, first.Err)
+	}
+	return fmt.Sprintf("%d items failed, first %v: %v", len(e.Failures), first.Item, first.Err)
+}
+
+// Unwrap returns the errors of the failed items.
+func (e *PartialFailureError) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, f := range e.Failures {
+		errs[i] = f.Err
+	}
+	return errs
+}
+
+// fail passes the error of an item to the error handler, Failures and dead
+// letter channel of the stage, and reports whether any of them took it.
+func (c *config) fail(ctx context.Context, msg interface{}, err error) bool {
+	handled := c.errorHandler != nil && c.errorHandler(err)
+	if err == errSkip {
+		return handled
+	}
+	failure := Failure{Item: msg, Err: err, Attempts: c.maxRetries + 1}
+	if c.failures != nil {
+		c.failures.add(failure)
+		handled = true
+	}
+	if c.deadLetter != nil {
+		select {
+		case <-ctx.Done():
+		case c.deadLetter <- failure:
+		}
+		handled = true
+	}
+	return handled
+}
@@ -0,0 +1,104 @@
+package dataflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errFetch = errors.New("fetch failed")
+
+func fetchOrFail(msg interface{}) (interface{}, error) {
+	if msg == "bad" {
+		return nil, errFetch
+	}
+	return msg.(string) + "!", nil
+}
+
+func TestWithFailures(t *testing.T) {
+	ctx := context.Background()
+	var failures Failures
+	var logged []error
+	var mu sync.Mutex
+
+	out := Map(ctx, From(ctx, "a", "bad", "b"), fetchOrFail,
+		WithRetry(2, ConstantBackoff(time.Millisecond)),
+		WithFailures(&failures),
+		WithErrorHandler(func(err error) bool {
+			mu.Lock()
+			defer mu.Unlock()
+			logged = append(logged, err)
+			return true
+		}))
+
+	items, err := Collect(ctx, out)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"a!", "b!"}, items, "the other items continue")
+	assert.Equal(t, []error{errFetch}, logged)
+	assert.Equal(t, []Failure{{Item: "bad", Err: errFetch, Attempts: 3}}, failures.List())
+
+	err = failures.Err()
+	assert.EqualError(t, err, "1 item failed: bad: fetch failed")
+	assert.ErrorIs(t, err, errFetch)
+	var partial *PartialFailureError
+	require.ErrorAs(t, err, &partial)
+	assert.Len(t, partial.Failures, 1)
+}
+
+func TestWithFailures_Empty(t *testing.T) {
+	var failures Failures
+	assert.NoError(t, failures.Err())
+	assert.Zero(t, failures.Len())
+}
+
+func TestWithFailures_ForEach(t *testing.T) {
+	ctx := context.Background()
+	var failures Failures
+	var seen []interface{}
+
+	err := ForEach(ctx, From(ctx, 1, 2, 3, 4), func(msg interface{}) error {
+		if msg.(int)%2 == 0 {
+			return fmt.Errorf("item %d", msg)
+		}
+		seen = append(seen, msg)
+		return nil
+	}, WithFailures(&failures))
+
+	assert.NoError(t, err, "recorded failures are not returned")
+	assert.Equal(t, []interface{}{1, 3}, seen)
+	assert.EqualError(t, failures.Err(), "2 items failed, first 2: item 2")
+}
+
+func TestWithDeadLetter(t *testing.T) {
+	ctx := context.Background()
+	dead := make(chan Failure, 1)
+
+	out := FlatMap(ctx, From(ctx, "bad", "a"), fetchOrFail, WithDeadLetter(dead))
+	items, err := Collect(ctx, out)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{"a!"}, items)
+
+	require.Len(t, dead, 1)
+	failure := <-dead
+	assert.Equal(t, "bad", failure.Item)
+	assert.Equal(t, errFetch, failure.Err)
+	assert.Equal(t, 1, failure.Attempts)
+}
+
+func TestFilter_NotAFailure(t *testing.T) {
+	ctx := context.Background()
+	var failures Failures
+
+	items, err := Collect(ctx, Filter(ctx, From(ctx, 1, 2), func(msg interface{}) bool {
+		return msg == 1
+	}, WithFailures(&failures)))
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{1}, items)
+	assert.Zero(t, failures.Len(), "filtered items did not fail")
+}
@@ -0,0 +1,44 @@
+//go:build faultinject
+
+package dataflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/faultinject"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFaultStage_Retried(t *testing.T) {
+	defer faultinject.Enable(FaultStage, faultinject.Fault{Err: errors.New("flaky"), Times: 2})()
+	ctx := context.Background()
+
+	var got []interface{}
+	err := ForEach(ctx, From(ctx, 1, 2, 3), func(msg interface{}) error {
+		got = append(got, msg)
+		return nil
+	}, WithRetry(2, func(int) time.Duration { return time.Millisecond }))
+
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{1, 2, 3}, got)
+	assert.Equal(t, 2, faultinject.Fired(FaultStage))
+}
+
+func TestFaultStage_Dropped(t *testing.T) {
+	defer faultinject.Enable(FaultStage, faultinject.Fault{Err: errors.New("down"), Skip: 1, Times: 1})()
+	ctx := context.Background()
+
+	out := Map(ctx, From(ctx, 1, 2, 3), func(msg interface{}) (interface{}, error) {
+		return msg.(int) * 10, nil
+	})
+	var got []interface{}
+	for v := range out {
+		got = append(got, v)
+	}
+	// Unhandled errors drop the item
+	assert.Equal(t, []interface{}{10, 30}, got)
+}
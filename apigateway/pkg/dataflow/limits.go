@@ -0,0 +1,60 @@
+package dataflow
+
+import (
+	"context"
+	"sync"
+)
+
+// keySlots bounds the items of each key processed at once.
+type keySlots struct {
+	key   func(interface{}) string
+	n     int
+	mu    sync.Mutex
+	slots map[string]*keySlot
+}
+
+// keySlot is the semaphore of one key, dropped once no worker uses it.
+type keySlot struct {
+	sem   chan struct{}
+	users int
+}
+
+// acquire waits for a slot of the key of msg and returns its release, false
+// when the context is done first.
+func (k *keySlots) acquire(ctx context.Context, msg interface{}) (func(), bool) {
+	key := k.key(msg)
+	k.mu.Lock()
+	slot, ok := k.slots[key]
+	if !ok {
+		slot = &keySlot{sem: make(chan struct{}, k.n)}
+		k.slots[key] = slot
+	}
+	slot.users++
+	k.mu.Unlock()
+
+	done := func() {
+		k.mu.Lock()
+		defer k.mu.Unlock()
+		if slot.users--; slot.users == 0 {
+			delete(k.slots, key)
+		}
+	}
+	select {
+	case <-ctx.Done():
+		done()
+		return nil, false
+	case slot.sem <- struct{}{}:
+	}
+	return func() {
+		<-slot.sem
+		done()
+	}, true
+}
+
+// acquire waits for the key slot of msg when the stage has per-key limits.
+func (c *config) acquire(ctx context.Context, msg interface{}) (func(), bool) {
+	if c.keys == nil {
+		return func() {}, true
+	}
+	return c.keys.acquire(ctx, msg)
+}
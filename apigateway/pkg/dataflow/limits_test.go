@@ -0,0 +1,109 @@
+package dataflow
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithRateLimit(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	var calls []time.Time
+
+	out := Map(ctx, From(ctx, 1, 2, 3, 4), func(msg interface{}) (interface{}, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		calls = append(calls, time.Now())
+		return msg, nil
+	}, WithWorkers(4), WithRateLimit(10, 200*time.Millisecond))
+
+	items, err := Collect(ctx, out)
+	require.NoError(t, err)
+	assert.Len(t, items, 4)
+	require.Len(t, calls, 4)
+	assert.GreaterOrEqual(t, calls[3].Sub(calls[0]), 50*time.Millisecond,
+		"4 calls at 10 per 200ms are 20ms apart, across workers")
+}
+
+func TestWithRateLimit_Retries(t *testing.T) {
+	ctx := context.Background()
+	var attempts int32
+	start := time.Now()
+
+	err := ForEach(ctx, From(ctx, "item"), func(msg interface{}) error {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			return errors.New("flaky")
+		}
+		return nil
+	}, WithRetry(2, nil), WithRateLimit(1, 30*time.Millisecond))
+
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), attempts)
+	assert.GreaterOrEqual(t, time.Since(start), 55*time.Millisecond, "retries wait for the limit too")
+}
+
+func TestWithRateLimit_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var failures Failures
+
+	out := Map(ctx, From(ctx, 1, 2), func(msg interface{}) (interface{}, error) {
+		cancel()
+		return msg, nil
+	}, WithRateLimit(1, time.Hour), WithFailures(&failures))
+
+	for range out {
+	}
+	assert.Zero(t, failures.Len(), "cancelled waits are not failures")
+}
+
+func TestWithKeyConcurrency(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	active := make(map[string]int)
+	peak := make(map[string]int)
+	host := func(msg interface{}) string {
+		return strings.SplitN(msg.(string), "/", 2)[0]
+	}
+
+	urls := []interface{}{"a/1", "a/2", "a/3", "a/4", "b/1", "b/2", "b/3", "b/4"}
+	err := ForEach(ctx, From(ctx, urls...), func(msg interface{}) error {
+		h := host(msg)
+		mu.Lock()
+		active[h]++
+		if active[h] > peak[h] {
+			peak[h] = active[h]
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		active[h]--
+		mu.Unlock()
+		return nil
+	}, WithWorkers(8), WithKeyConcurrency(host, 2))
+
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"a": 2, "b": 2}, peak)
+}
+
+func TestWithKeyConcurrency_ReleasesSlots(t *testing.T) {
+	ctx := context.Background()
+	slots := &keySlots{key: func(msg interface{}) string { return msg.(string) }, n: 1, slots: make(map[string]*keySlot)}
+
+	release, ok := slots.acquire(ctx, "a")
+	require.True(t, ok)
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	_, ok = slots.acquire(cctx, "a")
+	assert.False(t, ok, "the only slot of a is taken")
+
+	release()
+	assert.Empty(t, slots.slots, "unused keys are dropped")
+}
@@ -0,0 +1,41 @@
+package dataflow
+
+import "time"
+
+// Observer receives the measurements of the stages it is passed to with
+// WithObserver, e.g. to export them as metrics. Its methods are called by
+// the workers of the stages concurrently and must not block.
+type Observer interface {
+	// ItemStarted is called when a worker takes an item, queued is the
+	// number of items still waiting on the input of the stage.
+	ItemStarted(stage string, queued int)
+	// ItemDone is called once the item was processed, after its retries,
+	// with the time it took and the error of its last attempt, nil on
+	// success. Items filtered out are successes.
+	ItemDone(stage string, d time.Duration, err error)
+	// Retried is called before each retry of an item, attempt counts from 1.
+	Retried(stage string, attempt int)
+}
+
+// WithObserver reports the items of the stage to o under the name stage.
+// Throughput and latency follow from ItemDone, the items in flight from
+// ItemStarted less ItemDone.
+func WithObserver(stage string, o Observer) Option {
+	return func(c *config) {
+		c.observer = o
+		c.stage = stage
+	}
+}
+
+// observe reports an item taken from the input and returns the function
+// reporting it done.
+func (c *config) observe(queued int) func(error) {
+	if c.observer == nil {
+		return func(error) {}
+	}
+	c.observer.ItemStarted(c.stage, queued)
+	start := time.Now()
+	return func(err error) {
+		c.observer.ItemDone(c.stage, time.Since(start), err)
+	}
+}
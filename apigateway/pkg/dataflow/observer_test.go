@@ -0,0 +1,99 @@
+package dataflow
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordingObserver struct {
+	mu       sync.Mutex
+	started  map[string]int
+	queued   []int
+	done     map[string]int
+	failed   map[string]int
+	retries  map[string]int
+	inFlight int
+}
+
+func newRecordingObserver() *recordingObserver {
+	return &recordingObserver{
+		started: make(map[string]int), done: make(map[string]int),
+		failed: make(map[string]int), retries: make(map[string]int),
+	}
+}
+
+func (o *recordingObserver) ItemStarted(stage string, queued int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.started[stage]++
+	o.queued = append(o.queued, queued)
+	o.inFlight++
+}
+
+func (o *recordingObserver) ItemDone(stage string, d time.Duration, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.done[stage]++
+	if err != nil {
+		o.failed[stage]++
+	}
+	o.inFlight--
+}
+
+func (o *recordingObserver) Retried(stage string, attempt int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.retries[stage]++
+}
+
+func TestWithObserver(t *testing.T) {
+	ctx := context.Background()
+	obs := newRecordingObserver()
+	var attempts sync.Map
+
+	fetched := Map(ctx, From(ctx, 1, 2, 3, 4), func(msg interface{}) (interface{}, error) {
+		n, _ := attempts.LoadOrStore(msg, 0)
+		attempts.Store(msg, n.(int)+1)
+		if msg == 2 && n.(int) == 0 {
+			return nil, errors.New("flaky")
+		}
+		if msg == 4 {
+			return nil, errors.New("down")
+		}
+		return msg, nil
+	}, WithWorkers(2), WithRetry(1, nil), WithObserver("fetch", obs))
+	odd := Filter(ctx, fetched, func(msg interface{}) bool {
+		return msg.(int)%2 == 1
+	}, WithObserver("odd", obs))
+
+	items, err := Collect(ctx, odd)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []interface{}{1, 3}, items)
+
+	assert.Equal(t, map[string]int{"fetch": 4, "odd": 3}, obs.started)
+	assert.Equal(t, map[string]int{"fetch": 4, "odd": 3}, obs.done)
+	assert.Equal(t, map[string]int{"fetch": 1}, obs.failed, "filtered items are not failures")
+	assert.Equal(t, map[string]int{"fetch": 2}, obs.retries)
+	assert.Zero(t, obs.inFlight)
+	assert.Contains(t, obs.queued, 3, "the first item leaves 3 queued on the buffered source")
+}
+
+func TestWithObserver_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	obs := newRecordingObserver()
+
+	err := ForEach(ctx, From(ctx, 1), func(msg interface{}) error {
+		cancel()
+		return errors.New("interrupted")
+	}, WithRetry(3, ConstantBackoff(time.Hour)), WithObserver("sink", obs))
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, map[string]int{"sink": 1}, obs.done, "items cut short are reported done")
+	assert.Zero(t, obs.inFlight)
+}
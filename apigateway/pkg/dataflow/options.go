@@ -2,6 +2,8 @@ package dataflow
 
 import (
 	"time"
+
+	"golang.org/x/time/rate"
 )
 
 // Option configures the behavior of pipeline stages.
@@ -17,6 +19,15 @@ type config struct {
 	// For this library, if errorHandler is nil, we typically drop the error or stop?
 	// Idiomatic: Map returns (value, error). If error, we might drop the item.
 	errorHandler func(error) bool
+	// failures and deadLetter receive the items the stage gave up on
+	failures   *Failures
+	deadLetter chan<- Failure
+	// limiter spaces the calls of the stage, keys bounds them per key
+	limiter *rate.Limiter
+	keys    *keySlots
+	// observer receives the measurements of the stage, named stage
+	observer Observer
+	stage    string
 }
 
 // defaultConfig returns the default configuration.
@@ -64,6 +75,46 @@ func WithErrorHandler(h func(error) bool) Option {
 	}
 }
 
+// WithFailures records the items the stage gives up on in f, after retries,
+// so the pipeline goes on and f summarizes what failed once it finished.
+// ForEach does not return the errors recorded.
+func WithFailures(f *Failures) Option {
+	return func(c *config) {
+		c.failures = f
+	}
+}
+
+// WithDeadLetter sends the items the stage gives up on to ch, after retries.
+// Sends block until ch is read or the context is cancelled. ForEach does not
+// return the errors sent.
+func WithDeadLetter(ch chan<- Failure) Option {
+	return func(c *config) {
+		c.deadLetter = ch
+	}
+}
+
+// WithRateLimit spreads the calls of the stage evenly so there are at most n
+// per period, across all its workers. Retries are calls too, so a stage
+// retrying against a failing service does not exceed the limit either.
+func WithRateLimit(n int, per time.Duration) Option {
+	return func(c *config) {
+		if n > 0 && per > 0 {
+			c.limiter = rate.NewLimiter(rate.Every(per/time.Duration(n)), 1)
+		}
+	}
+}
+
+// WithKeyConcurrency lets at most n workers of the stage process items of
+// the same key at once, e.g. requests to one host. An item keeps its slot
+// through its retries. WithWorkers still bounds the workers of all keys.
+func WithKeyConcurrency(key func(interface{}) string, n int) Option {
+	return func(c *config) {
+		if key != nil && n > 0 {
+			c.keys = &keySlots{key: key, n: n, slots: make(map[string]*keySlot)}
+		}
+	}
+}
+
 // ConstantBackoff returns a backoff function that always returns the same duration.
 func ConstantBackoff(d time.Duration) func(int) time.Duration {
 	return func(_ int) time.Duration {
@@ -3,10 +3,17 @@ package dataflow
 import (
 	"context"
 	"errors"
+	"reflect"
 	"sync"
 	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/faultinject"
 )
 
+// FaultStage fails stage calls before they run, see package faultinject.
+// Retries and error handlers apply to the injected errors.
+const FaultStage = "dataflow.stage"
+
 // Stream is a read-only channel of messages.
 // We use interface{} for Go 1.17 compatibility.
 type Stream <-chan interface{}
@@ -35,10 +42,69 @@ func New(c <-chan interface{}) Stream {
 // Map transforms the stream using the provided function.
 // Supports parallelism via WithWorkers.
 func Map(ctx context.Context, input Stream, fn func(interface{}) (interface{}, error), opts ...Option) Stream {
+	return flatMap(ctx, input, func(msg interface{}) ([]interface{}, error) {
+		res, err := fn(msg)
+		if err != nil {
+			return nil, err
+		}
+		return []interface{}{res}, nil
+	}, opts...)
+}
+
+// FlatMap transforms every item into a slice, e.g. a parsed page into its
+// rows, and emits the elements of the slice one by one. A nil result emits
+// nothing, anything but a slice or array is emitted as a single item.
+// Supports the same options as Map.
+func FlatMap(ctx context.Context, input Stream, fn func(interface{}) (interface{}, error), opts ...Option) Stream {
+	return flatMap(ctx, input, func(msg interface{}) ([]interface{}, error) {
+		res, err := fn(msg)
+		if err != nil {
+			return nil, err
+		}
+		return elements(res), nil
+	}, opts...)
+}
+
+// elements returns the elements of a slice or array, v itself otherwise.
+func elements(v interface{}) []interface{} {
+	if v == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return []interface{}{v}
+	}
+	items := make([]interface{}, rv.Len())
+	for i := range items {
+		items[i] = rv.Index(i).Interface()
+	}
+	return items
+}
+
+// flatMap runs the workers of Map and FlatMap, emitting every item fn returns.
+func flatMap(ctx context.Context, input Stream, fn func(interface{}) ([]interface{}, error), opts ...Option) Stream {
 	cfg := defaultConfig()
 	for _, o := range opts {
 		o(cfg)
 	}
+	if faultinject.Enabled {
+		stage := fn
+		fn = func(msg interface{}) ([]interface{}, error) {
+			if err := faultinject.Hit(ctx, FaultStage); err != nil {
+				return nil, err
+			}
+			return stage(msg)
+		}
+	}
+	if cfg.limiter != nil {
+		limited := fn
+		fn = func(msg interface{}) ([]interface{}, error) {
+			if err := cfg.limiter.Wait(ctx); err != nil {
+				return nil, err
+			}
+			return limited(msg)
+		}
+	}
 
 	out := make(chan interface{}, cfg.bufferSize)
 	var wg sync.WaitGroup
@@ -55,50 +121,28 @@ func Map(ctx context.Context, input Stream, fn func(interface{}) (interface{}, e
 					return
 				}
 
-				// Retry logic wrapper
-				var res interface{}
-				var err error
-
-				// Attempt 0
-				res, err = fn(msg)
-
-				// Retries
-				if err != nil && cfg.maxRetries > 0 {
-					for i := 1; i <= cfg.maxRetries; i++ {
-						if cfg.backoff != nil {
-							select {
-							case <-ctx.Done():
-								return
-							case <-time.After(cfg.backoff(i)):
-							}
-						}
-						res, err = fn(msg)
-						if err == nil {
-							break
-						}
-					}
+				var res []interface{}
+				ok, err := cfg.run(ctx, msg, len(input), func() error {
+					var err error
+					res, err = fn(msg)
+					return err
+				})
+				if !ok {
+					return
 				}
-
 				if err != nil {
-					// Handle error
-					handled := false
-					if cfg.errorHandler != nil {
-						handled = cfg.errorHandler(err)
-					}
-					if !handled {
-						// Drop item by default if not handled.
-						// To stop pipeline on error, one would need to cancel context externally
-						// or we'd need a way to return the error.
-						// For this simple Map, we assume dropping.
-					}
+					// Failed items are dropped, handled or not
+					cfg.fail(ctx, msg, err)
 					continue
 				}
 
-				// Send result
-				select {
-				case <-ctx.Done():
-					return
-				case out <- res:
+				// Send results
+				for _, item := range res {
+					select {
+					case <-ctx.Done():
+						return
+					case out <- item:
+					}
 				}
 			}
 		}
@@ -152,6 +196,24 @@ func ForEach(ctx context.Context, input Stream, fn func(interface{}) error, opts
 	for _, o := range opts {
 		o(cfg)
 	}
+	if faultinject.Enabled {
+		stage := fn
+		fn = func(msg interface{}) error {
+			if err := faultinject.Hit(ctx, FaultStage); err != nil {
+				return err
+			}
+			return stage(msg)
+		}
+	}
+	if cfg.limiter != nil {
+		limited := fn
+		fn = func(msg interface{}) error {
+			if err := cfg.limiter.Wait(ctx); err != nil {
+				return err
+			}
+			return limited(msg)
+		}
+	}
 
 	var wg sync.WaitGroup
 	var errOnce sync.Once
@@ -168,33 +230,17 @@ func ForEach(ctx context.Context, input Stream, fn func(interface{}) error, opts
 					return
 				}
 
-				// Retry/Execution logic similar to Map
-				var err error
-				err = fn(msg) // Attempt 0
-
-				if err != nil && cfg.maxRetries > 0 {
-					for i := 1; i <= cfg.maxRetries; i++ {
-						if cfg.backoff != nil {
-							select {
-							case <-ctx.Done():
-								return
-							case <-time.After(cfg.backoff(i)):
-							}
-						}
-						err = fn(msg)
-						if err == nil {
-							break
-						}
-					}
+				ok, err := cfg.run(ctx, msg, len(input), func() error {
+					return fn(msg)
+				})
+				if !ok {
+					return
 				}
 
 				if err != nil {
-					if cfg.errorHandler != nil {
-						if cfg.errorHandler(err) {
-							continue
-						}
+					if cfg.fail(ctx, msg, err) {
+						continue
 					}
-					// If not handled, record error?
 					errOnce.Do(func() {
 						firstErr = err
 					})
@@ -217,3 +263,44 @@ func ForEach(ctx context.Context, input Stream, fn func(interface{}) error, opts
 	}
 	return firstErr
 }
+
+// run calls attempt for msg until it succeeds or the retries are exhausted,
+// holding the key slot of msg and reporting to the observer. queued is the
+// length of the stage input. It returns false when the context ended
+// first, and the error of the last attempt.
+func (c *config) run(ctx context.Context, msg interface{}, queued int, attempt func() error) (bool, error) {
+	done := c.observe(queued)
+	// The key slot is held across retries
+	release, ok := c.acquire(ctx, msg)
+	if !ok {
+		done(ctx.Err())
+		return false, nil
+	}
+	defer release()
+
+	err := attempt()
+	for i := 1; err != nil && i <= c.maxRetries; i++ {
+		if c.backoff != nil {
+			select {
+			case <-ctx.Done():
+				done(ctx.Err())
+				return false, nil
+			case <-time.After(c.backoff(i)):
+			}
+		}
+		if c.observer != nil {
+			c.observer.Retried(c.stage, i)
+		}
+		err = attempt()
+	}
+	if err != nil && ctx.Err() != nil {
+		done(ctx.Err())
+		return false, nil
+	}
+	if err == errSkip {
+		done(nil)
+	} else {
+		done(err)
+	}
+	return true, err
+}
@@ -0,0 +1,79 @@
+// Package promobserver exports the measurements of dataflow stages as
+// Prometheus metrics, labelled by stage name.
+//
+//	obs, err := promobserver.New(prometheus.DefaultRegisterer, "export")
+//	bodies := dataflow.Map(ctx, urls, fetch, dataflow.WithObserver("fetch", obs))
+package promobserver
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Observer is a dataflow.Observer updating Prometheus metrics:
+//
+//	<namespace>_dataflow_items_total{stage,result}     items processed, result "ok" or "error"
+//	<namespace>_dataflow_items_in_flight{stage}        items being processed
+//	<namespace>_dataflow_queue_depth{stage}            items waiting on the stage input, when the last was taken
+//	<namespace>_dataflow_retries_total{stage}          retries
+//	<namespace>_dataflow_item_duration_seconds{stage}  time per item, retries included
+type Observer struct {
+	items    *prometheus.CounterVec
+	inFlight *prometheus.GaugeVec
+	queue    *prometheus.GaugeVec
+	retries  *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// New creates the metrics under namespace and registers them with reg.
+func New(reg prometheus.Registerer, namespace string) (*Observer, error) {
+	o := &Observer{
+		items: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "dataflow", Name: "items_total",
+			Help: "Items processed by the stage, by result.",
+		}, []string{"stage", "result"}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace, Subsystem: "dataflow", Name: "items_in_flight",
+			Help: "Items the stage is processing.",
+		}, []string{"stage"}),
+		queue: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace, Subsystem: "dataflow", Name: "queue_depth",
+			Help: "Items waiting on the stage input when it last took one.",
+		}, []string{"stage"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace, Subsystem: "dataflow", Name: "retries_total",
+			Help: "Retries of the stage.",
+		}, []string{"stage"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace, Subsystem: "dataflow", Name: "item_duration_seconds",
+			Help:    "Time the stage took per item, retries included.",
+			Buckets: prometheus.ExponentialBuckets(0.001, 4, 10),
+		}, []string{"stage"}),
+	}
+	for _, c := range []prometheus.Collector{o.items, o.inFlight, o.queue, o.retries, o.duration} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+func (o *Observer) ItemStarted(stage string, queued int) {
+	o.inFlight.WithLabelValues(stage).Inc()
+	o.queue.WithLabelValues(stage).Set(float64(queued))
+}
+
+func (o *Observer) ItemDone(stage string, d time.Duration, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	o.inFlight.WithLabelValues(stage).Dec()
+	o.items.WithLabelValues(stage, result).Inc()
+	o.duration.WithLabelValues(stage).Observe(d.Seconds())
+}
+
+func (o *Observer) Retried(stage string, attempt int) {
+	o.retries.WithLabelValues(stage).Inc()
+}
@@ -0,0 +1,38 @@
+package promobserver
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/dataflow"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestObserver(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	obs, err := New(reg, "export")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	err = dataflow.ForEach(ctx, dataflow.From(ctx, 1, 2, 3), func(msg interface{}) error {
+		if msg == 3 {
+			return errors.New("down")
+		}
+		return nil
+	}, dataflow.WithRetry(2, nil), dataflow.WithObserver("save", obs))
+	require.EqualError(t, err, "down")
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(obs.items.WithLabelValues("save", "ok")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(obs.items.WithLabelValues("save", "error")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(obs.retries.WithLabelValues("save")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(obs.inFlight.WithLabelValues("save")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(obs.queue.WithLabelValues("save")), "the last item left none queued")
+	assert.Equal(t, 1, testutil.CollectAndCount(obs.duration))
+
+	_, err = New(reg, "export")
+	assert.Error(t, err, "metrics are registered once per registry")
+}
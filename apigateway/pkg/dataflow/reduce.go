@@ -0,0 +1,35 @@
+package dataflow
+
+import "context"
+
+// Reduce folds the stream into a single value, starting from initial. fn
+// runs on one item at a time, in arrival order. It returns the first error
+// of fn, or the context's error when cancelled before the stream ended.
+func Reduce(ctx context.Context, input Stream, initial interface{}, fn func(acc, msg interface{}) (interface{}, error)) (interface{}, error) {
+	acc := initial
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case msg, ok := <-input:
+			if !ok {
+				return acc, nil
+			}
+			var err error
+			if acc, err = fn(acc, msg); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// Collect returns the items of the stream in arrival order.
+func Collect(ctx context.Context, input Stream) ([]interface{}, error) {
+	items, err := Reduce(ctx, input, []interface{}(nil), func(acc, msg interface{}) (interface{}, error) {
+		return append(acc.([]interface{}), msg), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return items.([]interface{}), nil
+}
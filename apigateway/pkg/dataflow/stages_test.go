@@ -0,0 +1,107 @@
+package dataflow
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilter(t *testing.T) {
+	ctx := context.Background()
+	evens := Filter(ctx, From(ctx, 1, 2, 3, 4), func(msg interface{}) bool {
+		return msg.(int)%2 == 0
+	})
+
+	items, err := Collect(ctx, evens)
+	require.NoError(t, err)
+	assert.Equal(t, []interface{}{2, 4}, items)
+}
+
+func TestFlatMap(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("ExpandsSlices", func(t *testing.T) {
+		words := FlatMap(ctx, From(ctx, "a b", "", "c"), func(msg interface{}) (interface{}, error) {
+			if msg == "" {
+				return nil, nil
+			}
+			var out []string
+			for _, r := range msg.(string) {
+				if r != ' ' {
+					out = append(out, string(r))
+				}
+			}
+			return out, nil
+		})
+
+		items, err := Collect(ctx, words)
+		require.NoError(t, err)
+		assert.Equal(t, []interface{}{"a", "b", "c"}, items)
+	})
+
+	t.Run("Workers", func(t *testing.T) {
+		pairs := FlatMap(ctx, From(ctx, 1, 2, 3), func(msg interface{}) (interface{}, error) {
+			return [2]int{msg.(int), -msg.(int)}, nil
+		}, WithWorkers(3))
+
+		items, err := Collect(ctx, pairs)
+		require.NoError(t, err)
+		ints := make([]int, len(items))
+		for i, item := range items {
+			ints[i] = item.(int)
+		}
+		sort.Ints(ints)
+		assert.Equal(t, []int{-3, -2, -1, 1, 2, 3}, ints)
+	})
+
+	t.Run("ScalarsAndErrors", func(t *testing.T) {
+		out := FlatMap(ctx, From(ctx, 1, 2), func(msg interface{}) (interface{}, error) {
+			if msg == 2 {
+				return nil, errors.New("bad item")
+			}
+			return msg, nil
+		})
+
+		items, err := Collect(ctx, out)
+		require.NoError(t, err)
+		assert.Equal(t, []interface{}{1}, items, "failed items are dropped like in Map")
+	})
+}
+
+func TestReduce(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Sum", func(t *testing.T) {
+		sum, err := Reduce(ctx, From(ctx, 1, 2, 3), 10, func(acc, msg interface{}) (interface{}, error) {
+			return acc.(int) + msg.(int), nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 16, sum)
+	})
+
+	t.Run("Typed", func(t *testing.T) {
+		names, err := Reduce(ctx, From(ctx, "Ann", "Bob"), []string(nil), func(acc, msg interface{}) (interface{}, error) {
+			return append(acc.([]string), msg.(string)), nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"Ann", "Bob"}, names)
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		_, err := Reduce(ctx, From(ctx, 1, 2), 0, func(acc, msg interface{}) (interface{}, error) {
+			return nil, errors.New("stop")
+		})
+		assert.EqualError(t, err, "stop")
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		cctx, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := Collect(cctx, New(make(chan interface{})))
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+}
@@ -0,0 +1,95 @@
+// Package exceltest asserts on the content of xlsx workbooks in tests, so
+// exporter and handler tests can check the actual output:
+//
+//	exceltest.AssertCellValue(t, rec.Body.Bytes(), "Report", "B3", "Alice")
+//
+// The Assert functions report failures with t.Errorf and return whether the
+// assertion held, like testify's assert. Workbooks that cannot be opened
+// fail the test with t.Fatalf.
+package exceltest
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+)
+
+// Open opens an xlsx workbook, closed when the test ends.
+func Open(t testing.TB, data []byte) *excelize.File {
+	t.Helper()
+	f, err := excelize.OpenReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("open workbook: %v", err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
+
+// AssertCellValue asserts the formatted value of a cell, as Excel shows it.
+func AssertCellValue(t testing.TB, data []byte, sheet, cell, want string) bool {
+	t.Helper()
+	got, err := Open(t, data).GetCellValue(sheet, cell)
+	if err != nil {
+		t.Errorf("cell %s!%s: %v", sheet, cell, err)
+		return false
+	}
+	return assert.Equal(t, want, got, "cell %s!%s", sheet, cell)
+}
+
+// AssertRows asserts the formatted values of every row of a sheet. Trailing
+// empty cells of a row and trailing empty rows are not included.
+func AssertRows(t testing.TB, data []byte, sheet string, want [][]string) bool {
+	t.Helper()
+	got, err := Open(t, data).GetRows(sheet)
+	if err != nil {
+		t.Errorf("rows of %s: %v", sheet, err)
+		return false
+	}
+	return assert.Equal(t, want, got, "rows of %s", sheet)
+}
+
+// AssertSheets asserts the sheet names of the workbook, in order.
+func AssertSheets(t testing.TB, data []byte, want ...string) bool {
+	t.Helper()
+	return assert.Equal(t, want, Open(t, data).GetSheetList(), "sheets")
+}
+
+// AssertSheetProtected asserts that a sheet is protected.
+func AssertSheetProtected(t testing.TB, data []byte, sheet string) bool {
+	t.Helper()
+	// Unprotecting with a password fails with ErrUnprotectSheet only for
+	// sheets without protection
+	err := Open(t, data).UnprotectSheet(sheet, "exceltest")
+	if errors.Is(err, excelize.ErrUnprotectSheet) {
+		t.Errorf("sheet %s is not protected", sheet)
+		return false
+	}
+	if err != nil && !errors.Is(err, excelize.ErrUnprotectSheetPassword) {
+		t.Errorf("sheet %s: %v", sheet, err)
+		return false
+	}
+	return true
+}
+
+// AssertMergedRange asserts that a range of a sheet, e.g. "A1:C1", is merged
+// as a whole.
+func AssertMergedRange(t testing.TB, data []byte, sheet, rng string) bool {
+	t.Helper()
+	merged, err := Open(t, data).GetMergeCells(sheet)
+	if err != nil {
+		t.Errorf("merged cells of %s: %v", sheet, err)
+		return false
+	}
+	ranges := make([]string, len(merged))
+	for i, m := range merged {
+		ranges[i] = m.GetStartAxis() + ":" + m.GetEndAxis()
+		if ranges[i] == rng {
+			return true
+		}
+	}
+	t.Errorf("range %s!%s is not merged, merged ranges: %v", sheet, rng, ranges)
+	return false
+}
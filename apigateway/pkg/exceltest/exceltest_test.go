@@ -0,0 +1,64 @@
+package exceltest
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+// recorder records the failures of assertions expected to fail.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func testWorkbook(t *testing.T) []byte {
+	f := excelize.NewFile()
+	defer f.Close()
+	require.NoError(t, f.SetSheetName("Sheet1", "Report"))
+	require.NoError(t, f.SetSheetRow("Report", "A1", &[]interface{}{"Staff"}))
+	require.NoError(t, f.SetSheetRow("Report", "A2", &[]interface{}{"Name", "Salary"}))
+	require.NoError(t, f.SetSheetRow("Report", "A3", &[]interface{}{"Alice", 5000}))
+	require.NoError(t, f.MergeCell("Report", "A1", "B1"))
+	require.NoError(t, f.ProtectSheet("Report", &excelize.SheetProtectionOptions{Password: "s3cret"}))
+	_, err := f.NewSheet("Notes")
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, f.Write(&buf))
+	return buf.Bytes()
+}
+
+func TestAssertions(t *testing.T) {
+	data := testWorkbook(t)
+
+	AssertSheets(t, data, "Report", "Notes")
+	AssertCellValue(t, data, "Report", "A3", "Alice")
+	AssertCellValue(t, data, "Report", "B3", "5000")
+	AssertRows(t, data, "Report", [][]string{{"Staff"}, {"Name", "Salary"}, {"Alice", "5000"}})
+	AssertSheetProtected(t, data, "Report")
+	AssertMergedRange(t, data, "Report", "A1:B1")
+}
+
+func TestAssertions_Failures(t *testing.T) {
+	data := testWorkbook(t)
+	r := &recorder{TB: t}
+
+	assert.False(t, AssertCellValue(r, data, "Report", "A3", "Bob"))
+	assert.False(t, AssertSheetProtected(r, data, "Notes"))
+	assert.False(t, AssertMergedRange(r, data, "Report", "A2:B2"))
+	assert.False(t, AssertCellValue(r, data, "Missing", "A1", ""))
+	require.Len(t, r.errors, 4)
+	assert.Contains(t, r.errors[1], "sheet Notes is not protected")
+	assert.Contains(t, r.errors[2], "range Report!A2:B2 is not merged, merged ranges: [A1:B1]")
+}
@@ -0,0 +1,67 @@
+// Package exporter defines the interface shared by the Excel engines
+// (simpleexcel, simpleexcelv2 and simpleexcelv3), so callers can build and
+// write a workbook without depending on one engine:
+//
+//	var x exporter.Exporter = simpleexcelv2.NewExcelDataExporter().AsExporter()
+//	x.AddSheet("Staff").AddSection(&exporter.Section{ID: "staff", ShowHeader: true})
+//	x.Bind("staff", employees)
+//	err := x.Export(w)
+//
+// Engine-specific settings (YAML templates, formatters, styles) are made on
+// the engine before it is adapted; sections added through the interface use
+// the engine defaults for everything Section does not cover.
+package exporter
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrStreamUnsupported is returned by Stream on engines that only build
+// workbooks in memory.
+var ErrStreamUnsupported = errors.New("exporter: engine does not support streaming")
+
+// Exporter builds a workbook of sheets made of sections and writes it.
+type Exporter interface {
+	// AddSheet adds a sheet, sections are added to it in order.
+	AddSheet(name string) Sheet
+	// Bind binds the rows of a section, a slice of structs or maps.
+	Bind(sectionID string, data interface{}) Exporter
+	// Export builds the workbook from the bound data and writes it to w.
+	Export(w io.Writer) error
+	// Stream starts a streamed export to w, rows are written by section
+	// through the Streamer, which must be closed to finish the workbook.
+	Stream(w io.Writer) (Streamer, error)
+}
+
+// Sheet is a sheet of an Exporter.
+type Sheet interface {
+	AddSection(sec *Section) Sheet
+}
+
+// Streamer writes the rows of a streamed export.
+type Streamer interface {
+	Write(sectionID string, data interface{}) error
+	Close() error
+}
+
+// Section is the engine-neutral configuration of a section.
+type Section struct {
+	ID         string
+	Title      string
+	ShowHeader bool
+	Locked     bool
+	// Data binds the rows with the section, like Exporter.Bind
+	Data interface{}
+	// Columns are detected from the row type when empty
+	Columns []Column
+}
+
+// Column is the engine-neutral configuration of a column.
+type Column struct {
+	FieldName string
+	Header    string
+	Width     float64
+	// FormatterName names a formatter registered with the engine
+	FormatterName string
+}
@@ -0,0 +1,70 @@
+package exporter_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/exceltest"
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/exporter"
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/simpleexcel"
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/simpleexcelv2"
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/simpleexcelv3"
+)
+
+type staffRow struct {
+	Name string
+	Dept string
+}
+
+var engines = map[string]func() exporter.Exporter{
+	"simpleexcel":   func() exporter.Exporter { return simpleexcel.NewDataExporter().AsExporter() },
+	"simpleexcelv2": func() exporter.Exporter { return simpleexcelv2.NewExcelDataExporter().AsExporter() },
+	"simpleexcelv3": func() exporter.Exporter { return simpleexcelv3.NewExcelDataExporterV3V3().AsExporter() },
+}
+
+func staffSection() *exporter.Section {
+	return &exporter.Section{
+		ID:         "staff",
+		ShowHeader: true,
+		Columns:    []exporter.Column{{FieldName: "Name", Header: "Name"}, {FieldName: "Dept", Header: "Department"}},
+	}
+}
+
+func TestExport(t *testing.T) {
+	for name, newExporter := range engines {
+		t.Run(name, func(t *testing.T) {
+			x := newExporter()
+			x.AddSheet("Staff").AddSection(staffSection())
+			x.Bind("staff", []staffRow{{"Ann", "HR"}, {"Bob", "IT"}})
+
+			var buf bytes.Buffer
+			require.NoError(t, x.Export(&buf))
+			exceltest.AssertRows(t, buf.Bytes(), "Staff", [][]string{{"Name", "Department"}, {"Ann", "HR"}, {"Bob", "IT"}})
+		})
+	}
+}
+
+func TestStream(t *testing.T) {
+	for name, newExporter := range engines {
+		t.Run(name, func(t *testing.T) {
+			x := newExporter()
+			x.AddSheet("Staff").AddSection(staffSection())
+
+			var buf bytes.Buffer
+			streamer, err := x.Stream(&buf)
+			if name == "simpleexcel" {
+				assert.True(t, errors.Is(err, exporter.ErrStreamUnsupported), "got %v", err)
+				return
+			}
+			require.NoError(t, err)
+			require.NoError(t, streamer.Write("staff", []staffRow{{"Ann", "HR"}}))
+			require.NoError(t, streamer.Write("staff", []staffRow{{"Bob", "IT"}}))
+			require.NoError(t, streamer.Close())
+			exceltest.AssertRows(t, buf.Bytes(), "Staff", [][]string{{"Name", "Department"}, {"Ann", "HR"}, {"Bob", "IT"}})
+		})
+	}
+}
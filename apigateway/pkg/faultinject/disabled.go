@@ -0,0 +1,15 @@
+//go:build !faultinject
+
+package faultinject
+
+import "context"
+
+// Enabled reports whether faults can be injected in this build.
+const Enabled = false
+
+// Hit returns the error of the fault enabled at the point, if any.
+func Hit(ctx context.Context, point string) error { return nil }
+
+// Batch returns how many of the n items of a batch to process, and the error
+// to return once they are.
+func Batch(ctx context.Context, point string, n int) (int, error) { return n, nil }
@@ -0,0 +1,92 @@
+//go:build faultinject
+
+package faultinject
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Enabled reports whether faults can be injected in this build.
+const Enabled = true
+
+type injection struct {
+	fault Fault
+	hits  int
+	fired int
+}
+
+var (
+	mu         sync.Mutex
+	injections = make(map[string]*injection)
+)
+
+// Enable injects f at the point until the returned function is called.
+func Enable(point string, f Fault) (disable func()) {
+	mu.Lock()
+	defer mu.Unlock()
+	inj := &injection{fault: f}
+	injections[point] = inj
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		if injections[point] == inj {
+			delete(injections, point)
+		}
+	}
+}
+
+// Fired returns how often the fault enabled at the point fired.
+func Fired(point string) int {
+	mu.Lock()
+	defer mu.Unlock()
+	if inj, ok := injections[point]; ok {
+		return inj.fired
+	}
+	return 0
+}
+
+// Hit returns the error of the fault enabled at the point, if any.
+func Hit(ctx context.Context, point string) error {
+	_, err := Batch(ctx, point, 0)
+	return err
+}
+
+// Batch returns how many of the n items of a batch to process, and the error
+// to return once they are.
+func Batch(ctx context.Context, point string, n int) (int, error) {
+	f, ok := fire(point)
+	if !ok {
+		return n, nil
+	}
+	if f.Delay > 0 {
+		t := time.NewTimer(f.Delay)
+		defer t.Stop()
+		select {
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		case <-t.C:
+		}
+	}
+	if f.Err == nil {
+		return n, nil
+	}
+	return min(f.Keep, n), f.Err
+}
+
+// fire counts a hit of the point, returning the fault when it fires.
+func fire(point string) (Fault, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	inj, ok := injections[point]
+	if !ok {
+		return Fault{}, false
+	}
+	inj.hits++
+	if inj.hits <= inj.fault.Skip || (inj.fault.Times > 0 && inj.fired >= inj.fault.Times) {
+		return Fault{}, false
+	}
+	inj.fired++
+	return inj.fault, true
+}
@@ -0,0 +1,28 @@
+// Package faultinject lets tests inject failures into the exporter, the
+// pipelines and the repositories: write errors, slow queries and partial
+// batches.
+//
+// Code marks injection points with Hit and Batch. Points do nothing unless
+// the binary is built with the faultinject tag, which also provides Enable:
+//
+//	go test -tags faultinject ./...
+//
+// Tests enabling faults must carry the same build tag and must not run in
+// parallel with tests going through the same points.
+package faultinject
+
+import "time"
+
+// Fault is what happens when an injection point is hit.
+type Fault struct {
+	// Err is returned by the point; nil only delays it
+	Err error
+	// Delay is waited before returning, cut short when the context is done
+	Delay time.Duration
+	// Keep is the number of items of a batch processed before Err, see Batch
+	Keep int
+	// Skip lets that many hits through before the fault fires
+	Skip int
+	// Times limits how often the fault fires, 0 for every hit after Skip
+	Times int
+}
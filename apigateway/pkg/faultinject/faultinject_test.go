@@ -0,0 +1,62 @@
+//go:build faultinject
+
+package faultinject
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errInjected = errors.New("injected")
+
+func TestHit_NotEnabled(t *testing.T) {
+	assert.NoError(t, Hit(context.Background(), "nowhere"))
+	n, err := Batch(context.Background(), "nowhere", 5)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, n)
+}
+
+func TestHit_SkipAndTimes(t *testing.T) {
+	defer Enable("point", Fault{Err: errInjected, Skip: 1, Times: 2})()
+
+	var errs []error
+	for i := 0; i < 5; i++ {
+		errs = append(errs, Hit(context.Background(), "point"))
+	}
+	assert.Equal(t, []error{nil, errInjected, errInjected, nil, nil}, errs)
+	assert.Equal(t, 2, Fired("point"))
+}
+
+func TestBatch_Keep(t *testing.T) {
+	defer Enable("point", Fault{Err: errInjected, Keep: 3})()
+
+	n, err := Batch(context.Background(), "point", 10)
+	assert.ErrorIs(t, err, errInjected)
+	assert.Equal(t, 3, n)
+
+	n, err = Batch(context.Background(), "point", 2)
+	assert.ErrorIs(t, err, errInjected)
+	assert.Equal(t, 2, n, "never more than the batch")
+}
+
+func TestHit_Delay(t *testing.T) {
+	disable := Enable("point", Fault{Delay: 20 * time.Millisecond})
+
+	start := time.Now()
+	require.NoError(t, Hit(context.Background(), "point"))
+	assert.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	// Slow points give up with the context
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, Hit(ctx, "point"), context.DeadlineExceeded)
+
+	disable()
+	assert.Equal(t, 0, Fired("point"))
+	assert.NoError(t, Hit(context.Background(), "point"))
+}
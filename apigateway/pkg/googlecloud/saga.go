@@ -2,6 +2,7 @@ package googlecloud
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
@@ -393,7 +394,7 @@ func (o *SagaOrchestrator) Rollback(ctx context.Context, sagaID string, fromStep
 			if err := o.client.UpdateSagaStatus(ctx, sagaID, SagaStatusFailed, i, errMsg); err != nil {
 				return err
 			}
-			return fmt.Errorf(errMsg)
+			return errors.New(errMsg)
 		}
 
 		// Mark step as compensated
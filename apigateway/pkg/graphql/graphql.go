@@ -0,0 +1,561 @@
+// Package graphql executes GraphQL queries against resolvers returning plain
+// Go values.
+//
+// A Schema lists the fields of the Query type, each with its arguments and a
+// resolver. Object types are not declared: they are reflected from the Go
+// type the resolver returns, their fields being the JSON fields of the
+// struct, so the GraphQL shape of a DTO is its JSON shape. Queries are
+// validated against those types before any resolver runs.
+//
+// Only queries are supported, with variables, aliases, fragments and the
+// @include and @skip directives. Introspection is not; Schema.SDL describes
+// the schema instead.
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Request is a GraphQL request, as posted by clients.
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+// Response is the result of a request. Data is nil when the request was
+// rejected before execution.
+type Response struct {
+	Data   *Object  `json:"data,omitempty"`
+	Errors []*Error `json:"errors,omitempty"`
+}
+
+// Error is a GraphQL error. Path is set for errors raised while resolving
+// a field.
+type Error struct {
+	Message   string        `json:"message"`
+	Locations []Location    `json:"locations,omitempty"`
+	Path      []interface{} `json:"path,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Location is a position in the query, both starting at 1.
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// Arg declares an argument of a field.
+type Arg struct {
+	Name string
+	// Type is a GraphQL input type of the built-in scalars, e.g. "Int",
+	// "String!" or "[String!]"
+	Type        string
+	Description string
+}
+
+// Field is a field of the Query type.
+type Field struct {
+	Description string
+	Args        []Arg
+	// Type is a value of the type Resolve returns, e.g. (*T)(nil)
+	Type interface{}
+	// Resolve returns the value of the field, a nil pointer for null
+	Resolve func(ctx context.Context, p Params) (interface{}, error)
+}
+
+// Params are the arguments and the selected subfields of a resolved field.
+type Params struct {
+	// Args holds the given arguments: int, float64, string, bool,
+	// []interface{} or nil for an explicit null
+	Args map[string]interface{}
+	// selected holds the dotted paths of the selected subfields
+	selected map[string]bool
+}
+
+// Has reports whether a subfield is selected, by its dotted path such as
+// "items.details"; resolvers use it to skip loading unselected data.
+func (p Params) Has(path string) bool {
+	return p.selected[path]
+}
+
+// Int returns an Int argument, def when absent or null.
+func (p Params) Int(name string, def int) int {
+	if v, ok := p.Args[name].(int); ok {
+		return v
+	}
+	return def
+}
+
+// String returns a String or ID argument, "" when absent or null.
+func (p Params) String(name string) string {
+	s, _ := p.Args[name].(string)
+	return s
+}
+
+// Strings returns a [String] argument, nil when absent or null.
+func (p Params) Strings(name string) []string {
+	list, _ := p.Args[name].([]interface{})
+	if list == nil {
+		return nil
+	}
+	out := make([]string, 0, len(list))
+	for _, v := range list {
+		if s, ok := v.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// Schema holds the fields of the Query type. It must not be modified once
+// queries are executed.
+type Schema struct {
+	Query map[string]*Field
+}
+
+// Execute validates and runs a request. Resolver errors null their field
+// and are reported next to the data of the others.
+func (s *Schema) Execute(ctx context.Context, req Request) *Response {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return errorResponse(err)
+	}
+	op, err := doc.operation(req.OperationName)
+	if err != nil {
+		return errorResponse(err)
+	}
+	if op.kind != "query" {
+		return errorResponse(&Error{Message: fmt.Sprintf("%s operations are not supported", op.kind), Locations: []Location{op.loc}})
+	}
+
+	v := &validator{schema: s, doc: doc}
+	v.vars = v.coerceVariables(op, req.Variables)
+	var fields []*plannedField
+	if len(v.errs) == 0 {
+		fields = v.planRoot(op.selections)
+	}
+	if len(v.errs) > 0 {
+		return &Response{Errors: v.errs}
+	}
+
+	resp := &Response{Data: &Object{}}
+	for _, f := range fields {
+		if f.name == "__typename" {
+			resp.Data.set(f.key, "Query")
+			continue
+		}
+		val, err := f.field.Resolve(ctx, Params{Args: f.args, selected: f.selectedPaths()})
+		if err != nil {
+			resp.Errors = append(resp.Errors, &Error{Message: err.Error(), Locations: []Location{f.loc}, Path: []interface{}{f.key}})
+			resp.Data.set(f.key, nil)
+			continue
+		}
+		if val != nil && reflect.TypeOf(val) != f.typ {
+			// the plan indexes the fields of the declared type
+			resp.Errors = append(resp.Errors, &Error{Message: fmt.Sprintf("resolver returned %T, declared %s", val, f.typ), Locations: []Location{f.loc}, Path: []interface{}{f.key}})
+			resp.Data.set(f.key, nil)
+			continue
+		}
+		out, err := f.complete(reflect.ValueOf(val), []interface{}{f.key})
+		if err != nil {
+			resp.Errors = append(resp.Errors, err.(*Error))
+			out = nil
+		}
+		resp.Data.set(f.key, out)
+	}
+	return resp
+}
+
+func errorResponse(err error) *Response {
+	gerr, ok := err.(*Error)
+	if !ok {
+		gerr = &Error{Message: err.Error()}
+	}
+	return &Response{Errors: []*Error{gerr}}
+}
+
+func (d *document) operation(name string) (*operation, error) {
+	if name == "" {
+		if len(d.operations) > 1 {
+			return nil, &Error{Message: "operationName is required when the document has several operations"}
+		}
+		return d.operations[0], nil
+	}
+	for _, op := range d.operations {
+		if op.name == name {
+			return op, nil
+		}
+	}
+	return nil, &Error{Message: fmt.Sprintf("unknown operation %q", name)}
+}
+
+// Object is a JSON object keeping the order of the query's fields.
+type Object struct {
+	keys   []string
+	values []interface{}
+}
+
+func (o *Object) set(key string, v interface{}) {
+	o.keys = append(o.keys, key)
+	o.values = append(o.values, v)
+}
+
+// Get returns the value of key, nil when absent.
+func (o *Object) Get(key string) interface{} {
+	for i, k := range o.keys {
+		if k == key {
+			return o.values[i]
+		}
+	}
+	return nil
+}
+
+func (o *Object) MarshalJSON() ([]byte, error) {
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, k := range o.keys {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		key, _ := json.Marshal(k)
+		sb.Write(key)
+		sb.WriteByte(':')
+		val, err := json.Marshal(o.values[i])
+		if err != nil {
+			return nil, err
+		}
+		sb.Write(val)
+	}
+	sb.WriteByte('}')
+	return []byte(sb.String()), nil
+}
+
+// plannedField is a validated field of the query with its subfields.
+type plannedField struct {
+	key  string
+	name string
+	loc  Location
+	// field and args are set on the root fields
+	field *Field
+	args  map[string]interface{}
+	// index is the struct field of nested fields
+	index []int
+	// typ is the Go type of the value
+	typ      reflect.Type
+	children []*plannedField
+}
+
+// selectedPaths lists the dotted paths of the subfields of f.
+func (f *plannedField) selectedPaths() map[string]bool {
+	paths := make(map[string]bool)
+	var walk func(prefix string, fields []*plannedField)
+	walk = func(prefix string, fields []*plannedField) {
+		for _, c := range fields {
+			path := prefix + c.name
+			paths[path] = true
+			walk(path+".", c.children)
+		}
+	}
+	walk("", f.children)
+	return paths
+}
+
+// complete converts a resolved value to JSON values following the selection.
+func (f *plannedField) complete(v reflect.Value, path []interface{}) (interface{}, error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return nil, nil
+	}
+	kind := kindOf(v.Type())
+	switch kind {
+	case kindList:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+		out := make([]interface{}, v.Len())
+		for i := range out {
+			item, err := f.complete(v.Index(i), append(path[:len(path):len(path)], i))
+			if err != nil {
+				return nil, err
+			}
+			out[i] = item
+		}
+		return out, nil
+	case kindObject:
+		obj := &Object{}
+		for _, c := range f.children {
+			if c.name == "__typename" {
+				obj.set(c.key, typeName(v.Type()))
+				continue
+			}
+			fv, err := v.FieldByIndexErr(c.index)
+			if err != nil {
+				// a nil embedded pointer leaves its fields null
+				obj.set(c.key, nil)
+				continue
+			}
+			out, err := c.complete(fv, append(path[:len(path):len(path)], c.key))
+			if err != nil {
+				return nil, err
+			}
+			obj.set(c.key, out)
+		}
+		return obj, nil
+	case kindScalar:
+		return v.Interface(), nil
+	}
+	return nil, &Error{Message: fmt.Sprintf("cannot serialize %s", v.Type()), Locations: []Location{f.loc}, Path: path}
+}
+
+// validator checks the query against the schema while planning it.
+type validator struct {
+	schema *Schema
+	doc    *document
+	vars   map[string]interface{}
+	errs   []*Error
+}
+
+func (v *validator) errorf(loc Location, format string, args ...interface{}) {
+	v.errs = append(v.errs, &Error{Message: fmt.Sprintf(format, args...), Locations: []Location{loc}})
+}
+
+func (v *validator) coerceVariables(op *operation, given map[string]interface{}) map[string]interface{} {
+	vars := make(map[string]interface{})
+	for _, d := range op.vars {
+		if !isInputType(d.typ) {
+			v.errorf(d.loc, "variable $%s has unsupported type %s", d.name, d.typ)
+			continue
+		}
+		raw, ok := given[d.name]
+		if !ok && d.def != nil {
+			def, err := literal(d.def, nil)
+			if err != nil {
+				v.errorf(d.loc, "variable $%s: %v", d.name, err)
+				continue
+			}
+			raw, ok = def, true
+		}
+		if !ok {
+			if d.typ.nonNull {
+				v.errorf(d.loc, "variable $%s of required type %s was not provided", d.name, d.typ)
+			}
+			continue
+		}
+		val, err := coerce(d.typ, raw)
+		if err != nil {
+			v.errorf(d.loc, "variable $%s got invalid value: %v", d.name, err)
+			continue
+		}
+		vars[d.name] = val
+	}
+	return vars
+}
+
+func (v *validator) planRoot(sels []*selection) []*plannedField {
+	var out []*plannedField
+	for _, group := range v.collect(sels, make(map[string]bool)) {
+		first := group[0]
+		pf := &plannedField{key: first.key(), name: first.name, loc: first.loc}
+		v.checkSameField(group)
+		if first.name == "__typename" {
+			out = append(out, pf)
+			continue
+		}
+		field, ok := v.schema.Query[first.name]
+		if !ok {
+			v.errorf(first.loc, "Cannot query field %q on type \"Query\"", first.name)
+			continue
+		}
+		pf.field = field
+		pf.typ = reflect.TypeOf(field.Type)
+		pf.args = v.coerceArgs(field, first)
+		pf.children = v.planSelection(pf.typ, subSelections(group), first)
+		out = append(out, pf)
+	}
+	return out
+}
+
+// planSelection plans the subfields selected on a value of type t.
+func (v *validator) planSelection(t reflect.Type, sels []*selection, parent *selection) []*plannedField {
+	t = baseType(t)
+	for kindOf(t) == kindList {
+		t = baseType(t.Elem())
+	}
+	if kindOf(t) != kindObject {
+		if len(sels) > 0 {
+			v.errorf(parent.loc, "Field %q must not have a selection since type %q has no subfields", parent.name, graphqlTypeName(t))
+		}
+		return nil
+	}
+	if len(sels) == 0 {
+		v.errorf(parent.loc, "Field %q of type %q must have a selection of subfields", parent.name, typeName(t))
+		return nil
+	}
+
+	fields := jsonFields(t)
+	var out []*plannedField
+	for _, group := range v.collect(sels, make(map[string]bool)) {
+		first := group[0]
+		v.checkSameField(group)
+		pf := &plannedField{key: first.key(), name: first.name, loc: first.loc}
+		if len(first.args) > 0 {
+			v.errorf(first.args[0].loc, "Unknown argument %q on field %q", first.args[0].name, typeName(t)+"."+first.name)
+		}
+		if first.name == "__typename" {
+			out = append(out, pf)
+			continue
+		}
+		sf, ok := fields[first.name]
+		if !ok {
+			v.errorf(first.loc, "Cannot query field %q on type %q", first.name, typeName(t))
+			continue
+		}
+		pf.index, pf.typ = sf.index, sf.typ
+		pf.children = v.planSelection(sf.typ, subSelections(group), first)
+		out = append(out, pf)
+	}
+	return out
+}
+
+// collect groups the fields of a selection set by response key, expanding
+// fragments and dropping fields excluded by @include and @skip.
+func (v *validator) collect(sels []*selection, visited map[string]bool) [][]*selection {
+	var order []string
+	groups := make(map[string][]*selection)
+	var walk func(sels []*selection)
+	walk = func(sels []*selection) {
+		for _, s := range sels {
+			if !v.included(s.directives) {
+				continue
+			}
+			switch {
+			case s.spread != "":
+				if visited[s.spread] {
+					continue
+				}
+				f, ok := v.doc.fragments[s.spread]
+				if !ok {
+					v.errorf(s.loc, "Unknown fragment %q", s.spread)
+					continue
+				}
+				visited[s.spread] = true
+				walk(f.selections)
+			case s.inline:
+				walk(s.selections)
+			default:
+				key := s.key()
+				if _, seen := groups[key]; !seen {
+					order = append(order, key)
+				}
+				groups[key] = append(groups[key], s)
+			}
+		}
+	}
+	walk(sels)
+	out := make([][]*selection, len(order))
+	for i, key := range order {
+		out[i] = groups[key]
+	}
+	return out
+}
+
+func (v *validator) checkSameField(group []*selection) {
+	for _, s := range group[1:] {
+		if s.name != group[0].name {
+			v.errorf(s.loc, "Fields %q conflict because %s and %s are different fields", s.key(), group[0].name, s.name)
+		}
+	}
+}
+
+func subSelections(group []*selection) []*selection {
+	var sels []*selection
+	for _, s := range group {
+		sels = append(sels, s.selections...)
+	}
+	return sels
+}
+
+// included evaluates the @include and @skip directives.
+func (v *validator) included(dirs []*directive) bool {
+	for _, d := range dirs {
+		if d.name != "include" && d.name != "skip" {
+			v.errorf(d.loc, "Unknown directive \"@%s\"", d.name)
+			continue
+		}
+		if len(d.args) != 1 || d.args[0].name != "if" {
+			v.errorf(d.loc, "Directive \"@%s\" takes a single \"if\" argument", d.name)
+			continue
+		}
+		val, err := literal(d.args[0].value, v.vars)
+		b, ok := val.(bool)
+		if err != nil || !ok {
+			v.errorf(d.loc, "Directive \"@%s\" argument \"if\" must be a Boolean", d.name)
+			continue
+		}
+		if b == (d.name == "skip") {
+			return false
+		}
+	}
+	return true
+}
+
+func (v *validator) coerceArgs(field *Field, s *selection) map[string]interface{} {
+	args := make(map[string]interface{})
+	given := make(map[string]*argument)
+	for _, a := range s.args {
+		given[a.name] = a
+	}
+	for _, decl := range field.Args {
+		t, err := parseTypeRef(decl.Type)
+		if err != nil {
+			v.errorf(s.loc, "argument %q of %q has invalid type %q", decl.Name, s.name, decl.Type)
+			continue
+		}
+		a, ok := given[decl.Name]
+		delete(given, decl.Name)
+		if ok && a.value.kind == varValue {
+			if _, set := v.vars[a.value.raw]; !set {
+				// unset variables leave the argument out
+				ok = false
+			}
+		}
+		if !ok {
+			if t.nonNull {
+				v.errorf(s.loc, "Field %q argument %q of type %q is required, but it was not provided", s.name, decl.Name, decl.Type)
+			}
+			continue
+		}
+		raw, err := literal(a.value, v.vars)
+		if err == nil {
+			raw, err = coerce(t, raw)
+		}
+		if err != nil {
+			v.errorf(a.loc, "Argument %q has invalid value: %v", decl.Name, err)
+			continue
+		}
+		args[decl.Name] = raw
+	}
+	names := make([]string, 0, len(given))
+	for name := range given {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		v.errorf(given[name].loc, "Unknown argument %q on field \"Query.%s\"", name, s.name)
+	}
+	return args
+}
@@ -0,0 +1,188 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testPet struct {
+	Name string `json:"name"`
+}
+
+type testBase struct {
+	ID int64 `json:"id"`
+}
+
+type testOwner struct {
+	testBase
+	Name   string    `json:"name"`
+	Pets   []testPet `json:"pets"`
+	Best   *testPet  `json:"best"`
+	Secret string    `json:"-"`
+}
+
+func testSchema(calls *[]Params) *Schema {
+	owners := map[string]*testOwner{
+		"ann": {testBase: testBase{ID: 1}, Name: "Ann", Pets: []testPet{{Name: "Rex"}, {Name: "Tom"}}, Best: &testPet{Name: "Rex"}},
+		"bob": {testBase: testBase{ID: 2}, Name: "Bob"},
+	}
+	return &Schema{Query: map[string]*Field{
+		"owner": {
+			Description: "An owner by name",
+			Args:        []Arg{{Name: "name", Type: "String!"}, {Name: "limit", Type: "Int"}},
+			Type:        (*testOwner)(nil),
+			Resolve: func(ctx context.Context, p Params) (interface{}, error) {
+				*calls = append(*calls, p)
+				return owners[p.String("name")], nil
+			},
+		},
+		"names": {
+			Args: []Arg{{Name: "prefix", Type: "[String!]"}},
+			Type: []string(nil),
+			Resolve: func(ctx context.Context, p Params) (interface{}, error) {
+				return p.Strings("prefix"), nil
+			},
+		},
+		"broken": {
+			Type: (*testOwner)(nil),
+			Resolve: func(ctx context.Context, p Params) (interface{}, error) {
+				return nil, errors.New("datastore unavailable")
+			},
+		},
+	}}
+}
+
+func execute(t *testing.T, s *Schema, req Request) (string, *Response) {
+	t.Helper()
+	resp := s.Execute(context.Background(), req)
+	body, err := json.Marshal(resp)
+	require.NoError(t, err)
+	return string(body), resp
+}
+
+func TestExecute_SelectsFieldsInQueryOrder(t *testing.T) {
+	var calls []Params
+	body, _ := execute(t, testSchema(&calls), Request{Query: `
+		# the owner and their pets
+		query Owner($who: String!) {
+			__typename
+			first: owner(name: $who) { name id pets { name } }
+			owner(name: "bob", limit: 3) { ...ownerFields best { name } }
+		}
+		fragment ownerFields on testOwner { __typename name }`,
+		Variables: map[string]interface{}{"who": "ann"},
+	})
+
+	assert.JSONEq(t, `{"data":{
+		"__typename":"Query",
+		"first":{"name":"Ann","id":1,"pets":[{"name":"Rex"},{"name":"Tom"}]},
+		"owner":{"__typename":"testOwner","name":"Bob","best":null}
+	}}`, body)
+	assert.Contains(t, body, `"first":{"name":"Ann","id":1,`, "fields follow the query order")
+
+	require.Len(t, calls, 2)
+	assert.True(t, calls[0].Has("pets"))
+	assert.True(t, calls[0].Has("pets.name"))
+	assert.False(t, calls[0].Has("best"))
+	assert.Equal(t, 3, calls[1].Int("limit", 10))
+	assert.Equal(t, 10, calls[0].Int("limit", 10))
+}
+
+func TestExecute_Directives(t *testing.T) {
+	var calls []Params
+	body, _ := execute(t, testSchema(&calls), Request{
+		Query:     `query($pets: Boolean!) { owner(name: "ann") { name pets @include(if: $pets) { name } best @skip(if: true) { name } } }`,
+		Variables: map[string]interface{}{"pets": false},
+	})
+	assert.JSONEq(t, `{"data":{"owner":{"name":"Ann"}}}`, body)
+}
+
+func TestExecute_CoercesListArguments(t *testing.T) {
+	body, _ := execute(t, testSchema(new([]Params)), Request{Query: `{ a: names(prefix: "x") b: names(prefix: ["y", "z"]) c: names }`})
+	assert.JSONEq(t, `{"data":{"a":["x"],"b":["y","z"],"c":null}}`, body)
+}
+
+func TestExecute_ResolverErrorNullsItsField(t *testing.T) {
+	body, resp := execute(t, testSchema(new([]Params)), Request{Query: `{ broken { name } owner(name: "bob") { name } }`})
+	assert.JSONEq(t, `{
+		"data":{"broken":null,"owner":{"name":"Bob"}},
+		"errors":[{"message":"datastore unavailable","locations":[{"line":1,"column":3}],"path":["broken"]}]
+	}`, body)
+	require.Len(t, resp.Errors, 1)
+}
+
+func TestExecute_RejectsInvalidQueries(t *testing.T) {
+	tests := []struct {
+		name  string
+		req   Request
+		error string
+	}{
+		{"syntax", Request{Query: `{ owner(name: "ann") { name }`}, `Syntax Error: expected name, found <EOF>`},
+		{"unknown field", Request{Query: `{ owner(name: "ann") { secret } }`}, `Cannot query field "secret" on type "testOwner"`},
+		{"unknown root field", Request{Query: `{ pets { name } }`}, `Cannot query field "pets" on type "Query"`},
+		{"missing selection", Request{Query: `{ owner(name: "ann") }`}, `Field "owner" of type "testOwner" must have a selection of subfields`},
+		{"selection on scalar", Request{Query: `{ owner(name: "ann") { name { x } } }`}, `Field "name" must not have a selection since type "String" has no subfields`},
+		{"missing argument", Request{Query: `{ owner { name } }`}, `Field "owner" argument "name" of type "String!" is required, but it was not provided`},
+		{"unknown argument", Request{Query: `{ owner(name: "ann", age: 3) { name } }`}, `Unknown argument "age" on field "Query.owner"`},
+		{"wrong type", Request{Query: `{ owner(name: 3) { name } }`}, `Argument "name" has invalid value: expected String, found 3`},
+		{"int range", Request{Query: `{ owner(name: "ann", limit: 3000000000) { name } }`}, `Argument "limit" has invalid value: expected a 32-bit Int, found 3e+09`},
+		{"missing variable", Request{Query: `query($who: String!) { owner(name: $who) { name } }`}, `variable $who of required type String! was not provided`},
+		{"unknown fragment", Request{Query: `{ owner(name: "ann") { ...nope } }`}, `Unknown fragment "nope"`},
+		{"mutation", Request{Query: `mutation { owner(name: "ann") { name } }`}, `mutation operations are not supported`},
+		{"ambiguous operation", Request{Query: `query A { __typename } query B { __typename }`}, `operationName is required when the document has several operations`},
+		{"conflicting alias", Request{Query: `{ owner(name: "ann") { x: name x: id } }`}, `Fields "x" conflict because name and id are different fields`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls []Params
+			_, resp := execute(t, testSchema(&calls), tt.req)
+			assert.Nil(t, resp.Data)
+			require.NotEmpty(t, resp.Errors)
+			assert.Equal(t, tt.error, resp.Errors[0].Message)
+			assert.Empty(t, calls, "invalid queries run no resolver")
+		})
+	}
+}
+
+func TestExecute_SelectsOperationByName(t *testing.T) {
+	body, _ := execute(t, testSchema(new([]Params)), Request{
+		Query:         `query A { a: __typename } query B { b: __typename }`,
+		OperationName: "B",
+	})
+	assert.JSONEq(t, `{"data":{"b":"Query"}}`, body)
+}
+
+func TestParse_Strings(t *testing.T) {
+	doc, err := parse("{ f(a: \"tab\\t\\u00e9\\\"\", b: \"\"\"\n    block\n      indented\n  \"\"\") }")
+	require.NoError(t, err)
+	args := doc.operations[0].selections[0].args
+	assert.Equal(t, "tab\té\"", args[0].value.raw)
+	assert.Equal(t, "block\n  indented", args[1].value.raw)
+}
+
+func TestSchema_SDL(t *testing.T) {
+	sdl := testSchema(new([]Params)).SDL()
+	assert.Equal(t, `type Query {
+  broken: testOwner
+  names(prefix: [String!]): [String!]
+  "An owner by name"
+  owner(name: String!, limit: Int): testOwner
+}
+
+type testOwner {
+  best: testPet
+  id: Int!
+  name: String!
+  pets: [testPet!]
+}
+
+type testPet {
+  name: String!
+}
+`, sdl)
+}
@@ -0,0 +1,698 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// document is a parsed GraphQL document.
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	kind       string
+	name       string
+	vars       []*varDef
+	directives []*directive
+	selections []*selection
+	loc        Location
+}
+
+type varDef struct {
+	name string
+	typ  *typeRef
+	def  *value
+	loc  Location
+}
+
+type fragment struct {
+	name       string
+	selections []*selection
+}
+
+// selection is a field, a fragment spread (spread set) or an inline fragment
+// (inline set).
+type selection struct {
+	alias      string
+	name       string
+	args       []*argument
+	directives []*directive
+	selections []*selection
+
+	spread string
+	inline bool
+	loc    Location
+}
+
+// key is the response key of a field, its alias or its name.
+func (s *selection) key() string {
+	if s.alias != "" {
+		return s.alias
+	}
+	return s.name
+}
+
+type argument struct {
+	name  string
+	value *value
+	loc   Location
+}
+
+type directive struct {
+	name string
+	args []*argument
+	loc  Location
+}
+
+type valueKind int
+
+const (
+	varValue valueKind = iota
+	intValue
+	floatValue
+	stringValue
+	boolValue
+	nullValue
+	enumValue
+	listValue
+	objectValue
+)
+
+type value struct {
+	kind valueKind
+	// raw is the literal, the variable or the enum name
+	raw    string
+	list   []*value
+	fields []*argument
+	loc    Location
+}
+
+// typeRef is a type reference such as [String!]!.
+type typeRef struct {
+	name    string
+	elem    *typeRef
+	nonNull bool
+}
+
+func (t *typeRef) String() string {
+	s := t.name
+	if t.elem != nil {
+		s = "[" + t.elem.String() + "]"
+	}
+	if t.nonNull {
+		s += "!"
+	}
+	return s
+}
+
+// parseTypeRef parses the type of an Arg.
+func parseTypeRef(s string) (*typeRef, error) {
+	p := &parser{lex: lexer{src: s, line: 1, col: 1}}
+	var t *typeRef
+	err := p.run(func() {
+		t = p.parseType()
+		p.expectKind(tokEOF)
+	})
+	return t, err
+}
+
+// parse parses a query document.
+func parse(src string) (*document, error) {
+	p := &parser{lex: lexer{src: src, line: 1, col: 1}}
+	doc := &document{fragments: make(map[string]*fragment)}
+	err := p.run(func() {
+		for p.tok.kind != tokEOF {
+			if p.peekName("fragment") {
+				f := p.parseFragment()
+				if _, dup := doc.fragments[f.name]; dup {
+					p.failf(p.tok.loc, "there can be only one fragment named %q", f.name)
+				}
+				doc.fragments[f.name] = f
+				continue
+			}
+			doc.operations = append(doc.operations, p.parseOperation())
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(doc.operations) == 0 {
+		return nil, &Error{Message: "document contains no operation"}
+	}
+	return doc, nil
+}
+
+type parser struct {
+	lex lexer
+	tok token
+}
+
+// syntaxError aborts parsing, recovered by run.
+type syntaxError struct {
+	err *Error
+}
+
+func (p *parser) run(fn func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			se, ok := r.(syntaxError)
+			if !ok {
+				panic(r)
+			}
+			err = se.err
+		}
+	}()
+	p.advance()
+	fn()
+	return nil
+}
+
+func (p *parser) failf(loc Location, format string, args ...interface{}) {
+	panic(syntaxError{&Error{Message: "Syntax Error: " + fmt.Sprintf(format, args...), Locations: []Location{loc}}})
+}
+
+func (p *parser) advance() {
+	tok, err := p.lex.next()
+	if err != nil {
+		p.failf(tok.loc, "%v", err)
+	}
+	p.tok = tok
+}
+
+func (p *parser) peek(punct string) bool {
+	return p.tok.kind == tokPunct && p.tok.val == punct
+}
+
+func (p *parser) peekName(name string) bool {
+	return p.tok.kind == tokName && p.tok.val == name
+}
+
+func (p *parser) expect(punct string) Location {
+	if !p.peek(punct) {
+		p.failf(p.tok.loc, "expected %q, found %s", punct, p.tok)
+	}
+	loc := p.tok.loc
+	p.advance()
+	return loc
+}
+
+func (p *parser) expectKind(kind tokenKind) token {
+	if p.tok.kind != kind {
+		p.failf(p.tok.loc, "expected %s, found %s", kind, p.tok)
+	}
+	tok := p.tok
+	p.advance()
+	return tok
+}
+
+func (p *parser) parseName() string {
+	return p.expectKind(tokName).val
+}
+
+func (p *parser) parseOperation() *operation {
+	op := &operation{kind: "query", loc: p.tok.loc}
+	if p.peek("{") {
+		op.selections = p.parseSelectionSet()
+		return op
+	}
+	op.kind = p.parseName()
+	switch op.kind {
+	case "query", "mutation", "subscription":
+	default:
+		p.failf(op.loc, "unexpected %q, expected an operation", op.kind)
+	}
+	if p.tok.kind == tokName {
+		op.name = p.parseName()
+	}
+	if p.peek("(") {
+		p.advance()
+		for !p.peek(")") {
+			v := &varDef{loc: p.tok.loc}
+			p.expect("$")
+			v.name = p.parseName()
+			p.expect(":")
+			v.typ = p.parseType()
+			if p.peek("=") {
+				p.advance()
+				v.def = p.parseValue(true)
+			}
+			op.vars = append(op.vars, v)
+		}
+		p.advance()
+	}
+	op.directives = p.parseDirectives()
+	op.selections = p.parseSelectionSet()
+	return op
+}
+
+func (p *parser) parseFragment() *fragment {
+	p.advance()
+	f := &fragment{name: p.parseName()}
+	if f.name == "on" {
+		p.failf(p.tok.loc, "unexpected fragment name \"on\"")
+	}
+	if !p.peekName("on") {
+		p.failf(p.tok.loc, "expected \"on\", found %s", p.tok)
+	}
+	p.advance()
+	p.parseName()
+	p.parseDirectives()
+	f.selections = p.parseSelectionSet()
+	return f
+}
+
+func (p *parser) parseSelectionSet() []*selection {
+	p.expect("{")
+	var sels []*selection
+	for !p.peek("}") {
+		sels = append(sels, p.parseSelection())
+	}
+	p.advance()
+	return sels
+}
+
+func (p *parser) parseSelection() *selection {
+	s := &selection{loc: p.tok.loc}
+	if p.peek("...") {
+		p.advance()
+		if p.tok.kind == tokName && !p.peekName("on") {
+			s.spread = p.parseName()
+			s.directives = p.parseDirectives()
+			return s
+		}
+		// type conditions are not checked, every object has a single type
+		if p.peekName("on") {
+			p.advance()
+			p.parseName()
+		}
+		s.inline = true
+		s.directives = p.parseDirectives()
+		s.selections = p.parseSelectionSet()
+		return s
+	}
+
+	s.name = p.parseName()
+	if p.peek(":") {
+		p.advance()
+		s.alias, s.name = s.name, p.parseName()
+	}
+	s.args = p.parseArguments(false)
+	s.directives = p.parseDirectives()
+	if p.peek("{") {
+		s.selections = p.parseSelectionSet()
+	}
+	return s
+}
+
+func (p *parser) parseArguments(constant bool) []*argument {
+	if !p.peek("(") {
+		return nil
+	}
+	p.advance()
+	var args []*argument
+	for !p.peek(")") {
+		a := &argument{loc: p.tok.loc, name: p.parseName()}
+		p.expect(":")
+		a.value = p.parseValue(constant)
+		args = append(args, a)
+	}
+	p.advance()
+	return args
+}
+
+func (p *parser) parseDirectives() []*directive {
+	var dirs []*directive
+	for p.peek("@") {
+		d := &directive{loc: p.expect("@")}
+		d.name = p.parseName()
+		d.args = p.parseArguments(false)
+		dirs = append(dirs, d)
+	}
+	return dirs
+}
+
+func (p *parser) parseType() *typeRef {
+	t := &typeRef{}
+	if p.peek("[") {
+		p.advance()
+		t.elem = p.parseType()
+		p.expect("]")
+	} else {
+		t.name = p.parseName()
+	}
+	if p.peek("!") {
+		p.advance()
+		t.nonNull = true
+	}
+	return t
+}
+
+// parseValue parses a value literal; constant values, such as variable
+// defaults, cannot reference variables.
+func (p *parser) parseValue(constant bool) *value {
+	v := &value{loc: p.tok.loc}
+	switch {
+	case p.peek("$") && !constant:
+		p.advance()
+		v.kind, v.raw = varValue, p.parseName()
+	case p.peek("["):
+		p.advance()
+		v.kind = listValue
+		for !p.peek("]") {
+			v.list = append(v.list, p.parseValue(constant))
+		}
+		p.advance()
+	case p.peek("{"):
+		p.advance()
+		v.kind = objectValue
+		for !p.peek("}") {
+			f := &argument{loc: p.tok.loc, name: p.parseName()}
+			p.expect(":")
+			f.value = p.parseValue(constant)
+			v.fields = append(v.fields, f)
+		}
+		p.advance()
+	case p.tok.kind == tokInt:
+		v.kind, v.raw = intValue, p.tok.val
+		p.advance()
+	case p.tok.kind == tokFloat:
+		v.kind, v.raw = floatValue, p.tok.val
+		p.advance()
+	case p.tok.kind == tokString:
+		v.kind, v.raw = stringValue, p.tok.val
+		p.advance()
+	case p.tok.kind == tokName:
+		switch p.tok.val {
+		case "true", "false":
+			v.kind = boolValue
+		case "null":
+			v.kind = nullValue
+		default:
+			v.kind = enumValue
+		}
+		v.raw = p.tok.val
+		p.advance()
+	default:
+		p.failf(p.tok.loc, "unexpected %s", p.tok)
+	}
+	return v
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokPunct
+	tokName
+	tokInt
+	tokFloat
+	tokString
+)
+
+func (k tokenKind) String() string {
+	return [...]string{"<EOF>", "punctuator", "name", "int", "float", "string"}[k]
+}
+
+type token struct {
+	kind tokenKind
+	val  string
+	loc  Location
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "<EOF>"
+	case tokString:
+		return strconv.Quote(t.val)
+	}
+	return fmt.Sprintf("%q", t.val)
+}
+
+type lexer struct {
+	src       string
+	pos       int
+	line, col int
+}
+
+func (l *lexer) loc() Location {
+	return Location{Line: l.line, Column: l.col}
+}
+
+func (l *lexer) peekByte(off int) byte {
+	if l.pos+off < len(l.src) {
+		return l.src[l.pos+off]
+	}
+	return 0
+}
+
+// skip advances n bytes on the current line.
+func (l *lexer) skip(n int) {
+	l.pos += n
+	l.col += n
+}
+
+func (l *lexer) next() (token, error) {
+	l.skipIgnored()
+	tok := token{loc: l.loc()}
+	if l.pos >= len(l.src) {
+		return tok, nil
+	}
+	c := l.src[l.pos]
+	switch {
+	case strings.IndexByte("!$&()[]{}:=@|", c) >= 0:
+		tok.kind, tok.val = tokPunct, string(c)
+		l.skip(1)
+	case c == '.':
+		if !strings.HasPrefix(l.src[l.pos:], "...") {
+			return tok, fmt.Errorf("unexpected \".\"")
+		}
+		tok.kind, tok.val = tokPunct, "..."
+		l.skip(3)
+	case c == '_' || isLetter(c):
+		start := l.pos
+		for l.pos < len(l.src) && (l.src[l.pos] == '_' || isLetter(l.src[l.pos]) || isDigit(l.src[l.pos])) {
+			l.skip(1)
+		}
+		tok.kind, tok.val = tokName, l.src[start:l.pos]
+	case c == '-' || isDigit(c):
+		return l.number(tok)
+	case c == '"':
+		return l.string(tok)
+	default:
+		r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+		return tok, fmt.Errorf("unexpected character %q", r)
+	}
+	return tok, nil
+}
+
+func (l *lexer) skipIgnored() {
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; c {
+		case ' ', '\t', ',':
+			l.skip(1)
+		case '\n':
+			l.pos++
+			l.line, l.col = l.line+1, 1
+		case '\r':
+			l.pos++
+			if l.peekByte(0) == '\n' {
+				l.pos++
+			}
+			l.line, l.col = l.line+1, 1
+		case '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' && l.src[l.pos] != '\r' {
+				l.skip(1)
+			}
+		default:
+			if strings.HasPrefix(l.src[l.pos:], "\ufeff") {
+				l.pos += len("\ufeff")
+				continue
+			}
+			return
+		}
+	}
+}
+
+func (l *lexer) number(tok token) (token, error) {
+	start := l.pos
+	tok.kind = tokInt
+	if l.peekByte(0) == '-' {
+		l.skip(1)
+	}
+	if !l.digits() {
+		return tok, fmt.Errorf("invalid number %q", l.src[start:l.pos])
+	}
+	if l.peekByte(0) == '.' {
+		tok.kind = tokFloat
+		l.skip(1)
+		if !l.digits() {
+			return tok, fmt.Errorf("invalid number %q", l.src[start:l.pos])
+		}
+	}
+	if c := l.peekByte(0); c == 'e' || c == 'E' {
+		tok.kind = tokFloat
+		l.skip(1)
+		if c := l.peekByte(0); c == '+' || c == '-' {
+			l.skip(1)
+		}
+		if !l.digits() {
+			return tok, fmt.Errorf("invalid number %q", l.src[start:l.pos])
+		}
+	}
+	if c := l.peekByte(0); c == '_' || c == '.' || isLetter(c) {
+		return tok, fmt.Errorf("invalid number, unexpected %q", c)
+	}
+	tok.val = l.src[start:l.pos]
+	return tok, nil
+}
+
+func (l *lexer) digits() bool {
+	start := l.pos
+	for isDigit(l.peekByte(0)) {
+		l.skip(1)
+	}
+	return l.pos > start
+}
+
+func (l *lexer) string(tok token) (token, error) {
+	tok.kind = tokString
+	if strings.HasPrefix(l.src[l.pos:], `"""`) {
+		return l.blockString(tok)
+	}
+	l.skip(1)
+	var sb strings.Builder
+	for {
+		if l.pos >= len(l.src) {
+			return tok, fmt.Errorf("unterminated string")
+		}
+		c := l.src[l.pos]
+		switch {
+		case c == '"':
+			l.skip(1)
+			tok.val = sb.String()
+			return tok, nil
+		case c == '\n' || c == '\r':
+			return tok, fmt.Errorf("unterminated string")
+		case c == '\\':
+			r, n, err := unescape(l.src[l.pos:])
+			if err != nil {
+				return tok, err
+			}
+			sb.WriteRune(r)
+			l.skip(n)
+		default:
+			r, n := utf8.DecodeRuneInString(l.src[l.pos:])
+			sb.WriteRune(r)
+			l.pos += n
+			l.col++
+		}
+	}
+}
+
+// blockString reads a """block string""", removing the common indentation
+// and the blank first and last lines.
+func (l *lexer) blockString(tok token) (token, error) {
+	l.skip(3)
+	var sb strings.Builder
+	for {
+		if l.pos >= len(l.src) {
+			return tok, fmt.Errorf("unterminated string")
+		}
+		rest := l.src[l.pos:]
+		switch {
+		case strings.HasPrefix(rest, `"""`):
+			l.skip(3)
+			tok.val = blockStringValue(sb.String())
+			return tok, nil
+		case strings.HasPrefix(rest, `\"""`):
+			sb.WriteString(`"""`)
+			l.skip(4)
+		case rest[0] == '\n':
+			sb.WriteByte('\n')
+			l.pos++
+			l.line, l.col = l.line+1, 1
+		default:
+			r, n := utf8.DecodeRuneInString(rest)
+			sb.WriteRune(r)
+			l.pos += n
+			l.col++
+		}
+	}
+}
+
+func blockStringValue(raw string) string {
+	lines := strings.Split(strings.ReplaceAll(raw, "\r\n", "\n"), "\n")
+	indent := -1
+	for _, line := range lines[1:] {
+		trimmed := strings.TrimLeft(line, " \t")
+		if trimmed == "" {
+			continue
+		}
+		if n := len(line) - len(trimmed); indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	if indent > 0 {
+		for i := 1; i < len(lines); i++ {
+			if len(lines[i]) >= indent {
+				lines[i] = lines[i][indent:]
+			} else {
+				lines[i] = strings.TrimLeft(lines[i], " \t")
+			}
+		}
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[0]) == "" {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// unescape decodes the escape sequence at the start of s, returning the rune
+// and the length of the sequence.
+func unescape(s string) (rune, int, error) {
+	if len(s) < 2 {
+		return 0, 0, fmt.Errorf("unterminated string")
+	}
+	switch s[1] {
+	case '"':
+		return '"', 2, nil
+	case '\\':
+		return '\\', 2, nil
+	case '/':
+		return '/', 2, nil
+	case 'b':
+		return '\b', 2, nil
+	case 'f':
+		return '\f', 2, nil
+	case 'n':
+		return '\n', 2, nil
+	case 'r':
+		return '\r', 2, nil
+	case 't':
+		return '\t', 2, nil
+	case 'u':
+		if len(s) >= 6 {
+			if n, err := strconv.ParseUint(s[2:6], 16, 16); err == nil {
+				return rune(n), 6, nil
+			}
+		}
+		return 0, 0, fmt.Errorf("invalid unicode escape sequence")
+	}
+	return 0, 0, fmt.Errorf("invalid escape sequence \\%c", s[1])
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
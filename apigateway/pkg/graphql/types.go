@@ -0,0 +1,400 @@
+package graphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type typeKind int
+
+const (
+	kindScalar typeKind = iota
+	kindList
+	kindObject
+	kindUnsupported
+)
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// baseType strips the pointers of t.
+func baseType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func kindOf(t reflect.Type) typeKind {
+	t = baseType(t)
+	if t == nil {
+		return kindUnsupported
+	}
+	if t == timeType || t.Implements(marshalerType) || reflect.PtrTo(t).Implements(marshalerType) {
+		return kindScalar
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return kindScalar
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			// encoded as a base64 string
+			return kindScalar
+		}
+		return kindList
+	case reflect.Struct:
+		return kindObject
+	}
+	return kindUnsupported
+}
+
+// typeName is the GraphQL name of an object type.
+func typeName(t reflect.Type) string {
+	t = baseType(t)
+	name := t.Name()
+	if i := strings.IndexByte(name, '['); i >= 0 {
+		name = name[:i]
+	}
+	if name == "" {
+		return "Object"
+	}
+	return name
+}
+
+// graphqlTypeName is the GraphQL name of a scalar or object type.
+func graphqlTypeName(t reflect.Type) string {
+	t = baseType(t)
+	if kindOf(t) == kindObject {
+		return typeName(t)
+	}
+	switch {
+	case t == timeType:
+		return "String"
+	case t.Implements(marshalerType) || reflect.PtrTo(t).Implements(marshalerType):
+		return "JSON"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "Boolean"
+	case reflect.Float32, reflect.Float64:
+		return "Float"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "Int"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "String"
+		}
+	}
+	return "String"
+}
+
+type structField struct {
+	index []int
+	typ   reflect.Type
+}
+
+var fieldCache sync.Map // reflect.Type -> map[string]structField
+
+// jsonFields returns the JSON fields of a struct by name, flattening
+// embedded structs the way encoding/json does.
+func jsonFields(t reflect.Type) map[string]structField {
+	if cached, ok := fieldCache.Load(t); ok {
+		return cached.(map[string]structField)
+	}
+	fields := make(map[string]structField)
+	var walk func(t reflect.Type, index []int)
+	walk = func(t reflect.Type, index []int) {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name := strings.Split(tag, ",")[0]
+			idx := append(index[:len(index):len(index)], i)
+			if f.Anonymous && name == "" {
+				if ft := baseType(f.Type); ft.Kind() == reflect.Struct {
+					walk(ft, idx)
+					continue
+				}
+			}
+			if !f.IsExported() {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			if _, shadowed := fields[name]; shadowed && len(idx) > len(fields[name].index) {
+				continue
+			}
+			if kindOf(f.Type) == kindUnsupported {
+				continue
+			}
+			fields[name] = structField{index: idx, typ: f.Type}
+		}
+	}
+	walk(t, nil)
+	fieldCache.Store(t, fields)
+	return fields
+}
+
+// isInputType reports whether t is a list of, or one of, the built-in scalars.
+func isInputType(t *typeRef) bool {
+	if t.elem != nil {
+		return isInputType(t.elem)
+	}
+	switch t.name {
+	case "Int", "Float", "String", "Boolean", "ID":
+		return true
+	}
+	return false
+}
+
+// literal evaluates a value of the query to its JSON form, replacing the
+// variables.
+func literal(v *value, vars map[string]interface{}) (interface{}, error) {
+	switch v.kind {
+	case varValue:
+		return vars[v.raw], nil
+	case intValue:
+		n, err := strconv.ParseInt(v.raw, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer %s", v.raw)
+		}
+		return n, nil
+	case floatValue:
+		f, err := strconv.ParseFloat(v.raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float %s", v.raw)
+		}
+		return f, nil
+	case stringValue:
+		return v.raw, nil
+	case boolValue:
+		return v.raw == "true", nil
+	case nullValue:
+		return nil, nil
+	case listValue:
+		out := make([]interface{}, len(v.list))
+		for i, item := range v.list {
+			val, err := literal(item, vars)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = val
+		}
+		return out, nil
+	case enumValue:
+		return nil, fmt.Errorf("enum value %s is not supported", v.raw)
+	}
+	return nil, fmt.Errorf("input objects are not supported")
+}
+
+// coerce converts a JSON value, such as a decoded variable, to the input
+// type t.
+func coerce(t *typeRef, v interface{}) (interface{}, error) {
+	if v == nil {
+		if t.nonNull {
+			return nil, fmt.Errorf("expected non-null %s, found null", t)
+		}
+		return nil, nil
+	}
+	if t.elem != nil {
+		list, ok := v.([]interface{})
+		if !ok {
+			// a single value is a list of one
+			list = []interface{}{v}
+		}
+		out := make([]interface{}, len(list))
+		for i, item := range list {
+			val, err := coerce(t.elem, item)
+			if err != nil {
+				return nil, fmt.Errorf("at index %d: %v", i, err)
+			}
+			out[i] = val
+		}
+		return out, nil
+	}
+
+	switch t.name {
+	case "Int":
+		var f float64
+		switch n := v.(type) {
+		case int64:
+			f = float64(n)
+		case float64:
+			f = n
+		case json.Number:
+			var err error
+			if f, err = n.Float64(); err != nil {
+				return nil, fmt.Errorf("expected Int, found %s", n)
+			}
+		default:
+			return nil, fmt.Errorf("expected Int, found %s", describe(v))
+		}
+		if f != math.Trunc(f) || f > math.MaxInt32 || f < math.MinInt32 {
+			return nil, fmt.Errorf("expected a 32-bit Int, found %v", f)
+		}
+		return int(f), nil
+	case "Float":
+		switch n := v.(type) {
+		case int64:
+			return float64(n), nil
+		case float64:
+			return n, nil
+		case json.Number:
+			return n.Float64()
+		}
+		return nil, fmt.Errorf("expected Float, found %s", describe(v))
+	case "String":
+		if s, ok := v.(string); ok {
+			return s, nil
+		}
+		return nil, fmt.Errorf("expected String, found %s", describe(v))
+	case "ID":
+		switch n := v.(type) {
+		case string:
+			return n, nil
+		case int64:
+			return strconv.FormatInt(n, 10), nil
+		case float64:
+			if n == math.Trunc(n) {
+				return strconv.FormatFloat(n, 'f', 0, 64), nil
+			}
+		case json.Number:
+			return n.String(), nil
+		}
+		return nil, fmt.Errorf("expected ID, found %s", describe(v))
+	case "Boolean":
+		if b, ok := v.(bool); ok {
+			return b, nil
+		}
+		return nil, fmt.Errorf("expected Boolean, found %s", describe(v))
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+func describe(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return strconv.Quote(x)
+	case []interface{}:
+		return "a list"
+	case map[string]interface{}:
+		return "an object"
+	}
+	return fmt.Sprint(v)
+}
+
+// SDL describes the schema in the GraphQL schema definition language, the
+// Query type first and the object types in alphabetical order.
+func (s *Schema) SDL() string {
+	var sb strings.Builder
+	objects := make(map[string]reflect.Type)
+	usesJSON := false
+	var collect func(t reflect.Type)
+	collect = func(t reflect.Type) {
+		t = baseType(t)
+		switch kindOf(t) {
+		case kindScalar:
+			usesJSON = usesJSON || graphqlTypeName(t) == "JSON"
+		case kindList:
+			collect(t.Elem())
+		case kindObject:
+			name := typeName(t)
+			if _, seen := objects[name]; seen {
+				return
+			}
+			objects[name] = t
+			for _, f := range jsonFields(t) {
+				collect(f.typ)
+			}
+		}
+	}
+
+	names := make([]string, 0, len(s.Query))
+	for name := range s.Query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	sb.WriteString("type Query {\n")
+	for _, name := range names {
+		f := s.Query[name]
+		writeDescription(&sb, f.Description)
+		sb.WriteString("  " + name)
+		if len(f.Args) > 0 {
+			args := make([]string, len(f.Args))
+			for i, a := range f.Args {
+				args[i] = a.Name + ": " + a.Type
+				if a.Description != "" {
+					args[i] = fmt.Sprintf("%q %s", a.Description, args[i])
+				}
+			}
+			sb.WriteString("(" + strings.Join(args, ", ") + ")")
+		}
+		// root fields are nullable, a failing resolver nulls its field
+		sb.WriteString(": " + strings.TrimSuffix(sdlType(reflect.TypeOf(f.Type)), "!") + "\n")
+		collect(reflect.TypeOf(f.Type))
+	}
+	sb.WriteString("}\n")
+
+	typeNames := make([]string, 0, len(objects))
+	for name := range objects {
+		typeNames = append(typeNames, name)
+	}
+	sort.Strings(typeNames)
+	for _, name := range typeNames {
+		fields := jsonFields(objects[name])
+		fieldNames := make([]string, 0, len(fields))
+		for fn := range fields {
+			fieldNames = append(fieldNames, fn)
+		}
+		sort.Strings(fieldNames)
+		sb.WriteString("\ntype " + name + " {\n")
+		for _, fn := range fieldNames {
+			sb.WriteString("  " + fn + ": " + sdlType(fields[fn].typ) + "\n")
+		}
+		sb.WriteString("}\n")
+	}
+	if usesJSON {
+		sb.WriteString("\nscalar JSON\n")
+	}
+	return sb.String()
+}
+
+// sdlType is the GraphQL type of t: pointers and slices are nullable, other
+// values are not.
+func sdlType(t reflect.Type) string {
+	if t == nil {
+		return "String"
+	}
+	if t.Kind() == reflect.Ptr {
+		return strings.TrimSuffix(sdlType(t.Elem()), "!")
+	}
+	if kindOf(t) == kindList {
+		s := "[" + sdlType(t.Elem()) + "]"
+		if t.Kind() == reflect.Array {
+			s += "!"
+		}
+		return s
+	}
+	return graphqlTypeName(t) + "!"
+}
+
+func writeDescription(sb *strings.Builder, desc string) {
+	if desc != "" {
+		sb.WriteString("  " + strconv.Quote(desc) + "\n")
+	}
+}
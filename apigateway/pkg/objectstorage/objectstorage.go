@@ -0,0 +1,80 @@
+// Package objectstorage provides io.Writer sinks that upload straight into
+// cloud object storage (Google Cloud Storage, Amazon S3).
+//
+// Writers are backed by an io.Pipe: bytes written by the exporter are handed to
+// the upload as they arrive, so reports never touch a temp file on local disk.
+package objectstorage
+
+import (
+	"context"
+	"io"
+)
+
+// XLSXContentType is the MIME type used for uploaded workbooks when no
+// content type is configured.
+const XLSXContentType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// Provider opens writers for objects in a bucket.
+// Data written to the returned writer is uploaded as it arrives; Close blocks
+// until the upload has finished and returns its error.
+type Provider interface {
+	NewWriter(ctx context.Context, bucket, key string) (io.WriteCloser, error)
+}
+
+// Option configures a provider.
+type Option func(*config)
+
+type config struct {
+	contentType string
+}
+
+func defaultConfig() *config {
+	return &config{contentType: XLSXContentType}
+}
+
+// WithContentType overrides the Content-Type stored with uploaded objects.
+func WithContentType(contentType string) Option {
+	return func(c *config) {
+		if contentType != "" {
+			c.contentType = contentType
+		}
+	}
+}
+
+// uploadFunc consumes r until EOF and stores the bytes as a single object.
+type uploadFunc func(ctx context.Context, r io.Reader) error
+
+// pipeWriter feeds an upload running in its own goroutine.
+type pipeWriter struct {
+	pw   *io.PipeWriter
+	done chan error
+}
+
+func newPipeWriter(ctx context.Context, upload uploadFunc) *pipeWriter {
+	pr, pw := io.Pipe()
+	w := &pipeWriter{pw: pw, done: make(chan error, 1)}
+	go func() {
+		err := upload(ctx, pr)
+		// Unblock any pending Write if the upload stopped before reading everything.
+		pr.CloseWithError(err)
+		w.done <- err
+	}()
+	return w
+}
+
+func (w *pipeWriter) Write(p []byte) (int, error) {
+	return w.pw.Write(p)
+}
+
+// Close signals EOF to the upload and waits for it to complete.
+func (w *pipeWriter) Close() error {
+	w.pw.Close()
+	return <-w.done
+}
+
+// CloseWithError aborts the upload; the partially written object is discarded.
+func (w *pipeWriter) CloseWithError(err error) error {
+	w.pw.CloseWithError(err)
+	<-w.done
+	return err
+}
@@ -0,0 +1,61 @@
+package objectstorage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPipeWriter_UploadsWrittenBytes(t *testing.T) {
+	var got bytes.Buffer
+	w := newPipeWriter(context.Background(), func(ctx context.Context, r io.Reader) error {
+		_, err := io.Copy(&got, r)
+		return err
+	})
+
+	_, err := w.Write([]byte("hello "))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("world"))
+	require.NoError(t, err)
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, "hello world", got.String())
+}
+
+func TestPipeWriter_UploadErrorSurfacesOnWriteAndClose(t *testing.T) {
+	uploadErr := errors.New("bucket not found")
+	w := newPipeWriter(context.Background(), func(ctx context.Context, r io.Reader) error {
+		return uploadErr
+	})
+
+	_, err := w.Write([]byte("data"))
+	assert.ErrorIs(t, err, uploadErr)
+	assert.ErrorIs(t, w.Close(), uploadErr)
+}
+
+func TestPipeWriter_CloseWithErrorAbortsUpload(t *testing.T) {
+	renderErr := errors.New("render failed")
+	var readErr error
+	w := newPipeWriter(context.Background(), func(ctx context.Context, r io.Reader) error {
+		_, readErr = io.ReadAll(r)
+		return readErr
+	})
+
+	_, err := w.Write([]byte("partial"))
+	require.NoError(t, err)
+	assert.ErrorIs(t, w.CloseWithError(renderErr), renderErr)
+	assert.ErrorIs(t, readErr, renderErr)
+}
+
+func TestProviders_RequireBucketAndKey(t *testing.T) {
+	p := NewS3Provider(nil)
+	_, err := p.NewWriter(context.Background(), "", "report.xlsx")
+	assert.Error(t, err)
+	_, err = p.NewWriter(context.Background(), "reports", "")
+	assert.Error(t, err)
+}
@@ -0,0 +1,49 @@
+package objectstorage
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3Provider uploads objects to Amazon S3 (or any S3-compatible store).
+// Uploads use multipart so the object size does not need to be known up front.
+type S3Provider struct {
+	uploader *manager.Uploader
+	cfg      *config
+}
+
+// NewS3Provider wraps an S3 client, typically created with s3.NewFromConfig.
+func NewS3Provider(client manager.UploadAPIClient, opts ...Option) *S3Provider {
+	cfg := defaultConfig()
+	for _, o := range opts {
+		o(cfg)
+	}
+	return &S3Provider{
+		uploader: manager.NewUploader(client),
+		cfg:      cfg,
+	}
+}
+
+// NewWriter starts a multipart upload of s3://bucket/key.
+func (p *S3Provider) NewWriter(ctx context.Context, bucket, key string) (io.WriteCloser, error) {
+	if bucket == "" || key == "" {
+		return nil, fmt.Errorf("bucket and key are required")
+	}
+	return newPipeWriter(ctx, func(ctx context.Context, r io.Reader) error {
+		_, err := p.uploader.Upload(ctx, &s3.PutObjectInput{
+			Bucket:      aws.String(bucket),
+			Key:         aws.String(key),
+			Body:        r,
+			ContentType: aws.String(p.cfg.contentType),
+		})
+		if err != nil {
+			return fmt.Errorf("upload s3://%s/%s: %w", bucket, key, err)
+		}
+		return nil
+	}), nil
+}
@@ -0,0 +1,254 @@
+// Package openapi builds an OpenAPI 3 document from the routes a server
+// registered and a registry of operation docs naming their request and
+// response types.
+//
+// Every route ends up in the document, with its path parameters, even when
+// nobody documented it; documented ones also get a summary, query
+// parameters, security requirements and schemas reflected from their Go
+// types, so the document cannot drift from the DTOs.
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// Version is the OpenAPI version of built documents.
+const Version = "3.0.3"
+
+// Route is a registered route, as listed by the router.
+type Route struct {
+	Method string
+	// Path uses :name for parameters and * for wildcards
+	Path string
+	// Name is the handler name, the summary of undocumented routes
+	Name string
+}
+
+// Param documents a query parameter.
+type Param struct {
+	Name        string
+	Description string
+	// Type is a JSON schema type, "string" when empty
+	Type     string
+	Required bool
+}
+
+// Operation documents a route.
+type Operation struct {
+	Summary     string
+	Description string
+	// Tags groups operations, the first path segment when empty
+	Tags  []string
+	Query []Param
+	// Request is a value of the JSON request body type, nil without a body
+	Request interface{}
+	// Response is a value of the type answered as data, nil without data
+	Response interface{}
+	// ContentType is the type of non-JSON success responses, e.g. a
+	// spreadsheet download; Response is ignored when set
+	ContentType string
+	// Status is the success status, 200 when zero
+	Status int
+	// Security names the accepted security schemes, any of them will do
+	Security []string
+}
+
+// SecurityScheme is an OpenAPI security scheme.
+type SecurityScheme struct {
+	Type         string `json:"type"`
+	Description  string `json:"description,omitempty"`
+	Scheme       string `json:"scheme,omitempty"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+	In           string `json:"in,omitempty"`
+	Name         string `json:"name,omitempty"`
+}
+
+// Registry holds the operation docs of a server.
+type Registry struct {
+	Title       string
+	Version     string
+	Description string
+	// Envelope wraps every JSON response, its data set to the operation's
+	// Response; nil answers the response type as is
+	Envelope func(data *Schema) *Schema
+	// ErrorResponses are added to every operation, by status
+	ErrorResponses  map[int]ErrorResponse
+	SecuritySchemes map[string]SecurityScheme
+
+	ops map[string]Operation
+}
+
+// ErrorResponse documents an error status shared by the operations.
+type ErrorResponse struct {
+	Description string
+	// Data is a value of the type answered as data, nil without data
+	Data interface{}
+	// Only limits the response to operations with a request body or with
+	// security requirements, see the Only constants
+	Only string
+}
+
+// ErrorResponse.Only values
+const (
+	WithRequest  = "request"
+	WithSecurity = "security"
+)
+
+// NewRegistry creates an empty registry.
+func NewRegistry(title, version string) *Registry {
+	return &Registry{Title: title, Version: version, ops: make(map[string]Operation)}
+}
+
+// Document documents the route of method and path, which uses the router
+// syntax (":id").
+func (r *Registry) Document(method, path string, op Operation) {
+	r.ops[method+" "+path] = op
+}
+
+// Build builds the document of routes. Undocumented routes are listed with
+// their handler name as summary.
+func (r *Registry) Build(routes []Route) *Document {
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    Info{Title: r.Title, Version: r.Version, Description: r.Description},
+		Paths:   make(map[string]PathItem),
+	}
+	gen := newGenerator()
+
+	for _, rt := range routes {
+		if strings.Contains(rt.Path, "*") {
+			// static file and catch-all routes are not part of the API
+			continue
+		}
+		path, params := convertPath(rt.Path)
+		op, documented := r.ops[rt.Method+" "+rt.Path]
+		out := &OperationObject{
+			Summary:     op.Summary,
+			Description: op.Description,
+			Tags:        op.Tags,
+			OperationID: operationID(rt.Method, rt.Path),
+			Parameters:  params,
+			Responses:   make(map[string]*Response),
+		}
+		if !documented || out.Summary == "" {
+			out.Summary = handlerName(rt.Name)
+		}
+		if len(out.Tags) == 0 {
+			out.Tags = []string{firstSegment(rt.Path)}
+		}
+		for _, q := range op.Query {
+			typ := q.Type
+			if typ == "" {
+				typ = "string"
+			}
+			out.Parameters = append(out.Parameters, &Parameter{
+				Name: q.Name, In: "query", Description: q.Description, Required: q.Required, Schema: &Schema{Type: typ},
+			})
+		}
+		if op.Request != nil {
+			out.RequestBody = &RequestBody{Required: true, Content: map[string]MediaType{
+				"application/json": {Schema: gen.schemaOf(op.Request)},
+			}}
+		}
+
+		status := op.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		if op.ContentType != "" {
+			out.Responses[fmt.Sprint(status)] = &Response{Description: http.StatusText(status), Content: map[string]MediaType{
+				op.ContentType: {Schema: &Schema{Type: "string", Format: "binary"}},
+			}}
+		} else {
+			out.Responses[fmt.Sprint(status)] = r.jsonResponse(gen, http.StatusText(status), op.Response)
+		}
+		for code, er := range r.ErrorResponses {
+			if er.Only == WithRequest && op.Request == nil || er.Only == WithSecurity && len(op.Security) == 0 {
+				continue
+			}
+			out.Responses[fmt.Sprint(code)] = r.jsonResponse(gen, er.Description, er.Data)
+		}
+		for _, name := range op.Security {
+			out.Security = append(out.Security, map[string][]string{name: {}})
+		}
+
+		item := doc.Paths[path]
+		if item == nil {
+			item = make(PathItem)
+			doc.Paths[path] = item
+		}
+		item[strings.ToLower(rt.Method)] = out
+	}
+
+	doc.Components.Schemas = gen.components
+	if len(r.SecuritySchemes) > 0 {
+		doc.Components.SecuritySchemes = r.SecuritySchemes
+	}
+	return doc
+}
+
+func (r *Registry) jsonResponse(gen *generator, description string, data interface{}) *Response {
+	var schema *Schema
+	if data != nil {
+		schema = gen.schemaOf(data)
+	}
+	if r.Envelope != nil {
+		schema = r.Envelope(schema)
+	}
+	if schema == nil {
+		return &Response{Description: description}
+	}
+	return &Response{Description: description, Content: map[string]MediaType{"application/json": {Schema: schema}}}
+}
+
+var pathParam = regexp.MustCompile(`:([A-Za-z0-9_]+)`)
+
+// convertPath turns /employees/:id into /employees/{id} with its parameters.
+func convertPath(path string) (string, []*Parameter) {
+	var params []*Parameter
+	out := pathParam.ReplaceAllStringFunc(path, func(m string) string {
+		name := m[1:]
+		params = append(params, &Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		return "{" + name + "}"
+	})
+	return out, params
+}
+
+// operationID derives a stable id, e.g. get_employees_id_salaries.
+func operationID(method, path string) string {
+	parts := []string{strings.ToLower(method)}
+	for _, seg := range strings.Split(path, "/") {
+		seg = strings.Trim(seg, ":*")
+		seg = strings.NewReplacer(".", "_", "-", "_").Replace(seg)
+		if seg != "" {
+			parts = append(parts, seg)
+		}
+	}
+	return strings.Join(parts, "_")
+}
+
+// handlerName shortens a handler name such as
+// "github.com/x/handler.(*EmployeeHandler).GetHandler-fm" to
+// "EmployeeHandler.GetHandler".
+func handlerName(name string) string {
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	if i := strings.Index(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.TrimSuffix(name, "-fm")
+	return strings.NewReplacer("(*", "", ")", "").Replace(name)
+}
+
+func firstSegment(path string) string {
+	for _, seg := range strings.Split(path, "/") {
+		if seg != "" && !strings.HasPrefix(seg, ":") {
+			return seg
+		}
+	}
+	return "default"
+}
@@ -0,0 +1,86 @@
+package openapi
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testAddress struct {
+	City string `json:"city"`
+}
+
+type testBase struct {
+	ID int `json:"id"`
+}
+
+type testPerson struct {
+	testBase
+	Name     string         `json:"name"`
+	Born     time.Time      `json:"born"`
+	Left     *time.Time     `json:"left,omitempty"`
+	Tags     []string       `json:"tags"`
+	Labels   map[string]int `json:"labels"`
+	Home     *testAddress   `json:"home"`
+	Friends  []*testPerson  `json:"friends"`
+	Secret   string         `json:"-"`
+	internal string
+	Extra    map[string]string
+}
+
+func TestRegistry_Build(t *testing.T) {
+	r := NewRegistry("Test", "1")
+	r.Document(http.MethodPost, "/people/:id/friends", Operation{Summary: "Add a friend", Request: testPerson{}, Response: []testPerson{}, Status: http.StatusCreated, Security: []string{"key"}})
+	r.ErrorResponses = map[int]ErrorResponse{
+		http.StatusBadRequest:   {Description: "bad", Only: WithRequest},
+		http.StatusUnauthorized: {Description: "unauthorized", Only: WithSecurity},
+	}
+
+	doc := r.Build([]Route{
+		{Method: http.MethodPost, Path: "/people/:id/friends"},
+		{Method: http.MethodGet, Path: "/people/:id", Name: "github.com/x/handler.(*PersonHandler).GetHandler-fm"},
+		{Method: http.MethodGet, Path: "/static/*"},
+	})
+
+	require.Len(t, doc.Paths, 2)
+	get := doc.Paths["/people/{id}"]["get"]
+	require.NotNil(t, get)
+	assert.Equal(t, "PersonHandler.GetHandler", get.Summary)
+	assert.Equal(t, []string{"people"}, get.Tags)
+	assert.Equal(t, "get_people_id", get.OperationID)
+	require.Len(t, get.Parameters, 1)
+	assert.Equal(t, "id", get.Parameters[0].Name)
+	assert.Equal(t, "path", get.Parameters[0].In)
+	assert.NotContains(t, get.Responses, "400", "undocumented routes have no body")
+	assert.NotContains(t, get.Responses, "401")
+
+	post := doc.Paths["/people/{id}/friends"]["post"]
+	require.NotNil(t, post)
+	assert.Contains(t, post.Responses, "201")
+	assert.Contains(t, post.Responses, "400")
+	assert.Contains(t, post.Responses, "401")
+	assert.Equal(t, []map[string][]string{{"key": {}}}, post.Security)
+	assert.Equal(t, "#/components/schemas/testPerson", post.RequestBody.Content["application/json"].Schema.Ref)
+	assert.Equal(t, "#/components/schemas/testPerson", post.Responses["201"].Content["application/json"].Schema.Items.Ref)
+
+	person := doc.Components.Schemas["testPerson"]
+	require.NotNil(t, person)
+	assert.ElementsMatch(t, []string{"id", "name", "born", "left", "tags", "labels", "home", "friends", "Extra"}, keys(person.Properties))
+	assert.Equal(t, &Schema{Type: "string", Format: "date-time"}, person.Properties["born"])
+	assert.True(t, person.Properties["left"].Nullable)
+	assert.Equal(t, "integer", person.Properties["labels"].AdditionalProperties.Type)
+	assert.Equal(t, "#/components/schemas/testAddress", person.Properties["home"].Ref)
+	assert.Equal(t, "#/components/schemas/testPerson", person.Properties["friends"].Items.Ref, "recursive types are referenced")
+	assert.Contains(t, doc.Components.Schemas, "testAddress")
+}
+
+func keys(m map[string]*Schema) []string {
+	var out []string
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
@@ -0,0 +1,220 @@
+package openapi
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Document is an OpenAPI 3 document, marshalled as JSON.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations of a path by lower case method.
+type PathItem map[string]*OperationObject
+
+type OperationObject struct {
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	OperationID string                `json:"operationId"`
+	Parameters  []*Parameter          `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas,omitempty"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// Schema is the subset of JSON schema the generator produces.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Nullable             bool               `json:"nullable,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+}
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	durationType  = reflect.TypeOf(time.Duration(0))
+	rawJSONType   = reflect.TypeOf(json.RawMessage(nil))
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// generator reflects Go types into schemas, collecting named structs as
+// components referenced by name.
+type generator struct {
+	components map[string]*Schema
+	// names maps the struct types seen to their component name
+	names map[reflect.Type]string
+}
+
+func newGenerator() *generator {
+	return &generator{components: make(map[string]*Schema), names: make(map[reflect.Type]string)}
+}
+
+func (g *generator) schemaOf(v interface{}) *Schema {
+	return g.schema(reflect.TypeOf(v))
+}
+
+func (g *generator) schema(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+	if t.Kind() == reflect.Ptr {
+		s := g.schema(t.Elem())
+		if s.Ref == "" {
+			s.Nullable = true
+		}
+		return s
+	}
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t == durationType:
+		return &Schema{Type: "integer", Format: "int64", Description: "nanoseconds"}
+	case t == rawJSONType:
+		return &Schema{}
+	case t.Implements(marshalerType) || reflect.PtrTo(t).Implements(marshalerType):
+		// custom JSON encodings are documented as free form
+		return &Schema{}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		return g.structSchema(t)
+	}
+	// interface{} and anything else accepts any value
+	return &Schema{}
+}
+
+// structSchema returns a reference to the component of a named struct,
+// reflecting it on first use, or the inline schema of an anonymous one.
+func (g *generator) structSchema(t reflect.Type) *Schema {
+	if t.Name() == "" {
+		return g.objectSchema(t)
+	}
+	if name, ok := g.names[t]; ok {
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+	name := g.componentName(t)
+	g.names[t] = name
+	// registered before reflecting the fields, for recursive types
+	g.components[name] = &Schema{}
+	*g.components[name] = *g.objectSchema(t)
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// componentName is the type name, qualified by its package when two
+// packages declare the same name.
+func (g *generator) componentName(t reflect.Type) string {
+	name := t.Name()
+	if i := strings.IndexByte(name, '['); i >= 0 {
+		name = name[:i]
+	}
+	taken := false
+	for other, n := range g.names {
+		taken = taken || n == name && other != t
+	}
+	if taken {
+		pkg := t.PkgPath()
+		if i := strings.LastIndexByte(pkg, '/'); i >= 0 {
+			pkg = pkg[i+1:]
+		}
+		name = pkg + "." + name
+	}
+	return name
+}
+
+func (g *generator) objectSchema(t reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	g.addFields(s, t)
+	return s
+}
+
+// addFields adds the JSON fields of t, flattening embedded structs the way
+// encoding/json does.
+func (g *generator) addFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.addFields(s, ft)
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s.Properties[name] = g.schema(f.Type)
+	}
+}
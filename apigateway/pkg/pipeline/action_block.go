@@ -2,7 +2,6 @@ package pipeline
 
 import (
 	"fmt"
-	"sync"
 	"time"
 )
 
@@ -13,12 +12,10 @@ type ActionFunc func(interface{}) error
 // It supports configurable retry policies and concurrency for parallel processing
 type ActionBlock struct {
 	*BaseBlock
-	input      chan interface{}
-	action     ActionFunc
-	targets    []*Target
-	targetsMux sync.RWMutex
-	stopOnce   sync.Once
-	options    BlockOptions
+	input   *blockInput
+	action  ActionFunc
+	links   links
+	options BlockOptions
 }
 
 // NewActionBlock creates a new ActionBlock with the specified action function and options
@@ -26,11 +23,11 @@ type ActionBlock struct {
 func NewActionBlock(action ActionFunc, opts ...Option) *ActionBlock {
 	options := applyOptions(opts)
 	
+	base := NewBaseBlockContext(options.Context)
 	b := &ActionBlock{
-		BaseBlock: NewBaseBlock(),
-		input:     make(chan interface{}, options.BufferSize),
+		BaseBlock: base,
+		input:     newBlockInput(base, options),
 		action:    action,
-		targets:   make([]*Target, 0),
 		options:   options,
 	}
 
@@ -39,35 +36,48 @@ func NewActionBlock(action ActionFunc, opts ...Option) *ActionBlock {
 	for i := 0; i < options.ConcurrencyDegree; i++ {
 		go b.process()
 	}
+	// Complete or fault the targets once every worker finished
+	go func() {
+		b.wg.Wait()
+		b.finish(&b.links)
+	}()
 
 	return b
 }
 
-// Post sends a message to the action block
+// Post sends a message to the action block, following the overflow policy
+// once the block is full: by default it waits for room.
 func (b *ActionBlock) Post(message interface{}) bool {
 	if b.IsCompleted() {
 		return false
 	}
+	return b.input.offer(b.ctx, message, b.input.policy)
+}
 
-	select {
-	case b.input <- message:
-		return true
-	default:
+// TryPost sends a message without waiting, it returns false when the block
+// is full whatever its overflow policy.
+func (b *ActionBlock) TryPost(message interface{}) bool {
+	if b.IsCompleted() {
 		return false
 	}
+	return b.input.offer(b.ctx, message, OverflowDrop)
 }
 
-// LinkTo links this block to a target block with an optional filter function
-func (b *ActionBlock) LinkTo(target *Target, filter func(interface{}) bool) {
-	b.targetsMux.Lock()
-	defer b.targetsMux.Unlock()
+// Dropped returns the number of messages discarded by OverflowDropOldest.
+func (b *ActionBlock) Dropped() int64 {
+	return b.input.droppedCount()
+}
 
-	b.targets = append(b.targets, target)
+// LinkTo links this block to a target block. Each message goes to the first
+// link whose filter accepts it, a nil filter accepts every message
+func (b *ActionBlock) LinkTo(target *Target, filter func(interface{}) bool) {
+	b.links.add(target, filter)
+}
 
-	// If there's a filter, set it on the target
-	if filter != nil {
-		target.SetFilter(filter)
-	}
+// LinkFallback links this block to the target receiving the messages no
+// link accepts, instead of dropping them
+func (b *ActionBlock) LinkFallback(target *Target) {
+	b.links.setFallback(target)
 }
 
 // process handles the message processing loop for a single worker
@@ -84,7 +94,7 @@ func (b *ActionBlock) process() {
 		case <-b.ctx.Done():
 			return
 
-		case msg, ok := <-b.input:
+		case msg, ok := <-b.input.ch:
 			if !ok {
 				return
 			}
@@ -96,21 +106,9 @@ func (b *ActionBlock) process() {
 				continue
 			}
 
-			// Get a copy of targets to avoid holding the lock while sending
-			b.targetsMux.RLock()
-			targets := make([]*Target, len(b.targets))
-			copy(targets, b.targets)
-			b.targetsMux.RUnlock()
-
-			// Forward the message to all targets
-			for _, target := range targets {
-				if target.filter == nil || target.filter(msg) {
-					select {
-					case target.ch <- msg:
-					case <-b.ctx.Done():
-						return
-					}
-				}
+			// Forward the message to the first link accepting it
+			if !b.links.forward(b.ctx, msg) {
+				return
 			}
 		}
 	}
@@ -156,8 +154,6 @@ func (b *ActionBlock) executeAction(msg interface{}) error {
 // Complete marks the block as completed and closes the input channel
 // This signals all workers to finish processing
 func (b *ActionBlock) Complete() {
-	b.stopOnce.Do(func() {
-		close(b.input)
-	})
+	b.input.close()
 }
 
@@ -13,6 +13,7 @@ type FaultHandler func(error)
 
 // BaseBlock represents the base implementation of a dataflow block
 type BaseBlock struct {
+	parent           context.Context
 	ctx              context.Context
 	cancel           context.CancelFunc
 	wg               sync.WaitGroup
@@ -28,8 +29,18 @@ type BaseBlock struct {
 
 // NewBaseBlock creates a new BaseBlock
 func NewBaseBlock() *BaseBlock {
-	ctx, cancel := context.WithCancel(context.Background())
+	return NewBaseBlockContext(context.Background())
+}
+
+// NewBaseBlockContext creates a new BaseBlock whose context derives from parent,
+// cancelling parent stops the block
+func NewBaseBlockContext(parent context.Context) *BaseBlock {
+	if parent == nil {
+		parent = context.Background()
+	}
+	ctx, cancel := context.WithCancel(parent)
 	return &BaseBlock{
+		parent:     parent,
 		ctx:        ctx,
 		cancel:     cancel,
 		completion: make(chan struct{}),
@@ -59,9 +70,12 @@ func (b *BaseBlock) SignalCompletion() {
 }
 
 // Fault sets the error state and cancels the context
+// The first fault wins, later errors are only passed to the fault handlers
 func (b *BaseBlock) Fault(err error) {
 	b.errMutex.Lock()
-	b.err = err
+	if b.err == nil {
+		b.err = err
+	}
 	b.errMutex.Unlock()
 
 	if b.cancel != nil {
@@ -96,7 +110,7 @@ func (b *BaseBlock) Completion() <-chan struct{} {
 func (b *BaseBlock) Wait() error {
 	<-b.completion
 	b.wg.Wait()
-	return b.err
+	return b.loadErr()
 }
 
 // Error returns the error if the block faulted
@@ -104,11 +118,27 @@ func (b *BaseBlock) Error() error {
 	b.Complete()
 	b.wg.Wait()
 
+	return b.loadErr()
+}
+
+// loadErr returns the fault of the block, nil if it did not fault
+func (b *BaseBlock) loadErr() error {
 	b.errMutex.RLock()
 	defer b.errMutex.RUnlock()
 	return b.err
 }
 
+// finish ends a block once its workers returned. A block stopped by its parent
+// context faults with the context error, then the links complete with the
+// fault of the block so it reaches the blocks downstream.
+func (b *BaseBlock) finish(l *links) {
+	if err := b.parent.Err(); err != nil && b.loadErr() == nil {
+		b.Fault(err)
+	}
+	l.complete(b.loadErr())
+	b.SignalCompletion()
+}
+
 // IsCompleted returns true if the block has completed
 func (b *BaseBlock) IsCompleted() bool {
 	select {
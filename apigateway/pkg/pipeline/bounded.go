@@ -0,0 +1,170 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// OverflowPolicy decides what Post does with a message when the block
+// already holds BoundedCapacity messages.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock makes Post wait for room, so producers slow down to the
+	// pace of the block
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDrop rejects the new message, Post returns false
+	OverflowDrop
+	// OverflowDropOldest discards the oldest waiting message to make room,
+	// the latest messages win
+	OverflowDropOldest
+)
+
+// blockInput is the input queue of a block. It may be closed while posts
+// wait for room: they return false instead of sending on a closed channel.
+// Posts also give up once the block is faulted or cancelled.
+type blockInput struct {
+	ch     chan interface{}
+	policy OverflowPolicy
+	owner  *BaseBlock
+	// mu is held for reading by posts and for writing to close ch
+	mu        sync.RWMutex
+	closing   chan struct{}
+	closeOnce sync.Once
+	dropped   int64
+}
+
+func newBlockInput(owner *BaseBlock, options BlockOptions) *blockInput {
+	size := options.BufferSize
+	if options.BoundedCapacity > 0 {
+		size = options.BoundedCapacity
+	}
+	return &blockInput{
+		ch:      make(chan interface{}, size),
+		policy:  options.OverflowPolicy,
+		owner:   owner,
+		closing: make(chan struct{}),
+	}
+}
+
+// offer queues msg following policy and reports whether it was queued.
+// Waiting ends when the input is closed or ctx is done.
+func (in *blockInput) offer(ctx context.Context, msg interface{}, policy OverflowPolicy) bool {
+	in.mu.RLock()
+	defer in.mu.RUnlock()
+	select {
+	case <-in.closing:
+		return false
+	case <-in.owner.ctx.Done():
+		return false
+	default:
+	}
+
+	switch policy {
+	case OverflowDrop:
+		select {
+		case in.ch <- msg:
+			return true
+		default:
+			return false
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case in.ch <- msg:
+				return true
+			default:
+			}
+			select {
+			case <-in.ch:
+				atomic.AddInt64(&in.dropped, 1)
+			default:
+			}
+		}
+	default:
+		select {
+		case in.ch <- msg:
+			return true
+		case <-in.closing:
+			return false
+		case <-in.owner.ctx.Done():
+			return false
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// close stops accepting messages, once the waiting posts gave up.
+func (in *blockInput) close() {
+	in.closeOnce.Do(func() {
+		close(in.closing)
+		in.mu.Lock()
+		close(in.ch)
+		in.mu.Unlock()
+	})
+}
+
+// stopped reports whether the input takes no more messages.
+func (in *blockInput) stopped() bool {
+	select {
+	case <-in.closing:
+		return true
+	case <-in.owner.ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
+
+// droppedCount returns the number of messages OverflowDropOldest discarded.
+func (in *blockInput) droppedCount() int64 {
+	return atomic.LoadInt64(&in.dropped)
+}
+
+// target returns a Target forwarding to this input.
+func (in *blockInput) target() *Target {
+	return &Target{ch: in.ch, input: in}
+}
+
+// send forwards msg to the target following the overflow policy of the
+// target block. It reports whether msg was delivered.
+func (t *Target) send(ctx context.Context, msg interface{}) bool {
+	if t.input != nil {
+		return t.input.offer(ctx, msg, t.input.policy)
+	}
+	select {
+	case t.ch <- msg:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// stopped reports whether the target block takes no more messages.
+func (t *Target) stopped() bool {
+	return t.input != nil && t.input.stopped()
+}
+
+// fault faults the target block with err, targets that are plain channels
+// are closed.
+func (t *Target) fault(err error) {
+	if t.input != nil {
+		t.input.owner.Fault(err)
+		return
+	}
+	t.close()
+}
+
+// close tells the target no more messages follow. It may be called once per
+// linked source.
+func (t *Target) close() {
+	t.closeOnce.Do(func() {
+		if t.input != nil {
+			t.input.close()
+			return
+		}
+		close(t.ch)
+	})
+}
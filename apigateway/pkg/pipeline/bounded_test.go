@@ -0,0 +1,156 @@
+package pipeline
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockedAction returns an action block that holds every message until
+// release is closed, and the number of messages it received.
+func blockedAction(release <-chan struct{}, opts ...Option) (*ActionBlock, *int32) {
+	var received int32
+	return NewActionBlock(func(input interface{}) error {
+		<-release
+		atomic.AddInt32(&received, 1)
+		return nil
+	}, opts...), &received
+}
+
+func TestBoundedCapacity_Block(t *testing.T) {
+	release := make(chan struct{})
+	action, received := blockedAction(release, WithBoundedCapacity(2, OverflowBlock))
+
+	// One message is taken by the worker, two wait in the block
+	for i := 0; i < 3; i++ {
+		if !action.Post(i) {
+			t.Fatalf("Failed to post message %d", i)
+		}
+	}
+	if action.TryPost(3) {
+		t.Fatal("TryPost succeeded on a full block")
+	}
+
+	posted := make(chan bool)
+	go func() { posted <- action.Post(3) }()
+	select {
+	case <-posted:
+		t.Fatal("Post did not wait for room")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	if !<-posted {
+		t.Fatal("Post failed once there was room")
+	}
+	action.Complete()
+	if err := action.Wait(); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+	if got := atomic.LoadInt32(received); got != 4 {
+		t.Errorf("Expected 4 messages processed, got %d", got)
+	}
+}
+
+func TestBoundedCapacity_Drop(t *testing.T) {
+	release := make(chan struct{})
+	action, received := blockedAction(release, WithBoundedCapacity(1, OverflowDrop))
+
+	if !action.Post(0) {
+		t.Fatal("Failed to post message 0")
+	}
+	// Wait for the worker to take message 0
+	time.Sleep(10 * time.Millisecond)
+	if !action.Post(1) {
+		t.Fatal("Failed to post message 1")
+	}
+	if action.Post(2) {
+		t.Fatal("Post accepted a message over capacity")
+	}
+
+	close(release)
+	action.Complete()
+	action.Wait()
+	if got := atomic.LoadInt32(received); got != 2 {
+		t.Errorf("Expected 2 messages processed, got %d", got)
+	}
+}
+
+func TestBoundedCapacity_DropOldest(t *testing.T) {
+	release := make(chan struct{})
+	var got []interface{}
+	action := NewActionBlock(func(input interface{}) error {
+		<-release
+		got = append(got, input)
+		return nil
+	}, WithBoundedCapacity(2, OverflowDropOldest))
+
+	if !action.Post(0) {
+		t.Fatal("Failed to post message 0")
+	}
+	time.Sleep(10 * time.Millisecond)
+	for i := 1; i <= 4; i++ {
+		if !action.Post(i) {
+			t.Fatalf("Failed to post message %d", i)
+		}
+	}
+
+	close(release)
+	action.Complete()
+	action.Wait()
+	want := []interface{}{0, 3, 4}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("Expected the latest messages %v, got %v", want, got)
+	}
+	if action.Dropped() != 2 {
+		t.Errorf("Expected 2 dropped messages, got %d", action.Dropped())
+	}
+}
+
+func TestBoundedCapacity_LinkedBackpressure(t *testing.T) {
+	release := make(chan struct{})
+	buffer := NewBufferBlock(WithBufferSize(10))
+	action, received := blockedAction(release, WithBoundedCapacity(1, OverflowBlock))
+	LinkTo(buffer, action, nil)
+
+	for i := 0; i < 5; i++ {
+		if !buffer.Post(i) {
+			t.Fatalf("Failed to post message %d", i)
+		}
+	}
+	time.Sleep(20 * time.Millisecond)
+	if n := len(buffer.input.ch); n != 2 {
+		t.Errorf("Expected 2 messages held back in the buffer, got %d", n)
+	}
+
+	close(release)
+	buffer.Complete()
+	if err := WaitAll(buffer, action); err != nil {
+		t.Fatalf("WaitAll failed: %v", err)
+	}
+	if got := atomic.LoadInt32(received); got != 5 {
+		t.Errorf("Expected 5 messages processed, got %d", got)
+	}
+}
+
+func TestBoundedCapacity_CompleteWhileWaiting(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	action, _ := blockedAction(release, WithBoundedCapacity(1, OverflowBlock))
+	action.Post(0)
+	action.Post(1)
+
+	posted := make(chan bool)
+	go func() { posted <- action.Post(2) }()
+	time.Sleep(10 * time.Millisecond)
+	action.Complete()
+
+	select {
+	case ok := <-posted:
+		if ok {
+			t.Error("Post succeeded on a completed block")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Post kept waiting after Complete")
+	}
+}
@@ -0,0 +1,121 @@
+package pipeline
+
+import (
+	"fmt"
+	"sync"
+)
+
+// CloneFunc returns a copy of a message for one of the links of a BroadcastBlock
+type CloneFunc func(interface{}) interface{}
+
+// BroadcastBlock represents a block that offers every message to all of its links,
+// so one source can feed several branches of a graph
+// Messages are delivered to the links in parallel and in posting order, a slow
+// link only holds back the next message, not the other links
+type BroadcastBlock struct {
+	*BaseBlock
+	input *blockInput
+	clone CloneFunc
+	links links
+}
+
+// NewBroadcastBlock creates a new BroadcastBlock. Each link receives its own copy
+// of every message made by clone, or the message itself when clone is nil
+// Concurrency options are ignored: one worker keeps the messages in order
+func NewBroadcastBlock(clone CloneFunc, opts ...Option) *BroadcastBlock {
+	options := applyOptions(opts)
+
+	base := NewBaseBlockContext(options.Context)
+	b := &BroadcastBlock{
+		BaseBlock: base,
+		input:     newBlockInput(base, options),
+		clone:     clone,
+	}
+
+	b.wg.Add(1)
+	go b.process()
+	// Complete or fault the targets once the worker finished
+	go func() {
+		b.wg.Wait()
+		b.finish(&b.links)
+	}()
+
+	return b
+}
+
+// Post sends a message to the broadcast block, following the overflow policy
+// once the block is full: by default it waits for room.
+func (b *BroadcastBlock) Post(message interface{}) bool {
+	if b.IsCompleted() {
+		return false
+	}
+	return b.input.offer(b.ctx, message, b.input.policy)
+}
+
+// TryPost sends a message without waiting, it returns false when the block
+// is full whatever its overflow policy.
+func (b *BroadcastBlock) TryPost(message interface{}) bool {
+	if b.IsCompleted() {
+		return false
+	}
+	return b.input.offer(b.ctx, message, OverflowDrop)
+}
+
+// LinkTo links this block to a target block. Each message goes to every
+// link whose filter accepts it, a nil filter accepts every message
+func (b *BroadcastBlock) LinkTo(target *Target, filter func(interface{}) bool) {
+	b.links.add(target, filter)
+}
+
+// LinkFallback links this block to the target receiving the messages no
+// link accepts, instead of dropping them
+func (b *BroadcastBlock) LinkFallback(target *Target) {
+	b.links.setFallback(target)
+}
+
+// process delivers every message to all the links
+func (b *BroadcastBlock) process() {
+	defer b.wg.Done()
+	defer func() {
+		if r := recover(); r != nil {
+			b.Fault(fmt.Errorf("panic in BroadcastBlock: %v", r))
+		}
+	}()
+
+	for {
+		select {
+		case <-b.ctx.Done():
+			return
+
+		case msg, ok := <-b.input.ch:
+			if !ok {
+				return
+			}
+
+			// Copy the message for every link before any of them can change it
+			targets := b.links.matching(msg)
+			copies := make([]interface{}, len(targets))
+			for i := range targets {
+				copies[i] = msg
+				if b.clone != nil {
+					copies[i] = b.clone(msg)
+				}
+			}
+
+			var sends sync.WaitGroup
+			for i, target := range targets {
+				sends.Add(1)
+				go func(target *Target, msg interface{}) {
+					defer sends.Done()
+					target.send(b.ctx, msg)
+				}(target, copies[i])
+			}
+			sends.Wait()
+		}
+	}
+}
+
+// Complete marks the block as completed and closes the input channel
+func (b *BroadcastBlock) Complete() {
+	b.input.close()
+}
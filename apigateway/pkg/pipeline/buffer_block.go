@@ -2,18 +2,15 @@ package pipeline
 
 import (
 	"fmt"
-	"sync"
 )
 
 // BufferBlock represents a block that buffers messages for consumption by linked blocks
 // It supports configurable concurrency for parallel processing of messages
 type BufferBlock struct {
 	*BaseBlock
-	input      chan interface{}
-	targets    []*Target
-	targetsMux sync.RWMutex
-	capacity   int
-	stopOnce   sync.Once
+	input    *blockInput
+	links    links
+	capacity int
 }
 
 // NewBufferBlock creates a new BufferBlock with the specified options
@@ -21,10 +18,10 @@ type BufferBlock struct {
 func NewBufferBlock(opts ...Option) *BufferBlock {
 	options := applyOptions(opts)
 	
+	base := NewBaseBlockContext(options.Context)
 	b := &BufferBlock{
-		BaseBlock: NewBaseBlock(),
-		input:     make(chan interface{}, options.BufferSize),
-		targets:   make([]*Target, 0),
+		BaseBlock: base,
+		input:     newBlockInput(base, options),
 		capacity:  options.BufferSize,
 	}
 
@@ -33,35 +30,48 @@ func NewBufferBlock(opts ...Option) *BufferBlock {
 	for i := 0; i < options.ConcurrencyDegree; i++ {
 		go b.process()
 	}
+	// Complete or fault the targets once every worker finished
+	go func() {
+		b.wg.Wait()
+		b.finish(&b.links)
+	}()
 
 	return b
 }
 
-// Post sends a message to the buffer block
+// Post sends a message to the buffer block, following the overflow policy
+// once the block is full: by default it waits for room.
 func (b *BufferBlock) Post(message interface{}) bool {
 	if b.IsCompleted() {
 		return false
 	}
+	return b.input.offer(b.ctx, message, b.input.policy)
+}
 
-	select {
-	case b.input <- message:
-		return true
-	default:
+// TryPost sends a message without waiting, it returns false when the block
+// is full whatever its overflow policy.
+func (b *BufferBlock) TryPost(message interface{}) bool {
+	if b.IsCompleted() {
 		return false
 	}
+	return b.input.offer(b.ctx, message, OverflowDrop)
 }
 
-// LinkTo links this block to a target block with an optional filter function
-func (b *BufferBlock) LinkTo(target *Target, filter func(interface{}) bool) {
-	b.targetsMux.Lock()
-	defer b.targetsMux.Unlock()
+// Dropped returns the number of messages discarded by OverflowDropOldest.
+func (b *BufferBlock) Dropped() int64 {
+	return b.input.droppedCount()
+}
 
-	b.targets = append(b.targets, target)
+// LinkTo links this block to a target block. Each message goes to the first
+// link whose filter accepts it, a nil filter accepts every message
+func (b *BufferBlock) LinkTo(target *Target, filter func(interface{}) bool) {
+	b.links.add(target, filter)
+}
 
-	// If there's a filter, set it on the target
-	if filter != nil {
-		target.SetFilter(filter)
-	}
+// LinkFallback links this block to the target receiving the messages no
+// link accepts, instead of dropping them
+func (b *BufferBlock) LinkFallback(target *Target) {
+	b.links.setFallback(target)
 }
 
 // process handles the message processing loop for a single worker
@@ -71,13 +81,6 @@ func (b *BufferBlock) process() {
 		if r := recover(); r != nil {
 			b.Fault(fmt.Errorf("panic in BufferBlock: %v", r))
 		}
-		// Close target channels when all processing is done
-		b.targetsMux.RLock()
-		for _, t := range b.targets {
-			close(t.ch)
-		}
-		b.targetsMux.RUnlock()
-		b.SignalCompletion()
 	}()
 
 	for {
@@ -85,26 +88,14 @@ func (b *BufferBlock) process() {
 		case <-b.ctx.Done():
 			return
 
-		case msg, ok := <-b.input:
+		case msg, ok := <-b.input.ch:
 			if !ok {
 				return
 			}
 
-			// Get a copy of targets to avoid holding the lock while sending
-			b.targetsMux.RLock()
-			targets := make([]*Target, len(b.targets))
-			copy(targets, b.targets)
-			b.targetsMux.RUnlock()
-
-			// Forward the message to all targets
-			for _, target := range targets {
-				if target.filter == nil || target.filter(msg) {
-					select {
-					case target.ch <- msg:
-					case <-b.ctx.Done():
-						return
-					}
-				}
+			// Forward the message to the first link accepting it
+			if !b.links.forward(b.ctx, msg) {
+				return
 			}
 		}
 	}
@@ -113,8 +104,6 @@ func (b *BufferBlock) process() {
 // Complete marks the block as completed and closes the input channel
 // This signals all workers to finish processing
 func (b *BufferBlock) Complete() {
-	b.stopOnce.Do(func() {
-		close(b.input)
-	})
+	b.input.close()
 }
 
@@ -0,0 +1,130 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitAllWithin runs WaitAll and fails the test if it does not return in time.
+func waitAllWithin(t *testing.T, d time.Duration, blocks ...interface{}) error {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() { done <- WaitAll(blocks...) }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(d):
+		t.Fatal("WaitAll hung")
+		return nil
+	}
+}
+
+func TestFault_PropagatesDownstream(t *testing.T) {
+	errParse := errors.New("parse failed")
+	buffer := NewBufferBlock()
+	parser := NewTransformBlock(func(input interface{}) (interface{}, error) {
+		if input == "bad" {
+			return nil, errParse
+		}
+		return input, nil
+	})
+	action := NewActionBlock(func(input interface{}) error { return nil })
+	LinkTo(buffer, parser, nil)
+	LinkTo(parser, action, nil)
+
+	go func() {
+		for _, msg := range []string{"a", "bad", "b"} {
+			buffer.Post(msg)
+		}
+		buffer.Complete()
+	}()
+
+	if err := waitAllWithin(t, time.Second, buffer, parser, action); !errors.Is(err, errParse) {
+		t.Fatalf("Expected the parse error, got %v", err)
+	}
+	if err := action.Wait(); !errors.Is(err, errParse) {
+		t.Errorf("Expected the downstream block faulted with the parse error, got %v", err)
+	}
+	if err := buffer.Wait(); err != nil {
+		t.Errorf("Expected the upstream block to complete, got %v", err)
+	}
+}
+
+func TestFault_DownstreamDoesNotBlockUpstream(t *testing.T) {
+	errSink := errors.New("sink down")
+	buffer := NewBufferBlock()
+	sink := NewActionBlock(func(input interface{}) error { return errSink })
+	LinkTo(buffer, sink, nil)
+
+	go func() {
+		for i := 0; i < 10; i++ {
+			buffer.Post(i)
+		}
+		buffer.Complete()
+	}()
+
+	if err := waitAllWithin(t, time.Second, buffer, sink); !errors.Is(err, errSink) {
+		t.Fatalf("Expected the sink error, got %v", err)
+	}
+}
+
+func TestFault_FirstErrorWins(t *testing.T) {
+	block := NewBaseBlock()
+	first, second := errors.New("first"), errors.New("second")
+	block.Fault(first)
+	block.Fault(second)
+	if err := block.Wait(); err != first {
+		t.Errorf("Expected the first fault, got %v", err)
+	}
+}
+
+func TestWithContext_CancelTearsDown(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	release := make(chan struct{})
+
+	buffer := NewBufferBlock(WithContext(ctx))
+	fetch := NewTransformBlock(func(input interface{}) (interface{}, error) {
+		return input, nil
+	})
+	sink, _ := blockedAction(release)
+	LinkTo(buffer, fetch, nil)
+	LinkTo(fetch, sink, nil)
+
+	posted := make(chan struct{})
+	go func() {
+		defer close(posted)
+		for i := 0; buffer.Post(i); i++ {
+		}
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-posted:
+	case <-time.After(time.Second):
+		t.Fatal("Post kept waiting after the context was cancelled")
+	}
+	// The running action does not watch the context, let it return
+	close(release)
+	if err := waitAllWithin(t, time.Second, buffer, fetch); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if err := sink.Wait(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the sink faulted by the cancellation, got %v", err)
+	}
+}
+
+func TestWithContext_AlreadyCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	action := NewActionBlock(func(input interface{}) error { return nil }, WithContext(ctx))
+	if action.Post(1) {
+		t.Error("Post succeeded on a cancelled block")
+	}
+	if err := action.Wait(); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
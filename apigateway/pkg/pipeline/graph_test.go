@@ -0,0 +1,167 @@
+package pipeline
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBroadcastBlock_FanOut(t *testing.T) {
+	broadcast := NewBroadcastBlock(func(msg interface{}) interface{} {
+		words := msg.([]string)
+		return append([]string(nil), words...)
+	})
+
+	var mu sync.Mutex
+	var upper, sorted []string
+	toUpper := NewActionBlock(func(input interface{}) error {
+		words := input.([]string)
+		for i := range words {
+			words[i] = strings.ToUpper(words[i])
+		}
+		mu.Lock()
+		upper = append(upper, words...)
+		mu.Unlock()
+		return nil
+	})
+	toSorted := NewActionBlock(func(input interface{}) error {
+		words := input.([]string)
+		sort.Strings(words)
+		mu.Lock()
+		sorted = append(sorted, words...)
+		mu.Unlock()
+		return nil
+	})
+	LinkTo(broadcast, toUpper, nil)
+	LinkTo(broadcast, toSorted, nil)
+
+	if !broadcast.Post([]string{"b", "a"}) {
+		t.Fatal("Failed to post message")
+	}
+	broadcast.Complete()
+	if err := WaitAll(broadcast, toUpper, toSorted); err != nil {
+		t.Fatalf("WaitAll failed: %v", err)
+	}
+
+	if strings.Join(upper, ",") != "B,A" {
+		t.Errorf("Expected B,A, got %v", upper)
+	}
+	if strings.Join(sorted, ",") != "a,b" {
+		t.Errorf("Expected each link to get its own copy, got %v", sorted)
+	}
+}
+
+func TestBroadcastBlock_SlowLink(t *testing.T) {
+	broadcast := NewBroadcastBlock(nil)
+	release := make(chan struct{})
+	slow, _ := blockedAction(release, WithBoundedCapacity(1, OverflowBlock))
+	fast := make(chan interface{}, 10)
+	LinkTo(broadcast, slow, nil)
+	Link(broadcast, &Target{ch: fast}, nil)
+
+	broadcast.Post(1)
+	select {
+	case <-fast:
+	case <-time.After(time.Second):
+		t.Fatal("The fast link waited for the slow one")
+	}
+	close(release)
+	broadcast.Complete()
+	if err := WaitAll(broadcast, slow); err != nil {
+		t.Fatalf("WaitAll failed: %v", err)
+	}
+}
+
+func TestJoinBlock_Pairs(t *testing.T) {
+	join := NewJoinBlock(WithBufferSize(5))
+	var got []Tuple
+	collect := NewActionBlock(func(input interface{}) error {
+		got = append(got, input.(Tuple))
+		return nil
+	})
+	LinkTo(join, collect, nil)
+
+	for i := 0; i < 3; i++ {
+		join.PostFirst(i)
+	}
+	for _, s := range []string{"a", "b"} {
+		join.PostSecond(s)
+	}
+	join.Complete()
+	if err := WaitAll(join, collect); err != nil {
+		t.Fatalf("WaitAll failed: %v", err)
+	}
+
+	want := []Tuple{{0, "a"}, {1, "b"}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestJoinBlock_Graph(t *testing.T) {
+	fetch := NewTransformBlock(func(input interface{}) (interface{}, error) {
+		return "page " + input.(string), nil
+	})
+	pages := NewBroadcastBlock(nil)
+	names := NewTransformBlock(func(input interface{}) (interface{}, error) {
+		return strings.Fields(input.(string))[1], nil
+	})
+	links := NewTransformBlock(func(input interface{}) (interface{}, error) {
+		return len(input.(string)), nil
+	})
+	join := NewJoinBlock()
+	var got []Tuple
+	save := NewActionBlock(func(input interface{}) error {
+		got = append(got, input.(Tuple))
+		return nil
+	})
+
+	LinkTo(fetch, pages, nil)
+	LinkTo(pages, names, nil)
+	LinkTo(pages, links, nil)
+	Link(names, join.First(), nil)
+	Link(links, join.Second(), nil)
+	LinkTo(join, save, nil)
+
+	fetch.Post("go")
+	fetch.Post("rust")
+	fetch.Complete()
+	if err := WaitAll(fetch, pages, names, links, join, save); err != nil {
+		t.Fatalf("WaitAll failed: %v", err)
+	}
+
+	want := []Tuple{{"go", 7}, {"rust", 9}}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+}
+
+func TestJoinBlock_OneSideDone(t *testing.T) {
+	join := NewJoinBlock(WithBoundedCapacity(1, OverflowBlock))
+	drain := make(chan interface{}, 10)
+	Link(join, &Target{ch: drain}, nil)
+
+	join.PostFirst(1)
+	join.First().close()
+
+	// The second source would wait forever for a partner that never comes
+	posted := make(chan bool)
+	go func() {
+		for i := 0; ; i++ {
+			if !join.PostSecond(i) {
+				posted <- false
+				return
+			}
+		}
+	}()
+	select {
+	case <-posted:
+	case <-time.After(time.Second):
+		t.Fatal("The second source kept waiting after the first completed")
+	}
+	if err := join.Wait(); err != nil {
+		t.Fatalf("Wait failed: %v", err)
+	}
+}
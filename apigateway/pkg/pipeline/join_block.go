@@ -0,0 +1,128 @@
+package pipeline
+
+import (
+	"fmt"
+)
+
+// Tuple is a pair of messages joined by a JoinBlock
+type Tuple struct {
+	First  interface{}
+	Second interface{}
+}
+
+// JoinBlock represents a block that pairs the messages of two sources in the order
+// they arrive, the n-th message of the first with the n-th of the second, and
+// sends each pair as a Tuple
+// It completes once either input is completed and drained, messages left without
+// a partner are dropped
+type JoinBlock struct {
+	*BaseBlock
+	first  *blockInput
+	second *blockInput
+	links  links
+}
+
+// NewJoinBlock creates a new JoinBlock, buffer and capacity options apply to
+// each of its two inputs
+func NewJoinBlock(opts ...Option) *JoinBlock {
+	options := applyOptions(opts)
+
+	base := NewBaseBlockContext(options.Context)
+	b := &JoinBlock{
+		BaseBlock: base,
+		first:     newBlockInput(base, options),
+		second:    newBlockInput(base, options),
+	}
+
+	b.wg.Add(1)
+	go b.process()
+	// Complete or fault the targets once the worker finished
+	go func() {
+		b.wg.Wait()
+		b.finish(&b.links)
+	}()
+
+	return b
+}
+
+// First returns the target of the first input, to link a source to
+func (b *JoinBlock) First() *Target {
+	return b.first.target()
+}
+
+// Second returns the target of the second input, to link a source to
+func (b *JoinBlock) Second() *Target {
+	return b.second.target()
+}
+
+// PostFirst sends a message to the first input, following its overflow policy
+func (b *JoinBlock) PostFirst(message interface{}) bool {
+	if b.IsCompleted() {
+		return false
+	}
+	return b.first.offer(b.ctx, message, b.first.policy)
+}
+
+// PostSecond sends a message to the second input, following its overflow policy
+func (b *JoinBlock) PostSecond(message interface{}) bool {
+	if b.IsCompleted() {
+		return false
+	}
+	return b.second.offer(b.ctx, message, b.second.policy)
+}
+
+// LinkTo links this block to a target block. Each Tuple goes to the first
+// link whose filter accepts it, a nil filter accepts every Tuple
+func (b *JoinBlock) LinkTo(target *Target, filter func(interface{}) bool) {
+	b.links.add(target, filter)
+}
+
+// LinkFallback links this block to the target receiving the messages no
+// link accepts, instead of dropping them
+func (b *JoinBlock) LinkFallback(target *Target) {
+	b.links.setFallback(target)
+}
+
+// process pairs the messages of the inputs until one of them is done
+func (b *JoinBlock) process() {
+	defer b.wg.Done()
+	defer func() {
+		if r := recover(); r != nil {
+			b.Fault(fmt.Errorf("panic in JoinBlock: %v", r))
+		}
+		// Unblock the sources still feeding the other input
+		b.first.close()
+		b.second.close()
+	}()
+
+	for {
+		var pair Tuple
+		var ok bool
+		select {
+		case <-b.ctx.Done():
+			return
+		case pair.First, ok = <-b.first.ch:
+			if !ok {
+				return
+			}
+		}
+		select {
+		case <-b.ctx.Done():
+			return
+		case pair.Second, ok = <-b.second.ch:
+			if !ok {
+				return
+			}
+		}
+
+		if !b.links.forward(b.ctx, pair) {
+			return
+		}
+	}
+}
+
+// Complete marks the block as completed and closes both inputs
+func (b *JoinBlock) Complete() {
+	b.first.close()
+	b.second.close()
+}
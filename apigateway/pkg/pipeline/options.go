@@ -1,6 +1,8 @@
 package pipeline
 
 import (
+	"context"
+	"sync"
 	"time"
 )
 
@@ -16,6 +18,16 @@ type BlockOptions struct {
 	// BufferSize specifies the capacity of the input channel
 	// Default varies by block type
 	BufferSize int
+
+	// BoundedCapacity limits the messages waiting in the block, replacing
+	// BufferSize; OverflowPolicy decides what Post does once it is reached.
+	// Default is OverflowBlock: Post and linked sources wait for room
+	BoundedCapacity int
+	OverflowPolicy  OverflowPolicy
+
+	// Context is the parent context of the block, cancelling it faults the
+	// block with the context error. Default is context.Background()
+	Context context.Context
 }
 
 // RetryPolicy defines the retry policy for operations
@@ -67,6 +79,29 @@ func WithBufferSize(size int) Option {
 	}
 }
 
+// WithBoundedCapacity limits the messages waiting in the block to capacity
+// and sets what Post does when it is reached: wait for room (OverflowBlock),
+// reject the message (OverflowDrop) or discard the oldest one
+// (OverflowDropOldest). Linked sources follow the same policy.
+func WithBoundedCapacity(capacity int, policy OverflowPolicy) Option {
+	return func(o *BlockOptions) {
+		if capacity > 0 {
+			o.BoundedCapacity = capacity
+			o.OverflowPolicy = policy
+		}
+	}
+}
+
+// WithContext makes the block stop once ctx is done, faulting with ctx.Err()
+// Pass the request context to tear down a whole pipeline when the request ends
+func WithContext(ctx context.Context) Option {
+	return func(o *BlockOptions) {
+		if ctx != nil {
+			o.Context = ctx
+		}
+	}
+}
+
 // applyOptions applies the given options to the default options
 func applyOptions(opts []Option) BlockOptions {
 	options := DefaultBlockOptions()
@@ -80,6 +115,9 @@ func applyOptions(opts []Option) BlockOptions {
 type Target struct {
 	ch     chan<- interface{}
 	filter func(interface{}) bool
+	// input is the input of the target block, nil for NewTarget channels
+	input     *blockInput
+	closeOnce sync.Once
 }
 
 // NewTarget creates a new target with the specified channel
@@ -0,0 +1,101 @@
+package pipeline
+
+import (
+	"context"
+	"sync"
+)
+
+// link is a target with the predicate deciding which messages it accepts
+type link struct {
+	target    *Target
+	predicate func(interface{}) bool
+}
+
+// accepts reports whether msg may go to the link. The filter set on the
+// target with SetFilter applies on top of the link predicate.
+func (l link) accepts(msg interface{}) bool {
+	if l.predicate != nil && !l.predicate(msg) {
+		return false
+	}
+	return l.target.filter == nil || l.target.filter(msg)
+}
+
+// links holds the outgoing links of a block and routes messages to them
+type links struct {
+	mu       sync.RWMutex
+	routes   []link
+	fallback *Target
+}
+
+// add appends a link, predicate may be nil to accept every message
+func (l *links) add(target *Target, predicate func(interface{}) bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.routes = append(l.routes, link{target: target, predicate: predicate})
+}
+
+// setFallback sets the target of the messages no link accepts
+func (l *links) setFallback(target *Target) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.fallback = target
+}
+
+// route returns the first link accepting msg, else the fallback, else nil
+func (l *links) route(msg interface{}) *Target {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, r := range l.routes {
+		if r.accepts(msg) {
+			return r.target
+		}
+	}
+	return l.fallback
+}
+
+// matching returns every link accepting msg, else the fallback
+func (l *links) matching(msg interface{}) []*Target {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	var targets []*Target
+	for _, r := range l.routes {
+		if r.accepts(msg) {
+			targets = append(targets, r.target)
+		}
+	}
+	if len(targets) == 0 && l.fallback != nil {
+		targets = append(targets, l.fallback)
+	}
+	return targets
+}
+
+// forward sends msg to the route of msg, messages without a route are
+// dropped. It returns false when ctx is done and the worker should stop.
+func (l *links) forward(ctx context.Context, msg interface{}) bool {
+	target := l.route(msg)
+	if target == nil {
+		return true
+	}
+	return target.send(ctx, msg) || ctx.Err() == nil
+}
+
+// complete closes the targets of a block once its workers finished, or
+// faults them with err when the block faulted.
+func (l *links) complete(err error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	targets := make([]*Target, 0, len(l.routes)+1)
+	for _, r := range l.routes {
+		targets = append(targets, r.target)
+	}
+	if l.fallback != nil {
+		targets = append(targets, l.fallback)
+	}
+	for _, t := range targets {
+		if err != nil {
+			t.fault(err)
+		} else {
+			t.close()
+		}
+	}
+}
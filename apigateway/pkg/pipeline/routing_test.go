@@ -0,0 +1,155 @@
+package pipeline
+
+import (
+	"errors"
+	"testing"
+)
+
+// collector returns an action block appending its messages to got.
+func collector(got *[]interface{}) *ActionBlock {
+	return NewActionBlock(func(input interface{}) error {
+		*got = append(*got, input)
+		return nil
+	})
+}
+
+func isEven(msg interface{}) bool { return msg.(int)%2 == 0 }
+
+func TestLinkTo_FirstMatch(t *testing.T) {
+	source := NewBufferBlock(WithBufferSize(10))
+	var even, small, rest []interface{}
+	evenBlock, smallBlock, restBlock := collector(&even), collector(&small), collector(&rest)
+
+	LinkTo(source, evenBlock, isEven)
+	LinkTo(source, smallBlock, func(msg interface{}) bool { return msg.(int) < 4 })
+	LinkToFallback(source, restBlock)
+
+	for i := 0; i < 6; i++ {
+		source.Post(i)
+	}
+	source.Complete()
+	if err := WaitAll(source, evenBlock, smallBlock, restBlock); err != nil {
+		t.Fatalf("WaitAll failed: %v", err)
+	}
+
+	if len(even) != 3 || even[0] != 0 || even[1] != 2 || even[2] != 4 {
+		t.Errorf("Expected 0, 2, 4 on the first link, got %v", even)
+	}
+	if len(small) != 2 || small[0] != 1 || small[1] != 3 {
+		t.Errorf("Expected 1, 3 on the second link, got %v", small)
+	}
+	if len(rest) != 1 || rest[0] != 5 {
+		t.Errorf("Expected 5 on the fallback link, got %v", rest)
+	}
+}
+
+func TestLinkTo_NoRouteDropped(t *testing.T) {
+	source := NewTransformBlock(func(input interface{}) (interface{}, error) {
+		return input, nil
+	})
+	var even []interface{}
+	evenBlock := collector(&even)
+	LinkTo(source, evenBlock, isEven)
+
+	for i := 0; i < 4; i++ {
+		source.Post(i)
+	}
+	source.Complete()
+	if err := WaitAll(source, evenBlock); err != nil {
+		t.Fatalf("WaitAll failed: %v", err)
+	}
+	if len(even) != 2 {
+		t.Errorf("Expected the odd messages dropped, got %v", even)
+	}
+}
+
+func TestLinkToFallback_ErrorRecords(t *testing.T) {
+	type record struct {
+		url string
+		err error
+	}
+	fetch := NewTransformBlock(func(input interface{}) (interface{}, error) {
+		url := input.(string)
+		if url == "down" {
+			return record{url: url, err: errors.New("unreachable")}, nil
+		}
+		return record{url: url}, nil
+	})
+	var saved, failed []interface{}
+	save, deadLetters := collector(&saved), collector(&failed)
+
+	LinkTo(fetch, save, func(msg interface{}) bool { return msg.(record).err == nil })
+	LinkToFallback(fetch, deadLetters)
+
+	for _, url := range []string{"a", "down", "b"} {
+		fetch.Post(url)
+	}
+	fetch.Complete()
+	if err := WaitAll(fetch, save, deadLetters); err != nil {
+		t.Fatalf("WaitAll failed: %v", err)
+	}
+
+	if len(saved) != 2 {
+		t.Errorf("Expected 2 saved records, got %v", saved)
+	}
+	if len(failed) != 1 || failed[0].(record).url != "down" {
+		t.Errorf("Expected the failed record on the fallback link, got %v", failed)
+	}
+}
+
+func TestBroadcastBlock_Fallback(t *testing.T) {
+	broadcast := NewBroadcastBlock(nil, WithBufferSize(10))
+	var even, all, odd []interface{}
+	evenBlock, allEvenBlock, oddBlock := collector(&even), collector(&all), collector(&odd)
+
+	LinkTo(broadcast, evenBlock, isEven)
+	LinkTo(broadcast, allEvenBlock, isEven)
+	LinkToFallback(broadcast, oddBlock)
+
+	for i := 0; i < 4; i++ {
+		broadcast.Post(i)
+	}
+	broadcast.Complete()
+	if err := WaitAll(broadcast, evenBlock, allEvenBlock, oddBlock); err != nil {
+		t.Fatalf("WaitAll failed: %v", err)
+	}
+
+	if len(even) != 2 || len(all) != 2 {
+		t.Errorf("Expected the even messages on both links, got %v and %v", even, all)
+	}
+	if len(odd) != 2 {
+		t.Errorf("Expected the odd messages on the fallback link, got %v", odd)
+	}
+}
+
+func TestLinkTo_TargetFilter(t *testing.T) {
+	source := NewBufferBlock(WithBufferSize(10))
+	small := make(chan interface{}, 10)
+	target := NewTarget(small)
+	target.SetFilter(func(msg interface{}) bool { return msg.(int) < 4 })
+	var rest []interface{}
+	restBlock := collector(&rest)
+
+	// The target filter applies on top of the link predicate
+	Link(source, target, isEven)
+	LinkToFallback(source, restBlock)
+
+	for i := 0; i < 6; i++ {
+		source.Post(i)
+	}
+	source.Complete()
+	if err := WaitAll(source, restBlock); err != nil {
+		t.Fatalf("WaitAll failed: %v", err)
+	}
+
+	var got []interface{}
+	for msg := range small {
+		got = append(got, msg)
+	}
+	if len(got) != 2 || got[0] != 0 || got[1] != 2 {
+		t.Errorf("Expected 0, 2 on the filtered target, got %v", got)
+	}
+	if len(rest) != 4 {
+		t.Errorf("Expected 4 messages on the fallback link, got %v", rest)
+	}
+}
@@ -0,0 +1,60 @@
+package pipeline
+
+import (
+	"context"
+)
+
+// ToStream links a channel to the source block and returns it, so the output of
+// the block can feed channel stages such as those of pkg/dataflow.
+// The channel is closed once the source completes or faults, check the error
+// of the source with Wait.
+func ToStream(source interface{}, size int) <-chan interface{} {
+	ch := make(chan interface{}, size)
+	Link(source, NewTarget(ch), nil)
+	return ch
+}
+
+// FromStream posts every message of in to the destination block and completes
+// it once in is closed. Once the destination is completed or faulted the rest
+// of in is drained, so the stages feeding it do not block. It faults the
+// destination with ctx.Err() when ctx is done first.
+func FromStream(ctx context.Context, in <-chan interface{}, dest interface{}) {
+	target := targetOf(dest)
+	if target == nil {
+		return
+	}
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				target.fault(ctx.Err())
+				return
+			case msg, ok := <-in:
+				if !ok {
+					target.close()
+					return
+				}
+				if target.send(ctx, msg) {
+					continue
+				}
+				if ctx.Err() != nil {
+					target.fault(ctx.Err())
+					return
+				}
+				if target.stopped() {
+					for range in {
+					}
+					return
+				}
+			}
+		}
+	}()
+}
+
+// AsStage runs the block as a channel stage: the messages of in go through the
+// block and come out of the returned channel.
+func AsStage(ctx context.Context, in <-chan interface{}, block interface{}, size int) <-chan interface{} {
+	out := ToStream(block, size)
+	FromStream(ctx, in, block)
+	return out
+}
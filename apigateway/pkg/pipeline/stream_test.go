@@ -0,0 +1,102 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/dataflow"
+)
+
+func TestAsStage_WithDataflow(t *testing.T) {
+	ctx := context.Background()
+	upper := NewTransformBlock(func(input interface{}) (interface{}, error) {
+		return strings.ToUpper(input.(string)), nil
+	})
+
+	words := dataflow.From(ctx, "a", "b", "c")
+	out := dataflow.Map(ctx, AsStage(ctx, words, upper, 0), func(msg interface{}) (interface{}, error) {
+		return msg.(string) + "!", nil
+	})
+
+	items, err := dataflow.Collect(ctx, out)
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	if len(items) != 3 || items[0] != "A!" || items[2] != "C!" {
+		t.Errorf("Expected A! B! C!, got %v", items)
+	}
+	if err := upper.Wait(); err != nil {
+		t.Errorf("Expected the block to complete, got %v", err)
+	}
+}
+
+func TestToStream_SourceFault(t *testing.T) {
+	errFetch := errors.New("fetch failed")
+	fetch := NewTransformBlock(func(input interface{}) (interface{}, error) {
+		if input == "bad" {
+			return nil, errFetch
+		}
+		return input, nil
+	}, WithBufferSize(3))
+	out := ToStream(fetch, 10)
+
+	fetch.Post("a")
+	fetch.Post("bad")
+	fetch.Complete()
+
+	done := make(chan struct{})
+	go func() {
+		for range out {
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("The stream was not closed after the fault")
+	}
+	if err := fetch.Wait(); !errors.Is(err, errFetch) {
+		t.Errorf("Expected the fetch error, got %v", err)
+	}
+}
+
+func TestFromStream_DestinationFault(t *testing.T) {
+	ctx := context.Background()
+	errSink := errors.New("sink down")
+	sink := NewActionBlock(func(input interface{}) error { return errSink })
+
+	// The stage feeding the faulted block still runs to completion
+	finished := make(chan struct{})
+	in := make(chan interface{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			in <- i
+		}
+		close(in)
+		close(finished)
+	}()
+	FromStream(ctx, in, sink)
+
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("The feeding stage blocked on the faulted block")
+	}
+	if err := sink.Wait(); !errors.Is(err, errSink) {
+		t.Errorf("Expected the sink error, got %v", err)
+	}
+}
+
+func TestFromStream_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	action := NewActionBlock(func(input interface{}) error { return nil })
+	FromStream(ctx, make(chan interface{}), action)
+
+	cancel()
+	if err := waitAllWithin(t, time.Second, action); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
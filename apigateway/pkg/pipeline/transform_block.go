@@ -2,7 +2,6 @@ package pipeline
 
 import (
 	"fmt"
-	"sync"
 	"time"
 )
 
@@ -15,12 +14,10 @@ type TransformFunc func(interface{}) (interface{}, error)
 // It supports configurable retry policies and concurrency for parallel processing
 type TransformBlock struct {
 	*BaseBlock
-	input      chan interface{}
-	transform  TransformFunc
-	targets    []*Target
-	targetsMux sync.RWMutex
-	stopOnce   sync.Once
-	options    BlockOptions
+	input     *blockInput
+	transform TransformFunc
+	links     links
+	options   BlockOptions
 }
 
 // NewTransformBlock creates a new TransformBlock with the specified transform function and options
@@ -28,11 +25,11 @@ type TransformBlock struct {
 func NewTransformBlock(transform TransformFunc, opts ...Option) *TransformBlock {
 	options := applyOptions(opts)
 	
+	base := NewBaseBlockContext(options.Context)
 	b := &TransformBlock{
-		BaseBlock: NewBaseBlock(),
-		input:     make(chan interface{}, options.BufferSize),
+		BaseBlock: base,
+		input:     newBlockInput(base, options),
 		transform: transform,
-		targets:   make([]*Target, 0),
 		options:   options,
 	}
 
@@ -41,35 +38,48 @@ func NewTransformBlock(transform TransformFunc, opts ...Option) *TransformBlock
 	for i := 0; i < options.ConcurrencyDegree; i++ {
 		go b.process()
 	}
+	// Complete or fault the targets once every worker finished
+	go func() {
+		b.wg.Wait()
+		b.finish(&b.links)
+	}()
 
 	return b
 }
 
-// Post sends a message to the transform block
+// Post sends a message to the transform block, following the overflow policy
+// once the block is full: by default it waits for room.
 func (b *TransformBlock) Post(message interface{}) bool {
 	if b.IsCompleted() {
 		return false
 	}
+	return b.input.offer(b.ctx, message, b.input.policy)
+}
 
-	select {
-	case b.input <- message:
-		return true
-	default:
+// TryPost sends a message without waiting, it returns false when the block
+// is full whatever its overflow policy.
+func (b *TransformBlock) TryPost(message interface{}) bool {
+	if b.IsCompleted() {
 		return false
 	}
+	return b.input.offer(b.ctx, message, OverflowDrop)
 }
 
-// LinkTo links this block to a target block with an optional filter function
-func (b *TransformBlock) LinkTo(target *Target, filter func(interface{}) bool) {
-	b.targetsMux.Lock()
-	defer b.targetsMux.Unlock()
+// Dropped returns the number of messages discarded by OverflowDropOldest.
+func (b *TransformBlock) Dropped() int64 {
+	return b.input.droppedCount()
+}
 
-	b.targets = append(b.targets, target)
+// LinkTo links this block to a target block. Each message goes to the first
+// link whose filter accepts it, a nil filter accepts every message
+func (b *TransformBlock) LinkTo(target *Target, filter func(interface{}) bool) {
+	b.links.add(target, filter)
+}
 
-	// If there's a filter, set it on the target
-	if filter != nil {
-		target.SetFilter(filter)
-	}
+// LinkFallback links this block to the target receiving the messages no
+// link accepts, instead of dropping them
+func (b *TransformBlock) LinkFallback(target *Target) {
+	b.links.setFallback(target)
 }
 
 // process handles the message processing loop for a single worker
@@ -79,13 +89,6 @@ func (b *TransformBlock) process() {
 		if r := recover(); r != nil {
 			b.Fault(fmt.Errorf("panic in TransformBlock: %v", r))
 		}
-		// Close target channels when all processing is done
-		b.targetsMux.RLock()
-		for _, t := range b.targets {
-			close(t.ch)
-		}
-		b.targetsMux.RUnlock()
-		b.SignalCompletion()
 	}()
 
 	for {
@@ -93,7 +96,7 @@ func (b *TransformBlock) process() {
 		case <-b.ctx.Done():
 			return
 
-		case msg, ok := <-b.input:
+		case msg, ok := <-b.input.ch:
 			if !ok {
 				return
 			}
@@ -109,21 +112,9 @@ func (b *TransformBlock) process() {
 				continue
 			}
 
-			// Get a copy of targets to avoid holding the lock while sending
-			b.targetsMux.RLock()
-			targets := make([]*Target, len(b.targets))
-			copy(targets, b.targets)
-			b.targetsMux.RUnlock()
-
-			// Forward the result to all targets
-			for _, target := range targets {
-				if target.filter == nil || target.filter(result) {
-					select {
-					case target.ch <- result:
-					case <-b.ctx.Done():
-						return
-					}
-				}
+			// Forward the result to the first link accepting it
+			if !b.links.forward(b.ctx, result) {
+				return
 			}
 		}
 	}
@@ -169,8 +160,6 @@ func (b *TransformBlock) executeTransform(msg interface{}) (interface{}, error)
 // Complete marks the block as completed and closes the input channel
 // This signals all workers to finish processing
 func (b *TransformBlock) Complete() {
-	b.stopOnce.Do(func() {
-		close(b.input)
-	})
+	b.input.close()
 }
 
@@ -5,6 +5,8 @@ import (
 )
 
 // Link connects two blocks together with an optional filter function
+// Blocks other than BroadcastBlock send each message to the first link whose
+// filter accepts it, in link order
 func Link(source interface{}, target *Target, filter func(interface{}) bool) {
 	switch s := source.(type) {
 	case *BufferBlock:
@@ -13,22 +15,60 @@ func Link(source interface{}, target *Target, filter func(interface{}) bool) {
 		s.LinkTo(target, filter)
 	case *ActionBlock:
 		s.LinkTo(target, filter)
+	case *BroadcastBlock:
+		s.LinkTo(target, filter)
+	case *JoinBlock:
+		s.LinkTo(target, filter)
 	}
 }
 
 // LinkTo creates a target for the destination block and links it from the source block
+// A JoinBlock has two inputs, link to them with Link and its First or Second target
 func LinkTo(source interface{}, dest interface{}, filter func(interface{}) bool) {
+	if target := targetOf(dest); target != nil {
+		Link(source, target, filter)
+	}
+}
+
+// LinkFallback connects the target receiving the messages of the source that no
+// link accepts, such as error records the other links filter out
+func LinkFallback(source interface{}, target *Target) {
+	switch s := source.(type) {
+	case *BufferBlock:
+		s.LinkFallback(target)
+	case *TransformBlock:
+		s.LinkFallback(target)
+	case *ActionBlock:
+		s.LinkFallback(target)
+	case *BroadcastBlock:
+		s.LinkFallback(target)
+	case *JoinBlock:
+		s.LinkFallback(target)
+	}
+}
+
+// LinkToFallback creates a target for the destination block and makes it the
+// fallback link of the source block
+func LinkToFallback(source interface{}, dest interface{}) {
+	if target := targetOf(dest); target != nil {
+		LinkFallback(source, target)
+	}
+}
+
+// targetOf creates a target for the input of a block, nil for blocks without
+// a single input
+func targetOf(dest interface{}) *Target {
 	switch d := dest.(type) {
 	case *BufferBlock:
-		target := NewTarget(d.input)
-		Link(source, target, filter)
+		return d.input.target()
 	case *TransformBlock:
-		target := NewTarget(d.input)
-		Link(source, target, filter)
+		return d.input.target()
 	case *ActionBlock:
-		target := NewTarget(d.input)
-		Link(source, target, filter)
+		return d.input.target()
+	case *BroadcastBlock:
+		return d.input.target()
 	}
+	return nil
 }
 
 // CompleteAll completes all the provided blocks
@@ -43,6 +83,10 @@ func CompleteAll(blocks ...interface{}) {
 			block.Complete()
 		case *ActionBlock:
 			block.Complete()
+		case *BroadcastBlock:
+			block.Complete()
+		case *JoinBlock:
+			block.Complete()
 		}
 	}
 }
@@ -73,6 +117,14 @@ func WaitAll(blocks ...interface{}) error {
 				if err := b.Wait(); err != nil {
 					errCh <- err
 				}
+			case *BroadcastBlock:
+				if err := b.Wait(); err != nil {
+					errCh <- err
+				}
+			case *JoinBlock:
+				if err := b.Wait(); err != nil {
+					errCh <- err
+				}
 			}
 		}(b)
 	}
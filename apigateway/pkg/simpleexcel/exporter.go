@@ -43,6 +43,7 @@ type ReportTemplate struct {
 // SheetTemplate represents a sheet in the YAML.
 type SheetTemplate struct {
 	Name     string          `yaml:"name"`
+	Layout   *LayoutTemplate `yaml:"layout"` // Freeze panes and column sizing
 	Sections []SectionConfig `yaml:"sections"`
 }
 
@@ -119,6 +120,13 @@ func NewDataExporterFromYamlConfig(yamlConfig string) (*DataExporter, error) {
 	if err := yaml.Unmarshal([]byte(yamlConfig), &tmpl); err != nil {
 		return nil, fmt.Errorf("decode yaml: %w", err)
 	}
+	for _, sheet := range tmpl.Sheets {
+		if sheet.Layout != nil {
+			if err := sheet.Layout.validate(sheet.Name); err != nil {
+				return nil, err
+			}
+		}
+	}
 
 	exporter := &DataExporter{
 		template:   &tmpl,
@@ -133,6 +141,7 @@ func NewDataExporterFromYamlConfig(yamlConfig string) (*DataExporter, error) {
 		sb := &SheetBuilder{
 			exporter: exporter,
 			name:     sheetTmpl.Name,
+			layout:   sheetTmpl.Layout,
 			sections: make([]*SectionConfig, len(sheetTmpl.Sections)),
 		}
 		for j := range sheetTmpl.Sections {
@@ -221,6 +230,9 @@ func (e *DataExporter) BuildExcel() (*excelize.File, error) {
 		if err := e.renderSections(f, sheetName, sb.sections); err != nil {
 			return nil, err
 		}
+		if err := applyLayout(f, sheetName, sb.layout); err != nil {
+			return nil, err
+		}
 	}
 
 	return f, nil
@@ -259,6 +271,7 @@ func (e *DataExporter) ToBytes() ([]byte, error) {
 type SheetBuilder struct {
 	exporter *DataExporter
 	name     string
+	layout   *LayoutTemplate
 	sections []*SectionConfig
 }
 
@@ -0,0 +1,64 @@
+package simpleexcel
+
+import (
+	"io"
+
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/exporter"
+)
+
+// AsExporter adapts the exporter to the engine-neutral exporter.Exporter.
+// The workbook is built in memory, Stream returns
+// exporter.ErrStreamUnsupported.
+func (e *DataExporter) AsExporter() exporter.Exporter {
+	return &exporterAdapter{e: e}
+}
+
+type exporterAdapter struct {
+	e *DataExporter
+}
+
+func (a *exporterAdapter) AddSheet(name string) exporter.Sheet {
+	return &sheetAdapter{sb: a.e.AddSheet(name)}
+}
+
+func (a *exporterAdapter) Bind(sectionID string, data interface{}) exporter.Exporter {
+	a.e.BindSectionData(sectionID, data)
+	return a
+}
+
+func (a *exporterAdapter) Export(w io.Writer) error {
+	f, err := a.e.BuildExcel()
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return f.Write(w)
+}
+
+func (a *exporterAdapter) Stream(w io.Writer) (exporter.Streamer, error) {
+	return nil, exporter.ErrStreamUnsupported
+}
+
+type sheetAdapter struct {
+	sb *SheetBuilder
+}
+
+func (s *sheetAdapter) AddSection(sec *exporter.Section) exporter.Sheet {
+	config := &SectionConfig{
+		ID:         sec.ID,
+		Title:      sec.Title,
+		ShowHeader: sec.ShowHeader,
+		Locked:     sec.Locked,
+		Data:       sec.Data,
+	}
+	for _, col := range sec.Columns {
+		config.Columns = append(config.Columns, ColumnConfig{
+			FieldName:     col.FieldName,
+			Header:        col.Header,
+			Width:         col.Width,
+			FormatterName: col.FormatterName,
+		})
+	}
+	s.sb.AddSection(config)
+	return s
+}
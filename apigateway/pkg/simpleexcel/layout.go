@@ -0,0 +1,152 @@
+package simpleexcel
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// excelDefaultColWidth is the width excelize reports for columns that were
+// never sized.
+const excelDefaultColWidth = 9.140625
+
+// LayoutTemplate holds sheet-level layout options.
+//
+//	sheets:
+//	  - name: "Employees"
+//	    layout:
+//	      freeze_rows: 2
+//	      freeze_cols: 1
+//	      auto_fit_columns: true
+//	      max_column_width: 40
+type LayoutTemplate struct {
+	FreezeRows int `yaml:"freeze_rows"` // Rows kept visible at the top when scrolling
+	FreezeCols int `yaml:"freeze_cols"` // Columns kept visible at the left
+	// AutoFitColumns sizes columns without an explicit width to their content
+	AutoFitColumns bool    `yaml:"auto_fit_columns"`
+	MaxColumnWidth float64 `yaml:"max_column_width"` // Caps auto-fit widths, 0 means Excel's limit
+}
+
+// SetLayout sets the layout options of the sheet.
+func (sb *SheetBuilder) SetLayout(layout *LayoutTemplate) *SheetBuilder {
+	sb.layout = layout
+	return sb
+}
+
+func (l *LayoutTemplate) validate(sheet string) error {
+	if l.FreezeRows < 0 || l.FreezeCols < 0 {
+		return fmt.Errorf("sheet %s: freeze_rows and freeze_cols must not be negative", sheet)
+	}
+	if l.MaxColumnWidth < 0 || l.MaxColumnWidth > excelize.MaxColumnWidth {
+		return fmt.Errorf("sheet %s: max_column_width must be between 0 and %d", sheet, excelize.MaxColumnWidth)
+	}
+	return nil
+}
+
+// panes returns the freeze panes for the layout, or nil if nothing is frozen.
+func (l *LayoutTemplate) panes() *excelize.Panes {
+	if l == nil || (l.FreezeRows == 0 && l.FreezeCols == 0) {
+		return nil
+	}
+	active := "bottomRight"
+	if l.FreezeCols == 0 {
+		active = "bottomLeft"
+	} else if l.FreezeRows == 0 {
+		active = "topRight"
+	}
+	topLeft, _ := excelize.CoordinatesToCellName(l.FreezeCols+1, l.FreezeRows+1)
+	return &excelize.Panes{
+		Freeze:      true,
+		XSplit:      l.FreezeCols,
+		YSplit:      l.FreezeRows,
+		TopLeftCell: topLeft,
+		ActivePane:  active,
+		Selection:   []excelize.Selection{{SQRef: topLeft, ActiveCell: topLeft, Pane: active}},
+	}
+}
+
+// fitWidth returns the column width for content of the given length.
+func (l *LayoutTemplate) fitWidth(chars int) float64 {
+	width := float64(chars) + 2
+	limit := l.MaxColumnWidth
+	if limit == 0 {
+		limit = excelize.MaxColumnWidth
+	}
+	if width > limit {
+		width = limit
+	}
+	return width
+}
+
+// applyLayout applies the layout to a sheet rendered by BuildExcel.
+func applyLayout(f *excelize.File, sheet string, l *LayoutTemplate) error {
+	if l == nil {
+		return nil
+	}
+	if panes := l.panes(); panes != nil {
+		if err := f.SetPanes(sheet, panes); err != nil {
+			return fmt.Errorf("sheet %s: freeze panes: %w", sheet, err)
+		}
+	}
+	if l.AutoFitColumns {
+		return autoFitColumns(f, sheet, l)
+	}
+	return nil
+}
+
+// autoFitColumns sizes every column that has no explicit width to its
+// longest displayed value. Merged cells, such as section titles, are ignored.
+func autoFitColumns(f *excelize.File, sheet string, l *LayoutTemplate) error {
+	merged := make(map[string]bool)
+	merges, err := f.GetMergeCells(sheet)
+	if err != nil {
+		return err
+	}
+	for _, m := range merges {
+		c1, r1, _ := excelize.CellNameToCoordinates(m.GetStartAxis())
+		c2, r2, _ := excelize.CellNameToCoordinates(m.GetEndAxis())
+		for r := r1; r <= r2; r++ {
+			for c := c1; c <= c2; c++ {
+				cell, _ := excelize.CoordinatesToCellName(c, r)
+				merged[cell] = true
+			}
+		}
+	}
+
+	rows, err := f.GetRows(sheet)
+	if err != nil {
+		return err
+	}
+	var longest []int
+	for r, row := range rows {
+		for c, val := range row {
+			if val == "" {
+				continue
+			}
+			if cell, _ := excelize.CoordinatesToCellName(c+1, r+1); merged[cell] {
+				continue
+			}
+			for len(longest) <= c {
+				longest = append(longest, 0)
+			}
+			if n := utf8.RuneCountInString(val); n > longest[c] {
+				longest[c] = n
+			}
+		}
+	}
+
+	for c, n := range longest {
+		if n == 0 {
+			continue
+		}
+		name, _ := excelize.ColumnNumberToName(c + 1)
+		if width, err := f.GetColWidth(sheet, name); err != nil || width != excelDefaultColWidth {
+			continue
+		}
+		if err := f.SetColWidth(sheet, name, name, l.fitWidth(n)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package simpleexcel
+
+import (
+	"testing"
+)
+
+func TestDataExporter_LayoutFreezeAndAutoFit(t *testing.T) {
+	yamlConfig := `
+sheets:
+  - name: "Layout"
+    layout:
+      freeze_rows: 2
+      freeze_cols: 1
+      auto_fit_columns: true
+      max_column_width: 12
+    sections:
+      - id: "people"
+        title: "People"
+        show_header: true
+        columns:
+          - field_name: "Name"
+            header: "Name"
+          - field_name: "Note"
+            header: "Note"
+          - field_name: "Code"
+            header: "Code"
+            width: 30
+`
+	exporter, err := NewDataExporterFromYamlConfig(yamlConfig)
+	if err != nil {
+		t.Fatalf("Failed to create exporter from yaml: %v", err)
+	}
+	type row struct{ Name, Note, Code string }
+	exporter.BindSectionData("people", []row{{"Ann", "a rather long note about Ann", "X"}})
+
+	f, err := exporter.BuildExcel()
+	if err != nil {
+		t.Fatalf("Failed to build excel: %v", err)
+	}
+	defer f.Close()
+
+	panes, err := f.GetPanes("Layout")
+	if err != nil {
+		t.Fatalf("GetPanes: %v", err)
+	}
+	if !panes.Freeze || panes.XSplit != 1 || panes.YSplit != 2 || panes.TopLeftCell != "B3" {
+		t.Errorf("unexpected panes: %+v", panes)
+	}
+
+	for col, want := range map[string]float64{"A": 6, "B": 12, "C": 30} {
+		got, err := f.GetColWidth("Layout", col)
+		if err != nil {
+			t.Fatalf("GetColWidth %s: %v", col, err)
+		}
+		if got != want {
+			t.Errorf("column %s width = %v, want %v", col, got, want)
+		}
+	}
+}
+
+func TestDataExporter_LayoutRejectsNegativeFreeze(t *testing.T) {
+	_, err := NewDataExporterFromYamlConfig(`
+sheets:
+  - name: "Layout"
+    layout:
+      freeze_rows: -1
+`)
+	if err == nil {
+		t.Fatal("expected an error for negative freeze_rows")
+	}
+}
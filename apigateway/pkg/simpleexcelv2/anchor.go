@@ -0,0 +1,121 @@
+package simpleexcelv2
+
+import (
+	"fmt"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// sectionBox is the area a section takes on its sheet, endCol and endRow
+// are exclusive.
+type sectionBox struct {
+	col, row, endCol, endRow int
+}
+
+// anchorID returns the section ID sec is anchored to and whether it is
+// placed below it rather than to its right. below wins over right_of.
+func anchorID(sec *SectionConfig) (string, bool) {
+	if sec.Below != "" {
+		return sec.Below, true
+	}
+	return sec.RightOf, false
+}
+
+// sheetLayout is the anchor graph of the sections of a sheet.
+type sheetLayout struct {
+	// anchors holds the index of the section each section is anchored to,
+	// -1 for sections placed by position or flow
+	anchors []int
+	// order lists the section indexes in declared order, except that a
+	// section anchored to a later one is placed right after it
+	order []int
+	// problems describes the anchors that were dropped, by section index
+	problems map[int]string
+}
+
+// resolveLayout builds the anchor graph of sections. Anchors naming no
+// section of the sheet or forming a cycle are dropped, those sections are
+// placed as if they had none.
+func resolveLayout(sections []*SectionConfig) *sheetLayout {
+	l := &sheetLayout{anchors: make([]int, len(sections)), problems: make(map[int]string)}
+	ids := make(map[string]int, len(sections))
+	for i, sec := range sections {
+		if _, dup := ids[sec.ID]; sec.ID != "" && !dup {
+			ids[sec.ID] = i
+		}
+	}
+	for i, sec := range sections {
+		l.anchors[i] = -1
+		id, below := anchorID(sec)
+		if id == "" {
+			continue
+		}
+		key := "right_of"
+		if below {
+			key = "below"
+		}
+		j, ok := ids[id]
+		if !ok {
+			l.problems[i] = fmt.Sprintf("%s %q names no section of the sheet", key, id)
+			continue
+		}
+		l.anchors[i] = j
+	}
+
+	// Each section has at most one anchor, a section is in a cycle when
+	// following the anchors leads back to it
+	var cyclic []int
+	for i := range sections {
+		j := l.anchors[i]
+		for steps := 0; j >= 0 && j != i && steps < len(sections); steps++ {
+			j = l.anchors[j]
+		}
+		if j == i {
+			cyclic = append(cyclic, i)
+		}
+	}
+	for _, i := range cyclic {
+		id, _ := anchorID(sections[i])
+		l.problems[i] = fmt.Sprintf("anchor %q is part of a cycle", id)
+		l.anchors[i] = -1
+	}
+
+	placed := make([]bool, len(sections))
+	var place func(i int)
+	place = func(i int) {
+		if placed[i] {
+			return
+		}
+		placed[i] = true
+		if j := l.anchors[i]; j >= 0 {
+			place(j)
+		}
+		l.order = append(l.order, i)
+	}
+	for i := range sections {
+		place(i)
+	}
+	return l
+}
+
+// position returns the start coordinates of section i: its position when
+// valid, next to its anchor when it has one, else where the flow is.
+func (l *sheetLayout) position(sections []*SectionConfig, i int, boxes []sectionBox, nextColHorizontal, maxRow int) (int, int) {
+	sec := sections[i]
+	if j := l.anchors[i]; j >= 0 && !validPosition(sec.Position) {
+		if _, below := anchorID(sec); below {
+			return boxes[j].col, boxes[j].endRow + sec.gap
+		}
+		return boxes[j].endCol, boxes[j].row
+	}
+	return calculatePosition(sec, nextColHorizontal, maxRow)
+}
+
+// validPosition reports whether pos is a cell reference.
+func validPosition(pos string) bool {
+	if pos == "" {
+		return false
+	}
+	_, _, err := excelize.CellNameToCoordinates(pos)
+	return err == nil
+}
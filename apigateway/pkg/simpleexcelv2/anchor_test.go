@@ -0,0 +1,114 @@
+package simpleexcelv2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+type anchorRow struct {
+	Name string
+	Dept string
+}
+
+const anchorYAML = `
+sheets:
+  - name: "Staff"
+    sections:
+      - id: "leavers"
+        title: "Leavers"
+        below: "by_dept"
+        columns:
+          - field_name: "Name"
+      - id: "summary"
+        title: "Summary"
+        show_header: true
+        columns:
+          - field_name: "Name"
+            header: "Name"
+          - field_name: "Dept"
+            header: "Dept"
+      - id: "by_dept"
+        title: "By Dept"
+        right_of: "summary"
+        columns:
+          - field_name: "Dept"
+      - id: "footer"
+        type: "title"
+        title: "End"
+`
+
+func cellValue(t *testing.T, f *excelize.File, cell string) string {
+	v, err := f.GetCellValue("Staff", cell)
+	require.NoError(t, err)
+	return v
+}
+
+func TestAnchor_BelowAndRightOf(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(anchorYAML)
+	require.NoError(t, err)
+	exporter.BindSectionData("summary", []anchorRow{{"Ann", "d001"}, {"Bob", "d002"}, {"Cid", "d003"}}).
+		BindSectionData("by_dept", []anchorRow{{Dept: "d001"}}).
+		BindSectionData("leavers", []anchorRow{{Name: "Dee"}})
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+	assert.Empty(t, exporter.Warnings())
+
+	// summary takes A1:B5, by_dept starts beside it on its title row
+	assert.Equal(t, "Summary", cellValue(t, f, "A1"))
+	assert.Equal(t, "Ann", cellValue(t, f, "A3"))
+	assert.Equal(t, "By Dept", cellValue(t, f, "C1"))
+	assert.Equal(t, "d001", cellValue(t, f, "C2"))
+	// leavers is declared first but placed under by_dept, which ends on row 2
+	assert.Equal(t, "Leavers", cellValue(t, f, "C3"))
+	assert.Equal(t, "Dee", cellValue(t, f, "C4"))
+	// Unanchored sections still flow below everything placed before them
+	assert.Equal(t, "End", cellValue(t, f, "A6"))
+}
+
+func TestAnchor_PositionWins(t *testing.T) {
+	exporter := NewExcelDataExporter()
+	exporter.AddSheet("Staff").
+		AddSection(&SectionConfig{ID: "a", Columns: []ColumnConfig{{FieldName: "Name"}}}).
+		AddSection(&SectionConfig{ID: "b", Position: "E5", Below: "a", Columns: []ColumnConfig{{FieldName: "Name"}}})
+	exporter.BindSectionData("a", []anchorRow{{Name: "Ann"}}).
+		BindSectionData("b", []anchorRow{{Name: "Bob"}})
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+	assert.Equal(t, "Bob", cellValue(t, f, "E5"))
+}
+
+func TestAnchor_InvalidAnchors(t *testing.T) {
+	exporter := NewExcelDataExporter()
+	exporter.AddSheet("Staff").
+		AddSection(&SectionConfig{ID: "a", Below: "c", Columns: []ColumnConfig{{FieldName: "Name"}}}).
+		AddSection(&SectionConfig{ID: "b", RightOf: "a", Columns: []ColumnConfig{{FieldName: "Name"}}}).
+		AddSection(&SectionConfig{ID: "c", Below: "b", Columns: []ColumnConfig{{FieldName: "Name"}}}).
+		AddSection(&SectionConfig{ID: "d", RightOf: "nope", Columns: []ColumnConfig{{FieldName: "Name"}}})
+	for _, id := range []string{"a", "b", "c", "d"} {
+		exporter.BindSectionData(id, []anchorRow{{Name: id}})
+	}
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+	assert.Equal(t, []Warning{
+		{Kind: WarningInvalidAnchor, Sheet: "Staff", Section: "a", Message: `anchor "c" is part of a cycle`},
+		{Kind: WarningInvalidAnchor, Sheet: "Staff", Section: "b", Message: `anchor "a" is part of a cycle`},
+		{Kind: WarningInvalidAnchor, Sheet: "Staff", Section: "c", Message: `anchor "b" is part of a cycle`},
+		{Kind: WarningInvalidAnchor, Sheet: "Staff", Section: "d", Message: `right_of "nope" names no section of the sheet`},
+	}, exporter.Warnings())
+	assert.False(t, WarningInvalidAnchor.Adjusted())
+
+	// All of them flow down the sheet
+	for row, id := range []string{"a", "b", "c", "d"} {
+		assert.Equal(t, id, cellValue(t, f, "A"+string(rune('1'+row))))
+	}
+
+	exporter.Strict()
+	_, err = exporter.BuildExcel()
+	assert.Error(t, err)
+}
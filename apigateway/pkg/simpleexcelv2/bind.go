@@ -0,0 +1,86 @@
+package simpleexcelv2
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// BindError aggregates every section ID problem found by BindAll.
+type BindError struct {
+	// Unknown lists IDs that were supplied but don't match any section.
+	Unknown []string
+	// Missing lists section IDs that still have no data after binding.
+	Missing []string
+}
+
+func (e *BindError) Error() string {
+	var parts []string
+	if len(e.Unknown) > 0 {
+		parts = append(parts, fmt.Sprintf("unknown section IDs: %s", strings.Join(e.Unknown, ", ")))
+	}
+	if len(e.Missing) > 0 {
+		parts = append(parts, fmt.Sprintf("sections without data: %s", strings.Join(e.Missing, ", ")))
+	}
+	return "bind section data: " + strings.Join(parts, "; ")
+}
+
+// BindAll binds data to several sections at once, keyed by section ID.
+// Known IDs are always bound; unknown IDs and data sections that are left
+// without data, unless Optional, are reported together as a *BindError.
+// Sections meant to be filled through a Streamer should be bound with
+// BindSectionData instead, as they would be reported as missing here.
+func (e *ExcelDataExporter) BindAll(data map[string]interface{}) error {
+	known := make(map[string]bool)
+	for _, sheet := range e.sheets {
+		for _, sec := range sheet.sections {
+			if sec.ID != "" {
+				known[sec.ID] = true
+			}
+		}
+	}
+
+	bindErr := &BindError{}
+	for id, d := range data {
+		if !known[id] {
+			bindErr.Unknown = append(bindErr.Unknown, id)
+			continue
+		}
+		e.data[id] = d
+	}
+
+	for _, sheet := range e.sheets {
+		for _, sec := range sheet.sections {
+			if sec.Data != nil || sec.Optional || !needsData(sec) {
+				continue
+			}
+			if _, ok := e.data[sec.ID]; !ok {
+				bindErr.Missing = append(bindErr.Missing, sec.ID)
+			}
+		}
+	}
+
+	if len(bindErr.Unknown) == 0 && len(bindErr.Missing) == 0 {
+		return nil
+	}
+	sort.Strings(bindErr.Unknown)
+	return bindErr
+}
+
+// MustBind is like BindAll but panics on error. Use it when the template is
+// fixed at compile time and a mismatch is a programming error.
+func (e *ExcelDataExporter) MustBind(data map[string]interface{}) *ExcelDataExporter {
+	if err := e.BindAll(data); err != nil {
+		panic(err)
+	}
+	return e
+}
+
+// WithStrictBinding makes exports fail with a *StrictError when data was
+// bound to an unknown section ID or a column names a field the bound struct
+// lacks, typos that otherwise export empty sections. Other warnings still
+// only get reported, see Strict to fail on all of them.
+func (e *ExcelDataExporter) WithStrictBinding() *ExcelDataExporter {
+	e.strictBinding = true
+	return e
+}
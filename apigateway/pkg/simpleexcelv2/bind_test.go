@@ -0,0 +1,131 @@
+package simpleexcelv2
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const bindTestYAML = `
+sheets:
+  - name: "Report"
+    sections:
+      - id: "banner"
+        type: "title"
+        title: "Quarterly Report"
+      - id: "sales"
+        show_header: true
+      - id: "returns"
+        show_header: true
+      - id: "diff"
+        source_sections: ["sales", "returns"]
+`
+
+type bindRow struct {
+	Name string
+}
+
+func TestBindAll_BindsEverySection(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(bindTestYAML)
+	require.NoError(t, err)
+
+	err = exporter.BindAll(map[string]interface{}{
+		"sales":   []bindRow{{"A"}},
+		"returns": []bindRow{{"B"}},
+	})
+	require.NoError(t, err)
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+	val, _ := f.GetCellValue("Report", "A3")
+	assert.Equal(t, "A", val)
+}
+
+func TestBindAll_AggregatesUnknownAndMissing(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(bindTestYAML)
+	require.NoError(t, err)
+
+	err = exporter.BindAll(map[string]interface{}{
+		"sales":  []bindRow{{"A"}},
+		"salez":  []bindRow{{"typo"}},
+		"refund": []bindRow{{"typo"}},
+	})
+	require.Error(t, err)
+
+	var bindErr *BindError
+	require.True(t, errors.As(err, &bindErr))
+	assert.Equal(t, []string{"refund", "salez"}, bindErr.Unknown)
+	assert.Equal(t, []string{"returns"}, bindErr.Missing)
+	assert.Contains(t, err.Error(), "unknown section IDs: refund, salez")
+	assert.Contains(t, err.Error(), "sections without data: returns")
+
+	// Known IDs are still bound.
+	_, ok := exporter.data["sales"]
+	assert.True(t, ok)
+}
+
+func TestBindAll_OptionalSection(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(bindTestYAML)
+	require.NoError(t, err)
+	exporter.GetSection("returns").Optional = true
+
+	require.NoError(t, exporter.BindAll(map[string]interface{}{"sales": []bindRow{{"A"}}}))
+	_, err = exporter.BuildExcel()
+	require.NoError(t, err)
+	require.Len(t, exporter.Warnings(), 1)
+	assert.Equal(t, WarningUnboundSection, exporter.Warnings()[0].Kind)
+	assert.Equal(t, "returns", exporter.Warnings()[0].Section)
+}
+
+func TestMustBind_PanicsOnMismatch(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(bindTestYAML)
+	require.NoError(t, err)
+
+	assert.Panics(t, func() {
+		exporter.MustBind(map[string]interface{}{"nope": []bindRow{}})
+	})
+	assert.NotPanics(t, func() {
+		exporter.MustBind(map[string]interface{}{
+			"sales":   []bindRow{},
+			"returns": []bindRow{},
+		})
+	})
+}
+
+func TestWithStrictBinding_FailsOnTypos(t *testing.T) {
+	newExporter := func(t *testing.T) *ExcelDataExporter {
+		exporter, err := NewExcelDataExporterFromYamlConfig(`
+sheets:
+  - name: "Report"
+    sections:
+      - id: "sales"
+        show_header: true
+        position: "nowhere"
+        columns:
+          - field_name: "Name"
+          - field_name: "Nmae"
+`)
+		require.NoError(t, err)
+		return exporter.WithStrictBinding()
+	}
+
+	exporter := newExporter(t)
+	exporter.BindSectionData("sales", []bindRow{{"A"}}).BindSectionData("sale", []bindRow{{"B"}})
+	_, err := exporter.BuildExcel()
+	var strictErr *StrictError
+	require.True(t, errors.As(err, &strictErr), "got %v", err)
+	require.Len(t, strictErr.Warnings, 2, "the invalid position does not fail the export")
+	assert.Equal(t, WarningUnknownSection, strictErr.Warnings[0].Kind)
+	assert.Equal(t, "sale", strictErr.Warnings[0].Section)
+	assert.Equal(t, WarningUnknownField, strictErr.Warnings[1].Kind)
+	assert.Equal(t, "Nmae", strictErr.Warnings[1].Field)
+
+	// Maps are not checked, their keys may vary by row
+	exporter = newExporter(t)
+	exporter.BindSectionData("sales", []map[string]interface{}{{"Name": "A"}})
+	_, err = exporter.BuildExcel()
+	require.NoError(t, err)
+	assert.Len(t, exporter.Warnings(), 1)
+}
@@ -0,0 +1,303 @@
+package simpleexcelv2
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// SectionTypeCalendar draws a month calendar per employee from dated
+// records, see CalendarConfig.
+const SectionTypeCalendar = "calendar"
+
+// CalendarMonthLayout is the layout of CalendarConfig.Month.
+const CalendarMonthLayout = "2006-01"
+
+// CalendarConfig configures a calendar section, e.g. attendance or leave.
+// Records are grouped by employee_field and month; each group is drawn as a
+// block with a title row, a weekday header and two rows per week: the day
+// numbers, then the value_field of the record of that day.
+//
+//	sections:
+//	  - id: "attendance"
+//	    type: "calendar"
+//	    calendar:
+//	      employee_field: "EmpNo"
+//	      label_field: "Name"
+//	      date_field: "Date"
+//	      value_field: "Status"
+//	      holidays: ["2024-01-01"]
+//	      value_styles:
+//	        L: { fill: { color: "#BDD7EE" } }
+//
+// Style precedence is value_styles, then holiday_style, then weekend_style,
+// then the section's data_style.
+type CalendarConfig struct {
+	EmployeeField string `yaml:"employee_field"`
+	// LabelField names the employee in the block titles, employee_field by default
+	LabelField string `yaml:"label_field"`
+	DateField  string `yaml:"date_field"`
+	ValueField string `yaml:"value_field"`
+	// Month (YYYY-MM) draws that month for every employee, by default the
+	// months of each employee's records are drawn
+	Month string `yaml:"month"`
+	// WeekStart is monday (default) or sunday
+	WeekStart    string                    `yaml:"week_start"`
+	Holidays     []string                  `yaml:"holidays"` // YYYY-MM-DD
+	WeekendStyle *StyleTemplate            `yaml:"weekend_style"`
+	HolidayStyle *StyleTemplate            `yaml:"holiday_style"`
+	ValueStyles  map[string]*StyleTemplate `yaml:"value_styles"`
+}
+
+const (
+	defaultCalendarWeekendColor = "#F2F2F2"
+	defaultCalendarHolidayColor = "#FCE4D6"
+)
+
+func init() {
+	RegisterSectionRenderer(SectionTypeCalendar, calendarRenderer{})
+}
+
+// validate checks the calendar block of a section at parse time.
+func (c *CalendarConfig) validate(sec *SectionConfig) error {
+	if c == nil {
+		return fmt.Errorf("section %s: calendar sections need a calendar block", sec.ID)
+	}
+	if c.EmployeeField == "" || c.DateField == "" || c.ValueField == "" {
+		return fmt.Errorf("section %s: calendar needs employee_field, date_field and value_field", sec.ID)
+	}
+	if c.Month != "" {
+		if _, err := time.Parse(CalendarMonthLayout, c.Month); err != nil {
+			return fmt.Errorf("section %s: calendar month %q must be YYYY-MM", sec.ID, c.Month)
+		}
+	}
+	switch c.WeekStart {
+	case "", "monday", "sunday":
+	default:
+		return fmt.Errorf("section %s: calendar week_start must be monday or sunday, got %q", sec.ID, c.WeekStart)
+	}
+	for _, d := range c.Holidays {
+		if _, err := time.Parse(VariableDateLayout, d); err != nil {
+			return fmt.Errorf("section %s: calendar holiday %q must be YYYY-MM-DD", sec.ID, d)
+		}
+	}
+	return nil
+}
+
+// weekday returns the column (0-6) of a day.
+func (c *CalendarConfig) weekday(t time.Time) int {
+	if c.WeekStart == "sunday" {
+		return int(t.Weekday())
+	}
+	return (int(t.Weekday()) + 6) % 7
+}
+
+func (c *CalendarConfig) weekdayNames() []interface{} {
+	names := []interface{}{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}
+	if c.WeekStart == "sunday" {
+		return append([]interface{}{"Sun"}, names[:6]...)
+	}
+	return names
+}
+
+// calendarBlock is the month of one employee.
+type calendarBlock struct {
+	label  interface{}
+	month  time.Time
+	values map[int]interface{} // by day of month
+}
+
+// weeks returns the number of calendar rows the month spans.
+func (b *calendarBlock) weeks(c *CalendarConfig) int {
+	days := b.month.AddDate(0, 1, -1).Day()
+	return (c.weekday(b.month) + days + 6) / 7
+}
+
+func (b *calendarBlock) rows(c *CalendarConfig) int {
+	return 2 + 2*b.weeks(c)
+}
+
+type calendarRenderer struct{}
+
+// blocks groups the records by employee, in order of first appearance, and
+// month.
+func (calendarRenderer) blocks(rc *RenderContext) []*calendarBlock {
+	c := rc.Section.Calendar
+	items := reflect.ValueOf(rc.Section.Data)
+	if items.Kind() != reflect.Slice {
+		return nil
+	}
+	var fixed time.Time
+	if c.Month != "" {
+		fixed, _ = time.Parse(CalendarMonthLayout, c.Month)
+	}
+	labelField := c.LabelField
+	if labelField == "" {
+		labelField = c.EmployeeField
+	}
+
+	type employee struct {
+		label  interface{}
+		months map[time.Time]*calendarBlock
+	}
+	var order []string
+	employees := make(map[string]*employee)
+	for i := 0; i < items.Len(); i++ {
+		item := items.Index(i)
+		key := fmt.Sprint(rc.Value(item, c.EmployeeField))
+		emp, ok := employees[key]
+		if !ok {
+			emp = &employee{label: rc.Value(item, labelField), months: make(map[time.Time]*calendarBlock)}
+			employees[key] = emp
+			order = append(order, key)
+		}
+		date, ok := ganttDate(rc.Value(item, c.DateField))
+		if !ok {
+			continue
+		}
+		month := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+		if !fixed.IsZero() && !month.Equal(fixed) {
+			continue
+		}
+		block, ok := emp.months[month]
+		if !ok {
+			block = &calendarBlock{label: emp.label, month: month, values: make(map[int]interface{})}
+			emp.months[month] = block
+		}
+		block.values[date.Day()] = rc.Value(item, c.ValueField)
+	}
+
+	var blocks []*calendarBlock
+	for _, key := range order {
+		emp := employees[key]
+		if !fixed.IsZero() {
+			block, ok := emp.months[fixed]
+			if !ok {
+				block = &calendarBlock{label: emp.label, month: fixed, values: map[int]interface{}{}}
+			}
+			blocks = append(blocks, block)
+			continue
+		}
+		start := len(blocks)
+		for _, block := range emp.months {
+			blocks = append(blocks, block)
+		}
+		months := blocks[start:]
+		sort.Slice(months, func(i, j int) bool { return months[i].month.Before(months[j].month) })
+	}
+	return blocks
+}
+
+func (r calendarRenderer) Size(rc *RenderContext) (int, int) {
+	if rc.Section.Calendar == nil {
+		return 7, 0
+	}
+	rows := 0
+	for i, block := range r.blocks(rc) {
+		if i > 0 {
+			rows++ // blank row between months
+		}
+		rows += block.rows(rc.Section.Calendar)
+	}
+	return 7, rows
+}
+
+func (r calendarRenderer) Render(rc *RenderContext) error {
+	sec := rc.Section
+	c := sec.Calendar
+	if c == nil {
+		return fmt.Errorf("calendar section has no calendar block")
+	}
+
+	defaultTitle := &StyleTemplate{
+		Font:      &FontTemplate{Bold: true},
+		Alignment: &AlignmentTemplate{Horizontal: "left", Vertical: "top"},
+	}
+	titleID, err := rc.Style(resolveStyle(sec.TitleStyle, defaultTitle, sec.Locked))
+	if err != nil {
+		return err
+	}
+	defaultHeader := &StyleTemplate{
+		Font:      &FontTemplate{Bold: true},
+		Alignment: &AlignmentTemplate{Horizontal: "center", Vertical: "top"},
+	}
+	headerID, err := rc.Style(resolveStyle(sec.HeaderStyle, defaultHeader, sec.Locked))
+	if err != nil {
+		return err
+	}
+	center := &StyleTemplate{Alignment: &AlignmentTemplate{Horizontal: "center"}}
+	dataID, err := rc.Style(resolveStyle(sec.DataStyle, center, sec.Locked))
+	if err != nil {
+		return err
+	}
+	weekendID, err := rc.Style(resolveStyle(c.WeekendStyle, &StyleTemplate{Fill: &FillTemplate{Color: defaultCalendarWeekendColor}, Alignment: center.Alignment}, sec.Locked))
+	if err != nil {
+		return err
+	}
+	holidayID, err := rc.Style(resolveStyle(c.HolidayStyle, &StyleTemplate{Fill: &FillTemplate{Color: defaultCalendarHolidayColor}, Alignment: center.Alignment}, sec.Locked))
+	if err != nil {
+		return err
+	}
+	valueIDs := make(map[string]int, len(c.ValueStyles))
+	for v, tmpl := range c.ValueStyles {
+		id, err := rc.Style(resolveStyle(tmpl, center, sec.Locked))
+		if err != nil {
+			return err
+		}
+		valueIDs[v] = id
+	}
+	holidays := make(map[string]bool, len(c.Holidays))
+	for _, d := range c.Holidays {
+		holidays[d] = true
+	}
+
+	blocks := r.blocks(rc)
+	row := 0
+	for i, block := range blocks {
+		if i > 0 {
+			row++
+		}
+		title := fmt.Sprintf("%v - %s", block.label, block.month.Format("January 2006"))
+		rc.File.SetCellValue(rc.Sheet, rc.Cell(0, row), title)
+		rc.File.MergeCell(rc.Sheet, rc.Cell(0, row), rc.Cell(6, row))
+		rc.File.SetCellStyle(rc.Sheet, rc.Cell(0, row), rc.Cell(6, row), titleID)
+		row++
+
+		header := c.weekdayNames()
+		rc.File.SetSheetRow(rc.Sheet, rc.Cell(0, row), &header)
+		rc.File.SetCellStyle(rc.Sheet, rc.Cell(0, row), rc.Cell(6, row), headerID)
+		row++
+
+		offset := c.weekday(block.month)
+		days := block.month.AddDate(0, 1, -1).Day()
+		for day := 1; day <= days; day++ {
+			date := block.month.AddDate(0, 0, day-1)
+			slot := offset + day - 1
+			col, dayRow := slot%7, row+2*(slot/7)
+
+			styleID := dataID
+			if wd := date.Weekday(); wd == time.Saturday || wd == time.Sunday {
+				styleID = weekendID
+			}
+			if holidays[date.Format(VariableDateLayout)] {
+				styleID = holidayID
+			}
+			valueID := styleID
+			value, ok := block.values[day]
+			if ok {
+				if id, ok := valueIDs[fmt.Sprint(value)]; ok {
+					valueID = id
+				}
+				rc.File.SetCellValue(rc.Sheet, rc.Cell(col, dayRow+1), value)
+			}
+			rc.File.SetCellValue(rc.Sheet, rc.Cell(col, dayRow), day)
+			rc.File.SetCellStyle(rc.Sheet, rc.Cell(col, dayRow), rc.Cell(col, dayRow), styleID)
+			rc.File.SetCellStyle(rc.Sheet, rc.Cell(col, dayRow+1), rc.Cell(col, dayRow+1), valueID)
+		}
+		row += 2 * block.weeks(c)
+	}
+
+	rc.Place(SectionPlacement{StartRow: rc.Row, StartCol: rc.Col, DataLen: row})
+	return nil
+}
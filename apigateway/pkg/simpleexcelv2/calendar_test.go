@@ -0,0 +1,130 @@
+package simpleexcelv2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const calendarYAML = `
+sheets:
+  - name: "Attendance"
+    sections:
+      - id: "attendance"
+        type: "calendar"
+        calendar:
+          employee_field: "EmpNo"
+          label_field: "Name"
+          date_field: "Date"
+          value_field: "Status"
+          holidays: ["2024-01-01"]
+          value_styles:
+            L: { fill: { color: "#BDD7EE" } }
+      - id: "after"
+        columns:
+          - field_name: "Note"
+`
+
+type attendanceRow struct {
+	EmpNo  int
+	Name   string
+	Date   string
+	Status string
+}
+
+func TestCalendarSection(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(calendarYAML)
+	require.NoError(t, err)
+	exporter.BindSectionData("attendance", []attendanceRow{
+		{EmpNo: 1, Name: "Ann", Date: "2024-02-01", Status: "P"},
+		{EmpNo: 1, Name: "Ann", Date: "2024-01-02", Status: "L"},
+		{EmpNo: 2, Name: "Bob", Date: "2024-01-31", Status: "P"},
+	}).BindSectionData("after", []struct{ Note string }{{"end"}})
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+	defer f.Close()
+
+	rows, err := f.GetRows("Attendance")
+	require.NoError(t, err)
+	// January 2024 starts on a Monday and spans 5 weeks
+	assert.Equal(t, "Ann - January 2024", rows[0][0], "months are sorted per employee")
+	assert.Equal(t, []string{"Mon", "Tue", "Wed", "Thu", "Fri", "Sat", "Sun"}, rows[1])
+	assert.Equal(t, []string{"1", "2", "3", "4", "5", "6", "7"}, rows[2])
+	assert.Equal(t, []string{"", "L"}, rows[3])
+	assert.Equal(t, "Ann - February 2024", rows[13][0], "a blank row separates the months")
+	assert.Equal(t, []string{"", "", "", "1", "2", "3", "4"}, rows[15], "February 2024 starts on a Thursday")
+	assert.Equal(t, "P", rows[16][3])
+
+	// Ann's February has 5 weeks, Bob's January 5 more
+	assert.Equal(t, "Bob - January 2024", rows[26][0])
+	assert.Equal(t, "P", rows[37][2])
+	assert.Equal(t, "end", rows[38][0], "the next section starts below the calendar")
+
+	fill := func(cell string) string {
+		styleID, err := f.GetCellStyle("Attendance", cell)
+		require.NoError(t, err)
+		style, err := f.GetStyle(styleID)
+		require.NoError(t, err)
+		if len(style.Fill.Color) == 0 {
+			return ""
+		}
+		return style.Fill.Color[0]
+	}
+	assert.Equal(t, "FCE4D6", fill("A3"), "holiday")
+	assert.Equal(t, "BDD7EE", fill("B4"), "value style")
+	assert.Equal(t, "F2F2F2", fill("F3"), "weekend")
+	assert.Equal(t, "", fill("C3"))
+}
+
+func TestCalendarSection_FixedMonth(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(`
+sheets:
+  - name: "Attendance"
+    sections:
+      - id: "attendance"
+        type: "calendar"
+        calendar: { employee_field: "EmpNo", date_field: "Date", value_field: "Status", month: "2024-03", week_start: "sunday" }
+`)
+	require.NoError(t, err)
+	exporter.BindSectionData("attendance", []attendanceRow{
+		{EmpNo: 1, Date: "2024-02-01", Status: "P"},
+		{EmpNo: 2, Date: "2024-03-02", Status: "A"},
+	})
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+	defer f.Close()
+
+	rows, err := f.GetRows("Attendance")
+	require.NoError(t, err)
+	assert.Equal(t, "1 - March 2024", rows[0][0], "employees without records in the month get an empty calendar")
+	assert.Equal(t, "Sun", rows[1][0])
+	assert.Equal(t, "2 - March 2024", rows[15][0])
+	assert.Equal(t, "A", rows[18][6], "March 2 2024 is a Saturday")
+}
+
+func TestCalendarSection_RejectsInvalidConfig(t *testing.T) {
+	for name, block := range map[string]string{
+		"missing block": ``,
+		"missing field": `
+        calendar: { employee_field: "EmpNo", date_field: "Date" }`,
+		"bad month": `
+        calendar: { employee_field: "E", date_field: "D", value_field: "V", month: "March" }`,
+		"bad week start": `
+        calendar: { employee_field: "E", date_field: "D", value_field: "V", week_start: "friday" }`,
+		"bad holiday": `
+        calendar: { employee_field: "E", date_field: "D", value_field: "V", holidays: ["1/1/2024"] }`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewExcelDataExporterFromYamlConfig(`
+sheets:
+  - name: "S"
+    sections:
+      - id: "c"
+        type: "calendar"` + block)
+			assert.Error(t, err)
+		})
+	}
+}
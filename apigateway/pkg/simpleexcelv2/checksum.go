@@ -0,0 +1,230 @@
+package simpleexcelv2
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// checksumSheet is the very hidden sheet holding one row per section:
+// section ID, data range, locked columns and the HMAC of their cells.
+const checksumSheet = "_checksums"
+
+// TamperError is returned by ReadEditableSections when the locked cells of a
+// workbook no longer match the checksums written at export.
+type TamperError struct {
+	// Sections lists the IDs of the sections whose locked cells changed
+	Sections []string
+	// Missing is set when the workbook carries no checksums at all
+	Missing bool
+}
+
+func (e *TamperError) Error() string {
+	if e.Missing {
+		return "workbook has no locked-section checksums"
+	}
+	return fmt.Sprintf("locked cells were modified in section(s) %s", strings.Join(e.Sections, ", "))
+}
+
+// SetChecksumKey makes BuildExcel (and ToBytes, ToWriter, ExportToExcel)
+// write an HMAC-SHA256 of the locked cells of every section to a very hidden
+// sheet, so ReadEditableSections with WithChecksumKey can tell when someone
+// unprotected the sheet and changed read-only data. Streamed workbooks are
+// not signed.
+func (e *ExcelDataExporter) SetChecksumKey(key []byte) *ExcelDataExporter {
+	e.checksumKey = key
+	return e
+}
+
+// ReadOption configures ReadEditableSections.
+type ReadOption func(*readOptions)
+
+type readOptions struct {
+	checksumKey []byte
+}
+
+// WithChecksumKey verifies the locked-section checksums before reading,
+// returning a *TamperError if they are missing or don't match. Use the key
+// the workbook was exported with.
+func WithChecksumKey(key []byte) ReadOption {
+	return func(o *readOptions) {
+		o.checksumKey = key
+	}
+}
+
+// signLockedSections writes the checksum sheet for every recorded section.
+// Sections without locked columns are listed too, so removing a section's
+// row from the sheet is detected.
+func signLockedSections(f *excelize.File, key []byte) error {
+	ranges := sectionRanges(f)
+	if len(ranges) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(ranges))
+	for id := range ranges {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	if _, err := f.NewSheet(checksumSheet); err != nil {
+		return fmt.Errorf("create checksum sheet: %w", err)
+	}
+	for i, id := range ids {
+		rng := ranges[id]
+		cols, err := lockedColumns(f, rng)
+		if err != nil {
+			return err
+		}
+		sum, err := sectionChecksum(f, key, id, rng, cols)
+		if err != nil {
+			return err
+		}
+		row := []interface{}{id, rangeRef(rng), columnList(cols), sum}
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := f.SetSheetRow(checksumSheet, cell, &row); err != nil {
+			return fmt.Errorf("write checksum of section %s: %w", id, err)
+		}
+	}
+	return f.SetSheetVisible(checksumSheet, false, true)
+}
+
+// verifyLockedSections checks every section range of f against the
+// checksum sheet.
+func verifyLockedSections(f *excelize.File, key []byte) error {
+	if idx, _ := f.GetSheetIndex(checksumSheet); idx == -1 {
+		return &TamperError{Missing: true}
+	}
+	rows, err := f.GetRows(checksumSheet, excelize.Options{RawCellValue: true})
+	if err != nil {
+		return fmt.Errorf("read checksums: %w", err)
+	}
+
+	ranges := sectionRanges(f)
+	signed := make(map[string]bool, len(rows))
+	var tampered []string
+	for _, row := range rows {
+		if len(row) == 0 || row[0] == "" {
+			continue
+		}
+		id := row[0]
+		signed[id] = true
+		if !checksumRowValid(f, key, row, ranges) {
+			tampered = append(tampered, id)
+		}
+	}
+	for id := range ranges {
+		if !signed[id] {
+			tampered = append(tampered, id)
+		}
+	}
+	if len(tampered) > 0 {
+		sort.Strings(tampered)
+		return &TamperError{Sections: tampered}
+	}
+	return nil
+}
+
+// checksumRowValid recomputes one section's checksum from the range and
+// columns recorded at export. The range must also still match the section's
+// defined name, which Excel moves when rows are inserted or deleted.
+func checksumRowValid(f *excelize.File, key []byte, row []string, ranges map[string]sectionRange) bool {
+	if len(row) < 4 {
+		return false
+	}
+	id := row[0]
+	rng, ok := parseSectionRange(row[1])
+	if !ok || ranges[id] != rng {
+		return false
+	}
+	cols, ok := parseColumnList(row[2])
+	if !ok {
+		return false
+	}
+	want, err := hex.DecodeString(row[3])
+	if err != nil {
+		return false
+	}
+	sum, err := sectionChecksum(f, key, id, rng, cols)
+	if err != nil {
+		return false
+	}
+	got, _ := hex.DecodeString(sum)
+	return hmac.Equal(got, want)
+}
+
+// sectionChecksum returns the hex HMAC of the section identity and the raw
+// values of its locked columns, row by row.
+func sectionChecksum(f *excelize.File, key []byte, id string, rng sectionRange, cols []int) (string, error) {
+	mac := hmac.New(sha256.New, key)
+	writeField := func(s string) {
+		fmt.Fprintf(mac, "%d:%s", len(s), s)
+	}
+	writeField(id)
+	writeField(rangeRef(rng))
+	writeField(columnList(cols))
+
+	opts := excelize.Options{RawCellValue: true}
+	for r := rng.StartRow; r <= rng.EndRow; r++ {
+		for _, c := range cols {
+			cell, _ := excelize.CoordinatesToCellName(c, r)
+			val, err := f.GetCellValue(rng.Sheet, cell, opts)
+			if err != nil {
+				return "", fmt.Errorf("read %s!%s: %w", rng.Sheet, cell, err)
+			}
+			writeField(val)
+		}
+	}
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// lockedColumns returns the columns of rng whose data cells are locked.
+// Data styles are applied per column, so the first row decides.
+func lockedColumns(f *excelize.File, rng sectionRange) ([]int, error) {
+	var cols []int
+	for c := rng.StartCol; c <= rng.EndCol; c++ {
+		cell, _ := excelize.CoordinatesToCellName(c, rng.StartRow)
+		locked, err := cellLocked(f, rng.Sheet, cell)
+		if err != nil {
+			return nil, err
+		}
+		if locked {
+			cols = append(cols, c)
+		}
+	}
+	return cols, nil
+}
+
+func rangeRef(rng sectionRange) string {
+	start, _ := excelize.CoordinatesToCellName(rng.StartCol, rng.StartRow, true)
+	end, _ := excelize.CoordinatesToCellName(rng.EndCol, rng.EndRow, true)
+	return fmt.Sprintf("'%s'!%s:%s", strings.ReplaceAll(rng.Sheet, "'", "''"), start, end)
+}
+
+// columnList formats column numbers as letters, e.g. "A,E".
+func columnList(cols []int) string {
+	names := make([]string, len(cols))
+	for i, c := range cols {
+		names[i], _ = excelize.ColumnNumberToName(c)
+	}
+	return strings.Join(names, ",")
+}
+
+func parseColumnList(s string) ([]int, bool) {
+	if s == "" {
+		return nil, true
+	}
+	var cols []int
+	for _, name := range strings.Split(s, ",") {
+		c, err := excelize.ColumnNameToNumber(name)
+		if err != nil {
+			return nil, false
+		}
+		cols = append(cols, c)
+	}
+	return cols, true
+}
@@ -0,0 +1,122 @@
+package simpleexcelv2
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+var checksumKey = []byte("export-secret")
+
+// signedWorkbook exports importYAML with checksums and applies edits,
+// given as offsets into the "edit" and "reference" sections.
+func signedWorkbook(t *testing.T, key []byte, edit func(f *excelize.File, cell func(sec string, col, row int) string)) []byte {
+	exporter, err := NewExcelDataExporterFromYamlConfig(importYAML)
+	require.NoError(t, err)
+	rows := []importRow{{ID: 1, Name: "Alice"}, {ID: 2, Name: "Bob"}}
+	exporter.BindSectionData("reference", rows).
+		BindSectionData("edit", rows).
+		BindSectionData("plain", rows)
+	if key != nil {
+		exporter.SetChecksumKey(key)
+	}
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+	defer f.Close()
+	if edit != nil {
+		edit(f, func(sec string, col, row int) string {
+			meta := exporter.sectionMetadata[sec]
+			name, _ := excelize.CoordinatesToCellName(meta.StartCol+col, meta.StartRow+row)
+			return name
+		})
+	}
+	buf, err := f.WriteToBuffer()
+	require.NoError(t, err)
+	return buf.Bytes()
+}
+
+func TestChecksum_SheetIsVeryHidden(t *testing.T) {
+	f, err := excelize.OpenReader(bytes.NewReader(signedWorkbook(t, checksumKey, nil)))
+	require.NoError(t, err)
+	defer f.Close()
+
+	assert.Equal(t, []string{"Staff", checksumSheet}, f.GetSheetList())
+	visible, err := f.GetSheetVisible(checksumSheet)
+	require.NoError(t, err)
+	assert.False(t, visible)
+
+	rows, err := f.GetRows(checksumSheet)
+	require.NoError(t, err)
+	require.Len(t, rows, 3, "one row per section, locked columns or not")
+	assert.Equal(t, "edit", rows[0][0])
+	assert.Equal(t, "A", rows[0][2], "only the ID column of the edit section is locked")
+	assert.Equal(t, "A,B,C", rows[2][2], "every column of a locked section")
+}
+
+func TestChecksum_AllowsEditsToUnlockedCells(t *testing.T) {
+	workbook := signedWorkbook(t, checksumKey, func(f *excelize.File, cell func(string, int, int) string) {
+		require.NoError(t, f.SetCellValue("Staff", cell("edit", 1, 1), "Robert"))
+	})
+
+	sections, err := ReadEditableSections(bytes.NewReader(workbook), WithChecksumKey(checksumKey))
+	require.NoError(t, err)
+	require.Len(t, sections, 1)
+	assert.Equal(t, "Robert", sections[0].Rows[1].Values["name"])
+}
+
+func TestChecksum_DetectsTampering(t *testing.T) {
+	tests := map[string]struct {
+		edit func(f *excelize.File, cell func(string, int, int) string)
+		want []string
+	}{
+		"locked column": {
+			edit: func(f *excelize.File, cell func(string, int, int) string) {
+				require.NoError(t, f.SetCellValue("Staff", cell("edit", 0, 1), 99))
+			},
+			want: []string{"edit"},
+		},
+		"locked section": {
+			edit: func(f *excelize.File, cell func(string, int, int) string) {
+				require.NoError(t, f.SetCellValue("Staff", cell("reference", 1, 0), "Mallory"))
+			},
+			want: []string{"reference"},
+		},
+		"checksum row removed": {
+			edit: func(f *excelize.File, cell func(string, int, int) string) {
+				require.NoError(t, f.RemoveRow(checksumSheet, 1))
+			},
+			want: []string{"edit"},
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			workbook := signedWorkbook(t, checksumKey, tt.edit)
+
+			_, err := ReadEditableSections(bytes.NewReader(workbook), WithChecksumKey(checksumKey))
+
+			var tamper *TamperError
+			require.ErrorAs(t, err, &tamper)
+			assert.Equal(t, tt.want, tamper.Sections)
+			assert.False(t, tamper.Missing)
+		})
+	}
+}
+
+func TestChecksum_WrongKeyOrUnsigned(t *testing.T) {
+	_, err := ReadEditableSections(bytes.NewReader(signedWorkbook(t, checksumKey, nil)), WithChecksumKey([]byte("other")))
+	var tamper *TamperError
+	require.ErrorAs(t, err, &tamper)
+	assert.Len(t, tamper.Sections, 3)
+
+	unsigned := signedWorkbook(t, nil, nil)
+	_, err = ReadEditableSections(bytes.NewReader(unsigned), WithChecksumKey(checksumKey))
+	require.ErrorAs(t, err, &tamper)
+	assert.True(t, tamper.Missing)
+
+	_, err = ReadEditableSections(bytes.NewReader(unsigned))
+	assert.NoError(t, err, "checksums are only verified when a key is given")
+}
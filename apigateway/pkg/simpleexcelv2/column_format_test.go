@@ -0,0 +1,65 @@
+package simpleexcelv2
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+const columnFormatYAML = `
+sheets:
+  - name: "Pay"
+    sections:
+      - id: "pay"
+        show_header: true
+        data_style:
+          number_format: "0"
+        columns:
+          - field_name: "Amount"
+            format: "#,##0.00"
+          - field_name: "PaidOn"
+            date_format: "dd/mm/yyyy"
+          - field_name: "Count"
+`
+
+type payRow struct {
+	Amount float64
+	PaidOn time.Time
+	Count  float64
+}
+
+func TestColumnFormats(t *testing.T) {
+	rows := []payRow{{Amount: 1234.5, PaidOn: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC), Count: 2.6}}
+
+	for _, streamed := range []bool{false, true} {
+		exporter, err := NewExcelDataExporterFromYamlConfig(columnFormatYAML)
+		require.NoError(t, err)
+		exporter.SetLocale("de-DE")
+		var buf bytes.Buffer
+		if streamed {
+			s, err := exporter.StartStream(&buf)
+			require.NoError(t, err)
+			require.NoError(t, s.Write("pay", rows))
+			require.NoError(t, s.Close())
+		} else {
+			exporter.BindSectionData("pay", rows)
+			require.NoError(t, exporter.ToWriter(&buf))
+		}
+
+		f, err := excelize.OpenReader(&buf)
+		require.NoError(t, err)
+		got := func(cell string) string {
+			v, err := f.GetCellValue("Pay", cell)
+			require.NoError(t, err)
+			return v
+		}
+		assert.Equal(t, "1,234.50", got("A2"), "format wins over data_style, streamed=%v", streamed)
+		assert.Equal(t, "01/05/2024", got("B2"), "date_format wins over the locale, streamed=%v", streamed)
+		assert.Equal(t, "3", got("C2"), "data_style number_format still applies, streamed=%v", streamed)
+		f.Close()
+	}
+}
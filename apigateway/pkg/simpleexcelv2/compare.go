@@ -0,0 +1,90 @@
+package simpleexcelv2
+
+import (
+	"fmt"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Operations of comparison columns, set with op on compare_with. A is the
+// compare_with cell and B the compare_against cell of the same row.
+const (
+	CompareOpValue     = "value"      // A where it differs from B, else empty (default)
+	CompareOpDiffFlag  = "diff_flag"  // "Diff" where A differs from B, else empty
+	CompareOpDelta     = "delta"      // A-B
+	CompareOpPctChange = "pct_change" // (A-B)/B formatted as a percentage, empty where B is 0
+	CompareOpHighlight = "highlight"  // A, filled where it differs from B
+)
+
+// PctChangeNumFmt is the number format of pct_change columns without a format.
+const PctChangeNumFmt = "0.00%"
+
+// compareHighlightColor fills the changed cells of highlight columns.
+const compareHighlightColor = "FFEB9C"
+
+// compareFormula returns the formula of op comparing cell a with cell b.
+func compareFormula(op, a, b string) (string, error) {
+	switch op {
+	case "", CompareOpValue:
+		return fmt.Sprintf(`IF(%s<>%s, %s, "")`, a, b, a), nil
+	case CompareOpDiffFlag:
+		return fmt.Sprintf(`IF(%s<>%s, "Diff", "")`, a, b), nil
+	case CompareOpDelta:
+		return fmt.Sprintf(`%s-%s`, a, b), nil
+	case CompareOpPctChange:
+		return fmt.Sprintf(`IF(%s=0, "", (%s-%s)/%s)`, b, a, b, b), nil
+	case CompareOpHighlight:
+		return a, nil
+	}
+	return "", fmt.Errorf("unknown compare op %q", op)
+}
+
+// compareOp returns the operation of a comparison column.
+func compareOp(col ColumnConfig) string {
+	if col.CompareWith == nil {
+		return ""
+	}
+	return col.CompareWith.Op
+}
+
+// compareColumnStyle returns the data style of a comparison column with its
+// format, pct_change columns default to PctChangeNumFmt.
+func compareColumnStyle(style *StyleTemplate, col ColumnConfig) *StyleTemplate {
+	numFmt := col.Format
+	if numFmt == "" && style.NumberFormat == "" && compareOp(col) == CompareOpPctChange {
+		numFmt = PctChangeNumFmt
+	}
+	if numFmt == "" {
+		return style
+	}
+	formatted := *style
+	formatted.NumberFormat = numFmt
+	return &formatted
+}
+
+// highlightChanges fills the cells of a highlight column from startRow on
+// that differ from what they are compared against.
+func (e *ExcelDataExporter) highlightChanges(f *excelize.File, sheet string, col ColumnConfig, colNum, startRow, rows int) error {
+	if compareOp(col) != CompareOpHighlight || col.CompareAgainst == nil || rows <= 0 {
+		return nil
+	}
+	a, err := e.resolveCellAddress(sheet, col.CompareWith.SectionID, col.CompareWith.FieldName, 0)
+	if err != nil {
+		return err
+	}
+	b, err := e.resolveCellAddress(sheet, col.CompareAgainst.SectionID, col.CompareAgainst.FieldName, 0)
+	if err != nil {
+		return err
+	}
+	styleID, err := f.NewConditionalStyle(&excelize.Style{
+		Fill: excelize.Fill{Type: "pattern", Pattern: 1, Color: []string{compareHighlightColor}},
+	})
+	if err != nil {
+		return err
+	}
+	// The formula is relative to the first cell of the range
+	ref := e.getCellAddress(colNum, startRow) + ":" + e.getCellAddress(colNum, startRow+rows-1)
+	return f.SetConditionalFormat(sheet, ref, []excelize.ConditionalFormatOptions{
+		{Type: "formula", Criteria: fmt.Sprintf("%s<>%s", a, b), Format: styleID},
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestComparisonFeature(t *testing.T) {
@@ -45,6 +46,7 @@ sheets:
           compare_with:
             section_id: "section_a"
             field_name: "Value"
+            op: "diff_flag"
           compare_against:
             section_id: "section_b"
             field_name: "Value"
@@ -79,3 +81,143 @@ sheets:
 	formula2, _ := f.GetCellFormula("Executive Report", "E5")
 	assert.Equal(t, `IF(B5<>D5, "Diff", "")`, formula2)
 }
+
+func TestComparisonCrossSheet(t *testing.T) {
+	// The comparison is on the first sheet and refers to a later one
+	exporter, err := NewExcelDataExporterFromYamlConfig(`
+sheets:
+  - name: "Editable"
+    sections:
+    - id: "edit"
+      show_header: true
+      columns:
+        - field_name: "Name"
+          header: "Name"
+        - field_name: "Value"
+          header: "Value"
+        - field_name: "Changed"
+          header: "Changed"
+          compare_with:
+            section_id: "edit"
+            field_name: "Value"
+          compare_against:
+            section_id: "original"
+            field_name: "Value"
+  - name: "Original Data"
+    sections:
+    - id: "original"
+      title: "Original"
+      show_header: true
+      locked: true
+      columns:
+        - field_name: "Name"
+          header: "Name"
+        - field_name: "Value"
+          header: "Value"
+`)
+	require.NoError(t, err)
+	data := []map[string]interface{}{{"Name": "Item 1", "Value": 100}, {"Name": "Item 2", "Value": 200}}
+	exporter.BindSectionData("edit", data).BindSectionData("original", data)
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+
+	formula, err := f.GetCellFormula("Editable", "C3")
+	require.NoError(t, err)
+	assert.Equal(t, `IF(B3<>'Original Data'!B4, B3, "")`, formula)
+
+	placement := exporter.sectionMetadata["original"]
+	assert.Equal(t, "Original Data", placement.Sheet)
+}
+
+func TestSheetReference(t *testing.T) {
+	assert.Equal(t, "Original", sheetReference("Original"))
+	assert.Equal(t, "'Original Data'", sheetReference("Original Data"))
+	assert.Equal(t, "'Sheet2'", sheetReference("Sheet2"))
+	assert.Equal(t, "'TRUE'", sheetReference("TRUE"))
+	assert.Equal(t, "'Bob''s'", sheetReference("Bob's"))
+}
+
+func TestComparisonOps(t *testing.T) {
+	column := func(name, op string) string {
+		return `
+        - field_name: "` + name + `"
+          header: "` + name + `"
+          compare_with:
+            section_id: "edit"
+            field_name: "Value"
+            op: "` + op + `"
+          compare_against:
+            section_id: "original"
+            field_name: "Value"`
+	}
+	exporter, err := NewExcelDataExporterFromYamlConfig(`
+sheets:
+  - name: "Report"
+    sections:
+    - id: "original"
+      columns:
+        - field_name: "Value"
+    - id: "edit"
+      direction: "horizontal"
+      columns:
+        - field_name: "Value"
+    - id: "changes"
+      direction: "horizontal"
+      source_sections: ["original"]
+      show_header: true
+      columns:` + column("Flag", "diff_flag") + column("Delta", "delta") + column("Pct", "pct_change") + column("Mark", "highlight") + `
+`)
+	require.NoError(t, err)
+	exporter.BindSectionData("original", []map[string]interface{}{{"Value": 100}, {"Value": 200}}).
+		BindSectionData("edit", []map[string]interface{}{{"Value": 100}, {"Value": 250}})
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+
+	// original is in A1:A2, edit beside it in B1:B2, changes from C1 with a header
+	for cell, want := range map[string]string{
+		"C2": `IF(B1<>A1, "Diff", "")`,
+		"D3": `B2-A2`,
+		"E2": `IF(A1=0, "", (B1-A1)/A1)`,
+		"F3": `B2`,
+	} {
+		formula, err := f.GetCellFormula("Report", cell)
+		require.NoError(t, err)
+		assert.Equal(t, want, formula, cell)
+	}
+
+	styleID, err := f.GetCellStyle("Report", "E2")
+	require.NoError(t, err)
+	style, err := f.GetStyle(styleID)
+	require.NoError(t, err)
+	require.NotNil(t, style.CustomNumFmt)
+	assert.Equal(t, PctChangeNumFmt, *style.CustomNumFmt)
+
+	formats, err := f.GetConditionalFormats("Report")
+	require.NoError(t, err)
+	require.Len(t, formats["F2:F3"], 1)
+	assert.Equal(t, "formula", formats["F2:F3"][0].Type)
+	assert.Equal(t, "B1<>A1", formats["F2:F3"][0].Criteria)
+}
+
+func TestComparisonOps_Unknown(t *testing.T) {
+	result := ValidateTemplate(`sheets:
+  - name: "Report"
+    sections:
+      - id: "changes"
+        columns:
+          - field_name: "Delta"
+            compare_with:
+              section_id: "edit"
+              field_name: "Value"
+              op: "detla"
+`)
+	require.Len(t, result.Diagnostics, 1)
+	assert.Equal(t, "sheets[0].sections[0].columns[0].compare_with.op", result.Diagnostics[0].Path)
+	assert.Equal(t, "delta", result.Diagnostics[0].Suggestion)
+
+	formula, err := compareFormula("detla", "A1", "B1")
+	assert.Empty(t, formula)
+	assert.EqualError(t, err, `unknown compare op "detla"`)
+}
@@ -0,0 +1,338 @@
+package simpleexcelv2
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// sectionRangePrefix prefixes the workbook defined names that record where
+// each section's data rows were written, e.g. "section_employees".
+const sectionRangePrefix = "section_"
+
+// sectionAnchorPrefix prefixes the defined names that record the first data
+// row of sections exported without rows, so rows added to them can be found.
+const sectionAnchorPrefix = "anchor_"
+
+// Row change kinds.
+const (
+	RowChanged = "changed"
+	RowAdded   = "added"
+	RowRemoved = "removed"
+)
+
+// FieldChange is a single cell whose value differs between two workbooks.
+type FieldChange struct {
+	Field string `json:"field"`
+	Cell  string `json:"cell"`
+	Old   string `json:"old"`
+	New   string `json:"new"`
+}
+
+// RowChange lists the changed fields of one data row. Row is the 0-based
+// index into the data bound to the section; added rows continue the index
+// past the exported rows. Kind is RowChanged, RowAdded or RowRemoved.
+type RowChange struct {
+	Row     int           `json:"row"`
+	Kind    string        `json:"kind"`
+	Changes []FieldChange `json:"changes"`
+}
+
+// SectionDiff holds the changed rows of one section.
+type SectionDiff struct {
+	SectionID string      `json:"section_id"`
+	Sheet     string      `json:"sheet"`
+	Rows      []RowChange `json:"rows"`
+}
+
+// WorkbookDiff is the result of DiffWorkbooks. Sections without changes are omitted.
+type WorkbookDiff struct {
+	Sections []SectionDiff `json:"sections"`
+}
+
+// HasChanges reports whether any cell differs.
+func (d *WorkbookDiff) HasChanges() bool {
+	return len(d.Sections) > 0
+}
+
+// Section returns the diff for a section ID, or nil if it has no changes.
+func (d *WorkbookDiff) Section(id string) *SectionDiff {
+	for i := range d.Sections {
+		if d.Sections[i].SectionID == id {
+			return &d.Sections[i]
+		}
+	}
+	return nil
+}
+
+// DiffWorkbooks compares an exported workbook with an edited copy and returns
+// per-section, per-row field changes, so the backend can compute deltas
+// without relying on the compare_with formulas.
+// Sections are located through the data ranges the exporter records in the
+// workbook; field names come from the layout columns, falling back to the
+// column letter for columns the layout does not declare. Comparison columns
+// are skipped. Raw cell values are compared, not their displayed format.
+// Exported rows cleared in the edited copy are reported as removed; filled
+// rows below a section, up to the first blank row or the next section, are
+// reported as added.
+func DiffWorkbooks(original, edited io.Reader, layout *ReportTemplate) (*WorkbookDiff, error) {
+	if layout == nil {
+		return nil, fmt.Errorf("layout is required")
+	}
+	origFile, err := excelize.OpenReader(original)
+	if err != nil {
+		return nil, fmt.Errorf("open original workbook: %w", err)
+	}
+	defer origFile.Close()
+	editFile, err := excelize.OpenReader(edited)
+	if err != nil {
+		return nil, fmt.Errorf("open edited workbook: %w", err)
+	}
+	defer editFile.Close()
+
+	ranges := sectionRanges(origFile)
+	for id, rng := range sectionAnchors(origFile) {
+		if _, ok := ranges[id]; !ok {
+			ranges[id] = rng
+		}
+	}
+	layoutSections := make(map[string]*SectionConfig)
+	for _, sheet := range layout.Sheets {
+		for i := range sheet.Sections {
+			layoutSections[sheet.Sections[i].ID] = &sheet.Sections[i]
+		}
+	}
+	diff := &WorkbookDiff{}
+
+	for _, sheet := range layout.Sheets {
+		for i := range sheet.Sections {
+			sec := &sheet.Sections[i]
+			if sec.ID == "" || sec.Type == SectionTypeTitleOnly {
+				continue
+			}
+			rng, ok := ranges[sec.ID]
+			if !ok {
+				// Section was not part of the original export
+				continue
+			}
+			limit := nextSectionRow(ranges, layoutSections, sec.ID, rng)
+			secDiff, err := diffSection(origFile, editFile, sec, rng, limit)
+			if err != nil {
+				return nil, err
+			}
+			if len(secDiff.Rows) > 0 {
+				diff.Sections = append(diff.Sections, *secDiff)
+			}
+		}
+	}
+	return diff, nil
+}
+
+// sectionRange is a rectangular block of data cells on a sheet. Anchors of
+// sections without rows have EndRow = StartRow - 1.
+type sectionRange struct {
+	Sheet              string
+	StartCol, StartRow int
+	EndCol, EndRow     int
+}
+
+// nextSectionRow returns the first row of the nearest section placed below
+// rng on the same sheet with overlapping columns, counting its title and
+// header rows, or 0 if there is none. Added rows are not looked for past it.
+func nextSectionRow(ranges map[string]sectionRange, layout map[string]*SectionConfig, id string, rng sectionRange) int {
+	next := 0
+	for otherID, other := range ranges {
+		if otherID == id || other.Sheet != rng.Sheet || other.StartRow <= rng.EndRow ||
+			other.StartCol > rng.EndCol || other.EndCol < rng.StartCol {
+			continue
+		}
+		top := other.StartRow
+		if sec, ok := layout[otherID]; ok {
+			top -= headerRowCount(sec)
+		}
+		if next == 0 || top < next {
+			next = top
+		}
+	}
+	return next
+}
+
+// headerRowCount is the number of rows written above a section's data: its
+// title, hidden field row and header.
+func headerRowCount(sec *SectionConfig) int {
+	n := 0
+	if sec.Title != nil {
+		n++
+	}
+	if sec.Type == SectionTypeTitleOnly {
+		return n
+	}
+	if hasHiddenFields(sec) {
+		n++
+	}
+	if sec.ShowHeader {
+		n++
+	}
+	return n
+}
+
+// diffSection compares the exported rows of a section and then scans the rows
+// below it, up to limit (exclusive, 0 for no limit), for added ones.
+func diffSection(origFile, editFile *excelize.File, sec *SectionConfig, rng sectionRange, limit int) (*SectionDiff, error) {
+	secDiff := &SectionDiff{SectionID: sec.ID, Sheet: rng.Sheet}
+
+	for r := rng.StartRow; limit == 0 || r < limit; r++ {
+		changes, blank, err := compareRow(origFile, editFile, sec, rng, r)
+		if err != nil {
+			return nil, err
+		}
+		kind := RowChanged
+		if r > rng.EndRow {
+			if blank {
+				break
+			}
+			kind = RowAdded
+		} else if blank {
+			kind = RowRemoved
+		}
+		if len(changes) > 0 {
+			secDiff.Rows = append(secDiff.Rows, RowChange{Row: r - rng.StartRow, Kind: kind, Changes: changes})
+		}
+	}
+	return secDiff, nil
+}
+
+// compareRow compares one sheet row across the section's columns and reports
+// whether the edited row is blank.
+func compareRow(origFile, editFile *excelize.File, sec *SectionConfig, rng sectionRange, r int) ([]FieldChange, bool, error) {
+	opts := excelize.Options{RawCellValue: true}
+	var changes []FieldChange
+	blank := true
+	for c := rng.StartCol; c <= rng.EndCol; c++ {
+		offset := c - rng.StartCol
+		field := ""
+		if offset < len(sec.Columns) {
+			if sec.Columns[offset].CompareWith != nil {
+				continue
+			}
+			field = sec.Columns[offset].FieldName
+		}
+		cell, _ := excelize.CoordinatesToCellName(c, r)
+		if field == "" {
+			field, _ = excelize.ColumnNumberToName(c)
+		}
+		oldVal, err := origFile.GetCellValue(rng.Sheet, cell, opts)
+		if err != nil {
+			return nil, false, fmt.Errorf("read original %s!%s: %w", rng.Sheet, cell, err)
+		}
+		newVal, err := editFile.GetCellValue(rng.Sheet, cell, opts)
+		if err != nil {
+			return nil, false, fmt.Errorf("read edited %s!%s: %w", rng.Sheet, cell, err)
+		}
+		if newVal != "" {
+			blank = false
+		}
+		if oldVal != newVal {
+			changes = append(changes, FieldChange{Field: field, Cell: cell, Old: oldVal, New: newVal})
+		}
+	}
+	return changes, blank, nil
+}
+
+// sectionRanges reads the section data ranges recorded by the exporter.
+func sectionRanges(f *excelize.File) map[string]sectionRange {
+	return definedRanges(f, sectionRangePrefix)
+}
+
+// sectionAnchors reads the first data row recorded for sections exported
+// without rows, as empty ranges.
+func sectionAnchors(f *excelize.File) map[string]sectionRange {
+	anchors := definedRanges(f, sectionAnchorPrefix)
+	for id, rng := range anchors {
+		rng.EndRow = rng.StartRow - 1
+		anchors[id] = rng
+	}
+	return anchors
+}
+
+// definedRanges reads the defined names with prefix, keyed by section ID.
+func definedRanges(f *excelize.File, prefix string) map[string]sectionRange {
+	ranges := make(map[string]sectionRange)
+	for _, dn := range f.GetDefinedName() {
+		if !strings.HasPrefix(dn.Name, prefix) {
+			continue
+		}
+		rng, ok := parseSectionRange(dn.RefersTo)
+		if !ok {
+			continue
+		}
+		id := dn.Comment
+		if id == "" {
+			id = strings.TrimPrefix(dn.Name, prefix)
+		}
+		ranges[id] = rng
+	}
+	return ranges
+}
+
+// parseSectionRange parses references like 'My Sheet'!$A$3:$D$10.
+func parseSectionRange(ref string) (sectionRange, bool) {
+	idx := strings.LastIndex(ref, "!")
+	if idx < 0 {
+		return sectionRange{}, false
+	}
+	sheet := strings.Trim(ref[:idx], "'")
+	sheet = strings.ReplaceAll(sheet, "''", "'")
+	cells := strings.Split(strings.ReplaceAll(ref[idx+1:], "$", ""), ":")
+	if len(cells) != 2 {
+		return sectionRange{}, false
+	}
+	c1, r1, err := excelize.CellNameToCoordinates(cells[0])
+	if err != nil {
+		return sectionRange{}, false
+	}
+	c2, r2, err := excelize.CellNameToCoordinates(cells[1])
+	if err != nil {
+		return sectionRange{}, false
+	}
+	return sectionRange{Sheet: sheet, StartCol: c1, StartRow: r1, EndCol: c2, EndRow: r2}, true
+}
+
+// recordSectionRange stores the data range of a section as a workbook defined
+// name so DiffWorkbooks and importers can find it again. Sections without
+// rows get an anchor on their first data row instead, which importers ignore.
+// Sections without columns, e.g. ones taking them from data that turned out
+// empty, are anchored one column wide.
+func recordSectionRange(f *excelize.File, sheet, sectionID string, startCol, startRow, cols, rows int) {
+	if sectionID == "" {
+		return
+	}
+	if cols <= 0 {
+		cols = 1
+	}
+	prefix := sectionRangePrefix
+	if rows <= 0 {
+		prefix, rows = sectionAnchorPrefix, 1
+	}
+	rng := sectionRange{Sheet: sheet, StartCol: startCol, StartRow: startRow, EndCol: startCol + cols - 1, EndRow: startRow + rows - 1}
+	f.SetDefinedName(&excelize.DefinedName{
+		Name:     prefix + definedNameSafe(sectionID),
+		Comment:  sectionID,
+		RefersTo: rangeRef(rng),
+	})
+}
+
+// definedNameSafe replaces characters Excel does not allow in defined names.
+func definedNameSafe(id string) string {
+	var sb strings.Builder
+	for _, r := range id {
+		if r == '_' || r == '.' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			sb.WriteRune(r)
+		} else {
+			sb.WriteRune('_')
+		}
+	}
+	return sb.String()
+}
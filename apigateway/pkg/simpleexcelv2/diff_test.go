@@ -0,0 +1,183 @@
+package simpleexcelv2
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+const diffYAML = `
+sheets:
+  - name: "Staff List"
+    sections:
+      - id: "original"
+        title: "Original"
+        show_header: true
+        locked: true
+        columns:
+          - field_name: "Name"
+            header: "Name"
+          - field_name: "Salary"
+            header: "Salary"
+      - id: "edit"
+        title: "Editable"
+        show_header: true
+        columns:
+          - field_name: "Name"
+            header: "Name"
+          - field_name: "Salary"
+            header: "Salary"
+      - id: "diff"
+        show_header: true
+        columns:
+          - field_name: "SalaryDiff"
+            header: "Diff"
+            compare_with:
+              section_id: "edit"
+              field_name: "Salary"
+            compare_against:
+              section_id: "original"
+              field_name: "Salary"
+`
+
+type diffRow struct {
+	Name   string
+	Salary int
+}
+
+func exportDiffWorkbook(t *testing.T) (*ExcelDataExporter, []byte) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(diffYAML)
+	require.NoError(t, err)
+	rows := []diffRow{{"Alice", 100}, {"Bob", 200}, {"Carol", 300}}
+	exporter.BindSectionData("original", rows).BindSectionData("edit", rows)
+	data, err := exporter.ToBytes()
+	require.NoError(t, err)
+	return exporter, data
+}
+
+func TestDiffWorkbooks(t *testing.T) {
+	exporter, original := exportDiffWorkbook(t)
+
+	f, err := excelize.OpenReader(bytes.NewReader(original))
+	require.NoError(t, err)
+	// "original" fills rows 1-5 (title, header, 3 rows); "edit" data is rows 8-10.
+	require.NoError(t, f.SetCellValue("Staff List", "B9", 250))
+	require.NoError(t, f.SetCellValue("Staff List", "A10", "Caroline"))
+	var edited bytes.Buffer
+	require.NoError(t, f.Write(&edited))
+
+	diff, err := DiffWorkbooks(bytes.NewReader(original), &edited, exporter.Template())
+	require.NoError(t, err)
+	require.True(t, diff.HasChanges())
+	require.Len(t, diff.Sections, 1)
+
+	sec := diff.Section("edit")
+	require.NotNil(t, sec)
+	assert.Equal(t, "Staff List", sec.Sheet)
+	require.Len(t, sec.Rows, 2)
+	assert.Equal(t, RowChange{Row: 1, Kind: RowChanged, Changes: []FieldChange{{Field: "Salary", Cell: "B9", Old: "200", New: "250"}}}, sec.Rows[0])
+	assert.Equal(t, RowChange{Row: 2, Kind: RowChanged, Changes: []FieldChange{{Field: "Name", Cell: "A10", Old: "Carol", New: "Caroline"}}}, sec.Rows[1])
+}
+
+func TestDiffWorkbooks_AddedAndRemovedRows(t *testing.T) {
+	exporter, original := exportDiffWorkbook(t)
+
+	f, err := excelize.OpenReader(bytes.NewReader(original))
+	require.NoError(t, err)
+	// Clear Bob from "original" (row 4); add Dave below "edit" (row 11),
+	// where the "diff" section title would start if it had one.
+	require.NoError(t, f.SetCellValue("Staff List", "A4", ""))
+	require.NoError(t, f.SetCellValue("Staff List", "B4", ""))
+	require.NoError(t, f.SetCellValue("Staff List", "A11", "Dave"))
+	require.NoError(t, f.SetCellValue("Staff List", "B11", 400))
+	var edited bytes.Buffer
+	require.NoError(t, f.Write(&edited))
+
+	diff, err := DiffWorkbooks(bytes.NewReader(original), &edited, exporter.Template())
+	require.NoError(t, err)
+
+	orig := diff.Section("original")
+	require.NotNil(t, orig)
+	require.Len(t, orig.Rows, 1)
+	assert.Equal(t, RowChange{Row: 1, Kind: RowRemoved, Changes: []FieldChange{
+		{Field: "Name", Cell: "A4", Old: "Bob", New: ""},
+		{Field: "Salary", Cell: "B4", Old: "200", New: ""},
+	}}, orig.Rows[0])
+
+	// The "diff" section header sits on row 11, so nothing is added to "edit"
+	assert.Nil(t, diff.Section("edit"))
+}
+
+func TestDiffWorkbooks_RowsAddedToEmptySection(t *testing.T) {
+	const yml = `
+sheets:
+  - name: "Data"
+    sections:
+      - id: "known"
+        show_header: true
+        columns:
+          - field_name: "Name"
+            header: "Name"
+      - id: "derived"
+        title: "Derived"
+        show_header: true
+`
+	exporter, err := NewExcelDataExporterFromYamlConfig(yml)
+	require.NoError(t, err)
+	exporter.BindSectionData("known", []diffRow{}).BindSectionData("derived", []diffRow{{"Alice", 100}})
+	original, err := exporter.ToBytes()
+	require.NoError(t, err)
+
+	f, err := excelize.OpenReader(bytes.NewReader(original))
+	require.NoError(t, err)
+	// "known" header is row 1 with no rows, "derived" starts on row 2
+	// (title, header, data on row 4) so nothing fits between them.
+	require.NoError(t, f.SetCellValue("Data", "B4", 150))
+	require.NoError(t, f.SetCellValue("Data", "A5", "Bob"))
+	require.NoError(t, f.SetCellValue("Data", "B5", 200))
+	var edited bytes.Buffer
+	require.NoError(t, f.Write(&edited))
+
+	diff, err := DiffWorkbooks(bytes.NewReader(original), &edited, exporter.Template())
+	require.NoError(t, err)
+	assert.Nil(t, diff.Section("known"))
+
+	sec := diff.Section("derived")
+	require.NotNil(t, sec)
+	require.Len(t, sec.Rows, 2)
+	assert.Equal(t, RowChange{Row: 0, Kind: RowChanged, Changes: []FieldChange{{Field: "Salary", Cell: "B4", Old: "100", New: "150"}}}, sec.Rows[0])
+	assert.Equal(t, RowChange{Row: 1, Kind: RowAdded, Changes: []FieldChange{
+		{Field: "Name", Cell: "A5", Old: "", New: "Bob"},
+		{Field: "Salary", Cell: "B5", Old: "", New: "200"},
+	}}, sec.Rows[1])
+}
+
+func TestDiffWorkbooks_NoChanges(t *testing.T) {
+	exporter, original := exportDiffWorkbook(t)
+
+	diff, err := DiffWorkbooks(bytes.NewReader(original), bytes.NewReader(original), exporter.Template())
+	require.NoError(t, err)
+	assert.False(t, diff.HasChanges())
+}
+
+func TestDiffWorkbooks_StreamedExport(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(diffYAML)
+	require.NoError(t, err)
+
+	var original bytes.Buffer
+	streamer, err := exporter.StartStream(&original)
+	require.NoError(t, err)
+	require.NoError(t, streamer.Write("original", []diffRow{{"Alice", 100}}))
+	require.NoError(t, streamer.Write("original", []diffRow{{"Bob", 200}}))
+	require.NoError(t, streamer.Write("edit", []diffRow{{"Alice", 100}, {"Bob", 200}}))
+	require.NoError(t, streamer.Close())
+
+	f, err := excelize.OpenReader(bytes.NewReader(original.Bytes()))
+	require.NoError(t, err)
+	ranges := sectionRanges(f)
+	assert.Equal(t, sectionRange{Sheet: "Staff List", StartCol: 1, StartRow: 3, EndCol: 2, EndRow: 4}, ranges["original"])
+	assert.Equal(t, sectionRange{Sheet: "Staff List", StartCol: 1, StartRow: 7, EndCol: 2, EndRow: 8}, ranges["edit"])
+}
@@ -0,0 +1,71 @@
+package simpleexcelv2
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Values of SectionConfig.DistinctKeep.
+const (
+	DistinctKeepFirst = "first"
+	DistinctKeepLast  = "last"
+)
+
+// distinctData returns a copy of a slice without the rows whose fields equal
+// those of another row. The first of them is kept, or the last when keepLast;
+// kept rows stay in their bound order. Anything but a slice is returned as is.
+func (e *ExcelDataExporter) distinctData(data interface{}, fields []string, keepLast bool) interface{} {
+	v := reflect.ValueOf(data)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice || v.Len() < 2 {
+		return data
+	}
+
+	n := v.Len()
+	kept := make(map[string]int, n)
+	keys := make([]string, n)
+	for i := 0; i < n; i++ {
+		keys[i] = distinctKey(e.rowValues(v.Index(i), fields))
+		if _, seen := kept[keys[i]]; !seen || keepLast {
+			kept[keys[i]] = i
+		}
+	}
+	if len(kept) == n {
+		return data
+	}
+
+	out := reflect.MakeSlice(v.Type(), 0, len(kept))
+	for i := 0; i < n; i++ {
+		if kept[keys[i]] == i {
+			out = reflect.Append(out, v.Index(i))
+		}
+	}
+	return out.Interface()
+}
+
+// distinctKey encodes values with their kind, normalized as for sorting so
+// 1 and 1.0 match, as do "" and nil.
+func distinctKey(values []interface{}) string {
+	var b strings.Builder
+	for _, v := range values {
+		switch x := sortValue(v).(type) {
+		case nil:
+			b.WriteString("n")
+		case float64:
+			b.WriteString("f" + strconv.FormatFloat(x, 'g', -1, 64))
+		case time.Time:
+			b.WriteString("t" + strconv.FormatInt(x.UnixNano(), 10))
+		case string:
+			b.WriteString("s" + strconv.Quote(x))
+		default:
+			b.WriteString("v" + strconv.Quote(fmt.Sprint(x)))
+		}
+		b.WriteByte(0)
+	}
+	return b.String()
+}
@@ -0,0 +1,116 @@
+package simpleexcelv2
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type assignmentRow struct {
+	EmpNo  int
+	DeptNo string
+	Title  string
+}
+
+func assignmentRows() []assignmentRow {
+	return []assignmentRow{
+		{1, "d002", "Engineer"},
+		{2, "d001", "Staff"},
+		{1, "d002", "Senior Engineer"},
+		{1, "d001", "Manager"},
+		{2, "d001", "Senior Staff"},
+	}
+}
+
+func distinctExporter(t *testing.T, keep string) *ExcelDataExporter {
+	exporter := NewExcelDataExporter()
+	exporter.AddSheet("Staff").AddSection(&SectionConfig{
+		ID: "assignments", DistinctBy: []string{"EmpNo", "DeptNo"}, DistinctKeep: keep,
+		Columns: []ColumnConfig{{FieldName: "Title"}},
+	})
+	return exporter
+}
+
+func TestDistinctBy_KeepFirst(t *testing.T) {
+	exporter := distinctExporter(t, "")
+	rows := assignmentRows()
+	exporter.BindSectionData("assignments", rows)
+
+	assert.Equal(t, []string{"Engineer", "Staff", "Manager"}, csvFirstColumn(t, exporter))
+	assert.Len(t, rows, 5, "the bound slice is not changed")
+	assert.Empty(t, exporter.Warnings())
+}
+
+func TestDistinctBy_KeepLast(t *testing.T) {
+	exporter := distinctExporter(t, DistinctKeepLast)
+	exporter.BindSectionData("assignments", assignmentRows())
+
+	assert.Equal(t, []string{"Senior Engineer", "Manager", "Senior Staff"}, csvFirstColumn(t, exporter))
+}
+
+func TestDistinctBy_BeforeSort(t *testing.T) {
+	exporter := distinctExporter(t, DistinctKeepLast)
+	exporter.sheets[0].sections[0].SortBy = []string{"-Title"}
+	exporter.BindSectionData("assignments", assignmentRows())
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+	var titles []string
+	for _, cell := range []string{"A1", "A2", "A3", "A4"} {
+		v, err := f.GetCellValue("Staff", cell)
+		require.NoError(t, err)
+		titles = append(titles, v)
+	}
+	assert.Equal(t, []string{"Senior Staff", "Senior Engineer", "Manager", ""}, titles)
+}
+
+func TestDistinctBy_Maps(t *testing.T) {
+	exporter := NewExcelDataExporter()
+	exporter.AddSheet("Staff").AddSection(&SectionConfig{
+		ID: "staff", DistinctBy: []string{"id"},
+		Columns: []ColumnConfig{{FieldName: "name"}},
+	})
+	exporter.BindSectionData("staff", []map[string]interface{}{
+		{"id": 1, "name": "int"},
+		{"id": 1.0, "name": "float"},
+		{"id": "", "name": "empty"},
+		{"name": "missing"},
+	})
+
+	assert.Equal(t, []string{"int", "empty"}, csvFirstColumn(t, exporter),
+		"numbers compare by value, a missing key equals the empty string")
+}
+
+func TestDistinctBy_Warnings(t *testing.T) {
+	exporter := NewExcelDataExporter()
+	exporter.AddSheet("Staff").
+		AddSection(&SectionConfig{ID: "assignments", DistinctBy: []string{"EmpID"}, Columns: []ColumnConfig{{FieldName: "Title"}}}).
+		AddSection(&SectionConfig{ID: "streamed", DistinctBy: []string{"EmpNo"}, Columns: []ColumnConfig{{FieldName: "Title"}}})
+	exporter.BindSectionData("assignments", assignmentRows())
+
+	streamer, err := exporter.StartStream(&bytes.Buffer{})
+	require.NoError(t, err)
+	require.NoError(t, streamer.Write("streamed", assignmentRows()))
+	require.NoError(t, streamer.Close())
+
+	assert.Equal(t, []Warning{
+		{Kind: WarningUnknownField, Sheet: "Staff", Section: "assignments", Field: "EmpID", Message: "assignmentRow has no field EmpID to find duplicates by"},
+		{Kind: WarningStreamDuplicates, Sheet: "Staff", Section: "streamed", Message: "streamed rows are written as they are sent, distinct_by is not applied"},
+	}, exporter.Warnings())
+}
+
+func TestDistinctBy_ValidateKeep(t *testing.T) {
+	result := ValidateTemplate(`sheets:
+  - name: "Staff"
+    sections:
+      - id: "staff"
+        distinct_by: [EmpNo]
+        distinct_keep: lats
+`)
+
+	require.Len(t, result.Diagnostics, 1)
+	assert.Equal(t, TemplateDiagnostic{Severity: SeverityWarning, Line: 6, Column: 24, Path: "sheets[0].sections[0].distinct_keep",
+		Message: `unknown distinct_keep "lats", the first duplicate is kept`, Suggestion: "last"}, result.Diagnostics[0])
+}
@@ -1,10 +1,14 @@
 package simpleexcelv2
 
 import (
+	"bytes"
 	"context"
 	"os"
+	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/xuri/excelize/v2"
 )
 
@@ -94,3 +98,48 @@ func TestDynamicMapExport(t *testing.T) {
 		}
 	}
 }
+
+func TestDynamicMapExport_ColumnOrder(t *testing.T) {
+	data := []map[string]interface{}{
+		{"Name": "Product A", "Price": 100, "Color": "Red", "Stock": 5, "Brand": "Acme"},
+	}
+	headers := func(order []string) []string {
+		exporter := NewExcelDataExporter()
+		exporter.AddSheet("Dynamic").AddSection(&SectionConfig{
+			ID:         "products",
+			Data:       data,
+			ShowHeader: true,
+			Order:      order,
+			Columns:    []ColumnConfig{{FieldName: "Price", Header: "Price"}},
+		})
+		var buf bytes.Buffer
+		require.NoError(t, exporter.ToCSV(&buf))
+		return strings.Split(strings.SplitN(buf.String(), "\n", 2)[0], ",")
+	}
+
+	// Configured columns first, detected keys sorted
+	want := []string{"Price", "Brand", "Color", "Name", "Stock"}
+	for i := 0; i < 5; i++ {
+		assert.Equal(t, want, headers(nil))
+	}
+
+	// Listed fields come first, unknown ones are ignored
+	assert.Equal(t, []string{"Price", "Name", "Stock", "Brand", "Color"}, headers([]string{"Name", "Missing", "Stock", "Price"}))
+}
+
+func TestDynamicMapExport_OrderFromYAML(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(`
+sheets:
+  - name: "Dynamic"
+    sections:
+      - id: "products"
+        show_header: true
+        order: [Stock, Name]
+`)
+	require.NoError(t, err)
+	exporter.BindSectionData("products", []map[string]interface{}{{"Name": "A", "Brand": "Acme", "Stock": 5}})
+
+	var buf bytes.Buffer
+	require.NoError(t, exporter.ToCSV(&buf))
+	assert.Equal(t, "Stock,Name,Brand\n5,A,Acme", strings.TrimSpace(buf.String()))
+}
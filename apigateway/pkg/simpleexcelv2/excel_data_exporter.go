@@ -6,9 +6,12 @@ import (
 	"encoding/csv"
 	"fmt"
 	"io"
+	"os"
 	"reflect"
+	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/xuri/excelize/v2"
 	"gopkg.in/yaml.v2"
@@ -35,16 +38,42 @@ type ExcelDataExporter struct {
 	// sheets holds manually added sheets (for programmatic flow)
 	sheets []*SheetBuilder
 	// formatters holds registered formatter functions by name
-	formatters map[string]func(interface{}) interface{}
+	formatters map[string]CheckedFormatter
+	// formatErrors collects the values checked formatters rejected
+	formatErrors        []FormatError
+	formatErrorComments bool
+	// warnings collects the problems of the last export, strict fails it
+	// and strictBinding only on binding problems
+	warnings      []Warning
+	strict        bool
+	strictBinding bool
+	// truncated holds the columns of the current export with truncated
+	// values, by sheet, section ID and field
+	truncated map[string]bool
+	// result describes the last export, see Result
+	result      *ExportResult
+	exportStart time.Time
+	// richTextFormatters holds registered rich text formatters by name
+	richTextFormatters map[string]RichTextFormatter
 
 	// Metadata for coordinate mapping
 	sectionMetadata map[string]SectionPlacement
 
-	// Performance Caches
+	// Performance Caches, the reflection metadata of row types is shared
+	// by all exporters, see typeInfoOf
 	styleCache   map[string]int
 	colNameCache map[int]string
-	fieldCache   map[fieldCacheKey]int
 	logger       Logger
+
+	// objectStorage opens writers for ExportToObjectStorage
+	objectStorage ObjectWriterProvider
+	// locale selects default number/date formats, nil keeps raw values
+	locale *LocaleFormat
+	// checksumKey signs locked sections when set, see SetChecksumKey
+	checksumKey []byte
+	// scratchDir and scratchQuota configure spooling, see SetScratch
+	scratchDir   string
+	scratchQuota int64
 }
 
 // Logger interface for internal logging
@@ -64,15 +93,10 @@ func (e *ExcelDataExporter) log(format string, args ...interface{}) {
 	}
 }
 
-// fieldCacheKey is a unique key for caching field indices.
-type fieldCacheKey struct {
-	Type      reflect.Type
-	FieldName string
-}
-
 // SectionPlacement stores the starting coordinates and metadata of a rendered section.
 type SectionPlacement struct {
 	SectionID    string
+	Sheet        string // Sheet the section is on
 	StartRow     int
 	StartCol     int
 	FieldOffsets map[string]int // Map of FieldName to ColumnOffset (relative to startCol)
@@ -81,55 +105,93 @@ type SectionPlacement struct {
 
 // ReportTemplate represents the YAML structure.
 type ReportTemplate struct {
-	Sheets []SheetTemplate `yaml:"sheets"`
+	Version     string `yaml:"version"`
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	// Variables declares the parameters callers supply, see VariableDecl.
+	Variables VariableDecls   `yaml:"variables"`
+	Sheets    []SheetTemplate `yaml:"sheets"`
+	// Revision identifies the content the template was parsed from, the
+	// first 12 hex digits of its SHA-256, set by TemplateCache. Exporters
+	// keep the revision they were created with when the file changes.
+	Revision string `yaml:"-"`
 }
 
 // SheetTemplate represents a sheet in the YAML.
 type SheetTemplate struct {
-	Name     string          `yaml:"name"`
-	Sections []SectionConfig `yaml:"sections"`
+	Name       string              `yaml:"name"`
+	Layout     *LayoutTemplate     `yaml:"layout"`     // Freeze panes and column sizing
+	Protection *ProtectionTemplate `yaml:"protection"` // Password and allowed actions of locked sheets
+	Sections   []SectionConfig     `yaml:"sections"`
 }
 
 // SectionConfig defines a section of data in a sheet.
 type SectionConfig struct {
-	ID             string         `yaml:"id"`
-	Title          interface{}    `yaml:"title"`
-	ColSpan        int            `yaml:"col_span"`        // Number of columns to span for title-only sections
-	Data           interface{}    `yaml:"-"`               // Data is bound at runtime
-	SourceSections []string       `yaml:"source_sections"` // IDs of sections this depends on
-	Type           string         `yaml:"type"`            // "full", "title", "hidden"
-	Locked         bool           `yaml:"locked"`          // Section-level lock (default for all columns)
-	ShowHeader     bool           `yaml:"show_header"`
-	Direction      string         `yaml:"direction"` // "horizontal" or "vertical"
-	Position       string         `yaml:"position"`  // e.g., "A1"
-	TitleStyle     *StyleTemplate `yaml:"title_style"`
-	HeaderStyle    *StyleTemplate `yaml:"header_style"`
-	DataStyle      *StyleTemplate `yaml:"data_style"`
-	TitleHeight    float64        `yaml:"title_height"`
-	HeaderHeight   float64        `yaml:"header_height"`
-	DataHeight     float64        `yaml:"data_height"`
-	HasFilter      bool           `yaml:"has_filter"`
-	Columns        []ColumnConfig `yaml:"columns"`
+	ID             string           `yaml:"id"`
+	Title          interface{}      `yaml:"title"`
+	ColSpan        int              `yaml:"col_span"`        // Number of columns to span for title-only sections
+	Data           interface{}      `yaml:"-"`               // Data is bound at runtime
+	SourceSections []string         `yaml:"source_sections"` // IDs of sections this depends on
+	Type           string           `yaml:"type"`            // "full", "title", "hidden"
+	Optional       bool             `yaml:"optional"`        // BindAll accepts no data for it, the section is rendered without rows
+	Locked         bool             `yaml:"locked"`          // Section-level lock (default for all columns)
+	ShowHeader     bool             `yaml:"show_header"`
+	Direction      string           `yaml:"direction"` // "horizontal" or "vertical"
+	Position       string           `yaml:"position"`  // e.g., "A1"
+	Below          string           `yaml:"below"`     // ID of the section to place this one under, left-aligned with it
+	RightOf        string           `yaml:"right_of"`  // ID of the section to place this one beside, top-aligned with it
+	TitleStyle     *StyleTemplate   `yaml:"title_style"`
+	HeaderStyle    *StyleTemplate   `yaml:"header_style"`
+	DataStyle      *StyleTemplate   `yaml:"data_style"`
+	TitleHeight    float64          `yaml:"title_height"`
+	HeaderHeight   float64          `yaml:"header_height"`
+	DataHeight     float64          `yaml:"data_height"`
+	HasFilter      bool             `yaml:"has_filter"`
+	AutoFit        bool             `yaml:"auto_fit"` // Size the section's columns to its content, see LayoutTemplate
+	Columns        []ColumnConfig   `yaml:"columns"`
+	Order          []string         `yaml:"order"`         // Field names of detected columns to place first, the rest follow sorted
+	SortBy         []string         `yaml:"sort_by"`       // Fields the bound rows are sorted by, "-Field" for descending
+	DistinctBy     []string         `yaml:"distinct_by"`   // Fields identifying duplicate rows, only one of them is rendered
+	DistinctKeep   string           `yaml:"distinct_keep"` // Which duplicate is kept: "first" (default) or "last"
+	Gantt          *GanttConfig     `yaml:"gantt"`         // Axis and bar fields of "gantt" sections
+	OrgChart       *OrgChartConfig  `yaml:"org_chart"`     // Hierarchy fields of "org_chart" sections
+	Heatmap        *HeatmapConfig   `yaml:"heatmap"`       // Pivot fields of "heatmap" sections
+	Calendar       *CalendarConfig  `yaml:"calendar"`      // Record fields and day styles of "calendar" sections
+	LineChart      *LineChartConfig `yaml:"line_chart"`    // Category, series and value fields of "line_chart" sections
+	Repeat         *RepeatConfig    `yaml:"repeat"`        // Spacing of the instances of "repeat" sections
+
+	gap int // Blank rows left above a section placed below another one
 }
 
 // CompareConfig defines how to compare a column with another section.
 type CompareConfig struct {
 	SectionID string `yaml:"section_id"`
 	FieldName string `yaml:"field_name"`
+	Op        string `yaml:"op"` // On compare_with: "value" (default), "diff_flag", "delta", "pct_change" or "highlight"
 }
 
 // ColumnConfig defines a column in a section.
 type ColumnConfig struct {
-	FieldName       string                        `yaml:"field_name"` // Struct field name or map key
-	Header          string                        `yaml:"header"`
-	Width           float64                       `yaml:"width"`
-	Height          float64                       `yaml:"height"`
-	Locked          *bool                         `yaml:"locked"`            // Column-level lock override (overrides section Locked)
-	Formatter       func(interface{}) interface{} `yaml:"-"`                 // Optional custom formatter function (Programmatic)
-	FormatterName   string                        `yaml:"formatter"`         // Name of registered formatter (YAML)
-	HiddenFieldName string                        `yaml:"hidden_field_name"` // Hidden field name for backend use
-	CompareWith     *CompareConfig                `yaml:"compare_with"`      // For injecting comparison formulas
-	CompareAgainst  *CompareConfig                `yaml:"compare_against"`   // For injecting comparison formulas
+	FieldName         string                        `yaml:"field_name"` // Struct field name or map key
+	Header            string                        `yaml:"header"`
+	Width             float64                       `yaml:"width"`
+	Height            float64                       `yaml:"height"`
+	Locked            *bool                         `yaml:"locked"`            // Column-level lock override (overrides section Locked)
+	Formatter         func(interface{}) interface{} `yaml:"-"`                 // Optional custom formatter function (Programmatic)
+	FormatterName     string                        `yaml:"-"`                 // Name of a registered or built-in formatter (Programmatic)
+	Formatters        FormatterChain                `yaml:"formatter"`         // Formatter names applied in order, a name or a list in YAML
+	HiddenFieldName   string                        `yaml:"hidden_field_name"` // Hidden field name for backend use
+	CompareWith       *CompareConfig                `yaml:"compare_with"`      // For injecting comparison formulas
+	CompareAgainst    *CompareConfig                `yaml:"compare_against"`   // For injecting comparison formulas
+	Locale            string                        `yaml:"locale"`            // Overrides the exporter locale for this column (e.g. "en-US")
+	Type              string                        `yaml:"type"`              // Value type for the locale number format: "int", "float" or "date"; defaults to the struct field type
+	Validation        *ValidationRule               `yaml:"validation"`        // Excel data validation on export, checked again on import
+	Format            string                        `yaml:"format"`            // Excel number format code for the data cells, e.g. "#,##0.00"
+	DateFormat        string                        `yaml:"date_format"`       // Excel format code for date values, e.g. "yyyy-mm-dd"
+	RichText          string                        `yaml:"rich_text"`         // Name of a registered RichTextFormatter
+	RichTextFormatter RichTextFormatter             `yaml:"-"`                 // Optional rich text formatter (Programmatic)
+
+	accessor func(reflect.Value) interface{} // Reads the value of typed columns, see AddTypedSection
 }
 
 // IsLocked returns whether this column should be locked.
@@ -154,15 +216,17 @@ func (s *SectionConfig) GetColumn(fieldName string) *ColumnConfig {
 
 // StyleTemplate defines basic styling.
 type StyleTemplate struct {
-	Font      *FontTemplate      `yaml:"font"`
-	Fill      *FillTemplate      `yaml:"fill"`
-	Alignment *AlignmentTemplate `yaml:"alignment"`
-	Locked    *bool              `yaml:"locked"`
+	Font         *FontTemplate      `yaml:"font"`
+	Fill         *FillTemplate      `yaml:"fill"`
+	Alignment    *AlignmentTemplate `yaml:"alignment"`
+	Locked       *bool              `yaml:"locked"`
+	NumberFormat string             `yaml:"number_format"` // Excel format code, e.g. "#,##0.00"
 }
 
 type AlignmentTemplate struct {
 	Horizontal string `yaml:"horizontal"` // center, left, right
 	Vertical   string `yaml:"vertical"`   // top, center, bottom
+	Indent     int    `yaml:"indent"`
 }
 
 type FontTemplate struct {
@@ -180,17 +244,26 @@ type FillTemplate struct {
 
 func NewExcelDataExporter() *ExcelDataExporter {
 	return &ExcelDataExporter{
-		data:            make(map[string]interface{}),
-		sheets:          []*SheetBuilder{},
-		formatters:      make(map[string]func(interface{}) interface{}),
-		sectionMetadata: make(map[string]SectionPlacement),
-		styleCache:      make(map[string]int),
-		colNameCache:    make(map[int]string),
-		fieldCache:      make(map[fieldCacheKey]int),
+		data:               make(map[string]interface{}),
+		sheets:             []*SheetBuilder{},
+		formatters:         make(map[string]CheckedFormatter),
+		richTextFormatters: make(map[string]RichTextFormatter),
+		sectionMetadata:    make(map[string]SectionPlacement),
+		styleCache:         make(map[string]int),
+		colNameCache:       make(map[int]string),
 	}
 }
 
 func NewExcelDataExporterFromYamlConfig(yamlConfig string) (*ExcelDataExporter, error) {
+	tmpl, err := parseTemplate(yamlConfig)
+	if err != nil {
+		return nil, err
+	}
+	return newExporterFromTemplate(tmpl), nil
+}
+
+// parseTemplate decodes and validates a YAML template.
+func parseTemplate(yamlConfig string) (*ReportTemplate, error) {
 	var tmpl ReportTemplate
 	if yamlConfig == "" {
 		return nil, fmt.Errorf("yaml config is empty")
@@ -198,46 +271,100 @@ func NewExcelDataExporterFromYamlConfig(yamlConfig string) (*ExcelDataExporter,
 	if err := yaml.Unmarshal([]byte(yamlConfig), &tmpl); err != nil {
 		return nil, fmt.Errorf("decode yaml: %w", err)
 	}
+	if err := tmpl.checkVersion(); err != nil {
+		return nil, err
+	}
+	for _, decl := range tmpl.Variables {
+		if err := decl.validate(); err != nil {
+			return nil, err
+		}
+	}
+	for i := range tmpl.Sheets {
+		if layout := tmpl.Sheets[i].Layout; layout != nil {
+			if err := layout.validate(tmpl.Sheets[i].Name); err != nil {
+				return nil, err
+			}
+		}
+		for j := range tmpl.Sheets[i].Sections {
+			sec := &tmpl.Sheets[i].Sections[j]
+			switch sec.Type {
+			case SectionTypeGantt:
+				if err := sec.Gantt.validate(sec); err != nil {
+					return nil, err
+				}
+			case SectionTypeOrgChart:
+				if err := sec.OrgChart.validate(sec); err != nil {
+					return nil, err
+				}
+			case SectionTypeHeatmap:
+				if err := sec.Heatmap.validate(sec); err != nil {
+					return nil, err
+				}
+			case SectionTypeCalendar:
+				if err := sec.Calendar.validate(sec); err != nil {
+					return nil, err
+				}
+			case SectionTypeLineChart:
+				if err := sec.LineChart.validate(sec); err != nil {
+					return nil, err
+				}
+			}
+			for k := range sec.Columns {
+				if rule := sec.Columns[k].Validation; rule != nil {
+					if err := rule.validate(sec, &sec.Columns[k]); err != nil {
+						return nil, err
+					}
+				}
+			}
+		}
+	}
+	return &tmpl, nil
+}
 
+// newExporterFromTemplate creates an exporter whose sheets point into tmpl.
+func newExporterFromTemplate(tmpl *ReportTemplate) *ExcelDataExporter {
 	exporter := &ExcelDataExporter{
-		template:        &tmpl,
-		data:            make(map[string]interface{}),
-		formatters:      make(map[string]func(interface{}) interface{}),
-		sheets:          make([]*SheetBuilder, 0),
-		sectionMetadata: make(map[string]SectionPlacement),
-		styleCache:      make(map[string]int),
-		colNameCache:    make(map[int]string),
-		fieldCache:      make(map[fieldCacheKey]int),
+		template:           tmpl,
+		data:               make(map[string]interface{}),
+		formatters:         make(map[string]CheckedFormatter),
+		richTextFormatters: make(map[string]RichTextFormatter),
+		sheets:             make([]*SheetBuilder, 0),
+		sectionMetadata:    make(map[string]SectionPlacement),
+		styleCache:         make(map[string]int),
+		colNameCache:       make(map[int]string),
 	}
 
 	// Initialize sheets from template
 	for i := range tmpl.Sheets {
 		sheetTmpl := &tmpl.Sheets[i]
 		sb := &SheetBuilder{
-			exporter: exporter,
-			name:     sheetTmpl.Name,
-			sections: make([]*SectionConfig, len(sheetTmpl.Sections)),
+			exporter:   exporter,
+			layout:     sheetTmpl.Layout,
+			protection: sheetTmpl.Protection,
+			sections:   make([]*SectionConfig, len(sheetTmpl.Sections)),
 		}
+		sb.setName(sheetTmpl.Name)
 		for j := range sheetTmpl.Sections {
 			sb.sections[j] = &sheetTmpl.Sections[j]
 		}
 		exporter.sheets = append(exporter.sheets, sb)
 	}
 
-	return exporter, nil
+	return exporter
 }
 
 // =============================================================================
 // Fluent API
 // =============================================================================
 
-// AddSheet starts a new sheet builder.
+// AddSheet starts a new sheet builder. Names Excel does not accept are
+// sanitized, see WarningSheetName.
 func (e *ExcelDataExporter) AddSheet(name string) *SheetBuilder {
 	sb := &SheetBuilder{
 		exporter: e,
-		name:     name,
 		sections: []*SectionConfig{},
 	}
+	sb.setName(name)
 	e.sheets = append(e.sheets, sb)
 	return sb
 }
@@ -251,10 +378,23 @@ func (e *ExcelDataExporter) BindSectionData(id string, data interface{}) *ExcelD
 // RegisterFormatter registers a formatter function with a name.
 // This allows referencing formatters by name in YAML configurations.
 func (e *ExcelDataExporter) RegisterFormatter(name string, f func(interface{}) interface{}) *ExcelDataExporter {
+	e.formatters[name] = uncheckedFormatter(f)
+	return e
+}
+
+// RegisterCheckedFormatter registers a formatter that can reject values.
+// Rejected cells keep their unformatted value and are listed by
+// FormatErrors after the export.
+func (e *ExcelDataExporter) RegisterCheckedFormatter(name string, f CheckedFormatter) *ExcelDataExporter {
 	e.formatters[name] = f
 	return e
 }
 
+// Template returns the parsed YAML template, or nil for programmatic exporters.
+func (e *ExcelDataExporter) Template() *ReportTemplate {
+	return e.template
+}
+
 // GetSheet returns a SheetBuilder by name, or nil if not found.
 func (e *ExcelDataExporter) GetSheet(name string) *SheetBuilder {
 	for _, sheet := range e.sheets {
@@ -292,8 +432,16 @@ func (e *ExcelDataExporter) GetSection(id string) *SectionConfig {
 // returning the generated excelize.File instance or an error// BuildExcel generates the excel file
 func (e *ExcelDataExporter) BuildExcel() (*excelize.File, error) {
 	f := excelize.NewFile()
+	e.beginExport(e.sheets)
+	if err := e.checkExport(e.sheets, false); err != nil {
+		return nil, err
+	}
+	e.arrangeSections(e.sheets)
 
-	// Process All Sheets (both fluent and YAML-initialized are now in e.sheets)
+	// Process All Sheets (both fluent and YAML-initialized are now in e.sheets).
+	// Every sheet is laid out before any is rendered, so comparison columns
+	// can refer to sections of later sheets.
+	plans := make([]*sheetPlan, len(e.sheets))
 	for i, sb := range e.sheets {
 		sheetName := sb.name
 		if i == 0 {
@@ -315,11 +463,27 @@ func (e *ExcelDataExporter) BuildExcel() (*excelize.File, error) {
 			}
 		}
 
-		if err := e.renderSections(f, sheetName, sb.sections); err != nil {
+		plans[i] = e.layoutSections(f, sheetName, e.expandRepeats(sb.sections))
+	}
+	for i, sb := range e.sheets {
+		if err := e.renderSections(f, plans[i], sb.protection); err != nil {
+			return nil, err
+		}
+		if err := e.applyLayout(f, sb, plans[i].sections); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := e.addFormatErrorComments(f); err != nil {
+		return nil, err
+	}
+	if len(e.checksumKey) > 0 {
+		if err := signLockedSections(f, e.checksumKey); err != nil {
 			return nil, err
 		}
 	}
 
+	e.finishExport(0)
 	return f, nil
 }
 
@@ -330,7 +494,13 @@ func (e *ExcelDataExporter) ExportToExcel(ctx context.Context, path string) erro
 		return err
 	}
 	defer f.Close()
-	return f.SaveAs(path)
+	if err := f.SaveAs(path); err != nil {
+		return err
+	}
+	if info, err := os.Stat(path); err == nil {
+		e.finishExport(info.Size())
+	}
+	return nil
 }
 
 // ToBytes exports the Excel file to an in-memory byte slice.
@@ -346,19 +516,39 @@ func (e *ExcelDataExporter) ToBytes() ([]byte, error) {
 	if _, err := f.WriteTo(buf); err != nil {
 		return nil, err
 	}
+	e.finishExport(int64(buf.Len()))
 	return buf.Bytes(), nil
 }
 
 // StartStream initializes a streaming export session.
 // It returns a Streamer which can be used to write data incrementally.
 func (e *ExcelDataExporter) StartStream(w io.Writer) (*Streamer, error) {
+	return e.StartStreamContext(context.Background(), w)
+}
+
+// StartStreamContext is StartStream failing writes once ctx is done. With
+// SetScratch the scratch directory is removed as soon as ctx is cancelled.
+func (e *ExcelDataExporter) StartStreamContext(ctx context.Context, w io.Writer) (*Streamer, error) {
 	// 1. Initialize File
+	e.beginExport(e.sheets)
+	if err := e.checkExport(e.sheets, true); err != nil {
+		return nil, err
+	}
+	e.arrangeSections(e.sheets)
+	scratch, err := e.openScratch(ctx)
+	if err != nil {
+		return nil, err
+	}
 	f := excelize.NewFile()
 	streamer := &Streamer{
 		exporter:      e,
+		ctx:           ctx,
 		file:          f,
 		writer:        w,
+		scratch:       scratch,
 		streamWriters: make(map[string]*excelize.StreamWriter),
+		samplers:      make(map[string]*streamSampler),
+		streamed:      make(map[string]bool),
 	}
 
 	// 2. Prepare Sheets
@@ -373,9 +563,24 @@ func (e *ExcelDataExporter) StartStream(w io.Writer) (*Streamer, error) {
 		// Initialize StreamWriter for this sheet
 		sw, err := f.NewStreamWriter(sheetName)
 		if err != nil {
+			streamer.Abort(err)
 			return nil, fmt.Errorf("failed to create stream writer for sheet %s: %w", sheetName, err)
 		}
 		streamer.streamWriters[sheetName] = sw
+
+		// Panes and widths must be set before the first row is written
+		if panes := sb.layout.panes(); panes != nil {
+			if err := sw.SetPanes(panes); err != nil {
+				streamer.Abort(err)
+				return nil, fmt.Errorf("sheet %s: freeze panes: %w", sheetName, err)
+			}
+		}
+		if sb.autoFits() {
+			streamer.samplers[sheetName] = &streamSampler{sheet: sb, widths: streamColumnWidths(sb), longest: make(map[int]int)}
+		} else if err := setStreamColWidths(sw, streamColumnWidths(sb)); err != nil {
+			streamer.Abort(err)
+			return nil, fmt.Errorf("sheet %s: column width: %w", sheetName, err)
+		}
 	}
 
 	// Prepare state
@@ -385,6 +590,7 @@ func (e *ExcelDataExporter) StartStream(w io.Writer) (*Streamer, error) {
 
 	// Initial processing (render static sections of first sheet)
 	if err := streamer.advanceToNextStreamingSection(); err != nil {
+		streamer.Abort(err)
 		return nil, err
 	}
 
@@ -393,13 +599,39 @@ func (e *ExcelDataExporter) StartStream(w io.Writer) (*Streamer, error) {
 
 // ToWriter exports the Excel file directly to a writer.
 func (e *ExcelDataExporter) ToWriter(w io.Writer) error {
+	return e.ToWriterContext(context.Background(), w)
+}
+
+// ToWriterContext is ToWriter spooling through the scratch directory set
+// with SetScratch, which is removed as soon as ctx is cancelled.
+func (e *ExcelDataExporter) ToWriterContext(ctx context.Context, w io.Writer) error {
 	f, err := e.BuildExcel()
 	if err != nil {
 		return err
 	}
 	defer f.Close()
+	scratch, err := e.openScratch(ctx)
+	if err != nil {
+		return err
+	}
 
-	return f.Write(w)
+	cw := &countingWriter{w: w}
+	if scratch == nil {
+		if err := f.Write(cw); err != nil {
+			return err
+		}
+		e.finishExport(cw.n)
+		return nil
+	}
+	defer scratch.remove()
+	if err := f.Write(scratch); err != nil {
+		return err
+	}
+	if err := scratch.copyTo(cw); err != nil {
+		return err
+	}
+	e.finishExport(cw.n).ScratchBytes = scratch.used
+	return nil
 }
 
 // ToCSV exports the first sheet of data to CSV format.
@@ -409,11 +641,16 @@ func (e *ExcelDataExporter) ToCSV(w io.Writer) error {
 		return fmt.Errorf("no sheets to export")
 	}
 
-	csvWriter := csv.NewWriter(w)
+	cw := &countingWriter{w: w}
+	csvWriter := csv.NewWriter(cw)
 	defer csvWriter.Flush()
-
 	sheet := e.sheets[0]
-	for _, sec := range sheet.sections {
+	e.beginExport(e.sheets[:1])
+	if err := e.checkExport(e.sheets[:1], false); err != nil {
+		return err
+	}
+	e.arrangeSections(e.sheets[:1])
+	for _, sec := range e.expandRepeats(sheet.sections) {
 		// Perform Late Binding if needed
 		if sec.ID != "" && sec.Data == nil {
 			if data, ok := e.data[sec.ID]; ok {
@@ -428,11 +665,12 @@ func (e *ExcelDataExporter) ToCSV(w io.Writer) error {
 		}
 
 		// Resolve columns
-		cols := mergeColumns(sec.Data, sec.Columns)
+		cols := mergeColumns(sec.Data, sec.Columns, sec.Order)
 
 		// Title (if single title only)
 		if sec.Title != nil {
 			_ = csvWriter.Write([]string{fmt.Sprintf("%v", sec.Title)})
+			e.countRows(1, 1)
 		}
 
 		// Header
@@ -444,6 +682,7 @@ func (e *ExcelDataExporter) ToCSV(w io.Writer) error {
 			if err := csvWriter.Write(headerArr); err != nil {
 				return err
 			}
+			e.countRows(1, len(headerArr))
 		}
 
 		// Data
@@ -457,20 +696,16 @@ func (e *ExcelDataExporter) ToCSV(w io.Writer) error {
 				item := v.Index(i)
 				rowArr := make([]string, len(cols))
 				for j, col := range cols {
-					val := e.extractValue(item, col.FieldName)
-					// Apply formatter if any
-					if col.Formatter != nil {
-						val = col.Formatter(val)
-					} else if col.FormatterName != "" && e.formatters != nil {
-						if fn, ok := e.formatters[col.FormatterName]; ok {
-							val = fn(val)
-						}
+					val := e.formatCell(sheet.name, "", sec, &col, e.columnValue(item, &col))
+					if lf := e.columnLocale(col); lf != nil {
+						val = lf.FormatText(val)
 					}
 					rowArr[j] = fmt.Sprintf("%v", val)
 				}
 				if err := csvWriter.Write(rowArr); err != nil {
 					return err
 				}
+				e.countRows(1, len(rowArr))
 			}
 		}
 
@@ -478,6 +713,11 @@ func (e *ExcelDataExporter) ToCSV(w io.Writer) error {
 		_ = csvWriter.Write([]string{""})
 	}
 
+	csvWriter.Flush()
+	if err := csvWriter.Error(); err != nil {
+		return err
+	}
+	e.finishExport(cw.n)
 	return nil
 }
 
@@ -488,7 +728,37 @@ func (e *ExcelDataExporter) ToCSV(w io.Writer) error {
 type SheetBuilder struct {
 	exporter *ExcelDataExporter
 	name     string
-	sections []*SectionConfig
+	// renamedFrom is the name given, when it had to be sanitized
+	renamedFrom string
+	layout      *LayoutTemplate
+	protection  *ProtectionTemplate
+	sections    []*SectionConfig
+}
+
+// maxSheetNameLen is the longest sheet name Excel accepts.
+const maxSheetNameLen = 31
+
+// setName sets the sheet name, replacing the characters Excel rejects with
+// "_" and cutting it to 31 characters.
+func (sb *SheetBuilder) setName(name string) {
+	clean := strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`:\/?*[]`, r) {
+			return '_'
+		}
+		return r
+	}, name)
+	if utf8.RuneCountInString(clean) > maxSheetNameLen {
+		clean = string([]rune(clean)[:maxSheetNameLen])
+	}
+	// Names may not start or end with an apostrophe
+	clean = strings.Trim(clean, "'")
+	if clean == "" {
+		clean = "Sheet"
+	}
+	sb.name = clean
+	if clean != name {
+		sb.renamedFrom = name
+	}
 }
 
 func (sb *SheetBuilder) AddSection(config *SectionConfig) *SheetBuilder {
@@ -544,15 +814,60 @@ func (e *ExcelDataExporter) getDataLength(sec *SectionConfig) int {
 	return 0
 }
 
-func (e *ExcelDataExporter) renderSections(f *excelize.File, sheet string, sections []*SectionConfig) error {
+// sheetPlan is the layout of the sections of a sheet computed by
+// layoutSections and drawn by renderSections.
+type sheetPlan struct {
+	sheet      string
+	sections   []*SectionConfig
+	placements []SectionPlacement
+	// Area of every section, anchored sections are placed next to theirs
+	boxes []sectionBox
+}
+
+// layoutSections places the sections of a sheet and records their placement,
+// so that compare_with columns of any sheet can refer to them.
+func (e *ExcelDataExporter) layoutSections(f *excelize.File, sheet string, sections []*SectionConfig) *sheetPlan {
 	t0 := time.Now()
 	// --- PASS 1: Layout Calculation ---
 	tempRow, tempCol := 1, 1
 	maxRowForPass1 := 1
 
 	placements := make([]SectionPlacement, len(sections))
+	// Size of custom sections, see SectionRenderer
+	customCols := make([]int, len(sections))
+	customRows := make([]int, len(sections))
+	layout := resolveLayout(sections)
+	boxes := make([]sectionBox, len(sections))
+
+	for _, i := range layout.order {
+		sec := sections[i]
+		if r, ok := sectionRenderer(sec); ok {
+			sCol, sRow := layout.position(sections, i, boxes, tempCol, tempRow)
+			customCols[i], customRows[i] = r.Size(&RenderContext{File: f, Sheet: sheet, Section: sec, Col: sCol, Row: sRow, exporter: e})
+			placements[i] = SectionPlacement{
+				SectionID:    sec.ID,
+				Sheet:        sheet,
+				StartRow:     sRow,
+				StartCol:     sCol,
+				FieldOffsets: make(map[string]int),
+				DataLen:      customRows[i],
+			}
+			if sec.ID != "" {
+				e.sectionMetadata[sec.ID] = placements[i]
+			}
+			e.countRows(customRows[i], customRows[i]*customCols[i])
+			finishRow := sRow + customRows[i]
+			if finishRow > maxRowForPass1 {
+				maxRowForPass1 = finishRow
+			}
+			if finishRow > tempRow {
+				tempRow = finishRow
+			}
+			tempCol = sCol + customCols[i]
+			boxes[i] = sectionBox{col: sCol, row: sRow, endCol: tempCol, endRow: finishRow}
+			continue
+		}
 
-	for i, sec := range sections {
 		// Determine section type
 		sectionType := sec.Type
 		if sectionType == "" {
@@ -560,10 +875,10 @@ func (e *ExcelDataExporter) renderSections(f *excelize.File, sheet string, secti
 		}
 
 		// Determine effective columns merging user config and data fields
-		sec.Columns = mergeColumns(sec.Data, sec.Columns)
+		sec.Columns = mergeColumns(sec.Data, sec.Columns, sec.Order)
 
 		// Determine start coordinates
-		sCol, sRow := calculatePosition(sec, tempCol, tempRow)
+		sCol, sRow := layout.position(sections, i, boxes, tempCol, tempRow)
 
 		// Calculate data start row by skipping Title, Hidden Row, and Header
 		dataStartRow := sRow
@@ -589,10 +904,14 @@ func (e *ExcelDataExporter) renderSections(f *excelize.File, sheet string, secti
 		}
 
 		// We need to know DataLen for Pass 1 to update tempRow/tempCol trackers accurately
-		dataLen := e.getDataLength(sec)
+		dataLen := 0
+		if sectionType != SectionTypeTitleOnly {
+			dataLen = e.getDataLength(sec)
+		}
 
 		placements[i] = SectionPlacement{
 			SectionID:    sec.ID,
+			Sheet:        sheet,
 			StartRow:     dataStartRow,
 			StartCol:     sCol,
 			FieldOffsets: fieldOffsets,
@@ -615,19 +934,25 @@ func (e *ExcelDataExporter) renderSections(f *excelize.File, sheet string, secti
 		// For horizontal tracking
 		colSpan := len(sec.Columns)
 		if sectionType == SectionTypeTitleOnly {
-			colSpan = sec.ColSpan
+			colSpan = max(sec.ColSpan, 1)
 			if colSpan <= 1 && len(sec.Columns) > 1 {
 				colSpan = len(sec.Columns)
 			}
 		}
+		e.countRows(finishRow-sRow, (finishRow-sRow)*max(colSpan, 1))
 		tempCol = sCol + colSpan
+		boxes[i] = sectionBox{col: sCol, row: sRow, endCol: tempCol, endRow: finishRow}
 	}
 	e.log("Pass 1 (Layout) took %v", time.Since(t0))
+	return &sheetPlan{sheet: sheet, sections: sections, placements: placements, boxes: boxes}
+}
 
+// renderSections draws the sections of a sheet where layoutSections placed them.
+func (e *ExcelDataExporter) renderSections(f *excelize.File, plan *sheetPlan, protection *ProtectionTemplate) error {
+	sheet, sections := plan.sheet, plan.sections
+	placements, boxes := plan.placements, plan.boxes
 	t1 := time.Now()
 	// --- PASS 2: Actual Rendering ---
-	maxRow := 1
-	nextColHorizontal := 1
 	hasLockedCells := false
 	hiddenRows := []int{}
 
@@ -658,10 +983,18 @@ func (e *ExcelDataExporter) renderSections(f *excelize.File, sheet string, secti
 	for i, sec := range sections {
 		placement := placements[i]
 
-		// Re-calculate sCol, sRow for Pass 2 (should match Pass 1)
-		sCol, sRow := calculatePosition(sec, nextColHorizontal, maxRow)
+		// Render where Pass 1 placed the section
+		sCol, sRow := boxes[i].col, boxes[i].row
 		currentRow := sRow
 
+		if r, ok := sectionRenderer(sec); ok {
+			rc := &RenderContext{File: f, Sheet: sheet, Section: sec, Col: sCol, Row: sRow, exporter: e}
+			if err := r.Render(rc); err != nil {
+				return fmt.Errorf("render section %s: %w", sec.ID, err)
+			}
+			continue
+		}
+
 		sectionType := sec.Type
 		if sectionType == "" {
 			sectionType = SectionTypeFull
@@ -692,19 +1025,7 @@ func (e *ExcelDataExporter) renderSections(f *excelize.File, sheet string, secti
 				if sec.TitleHeight > 0 {
 					f.SetRowHeight(sheet, currentRow, sec.TitleHeight)
 				}
-				currentRow++
 			}
-			if currentRow > maxRow {
-				maxRow = currentRow
-			}
-			colSpan := sec.ColSpan
-			if colSpan <= 1 && len(sec.Columns) > 1 {
-				colSpan = len(sec.Columns)
-			}
-			if colSpan <= 1 {
-				colSpan = 1
-			}
-			nextColHorizontal = sCol + colSpan
 			continue
 		}
 
@@ -785,6 +1106,11 @@ func (e *ExcelDataExporter) renderSections(f *excelize.File, sheet string, secti
 					defaultDataStyle = &StyleTemplate{Fill: &FillTemplate{Color: "FFFF00"}}
 				}
 				style := resolveStyle(sec.DataStyle, defaultDataStyle, locked)
+				if col.CompareWith != nil {
+					style = compareColumnStyle(style, col)
+				} else if dataVal.Kind() == reflect.Slice {
+					style = e.columnDataStyle(style, col, dataVal.Type().Elem())
+				}
 				styleID, _ := e.createStyle(f, style)
 				dataStyleIDs[j] = styleID
 				if col.Height > maxColHeight {
@@ -816,28 +1142,31 @@ func (e *ExcelDataExporter) renderSections(f *excelize.File, sheet string, secti
 				for j, col := range sec.Columns {
 					if col.CompareWith != nil {
 						// Formula
-						formula, err := e.generateDiffFormula(col, i)
+						formula, err := e.generateDiffFormula(sheet, col, i)
 						if err == nil {
 							rowFormulas = append(rowFormulas, docFormula{j, formula})
 						} else {
 							rowValues[j] = fmt.Sprintf("Error: %v", err)
 						}
 					} else if item.IsValid() {
-						val := e.extractValue(item, col.FieldName)
-						if col.Formatter != nil {
-							val = col.Formatter(val)
-						} else if col.FormatterName != "" {
-							if fmtFunc, ok := e.formatters[col.FormatterName]; ok {
-								val = fmtFunc(val)
-							}
-						}
-						rowValues[j] = val
+						cell := e.getCellAddress(sCol+j, currentRow)
+						rowValues[j] = e.limitCell(sheet, sec, &col, e.formatCell(sheet, cell, sec, &col, e.columnValue(item, &col)))
 					}
 				}
 
 				// Write ROW
 				startCell := e.getCellAddress(sCol, currentRow)
 				f.SetSheetRow(sheet, startCell, &rowValues)
+				if item.IsValid() {
+					for j := range sec.Columns {
+						if sec.Columns[j].CompareWith != nil {
+							continue
+						}
+						if err := e.setRichTextCell(f, sheet, e.getCellAddress(sCol+j, currentRow), &sec.Columns[j], rowValues[j]); err != nil {
+							return err
+						}
+					}
+				}
 
 				// Apply Formulas
 				for _, form := range rowFormulas {
@@ -864,10 +1193,16 @@ func (e *ExcelDataExporter) renderSections(f *excelize.File, sheet string, secti
 
 					// Apply style to the whole range
 					f.SetCellStyle(sheet, startCell, endCell, dataStyleIDs[j])
+					if err := e.highlightChanges(f, sheet, sec.Columns[j], sCol+j, dataStartRow, dataLen); err != nil {
+						return fmt.Errorf("section %s: highlight %s: %w", sec.ID, sec.Columns[j].FieldName, err)
+					}
 				}
 			}
 		}
 
+		recordSectionRange(f, sheet, sec.ID, sCol, placement.StartRow, len(sec.Columns), dataLen)
+		e.addDataValidations(f, sheet, sec, sCol, placement.StartRow, dataLen)
+
 		// Apply AutoFilter if requested
 		if sec.HasFilter && sec.ShowHeader && len(sec.Columns) > 0 {
 			headerRow := sRow
@@ -890,11 +1225,6 @@ func (e *ExcelDataExporter) renderSections(f *excelize.File, sheet string, secti
 				hiddenRows = append(hiddenRows, r)
 			}
 		}
-
-		if currentRow > maxRow {
-			maxRow = currentRow
-		}
-		nextColHorizontal = sCol + len(sec.Columns)
 	}
 	e.log("Pass 2 (Rendering) took %v", time.Since(t1))
 
@@ -903,27 +1233,14 @@ func (e *ExcelDataExporter) renderSections(f *excelize.File, sheet string, secti
 	}
 
 	if hasLockedCells {
-		f.ProtectSheet(sheet, &excelize.SheetProtectionOptions{
-			Password:            "",
-			FormatCells:         false,
-			FormatColumns:       true,
-			FormatRows:          true,
-			InsertColumns:       false,
-			InsertRows:          false,
-			InsertHyperlinks:    false,
-			DeleteColumns:       false,
-			DeleteRows:          false,
-			Sort:                false,
-			AutoFilter:          true,
-			PivotTables:         false,
-			SelectLockedCells:   true,
-			SelectUnlockedCells: true,
-		})
+		return protectSheet(f, sheet, protection)
 	}
 	return nil
 }
 
-func (e *ExcelDataExporter) resolveCellAddress(sectionID, fieldName string, rowOffset int) (string, error) {
+// resolveCellAddress returns the cell of a field of a section rowOffset rows
+// into its data, prefixed with the section's sheet when that is not sheet.
+func (e *ExcelDataExporter) resolveCellAddress(sheet, sectionID, fieldName string, rowOffset int) (string, error) {
 	placement, ok := e.sectionMetadata[sectionID]
 	if !ok {
 		return "", fmt.Errorf("section %s not found", sectionID)
@@ -935,25 +1252,46 @@ func (e *ExcelDataExporter) resolveCellAddress(sectionID, fieldName string, rowO
 	}
 
 	// StartRow in metadata should point to the first row of DATA
-	return excelize.CoordinatesToCellName(placement.StartCol+colOffset, placement.StartRow+rowOffset)
+	cell, err := excelize.CoordinatesToCellName(placement.StartCol+colOffset, placement.StartRow+rowOffset)
+	if err != nil || placement.Sheet == "" || placement.Sheet == sheet {
+		return cell, err
+	}
+	return sheetReference(placement.Sheet) + "!" + cell, nil
 }
 
-func (e *ExcelDataExporter) generateDiffFormula(col ColumnConfig, rowOffset int) (string, error) {
+// sheetReference returns a sheet name as formulas refer to it. Names with
+// anything but letters and underscores are quoted, so that names with spaces
+// or looking like cells or booleans are read as sheets.
+func sheetReference(name string) string {
+	plain := name != "" && !strings.EqualFold(name, "TRUE") && !strings.EqualFold(name, "FALSE")
+	for _, r := range name {
+		if !(r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')) {
+			plain = false
+			break
+		}
+	}
+	if plain {
+		return name
+	}
+	return "'" + strings.ReplaceAll(name, "'", "''") + "'"
+}
+
+func (e *ExcelDataExporter) generateDiffFormula(sheet string, col ColumnConfig, rowOffset int) (string, error) {
 	if col.CompareWith == nil {
 		return "", nil
 	}
 
-	cellA, err := e.resolveCellAddress(col.CompareWith.SectionID, col.CompareWith.FieldName, rowOffset)
+	cellA, err := e.resolveCellAddress(sheet, col.CompareWith.SectionID, col.CompareWith.FieldName, rowOffset)
 	if err != nil {
 		return "", err
 	}
 
 	if col.CompareAgainst != nil {
-		cellB, err := e.resolveCellAddress(col.CompareAgainst.SectionID, col.CompareAgainst.FieldName, rowOffset)
+		cellB, err := e.resolveCellAddress(sheet, col.CompareAgainst.SectionID, col.CompareAgainst.FieldName, rowOffset)
 		if err != nil {
 			return "", err
 		}
-		return fmt.Sprintf(`IF(%s<>%s, %s, "")`, cellA, cellB, cellA), nil
+		return compareFormula(col.CompareWith.Op, cellA, cellB)
 	}
 
 	// Default comparison is not specified in the plan but let's assume it compares with something else if CompareAgainst is nil?
@@ -1035,11 +1373,14 @@ func (e *ExcelDataExporter) createStyle(f *excelize.File, tmpl *StyleTemplate) (
 		fmt.Fprintf(&sb, "i:%s|", tmpl.Fill.Color)
 	}
 	if tmpl.Alignment != nil {
-		fmt.Fprintf(&sb, "a:%s:%s|", tmpl.Alignment.Horizontal, tmpl.Alignment.Vertical)
+		fmt.Fprintf(&sb, "a:%s:%s:%d|", tmpl.Alignment.Horizontal, tmpl.Alignment.Vertical, tmpl.Alignment.Indent)
 	}
 	if tmpl.Locked != nil {
 		fmt.Fprintf(&sb, "l:%v|", *tmpl.Locked)
 	}
+	if tmpl.NumberFormat != "" {
+		fmt.Fprintf(&sb, "n:%s|", tmpl.NumberFormat)
+	}
 	key := sb.String()
 
 	if id, ok := e.styleCache[key]; ok {
@@ -1064,6 +1405,7 @@ func (e *ExcelDataExporter) createStyle(f *excelize.File, tmpl *StyleTemplate) (
 		style.Alignment = &excelize.Alignment{
 			Horizontal: tmpl.Alignment.Horizontal,
 			Vertical:   tmpl.Alignment.Vertical,
+			Indent:     tmpl.Alignment.Indent,
 		}
 	}
 	if tmpl.Locked != nil {
@@ -1071,6 +1413,10 @@ func (e *ExcelDataExporter) createStyle(f *excelize.File, tmpl *StyleTemplate) (
 			Locked: *tmpl.Locked,
 		}
 	}
+	if tmpl.NumberFormat != "" {
+		numFmt := tmpl.NumberFormat
+		style.CustomNumFmt = &numFmt
+	}
 	id, err := f.NewStyle(style)
 	if err == nil {
 		e.styleCache[key] = id
@@ -1079,24 +1425,15 @@ func (e *ExcelDataExporter) createStyle(f *excelize.File, tmpl *StyleTemplate) (
 }
 
 func (e *ExcelDataExporter) extractValue(item reflect.Value, fieldName string) interface{} {
+	if !item.IsValid() {
+		return ""
+	}
+	info := typeInfoOf(item.Type())
+	if m, ok := asRowMarshaler(item, info.marshaler); ok {
+		return m.ExcelCell(fieldName)
+	}
 	if item.Kind() == reflect.Struct {
-		t := item.Type()
-		key := fieldCacheKey{Type: t, FieldName: fieldName}
-		index, ok := e.fieldCache[key]
-		if !ok {
-			f, found := t.FieldByName(fieldName)
-			if found {
-				index = f.Index[0]
-				e.fieldCache[key] = index
-			} else {
-				e.fieldCache[key] = -1 // Not found
-				return ""
-			}
-		}
-
-		if index != -1 {
-			return item.Field(index).Interface()
-		}
+		return info.fieldValue(item, fieldName)
 	} else if item.Kind() == reflect.Map {
 		val := item.MapIndex(reflect.ValueOf(fieldName))
 		if val.IsValid() {
@@ -1107,14 +1444,16 @@ func (e *ExcelDataExporter) extractValue(item reflect.Value, fieldName string) i
 }
 
 // mergeColumns merges user-defined columns with detected fields from data.
-// It prioritizes user-defined columns, then appends remaining detected fields.
-func mergeColumns(data interface{}, userConfigs []ColumnConfig) []ColumnConfig {
-	if data == nil {
+// It prioritizes user-defined columns, then appends remaining detected fields:
+// those listed in order first, then the others in struct declaration order or
+// sorted map keys. Typed columns, see AddTypedSection, are kept as declared.
+func mergeColumns(data interface{}, userConfigs []ColumnConfig, order []string) []ColumnConfig {
+	if data == nil || typedColumns(userConfigs) {
 		return userConfigs
 	}
 
 	// 1. Detect all fields from data
-	detectedFields := getFields(data)
+	detectedFields := orderFields(getFields(data), order)
 
 	// 2. Index user configs by FieldName for O(1) lookup
 	userConfigMap := make(map[string]ColumnConfig)
@@ -1156,7 +1495,7 @@ func getFields(data interface{}) []string {
 		// For now assume slice as per assumed usage, or standard usage.
 		// If it's a single struct, we can treat it as one item.
 		if v.Kind() == reflect.Struct {
-			return getStructFields(v.Type())
+			return typeInfoOf(v.Type()).fields
 		}
 		return nil
 	}
@@ -1172,7 +1511,7 @@ func getFields(data interface{}) []string {
 	}
 
 	if elem.Kind() == reflect.Struct {
-		return getStructFields(elem.Type())
+		return typeInfoOf(elem.Type()).fields
 	} else if elem.Kind() == reflect.Map {
 		// Collect keys from all maps? Or just first?
 		// Collecting from all is safer but slower.
@@ -1207,21 +1546,35 @@ func getFields(data interface{}) []string {
 				}
 			}
 		}
+		// Map iteration order is random, keep exports byte-stable
+		sort.Strings(keys)
 		return keys
 	}
 
 	return nil
 }
 
-func getStructFields(t reflect.Type) []string {
-	var fields []string
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		// Skip unexported
-		if field.PkgPath != "" {
-			continue
+// orderFields moves the fields listed in order to the front, in that order.
+// Listed fields missing from fields are ignored.
+func orderFields(fields, order []string) []string {
+	if len(order) == 0 {
+		return fields
+	}
+	present := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		present[f] = true
+	}
+	ordered := make([]string, 0, len(fields))
+	for _, f := range order {
+		if present[f] {
+			ordered = append(ordered, f)
+			present[f] = false
+		}
+	}
+	for _, f := range fields {
+		if present[f] {
+			ordered = append(ordered, f)
 		}
-		fields = append(fields, field.Name)
 	}
-	return fields
+	return ordered
 }
@@ -0,0 +1,104 @@
+package simpleexcelv2
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/faultinject"
+)
+
+// ExportResult describes an export, for logging and metrics. Rows counts
+// every row the sections wrote, titles and headers included, and Cells the
+// cells of those rows.
+type ExportResult struct {
+	Sheets   int
+	Sections int
+	Rows     int
+	Cells    int
+	Duration time.Duration
+	// Bytes is the size of the output, 0 for BuildExcel which writes none
+	Bytes int64
+	// ScratchBytes is the size of the file spooled to the scratch
+	// directory, 0 without SetScratch
+	ScratchBytes int64
+	// Warnings lists what went wrong without failing the export: the
+	// exporter's Warnings and FormatErrors
+	Warnings []string
+}
+
+// Export writes the workbook to w like ToWriter and returns its statistics.
+func (e *ExcelDataExporter) Export(w io.Writer) (*ExportResult, error) {
+	if err := e.ToWriter(w); err != nil {
+		return nil, err
+	}
+	return e.result, nil
+}
+
+// Result returns the statistics of the last export: BuildExcel, ToWriter,
+// ToBytes, ToCSV or a stream once closed. It is nil before the first one.
+func (e *ExcelDataExporter) Result() *ExportResult {
+	return e.result
+}
+
+// beginExport resets the per-export state, sheets and sections are the
+// ones the export covers.
+func (e *ExcelDataExporter) beginExport(sheets []*SheetBuilder) {
+	e.formatErrors = nil
+	e.truncated = make(map[string]bool)
+	e.exportStart = time.Now()
+	e.result = &ExportResult{Sheets: len(sheets)}
+	for _, sb := range sheets {
+		e.result.Sections += len(sb.sections)
+	}
+}
+
+// countRows adds written rows and their cells to the result.
+func (e *ExcelDataExporter) countRows(rows, cells int) {
+	if e.result == nil {
+		return
+	}
+	e.result.Rows += rows
+	e.result.Cells += cells
+}
+
+// finishExport completes the result once written bytes were output. It may
+// run again when the built workbook is written afterwards.
+func (e *ExcelDataExporter) finishExport(written int64) *ExportResult {
+	r := e.result
+	r.Duration = time.Since(e.exportStart)
+	r.Bytes = written
+	r.ScratchBytes = 0
+	r.Warnings = nil
+	for _, w := range e.warnings {
+		r.Warnings = append(r.Warnings, w.String())
+	}
+	for i := range e.formatErrors {
+		r.Warnings = append(r.Warnings, e.formatErrors[i].Error())
+	}
+	return r
+}
+
+// Fault injection points, see package faultinject
+const (
+	// FaultWrite fails writes of the exported file, after Keep bytes
+	FaultWrite = "simpleexcelv2.write"
+	// FaultStreamBatch fails batches written to a Streamer, after Keep rows
+	FaultStreamBatch = "simpleexcelv2.stream_batch"
+)
+
+// countingWriter counts the bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	keep, faultErr := faultinject.Batch(context.Background(), FaultWrite, len(p))
+	n, err := c.w.Write(p[:keep])
+	c.n += int64(n)
+	if err == nil {
+		err = faultErr
+	}
+	return n, err
+}
@@ -0,0 +1,115 @@
+package simpleexcelv2
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+const resultTemplate = `
+sheets:
+  - name: "Staff"
+    sections:
+      - id: "intro"
+        type: "title"
+        title: "Staff list"
+      - id: "staff"
+        title: "Employees"
+        show_header: true
+        columns:
+          - field_name: "Name"
+          - field_name: "Salary"
+  - name: "Notes"
+    sections:
+      - id: "notes"
+        show_header: true
+        columns:
+          - field_name: "Name"
+`
+
+func TestExportResult_Buffered(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(resultTemplate)
+	require.NoError(t, err)
+	assert.Nil(t, exporter.Result())
+	exporter.BindSectionData("staff", []salaryRow{{"Ann", 5000}, {"Bob", 4000}, {"Cid", 3000}})
+	exporter.BindSectionData("notes", []salaryRow{{"Ann", nil}})
+
+	var buf bytes.Buffer
+	result, err := exporter.Export(&buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, result.Sheets)
+	assert.Equal(t, 3, result.Sections)
+	// Title row; title, header and 3 data rows of 2 cells; header and 1 row
+	// of the 2 cells merged from the data's fields
+	assert.Equal(t, 1+5+2, result.Rows)
+	assert.Equal(t, 1+10+4, result.Cells)
+	assert.Equal(t, int64(buf.Len()), result.Bytes)
+	assert.Positive(t, result.Duration)
+	assert.Empty(t, result.Warnings)
+	assert.Same(t, result, exporter.Result())
+}
+
+func TestExportResult_Warnings(t *testing.T) {
+	exporter := newSalaryExporter(t)
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+	defer f.Close()
+
+	result := exporter.Result()
+	require.NotNil(t, result)
+	assert.Zero(t, result.Bytes, "BuildExcel writes no output")
+	assert.Equal(t, []string{
+		"Pay!B4: format Salary value n/a: expected an int, got string",
+		"Pay!B5: format Salary value 4.5: expected an int, got float64",
+	}, result.Warnings)
+}
+
+func TestExportResult_Streamed(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(resultTemplate)
+	require.NoError(t, err)
+	exporter.BindSectionData("notes", []salaryRow{{"Ann", nil}})
+
+	var buf bytes.Buffer
+	streamer, err := exporter.StartStream(&buf)
+	require.NoError(t, err)
+	require.NoError(t, streamer.Write("staff", []salaryRow{{"Ann", 5000}, {"Bob", 4000}}))
+	require.NoError(t, streamer.Write("staff", []salaryRow{{"Cid", 3000}}))
+	require.NoError(t, streamer.Close())
+
+	result := exporter.Result()
+	assert.Equal(t, 2, result.Sheets)
+	assert.Equal(t, 3, result.Sections)
+	assert.Equal(t, int64(buf.Len()), result.Bytes)
+
+	f, err := excelize.OpenReader(&buf)
+	require.NoError(t, err)
+	defer f.Close()
+	rows := 0
+	for _, sheet := range f.GetSheetList() {
+		sheetRows, err := f.GetRows(sheet)
+		require.NoError(t, err)
+		rows += len(sheetRows)
+	}
+	assert.Equal(t, rows, result.Rows)
+}
+
+func TestExportResult_CSV(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(resultTemplate)
+	require.NoError(t, err)
+	exporter.BindSectionData("staff", []salaryRow{{"Ann", 5000}})
+
+	var buf bytes.Buffer
+	require.NoError(t, exporter.ToCSV(&buf))
+
+	result := exporter.Result()
+	assert.Equal(t, 1, result.Sheets)
+	assert.Equal(t, 2, result.Sections)
+	assert.Equal(t, int64(buf.Len()), result.Bytes)
+	// The empty title-only section is left out
+	assert.Equal(t, 3, result.Rows)
+}
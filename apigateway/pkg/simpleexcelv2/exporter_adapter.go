@@ -0,0 +1,63 @@
+package simpleexcelv2
+
+import (
+	"io"
+
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/exporter"
+)
+
+// AsExporter adapts the exporter to the engine-neutral exporter.Exporter.
+func (e *ExcelDataExporter) AsExporter() exporter.Exporter {
+	return &exporterAdapter{e: e}
+}
+
+type exporterAdapter struct {
+	e *ExcelDataExporter
+}
+
+func (a *exporterAdapter) AddSheet(name string) exporter.Sheet {
+	return &sheetAdapter{sb: a.e.AddSheet(name)}
+}
+
+func (a *exporterAdapter) Bind(sectionID string, data interface{}) exporter.Exporter {
+	a.e.BindSectionData(sectionID, data)
+	return a
+}
+
+func (a *exporterAdapter) Export(w io.Writer) error {
+	return a.e.ToWriter(w)
+}
+
+func (a *exporterAdapter) Stream(w io.Writer) (exporter.Streamer, error) {
+	streamer, err := a.e.StartStream(w)
+	if err != nil {
+		return nil, err
+	}
+	return streamer, nil
+}
+
+type sheetAdapter struct {
+	sb *SheetBuilder
+}
+
+func (s *sheetAdapter) AddSection(sec *exporter.Section) exporter.Sheet {
+	config := &SectionConfig{
+		ID:         sec.ID,
+		ShowHeader: sec.ShowHeader,
+		Locked:     sec.Locked,
+		Data:       sec.Data,
+	}
+	if sec.Title != "" {
+		config.Title = sec.Title
+	}
+	for _, col := range sec.Columns {
+		config.Columns = append(config.Columns, ColumnConfig{
+			FieldName:     col.FieldName,
+			Header:        col.Header,
+			Width:         col.Width,
+			FormatterName: col.FormatterName,
+		})
+	}
+	s.sb.AddSection(config)
+	return s
+}
@@ -0,0 +1,52 @@
+//go:build faultinject
+
+package simpleexcelv2
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/faultinject"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errDiskFull = errors.New("disk full")
+
+func faultExporter() *ExcelDataExporter {
+	exporter := NewExcelDataExporter()
+	exporter.AddSheet("Staff").AddSection(&SectionConfig{
+		ID:         "staff",
+		ShowHeader: true,
+		Columns:    []ColumnConfig{{FieldName: "Name", Header: "Name"}},
+	})
+	return exporter
+}
+
+func TestFaultWrite(t *testing.T) {
+	defer faultinject.Enable(FaultWrite, faultinject.Fault{Err: errDiskFull, Keep: 100})()
+	exporter := faultExporter()
+	exporter.BindSectionData("staff", []warnRow{{"Ann", "d001"}})
+
+	var buf bytes.Buffer
+	err := exporter.ToWriter(&buf)
+	assert.ErrorIs(t, err, errDiskFull)
+	assert.Equal(t, 100, buf.Len(), "bytes before the fault are written")
+}
+
+func TestFaultStreamBatch(t *testing.T) {
+	defer faultinject.Enable(FaultStreamBatch, faultinject.Fault{Err: errDiskFull, Keep: 1, Skip: 1})()
+	exporter := faultExporter()
+
+	var buf bytes.Buffer
+	streamer, err := exporter.StartStream(&buf)
+	require.NoError(t, err)
+	require.NoError(t, streamer.Write("staff", []warnRow{{"Ann", "d001"}}))
+
+	// The second batch is cut after its first row
+	err = streamer.Write("staff", []warnRow{{"Bob", "d002"}, {"Cid", "d003"}})
+	assert.ErrorIs(t, err, errDiskFull)
+	streamer.Abort(err)
+	assert.Equal(t, 3, exporter.Result().Rows, "header and the rows written before the fault")
+}
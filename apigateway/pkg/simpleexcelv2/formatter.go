@@ -0,0 +1,375 @@
+package simpleexcelv2
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// FormatterChain lists formatter names applied in order. In YAML the
+// `formatter` key takes a single name or a list:
+//
+//	columns:
+//	  - field_name: "Name"
+//	    formatter: [trim, upper]
+//	  - field_name: "Salary"
+//	    formatter: currency_vnd
+//
+// A name is looked up in the formatters registered with RegisterFormatter,
+// then in the built-ins. Built-ins taking an argument put it after a colon,
+// e.g. "truncate:20" or "date:02/01/2006". Unknown names are skipped.
+type FormatterChain []string
+
+// UnmarshalYAML decodes a single formatter name or a list of names.
+func (c *FormatterChain) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var name string
+	if err := unmarshal(&name); err == nil {
+		*c = nil
+		if name != "" {
+			*c = FormatterChain{name}
+		}
+		return nil
+	}
+	var names []string
+	if err := unmarshal(&names); err != nil {
+		return err
+	}
+	*c = names
+	return nil
+}
+
+// ComposeFormatters returns a formatter applying fs in order, each to the
+// result of the previous one. Nil formatters are skipped.
+func ComposeFormatters(fs ...func(interface{}) interface{}) func(interface{}) interface{} {
+	return func(v interface{}) interface{} {
+		for _, f := range fs {
+			if f != nil {
+				v = f(v)
+			}
+		}
+		return v
+	}
+}
+
+// builtinFormatters build the built-in formatters from their argument, the
+// text after the colon in "name:arg" ("" without one). They return nil for
+// an invalid argument.
+var builtinFormatters = map[string]func(arg string) func(interface{}) interface{}{
+	"trim":  stringFormatter(strings.TrimSpace),
+	"upper": stringFormatter(strings.ToUpper),
+	"lower": stringFormatter(strings.ToLower),
+	"currency": func(arg string) func(interface{}) interface{} {
+		if arg == "" {
+			arg = "USD"
+		}
+		return currencyFormatter(arg)
+	},
+	"currency_usd": func(string) func(interface{}) interface{} { return currencyFormatter("USD") },
+	"currency_eur": func(string) func(interface{}) interface{} { return currencyFormatter("EUR") },
+	"currency_vnd": func(string) func(interface{}) interface{} { return currencyFormatter("VND") },
+	"percent":      percentFormatter,
+	"date":         dateFormatter,
+	"mask":         maskFormatter,
+	"truncate":     truncateFormatter,
+}
+
+// currencies are the currencies known to the currency formatters.
+var currencies = map[string]struct {
+	symbol   string
+	suffix   bool // symbol after the amount
+	decimals int
+	locale   string
+}{
+	"USD": {symbol: "$", decimals: 2, locale: "en-US"},
+	"EUR": {symbol: "€", suffix: true, decimals: 2, locale: "de-DE"},
+	"VND": {symbol: "₫", suffix: true, decimals: 0, locale: "vi-VN"},
+}
+
+// CheckedFormatter is a formatter that reports values it cannot format, e.g.
+// a value of an unexpected type, instead of passing them through silently.
+// Register it with RegisterCheckedFormatter.
+type CheckedFormatter func(v interface{}) (interface{}, error)
+
+// FormatError is a value a CheckedFormatter rejected. The cell keeps the
+// value as it was before the column's formatters ran.
+type FormatError struct {
+	Sheet   string
+	Cell    string // e.g. "C5", empty for CSV output
+	Section string
+	Field   string
+	Value   interface{}
+	Err     error
+}
+
+func (e *FormatError) Error() string {
+	at := e.Sheet
+	if e.Cell != "" {
+		at += "!" + e.Cell
+	}
+	return fmt.Sprintf("%s: format %s value %v: %v", at, e.Field, e.Value, e.Err)
+}
+
+func (e *FormatError) Unwrap() error {
+	return e.Err
+}
+
+// FormatErrors returns the values rejected by checked formatters during the
+// last export, in the order the cells were written. Streamed exports collect
+// them until Close.
+func (e *ExcelDataExporter) FormatErrors() []FormatError {
+	return e.formatErrors
+}
+
+// SetFormatErrorComments adds a comment with the error to every cell whose
+// value a checked formatter rejected, in xlsx output.
+func (e *ExcelDataExporter) SetFormatErrorComments(enabled bool) *ExcelDataExporter {
+	e.formatErrorComments = enabled
+	return e
+}
+
+// formatValue applies the column's formatter to a value: the Formatter func
+// when set, else FormatterName followed by the Formatters chain. Formatter
+// errors are ignored, use formatCell where they are reported.
+func (e *ExcelDataExporter) formatValue(col *ColumnConfig, val interface{}) interface{} {
+	out, err := e.formatChecked(col, val)
+	if err != nil {
+		return val
+	}
+	return out
+}
+
+// formatCell formats the value of a cell, recording a FormatError and
+// keeping the unformatted value if a checked formatter rejects it.
+func (e *ExcelDataExporter) formatCell(sheet, cell string, sec *SectionConfig, col *ColumnConfig, val interface{}) interface{} {
+	out, err := e.formatChecked(col, val)
+	if err != nil {
+		e.formatErrors = append(e.formatErrors, FormatError{Sheet: sheet, Cell: cell, Section: sec.ID, Field: col.FieldName, Value: val, Err: err})
+		return val
+	}
+	return out
+}
+
+// formatChecked runs the column's formatters, stopping at the first error.
+func (e *ExcelDataExporter) formatChecked(col *ColumnConfig, val interface{}) (interface{}, error) {
+	if col.Formatter != nil {
+		return col.Formatter(val), nil
+	}
+	var err error
+	if col.FormatterName != "" {
+		if val, err = e.lookupFormatter(col.FormatterName)(val); err != nil {
+			return nil, err
+		}
+	}
+	for _, name := range col.Formatters {
+		if val, err = e.lookupFormatter(name)(val); err != nil {
+			return nil, err
+		}
+	}
+	return val, nil
+}
+
+// addFormatErrorComments comments the cells of the recorded format errors
+// when SetFormatErrorComments is enabled.
+func (e *ExcelDataExporter) addFormatErrorComments(f *excelize.File) error {
+	if !e.formatErrorComments {
+		return nil
+	}
+	for _, fe := range e.formatErrors {
+		if fe.Cell == "" {
+			continue
+		}
+		if err := f.AddComment(fe.Sheet, excelize.Comment{Cell: fe.Cell, Author: "simpleexcel", Text: fe.Err.Error()}); err != nil {
+			return fmt.Errorf("comment format error at %s!%s: %w", fe.Sheet, fe.Cell, err)
+		}
+	}
+	return nil
+}
+
+// namedFormatter resolves a formatter name, unknown names give the identity.
+// Values a checked formatter rejects are returned unchanged.
+func (e *ExcelDataExporter) namedFormatter(name string) func(interface{}) interface{} {
+	fn := e.lookupFormatter(name)
+	return func(v interface{}) interface{} {
+		out, err := fn(v)
+		if err != nil {
+			return v
+		}
+		return out
+	}
+}
+
+// lookupFormatter resolves a formatter name to the registered formatter or
+// the built-in, unknown names give the identity.
+func (e *ExcelDataExporter) lookupFormatter(name string) CheckedFormatter {
+	if fn, ok := e.findFormatter(name); ok {
+		return fn
+	}
+	return func(v interface{}) (interface{}, error) { return v, nil }
+}
+
+// findFormatter resolves a formatter name to the registered formatter or
+// the built-in, reporting whether it is known.
+func (e *ExcelDataExporter) findFormatter(name string) (CheckedFormatter, bool) {
+	if fn, ok := e.formatters[name]; ok {
+		return fn, true
+	}
+	base, arg := name, ""
+	if i := strings.Index(name, ":"); i >= 0 {
+		base, arg = name[:i], name[i+1:]
+	}
+	if build, ok := builtinFormatters[base]; ok {
+		if fn := build(arg); fn != nil {
+			return uncheckedFormatter(fn), true
+		}
+	}
+	return nil, false
+}
+
+// uncheckedFormatter adapts a formatter that never fails.
+func uncheckedFormatter(fn func(interface{}) interface{}) CheckedFormatter {
+	return func(v interface{}) (interface{}, error) { return fn(v), nil }
+}
+
+// stringFormatter applies fn to string values, other values are unchanged.
+func stringFormatter(fn func(string) string) func(string) func(interface{}) interface{} {
+	return func(string) func(interface{}) interface{} {
+		return func(v interface{}) interface{} {
+			if s, ok := v.(string); ok {
+				return fn(s)
+			}
+			return v
+		}
+	}
+}
+
+// currencyFormatter shows numbers as amounts of a currency code, e.g.
+// "$1,234.50" or "1.234.500 ₫".
+func currencyFormatter(code string) func(interface{}) interface{} {
+	cur, ok := currencies[strings.ToUpper(code)]
+	if !ok {
+		return nil
+	}
+	lf, _ := LookupLocale(cur.locale)
+	return func(v interface{}) interface{} {
+		f, ok := toFloat(v)
+		if !ok || math.IsNaN(f) || math.IsInf(f, 0) {
+			return v
+		}
+		sign := ""
+		if f < 0 {
+			sign, f = "-", -f
+		}
+		s := strconv.FormatFloat(f, 'f', cur.decimals, 64)
+		intPart, frac := s, ""
+		if cur.decimals > 0 {
+			intPart, frac = s[:len(s)-cur.decimals-1], s[len(s)-cur.decimals:]
+		}
+		amount := lf.groupDigits(intPart, frac)
+		if cur.suffix {
+			return sign + amount + " " + cur.symbol
+		}
+		return sign + cur.symbol + amount
+	}
+}
+
+// percentFormatter shows ratios as percentages, 0.256 is "26%", or "25.6%"
+// with "percent:1".
+func percentFormatter(arg string) func(interface{}) interface{} {
+	decimals := 0
+	if arg != "" {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 {
+			return nil
+		}
+		decimals = n
+	}
+	return func(v interface{}) interface{} {
+		f, ok := toFloat(v)
+		if !ok {
+			return v
+		}
+		return strconv.FormatFloat(f*100, 'f', decimals, 64) + "%"
+	}
+}
+
+// dateFormatter formats dates with a Go layout, YYYY-MM-DD by default. Nil
+// *time.Time values give nil, an empty cell.
+func dateFormatter(layout string) func(interface{}) interface{} {
+	if layout == "" {
+		layout = VariableDateLayout
+	}
+	return func(v interface{}) interface{} {
+		switch t := v.(type) {
+		case time.Time:
+			return t.Format(layout)
+		case *time.Time:
+			if t == nil {
+				return nil
+			}
+			return t.Format(layout)
+		}
+		return v
+	}
+}
+
+// maskFormatter replaces all but the last characters of a value with "*",
+// keeping 4 by default or the number given, e.g. "mask:2".
+func maskFormatter(arg string) func(interface{}) interface{} {
+	keep := 4
+	if arg != "" {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 {
+			return nil
+		}
+		keep = n
+	}
+	return func(v interface{}) interface{} {
+		text, ok := maskText(v)
+		if !ok {
+			return v
+		}
+		runes := []rune(text)
+		for i := 0; i < len(runes)-keep; i++ {
+			runes[i] = '*'
+		}
+		return string(runes)
+	}
+}
+
+// truncateFormatter cuts strings longer than the given number of characters,
+// ending them with "…", e.g. "truncate:20". The length is required.
+func truncateFormatter(arg string) func(interface{}) interface{} {
+	n, err := strconv.Atoi(arg)
+	if err != nil || n < 1 {
+		return nil
+	}
+	return func(v interface{}) interface{} {
+		s, ok := v.(string)
+		if !ok || utf8.RuneCountInString(s) <= n {
+			return v
+		}
+		return string([]rune(s)[:n-1]) + "…"
+	}
+}
+
+// maskText returns the text of a value for masking, dereferencing pointers;
+// ok is false for nil values.
+func maskText(v interface{}) (string, bool) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return "", false
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return "", false
+	}
+	return fmt.Sprint(rv.Interface()), true
+}
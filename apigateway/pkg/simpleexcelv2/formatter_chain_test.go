@@ -0,0 +1,100 @@
+package simpleexcelv2
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuiltinFormatters(t *testing.T) {
+	e := NewExcelDataExporter()
+	day := time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name string
+		in   interface{}
+		want interface{}
+	}{
+		{"trim", "  Ann ", "Ann"},
+		{"upper", "ann", "ANN"},
+		{"lower", "ANN", "ann"},
+		{"currency", 1234.5, "$1,234.50"},
+		{"currency:EUR", -1234.5, "-1.234,50 €"},
+		{"currency_vnd", 1234500, "1.234.500 ₫"},
+		{"percent", 0.256, "26%"},
+		{"percent:1", 0.256, "25.6%"},
+		{"date", day, "2024-03-05"},
+		{"date:02/01/2006", &day, "05/03/2024"},
+		{"date", (*time.Time)(nil), nil},
+		{"mask", "0123456789", "******6789"},
+		{"mask:2", 10042, "***42"},
+		{"truncate:5", "Engineering", "Engi…"},
+		{"truncate:20", "Sales", "Sales"},
+		// Not applicable or invalid: unchanged
+		{"upper", 42, 42},
+		{"currency", "n/a", "n/a"},
+		{"currency:XYZ", 1.5, 1.5},
+		{"truncate", "Engineering", "Engineering"},
+		{"unknown", "x", "x"},
+	} {
+		assert.Equal(t, tc.want, e.namedFormatter(tc.name)(tc.in), tc.name)
+	}
+}
+
+func TestComposeFormatters(t *testing.T) {
+	e := NewExcelDataExporter()
+	f := ComposeFormatters(e.namedFormatter("trim"), nil, e.namedFormatter("upper"))
+	assert.Equal(t, "ANN", f("  ann  "))
+}
+
+func TestFormatterChain_YAML(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(`
+sheets:
+  - name: "People"
+    sections:
+      - id: "people"
+        show_header: true
+        columns:
+          - field_name: "Name"
+            formatter: [trim, upper, "truncate:4"]
+          - field_name: "Salary"
+            formatter: currency_vnd
+          - field_name: "Dept"
+            formatter: "dept"
+`)
+	require.NoError(t, err)
+	cols := exporter.Template().Sheets[0].Sections[0].Columns
+	assert.Equal(t, FormatterChain{"trim", "upper", "truncate:4"}, cols[0].Formatters)
+	assert.Equal(t, FormatterChain{"currency_vnd"}, cols[1].Formatters)
+
+	exporter.RegisterFormatter("dept", func(v interface{}) interface{} {
+		return "D-" + v.(string)
+	})
+	exporter.BindSectionData("people", []struct {
+		Name   string
+		Salary int
+		Dept   string
+	}{{" ann lee ", 15000000, "d001"}})
+
+	var buf bytes.Buffer
+	require.NoError(t, exporter.ToCSV(&buf))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, `ANN…,15.000.000 ₫,D-d001`, lines[1])
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+	defer f.Close()
+	v, err := f.GetCellValue("People", "A2")
+	require.NoError(t, err)
+	assert.Equal(t, "ANN…", v)
+}
+
+func TestFormatterChain_RegisteredOverridesBuiltin(t *testing.T) {
+	e := NewExcelDataExporter()
+	e.RegisterFormatter("upper", func(v interface{}) interface{} { return "custom" })
+	col := &ColumnConfig{FormatterName: "trim", Formatters: FormatterChain{"upper"}}
+	assert.Equal(t, "custom", e.formatValue(col, " x "))
+}
@@ -0,0 +1,142 @@
+package simpleexcelv2
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+type salaryRow struct {
+	Name   string
+	Salary interface{}
+}
+
+// newSalaryExporter formats Salary with a checked formatter rejecting
+// anything but ints, then upper-cases it.
+func newSalaryExporter(t *testing.T) *ExcelDataExporter {
+	exporter, err := NewExcelDataExporterFromYamlConfig(`
+sheets:
+  - name: "Pay"
+    sections:
+      - id: "pay"
+        title: "Salaries"
+        show_header: true
+        columns:
+          - field_name: "Name"
+          - field_name: "Salary"
+            formatter: [salary, upper]
+`)
+	require.NoError(t, err)
+	exporter.RegisterCheckedFormatter("salary", func(v interface{}) (interface{}, error) {
+		n, ok := v.(int)
+		if !ok {
+			return nil, fmt.Errorf("expected an int, got %T", v)
+		}
+		return fmt.Sprintf("%d usd", n), nil
+	})
+	exporter.BindSectionData("pay", []salaryRow{{"Ann", 5000}, {"Bob", "n/a"}, {"Cid", 4.5}})
+	return exporter
+}
+
+func TestCheckedFormatter_ReportsCells(t *testing.T) {
+	exporter := newSalaryExporter(t)
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+	defer f.Close()
+
+	rows, err := f.GetRows("Pay")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Ann", "5000 USD"}, rows[2])
+	assert.Equal(t, []string{"Bob", "n/a"}, rows[3], "rejected values are written unformatted")
+	assert.Equal(t, []string{"Cid", "4.5"}, rows[4])
+
+	errs := exporter.FormatErrors()
+	require.Len(t, errs, 2)
+	assert.Equal(t, FormatError{Sheet: "Pay", Cell: "B4", Section: "pay", Field: "Salary", Value: "n/a", Err: errs[0].Err}, errs[0])
+	assert.EqualError(t, &errs[0], "Pay!B4: format Salary value n/a: expected an int, got string")
+	assert.Equal(t, "B5", errs[1].Cell)
+
+	comments, err := f.GetComments("Pay")
+	require.NoError(t, err)
+	assert.Empty(t, comments, "comments are opt-in")
+}
+
+func TestCheckedFormatter_Comments(t *testing.T) {
+	exporter := newSalaryExporter(t).SetFormatErrorComments(true)
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+	defer f.Close()
+
+	comments, err := f.GetComments("Pay")
+	require.NoError(t, err)
+	require.Len(t, comments, 2)
+	assert.Equal(t, "B4", comments[0].Cell)
+	assert.Contains(t, comments[0].Text, "expected an int, got string")
+
+	// A second export starts a new list
+	_, err = exporter.BuildExcel()
+	require.NoError(t, err)
+	assert.Len(t, exporter.FormatErrors(), 2)
+}
+
+func TestCheckedFormatter_Streamed(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(`
+sheets:
+  - name: "Pay"
+    sections:
+      - id: "pay"
+        show_header: true
+        columns:
+          - field_name: "Name"
+          - field_name: "Salary"
+            formatter: salary
+`)
+	require.NoError(t, err)
+	exporter.RegisterCheckedFormatter("salary", func(v interface{}) (interface{}, error) {
+		if _, ok := v.(int); !ok {
+			return nil, fmt.Errorf("expected an int, got %T", v)
+		}
+		return v, nil
+	})
+	exporter.SetFormatErrorComments(true)
+
+	var buf bytes.Buffer
+	streamer, err := exporter.StartStream(&buf)
+	require.NoError(t, err)
+	require.NoError(t, streamer.Write("pay", []salaryRow{{"Ann", 5000}}))
+	require.NoError(t, streamer.Write("pay", []salaryRow{{"Bob", "n/a"}}))
+	require.NoError(t, streamer.Close())
+
+	errs := exporter.FormatErrors()
+	require.Len(t, errs, 1)
+	assert.Equal(t, "B3", errs[0].Cell)
+
+	f, err := excelize.OpenReader(&buf)
+	require.NoError(t, err)
+	defer f.Close()
+	comments, err := f.GetComments("Pay")
+	require.NoError(t, err)
+	require.Len(t, comments, 1)
+	assert.Equal(t, "B3", comments[0].Cell)
+}
+
+func TestCheckedFormatter_CSV(t *testing.T) {
+	exporter := newSalaryExporter(t)
+
+	var buf bytes.Buffer
+	require.NoError(t, exporter.ToCSV(&buf))
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Equal(t, "Bob,n/a", lines[3])
+
+	errs := exporter.FormatErrors()
+	require.Len(t, errs, 2)
+	assert.Equal(t, "", errs[0].Cell)
+	assert.Equal(t, "Pay", errs[0].Sheet)
+}
@@ -0,0 +1,350 @@
+package simpleexcelv2
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// SectionTypeGantt draws each data item as a bar across a date axis, see
+// GanttConfig.
+const SectionTypeGantt = "gantt"
+
+// Gantt axis units.
+const (
+	GanttUnitDay   = "day"
+	GanttUnitWeek  = "week" // weeks start on Monday
+	GanttUnitMonth = "month"
+	GanttUnitYear  = "year"
+)
+
+// maxGanttPeriods caps the axis so a fine unit over a long range doesn't
+// produce thousands of columns.
+const maxGanttPeriods = 1000
+
+// GanttConfig configures a gantt section. The section's columns are written
+// as labels left of the axis, then each item's start to end range is filled
+// in the axis columns.
+//
+//	sections:
+//	  - id: "managers"
+//	    type: "gantt"
+//	    columns:
+//	      - field_name: "EmpNo"
+//	        header: "Manager"
+//	    gantt:
+//	      start_field: "FromDate"
+//	      end_field: "ToDate"
+//	      unit: "year"
+//
+// End dates in year 9999, the usual "still current" marker, end today.
+type GanttConfig struct {
+	StartField string `yaml:"start_field"`
+	EndField   string `yaml:"end_field"`
+	// Unit is the span of one axis column: day, week, month (default) or year
+	Unit string `yaml:"unit"`
+	// From and To (YYYY-MM-DD) fix the axis, by default it spans the data
+	From string `yaml:"from"`
+	To   string `yaml:"to"`
+	// BarColor fills the bar cells, e.g. "#4F81BD"
+	BarColor string `yaml:"bar_color"`
+	// ColumnWidth is the width of the axis columns
+	ColumnWidth float64 `yaml:"column_width"`
+}
+
+const (
+	defaultGanttBarColor = "4F81BD"
+	ganttOpenEndYear     = 9999
+)
+
+func init() {
+	RegisterSectionRenderer(SectionTypeGantt, ganttRenderer{now: time.Now})
+}
+
+// validate checks the gantt block of a section at parse time.
+func (g *GanttConfig) validate(sec *SectionConfig) error {
+	if g == nil {
+		return fmt.Errorf("section %s: gantt sections need a gantt block", sec.ID)
+	}
+	if g.StartField == "" || g.EndField == "" {
+		return fmt.Errorf("section %s: gantt needs start_field and end_field", sec.ID)
+	}
+	switch g.Unit {
+	case "", GanttUnitDay, GanttUnitWeek, GanttUnitMonth, GanttUnitYear:
+	default:
+		return fmt.Errorf("section %s: unknown gantt unit %q", sec.ID, g.Unit)
+	}
+	for _, d := range []string{g.From, g.To} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.Parse(VariableDateLayout, d); err != nil {
+			return fmt.Errorf("section %s: gantt date %q must be YYYY-MM-DD", sec.ID, d)
+		}
+	}
+	return nil
+}
+
+func (g *GanttConfig) unit() string {
+	if g.Unit == "" {
+		return GanttUnitMonth
+	}
+	return g.Unit
+}
+
+// truncate returns the start of the period containing t.
+func (g *GanttConfig) truncate(t time.Time) time.Time {
+	y, m, d := t.Date()
+	switch g.unit() {
+	case GanttUnitDay:
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	case GanttUnitWeek:
+		day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case GanttUnitYear:
+		return time.Date(y, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// next returns the start of the period after the one starting at t.
+func (g *GanttConfig) next(t time.Time) time.Time {
+	switch g.unit() {
+	case GanttUnitDay:
+		return t.AddDate(0, 0, 1)
+	case GanttUnitWeek:
+		return t.AddDate(0, 0, 7)
+	case GanttUnitYear:
+		return t.AddDate(1, 0, 0)
+	}
+	return t.AddDate(0, 1, 0)
+}
+
+func (g *GanttConfig) label(t time.Time) string {
+	switch g.unit() {
+	case GanttUnitYear:
+		return t.Format("2006")
+	case GanttUnitMonth:
+		return t.Format("2006-01")
+	}
+	return t.Format(VariableDateLayout)
+}
+
+// ganttBar is the date range of one item; ok is false when a date is missing.
+type ganttBar struct {
+	start, end time.Time
+	ok         bool
+}
+
+// ganttLayout is the axis and bars computed from the bound data.
+type ganttLayout struct {
+	periods []time.Time
+	bars    []ganttBar
+	items   reflect.Value
+}
+
+type ganttRenderer struct {
+	now func() time.Time
+}
+
+func (r ganttRenderer) layout(rc *RenderContext) (*ganttLayout, error) {
+	g := rc.Section.Gantt
+	if g == nil {
+		return nil, fmt.Errorf("gantt section has no gantt block")
+	}
+	l := &ganttLayout{items: reflect.ValueOf(rc.Section.Data)}
+	if l.items.Kind() != reflect.Slice {
+		l.items = reflect.Value{}
+	}
+
+	today := r.now()
+	var first, last time.Time
+	for i := 0; l.items.IsValid() && i < l.items.Len(); i++ {
+		item := l.items.Index(i)
+		start, okStart := ganttDate(rc.Value(item, g.StartField))
+		end, okEnd := ganttDate(rc.Value(item, g.EndField))
+		if end.Year() >= ganttOpenEndYear {
+			end = today
+		}
+		bar := ganttBar{start: start, end: end, ok: okStart && okEnd && !end.Before(start)}
+		l.bars = append(l.bars, bar)
+		if !bar.ok {
+			continue
+		}
+		if first.IsZero() || start.Before(first) {
+			first = start
+		}
+		if end.After(last) {
+			last = end
+		}
+	}
+
+	if g.From != "" {
+		first, _ = time.Parse(VariableDateLayout, g.From)
+	}
+	if g.To != "" {
+		last, _ = time.Parse(VariableDateLayout, g.To)
+	}
+	if first.IsZero() || last.Before(first) {
+		return l, nil
+	}
+	for p := g.truncate(first); !p.After(last); p = g.next(p) {
+		if len(l.periods) == maxGanttPeriods {
+			return nil, fmt.Errorf("gantt axis exceeds %d %ss, use a coarser unit or set from/to", maxGanttPeriods, g.unit())
+		}
+		l.periods = append(l.periods, p)
+	}
+	return l, nil
+}
+
+func (r ganttRenderer) Size(rc *RenderContext) (int, int) {
+	rows := 1 // axis header
+	if rc.Section.Title != nil {
+		rows++
+	}
+	l, err := r.layout(rc)
+	if err != nil {
+		// Render reports the error
+		return len(rc.Section.Columns), rows
+	}
+	return len(rc.Section.Columns) + len(l.periods), rows + len(l.bars)
+}
+
+func (r ganttRenderer) Render(rc *RenderContext) error {
+	sec := rc.Section
+	g := sec.Gantt
+	l, err := r.layout(rc)
+	if err != nil {
+		return err
+	}
+	labels := len(sec.Columns)
+	width := labels + len(l.periods)
+	row := 0
+
+	if sec.Title != nil && width > 0 {
+		defaultTitle := &StyleTemplate{
+			Font:      &FontTemplate{Bold: true},
+			Alignment: &AlignmentTemplate{Horizontal: "center", Vertical: "top"},
+		}
+		styleID, err := rc.Style(resolveStyle(sec.TitleStyle, defaultTitle, sec.Locked))
+		if err != nil {
+			return err
+		}
+		rc.File.SetCellValue(rc.Sheet, rc.Cell(0, row), sec.Title)
+		rc.File.MergeCell(rc.Sheet, rc.Cell(0, row), rc.Cell(width-1, row))
+		rc.File.SetCellStyle(rc.Sheet, rc.Cell(0, row), rc.Cell(width-1, row), styleID)
+		row++
+	}
+
+	// Header: label headers, then one column per period
+	defaultHeader := &StyleTemplate{
+		Font:      &FontTemplate{Bold: true},
+		Alignment: &AlignmentTemplate{Horizontal: "center", Vertical: "top"},
+	}
+	headerID, err := rc.Style(resolveStyle(sec.HeaderStyle, defaultHeader, sec.Locked))
+	if err != nil {
+		return err
+	}
+	header := make([]interface{}, width)
+	for j, col := range sec.Columns {
+		header[j] = col.Header
+		if col.Header == "" {
+			header[j] = col.FieldName
+		}
+		if col.Width > 0 {
+			name := rc.exporter.getColName(rc.Col + j)
+			rc.File.SetColWidth(rc.Sheet, name, name, col.Width)
+		}
+	}
+	for k, p := range l.periods {
+		header[labels+k] = g.label(p)
+	}
+	if width > 0 {
+		rc.File.SetSheetRow(rc.Sheet, rc.Cell(0, row), &header)
+		rc.File.SetCellStyle(rc.Sheet, rc.Cell(0, row), rc.Cell(width-1, row), headerID)
+	}
+	if len(l.periods) > 0 {
+		colWidth := g.ColumnWidth
+		if colWidth <= 0 {
+			colWidth = float64(len(header[labels].(string))) + 2
+		}
+		first := rc.exporter.getColName(rc.Col + labels)
+		last := rc.exporter.getColName(rc.Col + width - 1)
+		rc.File.SetColWidth(rc.Sheet, first, last, colWidth)
+	}
+	row++
+
+	// Data: labels and bars
+	color := strings.TrimPrefix(g.BarColor, "#")
+	if color == "" {
+		color = defaultGanttBarColor
+	}
+	barID, err := rc.Style(resolveStyle(&StyleTemplate{Fill: &FillTemplate{Color: color}}, nil, sec.Locked))
+	if err != nil {
+		return err
+	}
+	labelID, err := rc.Style(resolveStyle(sec.DataStyle, nil, sec.Locked))
+	if err != nil {
+		return err
+	}
+	dataRow := row
+	for i, bar := range l.bars {
+		item := l.items.Index(i)
+		values := make([]interface{}, labels)
+		for j := range sec.Columns {
+			values[j] = rc.columnValue(item, &sec.Columns[j], rc.Cell(j, row))
+		}
+		if labels > 0 {
+			rc.File.SetSheetRow(rc.Sheet, rc.Cell(0, row), &values)
+			rc.File.SetCellStyle(rc.Sheet, rc.Cell(0, row), rc.Cell(labels-1, row), labelID)
+			for j := range sec.Columns {
+				if err := rc.setColumnRichText(&sec.Columns[j], rc.Cell(j, row), values[j]); err != nil {
+					return err
+				}
+			}
+		}
+		if bar.ok {
+			from, to := -1, -1
+			for k, p := range l.periods {
+				end := g.next(p)
+				if bar.start.Before(end) && !bar.end.Before(p) {
+					if from < 0 {
+						from = k
+					}
+					to = k
+				}
+			}
+			if from >= 0 {
+				rc.File.SetCellStyle(rc.Sheet, rc.Cell(labels+from, row), rc.Cell(labels+to, row), barID)
+			}
+		}
+		row++
+	}
+
+	offsets := make(map[string]int, labels)
+	for j, col := range sec.Columns {
+		offsets[col.FieldName] = j
+	}
+	rc.Place(SectionPlacement{StartRow: rc.Row + dataRow, StartCol: rc.Col, FieldOffsets: offsets, DataLen: len(l.bars)})
+	return nil
+}
+
+// ganttDate reads a date bound as time.Time or a YYYY-MM-DD/RFC 3339 string.
+func ganttDate(v interface{}) (time.Time, bool) {
+	switch d := v.(type) {
+	case time.Time:
+		return d, !d.IsZero()
+	case *time.Time:
+		if d != nil {
+			return *d, !d.IsZero()
+		}
+	case string:
+		for _, layout := range []string{VariableDateLayout, time.RFC3339} {
+			if t, err := time.Parse(layout, d); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,141 @@
+package simpleexcelv2
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+const ganttYAML = `
+sheets:
+  - name: "Managers"
+    sections:
+      - id: "managers"
+        type: "gantt"
+        title: "Department managers"
+        columns:
+          - field_name: "EmpNo"
+            header: "Manager"
+        gantt:
+          start_field: "FromDate"
+          end_field: "ToDate"
+          to: "2020-04-30"
+          bar_color: "#FF0000"
+      - id: "after"
+        columns:
+          - field_name: "Note"
+`
+
+type ganttRow struct {
+	EmpNo    int
+	FromDate time.Time
+	ToDate   interface{}
+}
+
+func day(s string) time.Time {
+	t, _ := time.Parse(VariableDateLayout, s)
+	return t
+}
+
+func TestGanttSection(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(ganttYAML)
+	require.NoError(t, err)
+	exporter.BindSectionData("managers", []ganttRow{
+		{EmpNo: 1, FromDate: day("2020-01-15"), ToDate: day("2020-03-10")},
+		{EmpNo: 2, FromDate: day("2020-02-01"), ToDate: "9999-01-01"},
+		{EmpNo: 3, FromDate: day("2020-02-01")},
+	}).BindSectionData("after", []struct{ Note string }{{"end"}})
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+	defer f.Close()
+
+	rows, err := f.GetRows("Managers")
+	require.NoError(t, err)
+	assert.Equal(t, "Department managers", rows[0][0])
+	assert.Equal(t, []string{"Manager", "2020-01", "2020-02", "2020-03", "2020-04"}, rows[1])
+	assert.Equal(t, "3", rows[4][0])
+	assert.Equal(t, "end", rows[5][0], "the next section starts below the chart")
+
+	filled := func(cell string) bool {
+		styleID, err := f.GetCellStyle("Managers", cell)
+		require.NoError(t, err)
+		style, err := f.GetStyle(styleID)
+		require.NoError(t, err)
+		return style.Fill.Type == "pattern" && len(style.Fill.Color) > 0 && style.Fill.Color[0] == "FF0000"
+	}
+	bars := map[int]string{}
+	for r := 3; r <= 5; r++ {
+		line := ""
+		for c := 2; c <= 5; c++ {
+			cell, _ := excelize.CoordinatesToCellName(c, r)
+			if filled(cell) {
+				line += "#"
+			} else {
+				line += "."
+			}
+		}
+		bars[r] = line
+	}
+	assert.Equal(t, map[int]string{
+		3: "###.",
+		4: ".###", // open ended, clipped to the axis
+		5: "....", // no end date
+	}, bars)
+
+	p := exporter.sectionMetadata["managers"]
+	assert.Equal(t, 3, p.StartRow)
+	assert.Equal(t, 3, p.DataLen)
+	assert.Equal(t, 0, p.FieldOffsets["EmpNo"])
+}
+
+func TestGanttConfig_Periods(t *testing.T) {
+	week := &GanttConfig{Unit: GanttUnitWeek}
+	assert.Equal(t, day("2024-01-01"), week.truncate(day("2024-01-07")), "weeks start on Monday")
+	assert.Equal(t, day("2024-01-08"), week.next(day("2024-01-01")))
+
+	year := &GanttConfig{Unit: GanttUnitYear}
+	assert.Equal(t, "2024", year.label(year.truncate(day("2024-06-30"))))
+}
+
+func TestGanttSection_RejectsInvalidConfig(t *testing.T) {
+	tests := map[string]string{
+		"missing block": ``,
+		"missing field": `
+        gantt: { start_field: "FromDate" }`,
+		"unknown unit": `
+        gantt: { start_field: "A", end_field: "B", unit: "fortnight" }`,
+		"bad date": `
+        gantt: { start_field: "A", end_field: "B", from: "01/02/2020" }`,
+	}
+	for name, block := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewExcelDataExporterFromYamlConfig(`
+sheets:
+  - name: "S"
+    sections:
+      - id: "g"
+        type: "gantt"` + block)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestGanttSection_AxisTooLong(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(`
+sheets:
+  - name: "S"
+    sections:
+      - id: "g"
+        type: "gantt"
+        gantt: { start_field: "FromDate", end_field: "ToDate", unit: "day" }
+`)
+	require.NoError(t, err)
+	exporter.BindSectionData("g", []ganttRow{{FromDate: day("1990-01-01"), ToDate: day("2020-01-01")}})
+
+	_, err = exporter.BuildExcel()
+	assert.ErrorContains(t, err, "coarser unit")
+}
@@ -0,0 +1,328 @@
+package simpleexcelv2
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// SectionTypeHeatmap pivots the data items into a matrix colored by value,
+// see HeatmapConfig.
+const SectionTypeHeatmap = "heatmap"
+
+// Heatmap aggregates.
+const (
+	HeatmapCount = "count"
+	HeatmapSum   = "sum"
+	HeatmapAvg   = "avg"
+	HeatmapMin   = "min"
+	HeatmapMax   = "max"
+)
+
+// HeatmapConfig configures a heatmap section. Items are grouped by the
+// values of row_field and column_field, each group's value_field is
+// aggregated into the matching cell and the matrix gets a color scale
+// conditional format.
+//
+//	sections:
+//	  - id: "headcount"
+//	    type: "heatmap"
+//	    heatmap:
+//	      row_field: "DeptName"
+//	      column_field: "Month"
+//	      aggregate: "count"
+//
+// Row and column keys are sorted, numerically when they are all numbers.
+// Cells without items are left empty.
+type HeatmapConfig struct {
+	RowField    string `yaml:"row_field"`
+	ColumnField string `yaml:"column_field"`
+	// ValueField is aggregated, it is not needed to count items
+	ValueField string `yaml:"value_field"`
+	// Aggregate is count, sum, avg, min or max; sum by default, count
+	// without a value_field
+	Aggregate string `yaml:"aggregate"`
+	// RowLabel heads the row key column, row_field by default
+	RowLabel string `yaml:"row_label"`
+	// MinColor and MaxColor color the lowest and highest values, MidColor
+	// makes it a three color scale around the median
+	MinColor string `yaml:"min_color"`
+	MidColor string `yaml:"mid_color"`
+	MaxColor string `yaml:"max_color"`
+}
+
+const (
+	defaultHeatmapMinColor = "#FFFFFF"
+	defaultHeatmapMaxColor = "#63BE7B"
+)
+
+func init() {
+	RegisterSectionRenderer(SectionTypeHeatmap, heatmapRenderer{})
+}
+
+// validate checks the heatmap block of a section at parse time.
+func (h *HeatmapConfig) validate(sec *SectionConfig) error {
+	if h == nil {
+		return fmt.Errorf("section %s: heatmap sections need a heatmap block", sec.ID)
+	}
+	if h.RowField == "" || h.ColumnField == "" {
+		return fmt.Errorf("section %s: heatmap needs row_field and column_field", sec.ID)
+	}
+	switch h.Aggregate {
+	case "", HeatmapCount:
+	case HeatmapSum, HeatmapAvg, HeatmapMin, HeatmapMax:
+		if h.ValueField == "" {
+			return fmt.Errorf("section %s: heatmap aggregate %q needs a value_field", sec.ID, h.Aggregate)
+		}
+	default:
+		return fmt.Errorf("section %s: unknown heatmap aggregate %q", sec.ID, h.Aggregate)
+	}
+	return nil
+}
+
+func (h *HeatmapConfig) aggregate() string {
+	if h.Aggregate != "" {
+		return h.Aggregate
+	}
+	if h.ValueField == "" {
+		return HeatmapCount
+	}
+	return HeatmapSum
+}
+
+// heatmapCell accumulates the items of one row and column key.
+type heatmapCell struct {
+	n             int
+	sum, min, max float64
+}
+
+func (c *heatmapCell) add(v float64) {
+	if c.n == 0 || v < c.min {
+		c.min = v
+	}
+	if c.n == 0 || v > c.max {
+		c.max = v
+	}
+	c.n++
+	c.sum += v
+}
+
+func (c *heatmapCell) value(aggregate string) float64 {
+	switch aggregate {
+	case HeatmapCount:
+		return float64(c.n)
+	case HeatmapAvg:
+		return c.sum / float64(c.n)
+	case HeatmapMin:
+		return c.min
+	case HeatmapMax:
+		return c.max
+	}
+	return c.sum
+}
+
+// heatmapMatrix is the pivot of the bound data.
+type heatmapMatrix struct {
+	rows, cols []interface{}
+	cells      map[[2]string]*heatmapCell
+}
+
+type heatmapRenderer struct{}
+
+func (heatmapRenderer) pivot(rc *RenderContext) (*heatmapMatrix, error) {
+	h := rc.Section.Heatmap
+	if h == nil {
+		return nil, fmt.Errorf("heatmap section has no heatmap block")
+	}
+	m := &heatmapMatrix{cells: make(map[[2]string]*heatmapCell)}
+	items := reflect.ValueOf(rc.Section.Data)
+	if items.Kind() != reflect.Slice {
+		return m, nil
+	}
+
+	seenRows, seenCols := make(map[string]bool), make(map[string]bool)
+	for i := 0; i < items.Len(); i++ {
+		item := items.Index(i)
+		rowKey, colKey := rc.Value(item, h.RowField), rc.Value(item, h.ColumnField)
+		key := [2]string{fmt.Sprint(rowKey), fmt.Sprint(colKey)}
+		if !seenRows[key[0]] {
+			seenRows[key[0]] = true
+			m.rows = append(m.rows, rowKey)
+		}
+		if !seenCols[key[1]] {
+			seenCols[key[1]] = true
+			m.cols = append(m.cols, colKey)
+		}
+
+		var v float64
+		if h.aggregate() != HeatmapCount {
+			f, ok := toFloat(rc.Value(item, h.ValueField))
+			if !ok {
+				return nil, fmt.Errorf("heatmap value %s of item %d is not a number", h.ValueField, i)
+			}
+			v = f
+		}
+		cell := m.cells[key]
+		if cell == nil {
+			cell = &heatmapCell{}
+			m.cells[key] = cell
+		}
+		cell.add(v)
+	}
+	sortHeatmapKeys(m.rows)
+	sortHeatmapKeys(m.cols)
+	return m, nil
+}
+
+func (r heatmapRenderer) Size(rc *RenderContext) (int, int) {
+	rows := 1 // column keys
+	if rc.Section.Title != nil {
+		rows++
+	}
+	m, err := r.pivot(rc)
+	if err != nil {
+		// Render reports the error
+		return 1, rows
+	}
+	return 1 + len(m.cols), rows + len(m.rows)
+}
+
+func (r heatmapRenderer) Render(rc *RenderContext) error {
+	sec := rc.Section
+	h := sec.Heatmap
+	m, err := r.pivot(rc)
+	if err != nil {
+		return err
+	}
+	width := 1 + len(m.cols)
+	row := 0
+
+	if sec.Title != nil {
+		defaultTitle := &StyleTemplate{
+			Font:      &FontTemplate{Bold: true},
+			Alignment: &AlignmentTemplate{Horizontal: "center", Vertical: "top"},
+		}
+		styleID, err := rc.Style(resolveStyle(sec.TitleStyle, defaultTitle, sec.Locked))
+		if err != nil {
+			return err
+		}
+		rc.File.SetCellValue(rc.Sheet, rc.Cell(0, row), sec.Title)
+		rc.File.MergeCell(rc.Sheet, rc.Cell(0, row), rc.Cell(width-1, row))
+		rc.File.SetCellStyle(rc.Sheet, rc.Cell(0, row), rc.Cell(width-1, row), styleID)
+		row++
+	}
+
+	// Header: row label, then the column keys
+	defaultHeader := &StyleTemplate{
+		Font:      &FontTemplate{Bold: true},
+		Alignment: &AlignmentTemplate{Horizontal: "center", Vertical: "top"},
+	}
+	headerID, err := rc.Style(resolveStyle(sec.HeaderStyle, defaultHeader, sec.Locked))
+	if err != nil {
+		return err
+	}
+	header := make([]interface{}, width)
+	header[0] = h.RowLabel
+	if h.RowLabel == "" {
+		header[0] = h.RowField
+	}
+	copy(header[1:], m.cols)
+	rc.File.SetSheetRow(rc.Sheet, rc.Cell(0, row), &header)
+	rc.File.SetCellStyle(rc.Sheet, rc.Cell(0, row), rc.Cell(width-1, row), headerID)
+	row++
+
+	// Row keys share the header style, the values the data style
+	dataID, err := rc.Style(resolveStyle(sec.DataStyle, nil, sec.Locked))
+	if err != nil {
+		return err
+	}
+	dataRow := row
+	aggregate := h.aggregate()
+	for _, rowKey := range m.rows {
+		values := make([]interface{}, width)
+		values[0] = rowKey
+		for j, colKey := range m.cols {
+			if cell := m.cells[[2]string{fmt.Sprint(rowKey), fmt.Sprint(colKey)}]; cell != nil {
+				values[1+j] = cell.value(aggregate)
+			}
+		}
+		rc.File.SetSheetRow(rc.Sheet, rc.Cell(0, row), &values)
+		rc.File.SetCellStyle(rc.Sheet, rc.Cell(0, row), rc.Cell(0, row), headerID)
+		if width > 1 {
+			rc.File.SetCellStyle(rc.Sheet, rc.Cell(1, row), rc.Cell(width-1, row), dataID)
+		}
+		row++
+	}
+
+	if len(m.rows) > 0 && len(m.cols) > 0 {
+		scale := excelize.ConditionalFormatOptions{
+			Type:     "2_color_scale",
+			Criteria: "=",
+			MinType:  "min",
+			MaxType:  "max",
+			MinColor: h.MinColor,
+			MaxColor: h.MaxColor,
+		}
+		if scale.MinColor == "" {
+			scale.MinColor = defaultHeatmapMinColor
+		}
+		if scale.MaxColor == "" {
+			scale.MaxColor = defaultHeatmapMaxColor
+		}
+		if h.MidColor != "" {
+			scale.Type = "3_color_scale"
+			scale.MidType = "percentile"
+			scale.MidValue = "50"
+			scale.MidColor = h.MidColor
+		}
+		ref := rc.Cell(1, dataRow) + ":" + rc.Cell(width-1, row-1)
+		if err := rc.File.SetConditionalFormat(rc.Sheet, ref, []excelize.ConditionalFormatOptions{scale}); err != nil {
+			return fmt.Errorf("heatmap color scale: %w", err)
+		}
+	}
+
+	rc.Place(SectionPlacement{StartRow: rc.Row + dataRow, StartCol: rc.Col + 1, DataLen: len(m.rows)})
+	return nil
+}
+
+// sortHeatmapKeys sorts keys numerically when they are all numbers, else by
+// their text.
+func sortHeatmapKeys(keys []interface{}) {
+	numeric := true
+	for _, k := range keys {
+		if _, ok := toFloat(k); !ok {
+			numeric = false
+			break
+		}
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		if numeric {
+			a, _ := toFloat(keys[i])
+			b, _ := toFloat(keys[j])
+			return a < b
+		}
+		return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+	})
+}
+
+// toFloat returns the value of an integer or float, dereferencing pointers.
+func toFloat(v interface{}) (float64, bool) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return 0, false
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
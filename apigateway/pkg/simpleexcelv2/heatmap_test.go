@@ -0,0 +1,119 @@
+package simpleexcelv2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type hireRow struct {
+	Dept   string
+	Month  int
+	Salary float64
+}
+
+var hireRows = []hireRow{
+	{"Sales", 2, 100},
+	{"Dev", 1, 200},
+	{"Sales", 2, 300},
+	{"Dev", 10, 50},
+	{"Sales", 1, 40},
+}
+
+func TestHeatmapSection(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(`
+sheets:
+  - name: "Hires"
+    sections:
+      - id: "hires"
+        type: "heatmap"
+        title: "Hires"
+        heatmap:
+          row_field: "Dept"
+          column_field: "Month"
+          row_label: "Department"
+          mid_color: "#FFEB84"
+      - id: "after"
+        columns:
+          - field_name: "Note"
+`)
+	require.NoError(t, err)
+	exporter.BindSectionData("hires", hireRows).
+		BindSectionData("after", []struct{ Note string }{{"end"}})
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+	defer f.Close()
+
+	rows, err := f.GetRows("Hires")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Department", "1", "2", "10"}, rows[1], "numeric keys sort numerically")
+	assert.Equal(t, []string{"Dev", "1", "", "1"}, rows[2])
+	assert.Equal(t, []string{"Sales", "1", "2"}, rows[3])
+	assert.Equal(t, "end", rows[4][0], "the next section starts below the matrix")
+
+	formats, err := f.GetConditionalFormats("Hires")
+	require.NoError(t, err)
+	require.Contains(t, formats, "B3:D4")
+	scale := formats["B3:D4"][0]
+	assert.Equal(t, "3_color_scale", scale.Type)
+	assert.Equal(t, "#FFEB84", scale.MidColor)
+
+	p := exporter.sectionMetadata["hires"]
+	assert.Equal(t, SectionPlacement{SectionID: "hires", Sheet: "Hires", StartRow: 3, StartCol: 2, DataLen: 2}, p)
+}
+
+func TestHeatmapSection_Aggregates(t *testing.T) {
+	for aggregate, want := range map[string][]string{
+		"sum": {"Sales", "40", "400"},
+		"avg": {"Sales", "40", "200"},
+		"min": {"Sales", "40", "100"},
+		"max": {"Sales", "40", "300"},
+	} {
+		t.Run(aggregate, func(t *testing.T) {
+			exporter, err := NewExcelDataExporterFromYamlConfig(`
+sheets:
+  - name: "Hires"
+    sections:
+      - id: "hires"
+        type: "heatmap"
+        heatmap: { row_field: "Dept", column_field: "Month", value_field: "Salary", aggregate: "` + aggregate + `" }
+`)
+			require.NoError(t, err)
+			exporter.BindSectionData("hires", hireRows)
+
+			f, err := exporter.BuildExcel()
+			require.NoError(t, err)
+			defer f.Close()
+
+			rows, err := f.GetRows("Hires")
+			require.NoError(t, err)
+			assert.Equal(t, want, rows[2])
+		})
+	}
+}
+
+func TestHeatmapSection_RejectsInvalidConfig(t *testing.T) {
+	for name, block := range map[string]string{
+		"missing block":     ``,
+		"missing field":     `{ row_field: "Dept" }`,
+		"sum without value": `{ row_field: "Dept", column_field: "Month", aggregate: "sum" }`,
+		"unknown aggregate": `{ row_field: "Dept", column_field: "Month", aggregate: "median" }`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			yaml := `
+sheets:
+  - name: "S"
+    sections:
+      - id: "h"
+        type: "heatmap"`
+			if block != "" {
+				yaml += `
+        heatmap: ` + block
+			}
+			_, err := NewExcelDataExporterFromYamlConfig(yaml)
+			assert.Error(t, err)
+		})
+	}
+}
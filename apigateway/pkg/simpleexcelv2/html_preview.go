@@ -0,0 +1,114 @@
+package simpleexcelv2
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"reflect"
+)
+
+// htmlPreviewCSS is embedded in every preview so it renders without external assets.
+const htmlPreviewCSS = `body{font-family:Calibri,Arial,sans-serif;font-size:13px;margin:16px}
+h2{font-size:16px;margin:24px 0 8px}
+table.section{border-collapse:collapse;margin-bottom:16px}
+table.section th,table.section td{border:1px solid #C8C8C8;padding:2px 6px;white-space:nowrap}
+table.section caption{font-weight:bold;text-align:left;padding:4px 0}
+.locked{background:#` + DefaultLockedColor + `;color:#555}`
+
+// RenderHTML writes a self-contained HTML preview of the bound exporter to w.
+// Each sheet becomes a heading followed by one table per section. Locked
+// sections and columns are greyed out, hidden sections are skipped.
+// It is meant for previews in the web UI; use ToWriter for the real workbook.
+func (e *ExcelDataExporter) RenderHTML(ctx context.Context, w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	e.arrangeSections(e.sheets)
+
+	fmt.Fprintf(bw, "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<style>%s</style>\n</head>\n<body>\n", htmlPreviewCSS)
+	for _, sheet := range e.sheets {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fmt.Fprintf(bw, "<h2>%s</h2>\n", html.EscapeString(sheet.name))
+		for _, sec := range e.expandRepeats(sheet.sections) {
+			if err := e.renderSectionHTML(ctx, bw, sec); err != nil {
+				return err
+			}
+		}
+	}
+	bw.WriteString("</body>\n</html>\n")
+
+	return bw.Flush()
+}
+
+func (e *ExcelDataExporter) renderSectionHTML(ctx context.Context, w *bufio.Writer, sec *SectionConfig) error {
+	if sec.Type == SectionTypeHidden {
+		return nil
+	}
+
+	// Perform Late Binding if needed
+	if sec.ID != "" && sec.Data == nil {
+		if data, ok := e.data[sec.ID]; ok {
+			sec.Data = data
+		}
+	}
+
+	if sec.Type == SectionTypeTitleOnly {
+		if sec.Title != nil {
+			fmt.Fprintf(w, "<p%s><strong>%s</strong></p>\n", lockedClass(sec.Locked), html.EscapeString(fmt.Sprint(sec.Title)))
+		}
+		return nil
+	}
+
+	cols := mergeColumns(sec.Data, sec.Columns, sec.Order)
+	fmt.Fprintf(w, "<table class=\"section\" data-section=\"%s\">\n", html.EscapeString(sec.ID))
+	if sec.Title != nil {
+		fmt.Fprintf(w, "<caption>%s</caption>\n", html.EscapeString(fmt.Sprint(sec.Title)))
+	}
+
+	if sec.ShowHeader && len(cols) > 0 {
+		w.WriteString("<thead><tr>")
+		for _, col := range cols {
+			fmt.Fprintf(w, "<th%s>%s</th>", lockedClass(col.IsLocked(sec.Locked)), html.EscapeString(col.Header))
+		}
+		w.WriteString("</tr></thead>\n")
+	}
+
+	w.WriteString("<tbody>\n")
+	v := reflect.ValueOf(sec.Data)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Slice {
+		for i := 0; i < v.Len(); i++ {
+			if i%1000 == 0 {
+				if err := ctx.Err(); err != nil {
+					return err
+				}
+			}
+			item := v.Index(i)
+			if item.Kind() == reflect.Ptr {
+				item = item.Elem()
+			}
+			w.WriteString("<tr>")
+			for _, col := range cols {
+				val := e.formatCellValue(item, col)
+				if lf := e.columnLocale(col); lf != nil {
+					val = lf.FormatText(val)
+				}
+				fmt.Fprintf(w, "<td%s>%s</td>", lockedClass(col.IsLocked(sec.Locked)), html.EscapeString(fmt.Sprintf("%v", val)))
+			}
+			w.WriteString("</tr>\n")
+		}
+	}
+	w.WriteString("</tbody>\n</table>\n")
+	return nil
+}
+
+func lockedClass(locked bool) string {
+	if locked {
+		return ` class="locked"`
+	}
+	return ""
+}
@@ -0,0 +1,80 @@
+package simpleexcelv2
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const htmlPreviewYAML = `
+sheets:
+  - name: "Staff"
+    sections:
+      - id: "banner"
+        type: "title"
+        title: "Staff <Report>"
+      - id: "original"
+        title: "Original"
+        show_header: true
+        locked: true
+        columns:
+          - field_name: "Name"
+            header: "Name"
+      - id: "edit"
+        title: "Editable"
+        show_header: true
+        columns:
+          - field_name: "Name"
+            header: "Name"
+          - field_name: "Salary"
+            header: "Salary"
+            formatter: "money"
+      - id: "meta"
+        type: "hidden"
+        columns:
+          - field_name: "Name"
+`
+
+type previewRow struct {
+	Name   string
+	Salary int
+}
+
+func TestRenderHTML(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(htmlPreviewYAML)
+	require.NoError(t, err)
+
+	rows := []previewRow{{Name: "Alice & Bob", Salary: 1000}}
+	exporter.RegisterFormatter("money", func(v interface{}) interface{} {
+		return fmt.Sprintf("$%v", v)
+	})
+	exporter.BindSectionData("original", rows).
+		BindSectionData("edit", rows).
+		BindSectionData("meta", rows)
+
+	var buf bytes.Buffer
+	require.NoError(t, exporter.RenderHTML(context.Background(), &buf))
+	out := buf.String()
+
+	assert.Contains(t, out, "<h2>Staff</h2>")
+	assert.Contains(t, out, "Staff &lt;Report&gt;")
+	assert.Contains(t, out, `<table class="section" data-section="original">`)
+	assert.Contains(t, out, `<td class="locked">Alice &amp; Bob</td>`)
+	assert.Contains(t, out, "<td>$1000</td>")
+	assert.NotContains(t, out, `data-section="meta"`)
+}
+
+func TestRenderHTML_CanceledContext(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(htmlPreviewYAML)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = exporter.RenderHTML(ctx, &bytes.Buffer{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,194 @@
+package simpleexcelv2
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ImportedRow is one data row read back from an edited workbook.
+type ImportedRow struct {
+	// Index is the 0-based position within the section data
+	Index int
+	// Row is the 1-based sheet row, for error messages
+	Row int
+	// Values holds raw cell values keyed by hidden field name
+	Values map[string]string
+	// Cells holds the cell address of each value, e.g. "B4"
+	Cells map[string]string
+}
+
+// ImportedSection is an editable section read back from a workbook.
+type ImportedSection struct {
+	ID     string
+	Sheet  string
+	Fields []string
+	// Editable lists the fields whose cells were exported unlocked
+	Editable map[string]bool
+	Rows     []ImportedRow
+}
+
+// ReadEditableSections reads the editable sections of a workbook produced by
+// BuildExcel/ToBytes so user edits can be applied back to the database.
+//
+// Sections are located through the data ranges recorded at export time and
+// their fields are named by the hidden field-name row (hidden_field_name).
+// Columns without a hidden field name are ignored, and so are sections
+// without one or whose cells are all locked. Values are returned raw, so
+// numbers and dates come back in their stored form, not as displayed.
+// Rows left completely blank are skipped.
+func ReadEditableSections(r io.Reader, opts ...ReadOption) ([]ImportedSection, error) {
+	var o readOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	f, err := excelize.OpenReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("open workbook: %w", err)
+	}
+	defer f.Close()
+
+	if len(o.checksumKey) > 0 {
+		if err := verifyLockedSections(f, o.checksumKey); err != nil {
+			return nil, err
+		}
+	}
+
+	ranges := sectionRanges(f)
+	ids := make([]string, 0, len(ranges))
+	for id := range ranges {
+		ids = append(ids, id)
+	}
+	// Sheet order first, then top to bottom, so results are stable
+	sheetIndex := make(map[string]int)
+	for i, name := range f.GetSheetList() {
+		sheetIndex[name] = i
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := ranges[ids[i]], ranges[ids[j]]
+		if a.Sheet != b.Sheet {
+			return sheetIndex[a.Sheet] < sheetIndex[b.Sheet]
+		}
+		if a.StartRow != b.StartRow {
+			return a.StartRow < b.StartRow
+		}
+		return a.StartCol < b.StartCol
+	})
+
+	var sections []ImportedSection
+	for _, id := range ids {
+		sec, err := readSection(f, id, ranges[id])
+		if err != nil {
+			return nil, err
+		}
+		if sec != nil {
+			sections = append(sections, *sec)
+		}
+	}
+	return sections, nil
+}
+
+// readSection returns nil when the section has no field-name row or no
+// editable field.
+func readSection(f *excelize.File, id string, rng sectionRange) (*ImportedSection, error) {
+	fieldRow := findFieldNameRow(f, rng)
+	if fieldRow == 0 {
+		return nil, nil
+	}
+
+	sec := &ImportedSection{ID: id, Sheet: rng.Sheet, Editable: make(map[string]bool)}
+	fields := make(map[int]string)
+	for c := rng.StartCol; c <= rng.EndCol; c++ {
+		cell, _ := excelize.CoordinatesToCellName(c, fieldRow)
+		name, err := f.GetCellValue(rng.Sheet, cell)
+		if err != nil {
+			return nil, fmt.Errorf("read %s!%s: %w", rng.Sheet, cell, err)
+		}
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		fields[c] = name
+		sec.Fields = append(sec.Fields, name)
+
+		dataCell, _ := excelize.CoordinatesToCellName(c, rng.StartRow)
+		locked, err := cellLocked(f, rng.Sheet, dataCell)
+		if err != nil {
+			return nil, err
+		}
+		if !locked {
+			sec.Editable[name] = true
+		}
+	}
+	if len(sec.Editable) == 0 {
+		return nil, nil
+	}
+
+	opts := excelize.Options{RawCellValue: true}
+	for r := rng.StartRow; r <= rng.EndRow; r++ {
+		row := ImportedRow{
+			Index:  r - rng.StartRow,
+			Row:    r,
+			Values: make(map[string]string, len(fields)),
+			Cells:  make(map[string]string, len(fields)),
+		}
+		blank := true
+		for c := rng.StartCol; c <= rng.EndCol; c++ {
+			name, ok := fields[c]
+			if !ok {
+				continue
+			}
+			cell, _ := excelize.CoordinatesToCellName(c, r)
+			val, err := f.GetCellValue(rng.Sheet, cell, opts)
+			if err != nil {
+				return nil, fmt.Errorf("read %s!%s: %w", rng.Sheet, cell, err)
+			}
+			if strings.TrimSpace(val) != "" {
+				blank = false
+			}
+			row.Values[name] = val
+			row.Cells[name] = cell
+		}
+		if !blank {
+			sec.Rows = append(sec.Rows, row)
+		}
+	}
+	return sec, nil
+}
+
+// findFieldNameRow looks for the hidden field-name row just above the data,
+// skipping the header row. It returns 0 if there is none, including for
+// hidden sections whose data rows are hidden as well.
+func findFieldNameRow(f *excelize.File, rng sectionRange) int {
+	if visible, err := f.GetRowVisible(rng.Sheet, rng.StartRow); err != nil || !visible {
+		return 0
+	}
+	for r := rng.StartRow - 1; r >= rng.StartRow-2 && r >= 1; r-- {
+		visible, err := f.GetRowVisible(rng.Sheet, r)
+		if err == nil && !visible {
+			return r
+		}
+	}
+	return 0
+}
+
+// cellLocked reports whether a cell is locked. Cells without protection
+// settings are locked, matching Excel's default.
+func cellLocked(f *excelize.File, sheet, cell string) (bool, error) {
+	styleID, err := f.GetCellStyle(sheet, cell)
+	if err != nil {
+		return false, fmt.Errorf("read style of %s!%s: %w", sheet, cell, err)
+	}
+	style, err := f.GetStyle(styleID)
+	if err != nil {
+		return false, fmt.Errorf("read style of %s!%s: %w", sheet, cell, err)
+	}
+	if style == nil || style.Protection == nil {
+		return true, nil
+	}
+	return style.Protection.Locked, nil
+}
@@ -0,0 +1,94 @@
+package simpleexcelv2
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+const importYAML = `
+sheets:
+  - name: "Staff"
+    sections:
+      - id: "reference"
+        title: "Reference"
+        show_header: true
+        locked: true
+        columns:
+          - field_name: "ID"
+            hidden_field_name: "id"
+          - field_name: "Name"
+            hidden_field_name: "name"
+      - id: "edit"
+        title: "Editable"
+        show_header: true
+        columns:
+          - field_name: "ID"
+            header: "ID"
+            hidden_field_name: "id"
+            locked: true
+          - field_name: "Name"
+            header: "Name"
+            hidden_field_name: "name"
+          - field_name: "Note"
+            header: "Note"
+      - id: "plain"
+        show_header: true
+        columns:
+          - field_name: "Name"
+`
+
+type importRow struct {
+	ID   int
+	Name string
+	Note string
+}
+
+func TestReadEditableSections(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(importYAML)
+	require.NoError(t, err)
+	rows := []importRow{{ID: 1, Name: "Alice", Note: "a"}, {ID: 2, Name: "Bob"}, {ID: 3, Name: "Carol"}}
+	exporter.BindSectionData("reference", rows).
+		BindSectionData("edit", rows).
+		BindSectionData("plain", rows)
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+	meta, ok := exporter.sectionMetadata["edit"]
+	require.True(t, ok)
+
+	// Rename Bob and clear the third row entirely
+	nameCell, _ := excelize.CoordinatesToCellName(meta.StartCol+1, meta.StartRow+1)
+	require.NoError(t, f.SetCellValue("Staff", nameCell, "Robert"))
+	for c := 0; c < 3; c++ {
+		cell, _ := excelize.CoordinatesToCellName(meta.StartCol+c, meta.StartRow+2)
+		require.NoError(t, f.SetCellValue("Staff", cell, ""))
+	}
+	buf, err := f.WriteToBuffer()
+	require.NoError(t, err)
+
+	sections, err := ReadEditableSections(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Len(t, sections, 1, "locked and field-less sections are skipped")
+
+	sec := sections[0]
+	assert.Equal(t, "edit", sec.ID)
+	assert.Equal(t, "Staff", sec.Sheet)
+	assert.Equal(t, []string{"id", "name"}, sec.Fields)
+	assert.Equal(t, map[string]bool{"name": true}, sec.Editable)
+
+	require.Len(t, sec.Rows, 2)
+	assert.Equal(t, map[string]string{"id": "1", "name": "Alice"}, sec.Rows[0].Values)
+	assert.Equal(t, 1, sec.Rows[1].Index)
+	assert.Equal(t, meta.StartRow+1, sec.Rows[1].Row)
+	assert.Equal(t, "Robert", sec.Rows[1].Values["name"])
+	assert.Equal(t, nameCell, sec.Rows[1].Cells["name"])
+}
+
+func TestReadEditableSections_NotAWorkbook(t *testing.T) {
+	_, err := ReadEditableSections(bytes.NewReader([]byte("not a zip")))
+	assert.Error(t, err)
+}
@@ -0,0 +1,339 @@
+package simpleexcelv2
+
+import (
+	"fmt"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// excelDefaultColWidth is the width excelize reports for columns that were
+// never sized.
+const excelDefaultColWidth = 9.140625
+
+// defaultAutoFitSampleRows is the number of data rows streamed sheets measure
+// before their column widths are set.
+const defaultAutoFitSampleRows = 100
+
+// LayoutTemplate holds sheet-level layout options.
+//
+//	sheets:
+//	  - name: "Employees"
+//	    layout:
+//	      freeze_rows: 2
+//	      freeze_cols: 1
+//	      auto_fit_columns: true
+//	      max_column_width: 40
+type LayoutTemplate struct {
+	FreezeRows int `yaml:"freeze_rows"` // Rows kept visible at the top when scrolling
+	FreezeCols int `yaml:"freeze_cols"` // Columns kept visible at the left
+	// AutoFitColumns sizes columns without an explicit width to their
+	// content. Sections can opt in on their own with auto_fit.
+	AutoFitColumns bool    `yaml:"auto_fit_columns"`
+	MaxColumnWidth float64 `yaml:"max_column_width"` // Caps auto-fit widths, 0 means Excel's limit
+	// AutoFitSampleRows is the number of data rows a streamed sheet holds
+	// back and measures before its widths are set, 100 by default
+	AutoFitSampleRows int `yaml:"auto_fit_sample_rows"`
+}
+
+// SetLayout sets the layout options of the sheet.
+func (sb *SheetBuilder) SetLayout(layout *LayoutTemplate) *SheetBuilder {
+	sb.layout = layout
+	return sb
+}
+
+func (l *LayoutTemplate) validate(sheet string) error {
+	if l.FreezeRows < 0 || l.FreezeCols < 0 {
+		return fmt.Errorf("sheet %s: freeze_rows and freeze_cols must not be negative", sheet)
+	}
+	if l.MaxColumnWidth < 0 || l.MaxColumnWidth > excelize.MaxColumnWidth {
+		return fmt.Errorf("sheet %s: max_column_width must be between 0 and %d", sheet, excelize.MaxColumnWidth)
+	}
+	if l.AutoFitSampleRows < 0 {
+		return fmt.Errorf("sheet %s: auto_fit_sample_rows must not be negative", sheet)
+	}
+	return nil
+}
+
+// panes returns the freeze panes for the layout, or nil if nothing is frozen.
+func (l *LayoutTemplate) panes() *excelize.Panes {
+	if l == nil || (l.FreezeRows == 0 && l.FreezeCols == 0) {
+		return nil
+	}
+	active := "bottomRight"
+	if l.FreezeCols == 0 {
+		active = "bottomLeft"
+	} else if l.FreezeRows == 0 {
+		active = "topRight"
+	}
+	topLeft, _ := excelize.CoordinatesToCellName(l.FreezeCols+1, l.FreezeRows+1)
+	return &excelize.Panes{
+		Freeze:      true,
+		XSplit:      l.FreezeCols,
+		YSplit:      l.FreezeRows,
+		TopLeftCell: topLeft,
+		ActivePane:  active,
+		Selection:   []excelize.Selection{{SQRef: topLeft, ActiveCell: topLeft, Pane: active}},
+	}
+}
+
+// fitWidth returns the column width for content of the given length.
+func (l *LayoutTemplate) fitWidth(chars int) float64 {
+	width := float64(chars) + 2
+	limit := float64(excelize.MaxColumnWidth)
+	if l != nil && l.MaxColumnWidth > 0 {
+		limit = l.MaxColumnWidth
+	}
+	if width > limit {
+		width = limit
+	}
+	return width
+}
+
+func (l *LayoutTemplate) sampleRows() int {
+	if l == nil || l.AutoFitSampleRows == 0 {
+		return defaultAutoFitSampleRows
+	}
+	return l.AutoFitSampleRows
+}
+
+// autoFitAll reports whether every column of the sheet is auto-fit.
+func (sb *SheetBuilder) autoFitAll() bool {
+	return sb.layout != nil && sb.layout.AutoFitColumns
+}
+
+// autoFits reports whether the sheet or any of its sections auto-fits columns.
+func (sb *SheetBuilder) autoFits() bool {
+	if sb.autoFitAll() {
+		return true
+	}
+	for _, sec := range sb.sections {
+		if sec.AutoFit {
+			return true
+		}
+	}
+	return false
+}
+
+// cellRect is a block of cells, 1-based and inclusive.
+type cellRect struct {
+	col1, row1, col2, row2 int
+}
+
+func (r cellRect) contains(col, row int) bool {
+	return col >= r.col1 && col <= r.col2 && row >= r.row1 && row <= r.row2
+}
+
+// applyLayout applies the layout of a sheet rendered by BuildExcel with
+// sections, the sheet's sections with repeat sections expanded.
+func (e *ExcelDataExporter) applyLayout(f *excelize.File, sb *SheetBuilder, sections []*SectionConfig) error {
+	if panes := sb.layout.panes(); panes != nil {
+		if err := f.SetPanes(sb.name, panes); err != nil {
+			return fmt.Errorf("sheet %s: freeze panes: %w", sb.name, err)
+		}
+	}
+	if sb.autoFitAll() {
+		return autoFitColumns(f, sb.name, sb.layout, nil)
+	}
+
+	// Only the header and data cells of the auto_fit sections
+	var regions []cellRect
+	for _, sec := range sections {
+		p, ok := e.sectionMetadata[sec.ID]
+		if !sec.AutoFit || !ok || len(sec.Columns) == 0 {
+			continue
+		}
+		r := cellRect{col1: p.StartCol, row1: p.StartRow, col2: p.StartCol + len(sec.Columns) - 1, row2: p.StartRow + p.DataLen - 1}
+		if sec.ShowHeader {
+			r.row1--
+		}
+		regions = append(regions, r)
+	}
+	if len(regions) == 0 {
+		return nil
+	}
+	return autoFitColumns(f, sb.name, sb.layout, regions)
+}
+
+// autoFitColumns sizes every column that has no explicit width to its
+// longest displayed value within regions, or the whole sheet if regions is
+// nil. Merged cells, such as section titles, are ignored.
+func autoFitColumns(f *excelize.File, sheet string, l *LayoutTemplate, regions []cellRect) error {
+	merged := make(map[string]bool)
+	merges, err := f.GetMergeCells(sheet)
+	if err != nil {
+		return err
+	}
+	for _, m := range merges {
+		c1, r1, _ := excelize.CellNameToCoordinates(m.GetStartAxis())
+		c2, r2, _ := excelize.CellNameToCoordinates(m.GetEndAxis())
+		for r := r1; r <= r2; r++ {
+			for c := c1; c <= c2; c++ {
+				cell, _ := excelize.CoordinatesToCellName(c, r)
+				merged[cell] = true
+			}
+		}
+	}
+	inRegion := func(col, row int) bool {
+		if regions == nil {
+			return true
+		}
+		for _, r := range regions {
+			if r.contains(col, row) {
+				return true
+			}
+		}
+		return false
+	}
+
+	rows, err := f.GetRows(sheet)
+	if err != nil {
+		return err
+	}
+	var longest []int
+	for r, row := range rows {
+		for c, val := range row {
+			if val == "" || !inRegion(c+1, r+1) {
+				continue
+			}
+			if cell, _ := excelize.CoordinatesToCellName(c+1, r+1); merged[cell] {
+				continue
+			}
+			for len(longest) <= c {
+				longest = append(longest, 0)
+			}
+			if n := utf8.RuneCountInString(val); n > longest[c] {
+				longest[c] = n
+			}
+		}
+	}
+
+	for c, n := range longest {
+		if n == 0 {
+			continue
+		}
+		name, _ := excelize.ColumnNumberToName(c + 1)
+		if width, err := f.GetColWidth(sheet, name); err != nil || width != excelDefaultColWidth {
+			continue
+		}
+		if err := f.SetColWidth(sheet, name, name, l.fitWidth(n)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// streamColumnWidths returns the configured widths of the columns of a
+// streamed sheet, by 1-based column. Streamed sections all start in column A.
+func streamColumnWidths(sb *SheetBuilder) map[int]float64 {
+	widths := make(map[int]float64)
+	for _, sec := range sb.sections {
+		for j, col := range sec.Columns {
+			if col.Width > widths[j+1] {
+				widths[j+1] = col.Width
+			}
+		}
+	}
+	return widths
+}
+
+// setStreamColWidths sets column widths on a stream writer in column order,
+// as Excel expects them.
+func setStreamColWidths(sw *excelize.StreamWriter, widths map[int]float64) error {
+	cols := make([]int, 0, len(widths))
+	for col := range widths {
+		cols = append(cols, col)
+	}
+	sort.Ints(cols)
+	for _, col := range cols {
+		if err := sw.SetColWidth(col, col, widths[col]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// streamSampler holds back the rows of an auto-fit streamed sheet until
+// enough data rows were measured to size its columns: the stream writer only
+// takes widths before the first row. Widths are an approximation from the
+// sampled values as text.
+type streamSampler struct {
+	sheet   *SheetBuilder
+	widths  map[int]float64 // configured widths, kept as is
+	rows    []sampledRow
+	longest map[int]int
+	sampled int
+}
+
+type sampledRow struct {
+	cell   string
+	values []interface{}
+}
+
+// Kinds of streamed rows, only header and data rows are measured.
+const (
+	streamTitleRow = iota
+	streamHeaderRow
+	streamDataRow
+)
+
+// add holds back a row of sec and reports whether the sample is complete.
+func (sp *streamSampler) add(sec *SectionConfig, cell string, values []interface{}, kind int) bool {
+	sp.rows = append(sp.rows, sampledRow{cell: cell, values: values})
+	if kind == streamTitleRow || !(sp.sheet.autoFitAll() || sec.AutoFit) {
+		return false
+	}
+	for j, v := range values {
+		if c, ok := v.(excelize.Cell); ok {
+			if c.Formula != "" {
+				continue
+			}
+			v = c.Value
+		}
+		if n := displayLength(v); n > sp.longest[j+1] {
+			sp.longest[j+1] = n
+		}
+	}
+	if kind == streamDataRow {
+		sp.sampled++
+	}
+	return sp.sampled >= sp.sheet.layout.sampleRows()
+}
+
+// flush sets the column widths and writes the rows held back.
+func (sp *streamSampler) flush(sw *excelize.StreamWriter) error {
+	widths := make(map[int]float64, len(sp.longest)+len(sp.widths))
+	for col, n := range sp.longest {
+		if n > 0 {
+			widths[col] = sp.sheet.layout.fitWidth(n)
+		}
+	}
+	for col, w := range sp.widths {
+		widths[col] = w
+	}
+	if err := setStreamColWidths(sw, widths); err != nil {
+		return fmt.Errorf("sheet %s: column width: %w", sp.sheet.name, err)
+	}
+	for _, row := range sp.rows {
+		if err := sw.SetRow(row.cell, row.values); err != nil {
+			return err
+		}
+	}
+	sp.rows = nil
+	return nil
+}
+
+// displayLength approximates the displayed length of a streamed value.
+func displayLength(v interface{}) int {
+	switch t := v.(type) {
+	case nil:
+		return 0
+	case time.Time:
+		return len(VariableDateLayout)
+	case string:
+		return utf8.RuneCountInString(t)
+	}
+	return utf8.RuneCountInString(fmt.Sprint(v))
+}
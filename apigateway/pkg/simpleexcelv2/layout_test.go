@@ -0,0 +1,173 @@
+package simpleexcelv2
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+const layoutYAML = `
+sheets:
+  - name: "Staff"
+    layout:
+      freeze_rows: 2
+      freeze_cols: 1
+      auto_fit_columns: true
+      max_column_width: 20
+    sections:
+      - id: "staff"
+        title: "A title much longer than any column should become"
+        show_header: true
+        columns:
+          - field_name: "ID"
+            header: "ID"
+          - field_name: "Name"
+            header: "Name"
+          - field_name: "Bio"
+            header: "Bio"
+          - field_name: "Team"
+            header: "Team"
+            width: 30
+`
+
+type layoutRow struct {
+	ID   int
+	Name string
+	Bio  string
+	Team string
+}
+
+func exportLayout(t *testing.T, streamed bool) *excelize.File {
+	exporter, err := NewExcelDataExporterFromYamlConfig(layoutYAML)
+	require.NoError(t, err)
+	rows := []layoutRow{{ID: 1, Name: "Bezalel Simmel", Bio: "Joined in 1985 and has worked on every release since", Team: "x"}}
+	var buf bytes.Buffer
+	if streamed {
+		s, err := exporter.StartStream(&buf)
+		require.NoError(t, err)
+		require.NoError(t, s.Write("staff", rows))
+		require.NoError(t, s.Close())
+	} else {
+		exporter.BindSectionData("staff", rows)
+		require.NoError(t, exporter.ToWriter(&buf))
+	}
+	f, err := excelize.OpenReader(&buf)
+	require.NoError(t, err)
+	return f
+}
+
+func TestLayout_FreezePanes(t *testing.T) {
+	for _, streamed := range []bool{false, true} {
+		f := exportLayout(t, streamed)
+		panes, err := f.GetPanes("Staff")
+		require.NoError(t, err)
+		assert.True(t, panes.Freeze, "streamed=%v", streamed)
+		assert.Equal(t, 1, panes.XSplit)
+		assert.Equal(t, 2, panes.YSplit)
+		assert.Equal(t, "B3", panes.TopLeftCell)
+		f.Close()
+	}
+}
+
+func TestLayout_AutoFitColumns(t *testing.T) {
+	f := exportLayout(t, false)
+	defer f.Close()
+	width := func(col string) float64 {
+		w, err := f.GetColWidth("Staff", col)
+		require.NoError(t, err)
+		return w
+	}
+	assert.Equal(t, 4.0, width("A"), "the merged title is ignored")
+	assert.Equal(t, 16.0, width("B"))
+	assert.Equal(t, 20.0, width("C"), "capped by max_column_width")
+	assert.Equal(t, 30.0, width("D"), "explicit widths are kept")
+}
+
+func TestLayout_StreamedAutoFitColumns(t *testing.T) {
+	f := exportLayout(t, true)
+	defer f.Close()
+	width := func(col string) float64 {
+		w, err := f.GetColWidth("Staff", col)
+		require.NoError(t, err)
+		return w
+	}
+	assert.Equal(t, 4.0, width("A"), "the title is ignored")
+	assert.Equal(t, 16.0, width("B"))
+	assert.Equal(t, 20.0, width("C"))
+	assert.Equal(t, 30.0, width("D"))
+}
+
+const sectionAutoFitYAML = `
+sheets:
+  - name: "Staff"
+    layout:
+      auto_fit_sample_rows: 2
+    sections:
+      - id: "notes"
+        columns:
+          - field_name: "Name"
+      - id: "staff"
+        auto_fit: true
+        show_header: true
+        columns:
+          - field_name: "ID"
+            header: "Identifier"
+          - field_name: "Name"
+            header: "Name"
+`
+
+func TestLayout_SectionAutoFit(t *testing.T) {
+	staff := []layoutRow{{ID: 1, Name: "Ann"}, {ID: 2, Name: "Bezalel"}, {ID: 3, Name: "Christopher Columbus"}}
+	notes := []layoutRow{{Name: "a note longer than anything in the staff section"}}
+
+	for _, streamed := range []bool{false, true} {
+		exporter, err := NewExcelDataExporterFromYamlConfig(sectionAutoFitYAML)
+		require.NoError(t, err)
+		exporter.BindSectionData("notes", notes)
+		var buf bytes.Buffer
+		if streamed {
+			s, err := exporter.StartStream(&buf)
+			require.NoError(t, err)
+			require.NoError(t, s.Write("staff", staff[:2]))
+			require.NoError(t, s.Write("staff", staff[2:]))
+			require.NoError(t, s.Close())
+		} else {
+			exporter.BindSectionData("staff", staff)
+			require.NoError(t, exporter.ToWriter(&buf))
+		}
+		f, err := excelize.OpenReader(&buf)
+		require.NoError(t, err)
+
+		a, err := f.GetColWidth("Staff", "A")
+		require.NoError(t, err)
+		assert.Equal(t, 12.0, a, "streamed=%v: notes are not measured", streamed)
+		b, err := f.GetColWidth("Staff", "B")
+		require.NoError(t, err)
+		if streamed {
+			assert.Equal(t, 9.0, b, "only the first 2 data rows are sampled")
+		} else {
+			assert.Equal(t, 22.0, b)
+		}
+		rows, err := f.GetRows("Staff")
+		require.NoError(t, err)
+		assert.Len(t, rows, 5, "streamed=%v: rows held back for sampling are written", streamed)
+		assert.Equal(t, "Christopher Columbus", rows[4][1])
+		f.Close()
+	}
+}
+
+func TestLayout_RejectsInvalidOptions(t *testing.T) {
+	for _, layout := range []string{`{freeze_rows: -1}`, `{max_column_width: 300}`, `{auto_fit_sample_rows: -5}`} {
+		_, err := NewExcelDataExporterFromYamlConfig(`
+sheets:
+  - name: "S"
+    layout: ` + layout + `
+    sections:
+      - id: "s"
+`)
+		assert.Error(t, err, layout)
+	}
+}
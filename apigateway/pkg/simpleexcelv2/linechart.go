@@ -0,0 +1,299 @@
+package simpleexcelv2
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// SectionTypeLineChart tabulates the data items by category and series and
+// draws the table as line charts, see LineChartConfig.
+const SectionTypeLineChart = "line_chart"
+
+// LineChartConfig configures a line_chart section. Items are grouped by
+// category_field (the x axis, one table row each) and series_field (one
+// table column and line each), and each group's value_field is aggregated
+// like a heatmap cell.
+//
+//	sections:
+//	  - id: "trend"
+//	    type: "line_chart"
+//	    line_chart:
+//	      category_field: "Month"
+//	      series_field: "DeptName"
+//	      value_field: "Headcount"
+//	      split_series: true
+//
+// Categories and series are sorted like heatmap keys. The charts float to
+// the right of the table, stacked when split_series draws one per series.
+type LineChartConfig struct {
+	CategoryField string `yaml:"category_field"`
+	// SeriesField is optional, without it the table has a single series
+	// named after value_field
+	SeriesField string `yaml:"series_field"`
+	// ValueField is aggregated, it is not needed to count items
+	ValueField string `yaml:"value_field"`
+	// Aggregate is count, sum, avg, min or max; sum by default, count
+	// without a value_field
+	Aggregate string `yaml:"aggregate"`
+	// CategoryLabel heads the category column, category_field by default
+	CategoryLabel string `yaml:"category_label"`
+	// SplitSeries draws one chart per series instead of one with all lines
+	SplitSeries bool `yaml:"split_series"`
+	// YAxisTitle labels the value axis
+	YAxisTitle string `yaml:"y_axis_title"`
+	// Width and Height size each chart in pixels, 480x260 by default
+	Width  uint `yaml:"width"`
+	Height uint `yaml:"height"`
+}
+
+const (
+	defaultLineChartWidth  = 480
+	defaultLineChartHeight = 260
+	// lineChartRowHeight is the default row height in pixels, used to stack
+	// split charts without overlap
+	lineChartRowHeight = 20
+)
+
+func init() {
+	RegisterSectionRenderer(SectionTypeLineChart, lineChartRenderer{})
+}
+
+// validate checks the line_chart block of a section at parse time.
+func (c *LineChartConfig) validate(sec *SectionConfig) error {
+	if c == nil {
+		return fmt.Errorf("section %s: line_chart sections need a line_chart block", sec.ID)
+	}
+	if c.CategoryField == "" {
+		return fmt.Errorf("section %s: line_chart needs a category_field", sec.ID)
+	}
+	switch c.Aggregate {
+	case "", HeatmapCount:
+	case HeatmapSum, HeatmapAvg, HeatmapMin, HeatmapMax:
+		if c.ValueField == "" {
+			return fmt.Errorf("section %s: line_chart aggregate %q needs a value_field", sec.ID, c.Aggregate)
+		}
+	default:
+		return fmt.Errorf("section %s: unknown line_chart aggregate %q", sec.ID, c.Aggregate)
+	}
+	return nil
+}
+
+// heatmap is the pivot of the chart table: categories are rows, series are
+// columns.
+func (c *LineChartConfig) heatmap() *HeatmapConfig {
+	return &HeatmapConfig{RowField: c.CategoryField, ColumnField: c.SeriesField, ValueField: c.ValueField, Aggregate: c.Aggregate}
+}
+
+type lineChartRenderer struct{}
+
+// table pivots the bound data, with a single unnamed series when the
+// section has no series_field.
+func (lineChartRenderer) table(rc *RenderContext) (*heatmapMatrix, error) {
+	c := rc.Section.LineChart
+	if c == nil {
+		return nil, fmt.Errorf("line_chart section has no line_chart block")
+	}
+	if c.SeriesField != "" {
+		return heatmapRenderer{}.pivot(&RenderContext{File: rc.File, Sheet: rc.Sheet, Section: &SectionConfig{Data: rc.Section.Data, Heatmap: c.heatmap()}, exporter: rc.exporter})
+	}
+
+	m := &heatmapMatrix{cells: make(map[[2]string]*heatmapCell)}
+	items := reflect.ValueOf(rc.Section.Data)
+	if items.Kind() != reflect.Slice {
+		return m, nil
+	}
+	aggregate := c.heatmap().aggregate()
+	name := c.ValueField
+	if name == "" {
+		name = "Count"
+	}
+	m.cols = []interface{}{name}
+	seen := make(map[string]bool)
+	for i := 0; i < items.Len(); i++ {
+		item := items.Index(i)
+		category := rc.Value(item, c.CategoryField)
+		key := [2]string{fmt.Sprint(category), name}
+		if !seen[key[0]] {
+			seen[key[0]] = true
+			m.rows = append(m.rows, category)
+		}
+		var v float64
+		if aggregate != HeatmapCount {
+			f, ok := toFloat(rc.Value(item, c.ValueField))
+			if !ok {
+				return nil, fmt.Errorf("line_chart value %s of item %d is not a number", c.ValueField, i)
+			}
+			v = f
+		}
+		cell := m.cells[key]
+		if cell == nil {
+			cell = &heatmapCell{}
+			m.cells[key] = cell
+		}
+		cell.add(v)
+	}
+	sortHeatmapKeys(m.rows)
+	return m, nil
+}
+
+func (r lineChartRenderer) Size(rc *RenderContext) (int, int) {
+	rows := 1 // series names
+	if rc.Section.Title != nil {
+		rows++
+	}
+	m, err := r.table(rc)
+	if err != nil {
+		// Render reports the error
+		return 1, rows
+	}
+	return 1 + len(m.cols), rows + len(m.rows)
+}
+
+func (r lineChartRenderer) Render(rc *RenderContext) error {
+	sec := rc.Section
+	c := sec.LineChart
+	m, err := r.table(rc)
+	if err != nil {
+		return err
+	}
+	width := 1 + len(m.cols)
+	row := 0
+
+	if sec.Title != nil {
+		defaultTitle := &StyleTemplate{
+			Font:      &FontTemplate{Bold: true},
+			Alignment: &AlignmentTemplate{Horizontal: "center", Vertical: "top"},
+		}
+		styleID, err := rc.Style(resolveStyle(sec.TitleStyle, defaultTitle, sec.Locked))
+		if err != nil {
+			return err
+		}
+		rc.File.SetCellValue(rc.Sheet, rc.Cell(0, row), sec.Title)
+		rc.File.MergeCell(rc.Sheet, rc.Cell(0, row), rc.Cell(width-1, row))
+		rc.File.SetCellStyle(rc.Sheet, rc.Cell(0, row), rc.Cell(width-1, row), styleID)
+		row++
+	}
+
+	// Header: category label, then the series names
+	defaultHeader := &StyleTemplate{
+		Font:      &FontTemplate{Bold: true},
+		Alignment: &AlignmentTemplate{Horizontal: "center", Vertical: "top"},
+	}
+	headerID, err := rc.Style(resolveStyle(sec.HeaderStyle, defaultHeader, sec.Locked))
+	if err != nil {
+		return err
+	}
+	headerRow := row
+	header := make([]interface{}, width)
+	header[0] = c.CategoryLabel
+	if c.CategoryLabel == "" {
+		header[0] = c.CategoryField
+	}
+	copy(header[1:], m.cols)
+	rc.File.SetSheetRow(rc.Sheet, rc.Cell(0, row), &header)
+	rc.File.SetCellStyle(rc.Sheet, rc.Cell(0, row), rc.Cell(width-1, row), headerID)
+	row++
+
+	dataID, err := rc.Style(resolveStyle(sec.DataStyle, nil, sec.Locked))
+	if err != nil {
+		return err
+	}
+	dataRow := row
+	aggregate := c.heatmap().aggregate()
+	for _, category := range m.rows {
+		values := make([]interface{}, width)
+		values[0] = category
+		for j, series := range m.cols {
+			if cell := m.cells[[2]string{fmt.Sprint(category), fmt.Sprint(series)}]; cell != nil {
+				values[1+j] = cell.value(aggregate)
+			}
+		}
+		rc.File.SetSheetRow(rc.Sheet, rc.Cell(0, row), &values)
+		rc.File.SetCellStyle(rc.Sheet, rc.Cell(0, row), rc.Cell(width-1, row), dataID)
+		row++
+	}
+
+	if len(m.rows) > 0 {
+		if err := r.addCharts(rc, m, headerRow, dataRow, row-1); err != nil {
+			return err
+		}
+	}
+
+	rc.Place(SectionPlacement{StartRow: rc.Row + dataRow, StartCol: rc.Col, DataLen: len(m.rows)})
+	return nil
+}
+
+// addCharts draws the table rows firstRow to lastRow (offsets from the
+// section's top) next to it, one chart for all series or one per series.
+func (lineChartRenderer) addCharts(rc *RenderContext, m *heatmapMatrix, headerRow, firstRow, lastRow int) error {
+	c := rc.Section.LineChart
+	width, height := c.Width, c.Height
+	if width == 0 {
+		width = defaultLineChartWidth
+	}
+	if height == 0 {
+		height = defaultLineChartHeight
+	}
+
+	ref := func(col, row int) string {
+		name, _ := excelize.CoordinatesToCellName(rc.Col+col, rc.Row+row, true)
+		return name
+	}
+	sheet := quoteSheetName(rc.Sheet)
+	categories := fmt.Sprintf("%s!%s:%s", sheet, ref(0, firstRow), ref(0, lastRow))
+	series := make([]excelize.ChartSeries, len(m.cols))
+	for j := range m.cols {
+		series[j] = excelize.ChartSeries{
+			Name:       fmt.Sprintf("%s!%s", sheet, ref(1+j, headerRow)),
+			Categories: categories,
+			Values:     fmt.Sprintf("%s!%s:%s", sheet, ref(1+j, firstRow), ref(1+j, lastRow)),
+		}
+	}
+
+	groups := [][]excelize.ChartSeries{series}
+	titles := []string{fmt.Sprint(rc.Section.Title)}
+	if rc.Section.Title == nil {
+		titles[0] = ""
+	}
+	if c.SplitSeries {
+		groups, titles = nil, nil
+		for j, s := range series {
+			groups = append(groups, []excelize.ChartSeries{s})
+			titles = append(titles, fmt.Sprint(m.cols[j]))
+		}
+	}
+
+	// Stack the charts in the column after the table
+	rowsPerChart := int(height)/lineChartRowHeight + 1
+	for i, group := range groups {
+		chart := &excelize.Chart{
+			Type:      excelize.Line,
+			Series:    group,
+			Dimension: excelize.ChartDimension{Width: width, Height: height},
+			Legend:    excelize.ChartLegend{Position: "bottom"},
+			YAxis:     excelize.ChartAxis{MajorGridLines: true},
+		}
+		if len(group) == 1 {
+			chart.Legend.Position = "none"
+		}
+		if titles[i] != "" {
+			chart.Title = []excelize.RichTextRun{{Text: titles[i]}}
+		}
+		if c.YAxisTitle != "" {
+			chart.YAxis.Title = []excelize.RichTextRun{{Text: c.YAxisTitle}}
+		}
+		anchor := rc.Cell(len(m.cols)+2, i*rowsPerChart)
+		if err := rc.File.AddChart(rc.Sheet, anchor, chart); err != nil {
+			return fmt.Errorf("line chart %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// quoteSheetName quotes a sheet name for use in a cell reference.
+func quoteSheetName(name string) string {
+	return "'" + strings.ReplaceAll(name, "'", "''") + "'"
+}
@@ -0,0 +1,143 @@
+package simpleexcelv2
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type headcountRow struct {
+	Month     string
+	DeptName  string
+	Headcount int
+}
+
+var headcountRows = []headcountRow{
+	{"2024-02", "Sales", 12},
+	{"2024-01", "Sales", 10},
+	{"2024-01", "Finance", 4},
+	{"2024-02", "Finance", 5},
+	{"2024-03", "Sales", 11},
+}
+
+// countCharts counts the chart parts of the built workbook.
+func countCharts(t *testing.T, exporter *ExcelDataExporter) int {
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+	defer f.Close()
+	n := 0
+	f.Pkg.Range(func(k, v interface{}) bool {
+		if strings.HasPrefix(k.(string), "xl/charts/chart") {
+			n++
+		}
+		return true
+	})
+	return n
+}
+
+func TestLineChart_Table(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(`
+sheets:
+  - name: "Trend"
+    sections:
+      - id: "trend"
+        type: "line_chart"
+        title: "Headcount"
+        line_chart:
+          category_field: "Month"
+          category_label: "Month"
+          series_field: "DeptName"
+          value_field: "Headcount"
+`)
+	require.NoError(t, err)
+	exporter.BindSectionData("trend", headcountRows)
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+	defer f.Close()
+
+	rows, err := f.GetRows("Trend")
+	require.NoError(t, err)
+	require.Len(t, rows, 5)
+	assert.Equal(t, "Headcount", rows[0][0])
+	assert.Equal(t, []string{"Month", "Finance", "Sales"}, rows[1])
+	assert.Equal(t, []string{"2024-01", "4", "10"}, rows[2])
+	assert.Equal(t, []string{"2024-02", "5", "12"}, rows[3])
+	assert.Equal(t, []string{"2024-03", "", "11"}, rows[4])
+
+	assert.Equal(t, 1, countCharts(t, exporter))
+}
+
+func TestLineChart_SplitSeries(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(`
+sheets:
+  - name: "Head count"
+    sections:
+      - id: "trend"
+        type: "line_chart"
+        line_chart:
+          category_field: "Month"
+          series_field: "DeptName"
+          value_field: "Headcount"
+          split_series: true
+          y_axis_title: "Employees"
+`)
+	require.NoError(t, err)
+	exporter.BindSectionData("trend", headcountRows)
+
+	assert.Equal(t, 2, countCharts(t, exporter))
+}
+
+func TestLineChart_SingleSeries(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(`
+sheets:
+  - name: "Trend"
+    sections:
+      - id: "trend"
+        type: "line_chart"
+        line_chart:
+          category_field: "Month"
+`)
+	require.NoError(t, err)
+	exporter.BindSectionData("trend", headcountRows)
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+	defer f.Close()
+
+	rows, err := f.GetRows("Trend")
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"Month", "Count"},
+		{"2024-01", "2"},
+		{"2024-02", "2"},
+		{"2024-03", "1"},
+	}, rows)
+}
+
+func TestLineChart_Validation(t *testing.T) {
+	tests := []struct {
+		name  string
+		block string
+		err   string
+	}{
+		{"no block", "", "need a line_chart block"},
+		{"no category", "\n        line_chart:\n          value_field: \"Headcount\"", "needs a category_field"},
+		{"sum without value", "\n        line_chart:\n          category_field: \"Month\"\n          aggregate: \"sum\"", `aggregate "sum" needs a value_field`},
+		{"unknown aggregate", "\n        line_chart:\n          category_field: \"Month\"\n          aggregate: \"median\"", `unknown line_chart aggregate "median"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewExcelDataExporterFromYamlConfig(`
+sheets:
+  - name: "Trend"
+    sections:
+      - id: "trend"
+        type: "line_chart"` + tt.block + "\n")
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.err)
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package simpleexcelv2
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocaleVariable is the template variable that selects number and date formats.
+const LocaleVariable = "locale"
+
+// LocaleFormat describes how numbers and dates are shown for a locale.
+//
+// In xlsx output the Excel format codes are locale neutral ("," groups and "."
+// marks decimals) and Excel substitutes the viewer's separators, so only the
+// date order differs per locale. CSV and HTML output are plain text and use
+// the separators directly, e.g. 1.234,56 for de-DE.
+type LocaleFormat struct {
+	Tag         string
+	DecimalSep  string
+	ThousandSep string
+	// DateLayout is the Go layout for text output
+	DateLayout string
+	// DateNumFmt is the Excel number format code for date cells
+	DateNumFmt string
+}
+
+// IntNumFmt and FloatNumFmt are the Excel format codes for numeric cells.
+const (
+	IntNumFmt   = "#,##0"
+	FloatNumFmt = "#,##0.00"
+)
+
+var localeFormats = map[string]LocaleFormat{
+	"en-US": {Tag: "en-US", DecimalSep: ".", ThousandSep: ",", DateLayout: "01/02/2006", DateNumFmt: "mm/dd/yyyy"},
+	"en-GB": {Tag: "en-GB", DecimalSep: ".", ThousandSep: ",", DateLayout: "02/01/2006", DateNumFmt: "dd/mm/yyyy"},
+	"de-DE": {Tag: "de-DE", DecimalSep: ",", ThousandSep: ".", DateLayout: "02.01.2006", DateNumFmt: "dd.mm.yyyy"},
+	"fr-FR": {Tag: "fr-FR", DecimalSep: ",", ThousandSep: " ", DateLayout: "02/01/2006", DateNumFmt: "dd/mm/yyyy"},
+	"es-ES": {Tag: "es-ES", DecimalSep: ",", ThousandSep: ".", DateLayout: "02/01/2006", DateNumFmt: "dd/mm/yyyy"},
+	"it-IT": {Tag: "it-IT", DecimalSep: ",", ThousandSep: ".", DateLayout: "02/01/2006", DateNumFmt: "dd/mm/yyyy"},
+	"ja-JP": {Tag: "ja-JP", DecimalSep: ".", ThousandSep: ",", DateLayout: "2006/01/02", DateNumFmt: "yyyy/mm/dd"},
+	"vi-VN": {Tag: "vi-VN", DecimalSep: ",", ThousandSep: ".", DateLayout: "02/01/2006", DateNumFmt: "dd/mm/yyyy"},
+}
+
+// languageDefaults maps a bare language to the locale used when only the
+// language is given (e.g. "de" or an unknown region like "de-AT").
+var languageDefaults = map[string]string{
+	"en": "en-US",
+	"de": "de-DE",
+	"fr": "fr-FR",
+	"es": "es-ES",
+	"it": "it-IT",
+	"ja": "ja-JP",
+	"vi": "vi-VN",
+}
+
+// LookupLocale returns the formats for a BCP 47 tag such as "de-DE" or
+// "de_DE". Unknown regions fall back to the language default.
+func LookupLocale(tag string) (LocaleFormat, bool) {
+	tag = strings.ReplaceAll(strings.TrimSpace(tag), "_", "-")
+	parts := strings.SplitN(tag, "-", 2)
+	lang := strings.ToLower(parts[0])
+	if len(parts) == 2 {
+		if lf, ok := localeFormats[lang+"-"+strings.ToUpper(parts[1])]; ok {
+			return lf, true
+		}
+	}
+	if def, ok := languageDefaults[lang]; ok {
+		return localeFormats[def], true
+	}
+	return LocaleFormat{}, false
+}
+
+// SetLocale selects the default number and date formats. Unknown tags are
+// ignored and leave the current setting unchanged.
+// ResolveVariables calls it automatically when a "locale" variable is supplied.
+func (e *ExcelDataExporter) SetLocale(tag string) *ExcelDataExporter {
+	if lf, ok := LookupLocale(tag); ok {
+		e.locale = &lf
+	} else {
+		e.log("unknown locale %q ignored", tag)
+	}
+	return e
+}
+
+// columnLocale returns the effective locale for a column: its own `locale`
+// override first, then the exporter locale. Nil means no locale formatting.
+func (e *ExcelDataExporter) columnLocale(col ColumnConfig) *LocaleFormat {
+	if col.Locale != "" {
+		if lf, ok := LookupLocale(col.Locale); ok {
+			return &lf
+		}
+	}
+	return e.locale
+}
+
+// Column value types used to pick locale number formats, see ColumnConfig.Type.
+const (
+	ColumnTypeInt   = "int"
+	ColumnTypeFloat = "float"
+	ColumnTypeDate  = "date"
+)
+
+// NumFmt returns the Excel format code for a column value type, or "" for
+// types that are neither numeric nor dates.
+func (lf *LocaleFormat) NumFmt(valueType string) string {
+	switch valueType {
+	case ColumnTypeDate:
+		return lf.DateNumFmt
+	case ColumnTypeFloat:
+		return FloatNumFmt
+	case ColumnTypeInt:
+		return IntNumFmt
+	}
+	return ""
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// columnValueType returns the value type of a column of items of type elem:
+// its configured type, else the declared type of the struct field it names.
+// Columns read through formatters or accessors, or from maps, have no
+// declared type and return "" unless configured.
+func columnValueType(elem reflect.Type, col ColumnConfig) string {
+	if col.Type != "" {
+		return col.Type
+	}
+	if elem == nil || col.accessor != nil || col.Formatter != nil || col.FormatterName != "" || len(col.Formatters) > 0 {
+		return ""
+	}
+	for elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return ""
+	}
+	field, ok := elem.FieldByName(col.FieldName)
+	if !ok {
+		return ""
+	}
+	t := field.Type
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return ColumnTypeDate
+	}
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return ColumnTypeFloat
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return ColumnTypeInt
+	}
+	return ""
+}
+
+// FormatText renders numbers and dates as text with the locale separators,
+// other values are returned unchanged.
+func (lf *LocaleFormat) FormatText(val interface{}) interface{} {
+	if t, ok := val.(time.Time); ok {
+		return t.Format(lf.DateLayout)
+	}
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return lf.groupDigits(strconv.FormatInt(rv.Int(), 10), "")
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return lf.groupDigits(strconv.FormatUint(rv.Uint(), 10), "")
+	case reflect.Float32, reflect.Float64:
+		f := rv.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return val
+		}
+		s := strconv.FormatFloat(f, 'f', 2, 64)
+		intPart, frac := s[:len(s)-3], s[len(s)-2:]
+		return lf.groupDigits(intPart, frac)
+	}
+	return val
+}
+
+func (lf *LocaleFormat) groupDigits(intPart, frac string) string {
+	sign := ""
+	if strings.HasPrefix(intPart, "-") {
+		sign, intPart = "-", intPart[1:]
+	}
+	var sb strings.Builder
+	sb.WriteString(sign)
+	for i, r := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			sb.WriteString(lf.ThousandSep)
+		}
+		sb.WriteRune(r)
+	}
+	if frac != "" {
+		sb.WriteString(lf.DecimalSep)
+		sb.WriteString(frac)
+	}
+	return sb.String()
+}
+
+// columnDataStyle returns the data style for a column of items of type elem
+// with its number format applied. The column's date_format (for date
+// columns) and format win over the style's number_format, which wins over the
+// locale default for the column value type.
+func (e *ExcelDataExporter) columnDataStyle(style *StyleTemplate, col ColumnConfig, elem reflect.Type) *StyleTemplate {
+	valueType := columnValueType(elem, col)
+	numFmt := col.Format
+	if col.DateFormat != "" && valueType == ColumnTypeDate {
+		numFmt = col.DateFormat
+	}
+	if numFmt == "" && style.NumberFormat == "" {
+		if lf := e.columnLocale(col); lf != nil {
+			numFmt = lf.NumFmt(valueType)
+		}
+	}
+	if numFmt == "" {
+		return style
+	}
+	formatted := *style
+	formatted.NumberFormat = numFmt
+	return &formatted
+}
+
+// formatCellValue extracts a column value from an item and applies the
+// column formatter, if any.
+func (e *ExcelDataExporter) formatCellValue(item reflect.Value, col ColumnConfig) interface{} {
+	return e.formatValue(&col, e.columnValue(item, &col))
+}
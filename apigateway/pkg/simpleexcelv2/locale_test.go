@@ -0,0 +1,134 @@
+package simpleexcelv2
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type localeRow struct {
+	Name   string
+	Amount float64
+	Count  int
+	Hired  time.Time
+}
+
+const localeYAML = `
+variables:
+  locale:
+    type: string
+    default: "en-US"
+sheets:
+  - name: "Data"
+    sections:
+      - id: "rows"
+        show_header: true
+        columns:
+          - field_name: "Name"
+            header: "Name"
+          - field_name: "Amount"
+            header: "Amount"
+          - field_name: "Count"
+            header: "Count"
+            locale: "en-US"
+          - field_name: "Hired"
+            header: "Hired"
+`
+
+func TestLookupLocale(t *testing.T) {
+	lf, ok := LookupLocale("de_de")
+	require.True(t, ok)
+	assert.Equal(t, "de-DE", lf.Tag)
+
+	lf, ok = LookupLocale("de-AT")
+	require.True(t, ok)
+	assert.Equal(t, "de-DE", lf.Tag, "unknown region falls back to the language")
+
+	_, ok = LookupLocale("xx")
+	assert.False(t, ok)
+}
+
+func TestLocaleFormatText(t *testing.T) {
+	de, _ := LookupLocale("de-DE")
+	us, _ := LookupLocale("en-US")
+
+	assert.Equal(t, "1.234,56", de.FormatText(1234.56))
+	assert.Equal(t, "-1.234.567", de.FormatText(-1234567))
+	assert.Equal(t, "1,234.56", us.FormatText(1234.56))
+	assert.Equal(t, "31.01.2020", de.FormatText(time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "text", de.FormatText("text"))
+}
+
+func TestLocaleVariable_CSVAndExcel(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(localeYAML)
+	require.NoError(t, err)
+
+	_, err = exporter.ResolveVariables(map[string]interface{}{"locale": "de-DE"})
+	require.NoError(t, err)
+
+	rows := []localeRow{{Name: "A", Amount: 1234.5, Count: 12000, Hired: time.Date(2020, 1, 31, 0, 0, 0, 0, time.UTC)}}
+	exporter.BindSectionData("rows", rows)
+
+	var buf bytes.Buffer
+	require.NoError(t, exporter.ToCSV(&buf))
+	lines := strings.Split(buf.String(), "\n")
+	// Count keeps its en-US column override
+	assert.Equal(t, `A,"1.234,50","12,000",31.01.2020`, lines[1])
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+	defer f.Close()
+
+	styleID, err := f.GetCellStyle("Data", "D2")
+	require.NoError(t, err)
+	style, err := f.GetStyle(styleID)
+	require.NoError(t, err)
+	require.NotNil(t, style.CustomNumFmt)
+	assert.Equal(t, "dd.mm.yyyy", *style.CustomNumFmt)
+}
+
+func TestLocale_NotSetKeepsRawValues(t *testing.T) {
+	exporter := NewExcelDataExporter()
+	exporter.AddSheet("Data").AddSection(&SectionConfig{
+		Data: []localeRow{{Name: "A", Amount: 1234.5}},
+		Columns: []ColumnConfig{
+			{FieldName: "Amount", Header: "Amount"},
+		},
+	})
+
+	var buf bytes.Buffer
+	require.NoError(t, exporter.ToCSV(&buf))
+	assert.Contains(t, buf.String(), "1234.5,A,")
+}
+
+func TestLocale_FormatFollowsColumnType(t *testing.T) {
+	exporter := NewExcelDataExporter().SetLocale("de-DE")
+	exporter.AddSheet("Data").AddSection(&SectionConfig{
+		// The first row holds an int, the column is declared float
+		Data: []map[string]interface{}{{"amount": 3, "note": 1}, {"amount": 2.5, "note": "x"}},
+		Columns: []ColumnConfig{
+			{FieldName: "amount", Header: "Amount", Type: ColumnTypeFloat},
+			{FieldName: "note", Header: "Note"},
+		},
+	})
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+	defer f.Close()
+
+	numFmt := func(cell string) *string {
+		styleID, err := f.GetCellStyle("Data", cell)
+		require.NoError(t, err)
+		style, err := f.GetStyle(styleID)
+		require.NoError(t, err)
+		return style.CustomNumFmt
+	}
+	require.NotNil(t, numFmt("A1"))
+	assert.Equal(t, FloatNumFmt, *numFmt("A1"))
+	assert.Equal(t, FloatNumFmt, *numFmt("A2"))
+	assert.Nil(t, numFmt("B1"), "map columns without a type keep the general format")
+}
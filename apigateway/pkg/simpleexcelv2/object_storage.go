@@ -0,0 +1,76 @@
+package simpleexcelv2
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// ObjectWriterProvider opens a writer for an object in cloud storage.
+// pkg/objectstorage provides S3 and GCS implementations.
+type ObjectWriterProvider interface {
+	NewWriter(ctx context.Context, bucket, key string) (io.WriteCloser, error)
+}
+
+// objectAborter is implemented by writers that can discard a partial upload.
+type objectAborter interface {
+	CloseWithError(err error) error
+}
+
+// SetObjectStorage attaches the provider used by ExportToObjectStorage.
+func (e *ExcelDataExporter) SetObjectStorage(p ObjectWriterProvider) *ExcelDataExporter {
+	e.objectStorage = p
+	return e
+}
+
+// ExportToObjectStorage renders the workbook and streams it to bucket/key
+// without writing a temp file. If rendering fails the upload is aborted.
+func (e *ExcelDataExporter) ExportToObjectStorage(ctx context.Context, bucket, key string) error {
+	w, err := e.openObjectWriter(ctx, bucket, key)
+	if err != nil {
+		return err
+	}
+	if err := e.ToWriterContext(ctx, w); err != nil {
+		abortObjectWriter(w, err)
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to finish upload of %s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
+
+// StartStreamToObjectStorage is StartStream with the output going to bucket/key.
+// Closing the returned Streamer completes the upload.
+func (e *ExcelDataExporter) StartStreamToObjectStorage(ctx context.Context, bucket, key string) (*Streamer, error) {
+	w, err := e.openObjectWriter(ctx, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+	streamer, err := e.StartStreamContext(ctx, w)
+	if err != nil {
+		abortObjectWriter(w, err)
+		return nil, err
+	}
+	streamer.closer = w
+	return streamer, nil
+}
+
+func (e *ExcelDataExporter) openObjectWriter(ctx context.Context, bucket, key string) (io.WriteCloser, error) {
+	if e.objectStorage == nil {
+		return nil, fmt.Errorf("object storage is not configured, call SetObjectStorage first")
+	}
+	w, err := e.objectStorage.NewWriter(ctx, bucket, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open object %s/%s: %w", bucket, key, err)
+	}
+	return w, nil
+}
+
+func abortObjectWriter(w io.WriteCloser, cause error) {
+	if a, ok := w.(objectAborter); ok {
+		a.CloseWithError(cause)
+		return
+	}
+	w.Close()
+}
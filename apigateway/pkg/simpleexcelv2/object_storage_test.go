@@ -0,0 +1,118 @@
+package simpleexcelv2
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+type memoryObject struct {
+	bytes.Buffer
+	closed  bool
+	aborted error
+}
+
+func (o *memoryObject) Close() error {
+	o.closed = true
+	return nil
+}
+
+func (o *memoryObject) CloseWithError(err error) error {
+	o.aborted = err
+	return err
+}
+
+type memoryStorage struct {
+	objects map[string]*memoryObject
+}
+
+func (m *memoryStorage) NewWriter(ctx context.Context, bucket, key string) (io.WriteCloser, error) {
+	if m.objects == nil {
+		m.objects = make(map[string]*memoryObject)
+	}
+	obj := &memoryObject{}
+	m.objects[bucket+"/"+key] = obj
+	return obj, nil
+}
+
+func TestExportToObjectStorage(t *testing.T) {
+	store := &memoryStorage{}
+	exporter := NewExcelDataExporter().SetObjectStorage(store)
+	exporter.AddSheet("Report").AddSection(&SectionConfig{
+		ShowHeader: true,
+		Data:       []struct{ Name string }{{"Alice"}},
+	})
+
+	require.NoError(t, exporter.ExportToObjectStorage(context.Background(), "reports", "2024/report.xlsx"))
+
+	obj := store.objects["reports/2024/report.xlsx"]
+	require.NotNil(t, obj)
+	assert.True(t, obj.closed)
+
+	f, err := excelize.OpenReader(&obj.Buffer)
+	require.NoError(t, err)
+	val, _ := f.GetCellValue("Report", "A2")
+	assert.Equal(t, "Alice", val)
+}
+
+func TestExportToObjectStorage_NotConfigured(t *testing.T) {
+	exporter := NewExcelDataExporter()
+	exporter.AddSheet("Report")
+	assert.Error(t, exporter.ExportToObjectStorage(context.Background(), "reports", "report.xlsx"))
+}
+
+func TestStartStreamToObjectStorage(t *testing.T) {
+	store := &memoryStorage{}
+	exporter := NewExcelDataExporter().SetObjectStorage(store)
+	exporter.AddSheet("Stream").AddSection(&SectionConfig{
+		ID:         "rows",
+		ShowHeader: true,
+		Columns:    []ColumnConfig{{FieldName: "ID", Header: "ID"}},
+	})
+
+	streamer, err := exporter.StartStreamToObjectStorage(context.Background(), "reports", "stream.xlsx")
+	require.NoError(t, err)
+	require.NoError(t, streamer.Write("rows", []struct{ ID int }{{1}, {2}}))
+	require.NoError(t, streamer.Close())
+
+	obj := store.objects["reports/stream.xlsx"]
+	require.NotNil(t, obj)
+	assert.True(t, obj.closed)
+	assert.Nil(t, obj.aborted)
+
+	f, err := excelize.OpenReader(&obj.Buffer)
+	require.NoError(t, err)
+	val, _ := f.GetCellValue("Stream", "A3")
+	assert.Equal(t, "2", val)
+}
+
+type failingWriter struct{ memoryObject }
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+type failingStorage struct{ obj *failingWriter }
+
+func (s *failingStorage) NewWriter(ctx context.Context, bucket, key string) (io.WriteCloser, error) {
+	return s.obj, nil
+}
+
+func TestExportToObjectStorage_AbortsOnWriteError(t *testing.T) {
+	store := &failingStorage{obj: &failingWriter{}}
+	exporter := NewExcelDataExporter().SetObjectStorage(store)
+	exporter.AddSheet("Report").AddSection(&SectionConfig{
+		Data: []struct{ Name string }{{"Alice"}},
+	})
+
+	err := exporter.ExportToObjectStorage(context.Background(), "reports", "report.xlsx")
+	require.Error(t, err)
+	assert.NotNil(t, store.obj.aborted)
+	assert.False(t, store.obj.closed)
+}
@@ -0,0 +1,251 @@
+package simpleexcelv2
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// SectionTypeOrgChart lays data items out as a reporting hierarchy, see
+// OrgChartConfig.
+const SectionTypeOrgChart = "org_chart"
+
+// maxOutlineLevel is the deepest row outline level Excel supports.
+const maxOutlineLevel = 7
+
+// OrgChartConfig configures an org_chart section. Items are linked by
+// manager_field referencing another item's id_field; each item is written
+// below its manager, with the first column indented by depth and the reports
+// of every manager grouped as outline rows that can be collapsed in Excel.
+//
+//	sections:
+//	  - id: "orgchart"
+//	    type: "org_chart"
+//	    columns:
+//	      - field_name: "Name"
+//	      - field_name: "EmpNo"
+//	    org_chart:
+//	      id_field: "EmpNo"
+//	      manager_field: "ManagerNo"
+//
+// Items whose manager is empty, zero or not in the data are top level.
+type OrgChartConfig struct {
+	IDField      string `yaml:"id_field"`
+	ManagerField string `yaml:"manager_field"`
+}
+
+func init() {
+	RegisterSectionRenderer(SectionTypeOrgChart, orgChartRenderer{})
+}
+
+// validate checks the org_chart block of a section at parse time.
+func (o *OrgChartConfig) validate(sec *SectionConfig) error {
+	if o == nil {
+		return fmt.Errorf("section %s: org_chart sections need an org_chart block", sec.ID)
+	}
+	if o.IDField == "" || o.ManagerField == "" {
+		return fmt.Errorf("section %s: org_chart needs id_field and manager_field", sec.ID)
+	}
+	if len(sec.Columns) == 0 {
+		return fmt.Errorf("section %s: org_chart needs at least one column", sec.ID)
+	}
+	return nil
+}
+
+// orgNode is an item of the chart in display order.
+type orgNode struct {
+	index, depth int
+}
+
+type orgChartRenderer struct{}
+
+// order returns the items depth first, each manager followed by its reports
+// in data order. Items caught in a reporting cycle start at the top level.
+func (orgChartRenderer) order(rc *RenderContext, items reflect.Value) []orgNode {
+	o := rc.Section.OrgChart
+	n := items.Len()
+	ids := make(map[string]int, n)
+	for i := 0; i < n; i++ {
+		id := fmt.Sprint(rc.Value(items.Index(i), o.IDField))
+		if _, dup := ids[id]; !dup {
+			ids[id] = i
+		}
+	}
+
+	parent := make([]int, n)
+	children := make(map[int][]int)
+	for i := 0; i < n; i++ {
+		parent[i] = -1
+		mgr := rc.Value(items.Index(i), o.ManagerField)
+		if v := reflect.ValueOf(mgr); !v.IsValid() || v.IsZero() {
+			continue
+		}
+		if p, ok := ids[fmt.Sprint(mgr)]; ok && p != i {
+			parent[i] = p
+			children[p] = append(children[p], i)
+		}
+	}
+
+	nodes := make([]orgNode, 0, n)
+	visited := make([]bool, n)
+	var visit func(i, depth int)
+	visit = func(i, depth int) {
+		visited[i] = true
+		nodes = append(nodes, orgNode{index: i, depth: depth})
+		for _, c := range children[i] {
+			if !visited[c] {
+				visit(c, depth+1)
+			}
+		}
+	}
+	for i := 0; i < n; i++ {
+		if parent[i] < 0 {
+			visit(i, 0)
+		}
+	}
+	for i := 0; i < n; i++ {
+		if !visited[i] {
+			visit(i, 0)
+		}
+	}
+	return nodes
+}
+
+func orgChartItems(sec *SectionConfig) reflect.Value {
+	items := reflect.ValueOf(sec.Data)
+	if items.Kind() != reflect.Slice {
+		return reflect.Value{}
+	}
+	return items
+}
+
+func (orgChartRenderer) Size(rc *RenderContext) (int, int) {
+	rows := 0
+	if rc.Section.Title != nil {
+		rows++
+	}
+	if rc.Section.ShowHeader {
+		rows++
+	}
+	if items := orgChartItems(rc.Section); items.IsValid() {
+		rows += items.Len()
+	}
+	return len(rc.Section.Columns), rows
+}
+
+func (r orgChartRenderer) Render(rc *RenderContext) error {
+	sec := rc.Section
+	if sec.OrgChart == nil {
+		return fmt.Errorf("org_chart section has no org_chart block")
+	}
+	width := len(sec.Columns)
+	row := 0
+
+	if sec.Title != nil && width > 0 {
+		defaultTitle := &StyleTemplate{
+			Font:      &FontTemplate{Bold: true},
+			Alignment: &AlignmentTemplate{Horizontal: "center", Vertical: "top"},
+		}
+		styleID, err := rc.Style(resolveStyle(sec.TitleStyle, defaultTitle, sec.Locked))
+		if err != nil {
+			return err
+		}
+		rc.File.SetCellValue(rc.Sheet, rc.Cell(0, row), sec.Title)
+		rc.File.MergeCell(rc.Sheet, rc.Cell(0, row), rc.Cell(width-1, row))
+		rc.File.SetCellStyle(rc.Sheet, rc.Cell(0, row), rc.Cell(width-1, row), styleID)
+		row++
+	}
+
+	for j, col := range sec.Columns {
+		if col.Width > 0 {
+			name := rc.exporter.getColName(rc.Col + j)
+			rc.File.SetColWidth(rc.Sheet, name, name, col.Width)
+		}
+	}
+	if sec.ShowHeader && width > 0 {
+		defaultHeader := &StyleTemplate{
+			Font:      &FontTemplate{Bold: true},
+			Alignment: &AlignmentTemplate{Horizontal: "center", Vertical: "top"},
+		}
+		headerID, err := rc.Style(resolveStyle(sec.HeaderStyle, defaultHeader, sec.Locked))
+		if err != nil {
+			return err
+		}
+		header := make([]interface{}, width)
+		for j, col := range sec.Columns {
+			header[j] = col.Header
+			if col.Header == "" {
+				header[j] = col.FieldName
+			}
+		}
+		rc.File.SetSheetRow(rc.Sheet, rc.Cell(0, row), &header)
+		rc.File.SetCellStyle(rc.Sheet, rc.Cell(0, row), rc.Cell(width-1, row), headerID)
+		row++
+	}
+
+	dataStyle := resolveStyle(sec.DataStyle, nil, sec.Locked)
+	dataID, err := rc.Style(dataStyle)
+	if err != nil {
+		return err
+	}
+	dataRow := row
+	var nodes []orgNode
+	items := orgChartItems(sec)
+	if items.IsValid() {
+		nodes = r.order(rc, items)
+	}
+	if len(nodes) > 0 {
+		// Put the expand buttons on the manager rows rather than below their reports
+		summaryBelow := false
+		if err := rc.File.SetSheetProps(rc.Sheet, &excelize.SheetPropsOptions{OutlineSummaryBelow: &summaryBelow}); err != nil {
+			return err
+		}
+	}
+	for _, node := range nodes {
+		item := items.Index(node.index)
+		values := make([]interface{}, width)
+		for j := range sec.Columns {
+			values[j] = rc.columnValue(item, &sec.Columns[j], rc.Cell(j, row))
+		}
+		rc.File.SetSheetRow(rc.Sheet, rc.Cell(0, row), &values)
+		rc.File.SetCellStyle(rc.Sheet, rc.Cell(0, row), rc.Cell(width-1, row), dataID)
+		for j := range sec.Columns {
+			if err := rc.setColumnRichText(&sec.Columns[j], rc.Cell(j, row), values[j]); err != nil {
+				return err
+			}
+		}
+
+		if node.depth > 0 {
+			indented := *dataStyle
+			align := AlignmentTemplate{}
+			if indented.Alignment != nil {
+				align = *indented.Alignment
+			}
+			align.Horizontal = "left"
+			align.Indent = node.depth * 2
+			indented.Alignment = &align
+			indentID, err := rc.Style(&indented)
+			if err != nil {
+				return err
+			}
+			rc.File.SetCellStyle(rc.Sheet, rc.Cell(0, row), rc.Cell(0, row), indentID)
+
+			level := node.depth
+			if level > maxOutlineLevel {
+				level = maxOutlineLevel
+			}
+			if err := rc.File.SetRowOutlineLevel(rc.Sheet, rc.Row+row, uint8(level)); err != nil {
+				return err
+			}
+		}
+		row++
+	}
+
+	offsets := make(map[string]int, width)
+	for j, col := range sec.Columns {
+		offsets[col.FieldName] = j
+	}
+	rc.Place(SectionPlacement{StartRow: rc.Row + dataRow, StartCol: rc.Col, FieldOffsets: offsets, DataLen: len(nodes)})
+	return nil
+}
@@ -0,0 +1,125 @@
+package simpleexcelv2
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const orgChartYAML = `
+sheets:
+  - name: "Org"
+    sections:
+      - id: "org"
+        type: "org_chart"
+        title: "Org chart"
+        show_header: true
+        columns:
+          - field_name: "Name"
+            header: "Name"
+          - field_name: "EmpNo"
+            header: "No"
+        org_chart:
+          id_field: "EmpNo"
+          manager_field: "ManagerNo"
+`
+
+type orgRow struct {
+	EmpNo     int
+	ManagerNo int
+	Name      string
+}
+
+func TestOrgChartSection(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(orgChartYAML)
+	require.NoError(t, err)
+	exporter.BindSectionData("org", []orgRow{
+		{EmpNo: 3, ManagerNo: 2, Name: "Carol"},
+		{EmpNo: 2, ManagerNo: 1, Name: "Bob"},
+		{EmpNo: 1, Name: "Ann"},
+		{EmpNo: 4, ManagerNo: 1, Name: "Dan"},
+		{EmpNo: 5, ManagerNo: 99, Name: "Eve"}, // manager outside the data
+	})
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+	defer f.Close()
+
+	rows, err := f.GetRows("Org")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Name", "No"}, rows[1])
+	var names []string
+	for _, r := range rows[2:] {
+		names = append(names, r[0])
+	}
+	assert.Equal(t, []string{"Ann", "Bob", "Carol", "Dan", "Eve"}, names)
+
+	levels := map[int]uint8{}
+	indents := map[int]int{}
+	for r := 3; r <= 7; r++ {
+		level, err := f.GetRowOutlineLevel("Org", r)
+		require.NoError(t, err)
+		levels[r] = level
+		styleID, err := f.GetCellStyle("Org", fmt.Sprintf("A%d", r))
+		require.NoError(t, err)
+		style, err := f.GetStyle(styleID)
+		require.NoError(t, err)
+		if style.Alignment != nil {
+			indents[r] = style.Alignment.Indent
+		}
+	}
+	assert.Equal(t, map[int]uint8{3: 0, 4: 1, 5: 2, 6: 1, 7: 0}, levels)
+	assert.Equal(t, 4, indents[5])
+	assert.Equal(t, 0, indents[7])
+
+	props, err := f.GetSheetProps("Org")
+	require.NoError(t, err)
+	assert.False(t, *props.OutlineSummaryBelow, "expand buttons sit on the manager rows")
+
+	p := exporter.sectionMetadata["org"]
+	assert.Equal(t, 3, p.StartRow)
+	assert.Equal(t, 5, p.DataLen)
+}
+
+func TestOrgChartSection_Cycle(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(orgChartYAML)
+	require.NoError(t, err)
+	exporter.BindSectionData("org", []orgRow{
+		{EmpNo: 1, ManagerNo: 2, Name: "Ann"},
+		{EmpNo: 2, ManagerNo: 1, Name: "Bob"},
+	})
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+	defer f.Close()
+
+	rows, err := f.GetRows("Org")
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+	assert.Equal(t, "Ann", rows[2][0])
+	assert.Equal(t, "Bob", rows[3][0])
+}
+
+func TestOrgChartSection_RejectsInvalidConfig(t *testing.T) {
+	for name, block := range map[string]string{
+		"missing block": `
+        columns: [{ field_name: "Name" }]`,
+		"missing field": `
+        columns: [{ field_name: "Name" }]
+        org_chart: { id_field: "EmpNo" }`,
+		"no columns": `
+        org_chart: { id_field: "EmpNo", manager_field: "ManagerNo" }`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewExcelDataExporterFromYamlConfig(`
+sheets:
+  - name: "S"
+    sections:
+      - id: "o"
+        type: "org_chart"` + block)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -47,3 +47,43 @@ func BenchmarkRenderSections(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkRenderTypedSection(b *testing.B) {
+	type BenchRow struct {
+		ID    int
+		Name  string
+		Value float64
+		Note  string
+	}
+
+	rows := 1000
+	data := make([]BenchRow, rows)
+	for i := 0; i < rows; i++ {
+		data[i] = BenchRow{
+			ID:    i,
+			Name:  fmt.Sprintf("Item %d", i),
+			Value: float64(i) * 1.5,
+			Note:  "Some long note to simulate content",
+		}
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		exporter := NewExcelDataExporter()
+		AddTypedSection(exporter.AddSheet("BenchSheet"), SectionConfigT[BenchRow]{
+			SectionConfig: SectionConfig{Title: "Benchmark Section", ShowHeader: true},
+			Data:          data,
+			Columns: []TypedColumn[BenchRow]{
+				{ColumnConfig: ColumnConfig{Header: "ID", Width: 10}, Value: func(r BenchRow) any { return r.ID }},
+				{ColumnConfig: ColumnConfig{Header: "Name", Width: 30}, Value: func(r BenchRow) any { return r.Name }},
+				{ColumnConfig: ColumnConfig{Header: "Value", Width: 20}, Value: func(r BenchRow) any { return r.Value }},
+				{ColumnConfig: ColumnConfig{Header: "Notes", Width: 50}, Value: func(r BenchRow) any { return r.Note }},
+			},
+		})
+
+		_, err := exporter.BuildExcel()
+		if err != nil {
+			b.Fatalf("BuildExcel failed: %v", err)
+		}
+	}
+}
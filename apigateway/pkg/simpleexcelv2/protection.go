@@ -0,0 +1,67 @@
+package simpleexcelv2
+
+import (
+	"fmt"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ProtectionTemplate configures the protection applied to sheets with locked
+// cells.
+//
+//	sheets:
+//	  - name: "Employees"
+//	    protection:
+//	      password: "s3cret"
+//	      allow_sort: true
+//	      allow_insert_rows: true
+//
+// Without a password anyone can unprotect the sheet from Excel's Review tab.
+// Keep real passwords out of templates and set them with SetProtection.
+type ProtectionTemplate struct {
+	Password  string `yaml:"password"`
+	AllowSort bool   `yaml:"allow_sort"`
+	// AllowFilter lets users use the section filters, true by default
+	AllowFilter     *bool `yaml:"allow_filter"`
+	AllowInsertRows bool  `yaml:"allow_insert_rows"`
+}
+
+// SetProtection sets the protection of the sheet, used when it has locked cells.
+func (sb *SheetBuilder) SetProtection(protection *ProtectionTemplate) *SheetBuilder {
+	sb.protection = protection
+	return sb
+}
+
+// options returns the excelize protection options. A nil template gives the
+// defaults: no password, filtering and row/column formatting allowed.
+func (p *ProtectionTemplate) options() *excelize.SheetProtectionOptions {
+	opts := &excelize.SheetProtectionOptions{
+		FormatColumns:       true,
+		FormatRows:          true,
+		AutoFilter:          true,
+		SelectLockedCells:   true,
+		SelectUnlockedCells: true,
+	}
+	if p == nil {
+		return opts
+	}
+	if p.Password != "" {
+		// The legacy 16-bit hash is trivial to reverse
+		opts.AlgorithmName = "SHA-512"
+		opts.Password = p.Password
+	}
+	opts.Sort = p.AllowSort
+	if p.AllowFilter != nil {
+		opts.AutoFilter = *p.AllowFilter
+	}
+	opts.InsertRows = p.AllowInsertRows
+	return opts
+}
+
+// protectSheet protects a sheet rendered with locked cells.
+func protectSheet(f *excelize.File, sheet string, p *ProtectionTemplate) error {
+	if err := f.ProtectSheet(sheet, p.options()); err != nil {
+		return fmt.Errorf("sheet %s: protect: %w", sheet, err)
+	}
+	return nil
+}
@@ -2,6 +2,10 @@ package simpleexcelv2
 
 import (
 	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
 )
 
 func TestDataExporter_DefaultUnlockedCells(t *testing.T) {
@@ -70,3 +74,41 @@ func TestDataExporter_DefaultUnlockedCells(t *testing.T) {
 		}
 	}
 }
+
+func TestSheetProtection_Password(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(`
+sheets:
+  - name: "Locked"
+    protection:
+      password: "s3cret"
+    sections:
+      - id: "people"
+        locked: true
+        columns:
+          - field_name: "Name"
+`)
+	require.NoError(t, err)
+	exporter.BindSectionData("people", []struct{ Name string }{{"Ann"}})
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+	defer f.Close()
+
+	assert.ErrorIs(t, f.UnprotectSheet("Locked", "wrong"), excelize.ErrUnprotectSheetPassword)
+	assert.NoError(t, f.UnprotectSheet("Locked", "s3cret"))
+}
+
+func TestProtectionTemplate_Options(t *testing.T) {
+	defaults := (*ProtectionTemplate)(nil).options()
+	assert.Empty(t, defaults.Password)
+	assert.True(t, defaults.AutoFilter)
+	assert.False(t, defaults.Sort)
+	assert.False(t, defaults.InsertRows)
+
+	noFilter := false
+	opts := (&ProtectionTemplate{AllowSort: true, AllowInsertRows: true, AllowFilter: &noFilter}).options()
+	assert.True(t, opts.Sort)
+	assert.True(t, opts.InsertRows)
+	assert.False(t, opts.AutoFilter)
+	assert.True(t, opts.FormatRows, "hidden metadata rows can still be unhidden")
+}
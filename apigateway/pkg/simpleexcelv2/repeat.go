@@ -0,0 +1,116 @@
+package simpleexcelv2
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// SectionTypeRepeat is a section rendered once per group of its data, a map
+// of group keys to row slices such as map[string][]Employee.
+const SectionTypeRepeat = "repeat"
+
+// RepeatGroupPlaceholder is replaced by the group key in the titles of
+// repeat sections.
+const RepeatGroupPlaceholder = "{group}"
+
+// RepeatConfig configures a "repeat" section.
+//
+//	sections:
+//	  - id: "staff"
+//	    type: "repeat"
+//	    title: "Department {group}"
+//	    show_header: true
+//	    repeat:
+//	      gap: 1
+type RepeatConfig struct {
+	Gap int `yaml:"gap"` // Blank rows between the instances, 0 stacks them directly
+}
+
+// expandRepeats returns sections with every repeat section replaced by one
+// full section per group of its bound data, ordered by group key. The
+// instances are stacked below each other, their IDs are the repeat section ID
+// and the group key joined by a dot. Anchors to a repeat section refer to its
+// first instance for right_of and its last for below.
+func (e *ExcelDataExporter) expandRepeats(sections []*SectionConfig) []*SectionConfig {
+	type span struct{ first, last string }
+	spans := make(map[string]span)
+	var out []*SectionConfig
+	for _, sec := range sections {
+		if sec.Type != SectionTypeRepeat {
+			out = append(out, sec)
+			continue
+		}
+		data := sec.Data
+		if data == nil {
+			data = e.data[sec.ID]
+		}
+		keys, groups := repeatGroups(data)
+		prev := ""
+		for i, key := range keys {
+			inst := *sec
+			inst.Type = SectionTypeFull
+			inst.ID = sec.ID + "." + fmt.Sprint(key)
+			inst.Data = e.arrangeData(sec, groups[i])
+			inst.Columns = append([]ColumnConfig(nil), sec.Columns...)
+			if sec.Title != nil {
+				inst.Title = strings.ReplaceAll(fmt.Sprint(sec.Title), RepeatGroupPlaceholder, fmt.Sprint(key))
+			}
+			if prev != "" {
+				inst.Position, inst.RightOf, inst.Below = "", "", prev
+				if sec.Repeat != nil {
+					inst.gap = sec.Repeat.Gap
+				}
+			}
+			out = append(out, &inst)
+			prev = inst.ID
+		}
+		if len(keys) > 0 {
+			spans[sec.ID] = span{first: out[len(out)-len(keys)].ID, last: prev}
+		}
+	}
+	if len(spans) == 0 {
+		return out
+	}
+
+	for i, sec := range out {
+		below, okBelow := spans[sec.Below]
+		rightOf, okRight := spans[sec.RightOf]
+		if !okBelow && !okRight {
+			continue
+		}
+		moved := *sec
+		if okBelow {
+			moved.Below = below.last
+		}
+		if okRight {
+			moved.RightOf = rightOf.first
+		}
+		out[i] = &moved
+	}
+	return out
+}
+
+// repeatGroups returns the keys of a map of row slices, sorted as sort_by
+// orders values, and their slices. Other data gives no groups.
+func repeatGroups(data interface{}) ([]interface{}, []interface{}) {
+	v := reflect.ValueOf(data)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Map {
+		return nil, nil
+	}
+	mapKeys := v.MapKeys()
+	sort.SliceStable(mapKeys, func(a, b int) bool {
+		return compareValues(mapKeys[a].Interface(), mapKeys[b].Interface()) < 0
+	})
+	keys := make([]interface{}, len(mapKeys))
+	groups := make([]interface{}, len(mapKeys))
+	for i, k := range mapKeys {
+		keys[i] = k.Interface()
+		groups[i] = v.MapIndex(k).Interface()
+	}
+	return keys, groups
+}
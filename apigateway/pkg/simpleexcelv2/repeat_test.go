@@ -0,0 +1,123 @@
+package simpleexcelv2
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type repeatRow struct {
+	Name   string
+	Salary int
+}
+
+const repeatYAML = `
+sheets:
+  - name: "Staff"
+    sections:
+      - id: "intro"
+        type: "title"
+        title: "Staff by department"
+      - id: "staff"
+        type: "repeat"
+        title: "Department {group}"
+        show_header: true
+        sort_by: [-Salary]
+        repeat:
+          gap: 1
+        columns:
+          - field_name: "Name"
+            header: "Name"
+          - field_name: "Salary"
+            header: "Salary"
+      - id: "totals"
+        title: "Totals"
+        right_of: "staff"
+        columns:
+          - field_name: "Name"
+      - id: "footer"
+        type: "title"
+        title: "End"
+        below: "staff"
+`
+
+func repeatGroupsData() map[string][]repeatRow {
+	return map[string][]repeatRow{
+		"d002": {{"Cid", 300}},
+		"d001": {{"Ann", 100}, {"Bob", 200}},
+	}
+}
+
+func TestRepeat_OneSectionPerGroup(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(repeatYAML)
+	require.NoError(t, err)
+	exporter.BindSectionData("staff", repeatGroupsData()).
+		BindSectionData("totals", []repeatRow{{Name: "3 people"}})
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+	assert.Empty(t, exporter.Warnings())
+
+	want := map[string]string{
+		"A1": "Staff by department",
+		// d001 sorted first, its rows by sort_by
+		"A2": "Department d001", "A3": "Name", "A4": "Bob", "A5": "Ann",
+		// one blank row, then d002
+		"A6": "", "A7": "Department d002", "A8": "Name", "A9": "Cid",
+		// right_of the first instance, below the last one
+		"C2": "Totals", "C3": "3 people",
+		"A10": "End",
+	}
+	for cell, value := range want {
+		assert.Equal(t, value, cellValue(t, f, cell), cell)
+	}
+
+	placement, ok := exporter.sectionMetadata["staff.d002"]
+	require.True(t, ok)
+	assert.Equal(t, 9, placement.StartRow)
+	assert.Equal(t, 1, placement.DataLen)
+}
+
+func TestRepeat_CSVAndHTML(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(repeatYAML)
+	require.NoError(t, err)
+	exporter.BindSectionData("staff", repeatGroupsData()).
+		BindSectionData("totals", []repeatRow{})
+
+	var csv bytes.Buffer
+	require.NoError(t, exporter.ToCSV(&csv))
+	assert.Contains(t, csv.String(), "Department d001\nName,Salary\nBob,200\nAnn,100\n")
+	assert.Contains(t, csv.String(), "Department d002\nName,Salary\nCid,300\n")
+
+	var html bytes.Buffer
+	require.NoError(t, exporter.RenderHTML(context.Background(), &html))
+	assert.Contains(t, html.String(), `data-section="staff.d001"`)
+	assert.Less(t, strings.Index(html.String(), "d001"), strings.Index(html.String(), "d002"))
+}
+
+func TestRepeat_Warnings(t *testing.T) {
+	exporter := NewExcelDataExporter()
+	exporter.AddSheet("Staff").AddSection(&SectionConfig{
+		ID: "staff", Type: SectionTypeRepeat,
+		Columns: []ColumnConfig{{FieldName: "Dept"}},
+	})
+	exporter.BindSectionData("staff", repeatGroupsData())
+
+	_, err := exporter.BuildExcel()
+	require.NoError(t, err)
+	assert.Equal(t, []Warning{{Kind: WarningUnknownField, Sheet: "Staff", Section: "staff", Field: "Dept",
+		Message: "repeatRow has no field Dept"}}, exporter.Warnings(), "the rows of the groups are checked")
+}
+
+func TestRepeat_NotStreamed(t *testing.T) {
+	exporter := NewExcelDataExporter()
+	exporter.AddSheet("Staff").AddSection(&SectionConfig{ID: "staff", Type: SectionTypeRepeat})
+	exporter.BindSectionData("staff", repeatGroupsData())
+
+	_, err := exporter.StartStream(&bytes.Buffer{})
+	assert.EqualError(t, err, "section staff: repeat sections cannot be streamed")
+}
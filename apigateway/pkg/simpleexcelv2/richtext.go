@@ -0,0 +1,64 @@
+package simpleexcelv2
+
+import (
+	"fmt"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// RichTextFormatter turns a formatted cell value into rich text runs, e.g. a
+// red bold "OVERDUE: " prefix followed by the value in the cell's font. A nil
+// or empty result writes the value as plain text.
+//
+//	exporter.RegisterRichTextFormatter("overdue", func(v interface{}) []excelize.RichTextRun {
+//		s, _ := v.(string)
+//		if !strings.HasPrefix(s, "OVERDUE: ") {
+//			return nil
+//		}
+//		return []excelize.RichTextRun{
+//			{Text: "OVERDUE: ", Font: &excelize.Font{Bold: true, Color: "FF0000"}},
+//			{Text: strings.TrimPrefix(s, "OVERDUE: ")},
+//		}
+//	})
+//
+// Columns reference it by name with `rich_text: "overdue"`, or set
+// ColumnConfig.RichTextFormatter programmatically. It runs after the
+// column's formatters.
+type RichTextFormatter func(v interface{}) []excelize.RichTextRun
+
+// RegisterRichTextFormatter registers a rich text formatter with a name, for
+// the `rich_text` key of YAML columns.
+func (e *ExcelDataExporter) RegisterRichTextFormatter(name string, f RichTextFormatter) *ExcelDataExporter {
+	e.richTextFormatters[name] = f
+	return e
+}
+
+// richTextRuns returns the rich text of a formatted column value, nil when
+// the column has no rich text formatter or it leaves the value plain.
+func (e *ExcelDataExporter) richTextRuns(col *ColumnConfig, val interface{}) []excelize.RichTextRun {
+	fn := col.RichTextFormatter
+	if fn == nil && col.RichText != "" {
+		fn = e.richTextFormatters[col.RichText]
+	}
+	if fn == nil {
+		return nil
+	}
+	runs := fn(val)
+	if len(runs) == 0 {
+		return nil
+	}
+	return runs
+}
+
+// setRichTextCell rewrites a cell holding a formatted column value as rich
+// text when the column's rich text formatter returns runs for it.
+func (e *ExcelDataExporter) setRichTextCell(f *excelize.File, sheet, cell string, col *ColumnConfig, val interface{}) error {
+	runs := e.richTextRuns(col, val)
+	if runs == nil {
+		return nil
+	}
+	if err := f.SetCellRichText(sheet, cell, runs); err != nil {
+		return fmt.Errorf("rich text at %s!%s: %w", sheet, cell, err)
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package simpleexcelv2
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+type taskRow struct {
+	Task   string
+	Status string
+}
+
+var overdueRuns RichTextFormatter = func(v interface{}) []excelize.RichTextRun {
+	s, _ := v.(string)
+	if !strings.HasPrefix(s, "OVERDUE: ") {
+		return nil
+	}
+	return []excelize.RichTextRun{
+		{Text: "OVERDUE: ", Font: &excelize.Font{Bold: true, Color: "FF0000"}},
+		{Text: strings.TrimPrefix(s, "OVERDUE: ")},
+	}
+}
+
+func newTaskExporter(t *testing.T) *ExcelDataExporter {
+	exporter, err := NewExcelDataExporterFromYamlConfig(`
+sheets:
+  - name: "Tasks"
+    sections:
+      - id: "tasks"
+        show_header: true
+        columns:
+          - field_name: "Task"
+          - field_name: "Status"
+            formatter: trim
+            rich_text: overdue
+`)
+	require.NoError(t, err)
+	exporter.RegisterRichTextFormatter("overdue", overdueRuns)
+	return exporter
+}
+
+func assertOverdueCells(t *testing.T, f *excelize.File) {
+	runs, err := f.GetCellRichText("Tasks", "B2")
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, "OVERDUE: ", runs[0].Text)
+	require.NotNil(t, runs[0].Font)
+	assert.True(t, runs[0].Font.Bold)
+	assert.Equal(t, "FF0000", runs[0].Font.Color)
+	assert.Equal(t, "3 days", runs[1].Text, "rich text sees the formatted value")
+
+	value, err := f.GetCellValue("Tasks", "B2")
+	require.NoError(t, err)
+	assert.Equal(t, "OVERDUE: 3 days", value)
+
+	runs, err = f.GetCellRichText("Tasks", "B3")
+	require.NoError(t, err)
+	assert.Empty(t, runs, "values without runs stay plain")
+	value, err = f.GetCellValue("Tasks", "B3")
+	require.NoError(t, err)
+	assert.Equal(t, "on track", value)
+}
+
+func TestRichText_Buffered(t *testing.T) {
+	exporter := newTaskExporter(t)
+	exporter.BindSectionData("tasks", []taskRow{{"Payroll", " OVERDUE: 3 days "}, {"Review", "on track"}})
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+	defer f.Close()
+
+	assertOverdueCells(t, f)
+}
+
+func TestRichText_Streamed(t *testing.T) {
+	exporter := newTaskExporter(t)
+
+	var buf bytes.Buffer
+	streamer, err := exporter.StartStream(&buf)
+	require.NoError(t, err)
+	require.NoError(t, streamer.Write("tasks", []taskRow{{"Payroll", "OVERDUE: 3 days"}, {"Review", "on track"}}))
+	require.NoError(t, streamer.Close())
+
+	// Streamed runs are inline strings, which GetCellRichText cannot read
+	// back; check the sheet XML instead
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	sheet, err := zr.Open("xl/worksheets/sheet1.xml")
+	require.NoError(t, err)
+	xml, err := io.ReadAll(sheet)
+	require.NoError(t, err)
+	assert.Contains(t, string(xml), `<c r="B2"`)
+	assert.Contains(t, string(xml), `<color rgb="FFFF0000"></color>`)
+	assert.Contains(t, string(xml), `<t xml:space="preserve">OVERDUE: </t>`)
+	assert.Contains(t, string(xml), `on track`)
+}
+
+func TestRichText_Programmatic(t *testing.T) {
+	exporter := NewExcelDataExporter()
+	exporter.AddSheet("Tasks").AddSection(&SectionConfig{
+		ID:         "tasks",
+		ShowHeader: true,
+		Data:       []taskRow{{"Payroll", "OVERDUE: 3 days"}, {"Review", "on track"}},
+		Columns: []ColumnConfig{
+			{FieldName: "Task"},
+			{FieldName: "Status", RichTextFormatter: overdueRuns},
+		},
+	})
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+	defer f.Close()
+	assertOverdueCells(t, f)
+}
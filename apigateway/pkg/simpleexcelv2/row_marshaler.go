@@ -0,0 +1,50 @@
+package simpleexcelv2
+
+import "reflect"
+
+// RowMarshaler is implemented by row types that return their cell values
+// themselves, bypassing the field lookup by reflection. ExcelCell is called
+// with the FieldName of every column; return "" for unknown fields. Fields of
+// RowMarshaler types are not checked for unknown_field warnings.
+type RowMarshaler interface {
+	ExcelCell(field string) any
+}
+
+var rowMarshalerType = reflect.TypeOf((*RowMarshaler)(nil)).Elem()
+
+// marshalerKind is how a row type implements RowMarshaler.
+type marshalerKind uint8
+
+const (
+	marshalerNone    marshalerKind = iota
+	marshalerValue                 // methods on the value
+	marshalerPointer               // methods on the pointer only
+)
+
+func marshalerKindOf(t reflect.Type) marshalerKind {
+	switch {
+	case t.Implements(rowMarshalerType):
+		return marshalerValue
+	case t.Kind() != reflect.Ptr && t.Kind() != reflect.Interface && reflect.PointerTo(t).Implements(rowMarshalerType):
+		return marshalerPointer
+	}
+	return marshalerNone
+}
+
+// asRowMarshaler returns item as a RowMarshaler, kind being how its type
+// implements it.
+func asRowMarshaler(item reflect.Value, kind marshalerKind) (RowMarshaler, bool) {
+	switch {
+	case kind == marshalerNone:
+	case item.Kind() == reflect.Ptr:
+		if !item.IsNil() {
+			return item.Interface().(RowMarshaler), true
+		}
+	case item.CanAddr():
+		// A pointer is boxed without copying the row
+		return item.Addr().Interface().(RowMarshaler), true
+	case kind == marshalerValue:
+		return item.Interface().(RowMarshaler), true
+	}
+	return nil, false
+}
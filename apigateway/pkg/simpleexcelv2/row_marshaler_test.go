@@ -0,0 +1,115 @@
+package simpleexcelv2
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type marshaledRow struct {
+	first, last string
+	salary      int
+}
+
+func (r *marshaledRow) ExcelCell(field string) any {
+	switch field {
+	case "Name":
+		return r.first + " " + r.last
+	case "Salary":
+		return r.salary
+	}
+	return ""
+}
+
+// valueRow implements RowMarshaler on the value.
+type valueRow struct{ salary int }
+
+func (r valueRow) ExcelCell(field string) any {
+	if field == "Salary" {
+		return r.salary * 2
+	}
+	return ""
+}
+
+func marshalerExporter(data interface{}) *ExcelDataExporter {
+	exporter := NewExcelDataExporter()
+	exporter.AddSheet("Staff").AddSection(&SectionConfig{
+		ID:         "staff",
+		ShowHeader: true,
+		Data:       data,
+		Columns:    []ColumnConfig{{FieldName: "Name", Header: "Name"}, {FieldName: "Salary", Header: "Salary"}},
+	})
+	return exporter
+}
+
+func TestRowMarshaler(t *testing.T) {
+	rows := []marshaledRow{{"Ann", "Lee", 5000}, {"Bob", "Kim", 4200}}
+
+	f, err := marshalerExporter(rows).BuildExcel()
+	require.NoError(t, err)
+	defer f.Close()
+	got, err := f.GetRows("Staff")
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"Name", "Salary"}, {"Ann Lee", "5000"}, {"Bob Kim", "4200"}}, got)
+}
+
+func TestRowMarshaler_PointersAndValues(t *testing.T) {
+	tests := []struct {
+		name string
+		data interface{}
+		want string
+	}{
+		{"pointers", []*marshaledRow{{"Ann", "Lee", 5000}, nil}, "Name,Salary\nAnn Lee,5000\n,\n"},
+		{"value receiver", []valueRow{{21}}, "Name,Salary\n,42\n"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			exporter := marshalerExporter(tc.data)
+			var buf bytes.Buffer
+			require.NoError(t, exporter.ToCSV(&buf))
+			assert.Equal(t, tc.want, strings.TrimSuffix(buf.String(), "\n"))
+			assert.Empty(t, exporter.Warnings(), "fields are not looked up on marshalers")
+		})
+	}
+}
+
+type benchMarshaledRow struct {
+	ID   int
+	Name string
+}
+
+func (r *benchMarshaledRow) ExcelCell(field string) any {
+	switch field {
+	case "ID":
+		return r.ID
+	case "Name":
+		return r.Name
+	}
+	return ""
+}
+
+func BenchmarkExtractValue(b *testing.B) {
+	type reflected struct {
+		ID   int
+		Name string
+	}
+	exporter := NewExcelDataExporter()
+	for _, bc := range []struct {
+		name string
+		data interface{}
+	}{
+		{"reflect", []reflected{{1, "Ann"}}},
+		{"marshaler", []benchMarshaledRow{{1, "Ann"}}},
+	} {
+		item := reflect.ValueOf(bc.data).Index(0)
+		b.Run(bc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_ = exporter.extractValue(item, "Name")
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package simpleexcelv2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrScratchQuota is returned when an export spools more bytes than the
+// quota set with SetScratch.
+var ErrScratchQuota = errors.New("export scratch quota exceeded")
+
+// SetScratch makes ToWriter and streams spool the workbook to a file in a
+// directory of their own, created under dir, before copying it to their
+// writer. The directory is removed when the export succeeds, fails, is
+// aborted or its context is cancelled. An export spooling more than quota
+// bytes fails with ErrScratchQuota, quota 0 does not limit it. Streamed
+// rows excelize holds back while a stream is open still go to
+// os.TempDir(), it offers no per-file directory; they are removed once the
+// stream is closed or aborted.
+func (e *ExcelDataExporter) SetScratch(dir string, quota int64) *ExcelDataExporter {
+	e.scratchDir = dir
+	e.scratchQuota = quota
+	return e
+}
+
+// scratchSpace is the spool file of one export and the directory holding it.
+type scratchSpace struct {
+	ctx   context.Context
+	dir   string
+	file  *os.File
+	quota int64
+	used  int64
+	// stop unregisters the removal on context cancellation
+	stop func() bool
+	once sync.Once
+}
+
+// openScratch creates the scratch directory of an export, nil when
+// SetScratch was not called.
+func (e *ExcelDataExporter) openScratch(ctx context.Context) (*scratchSpace, error) {
+	if e.scratchDir == "" {
+		return nil, nil
+	}
+	dir, err := os.MkdirTemp(e.scratchDir, "export-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export scratch directory: %w", err)
+	}
+	file, err := os.Create(filepath.Join(dir, "workbook.xlsx"))
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to create export scratch file: %w", err)
+	}
+	s := &scratchSpace{ctx: ctx, dir: dir, file: file, quota: e.scratchQuota}
+	s.stop = context.AfterFunc(ctx, func() { os.RemoveAll(dir) })
+	return s, nil
+}
+
+// Write spools p, failing once the context is done or the quota is reached.
+func (s *scratchSpace) Write(p []byte) (int, error) {
+	if err := s.ctx.Err(); err != nil {
+		return 0, err
+	}
+	if s.quota > 0 && s.used+int64(len(p)) > s.quota {
+		return 0, fmt.Errorf("%w: more than %d bytes", ErrScratchQuota, s.quota)
+	}
+	n, err := s.file.Write(p)
+	s.used += int64(n)
+	return n, err
+}
+
+// copyTo writes the spooled workbook to w.
+func (s *scratchSpace) copyTo(w io.Writer) error {
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := io.Copy(w, io.NewSectionReader(s.file, 0, s.used))
+	if err == nil {
+		err = s.ctx.Err()
+	}
+	return err
+}
+
+// remove deletes the spool file and its directory, it may be called more
+// than once.
+func (s *scratchSpace) remove() {
+	s.once.Do(func() {
+		s.stop()
+		s.file.Close()
+		os.RemoveAll(s.dir)
+	})
+}
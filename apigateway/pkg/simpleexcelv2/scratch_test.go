@@ -0,0 +1,102 @@
+package simpleexcelv2
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func scratchExporter(dir string, quota int64) *ExcelDataExporter {
+	exporter := NewExcelDataExporter().SetScratch(dir, quota)
+	exporter.AddSheet("Staff").AddSection(&SectionConfig{
+		ID: "staff", ShowHeader: true,
+		Columns: []ColumnConfig{{FieldName: "Name", Header: "Name"}, {FieldName: "Salary", Header: "Salary"}},
+	})
+	return exporter
+}
+
+func assertScratchEmpty(t *testing.T, dir string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "the export directory is removed")
+}
+
+func TestScratch_ToWriter(t *testing.T) {
+	dir := t.TempDir()
+	exporter := scratchExporter(dir, 0)
+	exporter.BindSectionData("staff", []repeatRow{{"Ann", 100}})
+
+	var out bytes.Buffer
+	require.NoError(t, exporter.ToWriter(&out))
+	assertScratchEmpty(t, dir)
+
+	f, err := excelize.OpenReader(&out)
+	require.NoError(t, err)
+	v, err := f.GetCellValue("Staff", "A2")
+	require.NoError(t, err)
+	assert.Equal(t, "Ann", v)
+	assert.Equal(t, exporter.Result().Bytes, exporter.Result().ScratchBytes)
+}
+
+func TestScratch_Quota(t *testing.T) {
+	dir := t.TempDir()
+	exporter := scratchExporter(dir, 1024)
+	exporter.BindSectionData("staff", []repeatRow{{"Ann", 100}})
+
+	var out bytes.Buffer
+	err := exporter.ToWriter(&out)
+	assert.ErrorIs(t, err, ErrScratchQuota)
+	assert.Zero(t, out.Len(), "nothing is written past the quota")
+	assertScratchEmpty(t, dir)
+}
+
+func TestScratch_Stream(t *testing.T) {
+	dir := t.TempDir()
+	exporter := scratchExporter(dir, 0)
+
+	var out bytes.Buffer
+	streamer, err := exporter.StartStream(&out)
+	require.NoError(t, err)
+	require.NoError(t, streamer.Write("staff", []repeatRow{{"Ann", 100}, {"Bob", 200}}))
+	require.NoError(t, streamer.Close())
+	assertScratchEmpty(t, dir)
+
+	assert.Positive(t, exporter.Result().ScratchBytes)
+	assert.Equal(t, int64(out.Len()), exporter.Result().Bytes)
+	assert.EqualError(t, streamer.Close(), "stream is closed or not initialized")
+}
+
+func TestScratch_Cancelled(t *testing.T) {
+	dir := t.TempDir()
+	exporter := scratchExporter(dir, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	streamer, err := exporter.StartStreamContext(ctx, &bytes.Buffer{})
+	require.NoError(t, err)
+	require.NoError(t, streamer.Write("staff", []repeatRow{{"Ann", 100}}))
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "the export has a directory of its own")
+
+	cancel()
+	assert.Eventually(t, func() bool {
+		entries, err := os.ReadDir(dir)
+		return err == nil && len(entries) == 0
+	}, time.Second, 10*time.Millisecond, "cancellation removes the directory before the stream is aborted")
+	assert.ErrorIs(t, streamer.Write("staff", []repeatRow{{"Bob", 200}}), context.Canceled)
+	streamer.Abort(context.Canceled)
+}
+
+func TestScratch_MissingDir(t *testing.T) {
+	exporter := scratchExporter(t.TempDir()+"/missing", 0)
+
+	_, err := exporter.StartStream(&bytes.Buffer{})
+	assert.ErrorContains(t, err, "failed to create export scratch directory")
+}
@@ -0,0 +1,119 @@
+package simpleexcelv2
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// SectionRenderer draws a custom section type, e.g. an org chart, in place of
+// the built-in title/header/data layout. Register it with
+// RegisterSectionRenderer and set the section's type to the registered name.
+type SectionRenderer interface {
+	// Size returns the number of columns and rows the section occupies with
+	// its bound data. The sections after it are placed accordingly.
+	Size(rc *RenderContext) (cols, rows int)
+	// Render draws the section with its top-left cell at rc.Col, rc.Row.
+	Render(rc *RenderContext) error
+}
+
+// RenderContext gives a SectionRenderer access to the workbook being built.
+type RenderContext struct {
+	File    *excelize.File
+	Sheet   string
+	Section *SectionConfig
+	// Col and Row are the 1-based top-left cell of the section
+	Col, Row int
+
+	exporter *ExcelDataExporter
+}
+
+// Style returns the style ID for tmpl, created once per workbook and shared
+// with the built-in sections.
+func (rc *RenderContext) Style(tmpl *StyleTemplate) (int, error) {
+	return rc.exporter.createStyle(rc.File, tmpl)
+}
+
+// Placement returns where a section rendered earlier placed its data.
+func (rc *RenderContext) Placement(sectionID string) (SectionPlacement, bool) {
+	p, ok := rc.exporter.sectionMetadata[sectionID]
+	return p, ok
+}
+
+// Place records where this section's data starts, for formulas and
+// compare_with columns of later sections. By default the data is assumed to
+// fill the section from its top-left cell.
+func (rc *RenderContext) Place(p SectionPlacement) {
+	if rc.Section.ID == "" {
+		return
+	}
+	p.SectionID = rc.Section.ID
+	p.Sheet = rc.Sheet
+	rc.exporter.sectionMetadata[rc.Section.ID] = p
+}
+
+// Value returns a field of a bound data item (struct or map), as the
+// built-in sections read it. Missing fields return "".
+func (rc *RenderContext) Value(item reflect.Value, field string) interface{} {
+	for item.Kind() == reflect.Ptr || item.Kind() == reflect.Interface {
+		item = item.Elem()
+	}
+	return rc.exporter.extractValue(item, field)
+}
+
+// columnValue returns the value of a column for a bound data item, written
+// to cell, with the column's formatter applied.
+func (rc *RenderContext) columnValue(item reflect.Value, col *ColumnConfig, cell string) interface{} {
+	val := rc.exporter.formatCell(rc.Sheet, cell, rc.Section, col, rc.Value(item, col.FieldName))
+	return rc.exporter.limitCell(rc.Sheet, rc.Section, col, val)
+}
+
+// setColumnRichText rewrites the cell of a column value returned by
+// columnValue as rich text, when the column has a rich text formatter.
+func (rc *RenderContext) setColumnRichText(col *ColumnConfig, cell string, val interface{}) error {
+	return rc.exporter.setRichTextCell(rc.File, rc.Sheet, cell, col, val)
+}
+
+// Cell returns the name of the cell at the given offsets from the top-left
+// cell, e.g. Cell(0, 0) is the first cell of the section.
+func (rc *RenderContext) Cell(colOffset, rowOffset int) string {
+	return rc.exporter.getCellAddress(rc.Col+colOffset, rc.Row+rowOffset)
+}
+
+var (
+	sectionRenderersMu sync.RWMutex
+	sectionRenderers   = make(map[string]SectionRenderer)
+)
+
+// RegisterSectionRenderer makes a custom section type available to every
+// exporter. Like database/sql.Register it is meant to be called from init
+// and panics if typ is empty, a built-in type or already registered.
+func RegisterSectionRenderer(typ string, r SectionRenderer) {
+	sectionRenderersMu.Lock()
+	defer sectionRenderersMu.Unlock()
+	switch typ {
+	case "", SectionTypeFull, SectionTypeTitleOnly, SectionTypeHidden, SectionTypeRepeat:
+		panic(fmt.Sprintf("simpleexcelv2: cannot register section renderer for type %q", typ))
+	}
+	if r == nil {
+		panic("simpleexcelv2: section renderer is nil")
+	}
+	if _, dup := sectionRenderers[typ]; dup {
+		panic(fmt.Sprintf("simpleexcelv2: section renderer for type %q registered twice", typ))
+	}
+	sectionRenderers[typ] = r
+}
+
+// sectionRenderer returns the custom renderer for the section's type, if any.
+func sectionRenderer(sec *SectionConfig) (SectionRenderer, bool) {
+	switch sec.Type {
+	case "", SectionTypeFull, SectionTypeTitleOnly, SectionTypeHidden, SectionTypeRepeat:
+		return nil, false
+	}
+	sectionRenderersMu.RLock()
+	defer sectionRenderersMu.RUnlock()
+	r, ok := sectionRenderers[sec.Type]
+	return r, ok
+}
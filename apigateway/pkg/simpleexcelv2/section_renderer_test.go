@@ -0,0 +1,90 @@
+package simpleexcelv2
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// badgeRenderer draws one bold cell per bound item, in a single row.
+type badgeRenderer struct{}
+
+func (badgeRenderer) Size(rc *RenderContext) (int, int) {
+	return reflect.ValueOf(rc.Section.Data).Len(), 1
+}
+
+func (badgeRenderer) Render(rc *RenderContext) error {
+	styleID, err := rc.Style(&StyleTemplate{Font: &FontTemplate{Bold: true}})
+	if err != nil {
+		return err
+	}
+	items := reflect.ValueOf(rc.Section.Data)
+	for i := 0; i < items.Len(); i++ {
+		cell := rc.Cell(i, 0)
+		rc.File.SetCellValue(rc.Sheet, cell, fmt.Sprintf("[%v]", items.Index(i).Interface()))
+		rc.File.SetCellStyle(rc.Sheet, cell, cell, styleID)
+	}
+	rc.Place(SectionPlacement{StartRow: rc.Row, StartCol: rc.Col, DataLen: 1})
+	return nil
+}
+
+func init() {
+	RegisterSectionRenderer("test_badges", badgeRenderer{})
+}
+
+const badgeYAML = `
+sheets:
+  - name: "Board"
+    sections:
+      - id: "badges"
+        type: "test_badges"
+      - id: "people"
+        show_header: true
+        columns:
+          - field_name: "Name"
+            header: "Name"
+`
+
+func TestCustomSectionRenderer(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(badgeYAML)
+	require.NoError(t, err)
+	exporter.BindSectionData("badges", []string{"gold", "silver"}).
+		BindSectionData("people", []struct{ Name string }{{"Ann"}})
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+	defer f.Close()
+
+	for cell, want := range map[string]string{"A1": "[gold]", "B1": "[silver]", "A2": "Name", "A3": "Ann"} {
+		got, err := f.GetCellValue("Board", cell)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, cell)
+	}
+	styleID, err := f.GetCellStyle("Board", "B1")
+	require.NoError(t, err)
+	style, err := f.GetStyle(styleID)
+	require.NoError(t, err)
+	assert.True(t, style.Font.Bold)
+
+	assert.Equal(t, SectionPlacement{SectionID: "badges", Sheet: "Board", StartRow: 1, StartCol: 1, DataLen: 1}, exporter.sectionMetadata["badges"])
+	assert.Equal(t, 3, exporter.sectionMetadata["people"].StartRow, "the next section starts below the custom one")
+}
+
+func TestCustomSectionRenderer_NotStreamable(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(badgeYAML)
+	require.NoError(t, err)
+	exporter.BindSectionData("badges", []string{"gold"})
+
+	_, err = exporter.StartStream(&bytes.Buffer{})
+	assert.ErrorContains(t, err, "cannot be streamed")
+}
+
+func TestRegisterSectionRenderer_Rejects(t *testing.T) {
+	for _, typ := range []string{"", SectionTypeFull, SectionTypeHidden, "test_badges"} {
+		assert.Panics(t, func() { RegisterSectionRenderer(typ, badgeRenderer{}) }, typ)
+	}
+}
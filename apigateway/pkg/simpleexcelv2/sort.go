@@ -0,0 +1,205 @@
+package simpleexcelv2
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// sortKey is a parsed sort_by entry, "-Field" sorts descending.
+type sortKey struct {
+	field string
+	desc  bool
+}
+
+func parseSortKeys(sortBy []string) []sortKey {
+	keys := make([]sortKey, 0, len(sortBy))
+	for _, s := range sortBy {
+		s = strings.TrimSpace(s)
+		key := sortKey{field: strings.TrimPrefix(s, "-")}
+		key.desc = key.field != s
+		if key.field != "" {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// arrangeSections drops the duplicate rows of the sections of sheets
+// declaring distinct_by, then sorts those declaring sort_by. Both work on
+// copies, the caller's data is left as is.
+func (e *ExcelDataExporter) arrangeSections(sheets []*SheetBuilder) {
+	for _, sb := range sheets {
+		for _, sec := range sb.sections {
+			if len(sec.DistinctBy) == 0 && len(sec.SortBy) == 0 {
+				continue
+			}
+			if data, ok := e.data[sec.ID]; ok && sec.ID != "" {
+				e.data[sec.ID] = e.arrangeData(sec, data)
+			}
+			if sec.Data != nil {
+				sec.Data = e.arrangeData(sec, sec.Data)
+			}
+		}
+	}
+}
+
+func (e *ExcelDataExporter) arrangeData(sec *SectionConfig, data interface{}) interface{} {
+	if len(sec.DistinctBy) > 0 {
+		data = e.distinctData(data, sec.DistinctBy, sec.DistinctKeep == DistinctKeepLast)
+	}
+	if len(sec.SortBy) > 0 {
+		data = e.sortData(data, parseSortKeys(sec.SortBy))
+	}
+	return data
+}
+
+// sortData returns a sorted copy of a slice by keys, stable so rows with
+// equal keys keep their bound order. Anything but a slice is returned as is.
+func (e *ExcelDataExporter) sortData(data interface{}, keys []sortKey) interface{} {
+	v := reflect.ValueOf(data)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice || v.Len() < 2 || len(keys) == 0 {
+		return data
+	}
+
+	// Extract the keys once, comparisons then only look them up
+	n := v.Len()
+	fields := make([]string, len(keys))
+	for k, key := range keys {
+		fields[k] = key.field
+	}
+	values := make([][]interface{}, n)
+	for i := 0; i < n; i++ {
+		values[i] = e.rowValues(v.Index(i), fields)
+	}
+
+	perm := make([]int, n)
+	for i := range perm {
+		perm[i] = i
+	}
+	sort.SliceStable(perm, func(a, b int) bool {
+		ra, rb := values[perm[a]], values[perm[b]]
+		for k, key := range keys {
+			c := compareValues(ra[k], rb[k])
+			if c == 0 {
+				continue
+			}
+			if key.desc {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+
+	sorted := reflect.MakeSlice(v.Type(), n, n)
+	for i, j := range perm {
+		sorted.Index(i).Set(v.Index(j))
+	}
+	return sorted.Interface()
+}
+
+// compareValues orders two cell values: numbers numerically, times
+// chronologically, bools false first and strings lexically. Missing values
+// sort before everything else, values of different kinds by their text.
+func compareValues(a, b interface{}) int {
+	a, b = sortValue(a), sortValue(b)
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		}
+		return 1
+	}
+	switch x := a.(type) {
+	case float64:
+		if y, ok := b.(float64); ok {
+			return compareOrdered(x, y)
+		}
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y)
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			switch {
+			case x == y:
+				return 0
+			case !x:
+				return -1
+			}
+			return 1
+		}
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			return x.Compare(y)
+		}
+	}
+	return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+}
+
+// rowValues returns the values of fields of a row, nil for nil rows.
+func (e *ExcelDataExporter) rowValues(item reflect.Value, fields []string) []interface{} {
+	for item.Kind() == reflect.Ptr || item.Kind() == reflect.Interface {
+		if item.IsNil() {
+			return make([]interface{}, len(fields))
+		}
+		item = item.Elem()
+	}
+	values := make([]interface{}, len(fields))
+	for k, field := range fields {
+		values[k] = e.extractValue(item, field)
+	}
+	return values
+}
+
+func compareOrdered(x, y float64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+// sortValue normalizes v for compareValues: pointers are followed, numbers
+// become float64 and "" or nil become nil.
+func sortValue(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil
+	}
+	if t, ok := rv.Interface().(time.Time); ok {
+		return t
+	}
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.String:
+		if rv.Len() == 0 {
+			return nil
+		}
+		return rv.String()
+	}
+	return rv.Interface()
+}
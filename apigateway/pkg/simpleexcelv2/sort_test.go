@@ -0,0 +1,141 @@
+package simpleexcelv2
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sortRow struct {
+	Name  string
+	Dept  string
+	Hired time.Time
+	Age   *int
+}
+
+const sortYAML = `
+sheets:
+  - name: "Staff"
+    sections:
+      - id: "staff"
+        show_header: true
+        sort_by: [Dept, -Hired]
+        columns:
+          - field_name: "Name"
+            header: "Name"
+`
+
+func sortRows() []sortRow {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	return []sortRow{
+		{Name: "Ann", Dept: "d002", Hired: day(1)},
+		{Name: "Bob", Dept: "d001", Hired: day(2)},
+		{Name: "Cid", Dept: "d002", Hired: day(3)},
+		{Name: "Dee", Dept: "d001", Hired: day(2)},
+		{Name: "Eve", Dept: "d001", Hired: day(9)},
+	}
+}
+
+func sortedNames(t *testing.T, exporter *ExcelDataExporter) []string {
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+	var names []string
+	for row := 2; row <= 6; row++ {
+		v, err := f.GetCellValue("Staff", "A"+string(rune('0'+row)))
+		require.NoError(t, err)
+		names = append(names, v)
+	}
+	return names
+}
+
+// csvFirstColumn returns the first cell of the non-empty CSV lines.
+func csvFirstColumn(t *testing.T, exporter *ExcelDataExporter) []string {
+	var buf bytes.Buffer
+	require.NoError(t, exporter.ToCSV(&buf))
+	var cells []string
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if line != "" {
+			cells = append(cells, strings.Split(line, ",")[0])
+		}
+	}
+	return cells
+}
+
+func TestSortBy_MultiKeyStable(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(sortYAML)
+	require.NoError(t, err)
+	rows := sortRows()
+	exporter.BindSectionData("staff", rows)
+
+	// Bob and Dee tie on both keys and keep their bound order
+	assert.Equal(t, []string{"Eve", "Bob", "Dee", "Cid", "Ann"}, sortedNames(t, exporter))
+	assert.Equal(t, "Ann", rows[0].Name, "the bound slice is not reordered")
+	assert.Empty(t, exporter.Warnings())
+}
+
+func TestSortBy_CSVAndHTML(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(sortYAML)
+	require.NoError(t, err)
+	exporter.BindSectionData("staff", sortRows())
+
+	assert.Equal(t, []string{"Name", "Eve", "Bob", "Dee", "Cid", "Ann"}, csvFirstColumn(t, exporter))
+
+	var html bytes.Buffer
+	require.NoError(t, exporter.RenderHTML(context.Background(), &html))
+	assert.Less(t, strings.Index(html.String(), "Eve"), strings.Index(html.String(), "Ann"))
+}
+
+func TestSortBy_Values(t *testing.T) {
+	one, ten := 1, 10
+	exporter := NewExcelDataExporter()
+	exporter.AddSheet("Staff").AddSection(&SectionConfig{
+		ID: "staff", SortBy: []string{"Age"},
+		Columns: []ColumnConfig{{FieldName: "Name"}},
+		Data: []sortRow{
+			{Name: "Ten", Age: &ten},
+			{Name: "None"},
+			{Name: "One", Age: &one},
+		},
+	})
+
+	assert.Equal(t, []string{"None", "One", "Ten"}, csvFirstColumn(t, exporter), "pointers are followed, numbers compare numerically and nil comes first")
+}
+
+func TestSortBy_Maps(t *testing.T) {
+	exporter := NewExcelDataExporter()
+	exporter.AddSheet("Staff").AddSection(&SectionConfig{
+		ID: "staff", SortBy: []string{"-score"},
+		Columns: []ColumnConfig{{FieldName: "name"}},
+	})
+	exporter.BindSectionData("staff", []map[string]interface{}{
+		{"name": "low", "score": 2},
+		{"name": "high", "score": 10},
+		{"name": "mid", "score": 7.5},
+	})
+
+	assert.Equal(t, []string{"high", "mid", "low"}, csvFirstColumn(t, exporter))
+}
+
+func TestSortBy_Warnings(t *testing.T) {
+	exporter := NewExcelDataExporter()
+	exporter.AddSheet("Staff").
+		AddSection(&SectionConfig{ID: "staff", SortBy: []string{"-Salary"}, Columns: []ColumnConfig{{FieldName: "Name"}}}).
+		AddSection(&SectionConfig{ID: "leavers", SortBy: []string{"Name"}, Columns: []ColumnConfig{{FieldName: "Name"}}})
+	exporter.BindSectionData("staff", sortRows())
+
+	streamer, err := exporter.StartStream(&bytes.Buffer{})
+	require.NoError(t, err)
+	require.NoError(t, streamer.Write("leavers", sortRows()))
+	require.NoError(t, streamer.Close())
+
+	assert.Equal(t, []Warning{
+		{Kind: WarningUnknownField, Sheet: "Staff", Section: "staff", Field: "Salary", Message: "sortRow has no field Salary to sort by"},
+		{Kind: WarningUnsortedStream, Sheet: "Staff", Section: "leavers", Message: "streamed rows are written in the order they are sent, sort_by is not applied"},
+	}, exporter.Warnings())
+	assert.True(t, WarningUnsortedStream.Adjusted())
+}
@@ -1,18 +1,24 @@
 package simpleexcelv2
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"reflect"
 
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/faultinject"
 	"github.com/xuri/excelize/v2"
 )
 
 // Streamer manages a streaming export session.
 type Streamer struct {
 	exporter *ExcelDataExporter
-	file     *excelize.File
-	writer   io.Writer
+	// ctx fails writes once done, see StartStreamContext
+	ctx    context.Context
+	file   *excelize.File
+	writer io.Writer
+	// scratch spools the file before it is written, nil without SetScratch
+	scratch *scratchSpace
 	// streamWriters holds active stream writers for each sheet
 	streamWriters map[string]*excelize.StreamWriter
 	// currentSheetIndex tracks which sheet we are currently processing
@@ -23,6 +29,12 @@ type Streamer struct {
 	currentRow int
 	// sectionStarted indicates whether the current section's title/header has been written
 	sectionStarted bool
+	// closer is closed after the file is written (e.g. an object storage upload)
+	closer io.WriteCloser
+	// samplers hold back the first rows of auto-fit sheets, by sheet name
+	samplers map[string]*streamSampler
+	// streamed holds the IDs of the sections written to
+	streamed map[string]bool
 }
 
 // Write appends a batch of data to the specified section.
@@ -33,6 +45,9 @@ func (s *Streamer) Write(sectionID string, data interface{}) error {
 	if s.file == nil {
 		return fmt.Errorf("stream is closed or not initialized")
 	}
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
 
 	sheet := s.getCurrentSheet()
 	if sheet == nil {
@@ -78,12 +93,13 @@ func (s *Streamer) Write(sectionID string, data interface{}) error {
 
 	// 3. Current Section
 	sec := sheet.sections[s.currentSectionIndex]
+	s.streamed[sectionID] = true
 
 	// 4. Resolve Columns (once if not done)
 	initialWrite := false
-	if len(sec.Columns) == 0 || (len(sec.Columns) > 0 && len(sec.Columns[0].FieldName) == 0) {
+	if len(sec.Columns) == 0 || (len(sec.Columns[0].FieldName) == 0 && !typedColumns(sec.Columns)) {
 		// Dynamic discovery needed
-		sec.Columns = mergeColumns(data, sec.Columns)
+		sec.Columns = mergeColumns(data, sec.Columns, sec.Order)
 		initialWrite = true
 	} else if !s.sectionStarted {
 		// Columns exist but we haven't started this section (haven't written title/header)
@@ -93,6 +109,10 @@ func (s *Streamer) Write(sectionID string, data interface{}) error {
 	// 5. Render Title & Header (Lazy)
 	if initialWrite {
 		s.sectionStarted = true
+		s.exporter.checkFields(sheet.name, sec, data)
+		if err := s.exporter.strictError(); err != nil {
+			return err
+		}
 
 		// Render Title
 		if sec.Title != nil {
@@ -115,9 +135,9 @@ func (s *Streamer) Write(sectionID string, data interface{}) error {
 				colSpan = 1
 			}
 
-			if err := sw.SetRow(cell, []interface{}{
+			if err := s.setRow(sw, sec, cell, []interface{}{
 				excelize.Cell{Value: sec.Title, StyleID: sid},
-			}); err != nil {
+			}, streamTitleRow); err != nil {
 				return err
 			}
 			if colSpan > 1 {
@@ -142,11 +162,8 @@ func (s *Streamer) Write(sectionID string, data interface{}) error {
 					return err
 				}
 				headers[i] = excelize.Cell{Value: col.Header, StyleID: sid}
-				if col.Width > 0 {
-					sw.SetColWidth(i+1, i+1, col.Width)
-				}
 			}
-			if err := sw.SetRow(cell, headers); err != nil {
+			if err := s.setRow(sw, sec, cell, headers, streamHeaderRow); err != nil {
 				return err
 			}
 			s.currentRow++
@@ -161,10 +178,11 @@ func (s *Streamer) Write(sectionID string, data interface{}) error {
 		// Storing SectionPlacement for formula resolution
 		s.exporter.sectionMetadata[sec.ID] = SectionPlacement{
 			SectionID:    sec.ID,
+			Sheet:        s.getCurrentSheet().name,
 			StartRow:     s.currentRow, // Current stream row is the data start row
 			StartCol:     1,            // Streamer always starts at col 1 for now
 			FieldOffsets: fieldOffsets,
-			DataLen:      0, // Unknown/Irrelevant for streaming lookup
+			DataLen:      0, // Incremented as batches are written
 		}
 	}
 
@@ -177,10 +195,74 @@ func (s *Streamer) Write(sectionID string, data interface{}) error {
 
 // Close finishes the stream and writes the file to the output.
 func (s *Streamer) Close() error {
+	if s.file == nil {
+		return fmt.Errorf("stream is closed or not initialized")
+	}
+	err := s.flush()
+	s.release()
+	if err != nil {
+		if s.closer != nil {
+			abortObjectWriter(s.closer, err)
+		}
+		return err
+	}
+	if s.closer != nil {
+		return s.closer.Close()
+	}
+	return nil
+}
+
+// Abort discards the stream without writing the file. For object storage
+// streams the upload is cancelled with cause.
+func (s *Streamer) Abort(cause error) {
+	if s.closer != nil {
+		abortObjectWriter(s.closer, cause)
+	}
+	s.release()
+}
+
+// release removes the temp files of the stream: the rows excelize spilled
+// to disk and the scratch directory.
+func (s *Streamer) release() {
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+	if s.scratch != nil {
+		s.scratch.remove()
+	}
+}
+
+func (s *Streamer) flush() error {
 	// Finish current sheet
 	if err := s.finishCurrentSheet(); err != nil {
 		return err
 	}
+	for _, sheet := range s.exporter.sheets {
+		for _, sec := range sheet.sections {
+			if needsData(sec) && sec.Data == nil && !s.streamed[sec.ID] {
+				s.exporter.warnUnbound(sheet.name, sec)
+			}
+		}
+	}
+
+	// Record where each section's rows ended up and add column validation,
+	// which the stream writers include when they flush
+	for _, sheet := range s.exporter.sheets {
+		for _, sec := range sheet.sections {
+			if p, ok := s.exporter.sectionMetadata[sec.ID]; ok {
+				recordSectionRange(s.file, sheet.name, sec.ID, p.StartCol, p.StartRow, len(sec.Columns), p.DataLen)
+				s.exporter.addDataValidations(s.file, sheet.name, sec, p.StartCol, p.StartRow, p.DataLen)
+			}
+		}
+	}
+
+	// Write the rows of sheets with fewer rows than their auto-fit sample
+	for name, sp := range s.samplers {
+		if err := sp.flush(s.streamWriters[name]); err != nil {
+			return err
+		}
+	}
 
 	// Flush all stream writers
 	for _, sw := range s.streamWriters {
@@ -188,11 +270,26 @@ func (s *Streamer) Close() error {
 			return err
 		}
 	}
+	if err := s.exporter.addFormatErrorComments(s.file); err != nil {
+		return err
+	}
 
-	// Write entire file to output
-	if _, err := s.file.WriteTo(s.writer); err != nil {
+	// Write entire file to output, through the scratch file if any
+	cw := &countingWriter{w: s.writer}
+	if s.scratch == nil {
+		if err := s.file.Write(cw); err != nil {
+			return err
+		}
+		s.exporter.finishExport(cw.n)
+		return nil
+	}
+	if err := s.file.Write(s.scratch); err != nil {
+		return err
+	}
+	if err := s.scratch.copyTo(cw); err != nil {
 		return err
 	}
+	s.exporter.finishExport(cw.n).ScratchBytes = s.scratch.used
 
 	return nil
 }
@@ -217,6 +314,29 @@ func (s *Streamer) finishCurrentSheet() error {
 	return nil
 }
 
+// setRow writes a row of sec to the current sheet, or holds it back while the
+// sheet's auto-fit widths are sampled.
+func (s *Streamer) setRow(sw *excelize.StreamWriter, sec *SectionConfig, cell string, values []interface{}, kind int) error {
+	cells := 0
+	for _, v := range values {
+		if v != nil {
+			cells++
+		}
+	}
+	s.exporter.countRows(1, cells)
+
+	name := s.getCurrentSheet().name
+	sp, ok := s.samplers[name]
+	if !ok {
+		return sw.SetRow(cell, values)
+	}
+	if !sp.add(sec, cell, values, kind) {
+		return nil
+	}
+	delete(s.samplers, name)
+	return sp.flush(sw)
+}
+
 func (s *Streamer) getCurrentSheet() *SheetBuilder {
 	if s.currentSheetIndex >= len(s.exporter.sheets) {
 		return nil
@@ -236,6 +356,12 @@ func (s *Streamer) advanceToNextStreamingSection() error {
 
 	for s.currentSectionIndex < len(sheet.sections) {
 		sec := sheet.sections[s.currentSectionIndex]
+		if sec.Type == SectionTypeRepeat {
+			return fmt.Errorf("section %s: repeat sections cannot be streamed", sec.ID)
+		}
+		if _, ok := sectionRenderer(sec); ok {
+			return fmt.Errorf("section %s: custom section type %q cannot be streamed", sec.ID, sec.Type)
+		}
 
 		isStatic := false
 		if sec.Data != nil {
@@ -295,9 +421,9 @@ func (s *Streamer) renderStaticSection(sw *excelize.StreamWriter, sec *SectionCo
 			colSpan = 1
 		}
 
-		if err := sw.SetRow(cell, []interface{}{
+		if err := s.setRow(sw, sec, cell, []interface{}{
 			excelize.Cell{Value: sec.Title, StyleID: sid},
-		}); err != nil {
+		}, streamTitleRow); err != nil {
 			return err
 		}
 
@@ -324,12 +450,9 @@ func (s *Streamer) renderStaticSection(sw *excelize.StreamWriter, sec *SectionCo
 				return err
 			}
 			headers[i] = excelize.Cell{Value: col.Header, StyleID: sid}
-			if col.Width > 0 {
-				sw.SetColWidth(i+1, i+1, col.Width)
-			}
 		}
 
-		if err := sw.SetRow(cell, headers); err != nil {
+		if err := s.setRow(sw, sec, cell, headers, streamHeaderRow); err != nil {
 			return err
 		}
 		s.currentRow++
@@ -342,6 +465,7 @@ func (s *Streamer) renderStaticSection(sw *excelize.StreamWriter, sec *SectionCo
 	}
 	s.exporter.sectionMetadata[sec.ID] = SectionPlacement{
 		SectionID:    sec.ID,
+		Sheet:        s.getCurrentSheet().name,
 		StartRow:     s.currentRow,
 		StartCol:     1,
 		FieldOffsets: fieldOffsets,
@@ -359,7 +483,7 @@ func (s *Streamer) renderStaticSection(sw *excelize.StreamWriter, sec *SectionCo
 func (s *Streamer) writeBatch(sw *excelize.StreamWriter, sec *SectionConfig, data interface{}) error {
 	// Resolve Columns
 	if len(sec.Columns) == 0 {
-		sec.Columns = mergeColumns(data, sec.Columns)
+		sec.Columns = mergeColumns(data, sec.Columns, sec.Order)
 	}
 
 	dataVal := reflect.ValueOf(data)
@@ -379,6 +503,11 @@ func (s *Streamer) writeBatch(sw *excelize.StreamWriter, sec *SectionConfig, dat
 			defaultDataStyle = &StyleTemplate{Fill: &FillTemplate{Color: "FFFF00"}}
 		}
 		styleTmpl := resolveStyle(sec.DataStyle, defaultDataStyle, locked)
+		if col.CompareWith != nil {
+			styleTmpl = compareColumnStyle(styleTmpl, col)
+		} else {
+			styleTmpl = s.exporter.columnDataStyle(styleTmpl, col, dataVal.Type().Elem())
+		}
 		sid, err := s.exporter.createStyle(s.file, styleTmpl)
 		if err != nil {
 			return err
@@ -388,9 +517,11 @@ func (s *Streamer) writeBatch(sw *excelize.StreamWriter, sec *SectionConfig, dat
 
 	// Get metadata for formula resolution
 	placement, hasMetadata := s.exporter.sectionMetadata[sec.ID]
+	sheetName := s.getCurrentSheet().name
 
-	// Write rows
-	for i := 0; i < dataVal.Len(); i++ {
+	// Write rows, a fault at FaultStreamBatch writes part of them
+	rows, faultErr := faultinject.Batch(context.Background(), FaultStreamBatch, dataVal.Len())
+	for i := 0; i < rows; i++ {
 		item := dataVal.Index(i)
 		cell, _ := excelize.CoordinatesToCellName(1, s.currentRow)
 		rowVals := make([]interface{}, len(sec.Columns))
@@ -405,7 +536,7 @@ func (s *Streamer) writeBatch(sw *excelize.StreamWriter, sec *SectionConfig, dat
 		for j, col := range sec.Columns {
 			if col.CompareWith != nil {
 				// Generate Formula
-				formula, err := s.exporter.generateDiffFormula(col, rowOffset)
+				formula, err := s.exporter.generateDiffFormula(s.getCurrentSheet().name, col, rowOffset)
 				if err == nil {
 					rowVals[j] = excelize.Cell{
 						Formula: formula,
@@ -419,13 +550,11 @@ func (s *Streamer) writeBatch(sw *excelize.StreamWriter, sec *SectionConfig, dat
 				}
 			} else {
 				// Value Extraction
-				val := s.exporter.extractValue(item, col.FieldName)
-				if col.Formatter != nil {
-					val = col.Formatter(val)
-				} else if col.FormatterName != "" {
-					if fmtFunc, ok := s.exporter.formatters[col.FormatterName]; ok {
-						val = fmtFunc(val)
-					}
+				valCell := s.exporter.getCellAddress(1+j, s.currentRow)
+				val := s.exporter.limitCell(sheetName, sec, &col, s.exporter.formatCell(sheetName, valCell, sec, &col, s.exporter.columnValue(item, &col)))
+				if runs := s.exporter.richTextRuns(&col, val); runs != nil {
+					// The stream writer writes runs as an inline rich string
+					val = runs
 				}
 				rowVals[j] = excelize.Cell{
 					Value:   val,
@@ -433,10 +562,15 @@ func (s *Streamer) writeBatch(sw *excelize.StreamWriter, sec *SectionConfig, dat
 				}
 			}
 		}
-		if err := sw.SetRow(cell, rowVals); err != nil {
+		if err := s.setRow(sw, sec, cell, rowVals, streamDataRow); err != nil {
 			return err
 		}
 		s.currentRow++
 	}
-	return nil
+
+	if hasMetadata {
+		placement.DataLen += rows
+		s.exporter.sectionMetadata[sec.ID] = placement
+	}
+	return faultErr
 }
@@ -0,0 +1,257 @@
+package simpleexcelv2
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// TemplateCache keeps parsed and validated YAML templates so requests don't
+// re-read and re-parse them. Entries are keyed by the SHA-256 of the content,
+// so identical templates share one entry and an edited file gets a new one.
+// Files are re-read only when their size or modification time changes.
+//
+// Exporters handed out by the cache work on their own copy of the template,
+// since rendering binds data into the section configs.
+type TemplateCache struct {
+	mu        sync.RWMutex
+	templates map[string]*ReportTemplate
+	files     map[string]cachedFile
+	// fallback serves templates missing on disk, by file name
+	fallback fs.FS
+	hits     int64
+	misses   int64
+}
+
+type cachedFile struct {
+	size    int64
+	modTime time.Time
+	hash    string
+	// fallback marks entries read from the fallback filesystem
+	fallback bool
+}
+
+// TemplateCacheStats reports cache effectiveness.
+type TemplateCacheStats struct {
+	Templates int
+	Hits      int64
+	Misses    int64
+}
+
+// NewTemplateCache creates an empty cache.
+func NewTemplateCache() *TemplateCache {
+	return &TemplateCache{
+		templates: make(map[string]*ReportTemplate),
+		files:     make(map[string]cachedFile),
+	}
+}
+
+// SetFallback makes Load read templates missing on disk from fsys, looked up
+// by their file name, e.g. the defaults embedded in the binary. Files on disk
+// always take precedence so deployments can still override a template.
+func (c *TemplateCache) SetFallback(fsys fs.FS) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.fallback = fsys
+}
+
+// NewExporter returns an exporter for yamlConfig, parsing it only the first
+// time this content is seen.
+func (c *TemplateCache) NewExporter(yamlConfig string) (*ExcelDataExporter, error) {
+	tmpl, err := c.compile(contentHash([]byte(yamlConfig)), []byte(yamlConfig))
+	if err != nil {
+		return nil, err
+	}
+	return newExporterFromTemplate(tmpl.clone()), nil
+}
+
+// NewExporterFromFile returns an exporter for the template at path. The file
+// is only read again when its size or modification time changed.
+func (c *TemplateCache) NewExporterFromFile(path string) (*ExcelDataExporter, error) {
+	tmpl, err := c.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return newExporterFromTemplate(tmpl.clone()), nil
+}
+
+// Load compiles the template at path (or returns the cached one) without
+// creating an exporter. It is used to warm the cache at startup so broken
+// templates are reported before the first request. The returned template is
+// shared and must not be modified.
+func (c *TemplateCache) Load(path string) (*ReportTemplate, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		c.mu.RLock()
+		fallback := c.fallback
+		c.mu.RUnlock()
+		if fallback != nil && errors.Is(err, fs.ErrNotExist) {
+			return c.loadFallback(fallback, path)
+		}
+		return nil, fmt.Errorf("failed to read template %s: %w", path, err)
+	}
+
+	c.mu.RLock()
+	cf, ok := c.files[path]
+	c.mu.RUnlock()
+	if ok && !cf.fallback && cf.size == info.Size() && cf.modTime.Equal(info.ModTime()) {
+		if tmpl, err := c.compile(cf.hash, nil); err == nil {
+			return tmpl, nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template %s: %w", path, err)
+	}
+	hash := contentHash(data)
+	tmpl, err := c.compile(hash, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", path, err)
+	}
+
+	c.mu.Lock()
+	if old, ok := c.files[path]; ok && old.hash != hash {
+		// The file changed, drop the stale entry unless another path uses it
+		c.dropUnused(old.hash, path)
+	}
+	c.files[path] = cachedFile{size: info.Size(), modTime: info.ModTime(), hash: hash}
+	c.mu.Unlock()
+	return tmpl, nil
+}
+
+// loadFallback compiles the template named like path from fsys. Its content
+// never changes, so it is only read the first time.
+func (c *TemplateCache) loadFallback(fsys fs.FS, path string) (*ReportTemplate, error) {
+	c.mu.RLock()
+	cf, ok := c.files[path]
+	c.mu.RUnlock()
+	if ok && cf.fallback {
+		if tmpl, err := c.compile(cf.hash, nil); err == nil {
+			return tmpl, nil
+		}
+	}
+
+	data, err := fs.ReadFile(fsys, filepath.Base(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template %s: %w", path, err)
+	}
+	hash := contentHash(data)
+	tmpl, err := c.compile(hash, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", path, err)
+	}
+
+	c.mu.Lock()
+	if old, ok := c.files[path]; ok && old.hash != hash {
+		c.dropUnused(old.hash, path)
+	}
+	c.files[path] = cachedFile{hash: hash, fallback: true}
+	c.mu.Unlock()
+	return tmpl, nil
+}
+
+// Invalidate forgets the cached state of path so the next load re-reads it.
+// Call it when templates are updated in place within the same mtime tick.
+func (c *TemplateCache) Invalidate(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cf, ok := c.files[path]; ok {
+		delete(c.files, path)
+		c.dropUnused(cf.hash, path)
+	}
+}
+
+// Revision returns the Revision of the template cached for path, empty if
+// none is.
+func (c *TemplateCache) Revision(path string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if cf, ok := c.files[path]; ok {
+		return cf.hash[:revisionLength]
+	}
+	return ""
+}
+
+// Stats returns the number of cached templates and hit/miss counters.
+func (c *TemplateCache) Stats() TemplateCacheStats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return TemplateCacheStats{Templates: len(c.templates), Hits: c.hits, Misses: c.misses}
+}
+
+// compile returns the template for hash, parsing data on a miss. With nil
+// data a miss is reported as an error so the caller re-reads the source.
+func (c *TemplateCache) compile(hash string, data []byte) (*ReportTemplate, error) {
+	c.mu.Lock()
+	if tmpl, ok := c.templates[hash]; ok {
+		c.hits++
+		c.mu.Unlock()
+		return tmpl, nil
+	}
+	c.mu.Unlock()
+	if data == nil {
+		return nil, fmt.Errorf("template %s is not cached", hash)
+	}
+
+	tmpl, err := parseTemplate(string(data))
+	if err != nil {
+		return nil, err
+	}
+	tmpl.Revision = hash[:revisionLength]
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.misses++
+	if cached, ok := c.templates[hash]; ok {
+		// Another goroutine compiled it first
+		return cached, nil
+	}
+	c.templates[hash] = tmpl
+	return tmpl, nil
+}
+
+// dropUnused removes a template entry unless a path other than except still
+// refers to it. Must be called with c.mu held.
+func (c *TemplateCache) dropUnused(hash, except string) {
+	for p, cf := range c.files {
+		if p != except && cf.hash == hash {
+			return
+		}
+	}
+	delete(c.templates, hash)
+}
+
+// revisionLength is the number of hex digits of the content hash kept as
+// ReportTemplate.Revision.
+const revisionLength = 12
+
+func contentHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// clone copies the parts of the template that rendering mutates: sheets,
+// sections and columns. Styles and compare configs are shared, they are
+// only read.
+func (t *ReportTemplate) clone() *ReportTemplate {
+	cp := *t
+	cp.Variables = append(VariableDecls(nil), t.Variables...)
+	cp.Sheets = make([]SheetTemplate, len(t.Sheets))
+	for i, sheet := range t.Sheets {
+		cp.Sheets[i] = sheet
+		cp.Sheets[i].Sections = make([]SectionConfig, len(sheet.Sections))
+		for j, sec := range sheet.Sections {
+			sec.Columns = append([]ColumnConfig(nil), sec.Columns...)
+			sec.SourceSections = append([]string(nil), sec.SourceSections...)
+			cp.Sheets[i].Sections[j] = sec
+		}
+	}
+	return &cp
+}
@@ -0,0 +1,129 @@
+package simpleexcelv2
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const cacheYAML = `
+sheets:
+  - name: "Report"
+    sections:
+      - id: "rows"
+        show_header: true
+        columns:
+          - field_name: "Name"
+            header: "Name"
+`
+
+func TestTemplateCache_ReusesParsedTemplate(t *testing.T) {
+	cache := NewTemplateCache()
+
+	first, err := cache.NewExporter(cacheYAML)
+	require.NoError(t, err)
+	second, err := cache.NewExporter(cacheYAML)
+	require.NoError(t, err)
+
+	stats := cache.Stats()
+	assert.Equal(t, 1, stats.Templates)
+	assert.Equal(t, int64(1), stats.Misses)
+	assert.Equal(t, int64(1), stats.Hits)
+
+	// Each exporter renders its own copy of the sections
+	first.BindSectionData("rows", []struct{ Name, Extra string }{{"A", "x"}})
+	_, err = first.BuildExcel()
+	require.NoError(t, err)
+	assert.Len(t, first.GetSection("rows").Columns, 2)
+	assert.Len(t, second.GetSection("rows").Columns, 1)
+}
+
+func TestTemplateCache_ReloadsChangedFile(t *testing.T) {
+	cache := NewTemplateCache()
+	path := filepath.Join(t.TempDir(), "report.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(cacheYAML), 0o644))
+
+	exporter, err := cache.NewExporterFromFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "Name", exporter.GetSection("rows").Columns[0].Header)
+	revision := exporter.Template().Revision
+	assert.Len(t, revision, 12)
+
+	_, err = cache.NewExporterFromFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), cache.Stats().Hits)
+
+	updated := []byte(`
+sheets:
+  - name: "Report"
+    sections:
+      - id: "rows"
+        columns:
+          - field_name: "Name"
+            header: "Full Name"
+`)
+	require.NoError(t, os.WriteFile(path, updated, 0o644))
+	later := time.Now().Add(time.Second)
+	require.NoError(t, os.Chtimes(path, later, later))
+
+	reloaded, err := cache.NewExporterFromFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "Full Name", reloaded.GetSection("rows").Columns[0].Header)
+	assert.NotEqual(t, revision, reloaded.Template().Revision)
+	assert.Equal(t, 1, cache.Stats().Templates, "stale entry is dropped")
+
+	// Exporters created before the change keep rendering the old revision
+	assert.Equal(t, revision, exporter.Template().Revision)
+	assert.Equal(t, "Name", exporter.GetSection("rows").Columns[0].Header)
+}
+
+func TestTemplateCache_Invalidate(t *testing.T) {
+	cache := NewTemplateCache()
+	path := filepath.Join(t.TempDir(), "report.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(cacheYAML), 0o644))
+
+	_, err := cache.Load(path)
+	require.NoError(t, err)
+	cache.Invalidate(path)
+	assert.Equal(t, 0, cache.Stats().Templates)
+
+	_, err = cache.Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.Error(t, err)
+}
+
+func TestTemplateCache_Fallback(t *testing.T) {
+	cache := NewTemplateCache()
+	cache.SetFallback(fstest.MapFS{"report.yaml": {Data: []byte(cacheYAML)}})
+	dir := t.TempDir()
+	path := filepath.Join(dir, "report.yaml")
+
+	exporter, err := cache.NewExporterFromFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "Name", exporter.GetSection("rows").Columns[0].Header)
+	_, err = cache.Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), cache.Stats().Hits, "fallback is read once")
+
+	_, err = cache.Load(filepath.Join(dir, "missing.yaml"))
+	assert.Error(t, err)
+
+	// A file on disk overrides the fallback
+	override := []byte(`
+sheets:
+  - name: "Report"
+    sections:
+      - id: "rows"
+        columns:
+          - field_name: "Name"
+            header: "Full Name"
+`)
+	require.NoError(t, os.WriteFile(path, override, 0o644))
+	exporter, err = cache.NewExporterFromFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "Full Name", exporter.GetSection("rows").Columns[0].Header)
+}
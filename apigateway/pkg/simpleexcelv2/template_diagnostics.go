@@ -0,0 +1,439 @@
+package simpleexcelv2
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+	yamlv2 "gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Diagnostic severities.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// TemplateDiagnostic is a problem found in a YAML template, positioned for
+// an editor. Line and Column are 1-based, 0 when the problem has no position.
+type TemplateDiagnostic struct {
+	Severity string `json:"severity"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+	// Path locates the value, e.g. "sheets[0].sections[1].columns[2].header"
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+	// Suggestion is the closest valid key or value of a misspelled one
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// TemplateValidation is the result of ValidateTemplate.
+type TemplateValidation struct {
+	// Valid is set when there are no errors, warnings are allowed
+	Valid bool `json:"valid"`
+	// Partial is set when a syntax error stopped parsing, the diagnostics
+	// after it only cover the lines before it
+	Partial     bool                 `json:"partial"`
+	Diagnostics []TemplateDiagnostic `json:"diagnostics"`
+}
+
+// ValidateTemplate checks a YAML template, possibly still being written, and
+// reports every problem with its position instead of stopping at the first:
+// syntax errors, values of the wrong type, unknown keys with the closest
+// known one, unknown section types and invalid positions. Keys without a
+// value yet are skipped. When the structure is sound, the checks the
+// exporter runs when loading the template follow (variables, layouts,
+// section type configs, column validation).
+//
+// On a syntax error, the lines before it are checked on their own so an
+// editor validating on each keystroke still gets the diagnostics of what
+// is written so far.
+func ValidateTemplate(yamlConfig string) *TemplateValidation {
+	v := &templateValidator{sectionIDs: make(map[string]int)}
+	result := &TemplateValidation{}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(yamlConfig), &doc); err != nil {
+		line, msg := syntaxErrorPosition(err)
+		v.add(SeverityError, line, 0, "", msg, "")
+		result.Partial = true
+		if prefix, ok := parsePrefix(yamlConfig, line); ok {
+			v.walkDocument(prefix)
+		}
+	} else {
+		v.walkDocument(&doc)
+		if !v.hasErrors() && strings.TrimSpace(yamlConfig) != "" {
+			if _, err := parseTemplate(yamlConfig); err != nil {
+				v.add(SeverityError, 0, 0, "", err.Error(), "")
+			}
+		}
+	}
+
+	sort.SliceStable(v.diags, func(i, j int) bool {
+		if v.diags[i].Line != v.diags[j].Line {
+			return v.diags[i].Line < v.diags[j].Line
+		}
+		return v.diags[i].Column < v.diags[j].Column
+	})
+	result.Diagnostics = v.diags
+	if result.Diagnostics == nil {
+		result.Diagnostics = []TemplateDiagnostic{}
+	}
+	result.Valid = !v.hasErrors()
+	return result
+}
+
+var yamlErrorLine = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// syntaxErrorPosition splits the line off a yaml syntax error.
+func syntaxErrorPosition(err error) (int, string) {
+	msg := err.Error()
+	if m := yamlErrorLine.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return line, m[2]
+	}
+	return 0, strings.TrimPrefix(msg, "yaml: ")
+}
+
+// maxPrefixAttempts bounds the lines dropped looking for a parsable prefix,
+// the reported line of a syntax error can be a few lines past its cause.
+const maxPrefixAttempts = 5
+
+// parsePrefix parses the lines before line, dropping a few more if they
+// don't parse either.
+func parsePrefix(yamlConfig string, line int) (*yaml.Node, bool) {
+	lines := strings.Split(yamlConfig, "\n")
+	if line <= 1 || line > len(lines)+1 {
+		return nil, false
+	}
+	for n := line - 1; n > 0 && n >= line-maxPrefixAttempts; n-- {
+		var doc yaml.Node
+		if err := yaml.Unmarshal([]byte(strings.Join(lines[:n], "\n")), &doc); err == nil {
+			return &doc, true
+		}
+	}
+	return nil, false
+}
+
+// templateValidator walks the YAML nodes alongside the template types.
+type templateValidator struct {
+	diags []TemplateDiagnostic
+	// sectionIDs maps section IDs to the line of their first definition
+	sectionIDs map[string]int
+}
+
+var (
+	reportTemplateType = reflect.TypeOf(ReportTemplate{})
+	sectionConfigType  = reflect.TypeOf(SectionConfig{})
+	compareConfigType  = reflect.TypeOf(CompareConfig{})
+	formatterChainType = reflect.TypeOf(FormatterChain{})
+	variableDeclsType  = reflect.TypeOf(VariableDecls{})
+	variableDeclType   = reflect.TypeOf(VariableDecl{})
+)
+
+func (v *templateValidator) add(severity string, line, column int, path, msg, suggestion string) {
+	v.diags = append(v.diags, TemplateDiagnostic{
+		Severity: severity, Line: line, Column: column, Path: path, Message: msg, Suggestion: suggestion,
+	})
+}
+
+func (v *templateValidator) addAt(severity string, node *yaml.Node, path, msg, suggestion string) {
+	v.add(severity, node.Line, node.Column, path, msg, suggestion)
+}
+
+func (v *templateValidator) hasErrors() bool {
+	for _, d := range v.diags {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *templateValidator) walkDocument(doc *yaml.Node) {
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return
+	}
+	v.walk(doc.Content[0], reportTemplateType, "")
+}
+
+// walk checks node against the type t it decodes into.
+func (v *templateValidator) walk(node *yaml.Node, t reflect.Type, path string) {
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		// Not written yet
+		return
+	}
+
+	switch t {
+	case formatterChainType:
+		// A name or a list of names
+		if node.Kind == yaml.SequenceNode {
+			for i, item := range node.Content {
+				v.walk(item, reflect.TypeOf(""), fmt.Sprintf("%s[%d]", path, i))
+			}
+			return
+		}
+		v.walk(node, reflect.TypeOf(""), path)
+		return
+	case variableDeclsType:
+		// Variable names to a bare type or a declaration
+		if !v.expectKind(node, yaml.MappingNode, path, "a mapping of variable names") {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			name, value := node.Content[i], node.Content[i+1]
+			if value.Kind == yaml.ScalarNode {
+				v.walk(value, reflect.TypeOf(""), joinPath(path, name.Value))
+			} else {
+				v.walk(value, variableDeclType, joinPath(path, name.Value))
+			}
+		}
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		v.walk(node, t.Elem(), path)
+	case reflect.Interface:
+		// Any value
+	case reflect.Struct:
+		v.walkStruct(node, t, path)
+	case reflect.Slice, reflect.Array:
+		if !v.expectKind(node, yaml.SequenceNode, path, "a list") {
+			return
+		}
+		for i, item := range node.Content {
+			v.walk(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+		}
+	case reflect.Map:
+		if !v.expectKind(node, yaml.MappingNode, path, "a mapping") {
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			v.walk(node.Content[i+1], t.Elem(), joinPath(path, node.Content[i].Value))
+		}
+	default:
+		v.walkScalar(node, t, path)
+	}
+}
+
+// walkStruct checks the keys of a mapping against the yaml tags of t.
+func (v *templateValidator) walkStruct(node *yaml.Node, t reflect.Type, path string) {
+	if !v.expectKind(node, yaml.MappingNode, path, "a mapping") {
+		return
+	}
+	fields := yamlFields(t)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		field, ok := fields[key.Value]
+		if !ok {
+			names := make([]string, 0, len(fields))
+			for name := range fields {
+				names = append(names, name)
+			}
+			v.addAt(SeverityWarning, key, joinPath(path, key.Value),
+				fmt.Sprintf("unknown field %q, it is ignored", key.Value), closestName(key.Value, names))
+			continue
+		}
+		v.walk(value, field.Type, joinPath(path, key.Value))
+	}
+	if t == sectionConfigType {
+		v.checkSection(node, path)
+	}
+	if t == compareConfigType {
+		v.checkCompare(node, path)
+	}
+}
+
+// checkCompare checks the op of a compare_with or compare_against mapping.
+func (v *templateValidator) checkCompare(node *yaml.Node, path string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Value != "op" || value.Kind != yaml.ScalarNode || value.Tag == "!!null" {
+			continue
+		}
+		ops := []string{CompareOpValue, CompareOpDiffFlag, CompareOpDelta, CompareOpPctChange, CompareOpHighlight}
+		if !contains(ops, value.Value) {
+			v.addAt(SeverityError, value, joinPath(path, "op"),
+				fmt.Sprintf("unknown compare op %q", value.Value), closestName(value.Value, ops))
+		}
+	}
+}
+
+// checkSection checks the values of a section the types don't constrain:
+// its type, position and ID uniqueness.
+func (v *templateValidator) checkSection(node *yaml.Node, path string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if value.Kind != yaml.ScalarNode || value.Tag == "!!null" {
+			continue
+		}
+		switch key.Value {
+		case "type":
+			types := sectionTypes()
+			if !contains(types, value.Value) {
+				v.addAt(SeverityError, value, joinPath(path, "type"),
+					fmt.Sprintf("unknown section type %q", value.Value), closestName(value.Value, types))
+			}
+		case "position":
+			if _, _, err := excelize.CellNameToCoordinates(value.Value); err != nil {
+				v.addAt(SeverityWarning, value, joinPath(path, "position"),
+					fmt.Sprintf("position %q is not a cell reference, the section is placed automatically", value.Value), "")
+			}
+		case "direction":
+			directions := []string{SectionDirectionHorizontal, SectionDirectionVertical}
+			if !contains(directions, value.Value) {
+				v.addAt(SeverityWarning, value, joinPath(path, "direction"),
+					fmt.Sprintf("unknown direction %q, the section is placed vertically", value.Value), closestName(value.Value, directions))
+			}
+		case "distinct_keep":
+			policies := []string{DistinctKeepFirst, DistinctKeepLast}
+			if !contains(policies, value.Value) {
+				v.addAt(SeverityWarning, value, joinPath(path, "distinct_keep"),
+					fmt.Sprintf("unknown distinct_keep %q, the first duplicate is kept", value.Value), closestName(value.Value, policies))
+			}
+		case "id":
+			if first, dup := v.sectionIDs[value.Value]; dup {
+				v.addAt(SeverityWarning, value, joinPath(path, "id"),
+					fmt.Sprintf("section ID %q is already used on line %d, data binds to both", value.Value, first), "")
+			} else {
+				v.sectionIDs[value.Value] = value.Line
+			}
+		}
+	}
+}
+
+// walkScalar decodes a scalar the way the template parser does, so values
+// like "yes" or quoted numbers are judged as they will be loaded.
+func (v *templateValidator) walkScalar(node *yaml.Node, t reflect.Type, path string) {
+	if !v.expectKind(node, yaml.ScalarNode, path, scalarTypeName(t)) {
+		return
+	}
+	raw, err := yaml.Marshal(node)
+	if err == nil {
+		err = yamlv2.Unmarshal(raw, reflect.New(t).Interface())
+	}
+	if err != nil {
+		v.addAt(SeverityError, node, path, fmt.Sprintf("expected %s, got %q", scalarTypeName(t), node.Value), "")
+	}
+}
+
+// expectKind reports a node of another kind than want, described by what.
+func (v *templateValidator) expectKind(node *yaml.Node, want yaml.Kind, path, what string) bool {
+	if node.Kind == want {
+		return true
+	}
+	v.addAt(SeverityError, node, path, fmt.Sprintf("expected %s, got %s", what, nodeKindName(node)), "")
+	return false
+}
+
+// yamlFields maps the yaml keys of t to their fields.
+func yamlFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+		if name == "-" || f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f
+	}
+	return fields
+}
+
+// sectionTypes lists the built-in and registered section types.
+func sectionTypes() []string {
+	types := []string{SectionTypeFull, SectionTypeTitleOnly, SectionTypeHidden, SectionTypeRepeat}
+	sectionRenderersMu.RLock()
+	for typ := range sectionRenderers {
+		types = append(types, typ)
+	}
+	sectionRenderersMu.RUnlock()
+	sort.Strings(types[4:])
+	return types
+}
+
+func scalarTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	}
+	return "a string"
+}
+
+func nodeKindName(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	}
+	return fmt.Sprintf("%q", node.Value)
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// closestName returns the name nearest to s by edit distance, or "" when
+// none is close enough to be a likely typo.
+func closestName(s string, names []string) string {
+	limit := max(2, len(s)/3)
+	best, bestDist := "", limit+1
+	for _, name := range names {
+		d := editDistance(strings.ToLower(s), name)
+		if d < bestDist || (d == bestDist && name < best) {
+			best, bestDist = name, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,91 @@
+package simpleexcelv2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateTemplate_Valid(t *testing.T) {
+	result := ValidateTemplate(cacheYAML)
+
+	assert.True(t, result.Valid)
+	assert.False(t, result.Partial)
+	assert.Empty(t, result.Diagnostics)
+}
+
+func TestValidateTemplate_ReportsEveryProblem(t *testing.T) {
+	result := ValidateTemplate(`sheets:
+  - name: "Staff"
+    sections:
+      - id: "staff"
+        type: "gant"
+        show_headr: true
+        position: "top"
+        columns:
+          - feild_name: "Name"
+          - field_name: "Salary"
+            width: "wide"
+            formatter: [upper, 3]
+      - id: "staff"
+        locked: sometimes
+`)
+
+	assert.False(t, result.Valid)
+	assert.False(t, result.Partial)
+	require.Len(t, result.Diagnostics, 7)
+	want := []TemplateDiagnostic{
+		{Severity: SeverityError, Line: 5, Column: 15, Path: "sheets[0].sections[0].type", Message: `unknown section type "gant"`, Suggestion: "gantt"},
+		{Severity: SeverityWarning, Line: 6, Column: 9, Path: "sheets[0].sections[0].show_headr", Message: `unknown field "show_headr", it is ignored`, Suggestion: "show_header"},
+		{Severity: SeverityWarning, Line: 7, Column: 19, Path: "sheets[0].sections[0].position", Message: `position "top" is not a cell reference, the section is placed automatically`},
+		{Severity: SeverityWarning, Line: 9, Column: 13, Path: "sheets[0].sections[0].columns[0].feild_name", Message: `unknown field "feild_name", it is ignored`, Suggestion: "field_name"},
+		{Severity: SeverityError, Line: 11, Column: 20, Path: "sheets[0].sections[0].columns[1].width", Message: `expected a number, got "wide"`},
+		{Severity: SeverityWarning, Line: 13, Column: 13, Path: "sheets[0].sections[1].id", Message: `section ID "staff" is already used on line 4, data binds to both`},
+		{Severity: SeverityError, Line: 14, Column: 17, Path: "sheets[0].sections[1].locked", Message: `expected a boolean, got "sometimes"`},
+	}
+	assert.Equal(t, want, result.Diagnostics)
+}
+
+func TestValidateTemplate_PartialDocument(t *testing.T) {
+	// Being typed: a key without value and a broken last line
+	result := ValidateTemplate(`sheets:
+  - name: "Staff"
+    sections:
+      - id: "staff"
+        show_header:
+        colums:
+          - field_name: "Name
+`)
+
+	assert.False(t, result.Valid)
+	assert.True(t, result.Partial)
+	require.Len(t, result.Diagnostics, 2)
+	assert.Equal(t, "sheets[0].sections[0].colums", result.Diagnostics[0].Path)
+	assert.Equal(t, "columns", result.Diagnostics[0].Suggestion)
+	assert.Equal(t, SeverityError, result.Diagnostics[1].Severity)
+	assert.Equal(t, 7, result.Diagnostics[1].Line)
+	assert.Contains(t, result.Diagnostics[1].Message, "found unexpected end of stream")
+}
+
+func TestValidateTemplate_LoadChecks(t *testing.T) {
+	// Structurally sound, rejected by the template parser
+	result := ValidateTemplate(`variables:
+  limit:
+    type: number
+sheets:
+  - name: "Staff"
+`)
+
+	assert.False(t, result.Valid)
+	require.Len(t, result.Diagnostics, 1)
+	assert.Equal(t, 0, result.Diagnostics[0].Line)
+	assert.Contains(t, result.Diagnostics[0].Message, `variable limit: unsupported type "number"`)
+}
+
+func TestValidateTemplate_Empty(t *testing.T) {
+	result := ValidateTemplate("")
+
+	assert.True(t, result.Valid)
+	assert.NotNil(t, result.Diagnostics)
+}
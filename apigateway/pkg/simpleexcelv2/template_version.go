@@ -0,0 +1,26 @@
+package simpleexcelv2
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TemplateVersion is the template schema version written by this package.
+// Templates of the same major version are accepted; templates without a
+// version, like those of simpleexcel and simpleexcelv3, are read as the
+// current version since their schema is a subset of it.
+const TemplateVersion = "1.0"
+
+// checkVersion rejects templates written for a schema this package cannot
+// read, rather than rendering them with the unknown fields dropped.
+func (t *ReportTemplate) checkVersion() error {
+	if t.Version == "" {
+		return nil
+	}
+	major, _, _ := strings.Cut(t.Version, ".")
+	current, _, _ := strings.Cut(TemplateVersion, ".")
+	if major != current {
+		return fmt.Errorf("template version %q is not supported, this package reads version %s.x", t.Version, current)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package simpleexcelv2
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateVersion(t *testing.T) {
+	const sheets = `
+sheets:
+  - name: "Staff"
+    sections:
+      - id: "staff"
+`
+	for _, version := range []string{"", `version: "1.0"`, `version: "1.3"`} {
+		_, err := NewExcelDataExporterFromYamlConfig(version + sheets)
+		assert.NoError(t, err, "version %q", version)
+	}
+
+	_, err := NewExcelDataExporterFromYamlConfig(`version: "2.0"` + sheets)
+	require.Error(t, err)
+	assert.Equal(t, `template version "2.0" is not supported, this package reads version 1.x`, err.Error())
+}
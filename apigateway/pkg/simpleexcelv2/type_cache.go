@@ -0,0 +1,70 @@
+package simpleexcelv2
+
+import (
+	"reflect"
+	"sync"
+)
+
+// typeInfo is the reflection metadata of a row type, computed once per type
+// and shared by all exporters, so repeated exports skip the field walks.
+type typeInfo struct {
+	marshaler marshalerKind
+	// fields are the exported fields of struct types in declaration order,
+	// the columns detected for them
+	fields []string
+	// index holds the index path of every field a column can name,
+	// promoted fields of embedded structs included
+	index map[string][]int
+}
+
+// typeInfos caches *typeInfo by reflect.Type.
+var typeInfos sync.Map
+
+// typeInfoOf returns the cached metadata of t.
+func typeInfoOf(t reflect.Type) *typeInfo {
+	if info, ok := typeInfos.Load(t); ok {
+		return info.(*typeInfo)
+	}
+	info := &typeInfo{marshaler: marshalerKindOf(t)}
+	if t.Kind() == reflect.Struct {
+		info.index = make(map[string][]int)
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); f.IsExported() {
+				info.fields = append(info.fields, f.Name)
+			}
+		}
+		for _, f := range reflect.VisibleFields(t) {
+			// FieldByName resolves the name as Go does, ambiguous names
+			// at the same depth name no field
+			if sf, ok := t.FieldByName(f.Name); ok && sf.IsExported() {
+				info.index[f.Name] = sf.Index
+			}
+		}
+	}
+	// Concurrent first exports may compute the same metadata, keep one
+	actual, _ := typeInfos.LoadOrStore(t, info)
+	return actual.(*typeInfo)
+}
+
+// fieldValue returns the value of the named field of a struct, "" when the
+// struct has no such field or it is promoted through a nil pointer.
+func (info *typeInfo) fieldValue(item reflect.Value, name string) interface{} {
+	index, ok := info.index[name]
+	if !ok {
+		return ""
+	}
+	if len(index) == 1 {
+		return item.Field(index[0]).Interface()
+	}
+	v, err := item.FieldByIndexErr(index)
+	if err != nil {
+		return ""
+	}
+	return v.Interface()
+}
+
+// hasField reports whether a column can name the field.
+func (info *typeInfo) hasField(name string) bool {
+	_, ok := info.index[name]
+	return ok
+}
@@ -0,0 +1,115 @@
+package simpleexcelv2
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type auditFields struct {
+	CreatedBy string
+	Note      string
+}
+
+type contactFields struct {
+	Email string
+	Note  string
+}
+
+type cachedRow struct {
+	Name string
+	*auditFields
+	contactFields
+	salary int
+}
+
+func TestTypeInfo(t *testing.T) {
+	info := typeInfoOf(reflect.TypeOf(cachedRow{}))
+	assert.Same(t, info, typeInfoOf(reflect.TypeOf(cachedRow{})), "metadata is computed once")
+
+	// Detected columns are the exported top-level fields
+	assert.Equal(t, []string{"Name"}, info.fields)
+	assert.True(t, info.hasField("CreatedBy"), "promoted through the embedded pointer")
+	assert.True(t, info.hasField("Email"))
+	assert.False(t, info.hasField("Note"), "ambiguous between the embedded structs")
+	assert.False(t, info.hasField("salary"), "unexported")
+}
+
+func TestTypeInfo_PromotedFieldValues(t *testing.T) {
+	rows := []cachedRow{
+		{Name: "Ann", auditFields: &auditFields{CreatedBy: "hr"}, contactFields: contactFields{Email: "ann@example.com"}},
+		{Name: "Bob"},
+	}
+	exporter := NewExcelDataExporter()
+	exporter.AddSheet("Staff").AddSection(&SectionConfig{
+		ID:      "staff",
+		Data:    rows,
+		Columns: []ColumnConfig{{FieldName: "Name"}, {FieldName: "CreatedBy"}, {FieldName: "Email"}, {FieldName: "Note"}},
+	})
+
+	var buf bytes.Buffer
+	require.NoError(t, exporter.ToCSV(&buf))
+	assert.Equal(t, "Ann,hr,ann@example.com,\nBob,,,\n", strings.TrimSuffix(buf.String(), "\n"))
+	require.Len(t, exporter.Warnings(), 1)
+	assert.Equal(t, "Note", exporter.Warnings()[0].Field)
+}
+
+func TestTypeInfo_ConcurrentExports(t *testing.T) {
+	type row struct {
+		ID   int
+		Name string
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			exporter := NewExcelDataExporter()
+			exporter.AddSheet("Rows").AddSection(&SectionConfig{ShowHeader: true, Data: []row{{i, "x"}}})
+			var buf bytes.Buffer
+			assert.NoError(t, exporter.ToCSV(&buf))
+			assert.Equal(t, fmt.Sprintf("ID,Name\n%d,x\n", i), strings.TrimSuffix(buf.String(), "\n"))
+		}(i)
+	}
+	wg.Wait()
+}
+
+// BenchmarkRepeatedExport exports the same row type with a new exporter each
+// time, as request handlers do. "cold" drops the shared metadata before each
+// export, as the former per-exporter caches did.
+func BenchmarkRepeatedExport(b *testing.B) {
+	type wideRow struct {
+		ID, Age, Grade, Level                 int
+		First, Last, Email, Phone, Dept, Role string
+		Salary, Bonus                         float64
+	}
+	data := make([]wideRow, 20)
+	for i := range data {
+		data[i] = wideRow{ID: i, First: "Ann", Last: "Lee", Salary: 5000}
+	}
+	export := func(b *testing.B) {
+		exporter := NewExcelDataExporter()
+		exporter.AddSheet("Rows").AddSection(&SectionConfig{ShowHeader: true, Data: data})
+		if err := exporter.ToCSV(&bytes.Buffer{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.Run("cold", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			typeInfos.Delete(reflect.TypeOf(wideRow{}))
+			export(b)
+		}
+	})
+	b.Run("shared", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			export(b)
+		}
+	})
+}
@@ -0,0 +1,80 @@
+package simpleexcelv2
+
+import "reflect"
+
+// TypedColumn is a column whose value is read by an accessor function instead
+// of looking a field up by name. FieldName is optional; set it to refer to the
+// column from CompareWith or import.
+type TypedColumn[T any] struct {
+	ColumnConfig
+	Value func(T) any
+}
+
+// SectionConfigT is a section of rows of type T. The embedded SectionConfig
+// carries the title, styles and placement; its Columns and Data are replaced
+// by the typed ones.
+type SectionConfigT[T any] struct {
+	SectionConfig
+	Columns []TypedColumn[T]
+	Data    []T
+}
+
+// AddTypedSection adds a section whose columns are read through typed
+// accessors, checked at compile time and without reflection per cell. The
+// columns are exactly the ones declared: fields of T are not added as extra
+// columns. Data bound later with BindSectionData must still be a []T.
+func AddTypedSection[T any](sheet *SheetBuilder, cfg SectionConfigT[T]) *SheetBuilder {
+	sec := cfg.SectionConfig
+	sec.Columns = make([]ColumnConfig, len(cfg.Columns))
+	for i, col := range cfg.Columns {
+		sec.Columns[i] = col.ColumnConfig
+		if col.Value != nil {
+			sec.Columns[i].accessor = typedAccessor(col.Value)
+		}
+	}
+	if cfg.Data != nil {
+		sec.Data = cfg.Data
+	}
+	return sheet.AddSection(&sec)
+}
+
+// typedAccessor adapts fn to the rows of a []T or []*T.
+func typedAccessor[T any](fn func(T) any) func(reflect.Value) interface{} {
+	return func(item reflect.Value) interface{} {
+		// Slice elements are addressable, taking a pointer avoids copying
+		// the row into an interface
+		if item.CanAddr() {
+			if p, ok := item.Addr().Interface().(*T); ok {
+				return fn(*p)
+			}
+		}
+		switch v := item.Interface().(type) {
+		case T:
+			return fn(v)
+		case *T:
+			if v != nil {
+				return fn(*v)
+			}
+		}
+		return ""
+	}
+}
+
+// columnValue reads the value of a column from a row.
+func (e *ExcelDataExporter) columnValue(item reflect.Value, col *ColumnConfig) interface{} {
+	if col.accessor != nil {
+		return col.accessor(item)
+	}
+	return e.extractValue(item, col.FieldName)
+}
+
+// typedColumns reports whether the columns are read through accessors, in
+// which case they are the complete layout of the section.
+func typedColumns(cols []ColumnConfig) bool {
+	for _, col := range cols {
+		if col.accessor != nil {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package simpleexcelv2
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/exceltest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type typedEmployee struct {
+	First  string
+	Last   string
+	Salary float64
+	Secret string
+}
+
+func typedEmployeeSection(data []typedEmployee) SectionConfigT[typedEmployee] {
+	return SectionConfigT[typedEmployee]{
+		SectionConfig: SectionConfig{ID: "staff", ShowHeader: true},
+		Columns: []TypedColumn[typedEmployee]{
+			{ColumnConfig: ColumnConfig{Header: "Name"}, Value: func(e typedEmployee) any { return e.First + " " + e.Last }},
+			{ColumnConfig: ColumnConfig{Header: "Salary", FieldName: "Salary", FormatterName: "upper"}, Value: func(e typedEmployee) any { return e.Salary }},
+		},
+		Data: data,
+	}
+}
+
+var typedEmployees = []typedEmployee{
+	{First: "Ann", Last: "Lee", Salary: 5000, Secret: "x"},
+	{First: "Bob", Last: "Kim", Salary: 4200, Secret: "y"},
+}
+
+func TestAddTypedSection(t *testing.T) {
+	exporter := NewExcelDataExporter()
+	AddTypedSection(exporter.AddSheet("Staff"), typedEmployeeSection(typedEmployees))
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+	defer f.Close()
+
+	rows, err := f.GetRows("Staff")
+	require.NoError(t, err)
+	// Only the declared columns, Secret is not added
+	assert.Equal(t, [][]string{
+		{"Name", "Salary"},
+		{"Ann Lee", "5000"},
+		{"Bob Kim", "4200"},
+	}, rows)
+	assert.Empty(t, exporter.Warnings())
+}
+
+func TestAddTypedSection_BindPointers(t *testing.T) {
+	exporter := NewExcelDataExporter()
+	AddTypedSection(exporter.AddSheet("Staff"), typedEmployeeSection(nil))
+	exporter.BindSectionData("staff", []*typedEmployee{&typedEmployees[1], nil})
+
+	var buf bytes.Buffer
+	require.NoError(t, exporter.ToCSV(&buf))
+	assert.Equal(t, "Name,Salary\nBob Kim,4200\n,\n", strings.TrimSuffix(buf.String(), "\n"))
+}
+
+func TestAddTypedSection_Stream(t *testing.T) {
+	exporter := NewExcelDataExporter()
+	AddTypedSection(exporter.AddSheet("Staff"), typedEmployeeSection(nil))
+
+	var buf bytes.Buffer
+	streamer, err := exporter.StartStream(&buf)
+	require.NoError(t, err)
+	require.NoError(t, streamer.Write("staff", typedEmployees[:1]))
+	require.NoError(t, streamer.Write("staff", typedEmployees[1:]))
+	require.NoError(t, streamer.Close())
+
+	exceltest.AssertRows(t, buf.Bytes(), "Staff", [][]string{
+		{"Name", "Salary"},
+		{"Ann Lee", "5000"},
+		{"Bob Kim", "4200"},
+	})
+}
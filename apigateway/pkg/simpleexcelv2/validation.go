@@ -0,0 +1,450 @@
+package simpleexcelv2
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// ValidationRule constrains the values of a column. Rules are written to the
+// workbook as Excel data validation on export and checked again on import
+// (see ReportTemplate.ValidateImport), since users can paste over or remove
+// Excel validation.
+//
+//	columns:
+//	  - field_name: "HireDate"
+//	    hidden_field_name: "hire_date"
+//	    validation:
+//	      required: true
+//	      type: date
+//	      min: "1985-01-01"
+//	      compare: { op: ">=", field: "BirthDate" }
+//	      message: "Hire date must be after 1985 and after the birth date"
+//
+// Excel allows one rule per cell, so only the first of enum, type/min/max and
+// compare becomes Excel validation. Required and pattern are checked on
+// import only, Excel has no equivalent.
+type ValidationRule struct {
+	Required bool   `yaml:"required"`
+	Type     string `yaml:"type"` // string (default), int, float or date
+	// Min and Max bound the value, or the length for strings. Dates use YYYY-MM-DD.
+	Min     string        `yaml:"min"`
+	Max     string        `yaml:"max"`
+	Pattern string        `yaml:"pattern"` // regular expression the whole value must match
+	Enum    []string      `yaml:"enum"`
+	Compare *FieldCompare `yaml:"compare"`
+	// Message replaces the generated error message
+	Message string `yaml:"message"`
+
+	re *regexp.Regexp
+}
+
+// FieldCompare compares a value with another column of the same row.
+type FieldCompare struct {
+	Op    string `yaml:"op"`    // <, <=, >, >=, = or !=
+	Field string `yaml:"field"` // FieldName of a column in the same section
+}
+
+// compareOps maps compare ops to message wording and the Excel operator.
+var compareOps = map[string]struct {
+	words string
+	excel string
+}{
+	"<":  {"less than", "<"},
+	"<=": {"less than or equal to", "<="},
+	">":  {"greater than", ">"},
+	">=": {"greater than or equal to", ">="},
+	"=":  {"equal to", "="},
+	"!=": {"different from", "<>"},
+}
+
+// validate checks the rule itself when the template is parsed.
+func (r *ValidationRule) validate(sec *SectionConfig, col *ColumnConfig) error {
+	name := fmt.Sprintf("section %s column %s", sec.ID, col.FieldName)
+	switch r.Type {
+	case "":
+		r.Type = VariableTypeString
+	case VariableTypeString, VariableTypeInt, VariableTypeFloat, VariableTypeDate:
+	default:
+		return fmt.Errorf("%s: unsupported validation type %q", name, r.Type)
+	}
+	for _, bound := range []string{r.Min, r.Max} {
+		if bound == "" {
+			continue
+		}
+		if _, err := r.bound(bound); err != nil {
+			return fmt.Errorf("%s: invalid bound %q: %w", name, bound, err)
+		}
+	}
+	if r.Pattern != "" {
+		re, err := regexp.Compile(`^(?:` + r.Pattern + `)$`)
+		if err != nil {
+			return fmt.Errorf("%s: invalid pattern: %w", name, err)
+		}
+		r.re = re
+	}
+	if r.Compare != nil {
+		if _, ok := compareOps[r.Compare.Op]; !ok {
+			return fmt.Errorf("%s: unsupported compare op %q", name, r.Compare.Op)
+		}
+		if sec.GetColumn(r.Compare.Field) == nil {
+			return fmt.Errorf("%s: compare field %q is not a column of the section", name, r.Compare.Field)
+		}
+	}
+	return nil
+}
+
+// bound parses a min/max value: a length for strings, otherwise a value of
+// the rule type.
+func (r *ValidationRule) bound(s string) (float64, error) {
+	if r.Type == VariableTypeString || r.Type == "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("string bounds are lengths and must be non-negative integers")
+		}
+		return float64(n), nil
+	}
+	v, _, err := r.parse(s)
+	return v, err
+}
+
+// parse converts a raw cell value to a comparable number. Dates are read as
+// Excel serial numbers (how date cells are stored) or as YYYY-MM-DD text.
+func (r *ValidationRule) parse(raw string) (float64, string, error) {
+	switch r.Type {
+	case VariableTypeInt:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil || f != math.Trunc(f) {
+			return 0, "must be a whole number", fmt.Errorf("not an integer")
+		}
+		return f, "", nil
+	case VariableTypeFloat:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return 0, "must be a number", err
+		}
+		return f, "", nil
+	case VariableTypeDate:
+		if f, err := strconv.ParseFloat(raw, 64); err == nil {
+			return f, "", nil
+		}
+		t, err := time.Parse(VariableDateLayout, raw)
+		if err != nil {
+			return 0, "must be a date (YYYY-MM-DD)", err
+		}
+		return excelSerial(t), "", nil
+	}
+	return float64(utf8.RuneCountInString(raw)), "", nil
+}
+
+// excelSerial converts a date to its Excel serial number (1900 date system).
+func excelSerial(t time.Time) float64 {
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	y, m, d := t.Date()
+	return float64(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Sub(epoch).Hours() / 24)
+}
+
+// CellProblem is a value that failed a validation rule.
+type CellProblem struct {
+	SectionID string `json:"section_id"`
+	Sheet     string `json:"sheet"`
+	Row       int    `json:"row"` // 1-based sheet row
+	Cell      string `json:"cell"`
+	Field     string `json:"field"` // hidden field name
+	Rule      string `json:"rule"`  // required, type, enum, min, max, pattern or compare
+	Message   string `json:"message"`
+}
+
+// ValidationReport lists every problem found in an imported workbook.
+type ValidationReport struct {
+	Problems []CellProblem `json:"problems"`
+}
+
+func (r *ValidationReport) Error() string {
+	msgs := make([]string, len(r.Problems))
+	for i, p := range r.Problems {
+		msgs[i] = fmt.Sprintf("%s!%s %s: %s", p.Sheet, p.Cell, p.Field, p.Message)
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// ErrOrNil returns r if it holds any problem, nil otherwise.
+func (r *ValidationReport) ErrOrNil() error {
+	if len(r.Problems) == 0 {
+		return nil
+	}
+	return r
+}
+
+// ValidateImport checks imported rows against the column rules of the
+// template. Sections and columns are matched by section ID and hidden field
+// name; columns without a rule or without a hidden field name are not checked.
+func (t *ReportTemplate) ValidateImport(sections []ImportedSection) *ValidationReport {
+	report := &ValidationReport{}
+	for i := range sections {
+		imp := &sections[i]
+		sec := t.section(imp.ID)
+		if sec == nil {
+			continue
+		}
+		for _, row := range imp.Rows {
+			for j := range sec.Columns {
+				col := &sec.Columns[j]
+				if col.Validation == nil || col.HiddenFieldName == "" {
+					continue
+				}
+				raw, ok := row.Values[col.HiddenFieldName]
+				if !ok {
+					continue
+				}
+				rule, msg := col.Validation.check(sec, raw, row)
+				if rule == "" {
+					continue
+				}
+				if col.Validation.Message != "" {
+					msg = col.Validation.Message
+				}
+				report.Problems = append(report.Problems, CellProblem{
+					SectionID: imp.ID,
+					Sheet:     imp.Sheet,
+					Row:       row.Row,
+					Cell:      row.Cells[col.HiddenFieldName],
+					Field:     col.HiddenFieldName,
+					Rule:      rule,
+					Message:   msg,
+				})
+			}
+		}
+	}
+	return report
+}
+
+func (t *ReportTemplate) section(id string) *SectionConfig {
+	for i := range t.Sheets {
+		for j := range t.Sheets[i].Sections {
+			if t.Sheets[i].Sections[j].ID == id {
+				return &t.Sheets[i].Sections[j]
+			}
+		}
+	}
+	return nil
+}
+
+// check returns the name of the first failed rule and a message, or "" if
+// the value passes.
+func (r *ValidationRule) check(sec *SectionConfig, raw string, row ImportedRow) (string, string) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		if r.Required {
+			return "required", "is required"
+		}
+		return "", ""
+	}
+
+	if len(r.Enum) > 0 && !containsString(r.Enum, raw) {
+		return "enum", fmt.Sprintf("must be one of [%s], got %q", strings.Join(r.Enum, ", "), raw)
+	}
+
+	val, typeMsg, err := r.parse(raw)
+	if err != nil {
+		return "type", fmt.Sprintf("%s, got %q", typeMsg, raw)
+	}
+	isString := r.Type == VariableTypeString || r.Type == ""
+	if r.Min != "" {
+		if min, _ := r.bound(r.Min); val < min {
+			if isString {
+				return "min", fmt.Sprintf("must be at least %s characters", r.Min)
+			}
+			return "min", fmt.Sprintf("must be at least %s", r.Min)
+		}
+	}
+	if r.Max != "" {
+		if max, _ := r.bound(r.Max); val > max {
+			if isString {
+				return "max", fmt.Sprintf("must be at most %s characters", r.Max)
+			}
+			return "max", fmt.Sprintf("must be at most %s", r.Max)
+		}
+	}
+
+	re := r.re
+	if re == nil && r.Pattern != "" {
+		// Rules built in code are not compiled by parseTemplate
+		re, err = regexp.Compile(`^(?:` + r.Pattern + `)$`)
+		if err != nil {
+			return "pattern", fmt.Sprintf("invalid pattern: %v", err)
+		}
+	}
+	if re != nil && !re.MatchString(raw) {
+		return "pattern", fmt.Sprintf("must match %s", r.Pattern)
+	}
+
+	if r.Compare != nil {
+		other := sec.GetColumn(r.Compare.Field)
+		if other == nil || other.HiddenFieldName == "" {
+			return "", ""
+		}
+		otherRaw := strings.TrimSpace(row.Values[other.HiddenFieldName])
+		if otherRaw == "" {
+			return "", ""
+		}
+		if isString {
+			// Strings compare by value, not by length
+			if !compareStrings(raw, otherRaw, r.Compare.Op) {
+				return "compare", fmt.Sprintf("must be %s %s", compareOps[r.Compare.Op].words, other.HiddenFieldName)
+			}
+			return "", ""
+		}
+		otherVal, _, err := r.parse(otherRaw)
+		if err != nil {
+			// The other column reports its own type error
+			return "", ""
+		}
+		if !compareNumbers(val, otherVal, r.Compare.Op) {
+			return "compare", fmt.Sprintf("must be %s %s", compareOps[r.Compare.Op].words, other.HiddenFieldName)
+		}
+	}
+	return "", ""
+}
+
+func compareNumbers(a, b float64, op string) bool {
+	switch op {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	case "=":
+		return a == b
+	case "!=":
+		return a != b
+	}
+	return false
+}
+
+func compareStrings(a, b, op string) bool {
+	return compareNumbers(float64(strings.Compare(a, b)), 0, op)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// addDataValidations writes the Excel data validation for the columns of a
+// section whose data occupies rows startRow..startRow+rows-1.
+func (e *ExcelDataExporter) addDataValidations(f *excelize.File, sheet string, sec *SectionConfig, startCol, startRow, rows int) {
+	if rows <= 0 {
+		return
+	}
+	for j := range sec.Columns {
+		rule := sec.Columns[j].Validation
+		if rule == nil {
+			continue
+		}
+		dv, err := rule.dataValidation(sec, j, startCol, startRow)
+		if err != nil {
+			e.log("section %s column %s: skipping Excel validation: %v", sec.ID, sec.Columns[j].FieldName, err)
+			continue
+		}
+		if dv == nil {
+			continue
+		}
+		first := e.getCellAddress(startCol+j, startRow)
+		last := e.getCellAddress(startCol+j, startRow+rows-1)
+		dv.Sqref = first + ":" + last
+		if err := f.AddDataValidation(sheet, dv); err != nil {
+			e.log("section %s column %s: failed to add Excel validation: %v", sec.ID, sec.Columns[j].FieldName, err)
+		}
+	}
+}
+
+// dataValidation builds the Excel rule for column j, or nil when the rule
+// has nothing Excel can enforce.
+func (r *ValidationRule) dataValidation(sec *SectionConfig, j, startCol, startRow int) (*excelize.DataValidation, error) {
+	dv := excelize.NewDataValidation(!r.Required)
+	ruleType := r.Type
+	if ruleType == "" {
+		ruleType = VariableTypeString
+	}
+
+	switch {
+	case len(r.Enum) > 0:
+		if err := dv.SetDropList(r.Enum); err != nil {
+			return nil, err
+		}
+	case r.Min != "" || r.Max != "" || ruleType != VariableTypeString:
+		dvType := map[string]excelize.DataValidationType{
+			VariableTypeString: excelize.DataValidationTypeTextLength,
+			VariableTypeInt:    excelize.DataValidationTypeWhole,
+			VariableTypeFloat:  excelize.DataValidationTypeDecimal,
+			VariableTypeDate:   excelize.DataValidationTypeDate,
+		}[ruleType]
+		var min, max float64
+		var err error
+		if r.Min != "" {
+			if min, err = r.bound(r.Min); err != nil {
+				return nil, err
+			}
+		}
+		if r.Max != "" {
+			if max, err = r.bound(r.Max); err != nil {
+				return nil, err
+			}
+		}
+		switch {
+		case r.Min != "" && r.Max != "":
+			err = dv.SetRange(min, max, dvType, excelize.DataValidationOperatorBetween)
+		case r.Min != "":
+			err = dv.SetRange(min, min, dvType, excelize.DataValidationOperatorGreaterThanOrEqual)
+		case r.Max != "":
+			err = dv.SetRange(max, max, dvType, excelize.DataValidationOperatorLessThanOrEqual)
+		default:
+			// Type only: accept any value of the type
+			err = dv.SetRange(-math.MaxFloat32, math.MaxFloat32, dvType, excelize.DataValidationOperatorBetween)
+		}
+		if err != nil {
+			return nil, err
+		}
+	case r.Compare != nil:
+		otherIdx := -1
+		for k := range sec.Columns {
+			if sec.Columns[k].FieldName == r.Compare.Field {
+				otherIdx = k
+			}
+		}
+		if otherIdx < 0 {
+			return nil, fmt.Errorf("compare field %q not found", r.Compare.Field)
+		}
+		self, _ := excelize.CoordinatesToCellName(startCol+j, startRow)
+		other, _ := excelize.CoordinatesToCellName(startCol+otherIdx, startRow)
+		// Relative references, Excel shifts them for every row of the range
+		formula := fmt.Sprintf("OR(%s=\"\",%s%s%s)", other, self, compareOps[r.Compare.Op].excel, other)
+		dv.Type = "custom"
+		dv.Formula1 = "<formula1>" + xmlEscaper.Replace(formula) + "</formula1>"
+	default:
+		return nil, nil
+	}
+
+	msg := r.Message
+	if msg == "" {
+		msg = "The value does not meet the column rules."
+	}
+	dv.SetError(excelize.DataValidationErrorStyleStop, "Invalid value", msg)
+	return dv, nil
+}
+
+var xmlEscaper = strings.NewReplacer(`&`, `&amp;`, `<`, `&lt;`, `>`, `&gt;`, `"`, `&quot;`)
@@ -0,0 +1,204 @@
+package simpleexcelv2
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+const validationYAML = `
+sheets:
+  - name: "Staff"
+    sections:
+      - id: "staff"
+        show_header: true
+        columns:
+          - field_name: "Name"
+            hidden_field_name: "name"
+            validation:
+              required: true
+              max: 5
+          - field_name: "Code"
+            hidden_field_name: "code"
+            validation:
+              pattern: "[A-Z]{2}[0-9]+"
+          - field_name: "Gender"
+            hidden_field_name: "gender"
+            validation:
+              enum: ["M", "F"]
+          - field_name: "Age"
+            hidden_field_name: "age"
+            validation:
+              type: int
+              min: 18
+              max: 70
+          - field_name: "BirthDate"
+            hidden_field_name: "birth_date"
+            validation:
+              type: date
+          - field_name: "HireDate"
+            hidden_field_name: "hire_date"
+            validation:
+              type: date
+              compare: { op: ">", field: "BirthDate" }
+              message: "Hire date must be after the birth date"
+`
+
+type validationRow struct {
+	Name      string
+	Code      string
+	Gender    string
+	Age       int
+	BirthDate time.Time
+	HireDate  time.Time
+}
+
+func TestParseTemplate_RejectsInvalidRules(t *testing.T) {
+	tests := map[string]string{
+		"unknown type":  `{type: money}`,
+		"bad bound":     `{type: date, min: "yesterday"}`,
+		"bad pattern":   `{pattern: "[a-"}`,
+		"unknown field": `{compare: {op: ">", field: "Missing"}}`,
+		"unknown op":    `{compare: {op: "~", field: "Name"}}`,
+	}
+	for name, rule := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewExcelDataExporterFromYamlConfig(`
+sheets:
+  - name: "S"
+    sections:
+      - id: "s"
+        columns:
+          - field_name: "Name"
+            validation: ` + rule)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestExport_WritesDataValidation(t *testing.T) {
+	day := time.Date(1990, 5, 1, 0, 0, 0, 0, time.UTC)
+	rows := []validationRow{{Name: "Ann", Code: "AB1", Gender: "F", Age: 30, BirthDate: day, HireDate: day.AddDate(25, 0, 0)}}
+
+	build := func(t *testing.T, streamed bool) *excelize.File {
+		exporter, err := NewExcelDataExporterFromYamlConfig(validationYAML)
+		require.NoError(t, err)
+		var buf bytes.Buffer
+		if streamed {
+			s, err := exporter.StartStream(&buf)
+			require.NoError(t, err)
+			require.NoError(t, s.Write("staff", rows))
+			require.NoError(t, s.Close())
+		} else {
+			exporter.BindSectionData("staff", rows)
+			require.NoError(t, exporter.ToWriter(&buf))
+		}
+		f, err := excelize.OpenReader(&buf)
+		require.NoError(t, err)
+		return f
+	}
+
+	for _, streamed := range []bool{false, true} {
+		f := build(t, streamed)
+		dvs, err := f.GetDataValidations("Staff")
+		require.NoError(t, err)
+
+		bySqref := make(map[string]*excelize.DataValidation)
+		for _, dv := range dvs {
+			bySqref[dv.Sqref] = dv
+		}
+		// Buffered data starts on row 3, below the hidden field-name row and
+		// the header; the streamer writes no field-name row
+		row := "3"
+		if streamed {
+			row = "2"
+		}
+		cell := func(col string) string { return col + row + ":" + col + row }
+		require.Contains(t, bySqref, cell("A"), "streamed=%v", streamed)
+		assert.Equal(t, "textLength", bySqref[cell("A")].Type)
+		assert.NotContains(t, bySqref, cell("B"), "patterns are checked on import only")
+		assert.Equal(t, "list", bySqref[cell("C")].Type)
+		assert.Equal(t, "whole", bySqref[cell("D")].Type)
+		assert.Equal(t, "between", bySqref[cell("D")].Operator)
+		assert.Equal(t, "date", bySqref[cell("E")].Type)
+		assert.Equal(t, "date", bySqref[cell("F")].Type, "the type rule wins over compare")
+		f.Close()
+	}
+}
+
+func TestExport_CompareOnlyRuleUsesCustomFormula(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(`
+sheets:
+  - name: "S"
+    sections:
+      - id: "s"
+        columns:
+          - field_name: "Min"
+          - field_name: "Max"
+            validation:
+              compare: { op: ">=", field: "Min" }
+`)
+	require.NoError(t, err)
+	exporter.BindSectionData("s", []struct{ Min, Max string }{{"a", "b"}, {"c", "d"}})
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+
+	dvs, err := f.GetDataValidations("S")
+	require.NoError(t, err)
+	require.Len(t, dvs, 1)
+	assert.Equal(t, "custom", dvs[0].Type)
+	assert.Equal(t, "B1:B2", dvs[0].Sqref)
+	assert.Contains(t, dvs[0].Formula1, "B1&gt;=A1")
+}
+
+func TestValidateImport(t *testing.T) {
+	tmpl, err := parseTemplate(validationYAML)
+	require.NoError(t, err)
+
+	row := func(n int, vals map[string]string) ImportedRow {
+		cells := make(map[string]string)
+		for field := range vals {
+			cells[field] = field + "@" + string(rune('0'+n))
+		}
+		return ImportedRow{Row: n, Values: vals, Cells: cells}
+	}
+	birth := "1990-05-01"
+	sections := []ImportedSection{{
+		ID:    "staff",
+		Sheet: "Staff",
+		Rows: []ImportedRow{
+			row(3, map[string]string{"name": "Ann", "code": "AB1", "gender": "F", "age": "30", "birth_date": birth, "hire_date": "2015-05-01"}),
+			row(4, map[string]string{"name": "", "code": "ab1", "gender": "X", "age": "17", "birth_date": birth, "hire_date": "1980-01-01"}),
+			row(5, map[string]string{"name": "Bartholomew", "code": "", "gender": "M", "age": "3.5", "birth_date": "32874", "hire_date": "soon"}),
+		},
+	}}
+
+	report := tmpl.ValidateImport(sections)
+
+	type got struct {
+		Row         int
+		Field, Rule string
+	}
+	var problems []got
+	for _, p := range report.Problems {
+		problems = append(problems, got{p.Row, p.Field, p.Rule})
+		assert.Equal(t, "staff", p.SectionID)
+		assert.Equal(t, p.Field+"@"+string(rune('0'+p.Row)), p.Cell)
+	}
+	assert.Equal(t, []got{
+		{4, "name", "required"},
+		{4, "code", "pattern"},
+		{4, "gender", "enum"},
+		{4, "age", "min"},
+		{4, "hire_date", "compare"},
+		{5, "name", "max"},
+		{5, "age", "type"},
+		{5, "hire_date", "type"},
+	}, problems)
+	assert.Equal(t, "Hire date must be after the birth date", report.Problems[4].Message)
+	assert.Error(t, report.ErrOrNil())
+}
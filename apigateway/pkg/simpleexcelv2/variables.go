@@ -0,0 +1,312 @@
+package simpleexcelv2
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Variable types supported in template declarations.
+const (
+	VariableTypeString = "string"
+	VariableTypeInt    = "int"
+	VariableTypeFloat  = "float"
+	VariableTypeBool   = "bool"
+	VariableTypeDate   = "date" // YYYY-MM-DD, converted to time.Time
+	VariableTypeEnum   = "enum" // string restricted to Allowed
+)
+
+// VariableDateLayout is the layout accepted for date variables.
+const VariableDateLayout = "2006-01-02"
+
+// VariableDecl declares a template parameter.
+// In YAML it is either a bare type ("limit: int"), which declares a required
+// variable, or a mapping:
+//
+//	variables:
+//	  department:
+//	    type: enum
+//	    label: "Department"
+//	    required: true
+//	    allowed: ["d001", "d002"]
+//	  limit:
+//	    type: int
+//	    default: 100
+type VariableDecl struct {
+	Name        string        `yaml:"-" json:"name"`
+	Type        string        `yaml:"type" json:"type"`
+	Label       string        `yaml:"label" json:"label,omitempty"`
+	Description string        `yaml:"description" json:"description,omitempty"`
+	Required    bool          `yaml:"required" json:"required"`
+	Default     interface{}   `yaml:"default" json:"default,omitempty"`
+	Allowed     []interface{} `yaml:"allowed" json:"allowed,omitempty"`
+}
+
+// VariableDecls keeps declarations in the order they appear in the template,
+// so UIs can render parameter forms in a stable order.
+type VariableDecls []VariableDecl
+
+// UnmarshalYAML decodes the variables mapping, keeping declaration order.
+func (d *VariableDecls) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var items yaml.MapSlice
+	if err := unmarshal(&items); err != nil {
+		return err
+	}
+	decls := make(VariableDecls, 0, len(items))
+	for _, item := range items {
+		name := fmt.Sprintf("%v", item.Key)
+		decl := VariableDecl{Name: name}
+		switch v := item.Value.(type) {
+		case string:
+			// Shorthand "name: type" declares a required variable
+			decl.Type = v
+			decl.Required = true
+		case nil:
+			decl.Type = VariableTypeString
+			decl.Required = true
+		default:
+			raw, err := yaml.Marshal(v)
+			if err != nil {
+				return fmt.Errorf("variable %s: %w", name, err)
+			}
+			if err := yaml.Unmarshal(raw, &decl); err != nil {
+				return fmt.Errorf("variable %s: %w", name, err)
+			}
+			decl.Name = name
+		}
+		if decl.Type == "" {
+			decl.Type = VariableTypeString
+		}
+		decls = append(decls, decl)
+	}
+	*d = decls
+	return nil
+}
+
+// Lookup returns the declaration with the given name.
+func (d VariableDecls) Lookup(name string) (VariableDecl, bool) {
+	for _, decl := range d {
+		if decl.Name == name {
+			return decl, true
+		}
+	}
+	return VariableDecl{}, false
+}
+
+// validate checks the declaration itself: known type, enum values and a
+// default that converts to the declared type.
+func (d VariableDecl) validate() error {
+	switch d.Type {
+	case VariableTypeString, VariableTypeInt, VariableTypeFloat, VariableTypeBool, VariableTypeDate:
+	case VariableTypeEnum:
+		if len(d.Allowed) == 0 {
+			return fmt.Errorf("variable %s: enum requires allowed values", d.Name)
+		}
+	default:
+		return fmt.Errorf("variable %s: unsupported type %q", d.Name, d.Type)
+	}
+	for _, a := range d.Allowed {
+		if _, err := d.convert(a); err != nil {
+			return fmt.Errorf("variable %s: allowed value %v: %w", d.Name, a, err)
+		}
+	}
+	if d.Default != nil {
+		if _, err := d.Convert(d.Default); err != nil {
+			return fmt.Errorf("variable %s: default: %w", d.Name, err)
+		}
+	}
+	return nil
+}
+
+// Convert turns a supplied value (JSON-decoded, YAML-decoded or a string from
+// a query parameter) into the declared Go type and checks it against Allowed.
+func (d VariableDecl) Convert(raw interface{}) (interface{}, error) {
+	val, err := d.convert(raw)
+	if err != nil {
+		return nil, err
+	}
+	if len(d.Allowed) == 0 {
+		return val, nil
+	}
+	allowed := make([]string, len(d.Allowed))
+	for i, a := range d.Allowed {
+		av, _ := d.convert(a)
+		if reflect.DeepEqual(av, val) {
+			return val, nil
+		}
+		allowed[i] = fmt.Sprintf("%v", a)
+	}
+	return nil, fmt.Errorf("must be one of [%s], got %v", strings.Join(allowed, ", "), raw)
+}
+
+func (d VariableDecl) convert(raw interface{}) (interface{}, error) {
+	switch d.Type {
+	case VariableTypeString, VariableTypeEnum:
+		if s, ok := raw.(string); ok {
+			return s, nil
+		}
+		return nil, fmt.Errorf("must be a string, got %T", raw)
+	case VariableTypeInt:
+		switch v := raw.(type) {
+		case int:
+			return v, nil
+		case int64:
+			return int(v), nil
+		case float64:
+			if v != math.Trunc(v) {
+				return nil, fmt.Errorf("must be an integer, got %v", v)
+			}
+			return int(v), nil
+		case string:
+			i, err := strconv.Atoi(v)
+			if err != nil {
+				return nil, fmt.Errorf("must be an integer, got %q", v)
+			}
+			return i, nil
+		}
+		return nil, fmt.Errorf("must be an integer, got %T", raw)
+	case VariableTypeFloat:
+		switch v := raw.(type) {
+		case float64:
+			return v, nil
+		case int:
+			return float64(v), nil
+		case int64:
+			return float64(v), nil
+		case string:
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return nil, fmt.Errorf("must be a number, got %q", v)
+			}
+			return f, nil
+		}
+		return nil, fmt.Errorf("must be a number, got %T", raw)
+	case VariableTypeBool:
+		switch v := raw.(type) {
+		case bool:
+			return v, nil
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return nil, fmt.Errorf("must be true or false, got %q", v)
+			}
+			return b, nil
+		}
+		return nil, fmt.Errorf("must be true or false, got %T", raw)
+	case VariableTypeDate:
+		switch v := raw.(type) {
+		case time.Time:
+			return v, nil
+		case string:
+			t, err := time.Parse(VariableDateLayout, v)
+			if err != nil {
+				return nil, fmt.Errorf("must be a date (YYYY-MM-DD), got %q", v)
+			}
+			return t, nil
+		}
+		return nil, fmt.Errorf("must be a date string (YYYY-MM-DD), got %T", raw)
+	}
+	return nil, fmt.Errorf("unsupported variable type %q", d.Type)
+}
+
+// VariableValues holds converted variable values keyed by name.
+type VariableValues map[string]interface{}
+
+// Args returns the values for names in order, ready to be passed as
+// positional query arguments ($1, $2, ...).
+func (v VariableValues) Args(names ...string) []interface{} {
+	args := make([]interface{}, len(names))
+	for i, name := range names {
+		args[i] = v[name]
+	}
+	return args
+}
+
+// VariableProblem describes one invalid or missing variable.
+type VariableProblem struct {
+	Name    string
+	Message string
+}
+
+// VariableError reports every invalid or missing variable at once.
+type VariableError struct {
+	Problems []VariableProblem
+}
+
+func (e *VariableError) add(name, format string, args ...interface{}) {
+	e.Problems = append(e.Problems, VariableProblem{Name: name, Message: fmt.Sprintf(format, args...)})
+}
+
+func (e *VariableError) Error() string {
+	msgs := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		msgs[i] = p.Name + ": " + p.Message
+	}
+	return "invalid variables: " + strings.Join(msgs, "; ")
+}
+
+// Variables returns the variables declared by the YAML template, or nil for
+// programmatic exporters.
+func (e *ExcelDataExporter) Variables() VariableDecls {
+	if e.template == nil {
+		return nil
+	}
+	return e.template.Variables
+}
+
+// ResolveVariables validates supplied values against the template
+// declarations: required variables must be present, missing optional ones
+// take their default, every value is converted to its declared type and
+// checked against its allowed values, and undeclared names are rejected.
+// Problems are returned together as a *VariableError. A resolved "locale"
+// variable also selects the default number and date formats (see SetLocale).
+func (e *ExcelDataExporter) ResolveVariables(supplied map[string]interface{}) (VariableValues, error) {
+	decls := e.Variables()
+	vals := make(VariableValues, len(decls))
+	verr := &VariableError{}
+
+	for _, decl := range decls {
+		raw, ok := supplied[decl.Name]
+		if !ok || raw == nil {
+			if decl.Default != nil {
+				raw = decl.Default
+			} else if decl.Required {
+				verr.add(decl.Name, "is required (%s)", decl.Type)
+				continue
+			} else {
+				continue
+			}
+		}
+		val, err := decl.Convert(raw)
+		if err != nil {
+			verr.add(decl.Name, "%v", err)
+			continue
+		}
+		vals[decl.Name] = val
+	}
+
+	names := make([]string, 0, len(supplied))
+	for name := range supplied {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if _, ok := decls.Lookup(name); !ok {
+			verr.add(name, "is not declared by the template")
+		}
+	}
+
+	if len(verr.Problems) > 0 {
+		return nil, verr
+	}
+	if tag, ok := vals[LocaleVariable].(string); ok && tag != "" {
+		e.SetLocale(tag)
+	}
+	return vals, nil
+}
@@ -0,0 +1,101 @@
+package simpleexcelv2
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const variablesYAML = `
+variables:
+  department:
+    type: enum
+    label: "Department"
+    required: true
+    allowed: ["d001", "d002"]
+  limit:
+    type: int
+    default: 50
+  hired_after: date
+  active:
+    type: bool
+sheets:
+  - name: "Report"
+    sections:
+      - id: "employees"
+`
+
+func TestVariables_DeclarationOrderAndShorthand(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(variablesYAML)
+	require.NoError(t, err)
+
+	decls := exporter.Variables()
+	require.Len(t, decls, 4)
+	assert.Equal(t, []string{"department", "limit", "hired_after", "active"},
+		[]string{decls[0].Name, decls[1].Name, decls[2].Name, decls[3].Name})
+
+	hired, ok := decls.Lookup("hired_after")
+	require.True(t, ok)
+	assert.Equal(t, VariableTypeDate, hired.Type)
+	assert.True(t, hired.Required, "shorthand declarations are required")
+	assert.Equal(t, "Department", decls[0].Label)
+}
+
+func TestResolveVariables(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(variablesYAML)
+	require.NoError(t, err)
+
+	vals, err := exporter.ResolveVariables(map[string]interface{}{
+		"department":  "d002",
+		"hired_after": "2021-03-01",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "d002", vals["department"])
+	assert.Equal(t, 50, vals["limit"], "default applied")
+	assert.Equal(t, time.Date(2021, 3, 1, 0, 0, 0, 0, time.UTC), vals["hired_after"])
+	_, ok := vals["active"]
+	assert.False(t, ok, "optional variable without default stays unset")
+	assert.Equal(t, []interface{}{"d002", 50}, vals.Args("department", "limit"))
+}
+
+func TestResolveVariables_ReportsAllProblems(t *testing.T) {
+	exporter, err := NewExcelDataExporterFromYamlConfig(variablesYAML)
+	require.NoError(t, err)
+
+	_, err = exporter.ResolveVariables(map[string]interface{}{
+		"department": "d999",
+		"limit":      "ten",
+		"region":     "EU",
+	})
+
+	var verr *VariableError
+	require.True(t, errors.As(err, &verr))
+	var names []string
+	for _, p := range verr.Problems {
+		names = append(names, p.Name)
+	}
+	assert.Equal(t, []string{"department", "limit", "hired_after", "region"}, names)
+}
+
+func TestVariables_InvalidDeclaration(t *testing.T) {
+	_, err := NewExcelDataExporterFromYamlConfig(`
+variables:
+  status:
+    type: enum
+sheets: []
+`)
+	assert.ErrorContains(t, err, "enum requires allowed values")
+
+	_, err = NewExcelDataExporterFromYamlConfig(`
+variables:
+  limit:
+    type: int
+    default: "many"
+sheets: []
+`)
+	assert.ErrorContains(t, err, "default")
+}
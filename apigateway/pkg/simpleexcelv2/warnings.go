@@ -0,0 +1,319 @@
+package simpleexcelv2
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// WarningKind classifies export warnings.
+type WarningKind string
+
+const (
+	// WarningUnknownSection is data bound to an ID no section has
+	WarningUnknownSection WarningKind = "unknown_section"
+	// WarningUnknownField is a column whose field the bound struct lacks,
+	// its cells are left empty
+	WarningUnknownField WarningKind = "unknown_field"
+	// WarningInvalidPosition is a section position that is not a cell
+	// reference, the section is placed as if it had none
+	WarningInvalidPosition WarningKind = "invalid_position"
+	// WarningInvalidAnchor is a below or right_of naming no section of the
+	// sheet or forming a cycle, the section is placed as if it had none
+	WarningInvalidAnchor WarningKind = "invalid_anchor"
+	// WarningUnknownFormatter is a formatter name that is neither registered
+	// nor built in, values pass through unformatted
+	WarningUnknownFormatter WarningKind = "unknown_formatter"
+
+	// The kinds below describe how the output was adjusted rather than a
+	// mistake, Strict and WithStrictBinding do not fail on them
+
+	// WarningUnboundSection is a section no data was bound or streamed to,
+	// it is rendered without rows
+	WarningUnboundSection WarningKind = "unbound_section"
+	// WarningMaskedColumn is a column whose values are masked
+	WarningMaskedColumn WarningKind = "masked_column"
+	// WarningTruncatedValue is a column with values longer than an Excel
+	// cell holds, they are cut to the first 32767 characters
+	WarningTruncatedValue WarningKind = "truncated_value"
+	// WarningSheetName is a sheet name Excel does not accept, the sheet is
+	// exported under a sanitized name
+	WarningSheetName WarningKind = "sheet_name"
+	// WarningUnsortedStream is a streamed section declaring sort_by, its
+	// rows are written as they are sent
+	WarningUnsortedStream WarningKind = "unsorted_stream"
+	// WarningStreamDuplicates is a streamed section declaring distinct_by,
+	// duplicate rows are written as they are sent
+	WarningStreamDuplicates WarningKind = "stream_duplicates"
+)
+
+// Adjusted reports whether the kind describes an adjustment of the output
+// rather than a mistake in the export.
+func (k WarningKind) Adjusted() bool {
+	switch k {
+	case WarningUnboundSection, WarningMaskedColumn, WarningTruncatedValue, WarningSheetName, WarningUnsortedStream, WarningStreamDuplicates:
+		return true
+	}
+	return false
+}
+
+// Warning is a problem the export works around instead of failing, see
+// Warnings and Strict.
+type Warning struct {
+	Kind    WarningKind `json:"kind"`
+	Sheet   string      `json:"sheet,omitempty"`
+	Section string      `json:"section,omitempty"`
+	Field   string      `json:"field,omitempty"`
+	Message string      `json:"message"`
+}
+
+func (w Warning) String() string {
+	var where []string
+	if w.Sheet != "" {
+		where = append(where, "sheet "+w.Sheet)
+	}
+	if w.Section != "" {
+		where = append(where, "section "+w.Section)
+	}
+	if w.Field != "" {
+		where = append(where, "field "+w.Field)
+	}
+	if len(where) == 0 {
+		return w.Message
+	}
+	return strings.Join(where, ", ") + ": " + w.Message
+}
+
+// StrictError is returned by strict exporters for an export with warnings.
+type StrictError struct {
+	Warnings []Warning
+}
+
+func (e *StrictError) Error() string {
+	msgs := make([]string, len(e.Warnings))
+	for i, w := range e.Warnings {
+		msgs[i] = w.String()
+	}
+	return fmt.Sprintf("export has %d warning(s): %s", len(e.Warnings), strings.Join(msgs, "; "))
+}
+
+// Warnings returns the problems found by the last export: data bound to
+// unknown sections, columns naming fields the data lacks, invalid section
+// positions and unknown formatters, and the adjustments of the output:
+// unbound sections, masked columns, truncated values and sanitized sheet
+// names, see WarningKind.Adjusted.
+func (e *ExcelDataExporter) Warnings() []Warning {
+	return e.warnings
+}
+
+// Strict makes exports fail with a *StrictError instead of working around
+// the problems listed by Warnings. Streams fail on StartStream, or on the
+// Write whose batch lacks a column's field.
+func (e *ExcelDataExporter) Strict() *ExcelDataExporter {
+	e.strict = true
+	return e
+}
+
+// checkExport collects the warnings of the sections of sheets and, when
+// strict, returns them as an error. Unbound sections of streams are only
+// known once they close.
+func (e *ExcelDataExporter) checkExport(sheets []*SheetBuilder, streaming bool) error {
+	e.warnings = nil
+	ids := make(map[string]bool)
+	for _, sb := range e.sheets {
+		for _, sec := range sb.sections {
+			ids[sec.ID] = true
+		}
+	}
+	var unbound []string
+	for id := range e.data {
+		if !ids[id] {
+			unbound = append(unbound, id)
+		}
+	}
+	sort.Strings(unbound)
+	for _, id := range unbound {
+		e.warn(Warning{Kind: WarningUnknownSection, Section: id, Message: "data is bound to an unknown section"})
+	}
+
+	for _, sb := range sheets {
+		if sb.renamedFrom != "" {
+			e.warn(Warning{Kind: WarningSheetName, Sheet: sb.name,
+				Message: fmt.Sprintf("sheet name %q is not valid in Excel", sb.renamedFrom)})
+		}
+		anchors := resolveLayout(sb.sections)
+		for i, sec := range sb.sections {
+			if msg, ok := anchors.problems[i]; ok {
+				e.warn(Warning{Kind: WarningInvalidAnchor, Sheet: sb.name, Section: sec.ID, Message: msg})
+			}
+			if sec.Position != "" {
+				if _, _, err := excelize.CellNameToCoordinates(sec.Position); err != nil {
+					e.warn(Warning{Kind: WarningInvalidPosition, Sheet: sb.name, Section: sec.ID,
+						Message: fmt.Sprintf("position %q is not a cell reference", sec.Position)})
+				}
+			}
+			for _, col := range sec.Columns {
+				e.checkFormatters(sb.name, sec, &col)
+			}
+			data := sec.Data
+			if data == nil {
+				data = e.data[sec.ID]
+			}
+			e.checkFields(sb.name, sec, data)
+			if data == nil && !streaming && needsData(sec) {
+				e.warnUnbound(sb.name, sec)
+			}
+			if data == nil && streaming && len(sec.SortBy) > 0 {
+				e.warn(Warning{Kind: WarningUnsortedStream, Sheet: sb.name, Section: sec.ID,
+					Message: "streamed rows are written in the order they are sent, sort_by is not applied"})
+			}
+			if data == nil && streaming && len(sec.DistinctBy) > 0 {
+				e.warn(Warning{Kind: WarningStreamDuplicates, Sheet: sb.name, Section: sec.ID,
+					Message: "streamed rows are written as they are sent, distinct_by is not applied"})
+			}
+		}
+	}
+	return e.strictError()
+}
+
+// checkFormatters warns about the formatter names of col that resolve to
+// nothing. A Formatter func replaces the named ones, so they are not checked.
+func (e *ExcelDataExporter) checkFormatters(sheet string, sec *SectionConfig, col *ColumnConfig) {
+	if col.Formatter == nil {
+		names := col.Formatters
+		if col.FormatterName != "" {
+			names = append([]string{col.FormatterName}, names...)
+		}
+		for _, name := range names {
+			if base, _, _ := strings.Cut(name, ":"); base == "mask" {
+				e.warn(Warning{Kind: WarningMaskedColumn, Sheet: sheet, Section: sec.ID, Field: col.FieldName,
+					Message: "values are masked"})
+			}
+			if _, ok := e.findFormatter(name); !ok {
+				e.warn(Warning{Kind: WarningUnknownFormatter, Sheet: sheet, Section: sec.ID, Field: col.FieldName,
+					Message: fmt.Sprintf("formatter %q is not registered", name)})
+			}
+		}
+	}
+	if col.RichTextFormatter == nil && col.RichText != "" {
+		if _, ok := e.richTextFormatters[col.RichText]; !ok {
+			e.warn(Warning{Kind: WarningUnknownFormatter, Sheet: sheet, Section: sec.ID, Field: col.FieldName,
+				Message: fmt.Sprintf("rich text formatter %q is not registered", col.RichText)})
+		}
+	}
+}
+
+// checkFields warns about the columns, sort and distinct fields of sec
+// naming fields the struct elements of data lack. Maps are not checked, their
+// keys may vary by row.
+func (e *ExcelDataExporter) checkFields(sheet string, sec *SectionConfig, data interface{}) {
+	t := reflect.TypeOf(data)
+	if t == nil {
+		return
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Map && sec.Type == SectionTypeRepeat {
+		// The groups of repeat sections
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+		return
+	}
+	elem := t.Elem()
+	if elem.Kind() == reflect.Ptr {
+		elem = elem.Elem()
+	}
+	if elem.Kind() != reflect.Struct {
+		return
+	}
+	info := typeInfoOf(elem)
+	if info.marshaler != marshalerNone {
+		return
+	}
+	for _, col := range sec.Columns {
+		if col.FieldName == "" || col.CompareWith != nil || col.accessor != nil {
+			continue
+		}
+		if !info.hasField(col.FieldName) {
+			e.warn(Warning{Kind: WarningUnknownField, Sheet: sheet, Section: sec.ID, Field: col.FieldName,
+				Message: fmt.Sprintf("%s has no field %s", elem.Name(), col.FieldName)})
+		}
+	}
+	for _, key := range parseSortKeys(sec.SortBy) {
+		if !info.hasField(key.field) {
+			e.warn(Warning{Kind: WarningUnknownField, Sheet: sheet, Section: sec.ID, Field: key.field,
+				Message: fmt.Sprintf("%s has no field %s to sort by", elem.Name(), key.field)})
+		}
+	}
+	for _, field := range sec.DistinctBy {
+		if !info.hasField(field) {
+			e.warn(Warning{Kind: WarningUnknownField, Sheet: sheet, Section: sec.ID, Field: field,
+				Message: fmt.Sprintf("%s has no field %s to find duplicates by", elem.Name(), field)})
+		}
+	}
+}
+
+// needsData reports whether sec renders rows from bound data. Title-only
+// and comparison sections render without.
+func needsData(sec *SectionConfig) bool {
+	return sec.ID != "" && sec.Type != SectionTypeTitleOnly && len(sec.SourceSections) == 0
+}
+
+func (e *ExcelDataExporter) warnUnbound(sheet string, sec *SectionConfig) {
+	e.warn(Warning{Kind: WarningUnboundSection, Sheet: sheet, Section: sec.ID, Message: "no data is bound, the section has no rows"})
+}
+
+// limitCell cuts text values of col longer than an Excel cell holds,
+// warning once per column and export.
+func (e *ExcelDataExporter) limitCell(sheet string, sec *SectionConfig, col *ColumnConfig, val interface{}) interface{} {
+	text, ok := val.(string)
+	if !ok || len(text) <= excelize.TotalCellChars || utf8.RuneCountInString(text) <= excelize.TotalCellChars {
+		return val
+	}
+	key := sheet + "\x00" + sec.ID + "\x00" + col.FieldName
+	if !e.truncated[key] {
+		e.truncated[key] = true
+		e.warn(Warning{Kind: WarningTruncatedValue, Sheet: sheet, Section: sec.ID, Field: col.FieldName,
+			Message: fmt.Sprintf("values longer than %d characters are truncated", excelize.TotalCellChars)})
+	}
+	runes := 0
+	for i := range text {
+		if runes == excelize.TotalCellChars {
+			return text[:i]
+		}
+		runes++
+	}
+	return text
+}
+
+func (e *ExcelDataExporter) warn(w Warning) {
+	e.warnings = append(e.warnings, w)
+	e.log("warning: %s", w)
+}
+
+// strictError returns the warnings as a *StrictError for strict exporters,
+// or only the binding ones with WithStrictBinding.
+func (e *ExcelDataExporter) strictError() error {
+	if !e.strict && !e.strictBinding {
+		return nil
+	}
+	var failing []Warning
+	for _, w := range e.warnings {
+		if w.Kind.Adjusted() {
+			continue
+		}
+		if e.strict || w.Kind == WarningUnknownSection || w.Kind == WarningUnknownField {
+			failing = append(failing, w)
+		}
+	}
+	if len(failing) == 0 {
+		return nil
+	}
+	return &StrictError{Warnings: failing}
+}
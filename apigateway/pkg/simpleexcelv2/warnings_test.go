@@ -0,0 +1,166 @@
+package simpleexcelv2
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+type warnRow struct {
+	Name string
+	Dept string
+}
+
+// newWarningExporter has one problem of each kind.
+func newWarningExporter(t *testing.T) *ExcelDataExporter {
+	exporter, err := NewExcelDataExporterFromYamlConfig(`
+sheets:
+  - name: "Staff"
+    sections:
+      - id: "staff"
+        show_header: true
+        position: "not-a-cell"
+        columns:
+          - field_name: "Name"
+            formatter: [upper, shout]
+          - field_name: "Salary"
+          - field_name: "Dept"
+            rich_text: "badge"
+`)
+	require.NoError(t, err)
+	exporter.BindSectionData("staff", []warnRow{{"Ann", "d001"}})
+	exporter.BindSectionData("staf", []warnRow{{"Bob", "d002"}})
+	return exporter
+}
+
+func TestWarnings_Collected(t *testing.T) {
+	exporter := newWarningExporter(t)
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err, "warnings do not fail the export")
+	defer f.Close()
+
+	warnings := exporter.Warnings()
+	kinds := make([]WarningKind, len(warnings))
+	for i, w := range warnings {
+		kinds[i] = w.Kind
+	}
+	assert.Equal(t, []WarningKind{
+		WarningUnknownSection,
+		WarningInvalidPosition,
+		WarningUnknownFormatter,
+		WarningUnknownFormatter,
+		WarningUnknownField,
+	}, kinds)
+	assert.Equal(t, "section staf: data is bound to an unknown section", warnings[0].String())
+	assert.Equal(t, `sheet Staff, section staff, field Name: formatter "shout" is not registered`, warnings[2].String())
+	assert.Equal(t, "Salary", warnings[4].Field)
+
+	// The problems are worked around
+	rows, err := f.GetRows("Staff")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ANN", "", "d001"}, rows[1])
+	assert.Len(t, exporter.Result().Warnings, 5)
+}
+
+func TestWarnings_ResetPerExport(t *testing.T) {
+	exporter := NewExcelDataExporter()
+	exporter.AddSheet("Staff").AddSection(&SectionConfig{
+		ID:      "staff",
+		Columns: []ColumnConfig{{FieldName: "Name", FormatterName: "upper"}},
+	})
+	exporter.BindSectionData("staff", []warnRow{{"Ann", "d001"}})
+
+	_, err := exporter.ToBytes()
+	require.NoError(t, err)
+	assert.Empty(t, exporter.Warnings())
+
+	exporter.BindSectionData("other", []warnRow{})
+	_, err = exporter.ToBytes()
+	require.NoError(t, err)
+	assert.Len(t, exporter.Warnings(), 1)
+}
+
+func TestWarnings_Strict(t *testing.T) {
+	exporter := newWarningExporter(t).Strict()
+
+	_, err := exporter.BuildExcel()
+	var strictErr *StrictError
+	require.True(t, errors.As(err, &strictErr), "got %v", err)
+	assert.Len(t, strictErr.Warnings, 5)
+	assert.Contains(t, err.Error(), "export has 5 warning(s)")
+
+	var buf bytes.Buffer
+	assert.Error(t, newWarningExporter(t).Strict().ToCSV(&buf))
+}
+
+func TestWarnings_StrictStream(t *testing.T) {
+	exporter := NewExcelDataExporter().Strict()
+	exporter.AddSheet("Staff").AddSection(&SectionConfig{
+		ID:         "staff",
+		ShowHeader: true,
+		Columns:    []ColumnConfig{{FieldName: "Name"}, {FieldName: "Salary"}},
+	})
+
+	streamer, err := exporter.StartStream(&bytes.Buffer{})
+	require.NoError(t, err)
+	err = streamer.Write("staff", []warnRow{{"Ann", "d001"}})
+	var strictErr *StrictError
+	require.True(t, errors.As(err, &strictErr), "got %v", err)
+	assert.Equal(t, WarningUnknownField, strictErr.Warnings[0].Kind)
+	streamer.Abort(err)
+}
+
+func TestWarnings_Adjustments(t *testing.T) {
+	long := strings.Repeat("x", excelize.TotalCellChars+10)
+	exporter := NewExcelDataExporter().Strict()
+	exporter.AddSheet("Staff: [2024/Q1] and a name too long").
+		AddSection(&SectionConfig{
+			ID:      "staff",
+			Columns: []ColumnConfig{{FieldName: "Name", FormatterName: "mask:2"}, {FieldName: "Dept"}},
+			Data:    []warnRow{{"Ann", long}, {"Bob", long}},
+		}).
+		AddSection(&SectionConfig{ID: "leavers", Columns: []ColumnConfig{{FieldName: "Name"}}})
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err, "strict exporters do not fail on adjustments")
+	defer f.Close()
+
+	sheet := "Staff_ _2024_Q1_ and a name too"
+	assert.Equal(t, []Warning{
+		{Kind: WarningSheetName, Sheet: sheet, Message: `sheet name "Staff: [2024/Q1] and a name too long" is not valid in Excel`},
+		{Kind: WarningMaskedColumn, Sheet: sheet, Section: "staff", Field: "Name", Message: "values are masked"},
+		{Kind: WarningUnboundSection, Sheet: sheet, Section: "leavers", Message: "no data is bound, the section has no rows"},
+		{Kind: WarningTruncatedValue, Sheet: sheet, Section: "staff", Field: "Dept", Message: "values longer than 32767 characters are truncated"},
+	}, exporter.Warnings())
+	for _, w := range exporter.Warnings() {
+		assert.True(t, w.Kind.Adjusted())
+	}
+
+	val, err := f.GetCellValue(sheet, "B2")
+	require.NoError(t, err)
+	assert.Len(t, val, excelize.TotalCellChars)
+}
+
+func TestWarnings_StreamUnboundSection(t *testing.T) {
+	exporter := NewExcelDataExporter()
+	exporter.AddSheet("Staff").
+		AddSection(&SectionConfig{ID: "staff", Columns: []ColumnConfig{{FieldName: "Name"}}}).
+		AddSection(&SectionConfig{ID: "leavers", Columns: []ColumnConfig{{FieldName: "Name"}}})
+
+	streamer, err := exporter.StartStream(&bytes.Buffer{})
+	require.NoError(t, err)
+	assert.Empty(t, exporter.Warnings(), "streamed sections are bound by Write")
+	require.NoError(t, streamer.Write("staff", []warnRow{{"Ann", "d001"}}))
+	require.NoError(t, streamer.Close())
+
+	require.Len(t, exporter.Warnings(), 1)
+	assert.Equal(t, Warning{Kind: WarningUnboundSection, Sheet: "Staff", Section: "leavers",
+		Message: "no data is bound, the section has no rows"}, exporter.Warnings()[0])
+	assert.Len(t, exporter.Result().Warnings, 1)
+}
@@ -0,0 +1,82 @@
+package simpleexcelv3
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const bindTestYAML = `
+sheets:
+  - name: "Report"
+    sections:
+      - id: "banner"
+        type: "title"
+        title: "Quarterly Report"
+      - id: "sales"
+        show_header: true
+      - id: "returns"
+        show_header: true
+      - id: "diff"
+        source_sections: ["sales", "returns"]
+`
+
+type bindRow struct {
+	Name string
+}
+
+func TestBindAll_BindsEverySection(t *testing.T) {
+	exporter, err := NewExcelDataExporterV3V3FromYamlConfig(bindTestYAML)
+	require.NoError(t, err)
+
+	err = exporter.BindAll(map[string]interface{}{
+		"sales":   []bindRow{{"A"}},
+		"returns": []bindRow{{"B"}},
+	})
+	require.NoError(t, err)
+
+	f, err := exporter.BuildExcel()
+	require.NoError(t, err)
+	val, _ := f.GetCellValue("Report", "A3")
+	assert.Equal(t, "A", val)
+}
+
+func TestBindAll_AggregatesUnknownAndMissing(t *testing.T) {
+	exporter, err := NewExcelDataExporterV3V3FromYamlConfig(bindTestYAML)
+	require.NoError(t, err)
+
+	err = exporter.BindAll(map[string]interface{}{
+		"sales":  []bindRow{{"A"}},
+		"salez":  []bindRow{{"typo"}},
+		"refund": []bindRow{{"typo"}},
+	})
+	require.Error(t, err)
+
+	var bindErr *BindError
+	require.True(t, errors.As(err, &bindErr))
+	assert.Equal(t, []string{"refund", "salez"}, bindErr.Unknown)
+	assert.Equal(t, []string{"returns"}, bindErr.Missing)
+	assert.Contains(t, err.Error(), "unknown section IDs: refund, salez")
+	assert.Contains(t, err.Error(), "sections without data: returns")
+
+	// Known IDs are still bound.
+	_, ok := exporter.data["sales"]
+	assert.True(t, ok)
+}
+
+func TestMustBind_PanicsOnMismatch(t *testing.T) {
+	exporter, err := NewExcelDataExporterV3V3FromYamlConfig(bindTestYAML)
+	require.NoError(t, err)
+
+	assert.Panics(t, func() {
+		exporter.MustBind(map[string]interface{}{"nope": []bindRow{}})
+	})
+	assert.NotPanics(t, func() {
+		exporter.MustBind(map[string]interface{}{
+			"sales":   []bindRow{},
+			"returns": []bindRow{},
+		})
+	})
+}
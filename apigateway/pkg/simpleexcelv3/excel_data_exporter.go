@@ -43,6 +43,9 @@ type ExcelDataExporterV3 struct {
 	styleCache   map[string]int
 	colNameCache map[int]string
 	fieldCache   map[fieldCacheKey]int
+
+	// objectStorage opens writers for ExportToObjectStorage
+	objectStorage ObjectWriterProvider
 }
 
 // fieldCacheKey is a unique key for caching field indices.
@@ -0,0 +1,63 @@
+package simpleexcelv3
+
+import (
+	"io"
+
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/exporter"
+)
+
+// AsExporter adapts the exporter to the engine-neutral exporter.Exporter.
+func (e *ExcelDataExporterV3) AsExporter() exporter.Exporter {
+	return &exporterAdapter{e: e}
+}
+
+type exporterAdapter struct {
+	e *ExcelDataExporterV3
+}
+
+func (a *exporterAdapter) AddSheet(name string) exporter.Sheet {
+	return &sheetAdapter{sb: a.e.AddSheet(name)}
+}
+
+func (a *exporterAdapter) Bind(sectionID string, data interface{}) exporter.Exporter {
+	a.e.BindSectionData(sectionID, data)
+	return a
+}
+
+func (a *exporterAdapter) Export(w io.Writer) error {
+	return a.e.ToWriter(w)
+}
+
+func (a *exporterAdapter) Stream(w io.Writer) (exporter.Streamer, error) {
+	streamer, err := a.e.StartStreamV3(w)
+	if err != nil {
+		return nil, err
+	}
+	return streamer, nil
+}
+
+type sheetAdapter struct {
+	sb *SheetBuilderV3
+}
+
+func (s *sheetAdapter) AddSection(sec *exporter.Section) exporter.Sheet {
+	config := &SectionConfigV3{
+		ID:         sec.ID,
+		ShowHeader: sec.ShowHeader,
+		Locked:     sec.Locked,
+		Data:       sec.Data,
+	}
+	if sec.Title != "" {
+		config.Title = sec.Title
+	}
+	for _, col := range sec.Columns {
+		config.Columns = append(config.Columns, ColumnConfigV3{
+			FieldName:     col.FieldName,
+			Header:        col.Header,
+			Width:         col.Width,
+			FormatterName: col.FormatterName,
+		})
+	}
+	s.sb.AddSection(config)
+	return s
+}
@@ -0,0 +1,48 @@
+package simpleexcelv3
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// ObjectWriterProvider opens a writer for an object in cloud storage.
+// pkg/objectstorage provides S3 and GCS implementations.
+type ObjectWriterProvider interface {
+	NewWriter(ctx context.Context, bucket, key string) (io.WriteCloser, error)
+}
+
+// objectAborter is implemented by writers that can discard a partial upload.
+type objectAborter interface {
+	CloseWithError(err error) error
+}
+
+// SetObjectStorage attaches the provider used by ExportToObjectStorage.
+func (e *ExcelDataExporterV3) SetObjectStorage(p ObjectWriterProvider) *ExcelDataExporterV3 {
+	e.objectStorage = p
+	return e
+}
+
+// ExportToObjectStorage renders the workbook and streams it to bucket/key
+// without writing a temp file. If rendering fails the upload is aborted.
+func (e *ExcelDataExporterV3) ExportToObjectStorage(ctx context.Context, bucket, key string) error {
+	if e.objectStorage == nil {
+		return fmt.Errorf("object storage is not configured, call SetObjectStorage first")
+	}
+	w, err := e.objectStorage.NewWriter(ctx, bucket, key)
+	if err != nil {
+		return fmt.Errorf("failed to open object %s/%s: %w", bucket, key, err)
+	}
+	if err := e.ToWriter(w); err != nil {
+		if a, ok := w.(objectAborter); ok {
+			a.CloseWithError(err)
+		} else {
+			w.Close()
+		}
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to finish upload of %s/%s: %w", bucket, key, err)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+// Package templates embeds the default report templates into the binary, so
+// deployments work without shipping the YAML files next to the executable.
+// Files in the template directory (REPORT_TEMPLATE_DIR) override the
+// embedded template of the same name.
+package templates
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+//go:embed *.yaml
+var defaults embed.FS
+
+// FS returns the embedded default templates, keyed by file name.
+func FS() fs.FS {
+	return defaults
+}
+
+// List returns the file names of the embedded default templates, sorted.
+func List() ([]string, error) {
+	names, err := fs.Glob(defaults, "*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// CopyTo writes the embedded default templates to dir, creating it if needed,
+// e.g. as a starting point for customizing them. Existing files are kept
+// unless overwrite is set. It returns the names of the files written.
+func CopyTo(dir string, overwrite bool) ([]string, error) {
+	names, err := List()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create template dir %s: %w", dir, err)
+	}
+
+	var written []string
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if !overwrite {
+			if _, err := os.Stat(path); err == nil {
+				continue
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return written, fmt.Errorf("failed to check template %s: %w", path, err)
+			}
+		}
+		data, err := defaults.ReadFile(name)
+		if err != nil {
+			return written, err
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return written, fmt.Errorf("failed to write template %s: %w", path, err)
+		}
+		written = append(written, name)
+	}
+	return written, nil
+}
@@ -0,0 +1,57 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/locvowork/employee_management_sample/apigateway/pkg/simpleexcelv2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestList(t *testing.T) {
+	names, err := List()
+	require.NoError(t, err)
+	assert.Contains(t, names, "employee_list.yaml")
+	assert.IsIncreasing(t, names)
+}
+
+func TestDefaultsParse(t *testing.T) {
+	names, err := List()
+	require.NoError(t, err)
+
+	cache := simpleexcelv2.NewTemplateCache()
+	cache.SetFallback(FS())
+	for _, name := range names {
+		_, err := cache.Load(filepath.Join(t.TempDir(), name))
+		assert.NoError(t, err, name)
+	}
+}
+
+func TestCopyTo(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "templates")
+	names, err := List()
+	require.NoError(t, err)
+
+	written, err := CopyTo(dir, false)
+	require.NoError(t, err)
+	assert.Equal(t, names, written)
+
+	// Customized files are kept unless overwriting
+	custom := filepath.Join(dir, "employee_list.yaml")
+	require.NoError(t, os.WriteFile(custom, []byte("custom"), 0o644))
+	written, err = CopyTo(dir, false)
+	require.NoError(t, err)
+	assert.Empty(t, written)
+	data, err := os.ReadFile(custom)
+	require.NoError(t, err)
+	assert.Equal(t, "custom", string(data))
+
+	written, err = CopyTo(dir, true)
+	require.NoError(t, err)
+	assert.Equal(t, names, written)
+	data, err = os.ReadFile(custom)
+	require.NoError(t, err)
+	assert.NotEqual(t, "custom", string(data))
+}